@@ -0,0 +1,248 @@
+// Package cache contains a Redis implementation of the [op.Cache] and [op.ValueCache],
+// to share the cache between multiple instances of the OP.
+//
+// It speaks the Redis protocol (RESP) directly, to keep the example free of
+// additional dependencies. Production deployments would rather
+// wrap a full-featured client, such as github.com/redis/go-redis.
+package cache
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/zitadel/oidc/v3/pkg/op"
+)
+
+var _ op.ValueCache = (*Redis)(nil)
+
+// Redis is an [op.Cache] backed by a Redis server.
+//
+// It is also an [op.ValueCache]: clients are kept in memory of the instance,
+// and Redis only stores a stamp of the client, the hash of its encoding.
+// A client is only returned while Redis holds its stamp, so deleting the key on any instance,
+// e.g. by [op.Provider.InvalidateClient], invalidates it on all instances,
+// while instances caching the same client keep it.
+type Redis struct {
+	addr   string
+	dialer net.Dialer
+	idle   chan *redisConn
+	values *op.MemoryCache
+	codec  op.ClientCodec
+}
+
+type stampedValue struct {
+	stamp string
+	value any
+}
+
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// NewRedis creates a cache using the Redis server at addr (host:port).
+// Up to maxIdle connections are kept open for reuse.
+// The codec encodes the clients for their stamps.
+func NewRedis(addr string, maxIdle int, codec op.ClientCodec) *Redis {
+	return &Redis{
+		addr:   addr,
+		idle:   make(chan *redisConn, maxIdle),
+		values: op.NewMemoryCache(),
+		codec:  codec,
+	}
+}
+
+// Get implements [op.Cache].
+func (c *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := c.do(ctx, "GET", key)
+	if err != nil {
+		return nil, false, err
+	}
+	if value == nil {
+		return nil, false, nil
+	}
+	data, ok := value.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis GET: unexpected reply %v", value)
+	}
+	return data, true, nil
+}
+
+// Set implements [op.Cache].
+func (c *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := c.do(ctx, "SET", key, string(value), "PX", strconv.FormatInt(max(ttl.Milliseconds(), 1), 10))
+	return err
+}
+
+// Delete implements [op.Cache].
+func (c *Redis) Delete(ctx context.Context, key string) error {
+	c.values.Delete(ctx, key)
+	_, err := c.do(ctx, "DEL", key)
+	return err
+}
+
+// GetValue implements [op.ValueCache].
+// The value of this instance is returned if Redis still holds its stamp.
+func (c *Redis) GetValue(ctx context.Context, key string) (any, bool, error) {
+	local, ok, _ := c.values.GetValue(ctx, key)
+	if !ok {
+		return nil, false, nil
+	}
+	stamp, ok, err := c.Get(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+	value := local.(stampedValue)
+	if !ok || string(stamp) != value.stamp {
+		c.values.Delete(ctx, key)
+		return nil, false, nil
+	}
+	return value.value, true, nil
+}
+
+// SetValue implements [op.ValueCache].
+// The value, which must be an [op.Client], is kept by this instance
+// and its stamp is stored in Redis.
+func (c *Redis) SetValue(ctx context.Context, key string, value any, ttl time.Duration) error {
+	stamp, err := c.stamp(value)
+	if err != nil {
+		return err
+	}
+	if err := c.Set(ctx, key, []byte(stamp), ttl); err != nil {
+		return err
+	}
+	return c.values.SetValue(ctx, key, stampedValue{stamp: stamp, value: value}, ttl)
+}
+
+// stamp returns the hash of the encoded client,
+// so all instances holding the same client agree on its stamp.
+func (c *Redis) stamp(value any) (string, error) {
+	client, ok := value.(op.Client)
+	if !ok {
+		return "", fmt.Errorf("redis: cannot stamp value of type %T", value)
+	}
+	data, err := c.codec.MarshalClient(client)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// Close closes the idle connections.
+func (c *Redis) Close() error {
+	for {
+		select {
+		case conn := <-c.idle:
+			conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (c *Redis) do(ctx context.Context, args ...string) (any, error) {
+	conn, err := c.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Time{})
+	}
+	reply, err := conn.command(args...)
+	var redisErr redisError
+	if err != nil && !errors.As(err, &redisErr) {
+		// the connection is in an unknown state
+		conn.Close()
+		return nil, err
+	}
+	c.release(conn)
+	return reply, err
+}
+
+func (c *Redis) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-c.idle:
+		return conn, nil
+	default:
+	}
+	conn, err := c.dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+	return &redisConn{Conn: conn, r: bufio.NewReader(conn)}, nil
+}
+
+func (c *Redis) release(conn *redisConn) {
+	select {
+	case c.idle <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// command sends the arguments as RESP array of bulk strings and reads the reply.
+func (conn *redisConn) command(args ...string) (any, error) {
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(arg))+"\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+	if _, err := conn.Write(buf); err != nil {
+		return nil, err
+	}
+	return conn.reply()
+}
+
+// reply reads a simple string, error, integer or bulk string.
+// A null bulk string is returned as nil.
+func (conn *redisConn) reply() (any, error) {
+	line, err := conn.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: invalid reply %q", line)
+	}
+	kind, line := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return line, nil
+	case '-':
+		return nil, redisError(line)
+	case ':':
+		return strconv.ParseInt(line, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, fmt.Errorf("redis: invalid bulk length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err = io.ReadFull(conn.r, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	default:
+		return nil, fmt.Errorf("redis: unsupported reply %q", kind)
+	}
+}
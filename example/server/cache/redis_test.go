@@ -0,0 +1,153 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/example/server/storage"
+)
+
+// fakeRedis serves GET, SET and DEL from a map.
+func fakeRedis(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	var (
+		mu     sync.Mutex
+		values = make(map[string]string)
+	)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					args, err := readCommand(r)
+					if err != nil {
+						return
+					}
+					mu.Lock()
+					switch strings.ToUpper(args[0]) {
+					case "GET":
+						if v, ok := values[args[1]]; ok {
+							fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(v), v)
+						} else {
+							io.WriteString(conn, "$-1\r\n")
+						}
+					case "SET":
+						values[args[1]] = args[2]
+						io.WriteString(conn, "+OK\r\n")
+					case "DEL":
+						delete(values, args[1])
+						io.WriteString(conn, ":1\r\n")
+					default:
+						io.WriteString(conn, "-ERR unknown command\r\n")
+					}
+					mu.Unlock()
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+	args := make([]string, n)
+	for i := range args {
+		if _, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
+
+func TestRedis(t *testing.T) {
+	ctx := context.Background()
+	cache := NewRedis(fakeRedis(t), 2, storage.ClientCodec{})
+	defer cache.Close()
+
+	_, ok, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, cache.Set(ctx, "key", []byte(`{"issuer":"https://op.example.com"}`), time.Minute))
+	got, ok, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, `{"issuer":"https://op.example.com"}`, string(got))
+
+	require.NoError(t, cache.Delete(ctx, "key"))
+	_, ok, err = cache.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = cache.do(ctx, "UNKNOWN")
+	assert.ErrorContains(t, err, "unknown command")
+	_, _, err = cache.Get(ctx, "key")
+	assert.NoError(t, err, "connection reused after error reply")
+}
+
+func TestRedis_value(t *testing.T) {
+	ctx := context.Background()
+	addr := fakeRedis(t)
+	first := NewRedis(addr, 2, storage.ClientCodec{})
+	defer first.Close()
+	second := NewRedis(addr, 2, storage.ClientCodec{})
+	defer second.Close()
+
+	client := storage.WebClient("client1", "secret")
+	require.NoError(t, first.SetValue(ctx, "client", client, time.Minute))
+	got, ok, err := first.GetValue(ctx, "client")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Same(t, client, got)
+
+	_, ok, err = second.GetValue(ctx, "client")
+	require.NoError(t, err)
+	assert.False(t, ok, "values are kept by the instance")
+
+	require.NoError(t, second.SetValue(ctx, "client", storage.WebClient("client1", "secret"), time.Minute))
+	got, ok, err = first.GetValue(ctx, "client")
+	require.NoError(t, err)
+	assert.True(t, ok, "same client stored by another instance")
+	assert.Same(t, client, got)
+
+	require.NoError(t, second.SetValue(ctx, "client", storage.WebClient("client1", "rotated"), time.Minute))
+	_, ok, err = first.GetValue(ctx, "client")
+	require.NoError(t, err)
+	assert.False(t, ok, "changed by another instance")
+
+	require.NoError(t, first.SetValue(ctx, "client", client, time.Minute))
+	require.NoError(t, second.Delete(ctx, "client"))
+	_, ok, err = first.GetValue(ctx, "client")
+	require.NoError(t, err)
+	assert.False(t, ok, "deleted by another instance")
+
+	assert.Error(t, first.SetValue(ctx, "other", &struct{ id string }{"client1"}, time.Minute), "not a client")
+}
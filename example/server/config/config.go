@@ -14,6 +14,13 @@ type Config struct {
 	Port        string
 	RedirectURI []string
 	UsersFile   string
+	RedisAddr   string
+	// Conformance lists the profiles of the OpenID Foundation conformance suite
+	// the server is configured for (e.g. basic,hybrid,backchannel_logout)
+	Conformance []string
+	// ConformanceURL is the base url of the test plan in the conformance suite,
+	// which is used for the uris of the conformance client
+	ConformanceURL string
 }
 
 // FromEnvVars loads configuration parameters from environment variables.
@@ -26,6 +33,10 @@ func FromEnvVars(defaults *Config) *Config {
 		Port:        defaults.Port,
 		RedirectURI: defaults.RedirectURI,
 		UsersFile:   defaults.UsersFile,
+		RedisAddr:   defaults.RedisAddr,
+
+		Conformance:    defaults.Conformance,
+		ConformanceURL: defaults.ConformanceURL,
 	}
 	if value, ok := os.LookupEnv("PORT"); ok {
 		cfg.Port = value
@@ -33,8 +44,17 @@ func FromEnvVars(defaults *Config) *Config {
 	if value, ok := os.LookupEnv("USERS_FILE"); ok {
 		cfg.UsersFile = value
 	}
+	if value, ok := os.LookupEnv("REDIS_ADDR"); ok {
+		cfg.RedisAddr = value
+	}
 	if value, ok := os.LookupEnv("REDIRECT_URI"); ok {
 		cfg.RedirectURI = strings.Split(value, ",")
 	}
+	if value, ok := os.LookupEnv("CONFORMANCE"); ok {
+		cfg.Conformance = strings.Split(value, ",")
+	}
+	if value, ok := os.LookupEnv("CONFORMANCE_URL"); ok {
+		cfg.ConformanceURL = value
+	}
 	return cfg
 }
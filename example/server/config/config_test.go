@@ -62,6 +62,17 @@ func TestFromEnvVars(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "conformance profiles",
+			env: map[string]string{
+				"CONFORMANCE":     "basic,hybrid",
+				"CONFORMANCE_URL": "https://localhost.emobix.co.uk:8443/test/a/zitadel-oidc",
+			},
+			want: &Config{
+				Conformance:    []string{"basic", "hybrid"},
+				ConformanceURL: "https://localhost.emobix.co.uk:8443/test/a/zitadel-oidc",
+			},
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			os.Clearenv()
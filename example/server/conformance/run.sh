@@ -0,0 +1,32 @@
+#!/bin/sh
+# Runs the certification test plans of the OpenID Foundation conformance suite
+# against the example server, see README.md.
+#
+# usage: CONFORMANCE_SUITE=../conformance-suite ./run.sh [profile...]
+set -e
+
+suite="${CONFORMANCE_SUITE:-../conformance-suite}"
+config="$(dirname "$0")/config.json"
+if [ $# -eq 0 ]; then
+	set -- basic implicit hybrid form_post rp_initiated_logout backchannel_logout
+fi
+
+plan() {
+	case "$1" in
+	basic) echo "oidcc-basic-certification-test-plan[server_metadata=discovery][client_registration=static_client]" ;;
+	implicit) echo "oidcc-implicit-certification-test-plan[server_metadata=discovery][client_registration=static_client]" ;;
+	hybrid) echo "oidcc-hybrid-certification-test-plan[server_metadata=discovery][client_registration=static_client]" ;;
+	form_post) echo "oidcc-formpost-basic-certification-test-plan[server_metadata=discovery][client_registration=static_client]" ;;
+	rp_initiated_logout) echo "oidcc-rp-initiated-logout-certification-test-plan[response_type=code][client_registration=static_client]" ;;
+	backchannel_logout) echo "oidcc-backchannel-rp-initiated-logout-certification-test-plan[response_type=code][client_registration=static_client]" ;;
+	*) echo "unknown profile $1" >&2; return 1 ;;
+	esac
+}
+
+for profile in "$@"; do
+	plan "$profile" >/dev/null
+	set -- "$@" "$(plan "$profile")" "$config"
+	shift
+done
+
+exec python3 "$suite/scripts/run-test-plan.py" "$@"
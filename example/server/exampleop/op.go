@@ -10,7 +10,6 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
-	"golang.org/x/text/language"
 
 	"github.com/zitadel/oidc/v3/pkg/op"
 )
@@ -31,6 +30,17 @@ var requestCounter atomic.Uint64
 //
 // Use one of the pre-made clients in storage/clients.go or register a new one.
 func SetupServer(issuer string, storage Storage, logger *slog.Logger, wrapServer bool, extraOptions ...op.Option) chi.Router {
+	return setupServer(issuer, storage, logger, wrapServer, nil, extraOptions...)
+}
+
+// SetupConformanceServer creates an OIDC server like [SetupServer],
+// which is configured for the test plans of the OpenID Foundation conformance suite
+// of the profiles (see example/server/conformance).
+func SetupConformanceServer(issuer string, storage Storage, logger *slog.Logger, profiles []op.ConformanceProfile, extraOptions ...op.Option) chi.Router {
+	return setupServer(issuer, storage, logger, false, profiles, extraOptions...)
+}
+
+func setupServer(issuer string, storage Storage, logger *slog.Logger, wrapServer bool, profiles []op.ConformanceProfile, extraOptions ...op.Option) chi.Router {
 	// the OpenID Provider requires a 32-byte key for (token) encryption
 	// be sure to create a proper crypto random key and manage it securely!
 	key := sha256.Sum256([]byte("test"))
@@ -42,7 +52,7 @@ func SetupServer(issuer string, storage Storage, logger *slog.Logger, wrapServer
 
 	// for simplicity, we provide a very small default page for users who have signed out
 	router.HandleFunc(pathLoggedOut, func(w http.ResponseWriter, req *http.Request) {
-		w.Write([]byte("signed out successfully"))
+		renderMessage(w, req, "logout.title", "logout.done")
 		// The example middleware logs the completed request.
 	})
 
@@ -52,6 +62,7 @@ func SetupServer(issuer string, storage Storage, logger *slog.Logger, wrapServer
 		issuer,
 		key,
 		keyId,
+		profiles,
 		extraOptions...,
 	)
 	if err != nil {
@@ -59,21 +70,32 @@ func SetupServer(issuer string, storage Storage, logger *slog.Logger, wrapServer
 	}
 
 	//the provider will only take care of the OpenID Protocol, so there must be some sort of UI for the login process
-	//for the simplicity of the example this means a simple page with username and password field
+	//for the simplicity of the example this means a login flow with a single page with username and password field
 	//be sure to provide an IssuerInterceptor with the IssuerFromRequest from the OP so the login can select / and pass it to the storage
-	l := NewLogin(storage, op.AuthCallbackURL(provider), op.NewIssuerInterceptor(provider.IssuerFromRequest))
+	l, err := NewLogin(storage, op.AuthCallbackURL(provider), op.NewIssuerInterceptor(provider.IssuerFromRequest))
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	// regardless of how many pages / steps there are in the process, the UI must be registered in the router,
 	// so we will direct all calls to /login to the login UI
-	router.Mount("/login/", http.StripPrefix("/login", l.router))
+	router.Mount("/login/", l)
 
-	router.Route("/device", func(r chi.Router) {
-		registerDeviceAuth(storage, r)
-	})
+	// the verification pages of the device authorization flow, the user code
+	// of the short verification_uri_complete is resolved below the path
+	d, err := newDeviceHandler(storage)
+	if err != nil {
+		log.Fatal(err)
+	}
+	router.Handle(pathDevice, d)
+	router.Handle(pathDevice+"/*", d)
 
 	handler := http.Handler(provider)
 	if wrapServer {
-		handler = op.RegisterLegacyServer(op.NewLegacyServer(provider, *op.DefaultEndpoints), op.AuthorizeCallbackHandler(provider))
+		// the legacy server must serve the customized authorization endpoint of the provider
+		endpoints := *op.DefaultEndpoints
+		endpoints.Authorization = provider.AuthorizationEndpoint()
+		handler = op.RegisterLegacyServer(op.NewLegacyServer(provider, endpoints), op.AuthorizeCallbackHandler(provider))
 	}
 
 	// we register the http handler of the OP on the root, so that the discovery endpoint (/.well-known/openid-configuration)
@@ -113,6 +135,7 @@ func newOP(
 	issuer string,
 	key [32]byte, // encryption key
 	keyId string,
+	profiles []op.ConformanceProfile,
 	extraOptions ...op.Option,
 ) (op.OpenIDProvider, error) {
 	config := &op.Config{
@@ -137,22 +160,43 @@ func newOP(
 		// enables use of the `request` Object parameter
 		RequestObjectSupported: true,
 
-		// this example has only static texts (in English), so we'll set the here accordingly
-		SupportedUILocales: []language.Tag{language.English},
+		// the languages of the texts of the login, device and error pages
+		SupportedUILocales: catalog.Tags(),
+
+		// adds the session of the login (sid) to the id tokens, the storage implements op.SessionRequest
+		SessionIDClaim: true,
 
 		DeviceAuthorization: op.DeviceAuthorizationConfig{
 			Lifetime:     5 * time.Minute,
 			PollInterval: 5 * time.Second,
-			UserFormPath: "/device",
+			UserFormPath: pathDevice,
 			UserCode:     op.UserCodeBase20,
+			// QR code friendly verification_uri_complete, e.g. http://localhost:9998/device/BCDFGHJK
+			ShortVerificationURIComplete: true,
 		},
 	}
+	if len(profiles) > 0 {
+		// announces exactly what the conformance suite tests for the profiles,
+		// e.g. the hybrid response types or back-channel logout
+		conformance, err := op.ConformanceConfig(key, profiles...)
+		if err != nil {
+			return nil, err
+		}
+		config.SupportedResponseTypes = conformance.SupportedResponseTypes
+		config.SupportedResponseModes = conformance.SupportedResponseModes
+		config.BackChannelLogoutSupported = conformance.BackChannelLogoutSupported
+		config.BackChannelLogoutSessionSupported = conformance.BackChannelLogoutSessionSupported
+	}
 	handler, err := op.NewOpenIDProvider(issuer, config, storage,
 		append([]op.Option{
 			//we must explicitly allow the use of the http issuer
 			op.WithAllowInsecure(),
 			// as an example on how to customize an endpoint this will change the authorization_endpoint from /authorize to /auth
 			op.WithCustomAuthEndpoint(op.NewEndpoint("auth")),
+			// renders errors which cannot be returned to the client in the language of the user
+			op.WithLocalizedErrorPages(catalog),
+			// remembers the session of the login in a cookie for silent authentication (prompt=none)
+			op.WithSessionCookie("oidc_session"),
 		}, extraOptions...)...,
 	)
 	if err != nil {
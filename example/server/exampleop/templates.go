@@ -4,17 +4,29 @@ import (
 	"embed"
 	"html/template"
 	"log/slog"
+	"net/http"
+
+	"github.com/zitadel/oidc/v3/pkg/op/i18n"
 )
 
 var (
+	// catalog holds the texts of the pages in all supported languages,
+	// which are also announced as ui_locales_supported.
+	catalog = i18n.NewDefaultCatalog()
+
 	//go:embed templates
 	templateFS embed.FS
-	templates  = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+	templates  = template.Must(template.New("").Funcs(catalog.Localizer().FuncMap()).ParseFS(templateFS, "templates/*.html"))
 )
 
-const (
-	queryAuthRequestID = "authRequestID"
-)
+// executeTemplate renders the template in the language of the Accept-Language header.
+func executeTemplate(w http.ResponseWriter, r *http.Request, name string, data any) error {
+	tmpl, err := templates.Clone()
+	if err != nil {
+		return err
+	}
+	return tmpl.Funcs(catalog.FromRequest(r, nil).FuncMap()).ExecuteTemplate(w, name, data)
+}
 
 func errMsg(err error) string {
 	if err == nil {
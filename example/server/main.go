@@ -6,9 +6,13 @@ import (
 	"net/http"
 	"os"
 
+	"github.com/go-chi/chi/v5"
+
+	"github.com/zitadel/oidc/v3/example/server/cache"
 	"github.com/zitadel/oidc/v3/example/server/config"
 	"github.com/zitadel/oidc/v3/example/server/exampleop"
 	"github.com/zitadel/oidc/v3/example/server/storage"
+	"github.com/zitadel/oidc/v3/pkg/op"
 )
 
 func getUserStore(cfg *config.Config) (storage.UserStore, error) {
@@ -19,7 +23,10 @@ func getUserStore(cfg *config.Config) (storage.UserStore, error) {
 }
 
 func main() {
-	cfg := config.FromEnvVars(&config.Config{Port: "9998"})
+	cfg := config.FromEnvVars(&config.Config{
+		Port:           "9998",
+		ConformanceURL: "https://localhost.emobix.co.uk:8443/test/a/zitadel-oidc",
+	})
 	logger := slog.New(
 		slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
 			AddSource: true,
@@ -35,6 +42,12 @@ func main() {
 		storage.WebClient("web", "secret", cfg.RedirectURI...),
 		storage.WebClient("api", "secret", cfg.RedirectURI...),
 	)
+	if len(cfg.Conformance) > 0 {
+		storage.RegisterClients(
+			storage.ConformanceClient("conformance", "secret", cfg.ConformanceURL),
+			storage.ConformanceClient("conformance2", "secret", cfg.ConformanceURL),
+		)
+	}
 
 	// the OpenIDProvider interface needs a Storage interface handling various checks and state manipulations
 	// this might be the layer for accessing your database
@@ -46,13 +59,30 @@ func main() {
 	}
 
 	stor := storage.NewStorage(store)
-	router := exampleop.SetupServer(
-		issuer,
-		stor,
-		logger,
-		false,
-		//op.WithCrypto(newMyCrypto(sha256.Sum256([]byte("test")), logger)),
-	)
+	var opts []op.Option
+	//opts = append(opts, op.WithCrypto(newMyCrypto(sha256.Sum256([]byte("test")), logger)))
+	if cfg.RedisAddr != "" {
+		// share the clients, their keys and the discovery document between the instances
+		redis := cache.NewRedis(cfg.RedisAddr, 8, storage.ClientCodec{})
+		defer redis.Close()
+		opts = append(opts, op.WithCache(redis, op.DefaultCacheConfig))
+	}
+	var router chi.Router
+	if len(cfg.Conformance) > 0 {
+		profiles := make([]op.ConformanceProfile, len(cfg.Conformance))
+		for i, profile := range cfg.Conformance {
+			profiles[i] = op.ConformanceProfile(profile)
+		}
+		router = exampleop.SetupConformanceServer(issuer, stor, logger, profiles, opts...)
+	} else {
+		router = exampleop.SetupServer(
+			issuer,
+			stor,
+			logger,
+			false,
+			opts...,
+		)
+	}
 
 	server := &http.Server{
 		Addr:    ":" + cfg.Port,
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/text/language"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/login"
+)
+
+// authRequest is stored as json in the request column of the auth_requests table.
+type authRequest struct {
+	ID            string              `json:"id"`
+	CreationDate  time.Time           `json:"creation_date"`
+	ClientID      string              `json:"client_id"`
+	RedirectURI   string              `json:"redirect_uri"`
+	State         string              `json:"state,omitempty"`
+	Prompt        []string            `json:"prompt,omitempty"`
+	UILocales     []language.Tag      `json:"ui_locales,omitempty"`
+	LoginHint     string              `json:"login_hint,omitempty"`
+	MaxAge        *uint               `json:"max_age,omitempty"`
+	Scopes        []string            `json:"scopes"`
+	ResponseType  oidc.ResponseType   `json:"response_type"`
+	ResponseMode  oidc.ResponseMode   `json:"response_mode,omitempty"`
+	Nonce         string              `json:"nonce,omitempty"`
+	CodeChallenge *oidc.CodeChallenge `json:"code_challenge,omitempty"`
+
+	// set by the login
+	UserID   string    `json:"user_id,omitempty"`
+	IsDone   bool      `json:"done,omitempty"`
+	AuthTime time.Time `json:"auth_time,omitzero"`
+	AMR      []string  `json:"amr,omitempty"`
+	ACR      string    `json:"acr,omitempty"`
+}
+
+func (a *authRequest) GetID() string                         { return a.ID }
+func (a *authRequest) GetACR() string                        { return a.ACR }
+func (a *authRequest) GetAMR() []string                      { return a.AMR }
+func (a *authRequest) GetAudience() []string                 { return []string{a.ClientID} }
+func (a *authRequest) GetAuthTime() time.Time                { return a.AuthTime }
+func (a *authRequest) GetClientID() string                   { return a.ClientID }
+func (a *authRequest) GetCodeChallenge() *oidc.CodeChallenge { return a.CodeChallenge }
+func (a *authRequest) GetNonce() string                      { return a.Nonce }
+func (a *authRequest) GetRedirectURI() string                { return a.RedirectURI }
+func (a *authRequest) GetResponseType() oidc.ResponseType    { return a.ResponseType }
+func (a *authRequest) GetResponseMode() oidc.ResponseMode    { return a.ResponseMode }
+func (a *authRequest) GetScopes() []string                   { return a.Scopes }
+func (a *authRequest) GetState() string                      { return a.State }
+func (a *authRequest) GetSubject() string                    { return a.UserID }
+func (a *authRequest) Done() bool                            { return a.IsDone }
+
+// CreateAuthRequest implements the op.Storage interface
+// it will be called after parsing and validation of the authentication request
+func (s *Storage) CreateAuthRequest(ctx context.Context, authReq *oidc.AuthRequest, userID string) (op.AuthRequest, error) {
+	if len(authReq.Prompt) == 1 && authReq.Prompt[0] == oidc.PromptNone {
+		// this example doesn't keep sessions, so the user can't be logged in silently
+		return nil, oidc.ErrLoginRequired()
+	}
+	request := &authRequest{
+		ID:           uuid.NewString(),
+		CreationDate: time.Now(),
+		ClientID:     authReq.ClientID,
+		RedirectURI:  authReq.RedirectURI,
+		State:        authReq.State,
+		Prompt:       authReq.Prompt,
+		UILocales:    authReq.UILocales,
+		LoginHint:    authReq.LoginHint,
+		MaxAge:       authReq.MaxAge,
+		Scopes:       authReq.Scopes,
+		ResponseType: authReq.ResponseType,
+		ResponseMode: authReq.ResponseMode,
+		Nonce:        authReq.Nonce,
+		UserID:       userID,
+	}
+	if authReq.CodeChallenge != "" {
+		request.CodeChallenge = &oidc.CodeChallenge{
+			Challenge: authReq.CodeChallenge,
+			Method:    authReq.CodeChallengeMethod,
+		}
+	}
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		// abandoned logins are purged, so the table doesn't grow forever
+		if _, err := tx.ExecContext(ctx, `DELETE FROM auth_requests WHERE created_at < $1`, time.Now().Add(-authRequestLifetime)); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO auth_requests (id, request) VALUES ($1, $2)`, request.ID, asJSON(request))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (s *Storage) authRequest(ctx context.Context, query string, arg string) (*authRequest, error) {
+	request := new(authRequest)
+	err := s.db.QueryRowContext(ctx, query, arg).Scan(asJSON(request))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("request not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// AuthRequestByID implements the op.Storage interface
+// it will be called after the Login UI redirects back to the OIDC endpoint
+func (s *Storage) AuthRequestByID(ctx context.Context, id string) (op.AuthRequest, error) {
+	return s.authRequest(ctx, `SELECT request FROM auth_requests WHERE id = $1`, id)
+}
+
+// AuthRequestByCode implements the op.Storage interface
+// it will be called after parsing and validation of the token request (in an authorization code flow)
+func (s *Storage) AuthRequestByCode(ctx context.Context, code string) (op.AuthRequest, error) {
+	request, err := s.authRequest(ctx, `SELECT request FROM auth_requests WHERE code = $1`, code)
+	if err != nil {
+		return nil, fmt.Errorf("code invalid or expired")
+	}
+	return request, nil
+}
+
+// SaveAuthCode implements the op.Storage interface
+// it will be called after the authentication has been successful and before redirecting the user agent to the redirect_uri
+func (s *Storage) SaveAuthCode(ctx context.Context, id string, code string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE auth_requests SET code = $2 WHERE id = $1`, id, code)
+	return err
+}
+
+// DeleteAuthRequest implements the op.Storage interface
+// it will be called after creating the token response, which makes the code single use
+func (s *Storage) DeleteAuthRequest(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM auth_requests WHERE id = $1`, id)
+	return err
+}
+
+// CheckPassword is used by the password authenticator of the login flow
+// and returns the id of the user
+func (s *Storage) CheckPassword(ctx context.Context, username, password string) (string, error) {
+	// the users of the in-memory example have plain text passwords,
+	// be sure to have them hashed and salted (e.g. using bcrypt) in real world scenarios
+	user := s.users.GetUserByUsername(username)
+	if user != nil && user.Password == password {
+		return user.ID, nil
+	}
+	return "", fmt.Errorf("username or password wrong")
+}
+
+// LoginState implements the login.Storage interface
+func (s *Storage) LoginState(ctx context.Context, authRequestID string) (*login.State, error) {
+	request := new(authRequest)
+	var state *login.State
+	err := s.db.QueryRowContext(ctx, `SELECT request, login_state FROM auth_requests WHERE id = $1`, authRequestID).
+		Scan(asJSON(request), asJSON(&state))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("request not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if state != nil {
+		return state, nil
+	}
+	// the login is rendered in the language requested by the client
+	return &login.State{AuthRequestID: authRequestID, UILocales: request.UILocales, Scopes: request.Scopes}, nil
+}
+
+// SaveLoginState implements the login.Storage interface
+func (s *Storage) SaveLoginState(ctx context.Context, state *login.State) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE auth_requests SET login_state = $2 WHERE id = $1`, state.AuthRequestID, asJSON(state))
+	return err
+}
+
+// CompleteLogin implements the login.Storage interface
+// it sets the result of the login flow on the auth request and marks it as done
+func (s *Storage) CompleteLogin(ctx context.Context, state *login.State) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		request := new(authRequest)
+		err := tx.QueryRowContext(ctx, `SELECT request FROM auth_requests WHERE id = $1 FOR UPDATE`, state.AuthRequestID).Scan(asJSON(request))
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("request not found")
+		}
+		if err != nil {
+			return err
+		}
+		request.UserID = state.UserID
+		request.AuthTime = state.AuthTime
+		request.AMR = state.AMR
+		request.ACR = state.ACR
+		request.IsDone = true
+		_, err = tx.ExecContext(ctx, `UPDATE auth_requests SET request = $2, login_state = NULL WHERE id = $1`, request.ID, asJSON(request))
+		return err
+	})
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+)
+
+// Client is the database model of an OAuth/OIDC client.
+type Client struct {
+	ID                     string
+	RedirectURIs           []string
+	PostLogoutRedirectURIs []string
+	ApplicationType        op.ApplicationType
+	AuthMethod             oidc.AuthMethod
+	ResponseTypes          []oidc.ResponseType
+	GrantTypes             []oidc.GrantType
+	AccessTokenType        op.AccessTokenType
+	DevMode                bool
+}
+
+// opClient implements the op.Client interface for the Client.
+type opClient struct {
+	*Client
+}
+
+func (c opClient) GetID() string                        { return c.ID }
+func (c opClient) RedirectURIs() []string               { return c.Client.RedirectURIs }
+func (c opClient) PostLogoutRedirectURIs() []string     { return c.Client.PostLogoutRedirectURIs }
+func (c opClient) ApplicationType() op.ApplicationType  { return c.Client.ApplicationType }
+func (c opClient) AuthMethod() oidc.AuthMethod          { return c.Client.AuthMethod }
+func (c opClient) ResponseTypes() []oidc.ResponseType   { return c.Client.ResponseTypes }
+func (c opClient) GrantTypes() []oidc.GrantType         { return c.Client.GrantTypes }
+func (c opClient) LoginURL(id string) string            { return "/login/username?authRequestID=" + id }
+func (c opClient) AccessTokenType() op.AccessTokenType  { return c.Client.AccessTokenType }
+func (c opClient) IDTokenLifetime() time.Duration       { return time.Hour }
+func (c opClient) DevMode() bool                        { return c.Client.DevMode }
+func (c opClient) IDTokenUserinfoClaimsAssertion() bool { return false }
+func (c opClient) ClockSkew() time.Duration             { return 0 }
+func (c opClient) IsScopeAllowed(scope string) bool     { return false }
+func (c opClient) RestrictAdditionalIdTokenScopes() func([]string) []string {
+	return func(scopes []string) []string { return scopes }
+}
+func (c opClient) RestrictAdditionalAccessTokenScopes() func([]string) []string {
+	return func(scopes []string) []string { return scopes }
+}
+
+// RegisterClient creates or updates the client.
+// Only the bcrypt hash of the secret is stored, an empty secret registers a public client.
+func (s *Storage) RegisterClient(ctx context.Context, client *Client, secret string) error {
+	var secretHash string
+	if secret != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		secretHash = string(hash)
+	}
+	_, err := s.db.ExecContext(ctx, `INSERT INTO clients
+		(id, secret_hash, redirect_uris, post_logout_redirect_uris, application_type, auth_method, response_types, grant_types, access_token_type, dev_mode)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (id) DO UPDATE SET
+			secret_hash = EXCLUDED.secret_hash,
+			redirect_uris = EXCLUDED.redirect_uris,
+			post_logout_redirect_uris = EXCLUDED.post_logout_redirect_uris,
+			application_type = EXCLUDED.application_type,
+			auth_method = EXCLUDED.auth_method,
+			response_types = EXCLUDED.response_types,
+			grant_types = EXCLUDED.grant_types,
+			access_token_type = EXCLUDED.access_token_type,
+			dev_mode = EXCLUDED.dev_mode`,
+		client.ID, secretHash, asJSON(client.RedirectURIs), asJSON(client.PostLogoutRedirectURIs), client.ApplicationType,
+		client.AuthMethod, asJSON(client.ResponseTypes), asJSON(client.GrantTypes), client.AccessTokenType, client.DevMode,
+	)
+	return err
+}
+
+func (s *Storage) clientByID(ctx context.Context, clientID string) (*Client, string, error) {
+	client := &Client{ID: clientID}
+	var secretHash string
+	err := s.db.QueryRowContext(ctx, `SELECT secret_hash, redirect_uris, post_logout_redirect_uris, application_type, auth_method, response_types, grant_types, access_token_type, dev_mode
+		FROM clients WHERE id = $1`, clientID).Scan(
+		&secretHash, asJSON(&client.RedirectURIs), asJSON(&client.PostLogoutRedirectURIs), &client.ApplicationType,
+		&client.AuthMethod, asJSON(&client.ResponseTypes), asJSON(&client.GrantTypes), &client.AccessTokenType, &client.DevMode,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", fmt.Errorf("client not found")
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return client, secretHash, nil
+}
+
+// GetClientByClientID implements the op.Storage interface
+// it will be called whenever information (type, redirect_uris, ...) about the client behind the client_id is needed
+func (s *Storage) GetClientByClientID(ctx context.Context, clientID string) (op.Client, error) {
+	client, _, err := s.clientByID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	return opClient{client}, nil
+}
+
+// AuthorizeClientIDSecret implements the op.Storage interface
+// it will be called for validating the client_id, client_secret on token or introspection requests
+func (s *Storage) AuthorizeClientIDSecret(ctx context.Context, clientID, clientSecret string) error {
+	_, secretHash, err := s.clientByID(ctx, clientID)
+	if err != nil {
+		return err
+	}
+	if secretHash == "" || bcrypt.CompareHashAndPassword([]byte(secretHash), []byte(clientSecret)) != nil {
+		return fmt.Errorf("invalid secret")
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/zitadel/oidc/v3/pkg/op"
+)
+
+// StoreDeviceAuthorization implements the op.DeviceAuthorizationStorage interface
+// the unique constraint on the user_code column detects collisions of the short user codes
+func (s *Storage) StoreDeviceAuthorization(ctx context.Context, clientID, deviceCode, userCode string, expires time.Time, scopes []string) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		// expired device codes are purged, which keeps the chance of user code collisions low
+		if _, err := tx.ExecContext(ctx, `DELETE FROM device_authorizations WHERE expires < now()`); err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, `INSERT INTO device_authorizations (device_code, user_code, client_id, scopes, expires)
+			VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING`,
+			deviceCode, userCode, clientID, asJSON(scopes), expires)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return op.ErrDuplicateUserCode
+		}
+		return nil
+	})
+}
+
+func (s *Storage) deviceAuthorization(ctx context.Context, query string, args ...any) (*op.DeviceAuthorizationState, error) {
+	state := new(op.DeviceAuthorizationState)
+	var authTime sql.NullTime
+	err := s.db.QueryRowContext(ctx, `SELECT client_id, scopes, expires, done, denied, subject, auth_time
+		FROM device_authorizations WHERE `+query, args...).Scan(
+		&state.ClientID, asJSON(&state.Scopes), &state.Expires, &state.Done, &state.Denied, &state.Subject, &authTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	state.AuthTime = authTime.Time
+	return state, nil
+}
+
+// GetDeviceAuthorizatonState implements the op.DeviceAuthorizationStorage interface
+// it is polled by the device until the user completed or denied the authorization
+func (s *Storage) GetDeviceAuthorizatonState(ctx context.Context, clientID, deviceCode string) (*op.DeviceAuthorizationState, error) {
+	state, err := s.deviceAuthorization(ctx, `device_code = $1 AND client_id = $2`, deviceCode, clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.New("device code not found for client")
+	}
+	return state, err
+}
+
+// GetDeviceAuthorizationByUserCode implements the device.Storage interface
+func (s *Storage) GetDeviceAuthorizationByUserCode(ctx context.Context, userCode string) (*op.DeviceAuthorizationState, error) {
+	state, err := s.deviceAuthorization(ctx, `user_code = $1`, userCode)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.New("user code not found")
+	}
+	return state, err
+}
+
+// CompleteDeviceAuthorization implements the device.Storage interface
+func (s *Storage) CompleteDeviceAuthorization(ctx context.Context, userCode, subject string) error {
+	return s.updateDeviceAuthorization(ctx, `UPDATE device_authorizations SET done = TRUE, subject = $2, auth_time = now()
+		WHERE user_code = $1 AND NOT denied`, userCode, subject)
+}
+
+// DenyDeviceAuthorization implements the device.Storage interface
+func (s *Storage) DenyDeviceAuthorization(ctx context.Context, userCode string) error {
+	return s.updateDeviceAuthorization(ctx, `UPDATE device_authorizations SET denied = TRUE
+		WHERE user_code = $1 AND NOT done`, userCode)
+}
+
+func (s *Storage) updateDeviceAuthorization(ctx context.Context, query string, args ...any) error {
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errors.New("user code not found")
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"database/sql"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/google/uuid"
+
+	"github.com/zitadel/oidc/v3/pkg/op"
+)
+
+// keyGracePeriod is the time a public key is still published after its private key was rotated.
+// It must cover the lifetime of the tokens signed with it, which is the id_token lifetime of the clients.
+const keyGracePeriod = time.Hour
+
+// signingKeyLock is the key of the advisory lock, which ensures
+// only one instance of the OP creates a new signing key.
+const signingKeyLock = 4711
+
+type signingKey struct {
+	id        string
+	algorithm jose.SignatureAlgorithm
+	key       *rsa.PrivateKey
+	createdAt time.Time
+}
+
+func (s *signingKey) SignatureAlgorithm() jose.SignatureAlgorithm {
+	return s.algorithm
+}
+
+func (s *signingKey) Key() any {
+	return s.key
+}
+
+func (s *signingKey) ID() string {
+	return s.id
+}
+
+type publicKey struct {
+	*signingKey
+}
+
+func (s publicKey) Algorithm() jose.SignatureAlgorithm {
+	return s.algorithm
+}
+
+func (s publicKey) Use() string {
+	return "sig"
+}
+
+func (s publicKey) Key() any {
+	return &s.key.PublicKey
+}
+
+// currentKey returns the newest key, if it isn't due for rotation.
+// The keys must be ordered by creation, newest first.
+func currentKey(keys []*signingKey, now time.Time, rotation time.Duration) *signingKey {
+	if len(keys) == 0 || now.Sub(keys[0].createdAt) >= rotation {
+		return nil
+	}
+	return keys[0]
+}
+
+// publishedKeys returns the keys, which might have signed a still valid token.
+// Every key is used for signing until its successor is created,
+// so it's published until the grace period after that passed.
+func publishedKeys(keys []*signingKey, now time.Time) []*signingKey {
+	published := make([]*signingKey, 0, len(keys))
+	for i, key := range keys {
+		if i > 0 && now.Sub(keys[i-1].createdAt) >= keyGracePeriod {
+			break
+		}
+		published = append(published, key)
+	}
+	return published
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func loadSigningKeys(ctx context.Context, db queryer) ([]*signingKey, error) {
+	rows, err := db.QueryContext(ctx, `SELECT id, algorithm, private_key, created_at FROM signing_keys ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var keys []*signingKey
+	for rows.Next() {
+		var (
+			key  signingKey
+			der  []byte
+			algo string
+		)
+		if err := rows.Scan(&key.id, &algo, &der, &key.createdAt); err != nil {
+			return nil, err
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return nil, err
+		}
+		rsaKey, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			continue
+		}
+		key.algorithm = jose.SignatureAlgorithm(algo)
+		key.key = rsaKey
+		keys = append(keys, &key)
+	}
+	return keys, rows.Err()
+}
+
+// SigningKey implements the op.Storage interface
+// it will be called whenever a token is signed
+// and returns the current key, a new one is created (and persisted) when the current one is due for rotation
+func (s *Storage) SigningKey(ctx context.Context) (op.SigningKey, error) {
+	keys, err := loadSigningKeys(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	if key := currentKey(keys, time.Now(), s.keyRotation); key != nil {
+		return key, nil
+	}
+	var key *signingKey
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, signingKeyLock); err != nil {
+			return err
+		}
+		// another instance might have created the key while waiting for the lock
+		keys, err := loadSigningKeys(ctx, tx)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if key = currentKey(keys, now, s.keyRotation); key != nil {
+			return nil
+		}
+		key, err = generateSigningKey(now)
+		if err != nil {
+			return err
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(key.key)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO signing_keys (id, algorithm, private_key, created_at) VALUES ($1, $2, $3, $4)`,
+			key.id, string(key.algorithm), der, key.createdAt); err != nil {
+			return err
+		}
+		// keys which are no longer published are removed
+		published := publishedKeys(append([]*signingKey{key}, keys...), now)
+		_, err = tx.ExecContext(ctx, `DELETE FROM signing_keys WHERE created_at < $1`, published[len(published)-1].createdAt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func generateSigningKey(now time.Time) (*signingKey, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return &signingKey{
+		id:        uuid.NewString(),
+		algorithm: jose.RS256,
+		key:       key,
+		createdAt: now,
+	}, nil
+}
+
+// SignatureAlgorithms implements the op.Storage interface
+// it will be called to get the sign algorithm (for the discovery endpoint)
+func (s *Storage) SignatureAlgorithms(context.Context) ([]jose.SignatureAlgorithm, error) {
+	return []jose.SignatureAlgorithm{jose.RS256}, nil
+}
+
+// KeySet implements the op.Storage interface
+// it will be called to get the current (public) keys, among others for the keys_endpoint or for validating access_tokens on the userinfo_endpoint, ...
+// besides the current key, the rotated keys are published until the tokens signed with them expired
+func (s *Storage) KeySet(ctx context.Context) ([]op.Key, error) {
+	keys, err := loadSigningKeys(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	published := publishedKeys(keys, time.Now())
+	set := make([]op.Key, len(published))
+	for i, key := range published {
+		set[i] = publicKey{key}
+	}
+	return set, nil
+}
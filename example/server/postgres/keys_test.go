@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurrentKey(t *testing.T) {
+	now := time.Now()
+	key := &signingKey{id: "new", createdAt: now.Add(-time.Hour)}
+	tests := []struct {
+		name     string
+		keys     []*signingKey
+		rotation time.Duration
+		want     *signingKey
+	}{
+		{"no keys", nil, 24 * time.Hour, nil},
+		{"current", []*signingKey{key}, 24 * time.Hour, key},
+		{"due for rotation", []*signingKey{key}, time.Hour, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, currentKey(tt.keys, now, tt.rotation))
+		})
+	}
+}
+
+func TestPublishedKeys(t *testing.T) {
+	now := time.Now()
+	current := &signingKey{id: "current", createdAt: now.Add(-time.Minute)}
+	rotated := &signingKey{id: "rotated", createdAt: now.Add(-24 * time.Hour)}
+	expired := &signingKey{id: "expired", createdAt: now.Add(-48 * time.Hour)}
+
+	// rotated signed tokens until a minute ago, expired until a day ago
+	assert.Equal(t, []*signingKey{current, rotated}, publishedKeys([]*signingKey{current, rotated, expired}, now))
+	// after the grace period, tokens signed by rotated are expired as well
+	assert.Equal(t, []*signingKey{current}, publishedKeys([]*signingKey{current, rotated}, now.Add(keyGracePeriod+time.Minute)))
+	assert.Empty(t, publishedKeys(nil, now))
+}
@@ -0,0 +1,75 @@
+-- schema of the PostgreSQL example, applied on startup.
+-- lists (scopes, audience, ...) are stored as jsonb, so any database/sql driver can scan them.
+
+CREATE TABLE IF NOT EXISTS clients (
+    id                        TEXT PRIMARY KEY,
+    -- bcrypt hash of the client secret, empty for public clients
+    secret_hash               TEXT NOT NULL DEFAULT '',
+    redirect_uris             JSONB NOT NULL DEFAULT '[]',
+    post_logout_redirect_uris JSONB NOT NULL DEFAULT '[]',
+    application_type          TEXT NOT NULL,
+    auth_method               TEXT NOT NULL,
+    response_types            JSONB NOT NULL DEFAULT '[]',
+    grant_types               JSONB NOT NULL DEFAULT '[]',
+    access_token_type         TEXT NOT NULL,
+    dev_mode                  BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS auth_requests (
+    id          TEXT PRIMARY KEY,
+    code        TEXT UNIQUE,
+    request     JSONB NOT NULL,
+    login_state JSONB,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id              TEXT PRIMARY KEY,
+    -- all refresh tokens rotated from the same grant share the family,
+    -- which is revoked when a rotated token is used again
+    family_id       TEXT NOT NULL,
+    client_id       TEXT NOT NULL,
+    user_id         TEXT NOT NULL,
+    audience        JSONB NOT NULL DEFAULT '[]',
+    scopes          JSONB NOT NULL DEFAULT '[]',
+    amr             JSONB NOT NULL DEFAULT '[]',
+    auth_time       TIMESTAMPTZ NOT NULL,
+    expiration      TIMESTAMPTZ NOT NULL,
+    access_token_id TEXT NOT NULL,
+    -- set when the token was rotated
+    used_at         TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_idx ON refresh_tokens (family_id);
+
+CREATE TABLE IF NOT EXISTS access_tokens (
+    id               TEXT PRIMARY KEY,
+    client_id        TEXT NOT NULL,
+    subject          TEXT NOT NULL,
+    refresh_token_id TEXT NOT NULL DEFAULT '',
+    audience         JSONB NOT NULL DEFAULT '[]',
+    scopes           JSONB NOT NULL DEFAULT '[]',
+    expiration       TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS access_tokens_subject_idx ON access_tokens (subject, client_id);
+
+CREATE TABLE IF NOT EXISTS device_authorizations (
+    device_code TEXT PRIMARY KEY,
+    user_code   TEXT NOT NULL UNIQUE,
+    client_id   TEXT NOT NULL,
+    scopes      JSONB NOT NULL DEFAULT '[]',
+    expires     TIMESTAMPTZ NOT NULL,
+    done        BOOLEAN NOT NULL DEFAULT FALSE,
+    denied      BOOLEAN NOT NULL DEFAULT FALSE,
+    subject     TEXT NOT NULL DEFAULT '',
+    auth_time   TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS signing_keys (
+    id          TEXT PRIMARY KEY,
+    algorithm   TEXT NOT NULL,
+    -- PKCS #8 DER encoded private key
+    private_key BYTEA NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
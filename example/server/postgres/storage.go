@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+
+	"github.com/zitadel/oidc/v3/example/server/storage"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+)
+
+//go:embed schema.sql
+var schema string
+
+var (
+	_ op.Storage                          = (*Storage)(nil)
+	_ op.DeviceAuthorizationStorage       = (*Storage)(nil)
+	_ op.CanRevokeTokenFamily             = (*Storage)(nil)
+	_ op.CanListBackChannelLogoutSessions = (*Storage)(nil)
+)
+
+const (
+	accessTokenLifetime  = 5 * time.Minute
+	refreshTokenLifetime = 5 * time.Hour
+	// authRequestLifetime is the time a user has to log in,
+	// older auth requests are purged
+	authRequestLifetime = 30 * time.Minute
+)
+
+// Storage implements the op.Storage interface on top of PostgreSQL.
+// Everything issued by the OP (auth requests, codes, tokens, device codes
+// and the signing keys) is persisted, so multiple instances of the OP can share the database
+// and restarts don't invalidate any token.
+// For simplicity, the users are still read from the storage.UserStore of the in-memory example.
+type Storage struct {
+	db          *sql.DB
+	users       storage.UserStore
+	keyRotation time.Duration
+}
+
+// NewStorage creates the Storage and applies the schema.
+// The signing key is rotated after keyRotation.
+func NewStorage(ctx context.Context, db *sql.DB, users storage.UserStore, keyRotation time.Duration) (*Storage, error) {
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return nil, fmt.Errorf("apply schema: %w", err)
+	}
+	return &Storage{
+		db:          db,
+		users:       users,
+		keyRotation: keyRotation,
+	}, nil
+}
+
+// Health implements the op.Storage interface
+// the ready endpoint of the OP reports the database as unavailable if the ping fails
+func (s *Storage) Health(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// inTx runs fn in a transaction, which is committed if fn succeeds.
+func (s *Storage) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	return tx.Commit()
+}
+
+// jsonb stores the value in a jsonb column and scans it back.
+type jsonb struct {
+	v any
+}
+
+func asJSON(v any) jsonb {
+	return jsonb{v}
+}
+
+func (j jsonb) Value() (driver.Value, error) {
+	data, err := json.Marshal(j.v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (j jsonb) Scan(src any) error {
+	switch data := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(data, j.v)
+	case string:
+		return json.Unmarshal([]byte(data), j.v)
+	default:
+		return fmt.Errorf("unsupported jsonb type %T", src)
+	}
+}
+
+// setUserinfo sets the info based on the user and scopes
+func (s *Storage) setUserinfo(userInfo *oidc.UserInfo, userID string, scopes []string) error {
+	user := s.users.GetUserByID(userID)
+	if user == nil {
+		return fmt.Errorf("user not found")
+	}
+	for _, scope := range scopes {
+		switch scope {
+		case oidc.ScopeOpenID:
+			userInfo.Subject = user.ID
+		case oidc.ScopeEmail:
+			userInfo.Email = user.Email
+			userInfo.EmailVerified = oidc.Bool(user.EmailVerified)
+		case oidc.ScopeProfile:
+			userInfo.PreferredUsername = user.Username
+			userInfo.Name = user.FirstName + " " + user.LastName
+			userInfo.FamilyName = user.LastName
+			userInfo.GivenName = user.FirstName
+			userInfo.Locale = oidc.NewLocale(user.PreferredLanguage)
+		case oidc.ScopePhone:
+			userInfo.PhoneNumber = user.Phone
+			userInfo.PhoneNumberVerified = oidc.Bool(user.PhoneVerified)
+		}
+	}
+	return nil
+}
+
+// SetUserinfoFromScopes implements the op.Storage interface.
+// Provide an empty implementation and use SetUserinfoFromRequest instead.
+func (s *Storage) SetUserinfoFromScopes(ctx context.Context, userinfo *oidc.UserInfo, userID, clientID string, scopes []string) error {
+	return nil
+}
+
+// SetUserinfoFromRequest implements the op.CanSetUserinfoFromRequest interface
+// it will be called for the creation of an id_token
+func (s *Storage) SetUserinfoFromRequest(ctx context.Context, userinfo *oidc.UserInfo, token op.IDTokenRequest, scopes []string) error {
+	return s.setUserinfo(userinfo, token.GetSubject(), scopes)
+}
+
+// SetUserinfoFromToken implements the op.Storage interface
+// it will be called for the userinfo endpoint, so we read the token and pass the information from that to the private function
+func (s *Storage) SetUserinfoFromToken(ctx context.Context, userinfo *oidc.UserInfo, tokenID, subject, origin string) error {
+	token, err := s.accessTokenByID(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+	return s.setUserinfo(userinfo, token.Subject, token.Scopes)
+}
+
+// SetIntrospectionFromToken implements the op.Storage interface
+// it will be called for the introspection endpoint, so we read the token and pass the information from that to the private function
+func (s *Storage) SetIntrospectionFromToken(ctx context.Context, introspection *oidc.IntrospectionResponse, tokenID, subject, clientID string) error {
+	token, err := s.accessTokenByID(ctx, tokenID)
+	if err != nil {
+		return err
+	}
+	for _, aud := range token.Audience {
+		if aud == clientID {
+			userInfo := new(oidc.UserInfo)
+			if err := s.setUserinfo(userInfo, subject, token.Scopes); err != nil {
+				return err
+			}
+			introspection.SetUserInfo(userInfo)
+			introspection.Scope = token.Scopes
+			introspection.ClientID = token.ClientID
+			introspection.Expiration = oidc.FromTime(token.Expiration)
+			return nil
+		}
+	}
+	return fmt.Errorf("token is not valid for this client")
+}
+
+// GetPrivateClaimsFromScopes implements the op.Storage interface
+// this example doesn't assert any private claims
+func (s *Storage) GetPrivateClaimsFromScopes(ctx context.Context, userID, clientID string, scopes []string) (map[string]any, error) {
+	return nil, nil
+}
+
+// GetKeyByIDAndClientID implements the op.Storage interface
+// this example doesn't support the JWT Profile Grant and private_key_jwt
+func (s *Storage) GetKeyByIDAndClientID(ctx context.Context, keyID, clientID string) (*jose.JSONWebKey, error) {
+	return nil, fmt.Errorf("key not found")
+}
+
+// ValidateJWTProfileScopes implements the op.Storage interface
+func (s *Storage) ValidateJWTProfileScopes(ctx context.Context, userID string, scopes []string) ([]string, error) {
+	return nil, fmt.Errorf("jwt profile grant not supported")
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+)
+
+var errRefreshTokenReused = errors.New("refresh token was already used, all tokens of the grant are revoked")
+
+type accessToken struct {
+	ID             string
+	ClientID       string
+	Subject        string
+	RefreshTokenID string
+	Audience       []string
+	Scopes         []string
+	Expiration     time.Time
+}
+
+// refreshTokenRequest implements the op.RefreshTokenRequest interface.
+type refreshTokenRequest struct {
+	ID       string
+	FamilyID string
+	ClientID string
+	UserID   string
+	Audience []string
+	Scopes   []string
+	AMR      []string
+	AuthTime time.Time
+}
+
+func (r *refreshTokenRequest) GetAMR() []string                 { return r.AMR }
+func (r *refreshTokenRequest) GetAudience() []string            { return r.Audience }
+func (r *refreshTokenRequest) GetAuthTime() time.Time           { return r.AuthTime }
+func (r *refreshTokenRequest) GetClientID() string              { return r.ClientID }
+func (r *refreshTokenRequest) GetScopes() []string              { return r.Scopes }
+func (r *refreshTokenRequest) GetSubject() string               { return r.UserID }
+func (r *refreshTokenRequest) SetCurrentScopes(scopes []string) { r.Scopes = scopes }
+
+// newRefreshToken returns a random refresh token and its id.
+// Only the id, a hash of the token, is stored, so a leaked database
+// doesn't contain usable refresh tokens.
+func newRefreshToken() (token, id string) {
+	token = rand.Text()
+	return token, refreshTokenID(token)
+}
+
+func refreshTokenID(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func insertAccessToken(ctx context.Context, db execer, request op.TokenRequest, clientID, refreshTokenID string) (*accessToken, error) {
+	token := &accessToken{
+		ID:             uuid.NewString(),
+		ClientID:       clientID,
+		Subject:        request.GetSubject(),
+		RefreshTokenID: refreshTokenID,
+		Audience:       request.GetAudience(),
+		Scopes:         request.GetScopes(),
+		Expiration:     time.Now().Add(accessTokenLifetime),
+	}
+	_, err := db.ExecContext(ctx, `INSERT INTO access_tokens (id, client_id, subject, refresh_token_id, audience, scopes, expiration)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		token.ID, token.ClientID, token.Subject, token.RefreshTokenID, asJSON(token.Audience), asJSON(token.Scopes), token.Expiration)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func insertRefreshToken(ctx context.Context, db execer, request *refreshTokenRequest, accessTokenID string) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO refresh_tokens (id, family_id, client_id, user_id, audience, scopes, amr, auth_time, expiration, access_token_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		request.ID, request.FamilyID, request.ClientID, request.UserID, asJSON(request.Audience), asJSON(request.Scopes), asJSON(request.AMR),
+		request.AuthTime, time.Now().Add(refreshTokenLifetime), accessTokenID)
+	return err
+}
+
+// clientIDFromRequest returns the client the tokens are issued to
+func clientIDFromRequest(request op.TokenRequest) string {
+	if r, ok := request.(interface{ GetClientID() string }); ok {
+		return r.GetClientID()
+	}
+	return ""
+}
+
+// CreateAccessToken implements the op.Storage interface
+// it will be called for all requests able to return an access token (Authorization Code Flow, Implicit Flow, ...)
+func (s *Storage) CreateAccessToken(ctx context.Context, request op.TokenRequest) (string, time.Time, error) {
+	token, err := insertAccessToken(ctx, s.db, request, clientIDFromRequest(request), "")
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token.ID, token.Expiration, nil
+}
+
+// CreateAccessAndRefreshTokens implements the op.Storage interface
+// it will be called for all requests able to return an access and refresh token (Authorization Code Flow, Refresh Token Request)
+func (s *Storage) CreateAccessAndRefreshTokens(ctx context.Context, request op.TokenRequest, currentRefreshToken string) (accessTokenID string, newToken string, expiration time.Time, err error) {
+	if currentRefreshToken != "" {
+		return s.rotateRefreshToken(ctx, request, currentRefreshToken)
+	}
+	newToken, id := newRefreshToken()
+	refreshToken := &refreshTokenRequest{
+		ID:       id,
+		FamilyID: id,
+		ClientID: clientIDFromRequest(request),
+		UserID:   request.GetSubject(),
+		Audience: request.GetAudience(),
+		Scopes:   request.GetScopes(),
+	}
+	if r, ok := request.(interface {
+		GetAMR() []string
+		GetAuthTime() time.Time
+	}); ok {
+		refreshToken.AMR = r.GetAMR()
+		refreshToken.AuthTime = r.GetAuthTime()
+	}
+	var token *accessToken
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		// expired tokens are purged whenever a new grant is issued
+		if _, err := tx.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE expiration < now()`); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM access_tokens WHERE expiration < now()`); err != nil {
+			return err
+		}
+		token, err = insertAccessToken(ctx, tx, request, refreshToken.ClientID, refreshToken.ID)
+		if err != nil {
+			return err
+		}
+		return insertRefreshToken(ctx, tx, refreshToken, token.ID)
+	})
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	return token.ID, newToken, token.Expiration, nil
+}
+
+// rotateRefreshToken implements [Refresh Token Rotation]:
+// the current refresh token is marked as used, the access token issued with it is revoked
+// and a new refresh token of the same family is issued.
+// The used token is kept until it expires, to detect its reuse.
+//
+// [Refresh Token Rotation]: https://www.rfc-editor.org/rfc/rfc6819#section-5.2.2.3
+func (s *Storage) rotateRefreshToken(ctx context.Context, request op.TokenRequest, currentRefreshToken string) (string, string, time.Time, error) {
+	current, ok := request.(*refreshTokenRequest)
+	if !ok {
+		return "", "", time.Time{}, fmt.Errorf("unexpected refresh token request %T", request)
+	}
+	newToken, id := newRefreshToken()
+	var (
+		token  *accessToken
+		reused bool
+	)
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		// the update only succeeds for one of concurrent requests with the same token
+		var oldAccessTokenID string
+		err := tx.QueryRowContext(ctx, `UPDATE refresh_tokens SET used_at = now()
+			WHERE id = $1 AND used_at IS NULL AND expiration > now()
+			RETURNING access_token_id`, refreshTokenID(currentRefreshToken)).Scan(&oldAccessTokenID)
+		if errors.Is(err, sql.ErrNoRows) {
+			reused = true
+			return revokeFamily(ctx, tx, current.FamilyID)
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM access_tokens WHERE id = $1`, oldAccessTokenID); err != nil {
+			return err
+		}
+		token, err = insertAccessToken(ctx, tx, current, current.ClientID, id)
+		if err != nil {
+			return err
+		}
+		next := *current
+		next.ID = id
+		return insertRefreshToken(ctx, tx, &next, token.ID)
+	})
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	if reused {
+		return "", "", time.Time{}, errRefreshTokenReused
+	}
+	return token.ID, newToken, token.Expiration, nil
+}
+
+// revokeFamily deletes all refresh tokens of the grant and the access tokens issued with them.
+func revokeFamily(ctx context.Context, db execer, familyID string) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM access_tokens WHERE refresh_token_id IN
+		(SELECT id FROM refresh_tokens WHERE family_id = $1)`, familyID); err != nil {
+		return err
+	}
+	_, err := db.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE family_id = $1`, familyID)
+	return err
+}
+
+func (s *Storage) refreshToken(ctx context.Context, id string) (*refreshTokenRequest, bool, error) {
+	request := &refreshTokenRequest{ID: id}
+	var usedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, `SELECT family_id, client_id, user_id, audience, scopes, amr, auth_time, used_at
+		FROM refresh_tokens WHERE id = $1 AND expiration > now()`, id).Scan(
+		&request.FamilyID, &request.ClientID, &request.UserID, asJSON(&request.Audience), asJSON(&request.Scopes),
+		asJSON(&request.AMR), &request.AuthTime, &usedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, op.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return request, usedAt.Valid, nil
+}
+
+// TokenRequestByRefreshToken implements the op.Storage interface
+// it will be called after parsing and validation of the refresh token request.
+// The reuse of a rotated refresh token indicates it was stolen, so the whole grant is revoked.
+func (s *Storage) TokenRequestByRefreshToken(ctx context.Context, token string) (op.RefreshTokenRequest, error) {
+	request, used, err := s.refreshToken(ctx, refreshTokenID(token))
+	if err != nil {
+		return nil, err
+	}
+	if used {
+		if err := revokeFamily(ctx, s.db, request.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, errRefreshTokenReused
+	}
+	return request, nil
+}
+
+// GetRefreshTokenInfo implements the op.Storage interface
+// it returns the id (hash) of the refresh token, which is passed to RevokeToken(Family)
+func (s *Storage) GetRefreshTokenInfo(ctx context.Context, clientID string, token string) (userID string, tokenID string, err error) {
+	request, used, err := s.refreshToken(ctx, refreshTokenID(token))
+	if err != nil || used {
+		return "", "", op.ErrInvalidRefreshToken
+	}
+	return request.UserID, request.ID, nil
+}
+
+func (s *Storage) accessTokenByID(ctx context.Context, id string) (*accessToken, error) {
+	token := &accessToken{ID: id}
+	err := s.db.QueryRowContext(ctx, `SELECT client_id, subject, refresh_token_id, audience, scopes, expiration
+		FROM access_tokens WHERE id = $1 AND expiration > now()`, id).Scan(
+		&token.ClientID, &token.Subject, &token.RefreshTokenID, asJSON(&token.Audience), asJSON(&token.Scopes), &token.Expiration,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("token is invalid or has expired")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// TerminateSession implements the op.Storage interface
+// it will be called after the user signed out, therefore the access and refresh tokens of the user of this client are removed
+func (s *Storage) TerminateSession(ctx context.Context, userID string, clientID string) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM access_tokens WHERE subject = $1 AND client_id = $2`, userID, clientID); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE user_id = $1 AND client_id = $2`, userID, clientID)
+		return err
+	})
+}
+
+// BackChannelLogoutSessions implements the op.CanListBackChannelLogoutSessions interface
+// it returns every client the user holds tokens of
+func (s *Storage) BackChannelLogoutSessions(ctx context.Context, request *op.EndSessionRequest) ([]op.ClientSession, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT client_id FROM access_tokens WHERE subject = $1
+		UNION SELECT client_id FROM refresh_tokens WHERE user_id = $1 AND used_at IS NULL`, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var sessions []op.ClientSession
+	for rows.Next() {
+		var session op.ClientSession
+		if err := rows.Scan(&session.ClientID); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeToken implements the op.Storage interface
+// it is only called if RevokeTokenFamily isn't implemented, which it is
+func (s *Storage) RevokeToken(ctx context.Context, tokenIDOrToken string, userID string, clientID string) *oidc.Error {
+	if userID != "" {
+		return s.RevokeTokenFamily(ctx, &op.RevokeTokenFamilyRequest{TokenID: tokenIDOrToken, UserID: userID, ClientID: clientID, TokenType: oidc.AccessTokenType})
+	}
+	return s.RevokeTokenFamily(ctx, &op.RevokeTokenFamilyRequest{TokenID: refreshTokenID(tokenIDOrToken), ClientID: clientID, TokenType: oidc.RefreshTokenType})
+}
+
+// RevokeTokenFamily implements the op.CanRevokeTokenFamily interface
+// revoking a refresh token revokes all tokens of its grant
+func (s *Storage) RevokeTokenFamily(ctx context.Context, request *op.RevokeTokenFamilyRequest) *oidc.Error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		refreshTokenID := request.TokenID
+		if request.TokenType == oidc.AccessTokenType {
+			var clientID string
+			err := tx.QueryRowContext(ctx, `DELETE FROM access_tokens WHERE id = $1 RETURNING client_id, refresh_token_id`, request.TokenID).
+				Scan(&clientID, &refreshTokenID)
+			if errors.Is(err, sql.ErrNoRows) {
+				// the token is already revoked or expired
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if clientID != request.ClientID {
+				return oidc.ErrInvalidClient().WithDescription("token was not issued for this client")
+			}
+			if !request.RevokeRefreshToken || refreshTokenID == "" {
+				return nil
+			}
+		}
+		var familyID, clientID string
+		err := tx.QueryRowContext(ctx, `SELECT family_id, client_id FROM refresh_tokens WHERE id = $1`, refreshTokenID).Scan(&familyID, &clientID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if clientID != request.ClientID {
+			return oidc.ErrInvalidClient().WithDescription("token was not issued for this client")
+		}
+		return revokeFamily(ctx, tx, familyID)
+	})
+	if err != nil {
+		return oidc.DefaultToServerError(err, "revoking token failed")
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"slices"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/google/uuid"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/admin"
+)
+
+var (
+	_ op.SessionStorage           = &Storage{}
+	_ admin.TokenStorage          = &Storage{}
+	_ admin.AuthRequestStorage    = &Storage{}
+	_ admin.KeyRotator            = &Storage{}
+	_ admin.WritableClientStorage = &Storage{}
+)
+
+// notFoundError implements the op.StorageNotFoundError interface,
+// so the management API responds with 404 Not Found
+type notFoundError string
+
+func (e notFoundError) Error() string { return string(e) }
+func (notFoundError) IsNotFound()     {}
+
+const errClientNotFound = notFoundError("client not found")
+
+// ListSessions implements the op.SessionStorage interface
+// a session is established by every login, the tokens issued afterwards belong to it
+func (s *Storage) ListSessions(ctx context.Context, subject string) ([]op.UserSession, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var sessions []op.UserSession
+	for _, session := range s.sessions() {
+		if session.Subject == subject {
+			sessions = append(sessions, *session)
+		}
+	}
+	return sessions, nil
+}
+
+// TerminateSessionByID implements the op.SessionStorage interface
+// the access and refresh tokens of the session are removed
+func (s *Storage) TerminateSessionByID(ctx context.Context, sessionID string) (*op.UserSession, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	session, ok := s.sessions()[sessionID]
+	if !ok {
+		return nil, notFoundError("session not found")
+	}
+	for id, token := range s.tokens {
+		if token.SessionID == sessionID {
+			delete(s.tokens, id)
+		}
+	}
+	for id, token := range s.refreshTokens {
+		if token.SessionID == sessionID {
+			delete(s.refreshTokens, id)
+		}
+	}
+	return session, nil
+}
+
+// sessions collects the sessions from the tokens, the caller must hold the lock
+func (s *Storage) sessions() map[string]*op.UserSession {
+	sessions := make(map[string]*op.UserSession)
+	add := func(sessionID, subject, clientID string, authTime time.Time) {
+		if sessionID == "" {
+			return
+		}
+		session, ok := sessions[sessionID]
+		if !ok {
+			session = &op.UserSession{ID: sessionID, Subject: subject}
+			sessions[sessionID] = session
+		}
+		if !authTime.IsZero() {
+			session.AuthTime = authTime
+		}
+		if !slices.Contains(session.ClientIDs, clientID) {
+			session.ClientIDs = append(session.ClientIDs, clientID)
+		}
+	}
+	for _, token := range s.tokens {
+		add(token.SessionID, token.Subject, token.ApplicationID, token.AuthTime)
+	}
+	for _, token := range s.refreshTokens {
+		add(token.SessionID, token.UserID, token.ApplicationID, token.AuthTime)
+	}
+	return sessions
+}
+
+// ListTokens implements the admin.TokenStorage interface
+func (s *Storage) ListTokens(ctx context.Context, filter admin.TokenFilter) ([]admin.Token, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	matches := func(subject, clientID string) bool {
+		return (filter.Subject == "" || filter.Subject == subject) && (filter.ClientID == "" || filter.ClientID == clientID)
+	}
+	var tokens []admin.Token
+	for _, token := range s.tokens {
+		if matches(token.Subject, token.ApplicationID) {
+			tokens = append(tokens, admin.Token{
+				ID:         token.ID,
+				Type:       oidc.AccessTokenType,
+				ClientID:   token.ApplicationID,
+				Subject:    token.Subject,
+				Scopes:     token.Scopes,
+				Expiration: token.Expiration,
+			})
+		}
+	}
+	for _, token := range s.refreshTokens {
+		if matches(token.UserID, token.ApplicationID) {
+			tokens = append(tokens, admin.Token{
+				ID:         token.ID,
+				Type:       oidc.RefreshTokenType,
+				ClientID:   token.ApplicationID,
+				Subject:    token.UserID,
+				Scopes:     token.Scopes,
+				Expiration: token.Expiration,
+			})
+		}
+	}
+	return tokens, nil
+}
+
+// ListAuthRequests implements the admin.AuthRequestStorage interface
+func (s *Storage) ListAuthRequests(ctx context.Context) ([]op.AuthRequest, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	requests := make([]op.AuthRequest, 0, len(s.authRequests))
+	for _, request := range s.authRequests {
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
+
+// RotateSigningKey implements the admin.KeyRotator interface
+// the previous key is still published by KeySet, so the tokens signed with it stay valid
+func (s *Storage) RotateSigningKey(ctx context.Context) error {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	previous := s.signingKey
+	s.previousSigningKey = &previous
+	s.signingKey = signingKey{
+		id:        uuid.NewString(),
+		algorithm: jose.RS256,
+		key:       key,
+	}
+	return nil
+}
+
+// ListClients implements the admin.ClientStorage interface
+func (s *Storage) ListClients(ctx context.Context) ([]op.Client, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	clients := make([]op.Client, 0, len(s.clients))
+	for _, client := range s.clients {
+		clients = append(clients, RedirectGlobsClient(client))
+	}
+	return clients, nil
+}
+
+// CreateClient implements the admin.WritableClientStorage interface
+func (s *Storage) CreateClient(ctx context.Context, client *admin.Client) (string, error) {
+	var secret string
+	if client.AuthMethod != oidc.AuthMethodNone {
+		secret = rand.Text()
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.clients[client.ID]; ok {
+		return "", errors.New("client already exists")
+	}
+	s.clients[client.ID] = clientFromAdmin(client, secret)
+	return secret, nil
+}
+
+// UpdateClient implements the admin.WritableClientStorage interface
+// the secret of the client is kept
+func (s *Storage) UpdateClient(ctx context.Context, client *admin.Client) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	current, ok := s.clients[client.ID]
+	if !ok {
+		return errClientNotFound
+	}
+	s.clients[client.ID] = clientFromAdmin(client, current.secret)
+	return nil
+}
+
+// DeleteClient implements the admin.WritableClientStorage interface
+func (s *Storage) DeleteClient(ctx context.Context, clientID string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.clients[clientID]; !ok {
+		return errClientNotFound
+	}
+	delete(s.clients, clientID)
+	return nil
+}
+
+func clientFromAdmin(client *admin.Client, secret string) *Client {
+	return &Client{
+		id:                     client.ID,
+		secret:                 secret,
+		redirectURIs:           client.RedirectURIs,
+		postLogoutRedirectURIs: client.PostLogoutRedirectURIs,
+		applicationType:        client.ApplicationType,
+		authMethod:             client.AuthMethod,
+		loginURL:               defaultLoginURL,
+		responseTypes:          client.ResponseTypes,
+		grantTypes:             client.GrantTypes,
+		accessTokenType:        client.AccessTokenType,
+		devMode:                client.DevMode,
+		clockSkew:              time.Duration(client.ClockSkew) * time.Second,
+	}
+}
@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/zitadel/oidc/v3/pkg/oidc"
@@ -34,6 +36,10 @@ type Client struct {
 	clockSkew                      time.Duration
 	postLogoutRedirectURIGlobs     []string
 	redirectURIGlobs               []string
+	postLogoutRedirectURIs         []string
+	backChannelLogoutURI           string
+	frontChannelLogoutURI          string
+	accessTokenFormat              string
 }
 
 // GetID must return the client_id
@@ -48,7 +54,7 @@ func (c *Client) RedirectURIs() []string {
 
 // PostLogoutRedirectURIs must return the registered post_logout_redirect_uris for sign-outs
 func (c *Client) PostLogoutRedirectURIs() []string {
-	return []string{}
+	return c.postLogoutRedirectURIs
 }
 
 // ApplicationType must return the type of the client (app, native, user agent)
@@ -127,6 +133,35 @@ func (c *Client) ClockSkew() time.Duration {
 	return c.clockSkew
 }
 
+// BackChannelLogoutURI implements the op.BackChannelLogoutClient interface
+// the OP will post a logout token to it when the user signs out
+func (c *Client) BackChannelLogoutURI() string {
+	return c.backChannelLogoutURI
+}
+
+// BackChannelLogoutSessionRequired implements the op.BackChannelLogoutClient interface
+// tokens issued by token exchange don't belong to a session, so the sid claim can't be required
+func (c *Client) BackChannelLogoutSessionRequired() bool {
+	return false
+}
+
+// FrontChannelLogoutURI implements the op.FrontChannelLogoutClient interface
+// the user agent will load it when the session is terminated by op.LogoutSession
+func (c *Client) FrontChannelLogoutURI() string {
+	return c.frontChannelLogoutURI
+}
+
+// FrontChannelLogoutSessionRequired implements the op.FrontChannelLogoutClient interface
+func (c *Client) FrontChannelLogoutSessionRequired() bool {
+	return false
+}
+
+// AccessTokenFormat implements the op.AccessTokenFormatClient interface
+// the access tokens are issued in the format registered under this name, e.g. a CWT for constrained devices
+func (c *Client) AccessTokenFormat() string {
+	return c.accessTokenFormat
+}
+
 // RegisterClients enables you to register clients for the example implementation
 // there are some clients (web and native) to try out different cases
 // add more if necessary.
@@ -211,6 +246,37 @@ func DeviceClient(id, secret string) *Client {
 	}
 }
 
+// ConformanceClient creates a web client for the OpenID Foundation conformance suite,
+// which allows all response types of the basic, implicit and hybrid profiles.
+// baseURL is the url of the test plan (e.g. https://localhost.emobix.co.uk:8443/test/a/zitadel-oidc),
+// which is used for the redirect, post logout and back-channel logout uris.
+func ConformanceClient(id, secret, baseURL string) *Client {
+	return &Client{
+		id:              id,
+		secret:          secret,
+		redirectURIs:    []string{baseURL + "/callback"},
+		applicationType: op.ApplicationTypeWeb,
+		authMethod:      oidc.AuthMethodBasic,
+		loginURL:        defaultLoginURL,
+		responseTypes: []oidc.ResponseType{
+			oidc.ResponseTypeCode,
+			oidc.ResponseTypeIDTokenOnly,
+			oidc.ResponseTypeIDToken,
+			oidc.ResponseTypeCodeIDToken,
+			oidc.ResponseTypeCodeToken,
+			oidc.ResponseTypeCodeIDTokenToken,
+		},
+		grantTypes:                     []oidc.GrantType{oidc.GrantTypeCode, oidc.GrantTypeRefreshToken, oidc.GrantTypeImplicit},
+		accessTokenType:                op.AccessTokenTypeBearer,
+		devMode:                        false,
+		idTokenUserinfoClaimsAssertion: false,
+		clockSkew:                      0,
+		postLogoutRedirectURIs:         []string{baseURL + "/post_logout_redirect"},
+		backChannelLogoutURI:           baseURL + "/backchannel_logout",
+		frontChannelLogoutURI:          baseURL + "/frontchannel_logout",
+	}
+}
+
 type hasRedirectGlobs struct {
 	*Client
 }
@@ -233,3 +299,114 @@ func RedirectGlobsClient(client *Client) op.Client {
 	}
 	return client
 }
+
+// AccessTokenFormatClient sets the name of the op.AccessTokenFormat
+// the access tokens of the client are issued in.
+func AccessTokenFormatClient(client *Client, format string) *Client {
+	client.accessTokenFormat = format
+	return client
+}
+
+type hasRedirectURIPolicy struct {
+	*Client
+	policy op.RedirectURIPolicy
+}
+
+// RedirectURIPolicy replaces the built-in redirect_uri validation of the OP
+func (c hasRedirectURIPolicy) RedirectURIPolicy() op.RedirectURIPolicy {
+	return c.policy
+}
+
+// RedirectURIPolicyClient wraps the client in an op.HasRedirectURIPolicy,
+// e.g. op.NativeRedirectURIs for native apps.
+func RedirectURIPolicyClient(client *Client, policy op.RedirectURIPolicy) op.Client {
+	return hasRedirectURIPolicy{client, policy}
+}
+
+// ClientCodec encodes the clients of the storage as JSON,
+// e.g. for the op.CacheConfig or to compare cached clients.
+type ClientCodec struct{}
+
+var _ op.ClientCodec = ClientCodec{}
+
+// clientJSON is the encoding of a Client.
+type clientJSON struct {
+	ID                             string              `json:"id"`
+	Secret                         string              `json:"secret,omitempty"`
+	RedirectURIs                   []string            `json:"redirect_uris,omitempty"`
+	ApplicationType                op.ApplicationType  `json:"application_type"`
+	AuthMethod                     oidc.AuthMethod     `json:"auth_method"`
+	ResponseTypes                  []oidc.ResponseType `json:"response_types,omitempty"`
+	GrantTypes                     []oidc.GrantType    `json:"grant_types,omitempty"`
+	AccessTokenType                op.AccessTokenType  `json:"access_token_type"`
+	DevMode                        bool                `json:"dev_mode,omitempty"`
+	IDTokenUserinfoClaimsAssertion bool                `json:"id_token_userinfo_claims_assertion,omitempty"`
+	ClockSkew                      time.Duration       `json:"clock_skew,omitempty"`
+	PostLogoutRedirectURIGlobs     []string            `json:"post_logout_redirect_uri_globs,omitempty"`
+	RedirectURIGlobs               []string            `json:"redirect_uri_globs,omitempty"`
+	PostLogoutRedirectURIs         []string            `json:"post_logout_redirect_uris,omitempty"`
+	BackChannelLogoutURI           string              `json:"backchannel_logout_uri,omitempty"`
+	FrontChannelLogoutURI          string              `json:"frontchannel_logout_uri,omitempty"`
+	AccessTokenFormat              string              `json:"access_token_format,omitempty"`
+}
+
+// MarshalClient implements op.ClientCodec.
+// Only clients returned by the Storage can be encoded.
+func (ClientCodec) MarshalClient(client op.Client) ([]byte, error) {
+	var c *Client
+	switch client := client.(type) {
+	case *Client:
+		c = client
+	case hasRedirectGlobs:
+		c = client.Client
+	default:
+		return nil, fmt.Errorf("cannot encode client of type %T", client)
+	}
+	return json.Marshal(clientJSON{
+		ID:                             c.id,
+		Secret:                         c.secret,
+		RedirectURIs:                   c.redirectURIs,
+		ApplicationType:                c.applicationType,
+		AuthMethod:                     c.authMethod,
+		ResponseTypes:                  c.responseTypes,
+		GrantTypes:                     c.grantTypes,
+		AccessTokenType:                c.accessTokenType,
+		DevMode:                        c.devMode,
+		IDTokenUserinfoClaimsAssertion: c.idTokenUserinfoClaimsAssertion,
+		ClockSkew:                      c.clockSkew,
+		PostLogoutRedirectURIGlobs:     c.postLogoutRedirectURIGlobs,
+		RedirectURIGlobs:               c.redirectURIGlobs,
+		PostLogoutRedirectURIs:         c.postLogoutRedirectURIs,
+		BackChannelLogoutURI:           c.backChannelLogoutURI,
+		FrontChannelLogoutURI:          c.frontChannelLogoutURI,
+		AccessTokenFormat:              c.accessTokenFormat,
+	})
+}
+
+// UnmarshalClient implements op.ClientCodec.
+func (ClientCodec) UnmarshalClient(data []byte) (op.Client, error) {
+	var c clientJSON
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return RedirectGlobsClient(&Client{
+		id:                             c.ID,
+		secret:                         c.Secret,
+		redirectURIs:                   c.RedirectURIs,
+		applicationType:                c.ApplicationType,
+		authMethod:                     c.AuthMethod,
+		loginURL:                       defaultLoginURL,
+		responseTypes:                  c.ResponseTypes,
+		grantTypes:                     c.GrantTypes,
+		accessTokenType:                c.AccessTokenType,
+		devMode:                        c.DevMode,
+		idTokenUserinfoClaimsAssertion: c.IDTokenUserinfoClaimsAssertion,
+		clockSkew:                      c.ClockSkew,
+		postLogoutRedirectURIGlobs:     c.PostLogoutRedirectURIGlobs,
+		redirectURIGlobs:               c.RedirectURIGlobs,
+		postLogoutRedirectURIs:         c.PostLogoutRedirectURIs,
+		backChannelLogoutURI:           c.BackChannelLogoutURI,
+		frontChannelLogoutURI:          c.FrontChannelLogoutURI,
+		accessTokenFormat:              c.AccessTokenFormat,
+	}), nil
+}
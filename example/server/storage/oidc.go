@@ -30,7 +30,10 @@ type AuthRequest struct {
 	TransferState string
 	Prompt        []string
 	UiLocales     []language.Tag
+	UILocale      language.Tag
 	LoginHint     string
+	HostedDomain  string
+	Hints         map[string]string
 	MaxAuthAge    *time.Duration
 	UserID        string
 	Scopes        []string
@@ -39,8 +42,11 @@ type AuthRequest struct {
 	Nonce         string
 	CodeChallenge *OIDCCodeChallenge
 
-	done     bool
-	authTime time.Time
+	done      bool
+	authTime  time.Time
+	amr       []string
+	acr       string
+	sessionID string
 }
 
 // LogValue allows you to define which fields will be logged.
@@ -61,15 +67,16 @@ func (a *AuthRequest) GetID() string {
 }
 
 func (a *AuthRequest) GetACR() string {
-	return "" // we won't handle acr in this example
+	return a.acr
 }
 
 func (a *AuthRequest) GetAMR() []string {
-	// this example only uses password for authentication
-	if a.done {
+	// the login flow sets the methods used for authentication,
+	// other logins of this example only use password
+	if a.done && len(a.amr) == 0 {
 		return []string{"pwd"}
 	}
-	return nil
+	return a.amr
 }
 
 func (a *AuthRequest) GetAudience() []string {
@@ -92,6 +99,12 @@ func (a *AuthRequest) GetNonce() string {
 	return a.Nonce
 }
 
+// GetSessionID implements the op.SessionRequest interface
+// the session is established by the login of the user
+func (a *AuthRequest) GetSessionID() string {
+	return a.sessionID
+}
+
 func (a *AuthRequest) GetRedirectURI() string {
 	return a.CallbackURI
 }
@@ -158,7 +171,10 @@ func authRequestToInternal(authReq *oidc.AuthRequest, userID string) *AuthReques
 		TransferState: authReq.State,
 		Prompt:        PromptToInternal(authReq.Prompt),
 		UiLocales:     authReq.UILocales,
+		UILocale:      authReq.UILocale,
 		LoginHint:     authReq.LoginHint,
+		HostedDomain:  authReq.HostedDomain,
+		Hints:         authReq.Hints,
 		MaxAuthAge:    MaxAgeToInternal(authReq.MaxAge),
 		UserID:        userID,
 		Scopes:        authReq.Scopes,
@@ -206,6 +222,12 @@ type RefreshTokenRequest struct {
 	*RefreshToken
 }
 
+// GetSessionID implements the op.SessionRequest interface
+// the refresh token stays bound to the session of the login
+func (r *RefreshTokenRequest) GetSessionID() string {
+	return r.SessionID
+}
+
 func (r *RefreshTokenRequest) GetAMR() []string {
 	return r.AMR
 }
@@ -233,3 +255,8 @@ func (r *RefreshTokenRequest) GetSubject() string {
 func (r *RefreshTokenRequest) SetCurrentScopes(scopes []string) {
 	r.Scopes = scopes
 }
+
+// SetCurrentAudience implements the op.RefreshTokenAudienceRequest interface
+func (r *RefreshTokenRequest) SetCurrentAudience(audience []string) {
+	r.Audience = audience
+}
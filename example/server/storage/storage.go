@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -16,6 +17,7 @@ import (
 
 	"github.com/zitadel/oidc/v3/pkg/oidc"
 	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/login"
 )
 
 // serviceKey1 is a public key which will be used for the JWT Profile Authorization Grant
@@ -31,6 +33,8 @@ var serviceKey1 = &rsa.PublicKey{
 var (
 	_ op.Storage                  = &Storage{}
 	_ op.ClientCredentialsStorage = &Storage{}
+
+	_ op.CanListBackChannelLogoutSessions = &Storage{}
 )
 
 // storage implements the op.Storage interface
@@ -45,10 +49,18 @@ type Storage struct {
 	userStore     UserStore
 	services      map[string]Service
 	refreshTokens map[string]*RefreshToken
-	signingKey    signingKey
-	deviceCodes   map[string]deviceAuthorizationEntry
-	userCodes     map[string]string
-	serviceUsers  map[string]*Client
+	// opaqueAccessTokens maps the values of random opaque access tokens to the token ids,
+	// they are pruned when they expired or the tokens were revoked
+	opaqueAccessTokens map[string]opaqueAccessToken
+	signingKey         signingKey
+	// previousSigningKey is published until the tokens signed with it expired, see RotateSigningKey
+	previousSigningKey *signingKey
+	deviceCodes        map[string]deviceAuthorizationEntry
+	userCodes          map[string]string
+	serviceUsers       map[string]*Client
+	loginStates        map[string]*login.State
+	// completedStatelessRequests maps the IDs of completed stateless auth requests to their expiry
+	completedStatelessRequests map[string]time.Time
 }
 
 type signingKey struct {
@@ -96,12 +108,14 @@ func NewStorage(userStore UserStore) *Storage {
 func NewStorageWithClients(userStore UserStore, clients map[string]*Client) *Storage {
 	key, _ := rsa.GenerateKey(rand.Reader, 2048)
 	return &Storage{
-		authRequests:  make(map[string]*AuthRequest),
-		codes:         make(map[string]string),
-		tokens:        make(map[string]*Token),
-		refreshTokens: make(map[string]*RefreshToken),
-		clients:       clients,
-		userStore:     userStore,
+		authRequests:               make(map[string]*AuthRequest),
+		codes:                      make(map[string]string),
+		tokens:                     make(map[string]*Token),
+		refreshTokens:              make(map[string]*RefreshToken),
+		opaqueAccessTokens:         make(map[string]opaqueAccessToken),
+		completedStatelessRequests: make(map[string]time.Time),
+		clients:                    clients,
+		userStore:                  userStore,
 		services: map[string]Service{
 			userStore.ExampleClientID(): {
 				keys: map[string]*rsa.PublicKey{
@@ -116,6 +130,7 @@ func NewStorageWithClients(userStore UserStore, clients map[string]*Client) *Sto
 		},
 		deviceCodes: make(map[string]deviceAuthorizationEntry),
 		userCodes:   make(map[string]string),
+		loginStates: make(map[string]*login.State),
 		serviceUsers: map[string]*Client{
 			"sid1": {
 				id:     "sid1",
@@ -153,6 +168,7 @@ func (s *Storage) CheckUsernamePassword(username, password, id string) error {
 		request.done = true
 
 		request.authTime = time.Now()
+		request.sessionID = op.NewSessionID()
 
 		return nil
 	}
@@ -170,18 +186,90 @@ func (s *Storage) CheckUsernamePasswordSimple(username, password string) error {
 	return fmt.Errorf("username or password wrong")
 }
 
+// CheckPassword is used by the password authenticator of the login flow
+// and returns the id of the user
+func (s *Storage) CheckPassword(ctx context.Context, username, password string) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// for demonstration purposes the password is stored in plain text,
+	// be sure to have it hashed and salted (e.g. using bcrypt) in real world scenarios
+	user := s.userStore.GetUserByUsername(username)
+	if user != nil && user.Password == password {
+		return user.ID, nil
+	}
+	return "", fmt.Errorf("username or password wrong")
+}
+
+// LoginState implements the login.Storage interface
+func (s *Storage) LoginState(ctx context.Context, authRequestID string) (*login.State, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	request, ok := s.authRequests[authRequestID]
+	if !ok {
+		return nil, fmt.Errorf("request not found")
+	}
+	if state, ok := s.loginStates[authRequestID]; ok {
+		return state, nil
+	}
+	// the login is rendered in the language requested by the client,
+	// the user id of a new auth request is the subject of the id_token_hint
+	return &login.State{
+		AuthRequestID:      authRequestID,
+		UILocales:          request.UiLocales,
+		UILocale:           request.UILocale,
+		LoginHint:          request.LoginHint,
+		IDTokenHintSubject: request.UserID,
+		Hints:              request.Hints,
+		Scopes:             request.Scopes,
+	}, nil
+}
+
+// SaveLoginState implements the login.Storage interface
+// the states of abandoned logins are removed after their expiration, when a new login starts
+func (s *Storage) SaveLoginState(ctx context.Context, state *login.State) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.loginStates[state.AuthRequestID]; !ok {
+		now := time.Now()
+		for id, existing := range s.loginStates {
+			if now.After(existing.Expires) {
+				delete(s.loginStates, id)
+			}
+		}
+	}
+	s.loginStates[state.AuthRequestID] = state
+	return nil
+}
+
+// CompleteLogin implements the login.Storage interface
+// it sets the result of the login flow on the auth request and marks it as done
+func (s *Storage) CompleteLogin(ctx context.Context, state *login.State) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	request, ok := s.authRequests[state.AuthRequestID]
+	if !ok {
+		return fmt.Errorf("request not found")
+	}
+	request.UserID = state.UserID
+	request.authTime = state.AuthTime
+	request.amr = state.AMR
+	request.acr = state.ACR
+	request.done = true
+	request.sessionID = op.NewSessionID()
+	delete(s.loginStates, state.AuthRequestID)
+	return nil
+}
+
 // CreateAuthRequest implements the op.Storage interface
 // it will be called after parsing and validation of the authentication request
 func (s *Storage) CreateAuthRequest(ctx context.Context, authReq *oidc.AuthRequest, userID string) (op.AuthRequest, error) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	if len(authReq.Prompt) == 1 && authReq.Prompt[0] == "none" {
-		// With prompt=none, there is no way for the user to log in
-		// so return error right away.
-		return nil, oidc.ErrLoginRequired()
-	}
-
 	// typically, you'll fill your storage / storage model with the information of the passed object
 	request := authRequestToInternal(authReq, userID)
 
@@ -195,6 +283,53 @@ func (s *Storage) CreateAuthRequest(ctx context.Context, authReq *oidc.AuthReque
 	return request, nil
 }
 
+// SessionByID implements the op.SilentAuthStorage interface
+// it returns the session of the session cookie for auth requests with prompt=none
+func (s *Storage) SessionByID(ctx context.Context, sessionID string) (*op.UserSession, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	session, ok := s.sessions()[sessionID]
+	if !ok {
+		return nil, notFoundError("session not found")
+	}
+	return session, nil
+}
+
+// CreateSilentAuthRequest implements the op.SilentAuthStorage interface
+// the auth request is completed by the session, as this example does not require consent
+func (s *Storage) CreateSilentAuthRequest(ctx context.Context, authReq *oidc.AuthRequest, session *op.UserSession) (op.AuthRequest, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	request := authRequestToInternal(authReq, session.Subject)
+	request.ID = uuid.NewString()
+	request.authTime = session.AuthTime
+	request.sessionID = session.ID
+	request.done = true
+	s.authRequests[request.ID] = request
+	return request, nil
+}
+
+// CreateCompletedAuthRequest implements the op.StatelessAuthRequestStorage interface
+// it will be called on the callback of the login of stateless auth requests
+// the IDs of the completed requests are remembered until they expire, so they can't be replayed
+func (s *Storage) CreateCompletedAuthRequest(ctx context.Context, req *op.StatelessAuthRequest) (op.AuthRequest, error) {
+	s.lock.Lock()
+	now := time.Now()
+	for id, expiry := range s.completedStatelessRequests {
+		if now.After(expiry) {
+			delete(s.completedStatelessRequests, id)
+		}
+	}
+	if _, ok := s.completedStatelessRequests[req.ID]; ok {
+		s.lock.Unlock()
+		return nil, errors.New("auth request already completed")
+	}
+	s.completedStatelessRequests[req.ID] = req.Expiry
+	s.lock.Unlock()
+	return s.CreateSilentAuthRequest(ctx, req.Request, req.Session)
+}
+
 // AuthRequestByID implements the op.Storage interface
 // it will be called after the Login UI redirects back to the OIDC endpoint
 func (s *Storage) AuthRequestByID(ctx context.Context, id string) (op.AuthRequest, error) {
@@ -243,6 +378,7 @@ func (s *Storage) DeleteAuthRequest(ctx context.Context, id string) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 	delete(s.authRequests, id)
+	delete(s.loginStates, id)
 	for code, requestID := range s.codes {
 		if id == requestID {
 			delete(s.codes, code)
@@ -264,7 +400,7 @@ func (s *Storage) CreateAccessToken(ctx context.Context, request op.TokenRequest
 		applicationID = req.GetClientID()
 	}
 
-	token, err := s.accessToken(applicationID, "", request.GetSubject(), request.GetAudience(), request.GetScopes())
+	token, err := s.accessToken(applicationID, "", op.SessionIDFromRequest(request), request.GetSubject(), request.GetAudience(), request.GetScopes(), authTimeFromRequest(request))
 	if err != nil {
 		return "", time.Time{}, err
 	}
@@ -284,8 +420,11 @@ func (s *Storage) CreateAccessAndRefreshTokens(ctx context.Context, request op.T
 
 	// if currentRefreshToken is empty (Code Flow) we will have to create a new refresh token
 	if currentRefreshToken == "" {
-		refreshTokenID := uuid.NewString()
-		accessToken, err := s.accessToken(applicationID, refreshTokenID, request.GetSubject(), request.GetAudience(), request.GetScopes())
+		refreshTokenID, err := op.NewRefreshTokenValue(ctx)
+		if err != nil {
+			return "", "", time.Time{}, err
+		}
+		accessToken, err := s.accessToken(applicationID, refreshTokenID, op.SessionIDFromRequest(request), request.GetSubject(), request.GetAudience(), request.GetScopes(), authTimeFromRequest(request))
 		if err != nil {
 			return "", "", time.Time{}, err
 		}
@@ -298,10 +437,14 @@ func (s *Storage) CreateAccessAndRefreshTokens(ctx context.Context, request op.T
 
 	// if we get here, the currentRefreshToken was not empty, so the call is a refresh token request
 	// we therefore will have to check the currentRefreshToken and renew the refresh token
+	// (it is always rotated, so also for clients with op.RefreshTokenRotationRequired)
 
-	newRefreshToken = uuid.NewString()
+	newRefreshToken, err = op.NewRefreshTokenValue(ctx)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
 
-	accessToken, err := s.accessToken(applicationID, newRefreshToken, request.GetSubject(), request.GetAudience(), request.GetScopes())
+	accessToken, err := s.accessToken(applicationID, newRefreshToken, op.SessionIDFromRequest(request), request.GetSubject(), request.GetAudience(), request.GetScopes(), authTimeFromRequest(request))
 	if err != nil {
 		return "", "", time.Time{}, err
 	}
@@ -313,12 +456,55 @@ func (s *Storage) CreateAccessAndRefreshTokens(ctx context.Context, request op.T
 	return accessToken.ID, newRefreshToken, accessToken.Expiration, nil
 }
 
+type opaqueAccessToken struct {
+	tokenID    string
+	expiration time.Time
+}
+
+// SaveOpaqueAccessToken implements the op.OpaqueAccessTokenStorage interface
+// it will be called after the access token was created, if the OP issues random opaque access tokens
+func (s *Storage) SaveOpaqueAccessToken(ctx context.Context, value, tokenID, subject string, expiration time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := time.Now()
+	for v, token := range s.opaqueAccessTokens {
+		if _, ok := s.tokens[token.tokenID]; !ok || token.expiration.Before(now) {
+			delete(s.opaqueAccessTokens, v)
+		}
+	}
+	s.opaqueAccessTokens[value] = opaqueAccessToken{tokenID: tokenID, expiration: expiration}
+	return nil
+}
+
+// OpaqueAccessToken implements the op.OpaqueAccessTokenStorage interface
+// the access token is looked up by its id, so revoked and expired tokens are not returned
+func (s *Storage) OpaqueAccessToken(ctx context.Context, value string) (tokenID, subject string, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	token, ok := s.tokens[s.opaqueAccessTokens[value].tokenID]
+	if !ok || token.Expiration.Before(time.Now()) {
+		return "", "", errors.New("invalid access token")
+	}
+	return token.ID, token.Subject, nil
+}
+
+// authTimeFromRequest returns the auth_time of auth and refresh token requests
+func authTimeFromRequest(request op.TokenRequest) time.Time {
+	if req, ok := request.(interface{ GetAuthTime() time.Time }); ok {
+		return req.GetAuthTime()
+	}
+	return time.Time{}
+}
+
 func (s *Storage) exchangeRefreshToken(ctx context.Context, request op.TokenExchangeRequest) (accessTokenID string, newRefreshToken string, expiration time.Time, err error) {
 	applicationID := request.GetClientID()
 	authTime := request.GetAuthTime()
 
-	refreshTokenID := uuid.NewString()
-	accessToken, err := s.accessToken(applicationID, refreshTokenID, request.GetSubject(), request.GetAudience(), request.GetScopes())
+	refreshTokenID, err := op.NewRefreshTokenValue(ctx)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	accessToken, err := s.accessToken(applicationID, refreshTokenID, "", request.GetSubject(), request.GetAudience(), request.GetScopes(), authTimeFromRequest(request))
 	if err != nil {
 		return "", "", time.Time{}, err
 	}
@@ -340,7 +526,10 @@ func (s *Storage) TokenRequestByRefreshToken(ctx context.Context, refreshToken s
 	if !ok {
 		return nil, fmt.Errorf("invalid refresh_token")
 	}
-	return RefreshTokenRequestFromBusiness(token), nil
+	// the request works on a copy, so narrowed scopes and audience only apply to the issued tokens
+	// and the refresh token keeps the original grant
+	current := *token
+	return RefreshTokenRequestFromBusiness(&current), nil
 }
 
 // TerminateSession implements the op.Storage interface
@@ -357,6 +546,26 @@ func (s *Storage) TerminateSession(ctx context.Context, userID string, clientID
 	return nil
 }
 
+// BackChannelLogoutSessions implements the op.CanListBackChannelLogoutSessions interface
+// it will be called before the session is terminated and returns every client the user holds tokens of,
+// so they are notified by a logout token
+// if the id_token_hint identifies the session (sid), only the clients of this session are returned
+func (s *Storage) BackChannelLogoutSessions(ctx context.Context, request *op.EndSessionRequest) ([]op.ClientSession, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var sessions []op.ClientSession
+	for _, token := range s.tokens {
+		if request.SessionID != "" && token.SessionID != request.SessionID {
+			continue
+		}
+		session := op.ClientSession{ClientID: token.ApplicationID, SessionID: token.SessionID}
+		if token.Subject == request.UserID && !slices.Contains(sessions, session) {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
 // GetRefreshTokenInfo looks up a refresh token and returns the token id and user id.
 // If given something that is not a refresh token, it must return error.
 func (s *Storage) GetRefreshTokenInfo(ctx context.Context, clientID string, token string) (userID string, tokenID string, err error) {
@@ -398,12 +607,53 @@ func (s *Storage) RevokeToken(ctx context.Context, tokenIDOrToken string, userID
 	return nil
 }
 
+// RevokeTokenFamily implements the op.CanRevokeTokenFamily interface
+// it will be called instead of RevokeToken, after the op identified the token to be revoked
+func (s *Storage) RevokeTokenFamily(ctx context.Context, request *op.RevokeTokenFamilyRequest) *oidc.Error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	refreshTokenID := request.TokenID
+	if request.TokenType == oidc.AccessTokenType {
+		accessToken, ok := s.tokens[request.TokenID]
+		if !ok {
+			// the token is already revoked or expired
+			return nil
+		}
+		if accessToken.ApplicationID != request.ClientID {
+			return oidc.ErrInvalidClient().WithDescription("token was not issued for this client")
+		}
+		delete(s.tokens, accessToken.ID)
+		if !request.RevokeRefreshToken || accessToken.RefreshTokenID == "" {
+			return nil
+		}
+		refreshTokenID = accessToken.RefreshTokenID
+	}
+	refreshToken, ok := s.refreshTokens[refreshTokenID]
+	if !ok {
+		return nil
+	}
+	if refreshToken.ApplicationID != request.ClientID {
+		return oidc.ErrInvalidClient().WithDescription("token was not issued for this client")
+	}
+	delete(s.refreshTokens, refreshToken.ID)
+	// all access tokens issued with the refresh token must be revoked as well
+	for id, token := range s.tokens {
+		if token.RefreshTokenID == refreshToken.ID {
+			delete(s.tokens, id)
+		}
+	}
+	return nil
+}
+
 // SigningKey implements the op.Storage interface
 // it will be called when creating the OpenID Provider
 func (s *Storage) SigningKey(ctx context.Context) (op.SigningKey, error) {
-	// in this example the signing key is a static rsa.PrivateKey and the algorithm used is RS256
+	// in this example the signing key is a rsa.PrivateKey, which is only changed by RotateSigningKey, and the algorithm used is RS256
 	// you would obviously have a more complex implementation and store / retrieve the key from your database as well
-	return &s.signingKey, nil
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := s.signingKey
+	return &key, nil
 }
 
 // SignatureAlgorithms implements the op.Storage interface
@@ -415,12 +665,18 @@ func (s *Storage) SignatureAlgorithms(context.Context) ([]jose.SignatureAlgorith
 // KeySet implements the op.Storage interface
 // it will be called to get the current (public) keys, among others for the keys_endpoint or for validating access_tokens on the userinfo_endpoint, ...
 func (s *Storage) KeySet(ctx context.Context) ([]op.Key, error) {
-	// as mentioned above, this example only has a single signing key without key rotation,
-	// so it will directly use its public key
+	// as mentioned above, this example only has a single signing key, which is rotated on demand,
+	// so it will directly use its public key and the one of the previous key
 	//
 	// when using key rotation you typically would store the public keys alongside the private keys in your database
 	// and give both of them an expiration date, with the public key having a longer lifetime
-	return []op.Key{&publicKey{s.signingKey}}, nil
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	keys := []op.Key{&publicKey{s.signingKey}}
+	if s.previousSigningKey != nil {
+		keys = append(keys, &publicKey{*s.previousSigningKey})
+	}
+	return keys, nil
 }
 
 // GetClientByClientID implements the op.Storage interface
@@ -430,7 +686,7 @@ func (s *Storage) GetClientByClientID(ctx context.Context, clientID string) (op.
 	defer s.lock.Unlock()
 	client, ok := s.clients[clientID]
 	if !ok {
-		return nil, fmt.Errorf("client not found")
+		return nil, errClientNotFound
 	}
 	return RedirectGlobsClient(client), nil
 }
@@ -446,6 +702,7 @@ func (s *Storage) AuthorizeClientIDSecret(ctx context.Context, clientID, clientS
 	}
 	// for this example we directly check the secret
 	// obviously you would not have the secret in plain text, but rather hashed and salted (e.g. using bcrypt)
+	// and implement op.ClientSecretStorage instead, so the OP compares the secret and supports rotation
 	if client.secret != clientSecret {
 		return fmt.Errorf("invalid secret")
 	}
@@ -605,6 +862,7 @@ func (s *Storage) createRefreshToken(accessToken *Token, amr []string, authTime
 		Expiration:    time.Now().Add(5 * time.Hour),
 		Scopes:        accessToken.Scopes,
 		AccessToken:   accessToken.ID,
+		SessionID:     accessToken.SessionID,
 	}
 	s.refreshTokens[token.ID] = token
 	return token.Token, nil
@@ -642,15 +900,17 @@ func (s *Storage) renewRefreshToken(currentRefreshToken, newRefreshToken, newAcc
 }
 
 // accessToken will store an access_token in-memory based on the provided information
-func (s *Storage) accessToken(applicationID, refreshTokenID, subject string, audience, scopes []string) (*Token, error) {
+func (s *Storage) accessToken(applicationID, refreshTokenID, sessionID, subject string, audience, scopes []string, authTime time.Time) (*Token, error) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 	token := &Token{
 		ID:             uuid.NewString(),
 		ApplicationID:  applicationID,
 		RefreshTokenID: refreshTokenID,
+		SessionID:      sessionID,
 		Subject:        subject,
 		Audience:       audience,
+		AuthTime:       authTime,
 		Expiration:     time.Now().Add(5 * time.Minute),
 		Scopes:         scopes,
 	}
@@ -821,6 +1081,16 @@ type deviceAuthorizationEntry struct {
 }
 
 func (s *Storage) StoreDeviceAuthorization(ctx context.Context, clientID, deviceCode, userCode string, expires time.Time, scopes []string) error {
+	return s.StoreDeviceAuthorizationRequest(ctx, deviceCode, userCode, expires, &oidc.DeviceAuthorizationRequest{
+		ClientID: clientID,
+		Scopes:   scopes,
+	})
+}
+
+// StoreDeviceAuthorizationRequest implements the op.DeviceAuthorizationRequestStorage interface,
+// so the binding_message is displayed on the verification page
+func (s *Storage) StoreDeviceAuthorizationRequest(ctx context.Context, deviceCode, userCode string, expires time.Time, req *oidc.DeviceAuthorizationRequest) error {
+	clientID := req.ClientID
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
@@ -836,9 +1106,10 @@ func (s *Storage) StoreDeviceAuthorization(ctx context.Context, clientID, device
 		deviceCode: deviceCode,
 		userCode:   userCode,
 		state: &op.DeviceAuthorizationState{
-			ClientID: clientID,
-			Scopes:   scopes,
-			Expires:  expires,
+			ClientID:       clientID,
+			Scopes:         req.Scopes,
+			BindingMessage: req.BindingMessage,
+			Expires:        expires,
 		},
 	}
 
@@ -896,6 +1167,26 @@ func (s *Storage) DenyDeviceAuthorization(ctx context.Context, userCode string)
 	return nil
 }
 
+// CompleteAuthRequest authenticates the user for the auth request in a new session, without a password.
+// It is used by testing (it implements optest.Storage) and is not required to implement op.Storage
+func (s *Storage) CompleteAuthRequest(ctx context.Context, id, userID string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	request, ok := s.authRequests[id]
+	if !ok {
+		return fmt.Errorf("request not found")
+	}
+	if s.userStore.GetUserByID(userID) == nil {
+		return fmt.Errorf("user not found")
+	}
+	request.UserID = userID
+	request.done = true
+	request.authTime = time.Now()
+	request.sessionID = op.NewSessionID()
+	return nil
+}
+
 // AuthRequestDone is used by testing and is not required to implement op.Storage
 func (s *Storage) AuthRequestDone(id string) error {
 	s.lock.Lock()
@@ -126,6 +126,16 @@ func (s *multiStorage) TerminateSession(ctx context.Context, userID string, clie
 	return storage.TerminateSession(ctx, userID, clientID)
 }
 
+// RevokeTokenFamily implements the op.CanRevokeTokenFamily interface
+// it will be called instead of RevokeToken, after the op identified the token to be revoked
+func (s *multiStorage) RevokeTokenFamily(ctx context.Context, request *op.RevokeTokenFamilyRequest) *oidc.Error {
+	storage, err := s.storageFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	return storage.RevokeTokenFamily(ctx, request)
+}
+
 // GetRefreshTokenInfo looks up a refresh token and returns the token id and user id.
 // If given something that is not a refresh token, it must return error.
 func (s *multiStorage) GetRefreshTokenInfo(ctx context.Context, clientID string, token string) (userID string, tokenID string, err error) {
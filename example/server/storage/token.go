@@ -7,6 +7,8 @@ type Token struct {
 	ApplicationID  string
 	Subject        string
 	RefreshTokenID string
+	SessionID      string
+	AuthTime       time.Time
 	Audience       []string
 	Expiration     time.Time
 	Scopes         []string
@@ -23,4 +25,5 @@ type RefreshToken struct {
 	Expiration    time.Time
 	Scopes        []string
 	AccessToken   string // Token.ID
+	SessionID     string
 }
@@ -0,0 +1,9 @@
+// Package integration contains the end-to-end tests of the OpenID Provider
+// and the client packages. They boot an optest.Server and drive the flows
+// without a browser through the rp, rs and client packages,
+// against both the Provider router and the legacy op.Server implementation.
+//
+// Next to the outcome of the flows, the tests assert on the exchanged requests
+// and responses, recorded by an optest.Recorder, so regressions between
+// the OP and the RP surface here instead of in downstream applications.
+package integration
@@ -0,0 +1,265 @@
+package integration_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/pkg/client"
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	"github.com/zitadel/oidc/v3/pkg/client/rs"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/optest"
+)
+
+// run runs the test against the Provider router and the legacy server.
+func run(t *testing.T, test func(t *testing.T, s *optest.Server), opts ...optest.Option) {
+	t.Run("provider", func(t *testing.T) {
+		test(t, optest.New(t, opts...))
+	})
+	t.Run("legacy server", func(t *testing.T) {
+		test(t, optest.New(t, append(opts, optest.WithLegacyServer())...))
+	})
+}
+
+// assertTokenResponse asserts the headers required for token responses by RFC 6749, section 5.1.
+func assertTokenResponse(t *testing.T, exchange optest.Exchange) map[string]any {
+	t.Helper()
+	assert.Equal(t, http.StatusOK, exchange.Response.StatusCode, string(exchange.Body))
+	assert.Equal(t, "application/json", exchange.Response.Header.Get("Content-Type"))
+	assert.Equal(t, "no-store", exchange.Response.Header.Get("Cache-Control"))
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(exchange.Body, &body))
+	assert.Equal(t, oidc.BearerToken, body["token_type"])
+	assert.NotEmpty(t, body["access_token"])
+	assert.NotZero(t, body["expires_in"])
+	return body
+}
+
+// assertErrorResponse asserts the error response of the OP by RFC 6749, section 5.2.
+func assertErrorResponse(t *testing.T, exchange optest.Exchange, status int, want *oidc.Error) {
+	t.Helper()
+	assert.Equal(t, status, exchange.Response.StatusCode, string(exchange.Body))
+	assert.Equal(t, "application/json", exchange.Response.Header.Get("Content-Type"))
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(exchange.Body, &body))
+	assert.Equal(t, string(want.ErrorType), body["error"])
+}
+
+func form(t *testing.T, exchange optest.Exchange) url.Values {
+	t.Helper()
+	values, err := url.ParseQuery(string(exchange.RequestBody))
+	require.NoError(t, err)
+	return values
+}
+
+func TestDiscovery(t *testing.T) {
+	run(t, func(t *testing.T, s *optest.Server) {
+		recorder := s.Recorder()
+		discovery, err := client.Discover(context.Background(), s.Issuer, recorder.Client())
+		require.NoError(t, err)
+
+		exchange := recorder.Last(t, oidc.DiscoveryEndpoint)
+		assert.Equal(t, http.StatusOK, exchange.Response.StatusCode)
+		assert.Equal(t, "application/json", exchange.Response.Header.Get("Content-Type"))
+		assert.Equal(t, s.Issuer, discovery.Issuer)
+		for _, endpoint := range []string{
+			discovery.AuthorizationEndpoint, discovery.TokenEndpoint, discovery.IntrospectionEndpoint,
+			discovery.RevocationEndpoint, discovery.EndSessionEndpoint, discovery.DeviceAuthorizationEndpoint,
+			discovery.UserinfoEndpoint, discovery.JwksURI,
+		} {
+			assert.True(t, strings.HasPrefix(endpoint, s.Issuer+"/"), endpoint)
+		}
+		assert.Contains(t, discovery.CodeChallengeMethodsSupported, oidc.CodeChallengeMethodS256)
+		assert.Contains(t, discovery.GrantTypesSupported, oidc.GrantTypeRefreshToken)
+		assert.Contains(t, discovery.IDTokenSigningAlgValuesSupported, string(jose.RS256))
+	})
+}
+
+func TestCodeFlowPKCE(t *testing.T) {
+	run(t, func(t *testing.T, s *optest.Server) {
+		recorder := s.Recorder()
+		relyingParty := s.NativeRelyingParty(t, rp.WithHTTPClient(recorder.Client()))
+		tokens := s.CodeFlowPKCE(t, relyingParty)
+
+		exchange := recorder.Last(t, "/oauth/token")
+		request := form(t, exchange)
+		assert.Equal(t, string(oidc.GrantTypeCode), request.Get("grant_type"))
+		assert.Equal(t, optest.NativeClientID, request.Get("client_id"))
+		assert.NotEmpty(t, request.Get("code_verifier"))
+		assert.Empty(t, exchange.Request.Header.Get("Authorization"), "public client")
+		body := assertTokenResponse(t, exchange)
+		assert.NotEmpty(t, body["id_token"])
+		assert.NotEmpty(t, body["refresh_token"])
+
+		// the id_token is signed by a key of the jwks and bound to the access token
+		idToken, err := jose.ParseSigned(tokens.IDToken, []jose.SignatureAlgorithm{jose.RS256})
+		require.NoError(t, err)
+		keys := recorder.Last(t, "/keys")
+		var jwks jose.JSONWebKeySet
+		require.NoError(t, json.Unmarshal(keys.Body, &jwks))
+		assert.Len(t, jwks.Key(idToken.Signatures[0].Header.KeyID), 1)
+		assert.Equal(t, []string{optest.NativeClientID}, tokens.IDTokenClaims.GetAudience())
+		assert.Equal(t, optest.UserID, tokens.IDTokenClaims.GetSubject())
+		assert.NotEmpty(t, tokens.IDTokenClaims.GetAccessTokenHash())
+	})
+}
+
+func TestCodeFlowPKCE_wrongVerifier(t *testing.T) {
+	run(t, func(t *testing.T, s *optest.Server) {
+		recorder := s.Recorder()
+		relyingParty := s.NativeRelyingParty(t, rp.WithHTTPClient(recorder.Client()))
+		callback := s.Authorize(t, rp.AuthURL("state", relyingParty, rp.WithCodeChallenge(oidc.NewSHACodeChallenge("verifier"))))
+
+		_, err := rp.CodeExchange[*oidc.IDTokenClaims](context.Background(), callback.Query().Get("code"), relyingParty, rp.WithCodeVerifier("other"))
+		require.Error(t, err)
+		assertErrorResponse(t, recorder.Last(t, "/oauth/token"), http.StatusBadRequest, oidc.ErrInvalidGrant())
+	})
+}
+
+func TestRefreshToken(t *testing.T) {
+	run(t, func(t *testing.T, s *optest.Server) {
+		recorder := s.Recorder()
+		relyingParty := s.RelyingParty(t, rp.WithHTTPClient(recorder.Client()))
+		tokens := s.CodeFlow(t, relyingParty)
+
+		refreshed, err := rp.RefreshTokens[*oidc.IDTokenClaims](context.Background(), relyingParty, tokens.RefreshToken, "", "")
+		require.NoError(t, err)
+		exchange := recorder.Last(t, "/oauth/token")
+		request := form(t, exchange)
+		assert.Equal(t, string(oidc.GrantTypeRefreshToken), request.Get("grant_type"))
+		assert.Equal(t, tokens.RefreshToken, request.Get("refresh_token"))
+		assert.Empty(t, request.Get("client_secret"), "basic auth")
+		clientID, secret, ok := exchange.Request.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, optest.WebClientID, clientID)
+		assert.Equal(t, optest.WebClientSecret, secret)
+		assertTokenResponse(t, exchange)
+		assert.NotEqual(t, tokens.AccessToken, refreshed.AccessToken)
+		assert.NotEqual(t, tokens.RefreshToken, refreshed.RefreshToken, "rotated")
+		assert.Equal(t, tokens.IDTokenClaims.GetSubject(), refreshed.IDTokenClaims.GetSubject())
+
+		_, err = rp.RefreshTokens[*oidc.IDTokenClaims](context.Background(), relyingParty, tokens.RefreshToken, "", "")
+		require.Error(t, err, "reused refresh token")
+		assertErrorResponse(t, recorder.Last(t, "/oauth/token"), http.StatusBadRequest, oidc.ErrInvalidGrant())
+	})
+}
+
+func TestIntrospectAndRevoke(t *testing.T) {
+	run(t, func(t *testing.T, s *optest.Server) {
+		recorder := s.Recorder()
+		relyingParty := s.RelyingParty(t, rp.WithHTTPClient(recorder.Client()))
+		resourceServer := s.ResourceServer(t, rs.WithClient(recorder.Client()))
+		tokens := s.CodeFlow(t, relyingParty)
+
+		introspection, err := rs.Introspect[*oidc.IntrospectionResponse](context.Background(), resourceServer, tokens.AccessToken)
+		require.NoError(t, err)
+		exchange := recorder.Last(t, "/oauth/introspect")
+		assert.Equal(t, http.MethodPost, exchange.Request.Method)
+		assert.Equal(t, "application/x-www-form-urlencoded", exchange.Request.Header.Get("Content-Type"))
+		assert.Equal(t, tokens.AccessToken, form(t, exchange).Get("token"))
+		assert.Equal(t, "application/json", exchange.Response.Header.Get("Content-Type"))
+		assert.True(t, introspection.Active)
+		assert.Equal(t, optest.UserID, introspection.Subject)
+		assert.Equal(t, optest.WebClientID, introspection.ClientID)
+		assert.Contains(t, introspection.Scope, oidc.ScopeOpenID)
+
+		// with the strict typ check of RFC 9068, an id_token signed by the OP is not an access token
+		introspection, err = rs.Introspect[*oidc.IntrospectionResponse](context.Background(), resourceServer, tokens.IDToken)
+		require.NoError(t, err)
+		assert.False(t, introspection.Active)
+		_, err = rp.Userinfo[*oidc.UserInfo](context.Background(), tokens.IDToken, oidc.BearerToken, optest.UserID, relyingParty)
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusUnauthorized, recorder.Last(t, "/userinfo").Response.StatusCode)
+
+		require.NoError(t, rp.RevokeToken(context.Background(), relyingParty, tokens.RefreshToken, "refresh_token"))
+		exchange = recorder.Last(t, "/revoke")
+		assert.Equal(t, http.StatusOK, exchange.Response.StatusCode)
+		assert.Equal(t, "refresh_token", form(t, exchange).Get("token_type_hint"))
+
+		// the access token issued with the refresh token is revoked as well
+		introspection, err = rs.Introspect[*oidc.IntrospectionResponse](context.Background(), resourceServer, tokens.AccessToken)
+		require.NoError(t, err)
+		assert.False(t, introspection.Active)
+		assert.JSONEq(t, `{"active":false}`, string(recorder.Last(t, "/oauth/introspect").Body))
+
+		_, err = rp.RefreshTokens[*oidc.IDTokenClaims](context.Background(), relyingParty, tokens.RefreshToken, "", "")
+		require.Error(t, err)
+		assertErrorResponse(t, recorder.Last(t, "/oauth/token"), http.StatusBadRequest, oidc.ErrInvalidGrant())
+
+		// unknown tokens are revoked successfully (RFC 7009, section 2.2)
+		require.NoError(t, rp.RevokeToken(context.Background(), relyingParty, "unknown", ""))
+	}, optest.WithProviderOptions(op.WithAccessTokenVerifierOpts(op.WithAccessTokenTypes(oidc.TypeAccessToken))))
+}
+
+func TestEndSession(t *testing.T) {
+	run(t, func(t *testing.T, s *optest.Server) {
+		recorder := s.Recorder()
+		relyingParty := s.RelyingParty(t, rp.WithHTTPClient(recorder.Client()))
+		resourceServer := s.ResourceServer(t, rs.WithClient(recorder.Client()))
+		tokens := s.CodeFlow(t, relyingParty)
+
+		_, err := rp.EndSession(context.Background(), relyingParty, tokens.IDToken, "", "state", "", nil)
+		require.NoError(t, err)
+		exchange := recorder.Last(t, "/end_session")
+		assert.Equal(t, http.MethodPost, exchange.Request.Method)
+		assert.Equal(t, http.StatusFound, exchange.Response.StatusCode)
+		request := form(t, exchange)
+		assert.Equal(t, tokens.IDToken, request.Get("id_token_hint"))
+		assert.Equal(t, optest.WebClientID, request.Get("client_id"))
+
+		// the tokens of the user and client are terminated
+		introspection, err := rs.Introspect[*oidc.IntrospectionResponse](context.Background(), resourceServer, tokens.AccessToken)
+		require.NoError(t, err)
+		assert.False(t, introspection.Active)
+		_, err = rp.RefreshTokens[*oidc.IDTokenClaims](context.Background(), relyingParty, tokens.RefreshToken, "", "")
+		assert.Error(t, err)
+	})
+}
+
+func TestDeviceFlow(t *testing.T) {
+	run(t, func(t *testing.T, s *optest.Server) {
+		recorder := s.Recorder()
+		relyingParty := s.DeviceRelyingParty(t, rp.WithHTTPClient(recorder.Client()))
+
+		// polling before the user approved
+		authorization, err := rp.DeviceAuthorization(context.Background(), []string{oidc.ScopeOpenID}, relyingParty, nil)
+		require.NoError(t, err)
+		exchange := recorder.Last(t, "/device_authorization")
+		assert.Equal(t, http.StatusOK, exchange.Response.StatusCode)
+		assert.Equal(t, s.Issuer+"/device", authorization.VerificationURI)
+		assert.Contains(t, authorization.VerificationURIComplete, url.QueryEscape(authorization.UserCode))
+		assert.Equal(t, 1, authorization.Interval)
+		poll, err := http.NewRequest(http.MethodPost, relyingParty.OAuthConfig().Endpoint.TokenURL, strings.NewReader(url.Values{
+			"grant_type":  {string(oidc.GrantTypeDeviceCode)},
+			"device_code": {authorization.DeviceCode},
+		}.Encode()))
+		require.NoError(t, err)
+		poll.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		poll.SetBasicAuth(optest.DeviceClientID, optest.DeviceClientSecret)
+		resp, err := recorder.Client().Do(poll)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assertErrorResponse(t, recorder.Last(t, "/oauth/token"), http.StatusBadRequest, oidc.ErrAuthorizationPending())
+
+		tokens := s.DeviceFlow(t, relyingParty)
+		exchange = recorder.Last(t, "/oauth/token")
+		assert.Equal(t, string(oidc.GrantTypeDeviceCode), form(t, exchange).Get("grant_type"))
+		body := assertTokenResponse(t, exchange)
+		assert.NotEmpty(t, body["id_token"])
+		assert.NotEmpty(t, tokens.AccessToken)
+
+		info, err := rp.Userinfo[*oidc.UserInfo](context.Background(), tokens.AccessToken, tokens.TokenType, optest.UserID, relyingParty)
+		require.NoError(t, err)
+		assert.Equal(t, optest.UserID, info.Subject)
+		assert.Equal(t, "Bearer "+tokens.AccessToken, recorder.Last(t, "/userinfo").Request.Header.Get("Authorization"))
+	})
+}
@@ -0,0 +1,105 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroup_Do(t *testing.T) {
+	var (
+		g       Group[int]
+		calls   atomic.Int32
+		release = make(chan struct{})
+		wg      sync.WaitGroup
+	)
+	fn := func(context.Context) (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	}
+	results := make([]int, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, _, err := g.Do(context.Background(), "key", fn)
+			assert.NoError(t, err)
+			results[i] = v
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	for _, v := range results {
+		assert.Equal(t, 42, v)
+	}
+
+	// the next call executes again
+	release = make(chan struct{})
+	close(release)
+	_, shared, err := g.Do(context.Background(), "key", fn)
+	require.NoError(t, err)
+	assert.False(t, shared)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestGroup_Do_cancel(t *testing.T) {
+	var g Group[string]
+	started := make(chan struct{})
+	release := make(chan struct{})
+	canceled := make(chan struct{})
+	fn := func(ctx context.Context) (string, error) {
+		close(started)
+		select {
+		case <-release:
+			return "value", nil
+		case <-ctx.Done():
+			close(canceled)
+			return "", ctx.Err()
+		}
+	}
+
+	// the first caller gives up, the second still receives the result
+	first, cancelFirst := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, _, err := g.Do(first, "key", fn)
+		errs <- err
+	}()
+	<-started
+	values := make(chan string, 1)
+	go func() {
+		v, _, _ := g.Do(context.Background(), "key", fn)
+		values <- v
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancelFirst()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	close(release)
+	assert.Equal(t, "value", <-values)
+
+	// the call is canceled when all callers gave up
+	started = make(chan struct{})
+	release = make(chan struct{})
+	only, cancelOnly := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancelOnly()
+	}()
+	_, _, err := g.Do(only, "key", fn)
+	assert.True(t, errors.Is(err, context.Canceled))
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("call not canceled")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	jose "github.com/go-jose/go-jose/v4"
+	"github.com/google/uuid"
 	"github.com/muhlemmer/gu"
 	"github.com/zitadel/oidc/v3/pkg/oidc"
 )
@@ -124,6 +125,7 @@ func NewJWTProfileAssertion(issuer, clientID string, audience []string, issuedAt
 		Audience:  audience,
 		ExpiresAt: oidc.FromTime(expiration),
 		IssuedAt:  oidc.FromTime(issuedAt),
+		JWTID:     uuid.NewString(),
 	}
 	// make sure the private claim map is set correctly
 	data, err := json.Marshal(req)
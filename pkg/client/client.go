@@ -12,7 +12,9 @@ import (
 	"time"
 
 	"github.com/go-jose/go-jose/v4"
+	"github.com/google/uuid"
 	"github.com/zitadel/oidc/v3/internal/otel"
+	"github.com/zitadel/oidc/v3/internal/singleflight"
 	"golang.org/x/oauth2"
 
 	"github.com/zitadel/oidc/v3/pkg/crypto"
@@ -44,15 +46,25 @@ func Discover(ctx context.Context, issuer string, httpClient *http.Client, wellK
 	if len(wellKnownUrl) == 1 && wellKnownUrl[0] != "" {
 		wellKnown = wellKnownUrl[0]
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	// concurrent discoveries of the same issuer with the same client share one request
+	key := fmt.Sprintf("%s %p", wellKnown, httpClient)
+	shared, _, err := discoveries.Do(ctx, key, func(ctx context.Context) (*oidc.DiscoveryConfiguration, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+		if err != nil {
+			return nil, err
+		}
+		discoveryConfig := new(oidc.DiscoveryConfiguration)
+		err = httphelper.HttpRequest(httpClient, req, &discoveryConfig)
+		if err != nil {
+			return nil, errors.Join(oidc.ErrDiscoveryFailed, err)
+		}
+		return discoveryConfig, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 	discoveryConfig := new(oidc.DiscoveryConfiguration)
-	err = httphelper.HttpRequest(httpClient, req, &discoveryConfig)
-	if err != nil {
-		return nil, errors.Join(oidc.ErrDiscoveryFailed, err)
-	}
+	*discoveryConfig = *shared
 	slog.DebugContext(ctx, "discover", "config", discoveryConfig)
 
 	if discoveryConfig.Issuer != issuer {
@@ -61,6 +73,8 @@ func Discover(ctx context.Context, issuer string, httpClient *http.Client, wellK
 	return discoveryConfig, nil
 }
 
+var discoveries singleflight.Group[*oidc.DiscoveryConfiguration]
+
 type TokenEndpointCaller interface {
 	TokenEndpoint() string
 	HttpClient() *http.Client
@@ -237,6 +251,7 @@ func SignedJWTProfileAssertion(clientID string, audience []string, expiration ti
 		Audience:  audience,
 		ExpiresAt: oidc.FromTime(exp),
 		IssuedAt:  oidc.FromTime(iat),
+		JWTID:     uuid.NewString(),
 	}, signer)
 }
 
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// MTLSRoundTripper is an optional interface of custom [http.RoundTripper]s,
+// e.g. wrapping an [*http.Transport] for tracing, which allows
+// [NewMTLSHTTPClient] to present the client certificate.
+type MTLSRoundTripper interface {
+	http.RoundTripper
+	// WithTLSClientConfig returns a copy of the RoundTripper,
+	// which uses the tls.Config for its connections.
+	WithTLSClientConfig(tlsConfig *tls.Config) http.RoundTripper
+}
+
+// ErrMTLSTransport is returned by [NewMTLSHTTPClient] for a transport
+// which can't present the client certificate.
+var ErrMTLSTransport = errors.New("mTLS requires an *http.Transport or a transport implementing client.MTLSRoundTripper")
+
+// NewMTLSHTTPClient returns a copy of the http client, which presents the
+// client certificate of the tls.Config for mutual-TLS client authentication
+// (tls_client_auth or self_signed_tls_client_auth) and certificate-bound access tokens.
+// https://datatracker.ietf.org/doc/html/rfc8705
+//
+// The transport of the http client is cloned, if it is an [*http.Transport],
+// or copied by [MTLSRoundTripper.WithTLSClientConfig].
+// The [http.DefaultTransport] is cloned if the client has no transport.
+// Any other transport results in [ErrMTLSTransport].
+func NewMTLSHTTPClient(httpClient *http.Client, tlsConfig *tls.Config) (*http.Client, error) {
+	mtlsClient := *httpClient
+	switch transport := httpClient.Transport.(type) {
+	case nil:
+		mtlsClient.Transport = withTLSClientConfig(http.DefaultTransport.(*http.Transport), tlsConfig)
+	case *http.Transport:
+		mtlsClient.Transport = withTLSClientConfig(transport, tlsConfig)
+	case MTLSRoundTripper:
+		mtlsClient.Transport = transport.WithTLSClientConfig(tlsConfig)
+	default:
+		return nil, fmt.Errorf("%w, got %T", ErrMTLSTransport, transport)
+	}
+	return &mtlsClient, nil
+}
+
+func withTLSClientConfig(transport *http.Transport, tlsConfig *tls.Config) *http.Transport {
+	transport = transport.Clone()
+	transport.TLSClientConfig = tlsConfig
+	return transport
+}
+
+// MTLSEndpoints returns a copy of the discovery configuration,
+// where the endpoints are replaced with the mtls_endpoint_aliases announced by the OP.
+func MTLSEndpoints(config *oidc.DiscoveryConfiguration) *oidc.DiscoveryConfiguration {
+	mtlsConfig := *config
+	aliases := config.MTLSEndpointAliases
+	if aliases == nil {
+		return &mtlsConfig
+	}
+	setAlias(&mtlsConfig.TokenEndpoint, aliases.TokenEndpoint)
+	setAlias(&mtlsConfig.IntrospectionEndpoint, aliases.IntrospectionEndpoint)
+	setAlias(&mtlsConfig.UserinfoEndpoint, aliases.UserinfoEndpoint)
+	setAlias(&mtlsConfig.RevocationEndpoint, aliases.RevocationEndpoint)
+	setAlias(&mtlsConfig.DeviceAuthorizationEndpoint, aliases.DeviceAuthorizationEndpoint)
+	return &mtlsConfig
+}
+
+func setAlias(endpoint *string, alias string) {
+	if alias != "" {
+		*endpoint = alias
+	}
+}
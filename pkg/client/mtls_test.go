@@ -0,0 +1,50 @@
+package client
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tracingTransport struct {
+	base *http.Transport
+}
+
+func (t tracingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(r)
+}
+
+func (t tracingTransport) WithTLSClientConfig(tlsConfig *tls.Config) http.RoundTripper {
+	base := t.base.Clone()
+	base.TLSClientConfig = tlsConfig
+	return tracingTransport{base}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestNewMTLSHTTPClient(t *testing.T) {
+	tlsConfig := &tls.Config{ServerName: "op.example.com"}
+
+	got, err := NewMTLSHTTPClient(&http.Client{}, tlsConfig)
+	require.NoError(t, err)
+	assert.Same(t, tlsConfig, got.Transport.(*http.Transport).TLSClientConfig, "default transport")
+	assert.NotSame(t, tlsConfig, http.DefaultTransport.(*http.Transport).TLSClientConfig, "default transport unchanged")
+
+	transport := &http.Transport{MaxIdleConns: 7}
+	got, err = NewMTLSHTTPClient(&http.Client{Transport: transport}, tlsConfig)
+	require.NoError(t, err)
+	assert.Equal(t, 7, got.Transport.(*http.Transport).MaxIdleConns, "cloned transport")
+	assert.NotSame(t, tlsConfig, transport.TLSClientConfig)
+
+	got, err = NewMTLSHTTPClient(&http.Client{Transport: tracingTransport{transport}}, tlsConfig)
+	require.NoError(t, err)
+	assert.Same(t, tlsConfig, got.Transport.(tracingTransport).base.TLSClientConfig, "custom transport")
+
+	_, err = NewMTLSHTTPClient(&http.Client{Transport: roundTripperFunc(http.DefaultTransport.RoundTrip)}, tlsConfig)
+	assert.ErrorIs(t, err, ErrMTLSTransport)
+}
@@ -0,0 +1,60 @@
+// Package oidcchi mounts the relying party handlers of the rp package
+// and the token validation of the rs package on chi routers.
+// The oidcecho and oidcgin modules are its counterparts for echo and gin.
+package oidcchi
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	"github.com/zitadel/oidc/v3/pkg/client/rs"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// Paths of the [RelyingPartyRouter].
+const (
+	LoginPath    = "/login"
+	CallbackPath = "/callback"
+)
+
+// RelyingPartyRouter returns a router serving the login, which redirects to the OP,
+// and the callback, which exchanges the code and calls next with the tokens in the context
+// (see [Tokens]). It is mounted on the path of the redirect URI of the relying party:
+//
+//	router.Mount("/auth", oidcchi.RelyingPartyRouter[*oidc.IDTokenClaims](relyingParty, stateFn, next))
+//
+// for the redirect URI https://app.example.com/auth/callback.
+func RelyingPartyRouter[C oidc.IDClaims](relyingParty rp.RelyingParty, stateFn func() string, next http.Handler, urlParam ...rp.URLParamOpt) chi.Router {
+	router := chi.NewRouter()
+	router.Get(LoginPath, rp.AuthURLHandler(stateFn, relyingParty, urlParam...))
+	callback := rp.CodeExchangeHandler(rp.ContextCallback[C](next), relyingParty, urlParam...)
+	router.Get(CallbackPath, callback)
+	router.Post(CallbackPath, callback) // form_post response mode
+	return router
+}
+
+// Tokens returns the tokens and state of the callback of the [RelyingPartyRouter].
+func Tokens[C oidc.IDClaims](r *http.Request) (*oidc.Tokens[C], string, bool) {
+	return rp.TokensFromContext[C](r.Context())
+}
+
+// Protected returns a router, whose routes require an active bearer token with the scopes:
+//
+//	router.Group(func(r chi.Router) {
+//		r = oidcchi.Protected(r, resourceServer, "read")
+//		r.Get("/items", listItems)
+//	})
+func Protected(router chi.Router, resourceServer rs.ResourceServer, scopes ...string) chi.Router {
+	router = router.With(rs.Middleware(resourceServer))
+	if len(scopes) > 0 {
+		router = router.With(rs.RequireScopes(scopes...))
+	}
+	return router
+}
+
+// Introspection returns the introspection response of the token of a [Protected] route.
+func Introspection(r *http.Request) (*oidc.IntrospectionResponse, bool) {
+	return rs.IntrospectionFromContext(r.Context())
+}
@@ -0,0 +1,54 @@
+package oidcchi_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/pkg/client/oidcchi"
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	httphelper "github.com/zitadel/oidc/v3/pkg/http"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op/optest"
+)
+
+func TestRelyingPartyRouter(t *testing.T) {
+	s := optest.New(t)
+	key := []byte("test1234test1234")
+	cookieHandler := httphelper.NewCookieHandler(key, key, httphelper.WithUnsecure())
+	relyingParty := s.RelyingParty(t, rp.WithCookieHandler(cookieHandler), rp.WithPKCE(cookieHandler))
+
+	router := chi.NewRouter()
+	router.Mount("/auth", oidcchi.RelyingPartyRouter[*oidc.IDTokenClaims](relyingParty, func() string { return "state" },
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokens, state, ok := oidcchi.Tokens[*oidc.IDTokenClaims](r)
+			require.True(t, ok)
+			w.Write([]byte(state + " " + tokens.IDTokenClaims.Subject))
+		})))
+	app := httptest.NewServer(router)
+	defer app.Close()
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	client := &http.Client{Jar: jar, CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(app.URL + "/auth" + oidcchi.LoginPath)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	callback := s.Authorize(t, resp.Header.Get("Location"))
+	require.Equal(t, "/auth"+oidcchi.CallbackPath, callback.Path)
+	resp, err = client.Get(app.URL + callback.Path + "?" + callback.RawQuery)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "state "+optest.UserID, string(body))
+}
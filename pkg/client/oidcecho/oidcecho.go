@@ -0,0 +1,63 @@
+// Package oidcecho mounts the relying party handlers of the rp package
+// and the token validation of the rs package on echo routers.
+//
+// The package is a separate module, github.com/zitadel/oidc/v3/pkg/client/oidcecho,
+// so echo is not a dependency of the oidc module.
+package oidcecho
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	"github.com/zitadel/oidc/v3/pkg/client/rs"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// Paths of the [RelyingParty] routes.
+const (
+	LoginPath    = "/login"
+	CallbackPath = "/callback"
+)
+
+// RelyingParty adds the login route, which redirects to the OP,
+// and the callback route, which exchanges the code and calls next with the tokens
+// in the request context (see [Tokens]), to the group of the redirect URI of the relying party:
+//
+//	oidcecho.RelyingParty[*oidc.IDTokenClaims](e.Group("/auth"), relyingParty, stateFn, next)
+//
+// for the redirect URI https://app.example.com/auth/callback.
+func RelyingParty[C oidc.IDClaims](group *echo.Group, relyingParty rp.RelyingParty, stateFn func() string, next echo.HandlerFunc, urlParam ...rp.URLParamOpt) {
+	group.GET(LoginPath, echo.WrapHandler(rp.AuthURLHandler(stateFn, relyingParty, urlParam...)))
+	callback := echo.WrapMiddleware(func(next http.Handler) http.Handler {
+		return rp.CodeExchangeHandler(rp.ContextCallback[C](next), relyingParty, urlParam...)
+	})(next)
+	group.GET(CallbackPath, callback)
+	group.POST(CallbackPath, callback) // form_post response mode
+}
+
+// Tokens returns the tokens and state of the callback of the [RelyingParty] routes.
+func Tokens[C oidc.IDClaims](c echo.Context) (*oidc.Tokens[C], string, bool) {
+	return rp.TokensFromContext[C](c.Request().Context())
+}
+
+// Middleware returns a middleware, which only passes requests with an active bearer token
+// with the scopes to the next handler, see [rs.Middleware] and [rs.RequireScopes]:
+//
+//	api := e.Group("/api", oidcecho.Middleware(resourceServer, "read"))
+func Middleware(resourceServer rs.ResourceServer, scopes ...string) echo.MiddlewareFunc {
+	authorize := echo.WrapMiddleware(rs.Middleware(resourceServer))
+	if len(scopes) == 0 {
+		return authorize
+	}
+	requireScopes := echo.WrapMiddleware(rs.RequireScopes(scopes...))
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return authorize(requireScopes(next))
+	}
+}
+
+// Introspection returns the introspection response of the token of a request passed by the [Middleware].
+func Introspection(c echo.Context) (*oidc.IntrospectionResponse, bool) {
+	return rs.IntrospectionFromContext(c.Request().Context())
+}
@@ -0,0 +1,111 @@
+package oidcecho_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/pkg/client/oidcecho"
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	"github.com/zitadel/oidc/v3/pkg/client/rs"
+	httphelper "github.com/zitadel/oidc/v3/pkg/http"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op/optest"
+)
+
+func TestRelyingParty(t *testing.T) {
+	s := optest.New(t)
+	key := []byte("test1234test1234")
+	cookieHandler := httphelper.NewCookieHandler(key, key, httphelper.WithUnsecure())
+	relyingParty := s.RelyingParty(t, rp.WithCookieHandler(cookieHandler), rp.WithPKCE(cookieHandler))
+
+	e := echo.New()
+	oidcecho.RelyingParty[*oidc.IDTokenClaims](e.Group("/auth"), relyingParty, func() string { return "state" },
+		func(c echo.Context) error {
+			tokens, state, ok := oidcecho.Tokens[*oidc.IDTokenClaims](c)
+			require.True(t, ok)
+			return c.String(http.StatusOK, state+" "+tokens.IDTokenClaims.Subject)
+		})
+	app := httptest.NewServer(e)
+	defer app.Close()
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	client := &http.Client{Jar: jar, CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(app.URL + "/auth" + oidcecho.LoginPath)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	callback := s.Authorize(t, resp.Header.Get("Location"))
+	require.Equal(t, "/auth"+oidcecho.CallbackPath, callback.Path)
+	resp, err = client.Get(app.URL + callback.Path + "?" + callback.RawQuery)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "state "+optest.UserID, string(body))
+}
+
+func TestMiddleware(t *testing.T) {
+	introspection := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		w.Header().Set("Content-Type", "application/json")
+		switch r.PostForm.Get("token") {
+		case "active":
+			w.Write([]byte(`{"active":true,"sub":"user","scope":"read"}`))
+		case "outage":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(`{"active":false}`))
+		}
+	}))
+	defer introspection.Close()
+	resourceServer, err := rs.NewResourceServerClientCredentials(context.Background(), introspection.URL, "api", "secret",
+		rs.WithClient(introspection.Client()), rs.WithStaticEndpoints(introspection.URL+"/token", introspection.URL+"/introspect"))
+	require.NoError(t, err)
+
+	e := echo.New()
+	handler := func(c echo.Context) error {
+		resp, ok := oidcecho.Introspection(c)
+		require.True(t, ok)
+		return c.String(http.StatusOK, resp.Subject)
+	}
+	e.GET("/read", handler, oidcecho.Middleware(resourceServer, "read"))
+	e.GET("/write", handler, oidcecho.Middleware(resourceServer, "write"))
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		wantStatus    int
+	}{
+		{"active", "/read", "Bearer active", http.StatusOK},
+		{"missing", "/read", "", http.StatusUnauthorized},
+		{"inactive", "/read", "Bearer inactive", http.StatusUnauthorized},
+		{"introspection outage", "/read", "Bearer outage", http.StatusServiceUnavailable},
+		{"insufficient scope", "/write", "Bearer active", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, "user", w.Body.String())
+			}
+		})
+	}
+}
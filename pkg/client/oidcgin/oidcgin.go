@@ -0,0 +1,79 @@
+// Package oidcgin mounts the relying party handlers of the rp package
+// and the token validation of the rs package on gin routers.
+//
+// The package is a separate module, github.com/zitadel/oidc/v3/pkg/client/oidcgin,
+// so gin is not a dependency of the oidc module.
+package oidcgin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	"github.com/zitadel/oidc/v3/pkg/client/rs"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// Paths of the [RelyingParty] routes.
+const (
+	LoginPath    = "/login"
+	CallbackPath = "/callback"
+)
+
+// RelyingParty adds the login route, which redirects to the OP,
+// and the callback route, which exchanges the code and calls next with the tokens
+// in the request context (see [Tokens]), to the group of the redirect URI of the relying party:
+//
+//	oidcgin.RelyingParty[*oidc.IDTokenClaims](router.Group("/auth"), relyingParty, stateFn, next)
+//
+// for the redirect URI https://app.example.com/auth/callback.
+func RelyingParty[C oidc.IDClaims](router gin.IRoutes, relyingParty rp.RelyingParty, stateFn func() string, next gin.HandlerFunc, urlParam ...rp.URLParamOpt) {
+	router.GET(LoginPath, gin.WrapF(rp.AuthURLHandler(stateFn, relyingParty, urlParam...)))
+	callback := wrapMiddleware(func(next http.Handler) http.Handler {
+		return rp.CodeExchangeHandler(rp.ContextCallback[C](next), relyingParty, urlParam...)
+	})
+	router.GET(CallbackPath, callback, next)
+	router.POST(CallbackPath, callback, next) // form_post response mode
+}
+
+// Tokens returns the tokens and state of the callback of the [RelyingParty] routes.
+func Tokens[C oidc.IDClaims](c *gin.Context) (*oidc.Tokens[C], string, bool) {
+	return rp.TokensFromContext[C](c.Request.Context())
+}
+
+// Middleware returns a middleware, which only passes requests with an active bearer token
+// with the scopes to the next handlers, see [rs.Middleware] and [rs.RequireScopes]:
+//
+//	api := router.Group("/api", oidcgin.Middleware(resourceServer, "read"))
+func Middleware(resourceServer rs.ResourceServer, scopes ...string) gin.HandlerFunc {
+	authorize := rs.Middleware(resourceServer)
+	if len(scopes) == 0 {
+		return wrapMiddleware(authorize)
+	}
+	requireScopes := rs.RequireScopes(scopes...)
+	return wrapMiddleware(func(next http.Handler) http.Handler {
+		return authorize(requireScopes(next))
+	})
+}
+
+// Introspection returns the introspection response of the token of a request passed by the [Middleware].
+func Introspection(c *gin.Context) (*oidc.IntrospectionResponse, bool) {
+	return rs.IntrospectionFromContext(c.Request.Context())
+}
+
+// wrapMiddleware returns a handler calling the next handlers of the chain with the request
+// passed by the middleware, or aborting the chain if the middleware answered the request.
+func wrapMiddleware(middleware func(http.Handler) http.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		passed := false
+		middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			passed = true
+			c.Request = r
+			c.Next()
+		})).ServeHTTP(c.Writer, c.Request)
+		if !passed {
+			c.Abort()
+		}
+	}
+}
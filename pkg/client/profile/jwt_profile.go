@@ -3,33 +3,65 @@ package profile
 import (
 	"context"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	jose "github.com/go-jose/go-jose/v4"
 	"golang.org/x/oauth2"
 
+	"github.com/zitadel/oidc/v3/internal/singleflight"
 	"github.com/zitadel/oidc/v3/pkg/client"
 	"github.com/zitadel/oidc/v3/pkg/oidc"
 )
 
+const (
+	defaultAssertionLifetime = time.Hour
+	defaultRefreshBefore     = time.Minute
+)
+
 type TokenSource interface {
 	oauth2.TokenSource
 	TokenCtx(context.Context) (*oauth2.Token, error)
 }
 
+// ScopedTokenSource is a [TokenSource], which can also request tokens
+// for other scopes and audiences than the ones it was created with.
+// The tokens of all scopes and audiences are cached by the same source.
+type ScopedTokenSource interface {
+	TokenSource
+	// TokenFor returns a token for the scopes, requested with an assertion for the audience.
+	// Empty scopes or audience use the ones of the source.
+	TokenFor(ctx context.Context, scopes []string, audience []string) (*oauth2.Token, error)
+	// Scoped returns a TokenSource for the scopes and audience, sharing the cache of the source,
+	// e.g. to pass different scopes to different gRPC or http clients.
+	Scoped(scopes []string, audience []string) TokenSource
+}
+
 // jwtProfileTokenSource implement the oauth2.TokenSource
 // it will request a token using the OAuth2 JWT Profile Grant
 // therefore sending an `assertion` by signing a JWT with the provided private key
+//
+// Every token request is sent with a freshly signed assertion, so it has its own jti.
+// Tokens are reused until they expire and renewed refreshBefore their expiry.
+// It is safe for concurrent use, concurrent calls for the same scopes and audience
+// wait for a single token request, without blocking calls for other ones.
 type jwtProfileTokenSource struct {
-	clientID      string
-	audience      []string
-	signer        jose.Signer
-	scopes        []string
-	httpClient    *http.Client
-	tokenEndpoint string
+	clientID          string
+	audience          []string
+	signer            jose.Signer
+	scopes            []string
+	httpClient        *http.Client
+	tokenEndpoint     string
+	assertionLifetime time.Duration
+	refreshBefore     time.Duration
+
+	mu       sync.Mutex
+	tokens   map[string]*oauth2.Token
+	requests singleflight.Group[*oauth2.Token]
 }
 
-// NewJWTProfileTokenSourceFromKeyFile returns an implementation of TokenSource
+// NewJWTProfileTokenSourceFromKeyFile returns an implementation of ScopedTokenSource
 // It will request a token using the OAuth2 JWT Profile Grant,
 // therefore sending an `assertion` by singing a JWT with the provided private key from jsonFile.
 //
@@ -37,7 +69,7 @@ type jwtProfileTokenSource struct {
 //
 // Deprecated: use [github.com/zitadel/zitadel-go/v3/pkg/client.ConfigFromKeyFileData] instead.
 // The function will be removed in the next major release.
-func NewJWTProfileTokenSourceFromKeyFile(ctx context.Context, issuer, jsonFile string, scopes []string, options ...func(source *jwtProfileTokenSource)) (TokenSource, error) {
+func NewJWTProfileTokenSourceFromKeyFile(ctx context.Context, issuer, jsonFile string, scopes []string, options ...func(source *jwtProfileTokenSource)) (ScopedTokenSource, error) {
 	keyData, err := client.ConfigFromKeyFile(jsonFile)
 	if err != nil {
 		return nil, err
@@ -45,7 +77,7 @@ func NewJWTProfileTokenSourceFromKeyFile(ctx context.Context, issuer, jsonFile s
 	return NewJWTProfileTokenSource(ctx, issuer, keyData.UserID, keyData.KeyID, []byte(keyData.Key), scopes, options...)
 }
 
-// NewJWTProfileTokenSourceFromKeyFileData returns an implementation of oauth2.TokenSource
+// NewJWTProfileTokenSourceFromKeyFileData returns an implementation of ScopedTokenSource
 // It will request a token using the OAuth2 JWT Profile Grant,
 // therefore sending an `assertion` by singing a JWT with the provided private key in jsonData.
 //
@@ -53,7 +85,7 @@ func NewJWTProfileTokenSourceFromKeyFile(ctx context.Context, issuer, jsonFile s
 //
 // Deprecated: use [github.com/zitadel/zitadel-go/v3/pkg/client.ConfigFromKeyFileData] instead.
 // The function will be removed in the next major release.
-func NewJWTProfileTokenSourceFromKeyFileData(ctx context.Context, issuer string, jsonData []byte, scopes []string, options ...func(source *jwtProfileTokenSource)) (TokenSource, error) {
+func NewJWTProfileTokenSourceFromKeyFileData(ctx context.Context, issuer string, jsonData []byte, scopes []string, options ...func(source *jwtProfileTokenSource)) (ScopedTokenSource, error) {
 	keyData, err := client.ConfigFromKeyFileData(jsonData)
 	if err != nil {
 		return nil, err
@@ -65,18 +97,25 @@ func NewJWTProfileTokenSourceFromKeyFileData(ctx context.Context, issuer string,
 // It will request a token using the OAuth2 JWT Profile Grant,
 // therefore sending an `assertion` by singing a JWT with the provided private key.
 //
+// The source can be used wherever an oauth2.TokenSource is accepted,
+// e.g. oauth2.NewClient for http clients or as gRPC PerRPCCredentials
+// (google.golang.org/grpc/credentials/oauth.TokenSource).
+//
 // The passed context is only used for the call to the Discover endpoint.
-func NewJWTProfileTokenSource(ctx context.Context, issuer, clientID, keyID string, key []byte, scopes []string, options ...func(source *jwtProfileTokenSource)) (TokenSource, error) {
+func NewJWTProfileTokenSource(ctx context.Context, issuer, clientID, keyID string, key []byte, scopes []string, options ...func(source *jwtProfileTokenSource)) (ScopedTokenSource, error) {
 	signer, err := client.NewSignerFromPrivateKeyByte(key, keyID)
 	if err != nil {
 		return nil, err
 	}
 	source := &jwtProfileTokenSource{
-		clientID:   clientID,
-		audience:   []string{issuer},
-		signer:     signer,
-		scopes:     scopes,
-		httpClient: http.DefaultClient,
+		clientID:          clientID,
+		audience:          []string{issuer},
+		signer:            signer,
+		scopes:            scopes,
+		httpClient:        http.DefaultClient,
+		assertionLifetime: defaultAssertionLifetime,
+		refreshBefore:     defaultRefreshBefore,
+		tokens:            make(map[string]*oauth2.Token),
 	}
 	for _, opt := range options {
 		opt(source)
@@ -103,6 +142,29 @@ func WithStaticTokenEndpoint(issuer, tokenEndpoint string) func(source *jwtProfi
 	}
 }
 
+// WithAudience sets the audience of the assertions, which defaults to the issuer.
+func WithAudience(audience ...string) func(source *jwtProfileTokenSource) {
+	return func(source *jwtProfileTokenSource) {
+		source.audience = audience
+	}
+}
+
+// WithAssertionLifetime sets the lifetime of the signed assertions, which defaults to one hour.
+// Each assertion is only used for a single token request.
+func WithAssertionLifetime(lifetime time.Duration) func(source *jwtProfileTokenSource) {
+	return func(source *jwtProfileTokenSource) {
+		source.assertionLifetime = lifetime
+	}
+}
+
+// WithRefreshBefore sets how long before their expiry tokens are renewed,
+// which defaults to one minute, so requests don't fail with a token expiring in flight.
+func WithRefreshBefore(refreshBefore time.Duration) func(source *jwtProfileTokenSource) {
+	return func(source *jwtProfileTokenSource) {
+		source.refreshBefore = refreshBefore
+	}
+}
+
 func (j *jwtProfileTokenSource) TokenEndpoint() string {
 	return j.tokenEndpoint
 }
@@ -116,9 +178,71 @@ func (j *jwtProfileTokenSource) Token() (*oauth2.Token, error) {
 }
 
 func (j *jwtProfileTokenSource) TokenCtx(ctx context.Context) (*oauth2.Token, error) {
-	assertion, err := client.SignedJWTProfileAssertion(j.clientID, j.audience, time.Hour, j.signer)
-	if err != nil {
-		return nil, err
+	return j.TokenFor(ctx, nil, nil)
+}
+
+func (j *jwtProfileTokenSource) TokenFor(ctx context.Context, scopes []string, audience []string) (*oauth2.Token, error) {
+	if len(scopes) == 0 {
+		scopes = j.scopes
 	}
-	return client.JWTProfileExchange(ctx, oidc.NewJWTProfileGrantRequest(assertion, j.scopes...), j)
+	if len(audience) == 0 {
+		audience = j.audience
+	}
+	tokenKey := strings.Join(audience, " ") + "|" + strings.Join(scopes, " ")
+	if token := j.cachedToken(tokenKey); token != nil {
+		return token, nil
+	}
+	token, _, err := j.requests.Do(ctx, tokenKey, func(ctx context.Context) (*oauth2.Token, error) {
+		// the token might have been renewed by a request, which finished in the meantime
+		if token := j.cachedToken(tokenKey); token != nil {
+			return token, nil
+		}
+		assertion, err := client.SignedJWTProfileAssertion(j.clientID, audience, j.assertionLifetime, j.signer)
+		if err != nil {
+			return nil, err
+		}
+		token, err := client.JWTProfileExchange(ctx, oidc.NewJWTProfileGrantRequest(assertion, scopes...), j)
+		if err != nil {
+			return nil, err
+		}
+		j.mu.Lock()
+		j.tokens[tokenKey] = token
+		j.mu.Unlock()
+		return token, nil
+	})
+	return token, err
+}
+
+// cachedToken returns the token of the key, if it is still valid.
+func (j *jwtProfileTokenSource) cachedToken(tokenKey string) *oauth2.Token {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if token, ok := j.tokens[tokenKey]; ok && j.valid(token.Expiry) {
+		return token
+	}
+	return nil
+}
+
+func (j *jwtProfileTokenSource) Scoped(scopes []string, audience []string) TokenSource {
+	return &scopedTokenSource{source: j, scopes: scopes, audience: audience}
+}
+
+// valid reports whether the expiry is more than refreshBefore away.
+// A zero expiry never expires.
+func (j *jwtProfileTokenSource) valid(expiry time.Time) bool {
+	return expiry.IsZero() || time.Now().Add(j.refreshBefore).Before(expiry)
+}
+
+type scopedTokenSource struct {
+	source   *jwtProfileTokenSource
+	scopes   []string
+	audience []string
+}
+
+func (s *scopedTokenSource) Token() (*oauth2.Token, error) {
+	return s.TokenCtx(context.Background())
+}
+
+func (s *scopedTokenSource) TokenCtx(ctx context.Context) (*oauth2.Token, error) {
+	return s.source.TokenFor(ctx, s.scopes, s.audience)
 }
@@ -0,0 +1,130 @@
+package profile_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/zitadel/oidc/v3/pkg/client/profile"
+)
+
+type tokenServer struct {
+	// block delays the responses of the scope until it is closed
+	block      map[string]chan struct{}
+	mu         sync.Mutex
+	assertions []string
+	scopes     []string
+	expiresIn  int
+}
+
+func (s *tokenServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if block, ok := s.block[r.PostFormValue("scope")]; ok {
+		<-block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.assertions = append(s.assertions, r.PostFormValue("assertion"))
+	s.scopes = append(s.scopes, r.PostFormValue("scope"))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": r.PostFormValue("scope"),
+		"token_type":   "Bearer",
+		"expires_in":   s.expiresIn,
+	})
+}
+
+func newTokenSource(t *testing.T, expiresIn int) (profile.ScopedTokenSource, *tokenServer) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	ts := &tokenServer{expiresIn: expiresIn}
+	server := httptest.NewServer(ts)
+	t.Cleanup(server.Close)
+
+	source, err := profile.NewJWTProfileTokenSource(t.Context(), server.URL, "client", "key1", keyPEM, []string{"openid"},
+		profile.WithStaticTokenEndpoint(server.URL, server.URL),
+		profile.WithRefreshBefore(10*time.Second),
+	)
+	require.NoError(t, err)
+	return source, ts
+}
+
+func TestJWTProfileTokenSource_cache(t *testing.T) {
+	source, server := newTokenSource(t, 3600)
+
+	for range 3 {
+		token, err := source.Token()
+		require.NoError(t, err)
+		assert.Equal(t, "openid", token.AccessToken)
+	}
+	token, err := source.TokenFor(t.Context(), []string{"openid", "profile"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "openid profile", token.AccessToken)
+	token, err = source.Scoped([]string{"openid", "profile"}, nil).Token()
+	require.NoError(t, err)
+	assert.Equal(t, "openid profile", token.AccessToken)
+
+	assert.Equal(t, []string{"openid", "openid profile"}, server.scopes)
+	require.Len(t, server.assertions, 2)
+	assert.NotEqual(t, server.assertions[0], server.assertions[1], "fresh assertion")
+}
+
+func TestJWTProfileTokenSource_refreshBefore(t *testing.T) {
+	source, server := newTokenSource(t, 5)
+
+	_, err := source.Token()
+	require.NoError(t, err)
+	_, err = source.Token()
+	require.NoError(t, err)
+
+	require.Len(t, server.assertions, 2, "token expires within refreshBefore")
+	assert.NotEqual(t, server.assertions[0], server.assertions[1], "fresh assertion")
+}
+
+func TestJWTProfileTokenSource_oauth2(t *testing.T) {
+	source, server := newTokenSource(t, 3600)
+
+	ts := oauth2.ReuseTokenSource(nil, source)
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := ts.Token()
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, server.assertions, 1)
+}
+
+func TestJWTProfileTokenSource_concurrentScopes(t *testing.T) {
+	source, server := newTokenSource(t, 3600)
+	block := make(chan struct{})
+	server.block = map[string]chan struct{}{"slow": block}
+
+	slow := make(chan error)
+	go func() {
+		_, err := source.TokenFor(context.Background(), []string{"slow"}, nil)
+		slow <- err
+	}()
+	token, err := source.Token()
+	require.NoError(t, err, "not blocked by the request of the other scope")
+	assert.Equal(t, "openid", token.AccessToken)
+
+	close(block)
+	require.NoError(t, <-slow)
+}
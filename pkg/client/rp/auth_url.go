@@ -0,0 +1,156 @@
+package rp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/text/language"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// WithAuthURLParam sets a custom parameter in the auth request,
+// e.g. a parameter specific to the OP.
+func WithAuthURLParam(key, value string) AuthURLOpt {
+	return withURLParam(key, value)
+}
+
+// WithMaxAge sets the `max_age` param in the auth request,
+// the allowable elapsed time since the last active authentication of the user.
+// The duration is truncated to seconds.
+func WithMaxAge(maxAge time.Duration) AuthURLOpt {
+	return withURLParam("max_age", strconv.FormatInt(int64(maxAge/time.Second), 10))
+}
+
+// WithLoginHint sets the `login_hint` param in the auth request,
+// e.g. the email address or username of the user.
+func WithLoginHint(loginHint string) AuthURLOpt {
+	return withURLParam("login_hint", loginHint)
+}
+
+// WithIDTokenHint sets the `id_token_hint` param in the auth request
+// with a previously issued id_token of the user.
+func WithIDTokenHint(idToken string) AuthURLOpt {
+	return withURLParam("id_token_hint", idToken)
+}
+
+// WithACRValues sets the `acr_values` param in the auth request
+// with the requested authentication context class references in order of preference.
+func WithACRValues(acrValues ...string) AuthURLOpt {
+	return withURLParam("acr_values", oidc.SpaceDelimitedArray(acrValues).String())
+}
+
+// WithUILocales sets the `ui_locales` param in the auth request
+// with the preferred languages of the user interface in order of preference.
+func WithUILocales(locales ...language.Tag) AuthURLOpt {
+	return withURLParam("ui_locales", oidc.Locales(locales).String())
+}
+
+// WithDisplay sets the `display` param in the auth request.
+func WithDisplay(display oidc.Display) AuthURLOpt {
+	return withURLParam("display", string(display))
+}
+
+// WithResponseMode sets the `response_mode` param in the auth request.
+func WithResponseMode(mode oidc.ResponseMode) AuthURLOpt {
+	return withURLParam("response_mode", string(mode))
+}
+
+// WithResource sets the `resource` param (RFC 8707) in the auth request,
+// the absolute URI of the protected resource the access token is requested for.
+func WithResource(resource string) AuthURLOpt {
+	return withURLParam("resource", resource)
+}
+
+// WithClaimsRequest sets the `claims` param in the auth request.
+// The values of the claims must be encodable as JSON,
+// otherwise the param is invalid, which is reported by [ValidateAuthURLOpts].
+func WithClaimsRequest(claims *oidc.ClaimsRequest) AuthURLOpt {
+	return func() []oauth2.AuthCodeOption {
+		data, err := json.Marshal(claims)
+		if err != nil {
+			return []oauth2.AuthCodeOption{oauth2.SetAuthURLParam("claims", invalidClaimsRequest)}
+		}
+		return []oauth2.AuthCodeOption{oauth2.SetAuthURLParam("claims", string(data))}
+	}
+}
+
+// invalidClaimsRequest is set as claims param by [WithClaimsRequest]
+// if the claims can't be encoded.
+const invalidClaimsRequest = "invalid"
+
+var (
+	ErrPromptNoneExclusive  = errors.New("prompt none must not be combined with other values")
+	ErrRequestParamConflict = errors.New("request and request_uri must not be used together")
+	ErrInvalidClaimsRequest = errors.New("invalid claims: must be a JSON object")
+)
+
+// ValidateAuthURLOpts checks the combination of the auth request params set by the opts,
+// so invalid requests are detected before the user is redirected to the OP.
+// It is called by [ValidatedAuthURL], [RequestObjectAuthURL] and the handlers,
+// but not by [AuthURL].
+func ValidateAuthURLOpts(opts ...AuthURLOpt) error {
+	params := authURLParams(opts)
+	if prompt := params.Get("prompt"); prompt != "" {
+		prompts := strings.Fields(prompt)
+		if slices.Contains(prompts, oidc.PromptNone) && len(prompts) > 1 {
+			return ErrPromptNoneExclusive
+		}
+	}
+	if params.Has("request") && params.Has("request_uri") {
+		return ErrRequestParamConflict
+	}
+	if maxAge := params.Get("max_age"); maxAge != "" {
+		if _, err := strconv.ParseUint(maxAge, 10, 64); err != nil {
+			return fmt.Errorf("invalid max_age %q: must be a non negative number of seconds", maxAge)
+		}
+	}
+	if mode := oidc.ResponseMode(params.Get("response_mode")); mode != "" && !slices.Contains(supportedResponseModes, mode) {
+		return fmt.Errorf("invalid response_mode %q", mode)
+	}
+	if resource := params.Get("resource"); resource != "" {
+		u, err := url.Parse(resource)
+		if err != nil || !u.IsAbs() || u.Fragment != "" {
+			return fmt.Errorf("invalid resource %q: must be an absolute URI without fragment", resource)
+		}
+	}
+	if params.Has("claims") && !isJSONObject(params.Get("claims")) {
+		return ErrInvalidClaimsRequest
+	}
+	return nil
+}
+
+func isJSONObject(value string) bool {
+	var object map[string]json.RawMessage
+	return json.Unmarshal([]byte(value), &object) == nil && object != nil
+}
+
+var supportedResponseModes = []oidc.ResponseMode{
+	oidc.ResponseModeQuery,
+	oidc.ResponseModeFragment,
+	oidc.ResponseModeFormPost,
+	oidc.ResponseModeJWT,
+	oidc.ResponseModeQueryJWT,
+	oidc.ResponseModeFragmentJWT,
+	oidc.ResponseModeFormPostJWT,
+}
+
+// authURLParams returns the params set by the opts.
+func authURLParams[Opt ~func() []oauth2.AuthCodeOption](opts []Opt) url.Values {
+	var authOpts []oauth2.AuthCodeOption
+	for _, opt := range opts {
+		authOpts = append(authOpts, opt()...)
+	}
+	authURL, err := url.Parse((&oauth2.Config{}).AuthCodeURL("", authOpts...))
+	if err != nil {
+		return url.Values{}
+	}
+	return authURL.Query()
+}
@@ -0,0 +1,121 @@
+package rp
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"golang.org/x/text/language"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+func Test_authURLParams(t *testing.T) {
+	params := authURLParams([]AuthURLOpt{
+		WithPrompt(oidc.PromptLogin, oidc.PromptConsent),
+		WithMaxAge(90 * time.Second),
+		WithLoginHint("user@example.com"),
+		WithACRValues("urn:mace:incommon:iap:silver", "urn:mace:incommon:iap:bronze"),
+		WithUILocales(language.German, language.English),
+		WithResponseMode(oidc.ResponseModeFormPost),
+		WithResource("https://api.example.com"),
+		WithClaimsRequest(&oidc.ClaimsRequest{
+			IDToken: map[string]*oidc.ClaimRequest{
+				"email": {Essential: true},
+				"name":  nil,
+			},
+		}),
+		WithAuthURLParam("custom", "value"),
+	})
+	assert.Equal(t, "login consent", params.Get("prompt"))
+	assert.Equal(t, "90", params.Get("max_age"))
+	assert.Equal(t, "user@example.com", params.Get("login_hint"))
+	assert.Equal(t, "urn:mace:incommon:iap:silver urn:mace:incommon:iap:bronze", params.Get("acr_values"))
+	assert.Equal(t, "de en", params.Get("ui_locales"))
+	assert.Equal(t, "form_post", params.Get("response_mode"))
+	assert.Equal(t, "https://api.example.com", params.Get("resource"))
+	assert.JSONEq(t, `{"id_token":{"email":{"essential":true},"name":null}}`, params.Get("claims"))
+	assert.Equal(t, "value", params.Get("custom"))
+}
+
+func TestValidateAuthURLOpts(t *testing.T) {
+	errAny := errors.New("any error")
+	tests := []struct {
+		name    string
+		opts    []AuthURLOpt
+		wantErr error
+	}{
+		{
+			name: "valid",
+			opts: []AuthURLOpt{WithPrompt(oidc.PromptNone), WithMaxAge(0), WithResponseMode(oidc.ResponseModeQueryJWT)},
+		},
+		{
+			name:    "prompt none with login",
+			opts:    []AuthURLOpt{WithPrompt(oidc.PromptNone, oidc.PromptLogin)},
+			wantErr: ErrPromptNoneExclusive,
+		},
+		{
+			name:    "request and request_uri",
+			opts:    []AuthURLOpt{WithAuthURLParam("request", "jwt"), WithAuthURLParam("request_uri", "urn:example")},
+			wantErr: ErrRequestParamConflict,
+		},
+		{
+			name:    "negative max_age",
+			opts:    []AuthURLOpt{WithMaxAge(-time.Minute)},
+			wantErr: errAny,
+		},
+		{
+			name:    "unknown response_mode",
+			opts:    []AuthURLOpt{WithResponseMode("unknown")},
+			wantErr: errAny,
+		},
+		{
+			name:    "relative resource",
+			opts:    []AuthURLOpt{WithResource("/api")},
+			wantErr: errAny,
+		},
+		{
+			name:    "invalid claims",
+			opts:    []AuthURLOpt{WithAuthURLParam("claims", "{")},
+			wantErr: ErrInvalidClaimsRequest,
+		},
+		{
+			name:    "claims not an object",
+			opts:    []AuthURLOpt{WithAuthURLParam("claims", `["email"]`)},
+			wantErr: ErrInvalidClaimsRequest,
+		},
+		{
+			name: "claims not encodable",
+			opts: []AuthURLOpt{WithClaimsRequest(&oidc.ClaimsRequest{
+				UserInfo: map[string]*oidc.ClaimRequest{"email": {Value: func() {}}},
+			})},
+			wantErr: ErrInvalidClaimsRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAuthURLOpts(tt.opts...)
+			switch tt.wantErr {
+			case nil:
+				assert.NoError(t, err)
+			case errAny:
+				assert.Error(t, err)
+			default:
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatedAuthURL(t *testing.T) {
+	rp := &relyingParty{oauthConfig: &oauth2.Config{ClientID: "client", Endpoint: oauth2.Endpoint{AuthURL: "https://op.example.com/authorize"}}}
+
+	got, err := ValidatedAuthURL("state", rp, WithPrompt(oidc.PromptLogin))
+	assert.NoError(t, err)
+	assert.Equal(t, AuthURL("state", rp, WithPrompt(oidc.PromptLogin)), got)
+
+	_, err = ValidatedAuthURL("state", rp, WithPrompt(oidc.PromptNone, oidc.PromptLogin))
+	assert.ErrorIs(t, err, ErrPromptNoneExclusive)
+}
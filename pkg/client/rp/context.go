@@ -0,0 +1,40 @@
+package rp
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+type tokensKey struct{}
+
+type contextTokens struct {
+	tokens any
+	state  string
+}
+
+// ContextCallback returns a [CodeExchangeCallback], which passes the exchanged tokens and the state
+// in the request context to the next handler, e.g. of a router.
+// They are read with [TokensFromContext].
+func ContextCallback[C oidc.IDClaims](next http.Handler) CodeExchangeCallback[C] {
+	return func(w http.ResponseWriter, r *http.Request, tokens *oidc.Tokens[C], state string, _ RelyingParty) {
+		next.ServeHTTP(w, r.WithContext(ContextWithTokens(r.Context(), tokens, state)))
+	}
+}
+
+// ContextWithTokens returns a context carrying the tokens and the state of an authorization.
+func ContextWithTokens[C oidc.IDClaims](ctx context.Context, tokens *oidc.Tokens[C], state string) context.Context {
+	return context.WithValue(ctx, tokensKey{}, contextTokens{tokens: tokens, state: state})
+}
+
+// TokensFromContext returns the tokens and the state set by [ContextWithTokens] or [ContextCallback].
+// C must be the claims type of the exchange.
+func TokensFromContext[C oidc.IDClaims](ctx context.Context) (tokens *oidc.Tokens[C], state string, ok bool) {
+	v, ok := ctx.Value(tokensKey{}).(contextTokens)
+	if !ok {
+		return nil, "", false
+	}
+	tokens, ok = v.tokens.(*oidc.Tokens[C])
+	return tokens, v.state, ok
+}
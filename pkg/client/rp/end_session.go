@@ -0,0 +1,106 @@
+package rp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/zitadel/oidc/v3/pkg/client"
+)
+
+// logoutStateParam is the name of the cookie holding the state of the logout,
+// distinct from the state cookie of the login.
+const logoutStateParam = "logout_state"
+
+// EndSessionURL builds the URL of the RP-initiated logout at the end_session_endpoint
+// of the discovered metadata, see https://openid.net/specs/openid-connect-rpinitiated-1_0.html#RPLogout.
+// The id_token_hint should always be passed, as OPs may not redirect to the
+// postLogoutRedirect or even refuse the logout without it. The client_id is always set.
+// The state is only set together with the postLogoutRedirect, to which it is returned.
+// Additional params, e.g. logout_hint or ui_locales, can be set by the urlParam.
+func EndSessionURL(rp RelyingParty, idToken, state, postLogoutRedirect string, urlParam ...URLParamOpt) (string, error) {
+	endpoint := rp.GetEndSessionEndpoint()
+	if endpoint == "" {
+		return "", fmt.Errorf("end session %w", client.ErrEndpointNotSet)
+	}
+	endSessionURL, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	params := endSessionURL.Query()
+	extra := authURLParams(urlParam)
+	// set by the oauth2 package for the auth request
+	extra.Del("response_type")
+	for key, values := range extra {
+		params[key] = values
+	}
+	if idToken != "" {
+		params.Set("id_token_hint", idToken)
+	}
+	params.Set("client_id", rp.OAuthConfig().ClientID)
+	if postLogoutRedirect != "" {
+		params.Set("post_logout_redirect_uri", postLogoutRedirect)
+		if state != "" {
+			params.Set(stateParam, state)
+		}
+	}
+	endSessionURL.RawQuery = params.Encode()
+	return endSessionURL.String(), nil
+}
+
+// EndSessionURLHandler redirects the user agent to the [EndSessionURL] with the id_token
+// of the user returned by idToken. The state is stored in a cookie of the CookieHandler,
+// to be validated by the [PostLogoutHandler] served at the postLogoutRedirect,
+// so both require the rp to have a CookieHandler.
+func EndSessionURLHandler(stateFn func() string, idToken func(r *http.Request) string, rp RelyingParty, postLogoutRedirect string, urlParam ...URLParamOpt) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state := stateFn()
+		if rp.CookieHandler() == nil {
+			unauthorizedError(w, r, "failed to create state cookie: no cookie handler", state, rp)
+			return
+		}
+		if err := trySetCookie(r, w, logoutStateParam, state, rp); err != nil {
+			unauthorizedError(w, r, "failed to create state cookie: "+err.Error(), state, rp)
+			return
+		}
+		endSessionURL, err := EndSessionURL(rp, idToken(r), state, postLogoutRedirect, urlParam...)
+		if err != nil {
+			unauthorizedError(w, r, "failed to create end session request: "+err.Error(), state, rp)
+			return
+		}
+		http.Redirect(w, r, endSessionURL, http.StatusFound)
+	}
+}
+
+// PostLogoutCallback is called by the [PostLogoutHandler] after the state was validated
+// and the cookies were deleted, e.g. to redirect the user to the start page.
+type PostLogoutCallback func(w http.ResponseWriter, r *http.Request, state string, rp RelyingParty)
+
+// PostLogoutHandler serves the post_logout_redirect_uri the OP redirects to after the logout.
+// It validates the returned state against the cookie set by [EndSessionURLHandler]
+// and deletes the local cookies of the application by the CookieHandler,
+// e.g. the cookie holding the tokens or session of the user.
+// The cookies are deleted only if the state is valid, so the logout can't be forged cross-site.
+func PostLogoutHandler(callback PostLogoutCallback, rp RelyingParty, cookieNames ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state := r.FormValue(stateParam)
+		if rp.CookieHandler() == nil {
+			unauthorizedError(w, r, "failed to validate state: no cookie handler", state, rp)
+			return
+		}
+		expected, err := rp.CookieHandler().CheckCookie(r, logoutStateParam)
+		if err == nil && (state == "" || expected != state) {
+			err = errors.New(stateParam + " does not compare")
+		}
+		if err != nil {
+			unauthorizedError(w, r, "failed to validate state: "+err.Error(), state, rp)
+			return
+		}
+		rp.CookieHandler().DeleteCookie(w, logoutStateParam)
+		for _, name := range cookieNames {
+			rp.CookieHandler().DeleteCookie(w, name)
+		}
+		callback(w, r, state, rp)
+	}
+}
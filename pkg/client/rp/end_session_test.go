@@ -0,0 +1,106 @@
+package rp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/zitadel/oidc/v3/pkg/client"
+	httphelper "github.com/zitadel/oidc/v3/pkg/http"
+)
+
+func newEndSessionRP(t *testing.T, endSessionURL string) *relyingParty {
+	t.Helper()
+	cookieHandler := httphelper.NewCookieHandler([]byte("test1234test1234test1234test1234"), []byte("test1234test1234test1234test1234"), httphelper.WithUnsecure())
+	party, err := NewRelyingPartyOAuth(&oauth2.Config{ClientID: "client"}, WithCookieHandler(cookieHandler))
+	require.NoError(t, err)
+	rp := party.(*relyingParty)
+	rp.endpoints.EndSessionURL = endSessionURL
+	return rp
+}
+
+func TestEndSessionURL(t *testing.T) {
+	_, err := EndSessionURL(newEndSessionRP(t, ""), "id-token", "state", "https://app.example.com/logged-out")
+	assert.ErrorIs(t, err, client.ErrEndpointNotSet)
+
+	rp := newEndSessionRP(t, "https://op.example.com/end_session?tenant=1")
+	got, err := EndSessionURL(rp, "id-token", "state", "https://app.example.com/logged-out", WithURLParam("logout_hint", "user"))
+	require.NoError(t, err)
+	endSessionURL, err := url.Parse(got)
+	require.NoError(t, err)
+	assert.Equal(t, "op.example.com", endSessionURL.Host)
+	assert.Equal(t, url.Values{
+		"tenant":                   {"1"},
+		"id_token_hint":            {"id-token"},
+		"client_id":                {"client"},
+		"post_logout_redirect_uri": {"https://app.example.com/logged-out"},
+		"state":                    {"state"},
+		"logout_hint":              {"user"},
+	}, endSessionURL.Query())
+
+	got, err = EndSessionURL(rp, "", "state", "")
+	require.NoError(t, err)
+	assert.Equal(t, "https://op.example.com/end_session?client_id=client&tenant=1", got, "state requires a redirect")
+}
+
+func TestEndSessionURLHandler_PostLogoutHandler(t *testing.T) {
+	rp := newEndSessionRP(t, "https://op.example.com/end_session")
+	rp.unauthorizedHandler = func(w http.ResponseWriter, _ *http.Request, desc, _ string) {
+		http.Error(w, desc, http.StatusUnauthorized)
+	}
+
+	w := httptest.NewRecorder()
+	EndSessionURLHandler(func() string { return "state" }, func(*http.Request) string { return "id-token" }, rp, "https://app.example.com/logged-out")(
+		w, httptest.NewRequest(http.MethodGet, "/logout", nil))
+	require.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "id-token", location.Query().Get("id_token_hint"))
+	assert.Equal(t, "state", location.Query().Get("state"))
+	stateCookie := w.Result().Cookies()[0]
+	assert.Equal(t, "logout_state", stateCookie.Name)
+
+	var called bool
+	handler := PostLogoutHandler(func(w http.ResponseWriter, r *http.Request, state string, rp RelyingParty) {
+		called = true
+		assert.Equal(t, "state", state)
+	}, rp, "session")
+	postLogout := func(state string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/logged-out?state="+state, nil)
+		req.AddCookie(stateCookie)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	w = postLogout("forged")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.False(t, called)
+	assert.Empty(t, w.Result().Cookies())
+
+	w = postLogout("state")
+	assert.True(t, called)
+	deleted := make(map[string]int)
+	for _, cookie := range w.Result().Cookies() {
+		deleted[cookie.Name] = cookie.MaxAge
+	}
+	assert.Equal(t, map[string]int{"logout_state": -1, "session": -1}, deleted)
+}
+
+func TestEndSessionURLHandler_noCookieHandler(t *testing.T) {
+	rp := newEndSessionRP(t, "https://op.example.com/end_session")
+	rp.cookieHandler = nil
+	var unauthorized error
+	rp.unauthorizedHandler = func(_ http.ResponseWriter, _ *http.Request, desc, _ string) {
+		unauthorized = errors.New(desc)
+	}
+	EndSessionURLHandler(func() string { return "state" }, func(*http.Request) string { return "" }, rp, "https://app.example.com/logged-out")(
+		httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/logout", nil))
+	assert.Error(t, unauthorized)
+}
@@ -0,0 +1,97 @@
+package rp
+
+import (
+	"net/http"
+	"strings"
+
+	httphelper "github.com/zitadel/oidc/v3/pkg/http"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// Endpoint identifies an endpoint of the OP called by the RelyingParty.
+type Endpoint string
+
+const (
+	EndpointDiscovery           Endpoint = "discovery"
+	EndpointToken               Endpoint = "token"
+	EndpointUserinfo            Endpoint = "userinfo"
+	EndpointJWKS                Endpoint = "jwks"
+	EndpointIntrospection       Endpoint = "introspection"
+	EndpointRevocation          Endpoint = "revocation"
+	EndpointDeviceAuthorization Endpoint = "device_authorization"
+)
+
+// HTTPPolicy configures timeouts, retries and circuit breakers
+// of the outbound calls of the RelyingParty, see [httphelper.Policy].
+type HTTPPolicy struct {
+	// Default policy of all endpoints.
+	Default httphelper.Policy
+	// Endpoints overrides the Default policy per endpoint.
+	Endpoints map[Endpoint]httphelper.Policy
+}
+
+// WithHTTPPolicy wraps the transport of the http client with a [httphelper.PolicyTransport],
+// so transient failures of the OP are retried and a failing OP is not flooded with requests.
+// The endpoints are identified by their URLs from the discovery or the oauth2.Config.
+// Calls to other URLs, e.g. a custom discovery or JWKS URL, use the Default policy.
+//
+// The option is applied after all other options, so it can be combined with [WithHTTPClient].
+func WithHTTPPolicy(policy HTTPPolicy) Option {
+	return func(rp *relyingParty) error {
+		rp.httpPolicy = &policy
+		return nil
+	}
+}
+
+// applyHTTPPolicy wraps the http client with the policy of [WithHTTPPolicy].
+// The endpoint URLs are set by [relyingParty.setPolicyEndpoints], after the discovery.
+func (rp *relyingParty) applyHTTPPolicy() {
+	if rp.httpPolicy == nil {
+		return
+	}
+	if rp.issuer != "" {
+		discoveryURL := rp.DiscoveryEndpoint
+		if discoveryURL == "" {
+			discoveryURL = strings.TrimSuffix(rp.issuer, "/") + oidc.DiscoveryEndpoint
+		}
+		rp.policyEndpoints = map[string]Endpoint{discoveryURL: EndpointDiscovery}
+	}
+	httpClient := *rp.httpClient
+	httpClient.Transport = &httphelper.PolicyTransport{
+		Base:      rp.httpClient.Transport,
+		PolicyFor: rp.policyFor,
+	}
+	rp.httpClient = &httpClient
+}
+
+func (rp *relyingParty) setPolicyEndpoints() {
+	if rp.httpPolicy == nil {
+		return
+	}
+	if rp.policyEndpoints == nil {
+		rp.policyEndpoints = make(map[string]Endpoint)
+	}
+	for url, endpoint := range map[string]Endpoint{
+		rp.oauthConfig.Endpoint.TokenURL:    EndpointToken,
+		rp.endpoints.UserinfoURL:            EndpointUserinfo,
+		rp.endpoints.JKWsURL:                EndpointJWKS,
+		rp.endpoints.IntrospectURL:          EndpointIntrospection,
+		rp.endpoints.RevokeURL:              EndpointRevocation,
+		rp.endpoints.DeviceAuthorizationURL: EndpointDeviceAuthorization,
+	} {
+		if url != "" {
+			rp.policyEndpoints[url] = endpoint
+		}
+	}
+}
+
+func (rp *relyingParty) policyFor(req *http.Request) *httphelper.Policy {
+	url := *req.URL
+	url.RawQuery = ""
+	if endpoint, ok := rp.policyEndpoints[url.String()]; ok {
+		if policy, ok := rp.httpPolicy.Endpoints[endpoint]; ok {
+			return &policy
+		}
+	}
+	return &rp.httpPolicy.Default
+}
@@ -2,6 +2,7 @@ package rp
 
 import (
 	"context"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 
 	jose "github.com/go-jose/go-jose/v4"
 
+	"github.com/zitadel/oidc/v3/internal/singleflight"
 	"github.com/zitadel/oidc/v3/pkg/client"
 	httphelper "github.com/zitadel/oidc/v3/pkg/http"
 	"github.com/zitadel/oidc/v3/pkg/oidc"
@@ -35,55 +37,40 @@ func SkipRemoteCheck() func(set *remoteKeySet) {
 	}
 }
 
+// WithX509Chain only accepts keys of the remote key set with an X.509 certificate chain (x5c),
+// which is verified with the opts, e.g. to pin the keys to the CA of the opts.Roots.
+// Keys without or with an invalid chain are ignored.
+// Unless set, the opts.KeyUsages default to any usage.
+//
+// The public key of the first certificate must match the key,
+// which is validated when the key set is parsed.
+func WithX509Chain(opts x509.VerifyOptions) func(set *remoteKeySet) {
+	return func(set *remoteKeySet) {
+		if len(opts.KeyUsages) == 0 {
+			opts.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+		}
+		set.verifyChain = &opts
+	}
+}
+
 type remoteKeySet struct {
 	jwksURL         string
 	httpClient      *http.Client
 	defaultAlg      string
 	skipRemoteCheck bool
+	verifyChain     *x509.VerifyOptions
 
 	// guard all other fields
 	mu sync.Mutex
 
-	// inflight suppresses parallel execution of updateKeys and allows
+	// fetches suppresses parallel execution of updateKeys and allows
 	// multiple goroutines to wait for its result.
-	inflight *inflight
+	fetches singleflight.Group[[]jose.JSONWebKey]
 
 	// A set of cached keys and their expiry.
 	cachedKeys []jose.JSONWebKey
 }
 
-// inflight is used to wait on some in-flight request from multiple goroutines.
-type inflight struct {
-	doneCh chan struct{}
-
-	keys []jose.JSONWebKey
-	err  error
-}
-
-func newInflight() *inflight {
-	return &inflight{doneCh: make(chan struct{})}
-}
-
-// wait returns a channel that multiple goroutines can receive on. Once it returns
-// a value, the inflight request is done and result() can be inspected.
-func (i *inflight) wait() <-chan struct{} {
-	return i.doneCh
-}
-
-// done can only be called by a single goroutine. It records the result of the
-// inflight request and signals other goroutines that the result is safe to
-// inspect.
-func (i *inflight) done(keys []jose.JSONWebKey, err error) {
-	i.keys = keys
-	i.err = err
-	close(i.doneCh)
-}
-
-// result cannot be called until the wait() channel has returned a value.
-func (i *inflight) result() ([]jose.JSONWebKey, error) {
-	return i.keys, i.err
-}
-
 func (r *remoteKeySet) VerifySignature(ctx context.Context, jws *jose.JSONWebSignature) ([]byte, error) {
 	ctx, span := client.Tracer.Start(ctx, "VerifySignature")
 	defer span.End()
@@ -170,48 +157,25 @@ func (r *remoteKeySet) keysFromRemote(ctx context.Context) ([]jose.JSONWebKey, e
 	ctx, span := client.Tracer.Start(ctx, "keysFromRemote")
 	defer span.End()
 
-	// Need to lock to inspect the inflight request field.
-	r.mu.Lock()
-	// If there's not a current inflight request, create one.
-	if r.inflight == nil {
-		r.inflight = newInflight()
-
-		// This goroutine has exclusive ownership over the current inflight
-		// request. It releases the resource by nil'ing the inflight field
-		// once the goroutine is done.
-		go r.updateKeys(ctx)
-	}
-	inflight := r.inflight
-	r.mu.Unlock()
-
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	case <-inflight.wait():
-		return inflight.result()
-	}
+	// Concurrent callers share a single request, which is not canceled
+	// as long as one of them still waits for it.
+	keys, _, err := r.fetches.Do(ctx, "", r.updateKeys)
+	return keys, err
 }
 
-func (r *remoteKeySet) updateKeys(ctx context.Context) {
+// updateKeys fetches the remote keys and records them in the cache.
+func (r *remoteKeySet) updateKeys(ctx context.Context) ([]jose.JSONWebKey, error) {
 	ctx, span := client.Tracer.Start(ctx, "updateKeys")
 	defer span.End()
 
-	// Sync keys and finish inflight when that's done.
 	keys, err := r.fetchRemoteKeys(ctx)
-
-	r.inflight.done(keys, err)
-
-	// Lock to update the keys and indicate that there is no longer an
-	// inflight request.
+	if err != nil {
+		return nil, err
+	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
-
-	if err == nil {
-		r.cachedKeys = keys
-	}
-
-	// Free inflight so a different request can run.
-	r.inflight = nil
+	r.cachedKeys = keys
+	return keys, nil
 }
 
 func (r *remoteKeySet) fetchRemoteKeys(ctx context.Context) ([]jose.JSONWebKey, error) {
@@ -227,9 +191,31 @@ func (r *remoteKeySet) fetchRemoteKeys(ctx context.Context) ([]jose.JSONWebKey,
 	if err = httphelper.HttpRequest(r.httpClient, req, keySet); err != nil {
 		return nil, fmt.Errorf("oidc: failed to get keys: %v", err)
 	}
+	if r.verifyChain != nil {
+		return verifiedKeys(keySet.Keys, *r.verifyChain), nil
+	}
 	return keySet.Keys, nil
 }
 
+// verifiedKeys returns the keys with a certificate chain which is valid for the opts.
+func verifiedKeys(keys []jose.JSONWebKey, opts x509.VerifyOptions) []jose.JSONWebKey {
+	verified := make([]jose.JSONWebKey, 0, len(keys))
+	for _, key := range keys {
+		if len(key.Certificates) == 0 {
+			continue
+		}
+		opts.Intermediates = x509.NewCertPool()
+		for _, cert := range key.Certificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		if _, err := key.Certificates[0].Verify(opts); err != nil {
+			continue
+		}
+		verified = append(verified, key)
+	}
+	return verified
+}
+
 // jsonWebKeySet is an alias for jose.JSONWebKeySet which ignores unknown key types (kty)
 type jsonWebKeySet jose.JSONWebKeySet
 
@@ -1,11 +1,25 @@
 package rp
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-jose/go-jose/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
 )
 
 func TestJsonWebKeySet_UnmarshalJSON(t *testing.T) {
@@ -73,3 +87,91 @@ func TestJsonWebKeySet_UnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func newCertificateChain(t *testing.T) (*ecdsa.PrivateKey, []*x509.Certificate) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	leafDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "signing key"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}, ca, &key.PublicKey, caKey)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(leafDER)
+	require.NoError(t, err)
+	return key, []*x509.Certificate{leaf, ca}
+}
+
+func TestRemoteKeySet_WithX509Chain(t *testing.T) {
+	key, chain := newCertificateChain(t)
+	otherKey, otherChain := newCertificateChain(t)
+	plainKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	keySet := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &key.PublicKey, KeyID: "pinned", Algorithm: string(jose.ES256), Use: oidc.KeyUseSignature, Certificates: chain},
+		{Key: &otherKey.PublicKey, KeyID: "other", Algorithm: string(jose.ES256), Use: oidc.KeyUseSignature, Certificates: otherChain},
+		{Key: &plainKey.PublicKey, KeyID: "plain", Algorithm: string(jose.ES256), Use: oidc.KeyUseSignature},
+	}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewEncoder(w).Encode(keySet))
+	}))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(chain[1])
+	remote := NewRemoteKeySet(server.Client(), server.URL, WithX509Chain(x509.VerifyOptions{Roots: roots}))
+
+	tests := []struct {
+		name    string
+		key     *ecdsa.PrivateKey
+		keyID   string
+		wantErr bool
+	}{
+		{name: "pinned", key: key, keyID: "pinned"},
+		{name: "other CA", key: otherKey, keyID: "other", wantErr: true},
+		{name: "no certificate", key: plainKey, keyID: "plain", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := jose.NewSigner(jose.SigningKey{
+				Algorithm: jose.ES256,
+				Key:       &jose.JSONWebKey{Key: tt.key, KeyID: tt.keyID},
+			}, nil)
+			require.NoError(t, err)
+			signed, err := signer.Sign([]byte("payload"))
+			require.NoError(t, err)
+			compact, err := signed.CompactSerialize()
+			require.NoError(t, err)
+			jws, err := jose.ParseSigned(compact, []jose.SignatureAlgorithm{jose.ES256})
+			require.NoError(t, err)
+
+			payload, err := remote.VerifySignature(context.Background(), jws)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []byte("payload"), payload)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package rp
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -116,11 +117,17 @@ type relyingParty struct {
 	cookieHandler *httphelper.CookieHandler
 
 	oauthAuthStyle oauth2.AuthStyle
+	mtlsConfig     *tls.Config
+
+	httpPolicy      *HTTPPolicy
+	policyEndpoints map[string]Endpoint
 
 	errorHandler        func(http.ResponseWriter, *http.Request, string, string, string)
 	unauthorizedHandler func(http.ResponseWriter, *http.Request, string, string)
 	idTokenVerifier     *IDTokenVerifier
 	verifierOpts        []VerifierOption
+	keySetOpts          []func(*remoteKeySet)
+	decryptionKeys      *jose.JSONWebKeySet
 	signer              jose.Signer
 	logger              *slog.Logger
 }
@@ -171,7 +178,8 @@ func (rp *relyingParty) GetRevokeEndpoint() string {
 
 func (rp *relyingParty) IDTokenVerifier() *IDTokenVerifier {
 	if rp.idTokenVerifier == nil {
-		rp.idTokenVerifier = NewIDTokenVerifier(rp.issuer, rp.oauthConfig.ClientID, NewRemoteKeySet(rp.httpClient, rp.endpoints.JKWsURL), rp.verifierOpts...)
+		rp.idTokenVerifier = NewIDTokenVerifier(rp.issuer, rp.oauthConfig.ClientID, NewRemoteKeySet(rp.httpClient, rp.endpoints.JKWsURL, rp.keySetOpts...), rp.verifierOpts...)
+		rp.idTokenVerifier.DecryptionKeys = rp.decryptionKeys
 	}
 	return rp.idTokenVerifier
 }
@@ -224,6 +232,11 @@ func NewRelyingPartyOAuth(config *oauth2.Config, options ...Option) (RelyingPart
 			ErrInvalidOption,
 		)
 	}
+	if err := rp.applyMTLS(); err != nil {
+		return nil, err
+	}
+	rp.applyHTTPPolicy()
+	rp.setPolicyEndpoints()
 
 	rp.oauthConfig.Endpoint.AuthStyle = rp.oauthAuthStyle
 
@@ -258,16 +271,24 @@ func NewRelyingPartyOIDC(ctx context.Context, issuer, clientID, clientSecret, re
 			return nil, err
 		}
 	}
+	if err := rp.applyMTLS(); err != nil {
+		return nil, err
+	}
+	rp.applyHTTPPolicy()
 	discoveryConfiguration, err := client.Discover(ctx, rp.issuer, rp.httpClient, rp.DiscoveryEndpoint)
 	if err != nil {
 		return nil, err
 	}
+	if rp.mtlsConfig != nil {
+		discoveryConfiguration = client.MTLSEndpoints(discoveryConfiguration)
+	}
 	if rp.useSigningAlgsFromDiscovery {
 		rp.verifierOpts = append(rp.verifierOpts, WithSupportedSigningAlgorithms(discoveryConfiguration.IDTokenSigningAlgValuesSupported...))
 	}
 	endpoints := GetEndpoints(discoveryConfiguration)
 	rp.oauthConfig.Endpoint = endpoints.Endpoint
 	rp.endpoints = endpoints
+	rp.setPolicyEndpoints()
 
 	rp.oauthConfig.Endpoint.AuthStyle = rp.oauthAuthStyle
 	rp.endpoints.Endpoint.AuthStyle = rp.oauthAuthStyle
@@ -294,6 +315,22 @@ func NewRelyingPartyOIDC(ctx context.Context, issuer, clientID, clientSecret, re
 	return rp, nil
 }
 
+// applyMTLS sets up the mutual-TLS client authentication of [WithMTLS].
+// The client is identified by its certificate, so no client_secret is sent.
+func (rp *relyingParty) applyMTLS() error {
+	if rp.mtlsConfig == nil {
+		return nil
+	}
+	httpClient, err := client.NewMTLSHTTPClient(rp.httpClient, rp.mtlsConfig)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidOption, err)
+	}
+	rp.httpClient = httpClient
+	rp.oauthConfig.ClientSecret = ""
+	rp.oauthAuthStyle = oauth2.AuthStyleInParams
+	return nil
+}
+
 // Option is the type for providing dynamic options to the relyingParty
 type Option func(*relyingParty) error
 
@@ -411,6 +448,43 @@ func WithSigningAlgsFromDiscovery() Option {
 	}
 }
 
+// WithMTLS authenticates the client with mutual-TLS (tls_client_auth or self_signed_tls_client_auth),
+// presenting the client certificate of the tls.Config on all requests to the OP.
+// https://datatracker.ietf.org/doc/html/rfc8705
+//
+// The client_secret is omitted and the client_id is sent in the request body.
+// The mtls_endpoint_aliases from the discovery are preferred over the conventional endpoints.
+// The option is applied after all other options, so it can be combined with [WithHTTPClient].
+func WithMTLS(tlsConfig *tls.Config) Option {
+	return func(rp *relyingParty) error {
+		if tlsConfig == nil || len(tlsConfig.Certificates) == 0 && tlsConfig.GetClientCertificate == nil {
+			return fmt.Errorf("%w: mTLS requires a client certificate", ErrInvalidOption)
+		}
+		rp.mtlsConfig = tlsConfig
+		return nil
+	}
+}
+
+// WithKeySetOpts sets the options of the remote key set of the OP,
+// e.g. [WithX509Chain] to only trust keys certified by a CA.
+func WithKeySetOpts(opts ...func(*remoteKeySet)) Option {
+	return func(rp *relyingParty) error {
+		rp.keySetOpts = append(rp.keySetOpts, opts...)
+		return nil
+	}
+}
+
+// WithDecryptionKeys sets the private keys of the client to decrypt
+// encrypted (JWE) ID tokens, userinfo and JARM responses before their signature is verified.
+// The keys are selected by the kid of the JWE header and must match
+// the id_token_encrypted_response_alg and userinfo_encrypted_response_alg of the client registration.
+func WithDecryptionKeys(keys jose.JSONWebKeySet) Option {
+	return func(rp *relyingParty) error {
+		rp.decryptionKeys = &keys
+		return nil
+	}
+}
+
 type SignerFromKey func() (jose.Signer, error)
 
 // Deprecated: use [SignerFromKeyAndKeyID] instead.
@@ -444,19 +518,39 @@ func SignerFromKeyAndKeyID(key []byte, keyID string) SignerFromKey {
 }
 
 // AuthURL returns the auth request url
-// (wrapping the oauth2 `AuthCodeURL`)
+// (wrapping the oauth2 `AuthCodeURL`).
+// The opts are not validated, see [ValidatedAuthURL].
 func AuthURL(state string, rp RelyingParty, opts ...AuthURLOpt) string {
+	return rp.OAuthConfig().AuthCodeURL(state, authCodeOptions(opts)...)
+}
+
+// ValidatedAuthURL returns the auth request url like [AuthURL],
+// after checking the opts with [ValidateAuthURLOpts].
+func ValidatedAuthURL(state string, rp RelyingParty, opts ...AuthURLOpt) (string, error) {
+	if err := ValidateAuthURLOpts(opts...); err != nil {
+		return "", err
+	}
+	return AuthURL(state, rp, opts...), nil
+}
+
+func authCodeOptions(opts []AuthURLOpt) []oauth2.AuthCodeOption {
 	authOpts := make([]oauth2.AuthCodeOption, 0)
 	for _, opt := range opts {
 		authOpts = append(authOpts, opt()...)
 	}
-	return rp.OAuthConfig().AuthCodeURL(state, authOpts...)
+	return authOpts
 }
 
 // AuthURLHandler extends the `AuthURL` method with an http redirect handler
 // including handling setting cookie for secure `state` transfer.
 // Custom parameters can optionally be set to the redirect URL.
 func AuthURLHandler(stateFn func() string, rp RelyingParty, urlParam ...URLParamOpt) http.HandlerFunc {
+	return authURLHandler(stateFn, rp, urlParam, func(state string, opts []AuthURLOpt) (string, error) {
+		return ValidatedAuthURL(state, rp, opts...)
+	})
+}
+
+func authURLHandler(stateFn func() string, rp RelyingParty, urlParam []URLParamOpt, authURL func(state string, opts []AuthURLOpt) (string, error)) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		opts := make([]AuthURLOpt, len(urlParam))
 		for i, p := range urlParam {
@@ -484,7 +578,12 @@ func AuthURLHandler(stateFn func() string, rp RelyingParty, urlParam ...URLParam
 			opts = append(opts, WithCodeChallenge(codeChallenge))
 		}
 
-		http.Redirect(w, r, AuthURL(state, rp, opts...), http.StatusFound)
+		redirectURL, err := authURL(state, opts)
+		if err != nil {
+			unauthorizedError(w, r, "failed to create auth request: "+err.Error(), state, rp)
+			return
+		}
+		http.Redirect(w, r, redirectURL, http.StatusFound)
 	}
 }
 
@@ -579,21 +678,45 @@ type CodeExchangeCallback[C oidc.IDClaims] func(w http.ResponseWriter, r *http.R
 // including cookie handling for secure `state` transfer
 // and optional PKCE code verifier checking.
 // Custom parameters can optionally be set to the token URL.
+//
+// The authorization response is read according to the response_mode of the urlParam
+// (see [WithResponseModeURLParam]), which should be the same as passed to the [AuthURLHandler].
+// form_post responses are read from the POST body and JARM responses (query.jwt, form_post.jwt and jwt)
+// are verified with the keys of the OP. Without response_mode, they are detected from the request.
+// As form_post responses are cross-site POST requests, the cookies of the CookieHandler
+// must be set with [httphelper.WithSameSite](http.SameSiteNoneMode).
+// An id_token returned along with the code by the hybrid flow is verified with [VerifyAuthResponseTokens],
+// including the c_hash of the code and the at_hash of an access token, and must belong to the same user
+// as the id_token of the token response.
 func CodeExchangeHandler[C oidc.IDClaims](callback CodeExchangeCallback[C], rp RelyingParty, urlParam ...URLParamOpt) http.HandlerFunc {
+	responseMode := responseModeFromURLParams(urlParam)
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx, span := client.Tracer.Start(r.Context(), "CodeExchangeHandler")
 		r = r.WithContext(ctx)
 		defer span.End()
 
-		state, err := tryReadStateCookie(w, r, rp)
+		response, err := parseAuthorizationResponse(r, rp, responseMode)
+		if err != nil {
+			unauthorizedError(w, r, "failed to parse authorization response: "+err.Error(), "", rp)
+			return
+		}
+		state, err := tryReadStateCookie(w, r, rp, response.state)
 		if err != nil {
 			unauthorizedError(w, r, "failed to get state: "+err.Error(), state, rp)
 			return
 		}
-		if errValue := r.FormValue("error"); errValue != "" {
-			rp.ErrorHandler()(w, r, errValue, r.FormValue("error_description"), state)
+		if response.error != "" {
+			rp.ErrorHandler()(w, r, response.error, response.errorDescription, state)
 			return
 		}
+		var authResponseClaims C
+		if response.idToken != "" {
+			authResponseClaims, err = VerifyAuthResponseTokens[C](r.Context(), response.code, response.accessToken, response.idToken, rp.IDTokenVerifier())
+			if err != nil {
+				unauthorizedError(w, r, "failed to verify id_token of the authorization response: "+err.Error(), state, rp)
+				return
+			}
+		}
 		codeOpts := make([]CodeExchangeOpt, len(urlParam))
 		for i, p := range urlParam {
 			codeOpts[i] = CodeExchangeOpt(p)
@@ -616,15 +739,31 @@ func CodeExchangeHandler[C oidc.IDClaims](callback CodeExchangeCallback[C], rp R
 			}
 			codeOpts = append(codeOpts, WithClientAssertionJWT(assertion))
 		}
-		tokens, err := CodeExchange[C](r.Context(), r.FormValue("code"), rp, codeOpts...)
+		tokens, err := CodeExchange[C](r.Context(), response.code, rp, codeOpts...)
 		if err != nil {
 			unauthorizedError(w, r, "failed to exchange token: "+err.Error(), state, rp)
 			return
 		}
+		if response.idToken != "" {
+			if err = verifyHybridIDTokens(authResponseClaims, tokens.IDTokenClaims); err != nil {
+				unauthorizedError(w, r, "failed to exchange token: "+err.Error(), state, rp)
+				return
+			}
+		}
 		callback(w, r, tokens, state, rp)
 	}
 }
 
+// verifyHybridIDTokens checks that the id_token of the token response is issued for the same
+// user as the id_token of the authorization response of the hybrid flow,
+// https://openid.net/specs/openid-connect-core-1_0.html#HybridTokenResponse
+func verifyHybridIDTokens[C oidc.IDClaims](authResponse, tokenResponse C) error {
+	if authResponse.GetIssuer() != tokenResponse.GetIssuer() || authResponse.GetSubject() != tokenResponse.GetSubject() {
+		return errors.New("id_token of the token response does not match the id_token of the authorization response")
+	}
+	return nil
+}
+
 type SubjectGetter interface {
 	GetSubject() string
 }
@@ -665,7 +804,7 @@ func Userinfo[U SubjectGetter](ctx context.Context, token, tokenType, subject st
 		return nilU, err
 	}
 	req.Header.Set("authorization", tokenType+" "+token)
-	if err := httphelper.HttpRequest(rp.HttpClient(), req, &userinfo); err != nil {
+	if err := userinfoRequest(ctx, rp, req, &userinfo); err != nil {
 		return nilU, err
 	}
 	if userinfo.GetSubject() != subject {
@@ -675,12 +814,16 @@ func Userinfo[U SubjectGetter](ctx context.Context, token, tokenType, subject st
 }
 
 func trySetStateCookie(r *http.Request, w http.ResponseWriter, state string, rp RelyingParty) error {
+	return trySetCookie(r, w, stateParam, state, rp)
+}
+
+func trySetCookie(r *http.Request, w http.ResponseWriter, name, value string, rp RelyingParty) error {
 	if rp.CookieHandler() != nil {
 		var err error
 		if rp.CookieHandler().IsRequestAware() {
-			err = rp.CookieHandler().SetRequestAwareCookie(r, w, stateParam, state)
+			err = rp.CookieHandler().SetRequestAwareCookie(r, w, name, value)
 		} else {
-			err = rp.CookieHandler().SetCookie(w, stateParam, state)
+			err = rp.CookieHandler().SetCookie(w, name, value)
 		}
 
 		if err != nil {
@@ -690,14 +833,17 @@ func trySetStateCookie(r *http.Request, w http.ResponseWriter, state string, rp
 	return nil
 }
 
-func tryReadStateCookie(w http.ResponseWriter, r *http.Request, rp RelyingParty) (state string, err error) {
+func tryReadStateCookie(w http.ResponseWriter, r *http.Request, rp RelyingParty, responseState string) (state string, err error) {
 	if rp.CookieHandler() == nil {
-		return r.FormValue(stateParam), nil
+		return responseState, nil
 	}
-	state, err = rp.CookieHandler().CheckQueryCookie(r, stateParam)
+	state, err = rp.CookieHandler().CheckCookie(r, stateParam)
 	if err != nil {
 		return "", err
 	}
+	if state != responseState {
+		return "", errors.New(stateParam + " does not compare")
+	}
 	rp.CookieHandler().DeleteCookie(w, stateParam)
 	return state, nil
 }
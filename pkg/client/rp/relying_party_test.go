@@ -2,20 +2,30 @@ package rp
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
 	"errors"
 	"log/slog"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
+	jose "github.com/go-jose/go-jose/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/oauth2"
 
 	tu "github.com/zitadel/oidc/v3/internal/testutil"
+	httphelper "github.com/zitadel/oidc/v3/pkg/http"
 	"github.com/zitadel/oidc/v3/pkg/oidc"
 )
 
@@ -213,3 +223,266 @@ func Test_Oauth2OnlyRPWithPKCEFromDiscovery(t *testing.T) {
 		t.Fatal("RP should be nil when calling 'WithPKCEFromDiscovery' on an OAuth2 only relying party")
 	}
 }
+
+func selfSignedClientCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestWithMTLS(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case oidc.DiscoveryEndpoint:
+			json.NewEncoder(w).Encode(&oidc.DiscoveryConfiguration{
+				Issuer:        "https://" + r.Host,
+				TokenEndpoint: "https://" + r.Host + "/token",
+				MTLSEndpointAliases: &oidc.MTLSEndpointAliases{
+					TokenEndpoint: "https://" + r.Host + "/mtls/token",
+				},
+			})
+		case "/mtls/token":
+			if len(r.TLS.PeerCertificates) == 0 {
+				http.Error(w, "no client certificate", http.StatusUnauthorized)
+				return
+			}
+			_, _, basicAuth := r.BasicAuth()
+			if basicAuth || r.PostFormValue("client_secret") != "" || r.PostFormValue("client_id") != "client" {
+				http.Error(w, "unexpected client authentication", http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token":"mtls","token_type":"Bearer"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(server.Certificate())
+
+	_, err := NewRelyingPartyOIDC(t.Context(), server.URL, "client", "", "", nil, WithMTLS(&tls.Config{RootCAs: rootCAs}))
+	require.ErrorIs(t, err, ErrInvalidOption, "no client certificate")
+
+	rp, err := NewRelyingPartyOIDC(t.Context(), server.URL, "client", "secret", "", nil,
+		WithMTLS(&tls.Config{RootCAs: rootCAs, Certificates: []tls.Certificate{selfSignedClientCertificate(t)}}),
+		WithAuthStyle(oauth2.AuthStyleInHeader),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/mtls/token", rp.OAuthConfig().Endpoint.TokenURL)
+	assert.Empty(t, rp.OAuthConfig().ClientSecret)
+
+	token, err := ClientCredentials(t.Context(), rp, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "mtls", token.AccessToken)
+}
+
+func TestWithHTTPPolicy(t *testing.T) {
+	var discoveryCalls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		discoveryCalls++
+		if discoveryCalls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(&oidc.DiscoveryConfiguration{
+			Issuer:           "http://" + r.Host,
+			TokenEndpoint:    "http://" + r.Host + "/token",
+			UserinfoEndpoint: "http://" + r.Host + "/userinfo",
+		})
+	}))
+	defer server.Close()
+
+	rp, err := NewRelyingPartyOIDC(t.Context(), server.URL, "client", "secret", "", nil,
+		WithHTTPPolicy(HTTPPolicy{
+			Default: httphelper.Policy{MaxRetries: 1, Backoff: time.Millisecond},
+			Endpoints: map[Endpoint]httphelper.Policy{
+				EndpointUserinfo: {Timeout: time.Second},
+			},
+		}),
+	)
+	require.NoError(t, err, "discovery retried")
+	assert.Equal(t, 2, discoveryCalls)
+
+	policyFor := rp.(*relyingParty).policyFor
+	req := httptest.NewRequest(http.MethodGet, server.URL+"/userinfo?schema=openid", nil)
+	assert.Equal(t, &httphelper.Policy{Timeout: time.Second}, policyFor(req))
+	req = httptest.NewRequest(http.MethodPost, server.URL+"/token", nil)
+	assert.Equal(t, 1, policyFor(req).MaxRetries)
+}
+
+func Test_parseAuthorizationResponse(t *testing.T) {
+	rp := &relyingParty{
+		idTokenVerifier: NewIDTokenVerifier("https://op.example.com", "client", tu.KeySet{}),
+	}
+	jarm := func(claims *oidc.JARMResponseClaims) string {
+		payload, err := json.Marshal(claims)
+		require.NoError(t, err)
+		object, err := tu.Signer.Sign(payload)
+		require.NoError(t, err)
+		token, err := object.CompactSerialize()
+		require.NoError(t, err)
+		return token
+	}
+	validJARM := jarm(&oidc.JARMResponseClaims{
+		TokenClaims: oidc.TokenClaims{
+			Issuer:     "https://op.example.com",
+			Audience:   []string{"client"},
+			Expiration: oidc.FromTime(time.Now().Add(time.Minute)),
+		},
+		Code:  "jarm-code",
+		State: "jarm-state",
+	})
+	otherAudience := jarm(&oidc.JARMResponseClaims{
+		TokenClaims: oidc.TokenClaims{
+			Issuer:     "https://op.example.com",
+			Audience:   []string{"other"},
+			Expiration: oidc.FromTime(time.Now().Add(time.Minute)),
+		},
+		Code: "jarm-code",
+	})
+	post := func(query, body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/callback?"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req
+	}
+
+	tests := []struct {
+		name    string
+		req     *http.Request
+		mode    oidc.ResponseMode
+		want    *authorizationResponse
+		wantErr bool
+	}{
+		{
+			name: "query",
+			req:  httptest.NewRequest(http.MethodGet, "/callback?code=abc&state=xyz", nil),
+			want: &authorizationResponse{code: "abc", state: "xyz"},
+		},
+		{
+			name: "query error",
+			req:  httptest.NewRequest(http.MethodGet, "/callback?error=access_denied&error_description=denied&state=xyz", nil),
+			mode: oidc.ResponseModeQuery,
+			want: &authorizationResponse{state: "xyz", error: "access_denied", errorDescription: "denied"},
+		},
+		{
+			name: "form_post detected",
+			req:  post("", "code=abc&state=xyz"),
+			want: &authorizationResponse{code: "abc", state: "xyz"},
+		},
+		{
+			name: "form_post ignores query",
+			req:  post("code=query", "code=abc&state=xyz"),
+			mode: oidc.ResponseModeFormPost,
+			want: &authorizationResponse{code: "abc", state: "xyz"},
+		},
+		{
+			name:    "form_post requires POST",
+			req:     httptest.NewRequest(http.MethodGet, "/callback?code=abc&state=xyz", nil),
+			mode:    oidc.ResponseModeFormPost,
+			wantErr: true,
+		},
+		{
+			name:    "fragment",
+			req:     httptest.NewRequest(http.MethodGet, "/callback", nil),
+			mode:    oidc.ResponseModeFragment,
+			wantErr: true,
+		},
+		{
+			name: "jarm detected",
+			req:  httptest.NewRequest(http.MethodGet, "/callback?response="+validJARM, nil),
+			want: &authorizationResponse{code: "jarm-code", state: "jarm-state"},
+		},
+		{
+			name: "form_post.jwt",
+			req:  post("", "response="+validJARM),
+			mode: oidc.ResponseModeFormPostJWT,
+			want: &authorizationResponse{code: "jarm-code", state: "jarm-state"},
+		},
+		{
+			name:    "jarm missing",
+			req:     httptest.NewRequest(http.MethodGet, "/callback?code=abc", nil),
+			mode:    oidc.ResponseModeQueryJWT,
+			wantErr: true,
+		},
+		{
+			name:    "jarm audience",
+			req:     httptest.NewRequest(http.MethodGet, "/callback?response="+otherAudience, nil),
+			mode:    oidc.ResponseModeJWT,
+			wantErr: true,
+		},
+		{
+			name:    "jarm signature",
+			req:     httptest.NewRequest(http.MethodGet, "/callback?response="+validJARM[:len(validJARM)-4]+"AAAA", nil),
+			mode:    oidc.ResponseModeJWT,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAuthorizationResponse(tt.req, rp, tt.mode)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_responseModeFromURLParams(t *testing.T) {
+	assert.Equal(t, oidc.ResponseMode(""), responseModeFromURLParams(nil))
+	assert.Equal(t, oidc.ResponseModeFormPost, responseModeFromURLParams([]URLParamOpt{
+		WithURLParam("custom", "param"),
+		WithResponseModeURLParam(oidc.ResponseModeFormPost),
+	}))
+}
+
+func TestUserinfo_jwt(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	payload, err := json.Marshal(map[string]any{"sub": "user1", "email": "user1@example.com"})
+	require.NoError(t, err)
+	signed, err := tu.Signer.Sign(payload)
+	require.NoError(t, err)
+	token, err := signed.CompactSerialize()
+	require.NoError(t, err)
+	encrypted := encryptToken(t, token, key)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/jwt")
+		w.Write([]byte(encrypted))
+	}))
+	defer server.Close()
+
+	rp := &relyingParty{
+		httpClient:     server.Client(),
+		endpoints:      Endpoints{UserinfoURL: server.URL},
+		oauthConfig:    &oauth2.Config{ClientID: "client"},
+		decryptionKeys: &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: key, KeyID: "enc"}}},
+		verifierOpts:   []VerifierOption{WithSupportedSigningAlgorithms(string(tu.SignatureAlgorithm))},
+	}
+	rp.idTokenVerifier = rp.IDTokenVerifier()
+	rp.idTokenVerifier.KeySet = tu.KeySet{}
+
+	info, err := Userinfo[*oidc.UserInfo](t.Context(), "access", "Bearer", "user1", rp)
+	require.NoError(t, err)
+	assert.Equal(t, "user1@example.com", info.Email)
+
+	rp.idTokenVerifier.DecryptionKeys = nil
+	_, err = Userinfo[*oidc.UserInfo](t.Context(), "access", "Bearer", "user1", rp)
+	require.ErrorIs(t, err, oidc.ErrDecryption)
+}
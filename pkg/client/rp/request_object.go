@@ -0,0 +1,186 @@
+package rp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/google/uuid"
+
+	"github.com/zitadel/oidc/v3/pkg/crypto"
+)
+
+// DefaultRequestObjectLifetime is the lifetime of request objects,
+// unless set by [WithRequestObjectLifetime].
+const DefaultRequestObjectLifetime = 5 * time.Minute
+
+type requestObjectConfig struct {
+	signer     jose.Signer
+	lifetime   time.Duration
+	encryption *jose.Recipient
+	enc        jose.ContentEncryption
+}
+
+// RequestObjectOpt configures the request objects built by [SignedRequestObject].
+type RequestObjectOpt func(*requestObjectConfig)
+
+// WithRequestObjectSigner sets the signer of the request objects.
+// By default, the signer of [WithJWTProfile] is used.
+func WithRequestObjectSigner(signer jose.Signer) RequestObjectOpt {
+	return func(c *requestObjectConfig) {
+		c.signer = signer
+	}
+}
+
+// WithRequestObjectLifetime sets the duration the request objects are valid for,
+// [DefaultRequestObjectLifetime] by default.
+func WithRequestObjectLifetime(lifetime time.Duration) RequestObjectOpt {
+	return func(c *requestObjectConfig) {
+		c.lifetime = lifetime
+	}
+}
+
+// WithRequestObjectEncryption encrypts the signed request objects to the public key of the OP,
+// which must match its request_object_encryption_alg_values_supported and
+// request_object_encryption_enc_values_supported.
+func WithRequestObjectEncryption(key jose.JSONWebKey, alg jose.KeyAlgorithm, enc jose.ContentEncryption) RequestObjectOpt {
+	return func(c *requestObjectConfig) {
+		c.encryption = &jose.Recipient{
+			Algorithm: alg,
+			Key:       key.Key,
+			KeyID:     key.KeyID,
+		}
+		c.enc = enc
+	}
+}
+
+var ErrRequestObjectSigner = errors.New("request object: signer missing, use WithJWTProfile or WithRequestObjectSigner")
+
+// SignedRequestObject builds a request object (RFC 9101) containing all params of the auth request,
+// as sent by [AuthURL] with the same state and opts.
+// It is signed by the client and optionally encrypted, see [RequestObjectOpt].
+// Besides the params, it contains the iss (client_id), aud (issuer of the OP), iat, nbf, exp and jti claims.
+func SignedRequestObject(state string, rp RelyingParty, opts []AuthURLOpt, requestOpts ...RequestObjectOpt) (string, error) {
+	return signRequestObject(authRequestParams(state, rp, opts), rp, requestOpts)
+}
+
+// signRequestObject builds the request object of [SignedRequestObject] of the params.
+func signRequestObject(params url.Values, rp RelyingParty, requestOpts []RequestObjectOpt) (string, error) {
+	config := requestObjectConfig{
+		signer:   rp.Signer(),
+		lifetime: DefaultRequestObjectLifetime,
+	}
+	for _, opt := range requestOpts {
+		opt(&config)
+	}
+	if config.signer == nil {
+		return "", ErrRequestObjectSigner
+	}
+
+	claims, err := requestObjectClaims(params)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	claims["iss"] = rp.OAuthConfig().ClientID
+	claims["aud"] = rp.Issuer()
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Unix()
+	claims["exp"] = now.Add(config.lifetime).Unix()
+	claims["jti"] = uuid.NewString()
+
+	requestObject, err := crypto.Sign(claims, config.signer)
+	if err != nil {
+		return "", err
+	}
+	if config.encryption == nil {
+		return requestObject, nil
+	}
+	encrypter, err := jose.NewEncrypter(config.enc, *config.encryption, (&jose.EncrypterOptions{}).WithContentType("JWT"))
+	if err != nil {
+		return "", err
+	}
+	encrypted, err := encrypter.Encrypt([]byte(requestObject))
+	if err != nil {
+		return "", err
+	}
+	return encrypted.CompactSerialize()
+}
+
+// RequestObjectAuthURL returns the auth request url passing the params in a request object
+// built by [SignedRequestObject], so they don't appear in the query of the redirect.
+// Only the client_id, response_type and scope of the request object are additionally sent as query params,
+// as required by OpenID Connect Core 1.0 section 6.1.
+func RequestObjectAuthURL(state string, rp RelyingParty, opts []AuthURLOpt, requestOpts ...RequestObjectOpt) (string, error) {
+	if err := ValidateAuthURLOpts(opts...); err != nil {
+		return "", err
+	}
+	authParams := authRequestParams(state, rp, opts)
+	requestObject, err := signRequestObject(authParams, rp, requestOpts)
+	if err != nil {
+		return "", err
+	}
+	config := rp.OAuthConfig()
+	params := url.Values{
+		"client_id":     {config.ClientID},
+		"response_type": {authParams.Get("response_type")},
+		"request":       {requestObject},
+	}
+	if scope := authParams.Get("scope"); scope != "" {
+		params.Set("scope", scope)
+	}
+	if strings.Contains(config.Endpoint.AuthURL, "?") {
+		return config.Endpoint.AuthURL + "&" + params.Encode(), nil
+	}
+	return config.Endpoint.AuthURL + "?" + params.Encode(), nil
+}
+
+// RequestObjectAuthURLHandler is the [AuthURLHandler] sending the params in a request object,
+// see [RequestObjectAuthURL].
+func RequestObjectAuthURLHandler(stateFn func() string, rp RelyingParty, requestOpts []RequestObjectOpt, urlParam ...URLParamOpt) http.HandlerFunc {
+	return authURLHandler(stateFn, rp, urlParam, func(state string, opts []AuthURLOpt) (string, error) {
+		return RequestObjectAuthURL(state, rp, opts, requestOpts...)
+	})
+}
+
+// authRequestParams returns all params of the auth request of [AuthURL].
+func authRequestParams(state string, rp RelyingParty, opts []AuthURLOpt) url.Values {
+	authOpts := authCodeOptions(opts)
+	authURL, err := url.Parse(rp.OAuthConfig().AuthCodeURL(state, authOpts...))
+	if err != nil {
+		return url.Values{}
+	}
+	return authURL.Query()
+}
+
+// requestObjectClaims converts the params to claims.
+// The claims and max_age params are JSON values, all others are strings.
+func requestObjectClaims(params url.Values) (map[string]any, error) {
+	claims := make(map[string]any, len(params)+6)
+	for key := range params {
+		value := params.Get(key)
+		switch key {
+		case "request", "request_uri":
+			continue
+		case "claims":
+			if !json.Valid([]byte(value)) {
+				return nil, errors.New("request object: invalid claims param")
+			}
+			claims[key] = json.RawMessage(value)
+		case "max_age":
+			maxAge, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return nil, errors.New("request object: invalid max_age param")
+			}
+			claims[key] = maxAge
+		default:
+			claims[key] = value
+		}
+	}
+	return claims, nil
+}
@@ -0,0 +1,133 @@
+package rp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+func requestObjectRP(signer jose.Signer) *relyingParty {
+	return &relyingParty{
+		issuer: "https://op.example.com",
+		oauthConfig: &oauth2.Config{
+			ClientID:    "client",
+			RedirectURL: "https://rp.example.com/callback",
+			Scopes:      []string{oidc.ScopeOpenID, oidc.ScopeEmail},
+			Endpoint: oauth2.Endpoint{
+				AuthURL: "https://op.example.com/authorize",
+			},
+		},
+		signer: signer,
+	}
+}
+
+func TestSignedRequestObject(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, nil)
+	require.NoError(t, err)
+	rp := requestObjectRP(signer)
+
+	requestObject, err := SignedRequestObject("state", rp, []AuthURLOpt{
+		WithMaxAge(time.Minute),
+		WithLoginHint("user@example.com"),
+		WithClaimsRequest(&oidc.ClaimsRequest{
+			IDToken: map[string]*oidc.ClaimRequest{"email": {Essential: true}},
+		}),
+		WithAuthURLParam("request_uri", "urn:example"),
+	}, WithRequestObjectLifetime(time.Minute))
+	require.NoError(t, err)
+
+	jws, err := jose.ParseSigned(requestObject, []jose.SignatureAlgorithm{jose.ES256})
+	require.NoError(t, err)
+	payload, err := jws.Verify(&key.PublicKey)
+	require.NoError(t, err)
+	var claims map[string]any
+	require.NoError(t, json.Unmarshal(payload, &claims))
+
+	assert.Equal(t, "client", claims["iss"])
+	assert.Equal(t, "https://op.example.com", claims["aud"])
+	assert.Equal(t, "client", claims["client_id"])
+	assert.Equal(t, "code", claims["response_type"])
+	assert.Equal(t, "openid email", claims["scope"])
+	assert.Equal(t, "https://rp.example.com/callback", claims["redirect_uri"])
+	assert.Equal(t, "state", claims["state"])
+	assert.Equal(t, "user@example.com", claims["login_hint"])
+	assert.Equal(t, float64(60), claims["max_age"])
+	assert.Equal(t, map[string]any{"id_token": map[string]any{"email": map[string]any{"essential": true}}}, claims["claims"])
+	assert.NotContains(t, claims, "request_uri")
+	assert.NotEmpty(t, claims["jti"])
+	assert.Equal(t, claims["iat"], claims["nbf"])
+	assert.Equal(t, claims["iat"].(float64)+60, claims["exp"])
+}
+
+func TestSignedRequestObject_encrypted(t *testing.T) {
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: signingKey}, nil)
+	require.NoError(t, err)
+	opKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	requestObject, err := SignedRequestObject("state", requestObjectRP(nil), nil,
+		WithRequestObjectSigner(signer),
+		WithRequestObjectEncryption(jose.JSONWebKey{Key: &opKey.PublicKey, KeyID: "op"}, jose.RSA_OAEP_256, jose.A256GCM),
+	)
+	require.NoError(t, err)
+
+	jwe, err := jose.ParseEncrypted(requestObject, []jose.KeyAlgorithm{jose.RSA_OAEP_256}, []jose.ContentEncryption{jose.A256GCM})
+	require.NoError(t, err)
+	assert.Equal(t, "op", jwe.Header.KeyID)
+	assert.Equal(t, "JWT", jwe.Header.ExtraHeaders[jose.HeaderContentType])
+	nested, err := jwe.Decrypt(opKey)
+	require.NoError(t, err)
+	jws, err := jose.ParseSigned(string(nested), []jose.SignatureAlgorithm{jose.ES256})
+	require.NoError(t, err)
+	_, err = jws.Verify(&signingKey.PublicKey)
+	require.NoError(t, err)
+}
+
+func TestSignedRequestObject_noSigner(t *testing.T) {
+	_, err := SignedRequestObject("state", requestObjectRP(nil), nil)
+	assert.ErrorIs(t, err, ErrRequestObjectSigner)
+}
+
+func TestRequestObjectAuthURL(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, nil)
+	require.NoError(t, err)
+
+	authURL, err := RequestObjectAuthURL("state", requestObjectRP(signer), []AuthURLOpt{WithLoginHint("user@example.com")})
+	require.NoError(t, err)
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, "https://op.example.com/authorize", u.Scheme+"://"+u.Host+u.Path)
+
+	params := u.Query()
+	assert.Equal(t, "client", params.Get("client_id"))
+	assert.Equal(t, "code", params.Get("response_type"))
+	assert.Equal(t, "openid email", params.Get("scope"))
+	assert.NotEmpty(t, params.Get("request"))
+	assert.False(t, params.Has("state"))
+	assert.False(t, params.Has("login_hint"))
+	assert.False(t, params.Has("redirect_uri"))
+
+	authURL, err = RequestObjectAuthURL("state", requestObjectRP(signer), []AuthURLOpt{WithAuthURLParam("response_type", "code id_token")})
+	require.NoError(t, err)
+	u, err = url.Parse(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, "code id_token", u.Query().Get("response_type"), "response_type of the request object")
+}
@@ -0,0 +1,122 @@
+package rp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/zitadel/oidc/v3/pkg/client"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// authorizationResponse holds the parameters of the authorization response
+// received by the [CodeExchangeHandler].
+type authorizationResponse struct {
+	code string
+	// idToken and accessToken are returned by the hybrid flow
+	// along with the code.
+	idToken          string
+	accessToken      string
+	state            string
+	error            string
+	errorDescription string
+}
+
+// VerifyJARMResponse verifies the `response` JWT of a JWT Secured Authorization Response (JARM),
+// which must be signed by the OP and issued to the client of the verifier.
+// https://openid.net/specs/oauth-v2-jarm.html#section-4.4
+func VerifyJARMResponse(ctx context.Context, response string, v *IDTokenVerifier) (*oidc.JARMResponseClaims, error) {
+	ctx, span := client.Tracer.Start(ctx, "VerifyJARMResponse")
+	defer span.End()
+
+	claims := new(oidc.JARMResponseClaims)
+	decrypted, err := oidc.DecryptTokenWithKeys(response, v.DecryptionKeys)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := oidc.ParseToken(decrypted, claims)
+	if err != nil {
+		return nil, err
+	}
+	if err = oidc.CheckIssuer(claims, v.Issuer); err != nil {
+		return nil, err
+	}
+	if err = oidc.CheckAudience(claims, v.ClientID); err != nil {
+		return nil, err
+	}
+	if err = oidc.CheckSignature(ctx, decrypted, payload, claims, v.SupportedSignAlgs, v.KeySet); err != nil {
+		return nil, err
+	}
+	if err = oidc.CheckExpiration(claims, v.Offset); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// responseModeFromURLParams returns the response_mode set by the urlParam,
+// e.g. with [WithResponseModeURLParam].
+func responseModeFromURLParams(urlParam []URLParamOpt) oidc.ResponseMode {
+	return oidc.ResponseMode(authURLParams(urlParam).Get("response_mode"))
+}
+
+// parseAuthorizationResponse reads the authorization response of the response mode.
+// Without response mode, form_post is detected by the POST method
+// and JARM by the `response` parameter.
+func parseAuthorizationResponse(r *http.Request, rp RelyingParty, mode oidc.ResponseMode) (*authorizationResponse, error) {
+	if mode == "" {
+		if r.Method == http.MethodPost {
+			mode = oidc.ResponseModeFormPost
+		}
+		if r.FormValue("response") != "" && r.FormValue("code") == "" && r.FormValue("error") == "" {
+			mode = oidc.ResponseModeJWT
+		}
+	}
+	var params url.Values
+	switch mode {
+	case oidc.ResponseModeFragment, oidc.ResponseModeFragmentJWT:
+		return nil, fmt.Errorf("response_mode %s cannot be received by the server", mode)
+	case oidc.ResponseModeFormPost, oidc.ResponseModeFormPostJWT:
+		if r.Method != http.MethodPost {
+			return nil, fmt.Errorf("response_mode %s requires a POST request", mode)
+		}
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+		params = r.PostForm
+	case oidc.ResponseModeQuery, oidc.ResponseModeQueryJWT:
+		params = r.URL.Query()
+	default:
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+		params = r.Form
+	}
+	if !mode.IsJWT() {
+		return &authorizationResponse{
+			code:             params.Get("code"),
+			idToken:          params.Get("id_token"),
+			accessToken:      params.Get("access_token"),
+			state:            params.Get(stateParam),
+			error:            params.Get("error"),
+			errorDescription: params.Get("error_description"),
+		}, nil
+	}
+	response := params.Get("response")
+	if response == "" {
+		return nil, errors.New("response parameter of JARM missing")
+	}
+	claims, err := VerifyJARMResponse(r.Context(), response, rp.IDTokenVerifier())
+	if err != nil {
+		return nil, err
+	}
+	return &authorizationResponse{
+		code:             claims.Code,
+		idToken:          claims.IDToken,
+		accessToken:      claims.AccessToken,
+		state:            claims.State,
+		error:            claims.Error,
+		errorDescription: claims.ErrorDescription,
+	}, nil
+}
@@ -0,0 +1,400 @@
+// Package rptest provides a fake OpenID Provider and a fake [rp.RelyingParty]
+// for unit tests of login handlers and middleware.
+//
+// The [Provider] answers the requests of a relying party in memory,
+// using an [http.RoundTripper], so no network or real keys are needed.
+// Responses of the token and userinfo endpoint are scripted by the test:
+//
+//	p := rptest.NewProvider(t, "https://op.example.com")
+//	relyingParty := p.RelyingParty("client", "https://app.example.com/callback", oidc.ScopeOpenID)
+//	p.RespondToken(p.TokenResponse(t, p.IDTokenClaims("client", "user1")))
+//	p.RespondUserinfo(&oidc.UserInfo{Subject: "user1"})
+//
+//	handler := rp.CodeExchangeHandler(rp.UserinfoCallback(callback), relyingParty)
+//	handler.ServeHTTP(rec, rptest.CallbackRequest(t, relyingParty, "code", "state"))
+package rptest
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	"github.com/zitadel/oidc/v3/pkg/crypto"
+	httphelper "github.com/zitadel/oidc/v3/pkg/http"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// Paths of the endpoints of the [Provider], relative to the issuer.
+const (
+	DiscoveryPath           = oidc.DiscoveryEndpoint
+	AuthorizationPath       = "/authorize"
+	TokenPath               = "/oauth/token"
+	UserinfoPath            = "/userinfo"
+	KeysPath                = "/keys"
+	RevocationPath          = "/revoke"
+	EndSessionPath          = "/end_session"
+	DeviceAuthorizationPath = "/device_authorization"
+)
+
+// Provider is a fake OpenID Provider.
+// It serves the discovery, keys and revocation endpoint
+// and the scripted responses of the token and userinfo endpoint.
+// All requests to other hosts fail.
+type Provider struct {
+	// Issuer of the provider, as passed to [NewProvider].
+	Issuer string
+	// Key signs the tokens of the provider.
+	Key jose.JSONWebKey
+
+	mu       sync.Mutex
+	token    []response
+	userinfo []response
+	requests []*http.Request
+}
+
+type response struct {
+	status int
+	body   any
+}
+
+// NewProvider creates a fake OP for the issuer,
+// with a newly generated RSA signing key.
+func NewProvider(tb testing.TB, issuer string) *Provider {
+	tb.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(tb, err)
+	return &Provider{
+		Issuer: strings.TrimSuffix(issuer, "/"),
+		Key: jose.JSONWebKey{
+			Key:       key,
+			KeyID:     uuid.NewString(),
+			Algorithm: string(jose.RS256),
+			Use:       oidc.KeyUseSignature,
+		},
+	}
+}
+
+// Client returns a http client sending all requests to the provider.
+func (p *Provider) Client() *http.Client {
+	return &http.Client{Transport: p}
+}
+
+// Discovery returns the configuration served on the discovery endpoint.
+func (p *Provider) Discovery() *oidc.DiscoveryConfiguration {
+	return &oidc.DiscoveryConfiguration{
+		Issuer:                            p.Issuer,
+		AuthorizationEndpoint:             p.Issuer + AuthorizationPath,
+		TokenEndpoint:                     p.Issuer + TokenPath,
+		UserinfoEndpoint:                  p.Issuer + UserinfoPath,
+		JwksURI:                           p.Issuer + KeysPath,
+		RevocationEndpoint:                p.Issuer + RevocationPath,
+		EndSessionEndpoint:                p.Issuer + EndSessionPath,
+		DeviceAuthorizationEndpoint:       p.Issuer + DeviceAuthorizationPath,
+		ScopesSupported:                   []string{oidc.ScopeOpenID, oidc.ScopeProfile, oidc.ScopeEmail, oidc.ScopeOfflineAccess},
+		ResponseTypesSupported:            []string{string(oidc.ResponseTypeCode)},
+		GrantTypesSupported:               []oidc.GrantType{oidc.GrantTypeCode, oidc.GrantTypeRefreshToken},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{p.Key.Algorithm},
+		TokenEndpointAuthMethodsSupported: []oidc.AuthMethod{oidc.AuthMethodBasic, oidc.AuthMethodPost},
+		CodeChallengeMethodsSupported:     []oidc.CodeChallengeMethod{oidc.CodeChallengeMethodS256},
+	}
+}
+
+// KeySet returns a key set verifying the signatures of the provider,
+// without calling the keys endpoint.
+func (p *Provider) KeySet() oidc.KeySet {
+	return keySet{p.Key.Public()}
+}
+
+type keySet struct {
+	key jose.JSONWebKey
+}
+
+func (k keySet) VerifySignature(_ context.Context, jws *jose.JSONWebSignature) ([]byte, error) {
+	return jws.Verify(k.key)
+}
+
+// RespondToken scripts the next response of the token endpoint.
+// Scripted responses are used once, in order. Without one,
+// the token endpoint responds with an invalid_grant error.
+func (p *Provider) RespondToken(resp *oidc.AccessTokenResponse) {
+	p.respond(&p.token, http.StatusOK, resp)
+}
+
+// RespondTokenError scripts an error as next response of the token endpoint.
+func (p *Provider) RespondTokenError(err *oidc.Error) {
+	p.respond(&p.token, http.StatusBadRequest, err)
+}
+
+// RespondUserinfo scripts the next response of the userinfo endpoint.
+// Scripted responses are used once, in order. Without one,
+// the userinfo endpoint responds with status 401.
+func (p *Provider) RespondUserinfo(info *oidc.UserInfo) {
+	p.respond(&p.userinfo, http.StatusOK, info)
+}
+
+// RespondUserinfoError scripts an error status as next response of the userinfo endpoint.
+func (p *Provider) RespondUserinfoError(status int) {
+	p.respond(&p.userinfo, status, nil)
+}
+
+func (p *Provider) respond(queue *[]response, status int, body any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	*queue = append(*queue, response{status: status, body: body})
+}
+
+func (p *Provider) next(queue *[]response, fallback response) response {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(*queue) == 0 {
+		return fallback
+	}
+	resp := (*queue)[0]
+	*queue = (*queue)[1:]
+	return resp
+}
+
+// Requests returns the requests received by the provider, in order.
+// Form values of the requests are already parsed into [http.Request.PostForm].
+func (p *Provider) Requests() []*http.Request {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*http.Request(nil), p.requests...)
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (p *Provider) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := *req.URL
+	endpoint.RawQuery = ""
+	if !strings.HasPrefix(endpoint.String(), p.Issuer+"/") {
+		return nil, fmt.Errorf("rptest: unexpected request to %s", req.URL)
+	}
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+	req = req.Clone(req.Context())
+	if err := req.ParseForm(); err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.requests = append(p.requests, req)
+	p.mu.Unlock()
+
+	var resp response
+	switch strings.TrimPrefix(endpoint.String(), p.Issuer) {
+	case DiscoveryPath:
+		resp = response{http.StatusOK, p.Discovery()}
+	case KeysPath:
+		resp = response{http.StatusOK, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{p.Key.Public()}}}
+	case RevocationPath:
+		resp = response{status: http.StatusOK}
+	case TokenPath:
+		resp = p.next(&p.token, response{http.StatusBadRequest, oidc.ErrInvalidGrant().WithDescription("rptest: no token response scripted")})
+	case UserinfoPath:
+		resp = p.next(&p.userinfo, response{status: http.StatusUnauthorized})
+	default:
+		resp = response{status: http.StatusNotFound}
+	}
+	return resp.http(req)
+}
+
+func (r response) http(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	if r.body != nil {
+		rec.Header().Set("Content-Type", "application/json")
+		rec.WriteHeader(r.status)
+		if err := json.NewEncoder(rec).Encode(r.body); err != nil {
+			return nil, err
+		}
+	} else {
+		rec.WriteHeader(r.status)
+	}
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// IDTokenClaims returns valid claims of an ID token for the client and subject.
+// The token expires in an hour.
+func (p *Provider) IDTokenClaims(clientID, subject string) *oidc.IDTokenClaims {
+	now := time.Now()
+	return oidc.NewIDTokenClaims(p.Issuer, subject, nil, now.Add(time.Hour), now, "", "", nil, clientID, 0)
+}
+
+// SignIDToken signs the claims of an ID token with the key of the provider.
+func (p *Provider) SignIDToken(tb testing.TB, claims any) string {
+	tb.Helper()
+	return p.Sign(tb, oidc.TypeJWT, claims)
+}
+
+// Sign signs arbitrary claims with the key of the provider,
+// using typ as type header.
+func (p *Provider) Sign(tb testing.TB, typ string, claims any) string {
+	tb.Helper()
+	signer, err := crypto.NewCompactSigner(jose.SignatureAlgorithm(p.Key.Algorithm), p.Key.Key, p.Key.KeyID, typ)
+	require.NoError(tb, err)
+	token, err := signer.SignObject(claims)
+	require.NoError(tb, err)
+	return token
+}
+
+// TokenResponse returns a token response with a random bearer access token,
+// valid for an hour, and the claims signed as ID token.
+func (p *Provider) TokenResponse(tb testing.TB, claims *oidc.IDTokenClaims) *oidc.AccessTokenResponse {
+	tb.Helper()
+	return &oidc.AccessTokenResponse{
+		AccessToken: uuid.NewString(),
+		TokenType:   oidc.BearerToken,
+		ExpiresIn:   uint64(time.Hour / time.Second),
+		IDToken:     p.SignIDToken(tb, claims),
+	}
+}
+
+// RelyingParty creates a fake relying party of the provider,
+// with unsecure cookies using random keys.
+func (p *Provider) RelyingParty(clientID, redirectURI string, scopes ...string) *RelyingParty {
+	return &RelyingParty{
+		Config: &oauth2.Config{
+			ClientID:    clientID,
+			RedirectURL: redirectURI,
+			Scopes:      scopes,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:   p.Issuer + AuthorizationPath,
+				TokenURL:  p.Issuer + TokenPath,
+				AuthStyle: oauth2.AuthStyleInHeader,
+			},
+		},
+		IssuerURL:              p.Issuer,
+		Cookies:                httphelper.NewCookieHandler(randomKey(), randomKey(), httphelper.WithUnsecure()),
+		Client:                 p.Client(),
+		Verifier:               rp.NewIDTokenVerifier(p.Issuer, clientID, p.KeySet()),
+		UserinfoURL:            p.Issuer + UserinfoPath,
+		EndSessionURL:          p.Issuer + EndSessionPath,
+		RevokeURL:              p.Issuer + RevocationPath,
+		DeviceAuthorizationURL: p.Issuer + DeviceAuthorizationPath,
+	}
+}
+
+func randomKey() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
+
+var _ rp.RelyingParty = (*RelyingParty)(nil)
+
+// RelyingParty is a fake [rp.RelyingParty], which returns its fields.
+type RelyingParty struct {
+	Config                 *oauth2.Config
+	IssuerURL              string
+	PKCE                   bool
+	Cookies                *httphelper.CookieHandler
+	Client                 *http.Client
+	OAuth2Only             bool
+	JWTSigner              jose.Signer
+	Verifier               *rp.IDTokenVerifier
+	UserinfoURL            string
+	EndSessionURL          string
+	RevokeURL              string
+	DeviceAuthorizationURL string
+	// OnError is called on callback errors. Defaults to [rp.DefaultErrorHandler].
+	OnError rp.ErrorHandler
+}
+
+func (r *RelyingParty) OAuthConfig() *oauth2.Config {
+	return r.Config
+}
+
+func (r *RelyingParty) Issuer() string {
+	return r.IssuerURL
+}
+
+func (r *RelyingParty) IsPKCE() bool {
+	return r.PKCE
+}
+
+func (r *RelyingParty) CookieHandler() *httphelper.CookieHandler {
+	return r.Cookies
+}
+
+func (r *RelyingParty) HttpClient() *http.Client {
+	return r.Client
+}
+
+func (r *RelyingParty) IsOAuth2Only() bool {
+	return r.OAuth2Only
+}
+
+func (r *RelyingParty) Signer() jose.Signer {
+	return r.JWTSigner
+}
+
+func (r *RelyingParty) GetEndSessionEndpoint() string {
+	return r.EndSessionURL
+}
+
+func (r *RelyingParty) GetRevokeEndpoint() string {
+	return r.RevokeURL
+}
+
+func (r *RelyingParty) UserinfoEndpoint() string {
+	return r.UserinfoURL
+}
+
+func (r *RelyingParty) GetDeviceAuthorizationEndpoint() string {
+	return r.DeviceAuthorizationURL
+}
+
+func (r *RelyingParty) IDTokenVerifier() *rp.IDTokenVerifier {
+	return r.Verifier
+}
+
+func (r *RelyingParty) Logger(context.Context) (*slog.Logger, bool) {
+	return slog.Default(), false
+}
+
+func (r *RelyingParty) ErrorHandler() func(http.ResponseWriter, *http.Request, string, string, string) {
+	if r.OnError == nil {
+		return rp.DefaultErrorHandler
+	}
+	return r.OnError
+}
+
+// CallbackRequest creates the request of the OP redirecting back to the
+// redirect URI of relyingParty, with the code and state and the cookies
+// an [rp.AuthURLHandler] would have set before.
+func CallbackRequest(tb testing.TB, relyingParty rp.RelyingParty, code, state string) *http.Request {
+	tb.Helper()
+	query := url.Values{"code": {code}, "state": {state}}
+	req := httptest.NewRequest(http.MethodGet, relyingParty.OAuthConfig().RedirectURL+"?"+query.Encode(), nil)
+	if cookies := relyingParty.CookieHandler(); cookies != nil {
+		addCookie(tb, req, cookies, "state", state)
+		if relyingParty.IsPKCE() {
+			addCookie(tb, req, cookies, "pkce", "verifier")
+		}
+	}
+	return req
+}
+
+func addCookie(tb testing.TB, req *http.Request, cookies *httphelper.CookieHandler, name, value string) {
+	cookie, err := cookies.CreateCookie(name, value)
+	require.NoError(tb, err)
+	req.AddCookie(cookie)
+}
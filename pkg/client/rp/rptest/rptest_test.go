@@ -0,0 +1,175 @@
+package rptest_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	"github.com/zitadel/oidc/v3/pkg/client/rp/rptest"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+const (
+	issuer      = "https://op.example.com"
+	clientID    = "client"
+	redirectURI = "https://app.example.com/callback"
+)
+
+func TestCodeExchangeHandler(t *testing.T) {
+	p := rptest.NewProvider(t, issuer)
+	relyingParty := p.RelyingParty(clientID, redirectURI, oidc.ScopeOpenID)
+	p.RespondToken(p.TokenResponse(t, p.IDTokenClaims(clientID, "user1")))
+	p.RespondUserinfo(&oidc.UserInfo{Subject: "user1", UserInfoEmail: oidc.UserInfoEmail{Email: "user1@example.com"}})
+
+	var (
+		gotState string
+		gotInfo  *oidc.UserInfo
+	)
+	callback := func(w http.ResponseWriter, r *http.Request, tokens *oidc.Tokens[*oidc.IDTokenClaims], state string, _ rp.RelyingParty, info *oidc.UserInfo) {
+		gotState = state
+		gotInfo = info
+	}
+	handler := rp.CodeExchangeHandler(rp.UserinfoCallback(callback), relyingParty)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, rptest.CallbackRequest(t, relyingParty, "code1", "state1"))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "state1", gotState)
+	require.NotNil(t, gotInfo)
+	assert.Equal(t, "user1@example.com", gotInfo.Email)
+
+	requests := p.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, "code1", requests[0].PostForm.Get("code"))
+	assert.Equal(t, redirectURI, requests[0].PostForm.Get("redirect_uri"))
+	assert.Contains(t, requests[1].Header.Get("Authorization"), "Bearer ")
+}
+
+func TestCodeExchangeHandler_errors(t *testing.T) {
+	p := rptest.NewProvider(t, issuer)
+	relyingParty := p.RelyingParty(clientID, redirectURI, oidc.ScopeOpenID)
+	handler := rp.CodeExchangeHandler(func(w http.ResponseWriter, r *http.Request, tokens *oidc.Tokens[*oidc.IDTokenClaims], state string, _ rp.RelyingParty) {
+		t.Error("callback must not be called")
+	}, relyingParty)
+
+	tests := []struct {
+		name   string
+		script func()
+	}{
+		{
+			name:   "no response scripted",
+			script: func() {},
+		},
+		{
+			name: "token error",
+			script: func() {
+				p.RespondTokenError(oidc.ErrInvalidGrant().WithDescription("code expired"))
+			},
+		},
+		{
+			name: "wrong audience",
+			script: func() {
+				p.RespondToken(p.TokenResponse(t, p.IDTokenClaims("other", "user1")))
+			},
+		},
+		{
+			name: "foreign key",
+			script: func() {
+				other := rptest.NewProvider(t, issuer)
+				p.RespondToken(other.TokenResponse(t, other.IDTokenClaims(clientID, "user1")))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.script()
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, rptest.CallbackRequest(t, relyingParty, "code", "state"))
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		})
+	}
+}
+
+func TestProvider_RelyingPartyOIDC(t *testing.T) {
+	p := rptest.NewProvider(t, issuer)
+	relyingParty, err := rp.NewRelyingPartyOIDC(context.Background(), issuer, clientID, "secret", redirectURI, []string{oidc.ScopeOpenID},
+		rp.WithHTTPClient(p.Client()))
+	require.NoError(t, err)
+	assert.Equal(t, issuer+rptest.TokenPath, relyingParty.OAuthConfig().Endpoint.TokenURL)
+
+	claims := p.IDTokenClaims(clientID, "user1")
+	claims.Claims = map[string]any{"role": "admin"}
+	got, err := rp.VerifyIDToken[*oidc.IDTokenClaims](context.Background(), p.SignIDToken(t, claims), relyingParty.IDTokenVerifier())
+	require.NoError(t, err, "keys fetched from the provider")
+	assert.Equal(t, "admin", got.Claims["role"])
+
+	_, err = p.Client().Get("https://other.example.com/")
+	assert.ErrorContains(t, err, "unexpected request")
+}
+
+func TestCodeExchangeHandler_hybrid(t *testing.T) {
+	p := rptest.NewProvider(t, issuer)
+	relyingParty := p.RelyingParty(clientID, redirectURI, oidc.ScopeOpenID)
+	handler := rp.CodeExchangeHandler(func(w http.ResponseWriter, r *http.Request, tokens *oidc.Tokens[*oidc.IDTokenClaims], state string, _ rp.RelyingParty) {
+		w.WriteHeader(http.StatusOK)
+	}, relyingParty)
+	codeHash, err := oidc.ClaimHash("code1", jose.SignatureAlgorithm(p.Key.Algorithm))
+	require.NoError(t, err)
+	hybridRequest := func(claims *oidc.IDTokenClaims) *http.Request {
+		req := rptest.CallbackRequest(t, relyingParty, "code1", "state1")
+		query := req.URL.Query()
+		query.Set("id_token", p.SignIDToken(t, claims))
+		req.URL.RawQuery = query.Encode()
+		return req
+	}
+	withCodeHash := func(claims *oidc.IDTokenClaims, hash string) *oidc.IDTokenClaims {
+		claims.CodeHash = hash
+		return claims
+	}
+
+	tests := []struct {
+		name          string
+		authResponse  *oidc.IDTokenClaims
+		tokenResponse *oidc.IDTokenClaims
+		wantStatus    int
+	}{
+		{
+			name:          "valid",
+			authResponse:  withCodeHash(p.IDTokenClaims(clientID, "user1"), codeHash),
+			tokenResponse: p.IDTokenClaims(clientID, "user1"),
+			wantStatus:    http.StatusOK,
+		},
+		{
+			name:         "c_hash missing",
+			authResponse: p.IDTokenClaims(clientID, "user1"),
+			wantStatus:   http.StatusUnauthorized,
+		},
+		{
+			name:         "c_hash of another code",
+			authResponse: withCodeHash(p.IDTokenClaims(clientID, "user1"), "invalid"),
+			wantStatus:   http.StatusUnauthorized,
+		},
+		{
+			name:          "token response of another user",
+			authResponse:  withCodeHash(p.IDTokenClaims(clientID, "user1"), codeHash),
+			tokenResponse: p.IDTokenClaims(clientID, "user2"),
+			wantStatus:    http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.tokenResponse != nil {
+				p.RespondToken(p.TokenResponse(t, tt.tokenResponse))
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, hybridRequest(tt.authResponse))
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+		})
+	}
+	assert.Len(t, p.Requests(), 2, "tokens only requested for valid authorization responses")
+}
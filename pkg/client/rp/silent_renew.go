@@ -0,0 +1,97 @@
+package rp
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// SilentRenewMessageType is the type of the [SilentRenewResult] posted to the parent window.
+const SilentRenewMessageType = "oidc_silent_renew"
+
+// SilentRenewResult is posted by the page of the [SilentRenewHandler] to the parent window.
+// Error is empty on success, otherwise it contains the error of the OP,
+// e.g. login_required if the user has to log in interactively.
+type SilentRenewResult struct {
+	Type             string `json:"type"`
+	State            string `json:"state,omitempty"`
+	Error            string `json:"error,omitempty"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// SilentRenewCallback is called by the [SilentRenewHandler] with the renewed tokens,
+// e.g. to update the session of the application. It may set cookies, but must not write the body.
+// A returned error fails the renewal.
+type SilentRenewCallback[C oidc.IDClaims] func(w http.ResponseWriter, r *http.Request, tokens *oidc.Tokens[C], state string, rp RelyingParty) error
+
+// SilentAuthURLHandler is the [AuthURLHandler] of a silent authentication (prompt=none),
+// which is loaded by the application in a hidden iframe to renew the tokens without user interaction.
+// The redirect_uri of the rp must be served by the [SilentRenewHandler].
+func SilentAuthURLHandler(stateFn func() string, rp RelyingParty, urlParam ...URLParamOpt) http.HandlerFunc {
+	return AuthURLHandler(stateFn, rp, append(urlParam, WithPromptURLParam(oidc.PromptNone))...)
+}
+
+// SilentRenewHandler is the [CodeExchangeHandler] of a silent authentication in a hidden iframe.
+// Instead of redirecting the user, it renders a page, which posts the [SilentRenewResult]
+// to the parent window with the targetOrigin, the origin of the application.
+// Errors of the OP, such as login_required, consent_required or interaction_required, are posted as well,
+// so the application can fall back to an interactive login.
+func SilentRenewHandler[C oidc.IDClaims](callback SilentRenewCallback[C], rp RelyingParty, targetOrigin string, urlParam ...URLParamOpt) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		silentRP := &silentRenewRelyingParty{RelyingParty: rp}
+		CodeExchangeHandler(func(w http.ResponseWriter, r *http.Request, tokens *oidc.Tokens[C], state string, rp RelyingParty) {
+			result := SilentRenewResult{Type: SilentRenewMessageType, State: state}
+			if err := callback(w, r, tokens, state, rp); err != nil {
+				result.Error = string(oidc.ServerError)
+				result.ErrorDescription = err.Error()
+			}
+			renderSilentRenewResult(w, r, result, targetOrigin)
+		}, silentRP, urlParam...)(w, r)
+		if silentRP.result != nil {
+			renderSilentRenewResult(w, r, *silentRP.result, targetOrigin)
+		}
+	}
+}
+
+// silentRenewRelyingParty records the errors of the [CodeExchangeHandler]
+// to post them to the parent window instead of calling the handlers of the RelyingParty.
+type silentRenewRelyingParty struct {
+	RelyingParty
+	result *SilentRenewResult
+}
+
+func (s *silentRenewRelyingParty) ErrorHandler() func(http.ResponseWriter, *http.Request, string, string, string) {
+	return func(_ http.ResponseWriter, _ *http.Request, errorType, errorDesc, state string) {
+		s.result = &SilentRenewResult{Type: SilentRenewMessageType, State: state, Error: errorType, ErrorDescription: errorDesc}
+	}
+}
+
+func (s *silentRenewRelyingParty) UnauthorizedHandler() func(http.ResponseWriter, *http.Request, string, string) {
+	return func(_ http.ResponseWriter, _ *http.Request, desc, state string) {
+		s.result = &SilentRenewResult{Type: SilentRenewMessageType, State: state, Error: string(oidc.InvalidRequest), ErrorDescription: desc}
+	}
+}
+
+var silentRenewTemplate = template.Must(template.New("silent_renew").Parse(`<!DOCTYPE html>
+<html>
+	<head>
+		<meta charset="UTF-8">
+	</head>
+	<body>
+		<script>window.parent.postMessage({{.Result}}, {{.TargetOrigin}});</script>
+	</body>
+</html>`))
+
+func renderSilentRenewResult(w http.ResponseWriter, r *http.Request, result SilentRenewResult, targetOrigin string) {
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	err := silentRenewTemplate.Execute(w, struct {
+		Result       SilentRenewResult
+		TargetOrigin string
+	}{result, targetOrigin})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "silent renew template", "error", err)
+	}
+}
@@ -0,0 +1,41 @@
+package rp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+func TestSilentAuthURLHandler(t *testing.T) {
+	rp, err := NewRelyingPartyOAuth(&oauth2.Config{Endpoint: oauth2.Endpoint{AuthURL: "https://op.example.com/authorize"}})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	SilentAuthURLHandler(func() string { return "state" }, rp)(w, httptest.NewRequest(http.MethodGet, "/silent", nil))
+	require.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, oidc.PromptNone, location.Query().Get("prompt"))
+}
+
+func TestSilentRenewHandler_error(t *testing.T) {
+	rp, err := NewRelyingPartyOAuth(&oauth2.Config{})
+	require.NoError(t, err)
+	handler := SilentRenewHandler(func(http.ResponseWriter, *http.Request, *oidc.Tokens[*oidc.IDTokenClaims], string, RelyingParty) error {
+		t.Fatal("callback must not be called on errors")
+		return nil
+	}, rp, "https://app.example.com")
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/callback?error=login_required&state=state", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"type":"oidc_silent_renew"`)
+	assert.Contains(t, w.Body.String(), `"error":"login_required"`)
+	assert.Contains(t, w.Body.String(), `"https://app.example.com")`)
+}
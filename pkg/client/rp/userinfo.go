@@ -0,0 +1,64 @@
+package rp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	jose "github.com/go-jose/go-jose/v4"
+
+	httphelper "github.com/zitadel/oidc/v3/pkg/http"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// userinfoRequest calls the userinfo endpoint and unmarshals the response into userinfo.
+// Userinfo responses as JWT (application/jwt) are decrypted with the keys of [WithDecryptionKeys],
+// if encrypted, and must be signed by the OP.
+// https://openid.net/specs/openid-connect-core-1_0.html#UserInfoResponse
+func userinfoRequest(ctx context.Context, rp RelyingParty, req *http.Request, userinfo any) error {
+	resp, err := rp.HttpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if resp.StatusCode != http.StatusOK || mediaType != "application/jwt" {
+		return httphelper.HttpResponse(resp, userinfo)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read response body: %v", err)
+	}
+	v := rp.IDTokenVerifier()
+	token, err := oidc.DecryptTokenWithKeys(string(body), v.DecryptionKeys)
+	if err != nil {
+		return err
+	}
+	jws, err := jose.ParseSigned(token, supportedSigningAlgorithms(v))
+	if err != nil {
+		return fmt.Errorf("%w: %v", oidc.ErrParse, err)
+	}
+	payload, err := v.KeySet.VerifySignature(ctx, jws)
+	if err != nil {
+		return fmt.Errorf("%w (%w)", oidc.ErrSignatureInvalid, err)
+	}
+	if err = json.Unmarshal(payload, userinfo); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %v %s", err, payload)
+	}
+	return nil
+}
+
+func supportedSigningAlgorithms(v *IDTokenVerifier) []jose.SignatureAlgorithm {
+	if len(v.SupportedSignAlgs) == 0 {
+		return []jose.SignatureAlgorithm{jose.RS256, jose.ES256, jose.PS256}
+	}
+	algs := make([]jose.SignatureAlgorithm, len(v.SupportedSignAlgs))
+	for i, alg := range v.SupportedSignAlgs {
+		algs[i] = jose.SignatureAlgorithm(alg)
+	}
+	return algs
+}
@@ -2,6 +2,7 @@ package rp
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	jose "github.com/go-jose/go-jose/v4"
@@ -36,7 +37,7 @@ func VerifyIDToken[C oidc.Claims](ctx context.Context, token string, v *IDTokenV
 
 	var nilClaims C
 
-	decrypted, err := oidc.DecryptToken(token)
+	decrypted, err := oidc.DecryptTokenWithKeys(token, v.DecryptionKeys)
 	if err != nil {
 		return nilClaims, err
 	}
@@ -57,7 +58,15 @@ func VerifyIDToken[C oidc.Claims](ctx context.Context, token string, v *IDTokenV
 		return nilClaims, err
 	}
 
-	if err = oidc.CheckAZPVerifier(claims, v.AZP); err != nil {
+	if err = oidc.CheckTrustedAudiences(claims, v.ClientID, v.TrustedAudiences); err != nil {
+		return nilClaims, err
+	}
+
+	if err = oidc.CheckAZPPolicy(claims, v.AZP, v.AZPPolicy); err != nil {
+		return nilClaims, err
+	}
+
+	if err = oidc.CheckHeader(decrypted, v.AllowedTypes); err != nil {
 		return nilClaims, err
 	}
 
@@ -92,6 +101,15 @@ func VerifyIDToken[C oidc.Claims](ctx context.Context, token string, v *IDTokenV
 
 type IDTokenVerifier oidc.Verifier
 
+// VerifyLogoutToken validates a logout token of the OpenID Connect Back-Channel Logout
+// sent by the OP, see [oidc.VerifyLogoutToken].
+func VerifyLogoutToken(ctx context.Context, token string, v *IDTokenVerifier) (*oidc.LogoutTokenClaims, error) {
+	ctx, span := client.Tracer.Start(ctx, "VerifyLogoutToken")
+	defer span.End()
+
+	return oidc.VerifyLogoutToken(ctx, token, (*oidc.Verifier)(v))
+}
+
 // VerifyAccessToken validates the access token according to
 // https://openid.net/specs/openid-connect-core-1_0.html#CodeFlowTokenValidation
 func VerifyAccessToken(accessToken, atHash string, sigAlgorithm jose.SignatureAlgorithm) error {
@@ -109,6 +127,65 @@ func VerifyAccessToken(accessToken, atHash string, sigAlgorithm jose.SignatureAl
 	return nil
 }
 
+// VerifyCode validates the code according to
+// https://openid.net/specs/openid-connect-core-1_0.html#HybridCodeValidation
+func VerifyCode(code, cHash string, sigAlgorithm jose.SignatureAlgorithm) error {
+	if cHash == "" {
+		return nil
+	}
+
+	actual, err := oidc.ClaimHash(code, sigAlgorithm)
+	if err != nil {
+		return err
+	}
+	if actual != cHash {
+		return oidc.ErrCHash
+	}
+	return nil
+}
+
+// codeHashClaims is implemented by claims carrying the c_hash, such as [oidc.IDTokenClaims].
+type codeHashClaims interface {
+	GetCodeHash() string
+}
+
+// VerifyAuthResponseTokens implements the ID Token Validation of the implicit and hybrid flow,
+// where the id_token is returned from the authorization endpoint along with the code and / or access token
+// https://openid.net/specs/openid-connect-core-1_0.html#HybridIDToken
+//
+// In this case the c_hash and at_hash are required for the returned code and access token.
+// The hash algorithm is selected by the signing algorithm of the id_token (SHA-256, SHA-384 or SHA-512).
+// Empty values of code or accessToken are not checked.
+func VerifyAuthResponseTokens[C oidc.IDClaims](ctx context.Context, code, accessToken, idToken string, v *IDTokenVerifier) (claims C, err error) {
+	ctx, span := client.Tracer.Start(ctx, "VerifyAuthResponseTokens")
+	defer span.End()
+
+	var nilClaims C
+
+	claims, err = VerifyIDToken[C](ctx, idToken, v)
+	if err != nil {
+		return nilClaims, err
+	}
+	if code != "" {
+		hashClaims, ok := any(claims).(codeHashClaims)
+		if !ok || hashClaims.GetCodeHash() == "" {
+			return nilClaims, fmt.Errorf("%w: c_hash", oidc.ErrHashMissing)
+		}
+		if err = VerifyCode(code, hashClaims.GetCodeHash(), claims.GetSignatureAlgorithm()); err != nil {
+			return nilClaims, err
+		}
+	}
+	if accessToken != "" {
+		if claims.GetAccessTokenHash() == "" {
+			return nilClaims, fmt.Errorf("%w: at_hash", oidc.ErrHashMissing)
+		}
+		if err = VerifyAccessToken(accessToken, claims.GetAccessTokenHash(), claims.GetSignatureAlgorithm()); err != nil {
+			return nilClaims, err
+		}
+	}
+	return claims, nil
+}
+
 // NewIDTokenVerifier returns a oidc.Verifier suitable for ID token verification.
 func NewIDTokenVerifier(issuer, clientID string, keySet oidc.KeySet, options ...VerifierOption) *IDTokenVerifier {
 	v := &IDTokenVerifier{
@@ -181,3 +258,28 @@ func WithSupportedSigningAlgorithms(algs ...string) VerifierOption {
 		v.SupportedSignAlgs = algs
 	}
 }
+
+// WithTrustedAudiences sets the audiences, besides the client_id, accepted in the aud claim.
+// Tokens with other audiences are rejected.
+func WithTrustedAudiences(audiences ...string) VerifierOption {
+	return func(v *IDTokenVerifier) {
+		v.TrustedAudiences = audiences
+	}
+}
+
+// WithAZPPolicy sets when the azp claim is required,
+// e.g. [oidc.AZPRequired] for FAPI or [oidc.AZPOptional] for providers
+// issuing multiple audiences without azp.
+func WithAZPPolicy(policy oidc.AZPPolicy) VerifierOption {
+	return func(v *IDTokenVerifier) {
+		v.AZPPolicy = policy
+	}
+}
+
+// WithAllowedTypes enforces the typ header to be one of the types, e.g. "JWT".
+// Pass an empty string to also accept tokens without typ header.
+func WithAllowedTypes(types ...string) VerifierOption {
+	return func(v *IDTokenVerifier) {
+		v.AllowedTypes = types
+	}
+}
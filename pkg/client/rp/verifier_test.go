@@ -2,6 +2,8 @@ package rp
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
 	"testing"
 	"time"
 
@@ -91,6 +93,98 @@ func TestVerifyTokens(t *testing.T) {
 	}
 }
 
+func TestVerifyAuthResponseTokens(t *testing.T) {
+	verifier := &IDTokenVerifier{
+		Issuer:            tu.ValidIssuer,
+		MaxAgeIAT:         2 * time.Minute,
+		Offset:            time.Second,
+		SupportedSignAlgs: []string{string(tu.SignatureAlgorithm)},
+		KeySet:            tu.KeySet{},
+		ACR:               tu.ACRVerify,
+		AZP:               oidc.DefaultAZPVerifier(tu.ValidClientID),
+		ClientID:          tu.ValidClientID,
+	}
+	code := "code"
+	cHash, err := oidc.ClaimHash(code, tu.SignatureAlgorithm)
+	require.NoError(t, err)
+	accessToken, _ := tu.ValidAccessToken()
+	atHash, err := oidc.ClaimHash(accessToken, tu.SignatureAlgorithm)
+	require.NoError(t, err)
+	idToken := func(atHash, cHash string) string {
+		var custom map[string]any
+		if cHash != "" {
+			custom = map[string]any{"c_hash": cHash}
+		}
+		token, _ := tu.NewIDTokenCustom(
+			tu.ValidIssuer, tu.ValidSubject, tu.ValidAudience,
+			tu.ValidExpiration, tu.ValidAuthTime, tu.ValidNonce,
+			tu.ValidACR, tu.ValidAMR, tu.ValidClientID, tu.ValidSkew, atHash, custom,
+		)
+		return token
+	}
+
+	tests := []struct {
+		name        string
+		code        string
+		accessToken string
+		idToken     string
+		wantErr     error
+	}{
+		{
+			name:    "code id_token",
+			code:    code,
+			idToken: idToken("", cHash),
+		},
+		{
+			name:        "code id_token token",
+			code:        code,
+			accessToken: accessToken,
+			idToken:     idToken(atHash, cHash),
+		},
+		{
+			name:        "id_token token",
+			accessToken: accessToken,
+			idToken:     idToken(atHash, ""),
+		},
+		{
+			name:    "c_hash missing",
+			code:    code,
+			idToken: idToken(atHash, ""),
+			wantErr: oidc.ErrHashMissing,
+		},
+		{
+			name:    "wrong code",
+			code:    "other",
+			idToken: idToken("", cHash),
+			wantErr: oidc.ErrCHash,
+		},
+		{
+			name:        "at_hash missing",
+			accessToken: accessToken,
+			idToken:     idToken("", cHash),
+			wantErr:     oidc.ErrHashMissing,
+		},
+		{
+			name:        "wrong access token",
+			accessToken: "other",
+			idToken:     idToken(atHash, ""),
+			wantErr:     oidc.ErrAtHash,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifyAuthResponseTokens[*oidc.IDTokenClaims](context.Background(), tt.code, tt.accessToken, tt.idToken, verifier)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, got)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tu.ValidSubject, got.Subject)
+		})
+	}
+}
+
 func TestVerifyIDToken(t *testing.T) {
 	verifier := &IDTokenVerifier{
 		Issuer:            tu.ValidIssuer,
@@ -234,6 +328,23 @@ func TestVerifyIDToken(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name:        "untrusted audience",
+			tokenClaims: tu.ValidIDToken,
+			customVerifier: func(verifier *IDTokenVerifier) {
+				verifier.TrustedAudiences = []string{}
+			},
+			wantErr: true,
+		},
+		{
+			name:        "typ not allowed",
+			tokenClaims: tu.ValidIDToken,
+			customVerifier: func(verifier *IDTokenVerifier) {
+				verifier.TrustedAudiences = nil
+				verifier.AllowedTypes = []string{"at+jwt"}
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -338,6 +449,9 @@ func TestNewIDTokenVerifier(t *testing.T) {
 					WithAZPVerifier(nil),
 					WithAuthTimeMaxAge(2 * time.Hour),
 					WithSupportedSigningAlgorithms("ABC", "DEF"),
+					WithTrustedAudiences("api"),
+					WithAZPPolicy(oidc.AZPRequired),
+					WithAllowedTypes("JWT"),
 				},
 			},
 			want: &IDTokenVerifier{
@@ -350,6 +464,9 @@ func TestNewIDTokenVerifier(t *testing.T) {
 				ACR:               nil,
 				MaxAge:            2 * time.Hour,
 				SupportedSignAlgs: []string{"ABC", "DEF"},
+				TrustedAudiences:  []string{"api"},
+				AZPPolicy:         oidc.AZPRequired,
+				AllowedTypes:      []string{"JWT"},
 			},
 		},
 	}
@@ -360,3 +477,37 @@ func TestNewIDTokenVerifier(t *testing.T) {
 		})
 	}
 }
+
+func encryptToken(t *testing.T, token string, key *rsa.PrivateKey) string {
+	encrypter, err := jose.NewEncrypter(jose.A256GCM, jose.Recipient{Algorithm: jose.RSA_OAEP_256, Key: &key.PublicKey, KeyID: "enc"},
+		(&jose.EncrypterOptions{}).WithContentType("JWT"))
+	require.NoError(t, err)
+	object, err := encrypter.Encrypt([]byte(token))
+	require.NoError(t, err)
+	encrypted, err := object.CompactSerialize()
+	require.NoError(t, err)
+	return encrypted
+}
+
+func TestVerifyIDToken_encrypted(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	token, want := tu.ValidIDToken()
+	encrypted := encryptToken(t, token, key)
+
+	verifier := &IDTokenVerifier{
+		Issuer:   tu.ValidIssuer,
+		ClientID: tu.ValidClientID,
+		KeySet:   tu.KeySet{},
+		Nonce:    func(context.Context) string { return tu.ValidNonce },
+		AZP:      oidc.DefaultAZPVerifier(tu.ValidClientID),
+		ACR:      tu.ACRVerify,
+	}
+	_, err = VerifyIDToken[*oidc.IDTokenClaims](context.Background(), encrypted, verifier)
+	require.ErrorIs(t, err, oidc.ErrDecryption, "no decryption keys")
+
+	verifier.DecryptionKeys = &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: key, KeyID: "enc"}}}
+	got, err := VerifyIDToken[*oidc.IDTokenClaims](context.Background(), encrypted, verifier)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/oauth2"
+)
+
+// TokenSourceCredentials sends the access tokens of a token source,
+// such as the profile.NewJWTProfileTokenSource or a client credentials token source,
+// as authorization metadata of gRPC calls.
+// It implements credentials.PerRPCCredentials of google.golang.org/grpc
+// and can be passed to grpc.WithPerRPCCredentials, see the github.com/zitadel/oidc/v3/pkg/grpcauth module.
+type TokenSourceCredentials struct {
+	source   oauth2.TokenSource
+	insecure bool
+}
+
+// NewTokenSourceCredentials returns the [TokenSourceCredentials] of the token source.
+// The tokens are cached until they expire, see oauth2.ReuseTokenSource.
+// If insecure is set, the tokens are also sent over connections without transport security,
+// which must only be used for development.
+func NewTokenSourceCredentials(source oauth2.TokenSource, insecure bool) *TokenSourceCredentials {
+	return &TokenSourceCredentials{
+		source:   oauth2.ReuseTokenSource(nil, source),
+		insecure: insecure,
+	}
+}
+
+// GetRequestMetadata returns the authorization metadata with the current access token.
+func (c *TokenSourceCredentials) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	_, span := Tracer.Start(ctx, "GetRequestMetadata")
+	defer span.End()
+
+	token, err := c.source.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("token source returned no access token")
+	}
+	return map[string]string{
+		"authorization": token.Type() + " " + token.AccessToken,
+	}, nil
+}
+
+// RequireTransportSecurity reports whether the credentials require a secure connection.
+func (c *TokenSourceCredentials) RequireTransportSecurity() bool {
+	return !c.insecure
+}
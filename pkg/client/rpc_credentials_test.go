@@ -0,0 +1,23 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestTokenSourceCredentials(t *testing.T) {
+	creds := NewTokenSourceCredentials(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token", TokenType: "bearer"}), false)
+	md, err := creds.GetRequestMetadata(context.Background(), "https://api.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"authorization": "Bearer token"}, md)
+	assert.True(t, creds.RequireTransportSecurity())
+
+	creds = NewTokenSourceCredentials(oauth2.StaticTokenSource(&oauth2.Token{}), true)
+	_, err = creds.GetRequestMetadata(context.Background())
+	assert.Error(t, err)
+	assert.False(t, creds.RequireTransportSecurity())
+}
@@ -0,0 +1,35 @@
+package rs
+
+import (
+	"context"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+type claimsKey struct{}
+
+// ContextWithClaims returns a context carrying the claims of the authorized request,
+// such as the introspection response of its access token.
+// They are read with [ClaimsFromContext] using the same type.
+func ContextWithClaims[T any](ctx context.Context, claims T) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims set by [ContextWithClaims], [Middleware]
+// or [ContextWithIntrospection]. ok is false if there are none or they are not of type T.
+func ClaimsFromContext[T any](ctx context.Context) (claims T, ok bool) {
+	claims, ok = ctx.Value(claimsKey{}).(T)
+	return claims, ok
+}
+
+// ContextWithIntrospection returns a context carrying the introspection response
+// of the authorized request.
+func ContextWithIntrospection(ctx context.Context, resp *oidc.IntrospectionResponse) context.Context {
+	return ContextWithClaims(ctx, resp)
+}
+
+// IntrospectionFromContext returns the introspection response set by [ContextWithIntrospection] or [Middleware].
+// It is a shorthand for ClaimsFromContext[*oidc.IntrospectionResponse].
+func IntrospectionFromContext(ctx context.Context) (*oidc.IntrospectionResponse, bool) {
+	return ClaimsFromContext[*oidc.IntrospectionResponse](ctx)
+}
@@ -0,0 +1,31 @@
+package rs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+func TestClaimsFromContext(t *testing.T) {
+	_, ok := ClaimsFromContext[*oidc.IntrospectionResponse](context.Background())
+	assert.False(t, ok)
+
+	resp := &oidc.IntrospectionResponse{Active: true, Subject: "user"}
+	ctx := ContextWithIntrospection(context.Background(), resp)
+	got, ok := ClaimsFromContext[*oidc.IntrospectionResponse](ctx)
+	assert.True(t, ok)
+	assert.Same(t, resp, got)
+
+	_, ok = ClaimsFromContext[map[string]any](ctx)
+	assert.False(t, ok, "other claims type")
+
+	ctx = ContextWithClaims(ctx, map[string]any{"sub": "user"})
+	claims, ok := ClaimsFromContext[map[string]any](ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "user", claims["sub"])
+	_, ok = IntrospectionFromContext(ctx)
+	assert.False(t, ok, "claims replaced")
+}
@@ -0,0 +1,67 @@
+package rs
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/zitadel/oidc/v3/pkg/client"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// AuthorizationMetadataKey is the key of the access token in gRPC metadata,
+// which are lower case.
+const AuthorizationMetadataKey = "authorization"
+
+var (
+	ErrTokenMissing  = errors.New("resource server: bearer token missing")
+	ErrTokenInactive = errors.New("resource server: token is not active")
+)
+
+// TokenFromMetadata returns the bearer token of the authorization entry of metadata,
+// such as the metadata.MD of an incoming gRPC request.
+func TokenFromMetadata(md map[string][]string) (string, error) {
+	values := md[AuthorizationMetadataKey]
+	if len(values) != 1 {
+		return "", ErrTokenMissing
+	}
+	return bearerToken(values[0])
+}
+
+// bearerToken returns the token of the authorization value,
+// whose auth scheme is case-insensitive (RFC 9110, section 11.1).
+func bearerToken(authorization string) (string, error) {
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, oidc.BearerToken) || token == "" {
+		return "", ErrTokenMissing
+	}
+	return token, nil
+}
+
+// AuthorizeMetadata introspects the bearer token of the metadata
+// and returns the response, if the token is active.
+//
+// It implements the token validation of the gRPC server interceptors
+// of the github.com/zitadel/oidc/v3/pkg/grpcauth module.
+func AuthorizeMetadata(ctx context.Context, rp ResourceServer, md map[string][]string) (*oidc.IntrospectionResponse, error) {
+	ctx, span := client.Tracer.Start(ctx, "AuthorizeMetadata")
+	defer span.End()
+
+	token, err := TokenFromMetadata(md)
+	if err != nil {
+		return nil, err
+	}
+	return introspectActive(ctx, rp, token)
+}
+
+// introspectActive returns the introspection response of the token, if it is active.
+func introspectActive(ctx context.Context, rp ResourceServer, token string) (*oidc.IntrospectionResponse, error) {
+	resp, err := Introspect[*oidc.IntrospectionResponse](ctx, rp, token)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Active {
+		return nil, ErrTokenInactive
+	}
+	return resp, nil
+}
@@ -0,0 +1,64 @@
+package rs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenFromMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		md      map[string][]string
+		want    string
+		wantErr error
+	}{
+		{"bearer", map[string][]string{"authorization": {"Bearer token"}}, "token", nil},
+		{"case insensitive scheme", map[string][]string{"authorization": {"bearer token"}}, "token", nil},
+		{"missing", map[string][]string{}, "", ErrTokenMissing},
+		{"multiple", map[string][]string{"authorization": {"Bearer a", "Bearer b"}}, "", ErrTokenMissing},
+		{"basic", map[string][]string{"authorization": {"Basic dXNlcjpwdw=="}}, "", ErrTokenMissing},
+		{"empty token", map[string][]string{"authorization": {"Bearer "}}, "", ErrTokenMissing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TokenFromMetadata(tt.md)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAuthorizeMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostForm.Get("token") == "active" {
+			w.Write([]byte(`{"active":true,"sub":"user","scope":"read"}`))
+			return
+		}
+		w.Write([]byte(`{"active":false}`))
+	}))
+	defer server.Close()
+	rs, err := newResourceServer(context.Background(), server.URL, func() (any, error) { return nil, nil },
+		WithClient(server.Client()), WithStaticEndpoints(server.URL+"/token", server.URL+"/introspect"))
+	require.NoError(t, err)
+
+	resp, err := AuthorizeMetadata(context.Background(), rs, map[string][]string{"authorization": {"Bearer active"}})
+	require.NoError(t, err)
+	assert.Equal(t, "user", resp.Subject)
+
+	ctx := ContextWithIntrospection(context.Background(), resp)
+	got, ok := IntrospectionFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, resp, got)
+
+	_, err = AuthorizeMetadata(context.Background(), rs, map[string][]string{"authorization": {"Bearer inactive"}})
+	assert.ErrorIs(t, err, ErrTokenInactive)
+	_, err = AuthorizeMetadata(context.Background(), rs, nil)
+	assert.ErrorIs(t, err, ErrTokenMissing)
+}
@@ -0,0 +1,64 @@
+package rs
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Middleware returns a net/http middleware, as used by chi and most other routers,
+// which only passes requests with an active bearer token to the next handler.
+// The introspection response is available to the next handler by [IntrospectionFromContext].
+// Requests without an active token are answered with 401 Unauthorized
+// and with 503 Service Unavailable, if the token could not be introspected.
+func Middleware(rp ResourceServer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, err := bearerToken(r.Header.Get("authorization"))
+			if err != nil {
+				unauthorized(w, `Bearer`)
+				return
+			}
+			resp, err := introspectActive(r.Context(), rp, token)
+			if errors.Is(err, ErrTokenInactive) {
+				unauthorized(w, `Bearer error="invalid_token"`)
+				return
+			}
+			if err != nil {
+				slog.ErrorContext(r.Context(), "resource server: introspection failed", "error", err)
+				http.Error(w, "token introspection failed", http.StatusServiceUnavailable)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ContextWithIntrospection(r.Context(), resp)))
+		})
+	}
+}
+
+// RequireScopes returns a middleware, which must be used after [Middleware],
+// answering requests whose token lacks any of the scopes with 403 Forbidden.
+func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			resp, ok := IntrospectionFromContext(r.Context())
+			if !ok {
+				unauthorized(w, `Bearer`)
+				return
+			}
+			for _, scope := range scopes {
+				if !slices.Contains(resp.Scope, scope) {
+					w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+strings.Join(scopes, " ")+`"`)
+					http.Error(w, "insufficient scope", http.StatusForbidden)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter, challenge string) {
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+}
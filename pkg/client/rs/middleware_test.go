@@ -0,0 +1,66 @@
+package rs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		w.Header().Set("Content-Type", "application/json")
+		switch r.PostForm.Get("token") {
+		case "active":
+			w.Write([]byte(`{"active":true,"sub":"user","scope":"read"}`))
+		case "outage":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(`{"active":false}`))
+		}
+	}))
+	defer server.Close()
+	rs, err := newResourceServer(context.Background(), server.URL, func() (any, error) { return nil, nil },
+		WithClient(server.Client()), WithStaticEndpoints(server.URL+"/token", server.URL+"/introspect"))
+	require.NoError(t, err)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, ok := IntrospectionFromContext(r.Context())
+		require.True(t, ok)
+		w.Write([]byte(resp.Subject))
+	})
+	tests := []struct {
+		name          string
+		authorization string
+		scopes        []string
+		wantStatus    int
+		wantChallenge string
+	}{
+		{"active", "Bearer active", nil, http.StatusOK, ""},
+		{"case insensitive scheme", "bearer active", nil, http.StatusOK, ""},
+		{"scope", "Bearer active", []string{"read"}, http.StatusOK, ""},
+		{"missing", "", nil, http.StatusUnauthorized, `Bearer`},
+		{"inactive", "Bearer inactive", nil, http.StatusUnauthorized, `Bearer error="invalid_token"`},
+		{"introspection outage", "Bearer outage", nil, http.StatusServiceUnavailable, ""},
+		{"insufficient scope", "Bearer active", []string{"read", "write"}, http.StatusForbidden, `Bearer error="insufficient_scope", scope="read write"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			Middleware(rs)(RequireScopes(tt.scopes...)(next)).ServeHTTP(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantChallenge, w.Header().Get("WWW-Authenticate"))
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, "user", w.Body.String())
+			}
+		})
+	}
+}
@@ -2,8 +2,10 @@ package rs
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/zitadel/oidc/v3/pkg/client"
@@ -24,6 +26,7 @@ type resourceServer struct {
 	introspectURL string
 	httpClient    *http.Client
 	authFn        func() (any, error)
+	mtlsConfig    *tls.Config
 }
 
 func (r *resourceServer) IntrospectionURL() string {
@@ -64,6 +67,26 @@ func NewResourceServerJWTProfile(ctx context.Context, issuer, clientID, keyID st
 	return newResourceServer(ctx, issuer, authorizer, options...)
 }
 
+// NewResourceServerMTLS creates a ResourceServer authenticating with mutual-TLS
+// (tls_client_auth or self_signed_tls_client_auth), which presents the client certificate
+// of the tls.Config and sends only the client_id in the request body.
+// The mtls_endpoint_aliases from the discovery are preferred over the conventional endpoints.
+// https://datatracker.ietf.org/doc/html/rfc8705
+func NewResourceServerMTLS(ctx context.Context, issuer, clientID string, tlsConfig *tls.Config, options ...Option) (ResourceServer, error) {
+	if tlsConfig == nil || len(tlsConfig.Certificates) == 0 && tlsConfig.GetClientCertificate == nil {
+		return nil, errors.New("mTLS requires a client certificate")
+	}
+	authorizer := func() (any, error) {
+		return httphelper.FormAuthorization(func(values url.Values) {
+			values.Set("client_id", clientID)
+		}), nil
+	}
+	options = append([]Option{func(server *resourceServer) {
+		server.mtlsConfig = tlsConfig
+	}}, options...)
+	return newResourceServer(ctx, issuer, authorizer, options...)
+}
+
 func newResourceServer(ctx context.Context, issuer string, authorizer func() (any, error), options ...Option) (*resourceServer, error) {
 	rs := &resourceServer{
 		issuer:     issuer,
@@ -72,11 +95,21 @@ func newResourceServer(ctx context.Context, issuer string, authorizer func() (an
 	for _, optFunc := range options {
 		optFunc(rs)
 	}
+	if rs.mtlsConfig != nil {
+		httpClient, err := client.NewMTLSHTTPClient(rs.httpClient, rs.mtlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		rs.httpClient = httpClient
+	}
 	if rs.introspectURL == "" || rs.tokenURL == "" {
 		config, err := client.Discover(ctx, rs.issuer, rs.httpClient)
 		if err != nil {
 			return nil, err
 		}
+		if rs.mtlsConfig != nil {
+			config = client.MTLSEndpoints(config)
+		}
 		if rs.tokenURL == "" {
 			rs.tokenURL = config.TokenEndpoint
 		}
@@ -2,10 +2,15 @@ package rs
 
 import (
 	"context"
+	"crypto/tls"
+	"net/http"
+	"net/url"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	httphelper "github.com/zitadel/oidc/v3/pkg/http"
 	"github.com/zitadel/oidc/v3/pkg/oidc"
 )
 
@@ -219,3 +224,23 @@ func TestIntrospect(t *testing.T) {
 		})
 	}
 }
+
+func TestNewResourceServerMTLS(t *testing.T) {
+	_, err := NewResourceServerMTLS(context.Background(), "https://issuer.example.com", "client", &tls.Config{})
+	require.Error(t, err, "no client certificate")
+
+	rs, err := NewResourceServerMTLS(context.Background(), "https://issuer.example.com", "client",
+		&tls.Config{Certificates: []tls.Certificate{{}}},
+		WithStaticEndpoints("https://mtls.example.com/token", "https://mtls.example.com/introspect"),
+	)
+	require.NoError(t, err)
+	transport, ok := rs.HttpClient().Transport.(*http.Transport)
+	require.True(t, ok)
+	assert.Len(t, transport.TLSClientConfig.Certificates, 1)
+
+	authFn, err := rs.AuthFn()
+	require.NoError(t, err)
+	values := url.Values{}
+	authFn.(httphelper.FormAuthorization)(values)
+	assert.Equal(t, url.Values{"client_id": {"client"}}, values)
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	httphelper "github.com/zitadel/oidc/v3/pkg/http"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// WebFinger returns the issuer of the user identified by identifier, e.g. the email address
+// entered by the user, with a WebFinger request to the host of the identifier
+// (OpenID Connect Discovery section 2).
+func WebFinger(ctx context.Context, identifier string, httpClient *http.Client) (string, error) {
+	ctx, span := Tracer.Start(ctx, "WebFinger")
+	defer span.End()
+
+	resource, host, err := oidc.NormalizeWebFingerResource(identifier)
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{
+		"resource": {resource},
+		"rel":      {oidc.WebFingerRelIssuer},
+	}
+	endpoint := (&url.URL{Scheme: "https", Host: host, Path: oidc.WebFingerEndpoint, RawQuery: query.Encode()}).String()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	resp := new(oidc.WebFingerResponse)
+	if err = httphelper.HttpRequest(httpClient, req, resp); err != nil {
+		return "", errors.Join(oidc.ErrDiscoveryFailed, err)
+	}
+	issuer := resp.Issuer()
+	if issuer == "" {
+		return "", errors.Join(oidc.ErrDiscoveryFailed, errors.New("webfinger response contains no issuer"))
+	}
+	return issuer, nil
+}
+
+// DiscoverIssuer finds the issuer of the user identified by identifier with [WebFinger]
+// and returns its configuration by [Discover], e.g. for "enter your email" home realm discovery.
+func DiscoverIssuer(ctx context.Context, identifier string, httpClient *http.Client) (*oidc.DiscoveryConfiguration, error) {
+	ctx, span := Tracer.Start(ctx, "DiscoverIssuer")
+	defer span.End()
+
+	issuer, err := WebFinger(ctx, identifier, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	return Discover(ctx, issuer, httpClient)
+}
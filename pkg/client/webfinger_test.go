@@ -0,0 +1,40 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+func TestDiscoverIssuer(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "https://")
+	mux.HandleFunc(oidc.WebFingerEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "https://joe@"+host, r.URL.Query().Get("resource"))
+		assert.Equal(t, oidc.WebFingerRelIssuer, r.URL.Query().Get("rel"))
+		json.NewEncoder(w).Encode(&oidc.WebFingerResponse{
+			Subject: r.URL.Query().Get("resource"),
+			Links:   []oidc.WebFingerLink{{Rel: oidc.WebFingerRelIssuer, Href: server.URL}},
+		})
+	})
+	mux.HandleFunc(oidc.DiscoveryEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&oidc.DiscoveryConfiguration{Issuer: server.URL})
+	})
+
+	config, err := DiscoverIssuer(context.Background(), "joe@"+host, server.Client())
+	require.NoError(t, err)
+	assert.Equal(t, server.URL, config.Issuer)
+
+	_, err = DiscoverIssuer(context.Background(), "", server.Client())
+	assert.ErrorIs(t, err, oidc.ErrWebFingerIdentifier)
+}
@@ -0,0 +1,63 @@
+package crypto
+
+import (
+	"encoding/json"
+	"errors"
+
+	jose "github.com/go-jose/go-jose/v4"
+)
+
+// CompactSigner signs payloads into JWS compact serialization with a go-jose signer,
+// which is created once for the key, so a CompactSigner should be created
+// per key and reused for every token signed with it.
+// It is safe for concurrent use.
+type CompactSigner struct {
+	signer jose.Signer
+}
+
+// NewCompactSigner creates a signer for the key, which may be wrapped in a [jose.JSONWebKey].
+// The protected header contains the alg, the keyID (kid) and the typ, if not empty.
+func NewCompactSigner(alg jose.SignatureAlgorithm, key any, keyID, typ string) (*CompactSigner, error) {
+	if jwk, ok := key.(*jose.JSONWebKey); ok {
+		key = jwk.Key
+		if keyID == "" {
+			keyID = jwk.KeyID
+		}
+	}
+	options := new(jose.SignerOptions)
+	if typ != "" {
+		options = options.WithType(jose.ContentType(typ))
+	}
+	if _, ok := key.([]byte); ok && keyID != "" {
+		// go-jose only sets the kid of asymmetric keys
+		options = options.WithHeader(jose.HeaderKey("kid"), keyID)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: alg,
+		Key: &jose.JSONWebKey{
+			Key:   key,
+			KeyID: keyID,
+		},
+	}, options)
+	if err != nil {
+		return nil, err
+	}
+	return &CompactSigner{signer: signer}, nil
+}
+
+// Sign signs the payload and returns the compact serialization.
+func (s *CompactSigner) Sign(payload []byte) (string, error) {
+	if s == nil {
+		return "", errors.New("missing signer")
+	}
+	return SignPayload(payload, s.signer)
+}
+
+// SignObject marshals the object once and signs it.
+func (s *CompactSigner) SignObject(object any) (string, error) {
+	payload, err := json.Marshal(object)
+	if err != nil {
+		return "", err
+	}
+	return s.Sign(payload)
+}
@@ -0,0 +1,111 @@
+package crypto_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	zcrypto "github.com/zitadel/oidc/v3/pkg/crypto"
+)
+
+var (
+	rsaKey, _   = rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _    = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ec384Key, _ = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	_, edKey, _ = ed25519.GenerateKey(rand.Reader)
+	payload     = []byte(`{"iss":"https://op.example.com","sub":"user1","aud":["client"],"exp":1700000000}`)
+)
+
+func joseSigner(t testing.TB, alg jose.SignatureAlgorithm, key any) jose.Signer {
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: alg,
+		Key:       &jose.JSONWebKey{Key: key, KeyID: "kid"},
+	}, (&jose.SignerOptions{}).WithType("JWT"))
+	require.NoError(t, err)
+	return signer
+}
+
+func TestCompactSigner_Sign(t *testing.T) {
+	tests := []struct {
+		alg       jose.SignatureAlgorithm
+		key       any
+		verifyKey any
+	}{
+		{jose.RS256, rsaKey, &rsaKey.PublicKey},
+		{jose.RS512, rsaKey, &rsaKey.PublicKey},
+		{jose.PS256, rsaKey, &rsaKey.PublicKey},
+		{jose.ES256, ecKey, &ecKey.PublicKey},
+		{jose.ES384, ec384Key, &ec384Key.PublicKey},
+		{jose.EdDSA, edKey, edKey.Public()},
+		{jose.HS256, []byte("secretsecretsecretsecretsecretse"), []byte("secretsecretsecretsecretsecretse")},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.alg), func(t *testing.T) {
+			signer, err := zcrypto.NewCompactSigner(tt.alg, tt.key, "kid", "JWT")
+			require.NoError(t, err)
+			token, err := signer.Sign(payload)
+			require.NoError(t, err)
+
+			jws, err := jose.ParseSigned(token, []jose.SignatureAlgorithm{tt.alg})
+			require.NoError(t, err)
+			got, err := jws.Verify(tt.verifyKey)
+			require.NoError(t, err)
+			assert.Equal(t, payload, got)
+
+			header := jws.Signatures[0].Protected
+			assert.Equal(t, "kid", header.KeyID)
+			assert.Equal(t, "JWT", header.ExtraHeaders["typ"])
+		})
+	}
+}
+
+func TestCompactSigner_joseCompatible(t *testing.T) {
+	// RS256 signatures are deterministic, so the tokens must be equal.
+	want, err := zcrypto.SignPayload(payload, joseSigner(t, jose.RS256, rsaKey))
+	require.NoError(t, err)
+
+	signer, err := zcrypto.NewCompactSigner(jose.RS256, &jose.JSONWebKey{Key: rsaKey, KeyID: "kid"}, "", "JWT")
+	require.NoError(t, err)
+	got, err := signer.Sign(payload)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestCompactSigner_Sign_error(t *testing.T) {
+	for _, alg := range []jose.SignatureAlgorithm{jose.RS256, jose.ES384} {
+		signer, err := zcrypto.NewCompactSigner(alg, ecKey, "kid", "JWT")
+		if err == nil {
+			_, err = signer.Sign(payload)
+		}
+		assert.Error(t, err, alg)
+	}
+}
+
+func BenchmarkSign(b *testing.B) {
+	b.Run("jose signer per token", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := zcrypto.SignPayload(payload, joseSigner(b, jose.ES256, ecKey)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("compact signer", func(b *testing.B) {
+		signer, err := zcrypto.NewCompactSigner(jose.ES256, ecKey, "kid", "JWT")
+		require.NoError(b, err)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for b.Loop() {
+			if _, err := signer.Sign(payload); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
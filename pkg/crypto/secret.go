@@ -0,0 +1,118 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrSecretMismatch  = errors.New("secret does not match the hash")
+	ErrUnsupportedHash = errors.New("unsupported secret hash")
+)
+
+// Parameters of [HashSecret], the recommendation of RFC 9106 section 4
+// for memory constrained environments.
+const (
+	argon2idMemory  = 64 * 1024 // KiB
+	argon2idTime    = 3
+	argon2idThreads = 4
+	argon2idSaltLen = 16
+	argon2idKeyLen  = 32
+)
+
+// Bounds of the parameters of the hashes accepted by [CompareSecret],
+// as they are read from the hash and every comparison costs their memory and time.
+const (
+	maxArgon2idMemory  = 256 * 1024 // KiB
+	maxArgon2idTime    = 10
+	maxArgon2idThreads = 16
+	minArgon2idSaltLen = 8
+	minArgon2idKeyLen  = 16
+	maxArgon2idKeyLen  = 64
+	maxBcryptCost      = 14
+)
+
+// HashSecret returns the argon2id hash of the secret in the PHC string format,
+// e.g. $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>, which can be verified by [CompareSecret].
+//
+// The hash is meant for secrets chosen by humans. Every comparison costs 64 MiB of memory,
+// which is wasted on the high-entropy secrets generated by most OPs,
+// whose SHA-256 hash can't be reversed by brute force either.
+func HashSecret(secret string) (string, error) {
+	salt := make([]byte, argon2idSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(secret), salt, argon2idTime, argon2idMemory, argon2idThreads, argon2idKeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argon2idMemory, argon2idTime, argon2idThreads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// CompareSecret compares the secret with a bcrypt hash or an argon2id hash in the PHC string format
+// in constant time. It returns [ErrSecretMismatch] if the secret does not match
+// and [ErrUnsupportedHash] for other hashes, including hashes whose cost exceeds
+// a bcrypt cost of 14 or the argon2id parameters m=262144,t=10,p=16.
+func CompareSecret(hash, secret string) error {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		cost, err := bcrypt.Cost([]byte(hash))
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrUnsupportedHash, err)
+		}
+		if cost > maxBcryptCost {
+			return fmt.Errorf("%w: bcrypt cost %d exceeds %d", ErrUnsupportedHash, cost, maxBcryptCost)
+		}
+		err = bcrypt.CompareHashAndPassword([]byte(hash), []byte(secret))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrSecretMismatch
+		}
+		return err
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return compareArgon2id(hash, secret)
+	default:
+		return ErrUnsupportedHash
+	}
+}
+
+func compareArgon2id(hash, secret string) error {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return fmt.Errorf("%w: malformed argon2id hash", ErrUnsupportedHash)
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return fmt.Errorf("%w: argon2id version %q", ErrUnsupportedHash, parts[2])
+	}
+	var (
+		memory, time uint32
+		threads      uint8
+	)
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return fmt.Errorf("%w: argon2id parameters: %w", ErrUnsupportedHash, err)
+	}
+	// argon2.IDKey panics for zero threads and requires 8 KiB of memory per thread
+	if time < 1 || time > maxArgon2idTime || threads < 1 || threads > maxArgon2idThreads ||
+		memory < 8*uint32(threads) || memory > maxArgon2idMemory {
+		return fmt.Errorf("%w: argon2id parameters %q out of bounds", ErrUnsupportedHash, parts[3])
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(salt) < minArgon2idSaltLen {
+		return fmt.Errorf("%w: argon2id salt", ErrUnsupportedHash)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) < minArgon2idKeyLen || len(key) > maxArgon2idKeyLen {
+		return fmt.Errorf("%w: argon2id key", ErrUnsupportedHash)
+	}
+	other := argon2.IDKey([]byte(secret), salt, time, memory, threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return ErrSecretMismatch
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package crypto_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	zcrypto "github.com/zitadel/oidc/v3/pkg/crypto"
+)
+
+func TestHashSecret(t *testing.T) {
+	hash, err := zcrypto.HashSecret("secret")
+	require.NoError(t, err)
+	assert.Regexp(t, `^\$argon2id\$v=19\$m=65536,t=3,p=4\$[A-Za-z0-9+/]+\$[A-Za-z0-9+/]+$`, hash)
+
+	other, err := zcrypto.HashSecret("secret")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other, "salt must be random")
+
+	assert.NoError(t, zcrypto.CompareSecret(hash, "secret"))
+	assert.ErrorIs(t, zcrypto.CompareSecret(hash, "wrong"), zcrypto.ErrSecretMismatch)
+}
+
+func TestCompareSecret(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		hash    string
+		secret  string
+		wantErr error
+	}{
+		{
+			name:   "bcrypt",
+			hash:   string(bcryptHash),
+			secret: "secret",
+		},
+		{
+			name:    "bcrypt mismatch",
+			hash:    string(bcryptHash),
+			secret:  "wrong",
+			wantErr: zcrypto.ErrSecretMismatch,
+		},
+		{
+			name:   "argon2id",
+			hash:   "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+			secret: "password",
+		},
+		{
+			name:    "argon2id mismatch",
+			hash:    "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+			secret:  "secret",
+			wantErr: zcrypto.ErrSecretMismatch,
+		},
+		{
+			name:    "argon2id version",
+			hash:    "$argon2id$v=16$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+			secret:  "password",
+			wantErr: zcrypto.ErrUnsupportedHash,
+		},
+		{
+			name:    "argon2id malformed",
+			hash:    "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ",
+			secret:  "password",
+			wantErr: zcrypto.ErrUnsupportedHash,
+		},
+		{
+			name:    "bcrypt cost",
+			hash:    strings.Replace(string(bcryptHash), "$04$", "$31$", 1),
+			secret:  "secret",
+			wantErr: zcrypto.ErrUnsupportedHash,
+		},
+		{
+			name:    "argon2id no threads",
+			hash:    "$argon2id$v=19$m=65536,t=2,p=0$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+			secret:  "password",
+			wantErr: zcrypto.ErrUnsupportedHash,
+		},
+		{
+			name:    "argon2id memory",
+			hash:    "$argon2id$v=19$m=4294967295,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+			secret:  "password",
+			wantErr: zcrypto.ErrUnsupportedHash,
+		},
+		{
+			name:    "argon2id time",
+			hash:    "$argon2id$v=19$m=65536,t=1000000,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc",
+			secret:  "password",
+			wantErr: zcrypto.ErrUnsupportedHash,
+		},
+		{
+			name:    "argon2id key length",
+			hash:    "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$" + strings.Repeat("A", 1<<20),
+			secret:  "password",
+			wantErr: zcrypto.ErrUnsupportedHash,
+		},
+		{
+			name:    "plain text",
+			hash:    "secret",
+			secret:  "secret",
+			wantErr: zcrypto.ErrUnsupportedHash,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := zcrypto.CompareSecret(tt.hash, tt.secret)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,368 @@
+package cwt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"unicode/utf8"
+)
+
+// maxDepth limits the nesting of decoded arrays, maps and tags.
+const maxDepth = 16
+
+// ErrCBOR is returned for data, which is not well-formed or not supported CBOR.
+var ErrCBOR = errors.New("malformed cbor")
+
+// cborTag is a tagged CBOR data item.
+type cborTag struct {
+	number  uint64
+	content any
+}
+
+const (
+	majorUint byte = iota
+	majorNegInt
+	majorBytes
+	majorText
+	majorArray
+	majorMap
+	majorTag
+	majorSimple
+)
+
+// cborMarshal encodes the value with the deterministic encoding of RFC 8949 section 4.2.1:
+// shortest argument encodings and map keys sorted by their encoding.
+// Integral floats are encoded as integers.
+func cborMarshal(v any) ([]byte, error) {
+	e := new(cborEncoder)
+	if err := e.encode(v); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+type cborEncoder struct {
+	buf []byte
+}
+
+func (e *cborEncoder) head(major byte, n uint64) {
+	switch {
+	case n < 24:
+		e.buf = append(e.buf, major<<5|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, major<<5|24, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, major<<5|25), uint16(n))
+	case n <= math.MaxUint32:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, major<<5|26), uint32(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, major<<5|27), n)
+	}
+}
+
+func (e *cborEncoder) int(n int64) {
+	if n < 0 {
+		e.head(majorNegInt, uint64(-1-n))
+		return
+	}
+	e.head(majorUint, uint64(n))
+}
+
+func (e *cborEncoder) float(f float64) {
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		e.int(int64(f))
+		return
+	}
+	e.buf = binary.BigEndian.AppendUint64(append(e.buf, majorSimple<<5|27), math.Float64bits(f))
+}
+
+func (e *cborEncoder) encode(v any) error {
+	switch v := v.(type) {
+	case nil:
+		e.buf = append(e.buf, 0xf6)
+	case bool:
+		if v {
+			e.buf = append(e.buf, 0xf5)
+		} else {
+			e.buf = append(e.buf, 0xf4)
+		}
+	case int:
+		e.int(int64(v))
+	case int32:
+		e.int(int64(v))
+	case int64:
+		e.int(v)
+	case uint32:
+		e.head(majorUint, uint64(v))
+	case uint64:
+		e.head(majorUint, v)
+	case float32:
+		e.float(float64(v))
+	case float64:
+		e.float(v)
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			e.int(n)
+			return nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return fmt.Errorf("%w: number %q: %w", ErrCBOR, v, err)
+		}
+		e.float(f)
+	case string:
+		e.head(majorText, uint64(len(v)))
+		e.buf = append(e.buf, v...)
+	case []byte:
+		e.head(majorBytes, uint64(len(v)))
+		e.buf = append(e.buf, v...)
+	case []string:
+		e.head(majorArray, uint64(len(v)))
+		for _, item := range v {
+			if err := e.encode(item); err != nil {
+				return err
+			}
+		}
+	case []any:
+		e.head(majorArray, uint64(len(v)))
+		for _, item := range v {
+			if err := e.encode(item); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		m := make(map[any]any, len(v))
+		for key, value := range v {
+			m[key] = value
+		}
+		return e.encodeMap(m)
+	case map[any]any:
+		return e.encodeMap(v)
+	case cborTag:
+		e.head(majorTag, v.number)
+		return e.encode(v.content)
+	default:
+		return fmt.Errorf("%w: unsupported type %T", ErrCBOR, v)
+	}
+	return nil
+}
+
+func (e *cborEncoder) encodeMap(m map[any]any) error {
+	type entry struct {
+		key, value []byte
+	}
+	entries := make([]entry, 0, len(m))
+	for key, value := range m {
+		k, err := cborMarshal(key)
+		if err != nil {
+			return err
+		}
+		v, err := cborMarshal(value)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{k, v})
+	}
+	slices.SortFunc(entries, func(a, b entry) int {
+		return bytes.Compare(a.key, b.key)
+	})
+	e.head(majorMap, uint64(len(entries)))
+	for _, entry := range entries {
+		e.buf = append(e.buf, entry.key...)
+		e.buf = append(e.buf, entry.value...)
+	}
+	return nil
+}
+
+// cborUnmarshal decodes a single data item, which must span the complete data.
+// Integers are decoded to int64 (uint64 if they exceed it), byte strings to []byte,
+// arrays to []any, maps to map[any]any and tags to cborTag.
+// Indefinite lengths are not supported.
+func cborUnmarshal(data []byte) (any, error) {
+	d := &cborDecoder{data: data}
+	v, err := d.decode(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.off != len(d.data) {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrCBOR, len(d.data)-d.off)
+	}
+	return v, nil
+}
+
+type cborDecoder struct {
+	data []byte
+	off  int
+}
+
+func (d *cborDecoder) next(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.off) {
+		return nil, fmt.Errorf("%w: unexpected end of data", ErrCBOR)
+	}
+	b := d.data[d.off : d.off+int(n)]
+	d.off += int(n)
+	return b, nil
+}
+
+func (d *cborDecoder) argument(info byte) (uint64, error) {
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info <= 27:
+		b, err := d.next(1 << (info - 24))
+		if err != nil {
+			return 0, err
+		}
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("%w: indefinite length or reserved additional information %d", ErrCBOR, info)
+	}
+}
+
+func (d *cborDecoder) decode(depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("%w: nesting exceeds %d", ErrCBOR, maxDepth)
+	}
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	if major == majorSimple {
+		return d.decodeSimple(info)
+	}
+	arg, err := d.argument(info)
+	if err != nil {
+		return nil, err
+	}
+	remaining := uint64(len(d.data) - d.off)
+	switch major {
+	case majorUint:
+		if arg > math.MaxInt64 {
+			return arg, nil
+		}
+		return int64(arg), nil
+	case majorNegInt:
+		if arg > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: negative integer overflows int64", ErrCBOR)
+		}
+		return -1 - int64(arg), nil
+	case majorBytes:
+		v, err := d.next(arg)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.Clone(v), nil
+	case majorText:
+		v, err := d.next(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !utf8.Valid(v) {
+			return nil, fmt.Errorf("%w: invalid utf-8 in text string", ErrCBOR)
+		}
+		return string(v), nil
+	case majorArray:
+		if arg > remaining {
+			return nil, fmt.Errorf("%w: array length exceeds data", ErrCBOR)
+		}
+		items := make([]any, arg)
+		for i := range items {
+			if items[i], err = d.decode(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	case majorMap:
+		if arg > remaining/2 {
+			return nil, fmt.Errorf("%w: map length exceeds data", ErrCBOR)
+		}
+		m := make(map[any]any, arg)
+		for range arg {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, uint64, string:
+			default:
+				return nil, fmt.Errorf("%w: unsupported map key type %T", ErrCBOR, key)
+			}
+			if _, ok := m[key]; ok {
+				return nil, fmt.Errorf("%w: duplicate map key %v", ErrCBOR, key)
+			}
+			if m[key], err = d.decode(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	default: // majorTag
+		content, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return cborTag{number: arg, content: content}, nil
+	}
+}
+
+func (d *cborDecoder) decodeSimple(info byte) (any, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		b, err := d.next(2)
+		if err != nil {
+			return nil, err
+		}
+		return halfToFloat(binary.BigEndian.Uint16(b)), nil
+	case 26:
+		b, err := d.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 27:
+		b, err := d.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported simple value %d", ErrCBOR, info)
+	}
+}
+
+// halfToFloat converts an IEEE 754 half-precision float, RFC 8949 appendix D.
+func halfToFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var v float64
+	switch exp {
+	case 0:
+		v = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			v = math.Inf(1)
+		} else {
+			v = math.NaN()
+		}
+	default:
+		v = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -v
+	}
+	return v
+}
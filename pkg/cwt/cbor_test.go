@@ -0,0 +1,111 @@
+package cwt
+
+import (
+	"encoding/hex"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Examples of RFC 8949 appendix A.
+func TestCBOR_examples(t *testing.T) {
+	tests := []struct {
+		value   any
+		encoded string
+	}{
+		{int64(0), "00"},
+		{int64(23), "17"},
+		{int64(24), "1818"},
+		{int64(1000), "1903e8"},
+		{int64(1000000000000), "1b000000e8d4a51000"},
+		{uint64(18446744073709551615), "1bffffffffffffffff"},
+		{int64(-1), "20"},
+		{int64(-1000), "3903e7"},
+		{1.1, "fb3ff199999999999a"},
+		{false, "f4"},
+		{true, "f5"},
+		{nil, "f6"},
+		{[]byte{1, 2, 3, 4}, "4401020304"},
+		{"IETF", "6449455446"},
+		{"ü", "62c3bc"},
+		{[]any{int64(1), []any{int64(2), int64(3)}}, "8201820203"},
+		{map[any]any{int64(1): int64(2), int64(3): int64(4)}, "a201020304"},
+		{map[any]any{"a": int64(1), "b": []any{int64(2), int64(3)}}, "a26161016162820203"},
+		{cborTag{number: 1, content: int64(1363896240)}, "c11a514b67b0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			encoded, err := cborMarshal(tt.value)
+			require.NoError(t, err)
+			assert.Equal(t, tt.encoded, hex.EncodeToString(encoded))
+
+			decoded, err := cborUnmarshal(encoded)
+			require.NoError(t, err)
+			assert.Equal(t, tt.value, decoded)
+		})
+	}
+}
+
+func TestCBOR_deterministic(t *testing.T) {
+	encoded, err := cborMarshal(map[any]any{"aa": int64(1), "b": int64(2), int64(10): int64(3), int64(-1): int64(4)})
+	require.NoError(t, err)
+	assert.Equal(t, "a40a03200461620262616101", hex.EncodeToString(encoded))
+
+	encoded, err = cborMarshal(3.0)
+	require.NoError(t, err)
+	assert.Equal(t, "03", hex.EncodeToString(encoded), "integral floats")
+}
+
+func TestCBOR_decodeFloats(t *testing.T) {
+	tests := []struct {
+		encoded string
+		want    float64
+	}{
+		{"f93c00", 1},
+		{"f97bff", 65504},
+		{"f90001", 5.960464477539063e-8},
+		{"f9c400", -4},
+		{"fa47c35000", 100000},
+		{"f97c00", math.Inf(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.encoded, func(t *testing.T) {
+			data, err := hex.DecodeString(tt.encoded)
+			require.NoError(t, err)
+			got, err := cborUnmarshal(data)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCBOR_malformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"empty", ""},
+		{"truncated argument", "19e8"},
+		{"truncated string", "6449455446ff"[:8]},
+		{"trailing bytes", "0000"},
+		{"indefinite length", "9f01ff"},
+		{"invalid utf-8", "61ff"},
+		{"array exceeding data", "9bffffffffffffffff"},
+		{"map exceeding data", "a2010203"},
+		{"duplicate key", "a201020103"},
+		{"byte string key", "a1410102"},
+		{"negative overflow", "3bffffffffffffffff"},
+		{"nesting", "8181818181818181818181818181818181818100"},
+		{"simple value", "f0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := hex.DecodeString(tt.encoded)
+			require.NoError(t, err)
+			_, err = cborUnmarshal(data)
+			assert.ErrorIs(t, err, ErrCBOR)
+		})
+	}
+}
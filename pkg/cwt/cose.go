@@ -0,0 +1,246 @@
+package cwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
+	jose "github.com/go-jose/go-jose/v4"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// COSE tags, labels and algorithm identifiers of RFC 9052 and RFC 9053.
+const (
+	tagCOSESign1 = 18
+	tagCWT       = 61
+
+	headerAlg int64 = 1
+	headerKID int64 = 4
+)
+
+var coseAlgorithms = map[jose.SignatureAlgorithm]int64{
+	jose.ES256: -7,
+	jose.EdDSA: -8,
+	jose.ES384: -35,
+	jose.ES512: -36,
+	jose.PS256: -37,
+	jose.PS384: -38,
+	jose.PS512: -39,
+	jose.RS256: -257,
+	jose.RS384: -258,
+	jose.RS512: -259,
+}
+
+func joseAlgorithm(id int64) (jose.SignatureAlgorithm, bool) {
+	for alg, algID := range coseAlgorithms {
+		if algID == id {
+			return alg, true
+		}
+	}
+	return "", false
+}
+
+func hashOf(alg jose.SignatureAlgorithm) crypto.Hash {
+	switch alg {
+	case jose.ES256, jose.RS256, jose.PS256:
+		return crypto.SHA256
+	case jose.ES384, jose.RS384, jose.PS384:
+		return crypto.SHA384
+	case jose.ES512, jose.RS512, jose.PS512:
+		return crypto.SHA512
+	default:
+		return 0
+	}
+}
+
+func curveOf(alg jose.SignatureAlgorithm) elliptic.Curve {
+	switch alg {
+	case jose.ES256:
+		return elliptic.P256()
+	case jose.ES384:
+		return elliptic.P384()
+	case jose.ES512:
+		return elliptic.P521()
+	default:
+		return nil
+	}
+}
+
+// sigStructure returns the Sig_structure of a COSE_Sign1 without external data, RFC 9052 section 4.4.
+func sigStructure(protected, payload []byte) ([]byte, error) {
+	return cborMarshal([]any{"Signature1", protected, []byte{}, payload})
+}
+
+// sign1 returns the tagged COSE_Sign1 message of the payload.
+func sign1(payload []byte, alg jose.SignatureAlgorithm, key crypto.Signer, keyID string) ([]byte, error) {
+	algID, ok := coseAlgorithms[alg]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", oidc.ErrSignatureUnsupportedAlg, alg)
+	}
+	protected, err := cborMarshal(map[any]any{headerAlg: algID})
+	if err != nil {
+		return nil, err
+	}
+	unprotected := map[any]any{}
+	if keyID != "" {
+		unprotected[headerKID] = []byte(keyID)
+	}
+	toBeSigned, err := sigStructure(protected, payload)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := signMessage(alg, key, toBeSigned)
+	if err != nil {
+		return nil, err
+	}
+	return cborMarshal(cborTag{number: tagCOSESign1, content: []any{protected, unprotected, payload, signature}})
+}
+
+func signMessage(alg jose.SignatureAlgorithm, key crypto.Signer, message []byte) ([]byte, error) {
+	if alg == jose.EdDSA {
+		return key.Sign(rand.Reader, message, crypto.Hash(0))
+	}
+	h := hashOf(alg)
+	digest := h.New()
+	digest.Write(message)
+	sum := digest.Sum(nil)
+	switch alg {
+	case jose.PS256, jose.PS384, jose.PS512:
+		return key.Sign(rand.Reader, sum, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: h})
+	case jose.ES256, jose.ES384, jose.ES512:
+		der, err := key.Sign(rand.Reader, sum, h)
+		if err != nil {
+			return nil, err
+		}
+		// COSE uses the fixed size concatenation of r and s instead of ASN.1, RFC 9053 section 2.1.
+		var sig struct{ R, S *big.Int }
+		if _, err = asn1.Unmarshal(der, &sig); err != nil {
+			return nil, err
+		}
+		size := (curveOf(alg).Params().BitSize + 7) / 8
+		return append(sig.R.FillBytes(make([]byte, size)), sig.S.FillBytes(make([]byte, size))...), nil
+	default:
+		return key.Sign(rand.Reader, sum, h)
+	}
+}
+
+// verify1 verifies the COSE_Sign1 message, optionally tagged as CWT,
+// with the key of its kid and returns the payload.
+func verify1(message []byte, keys func(keyID string) (crypto.PublicKey, error)) ([]byte, error) {
+	v, err := cborUnmarshal(message)
+	if err != nil {
+		return nil, err
+	}
+	if tag, ok := v.(cborTag); ok && tag.number == tagCWT {
+		v = tag.content
+	}
+	if tag, ok := v.(cborTag); ok && tag.number == tagCOSESign1 {
+		v = tag.content
+	}
+	parts, ok := v.([]any)
+	if !ok || len(parts) != 4 {
+		return nil, fmt.Errorf("%w: not a COSE_Sign1 message", oidc.ErrParse)
+	}
+	protected, ok1 := parts[0].([]byte)
+	unprotected, ok2 := parts[1].(map[any]any)
+	payload, ok3 := parts[2].([]byte)
+	signature, ok4 := parts[3].([]byte)
+	if !ok1 || !ok2 || !ok3 || !ok4 {
+		return nil, fmt.Errorf("%w: malformed COSE_Sign1 message", oidc.ErrParse)
+	}
+	header := map[any]any{}
+	if len(protected) > 0 {
+		v, err := cborUnmarshal(protected)
+		if err != nil {
+			return nil, err
+		}
+		if header, ok = v.(map[any]any); !ok {
+			return nil, fmt.Errorf("%w: malformed protected header", oidc.ErrParse)
+		}
+	}
+	algID, ok := header[headerAlg].(int64)
+	if !ok {
+		return nil, fmt.Errorf("%w: protected alg header missing", oidc.ErrSignatureUnsupportedAlg)
+	}
+	alg, ok := joseAlgorithm(algID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", oidc.ErrSignatureUnsupportedAlg, algID)
+	}
+	keyID, ok := header[headerKID].([]byte)
+	if !ok {
+		keyID, _ = unprotected[headerKID].([]byte)
+	}
+	key, err := keys(string(keyID))
+	if err != nil {
+		return nil, err
+	}
+	toBeSigned, err := sigStructure(protected, payload)
+	if err != nil {
+		return nil, err
+	}
+	if err = verifyMessage(alg, key, toBeSigned, signature); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+var errKeyType = errors.New("key type does not match the algorithm")
+
+func verifyMessage(alg jose.SignatureAlgorithm, key crypto.PublicKey, message, signature []byte) error {
+	if alg == jose.EdDSA {
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: %w", oidc.ErrSignatureInvalid, errKeyType)
+		}
+		if !ed25519.Verify(pub, message, signature) {
+			return oidc.ErrSignatureInvalid
+		}
+		return nil
+	}
+	h := hashOf(alg)
+	digest := h.New()
+	digest.Write(message)
+	sum := digest.Sum(nil)
+	switch alg {
+	case jose.ES256, jose.ES384, jose.ES512:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve != curveOf(alg) {
+			return fmt.Errorf("%w: %w", oidc.ErrSignatureInvalid, errKeyType)
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return oidc.ErrSignatureInvalid
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, sum, r, s) {
+			return oidc.ErrSignatureInvalid
+		}
+		return nil
+	default:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: %w", oidc.ErrSignatureInvalid, errKeyType)
+		}
+		switch alg {
+		case jose.PS256, jose.PS384, jose.PS512:
+			err := rsa.VerifyPSS(pub, h, sum, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: h})
+			if err != nil {
+				return fmt.Errorf("%w: %w", oidc.ErrSignatureInvalid, err)
+			}
+		default:
+			if err := rsa.VerifyPKCS1v15(pub, h, sum, signature); err != nil {
+				return fmt.Errorf("%w: %w", oidc.ErrSignatureInvalid, err)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,295 @@
+// Package cwt implements CBOR Web Tokens (RFC 8392) signed with COSE_Sign1 (RFC 9052)
+// as a compact access token format for constrained resource servers.
+//
+// The [AccessTokenFormat] issues them from the OP, see op.WithAccessTokenFormat,
+// and the [Verifier] verifies them at the resource server.
+//
+// The package is a separate module, github.com/zitadel/oidc/v3/pkg/cwt,
+// so the CBOR and COSE encoding is not part of the oidc module.
+package cwt
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// Claim keys of RFC 8392 section 3.1 and the scope claim of RFC 8693.
+// All other claims are encoded with their JSON names as text keys.
+const (
+	claimIss   int64 = 1
+	claimSub   int64 = 2
+	claimAud   int64 = 3
+	claimExp   int64 = 4
+	claimNbf   int64 = 5
+	claimIat   int64 = 6
+	claimCti   int64 = 7
+	claimScope int64 = 9
+)
+
+var registeredClaims = map[string]int64{
+	"iss":   claimIss,
+	"sub":   claimSub,
+	"aud":   claimAud,
+	"exp":   claimExp,
+	"nbf":   claimNbf,
+	"iat":   claimIat,
+	"jti":   claimCti,
+	"scope": claimScope,
+}
+
+var (
+	ErrKeyNotFound  = errors.New("no verification key found for kid")
+	ErrNotYetValid  = errors.New("token is not yet valid")
+	ErrExpMissing   = errors.New("expiration of token is missing")
+	ErrInvalidClaim = errors.New("invalid claim")
+)
+
+// Signer signs CWTs with a COSE_Sign1 structure.
+type Signer struct {
+	alg   jose.SignatureAlgorithm
+	key   crypto.Signer
+	keyID string
+}
+
+// NewSigner returns a Signer for one of the ES256, ES384, ES512, EdDSA,
+// RS256, RS384, RS512, PS256, PS384 or PS512 algorithms.
+// The keyID is set as kid to the unprotected header, if not empty.
+func NewSigner(alg jose.SignatureAlgorithm, key crypto.Signer, keyID string) (*Signer, error) {
+	if _, ok := coseAlgorithms[alg]; !ok {
+		return nil, fmt.Errorf("%w: %q", oidc.ErrSignatureUnsupportedAlg, alg)
+	}
+	if key == nil {
+		return nil, errors.New("cwt signer requires a key")
+	}
+	return &Signer{alg: alg, key: key, keyID: keyID}, nil
+}
+
+// Sign returns the CWT of the claims, tagged as COSE_Sign1 message.
+func (s *Signer) Sign(claims *oidc.AccessTokenClaims) ([]byte, error) {
+	payload, err := encodeClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+	return sign1(payload, s.alg, s.key, s.keyID)
+}
+
+// Verifier verifies CWTs signed by a [Signer].
+type Verifier struct {
+	// Issuer, which must match the iss claim.
+	Issuer string
+	// Audience, which must be contained in the aud claim, if not empty.
+	Audience string
+	// Keys by their kid. A key with an empty kid is used for tokens without kid.
+	Keys map[string]crypto.PublicKey
+	// Offset is the allowed clock skew for the exp and nbf claims.
+	Offset time.Duration
+}
+
+// KeysFromJWKS returns the public keys of the key set by their kid,
+// e.g. of the jwks_uri of the OP, to be used in the [Verifier].
+func KeysFromJWKS(keySet *jose.JSONWebKeySet) map[string]crypto.PublicKey {
+	keys := make(map[string]crypto.PublicKey, len(keySet.Keys))
+	for _, key := range keySet.Keys {
+		if key.Use != "" && key.Use != oidc.KeyUseSignature {
+			continue
+		}
+		keys[key.KeyID] = key.Public().Key
+	}
+	return keys
+}
+
+// Verify verifies the signature, issuer, audience, expiration and not before of the CWT
+// and returns its claims.
+func (v *Verifier) Verify(token []byte) (*oidc.AccessTokenClaims, error) {
+	payload, err := verify1(token, func(keyID string) (crypto.PublicKey, error) {
+		key, ok := v.Keys[keyID]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrKeyNotFound, keyID)
+		}
+		return key, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	claims, err := decodeClaims(payload)
+	if err != nil {
+		return nil, err
+	}
+	if err = oidc.CheckIssuer(claims, v.Issuer); err != nil {
+		return nil, err
+	}
+	if v.Audience != "" {
+		if err = oidc.CheckAudience(claims, v.Audience); err != nil {
+			return nil, err
+		}
+	}
+	if claims.Expiration == 0 {
+		return nil, ErrExpMissing
+	}
+	if err = oidc.CheckExpiration(claims, -v.Offset); err != nil {
+		return nil, err
+	}
+	if claims.NotBefore != 0 && time.Now().Add(v.Offset).Before(claims.NotBefore.AsTime()) {
+		return nil, ErrNotYetValid
+	}
+	return claims, nil
+}
+
+// AccessTokenFormat issues and verifies access tokens as base64url encoded CWTs.
+// It implements op.AccessTokenFormat.
+type AccessTokenFormat struct {
+	Signer   *Signer
+	Verifier *Verifier
+}
+
+// EncodeAccessToken returns the base64url encoded CWT of the claims.
+func (f *AccessTokenFormat) EncodeAccessToken(_ context.Context, claims *oidc.AccessTokenClaims) (string, error) {
+	token, err := f.Signer.Sign(claims)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// DecodeAccessToken verifies the base64url encoded CWT and returns its claims.
+func (f *AccessTokenFormat) DecodeAccessToken(_ context.Context, token string) (*oidc.AccessTokenClaims, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", oidc.ErrParse, err)
+	}
+	return f.Verifier.Verify(data)
+}
+
+// encodeClaims returns the CBOR claims set of the JSON representation of the claims.
+func encodeClaims(claims *oidc.AccessTokenClaims) ([]byte, error) {
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	var jsonClaims map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err = dec.Decode(&jsonClaims); err != nil {
+		return nil, err
+	}
+	claimsSet := make(map[any]any, len(jsonClaims))
+	for name, value := range jsonClaims {
+		key, ok := registeredClaims[name]
+		if !ok {
+			claimsSet[name] = value
+			continue
+		}
+		switch key {
+		case claimCti:
+			jti, _ := value.(string)
+			value = []byte(jti)
+		case claimAud:
+			if aud, ok := value.([]any); ok && len(aud) == 1 {
+				value = aud[0]
+			}
+		}
+		claimsSet[key] = value
+	}
+	return cborMarshal(claimsSet)
+}
+
+// decodeClaims returns the claims of the CBOR claims set.
+func decodeClaims(payload []byte) (*oidc.AccessTokenClaims, error) {
+	v, err := cborUnmarshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	claimsSet, ok := v.(map[any]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: claims set is not a map", oidc.ErrParse)
+	}
+	jsonClaims := make(map[string]any, len(claimsSet))
+	for key, value := range claimsSet {
+		switch key := key.(type) {
+		case string:
+			if _, ok := registeredClaims[key]; ok {
+				return nil, fmt.Errorf("%w: %q must use its integer key", ErrInvalidClaim, key)
+			}
+			if jsonClaims[key], err = jsonValue(value); err != nil {
+				return nil, err
+			}
+		case int64:
+			name, ok := claimName(key)
+			if !ok {
+				continue // ignore unknown registered claims
+			}
+			if key == claimCti {
+				cti, ok := value.([]byte)
+				if !ok {
+					return nil, fmt.Errorf("%w: cti must be a byte string", ErrInvalidClaim)
+				}
+				value = string(cti)
+			}
+			if jsonClaims[name], err = jsonValue(value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	data, err := json.Marshal(jsonClaims)
+	if err != nil {
+		return nil, err
+	}
+	claims := new(oidc.AccessTokenClaims)
+	if err = json.Unmarshal(data, claims); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidClaim, err)
+	}
+	return claims, nil
+}
+
+func claimName(key int64) (string, bool) {
+	for name, k := range registeredClaims {
+		if k == key {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// jsonValue converts decoded CBOR to values, which can be marshaled to JSON.
+func jsonValue(v any) (any, error) {
+	switch v := v.(type) {
+	case []any:
+		items := make([]any, len(v))
+		for i, item := range v {
+			var err error
+			if items[i], err = jsonValue(item); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, value := range v {
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: nested map key %v is not a text string", ErrInvalidClaim, key)
+			}
+			var err error
+			if m[name], err = jsonValue(value); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case []byte:
+		return base64.RawURLEncoding.EncodeToString(v), nil
+	case cborTag:
+		return nil, fmt.Errorf("%w: unsupported tag %d", ErrInvalidClaim, v.number)
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,208 @@
+package cwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+func testClaims() *oidc.AccessTokenClaims {
+	claims := oidc.NewAccessTokenClaims("https://op.example.com", "user", []string{"sensor"}, time.Now().Add(time.Hour), "token1", "device", 0)
+	claims.Scopes = []string{"read", "write"}
+	claims.Confirmation = &oidc.Confirmation{JWKThumbprint: "thumbprint"}
+	claims.Claims = map[string]any{"room": "42", "level": float64(3)}
+	return claims
+}
+
+func TestSigner_Verifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecKey384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	ecKey521, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		alg jose.SignatureAlgorithm
+		key crypto.Signer
+	}{
+		{jose.ES256, ecKey},
+		{jose.ES384, ecKey384},
+		{jose.ES512, ecKey521},
+		{jose.EdDSA, edKey},
+		{jose.RS256, rsaKey},
+		{jose.PS384, rsaKey},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.alg), func(t *testing.T) {
+			signer, err := NewSigner(tt.alg, tt.key, "key1")
+			require.NoError(t, err)
+			token, err := signer.Sign(testClaims())
+			require.NoError(t, err)
+
+			verifier := &Verifier{
+				Issuer:   "https://op.example.com",
+				Audience: "sensor",
+				Keys:     map[string]crypto.PublicKey{"key1": tt.key.Public()},
+			}
+			claims, err := verifier.Verify(token)
+			require.NoError(t, err)
+			want := testClaims()
+			assert.Equal(t, want.Issuer, claims.Issuer)
+			assert.Equal(t, want.Subject, claims.Subject)
+			assert.Equal(t, want.Audience, claims.Audience)
+			assert.Equal(t, want.Expiration, claims.Expiration)
+			assert.Equal(t, want.ClientID, claims.ClientID)
+			assert.Equal(t, "token1", claims.JWTID)
+			assert.Equal(t, want.Scopes, claims.Scopes)
+			assert.Equal(t, want.Confirmation, claims.Confirmation)
+			assert.Equal(t, "42", claims.Claims["room"])
+			assert.Equal(t, float64(3), claims.Claims["level"])
+		})
+	}
+}
+
+func TestSigner_claimKeys(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := NewSigner(jose.EdDSA, key, "")
+	require.NoError(t, err)
+	token, err := signer.Sign(testClaims())
+	require.NoError(t, err)
+
+	v, err := cborUnmarshal(token)
+	require.NoError(t, err)
+	tag, ok := v.(cborTag)
+	require.True(t, ok)
+	assert.Equal(t, uint64(tagCOSESign1), tag.number)
+	payload, err := cborUnmarshal(tag.content.([]any)[2].([]byte))
+	require.NoError(t, err)
+	claimsSet := payload.(map[any]any)
+	assert.Equal(t, "https://op.example.com", claimsSet[claimIss])
+	assert.Equal(t, "sensor", claimsSet[claimAud], "single audience as text string")
+	assert.Equal(t, []byte("token1"), claimsSet[claimCti])
+	assert.Equal(t, "read write", claimsSet[claimScope])
+	assert.IsType(t, int64(0), claimsSet[claimExp])
+	assert.Equal(t, "device", claimsSet["client_id"])
+	assert.NotContains(t, claimsSet, "iss")
+}
+
+func TestVerifier_Verify_errors(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signer, err := NewSigner(jose.ES256, key, "key1")
+	require.NoError(t, err)
+	sign := func(modify func(*oidc.AccessTokenClaims)) []byte {
+		claims := testClaims()
+		modify(claims)
+		token, err := signer.Sign(claims)
+		require.NoError(t, err)
+		return token
+	}
+	valid := sign(func(*oidc.AccessTokenClaims) {})
+	tampered := append([]byte{}, valid...)
+	tampered[len(tampered)-1] ^= 1
+	// wrapped in the CWT tag, which is accepted
+	tagged, err := cborMarshal(cborTag{number: tagCWT, content: mustUnmarshal(t, valid)})
+	require.NoError(t, err)
+
+	verifier := &Verifier{
+		Issuer:   "https://op.example.com",
+		Audience: "sensor",
+		Keys:     map[string]crypto.PublicKey{"key1": key.Public()},
+	}
+	tests := []struct {
+		name     string
+		token    []byte
+		verifier *Verifier
+		wantErr  error
+	}{
+		{"tagged", tagged, verifier, nil},
+		{"signature", tampered, verifier, oidc.ErrSignatureInvalid},
+		{"other key", valid, &Verifier{Issuer: verifier.Issuer, Keys: map[string]crypto.PublicKey{"key1": otherKey.Public()}}, oidc.ErrSignatureInvalid},
+		{"key type", valid, &Verifier{Issuer: verifier.Issuer, Keys: map[string]crypto.PublicKey{"key1": ed25519.PublicKey(make([]byte, 32))}}, oidc.ErrSignatureInvalid},
+		{"unknown kid", valid, &Verifier{Issuer: verifier.Issuer, Keys: map[string]crypto.PublicKey{"key2": key.Public()}}, ErrKeyNotFound},
+		{"issuer", valid, &Verifier{Issuer: "https://other.example.com", Keys: verifier.Keys}, oidc.ErrIssuerInvalid},
+		{"audience", valid, &Verifier{Issuer: verifier.Issuer, Audience: "other", Keys: verifier.Keys}, oidc.ErrAudience},
+		{"expired", sign(func(c *oidc.AccessTokenClaims) { c.Expiration = oidc.FromTime(time.Now().Add(-time.Minute)) }), verifier, oidc.ErrExpired},
+		{"expiration missing", sign(func(c *oidc.AccessTokenClaims) { c.Expiration = 0 }), verifier, ErrExpMissing},
+		{"not yet valid", sign(func(c *oidc.AccessTokenClaims) { c.NotBefore = oidc.FromTime(time.Now().Add(time.Minute)) }), verifier, ErrNotYetValid},
+		{"not cbor", []byte("not a token"), verifier, ErrCBOR},
+		{"not cose", []byte{0x80}, verifier, oidc.ErrParse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.verifier.Verify(tt.token)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func mustUnmarshal(t *testing.T, data []byte) any {
+	v, err := cborUnmarshal(data)
+	require.NoError(t, err)
+	return v
+}
+
+func TestNewSigner_unsupportedAlg(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, err = NewSigner(jose.HS256, key, "")
+	assert.ErrorIs(t, err, oidc.ErrSignatureUnsupportedAlg)
+}
+
+func TestAccessTokenFormat(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := NewSigner(jose.EdDSA, key, "key1")
+	require.NoError(t, err)
+	format := &AccessTokenFormat{
+		Signer:   signer,
+		Verifier: &Verifier{Issuer: "https://op.example.com", Keys: map[string]crypto.PublicKey{"key1": key.Public()}},
+	}
+	ctx := context.Background()
+	token, err := format.EncodeAccessToken(ctx, testClaims())
+	require.NoError(t, err)
+	_, err = base64.RawURLEncoding.DecodeString(token)
+	require.NoError(t, err)
+
+	claims, err := format.DecodeAccessToken(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, "user", claims.Subject)
+
+	_, err = format.DecodeAccessToken(ctx, "eyJhbGciOiJSUzI1NiJ9.e30.c2ln")
+	assert.Error(t, err, "JWT")
+}
+
+func TestKeysFromJWKS(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keys := KeysFromJWKS(&jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: key.Public(), KeyID: "sig", Use: oidc.KeyUseSignature},
+		{Key: key.Public(), KeyID: "enc", Use: "enc"},
+	}})
+	assert.Equal(t, map[string]crypto.PublicKey{"sig": key.Public()}, keys)
+}
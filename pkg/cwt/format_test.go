@@ -0,0 +1,53 @@
+package cwt_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	"github.com/zitadel/oidc/v3/pkg/cwt"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/optest"
+)
+
+func TestAccessTokenFormat_cwt(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := cwt.NewSigner(jose.EdDSA, key, "cwt1")
+	require.NoError(t, err)
+	verifier := &cwt.Verifier{
+		Audience: optest.WebClientID,
+		Keys:     map[string]crypto.PublicKey{"cwt1": key.Public()},
+	}
+	s := optest.New(t,
+		optest.WithClients(
+			optest.WebClient(optest.WebClientID, optest.WebClientSecret, optest.RedirectURI).WithAccessTokenFormat("cwt"),
+		),
+		optest.WithProviderOptions(op.WithAccessTokenFormat("cwt", &cwt.AccessTokenFormat{Signer: signer, Verifier: verifier})),
+	)
+	verifier.Issuer = s.Issuer
+	relyingParty := s.RelyingParty(t)
+
+	tokens := s.CodeFlow(t, relyingParty)
+	claims, err := (&cwt.AccessTokenFormat{Verifier: verifier}).DecodeAccessToken(context.Background(), tokens.AccessToken)
+	require.NoError(t, err, "access token must be a CWT")
+	assert.Equal(t, optest.UserID, claims.Subject)
+	assert.Equal(t, optest.WebClientID, claims.ClientID)
+
+	userinfo, err := rp.Userinfo[*oidc.UserInfo](context.Background(), tokens.AccessToken, tokens.TokenType, tokens.IDTokenClaims.Subject, relyingParty)
+	require.NoError(t, err)
+	assert.Equal(t, optest.UserID, userinfo.Subject)
+
+	refreshed, err := rp.RefreshTokens[*oidc.IDTokenClaims](context.Background(), relyingParty, tokens.RefreshToken, "", "")
+	require.NoError(t, err)
+	_, err = (&cwt.AccessTokenFormat{Verifier: verifier}).DecodeAccessToken(context.Background(), refreshed.AccessToken)
+	assert.NoError(t, err, "refreshed access token must be a CWT")
+}
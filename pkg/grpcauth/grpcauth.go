@@ -0,0 +1,84 @@
+// Package grpcauth authorizes gRPC calls with OAuth2 access tokens.
+//
+// The server interceptors introspect the bearer token of the authorization metadata
+// with a [rs.ResourceServer] and the [PerRPCCredentials] of the client send
+// the tokens of a token source.
+//
+// The package is a separate module, github.com/zitadel/oidc/v3/pkg/grpcauth,
+// so google.golang.org/grpc is not a dependency of the oidc module.
+package grpcauth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/zitadel/oidc/v3/pkg/client"
+	"github.com/zitadel/oidc/v3/pkg/client/rs"
+)
+
+var _ credentials.PerRPCCredentials = (*client.TokenSourceCredentials)(nil)
+
+// UnaryServerInterceptor returns an interceptor, which only passes calls with an active bearer token
+// to the handler. The introspection response is available to the handler by [rs.IntrospectionFromContext].
+// Calls without an active token fail with codes.Unauthenticated,
+// and with codes.Unavailable if the token could not be introspected.
+func UnaryServerInterceptor(server rs.ResourceServer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authorize(ctx, server)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns the stream interceptor of [UnaryServerInterceptor].
+func StreamServerInterceptor(server rs.ResourceServer) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorize(stream.Context(), server)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// PerRPCCredentials returns the credentials sending the access tokens of the source,
+// such as the profile.NewJWTProfileTokenSource or a client credentials token source,
+// to be passed to grpc.WithPerRPCCredentials, see [client.NewTokenSourceCredentials].
+func PerRPCCredentials(source oauth2.TokenSource, insecure bool) credentials.PerRPCCredentials {
+	return client.NewTokenSourceCredentials(source, insecure)
+}
+
+// authorize returns the context with the introspection response of the active token
+// of the incoming metadata.
+func authorize(ctx context.Context, server rs.ResourceServer) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	resp, err := rs.AuthorizeMetadata(ctx, server, md)
+	if err == nil {
+		return rs.ContextWithIntrospection(ctx, resp), nil
+	}
+	if errors.Is(err, rs.ErrTokenMissing) || errors.Is(err, rs.ErrTokenInactive) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	slog.ErrorContext(ctx, "grpcauth: introspection failed", "error", err)
+	return nil, status.Error(codes.Unavailable, "token introspection failed")
+}
+
+// serverStream passes the context with the introspection response to stream handlers.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
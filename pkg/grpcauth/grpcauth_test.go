@@ -0,0 +1,108 @@
+package grpcauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/zitadel/oidc/v3/pkg/client/rs"
+)
+
+func newResourceServer(t *testing.T) rs.ResourceServer {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		w.Header().Set("Content-Type", "application/json")
+		switch r.PostForm.Get("token") {
+		case "active":
+			w.Write([]byte(`{"active":true,"sub":"user","scope":"read"}`))
+		case "outage":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(`{"active":false}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	resourceServer, err := rs.NewResourceServerClientCredentials(context.Background(), server.URL, "api", "secret",
+		rs.WithClient(server.Client()), rs.WithStaticEndpoints(server.URL+"/token", server.URL+"/introspect"))
+	require.NoError(t, err)
+	return resourceServer
+}
+
+func incomingContext(authorization string) context.Context {
+	md := metadata.MD{}
+	if authorization != "" {
+		md.Set("authorization", authorization)
+	}
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+var authorizeTests = []struct {
+	name          string
+	authorization string
+	wantCode      codes.Code
+}{
+	{"active", "Bearer active", codes.OK},
+	{"missing", "", codes.Unauthenticated},
+	{"inactive", "Bearer inactive", codes.Unauthenticated},
+	{"outage", "Bearer outage", codes.Unavailable},
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor(newResourceServer(t))
+	handler := func(ctx context.Context, req any) (any, error) {
+		resp, ok := rs.IntrospectionFromContext(ctx)
+		require.True(t, ok)
+		return resp.Subject, nil
+	}
+	for _, tt := range authorizeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := interceptor(incomingContext(tt.authorization), nil, &grpc.UnaryServerInfo{}, handler)
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			if tt.wantCode == codes.OK {
+				assert.Equal(t, "user", got)
+			}
+		})
+	}
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	interceptor := StreamServerInterceptor(newResourceServer(t))
+	handler := func(srv any, stream grpc.ServerStream) error {
+		resp, ok := rs.IntrospectionFromContext(stream.Context())
+		require.True(t, ok)
+		assert.Equal(t, "user", resp.Subject)
+		return nil
+	}
+	for _, tt := range authorizeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := interceptor(nil, testServerStream{ctx: incomingContext(tt.authorization)}, &grpc.StreamServerInfo{}, handler)
+			assert.Equal(t, tt.wantCode, status.Code(err))
+		})
+	}
+}
+
+func TestPerRPCCredentials(t *testing.T) {
+	creds := PerRPCCredentials(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token", TokenType: "Bearer"}), false)
+	md, err := creds.GetRequestMetadata(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"authorization": "Bearer token"}, md)
+	assert.True(t, creds.RequireTransportSecurity())
+}
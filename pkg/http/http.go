@@ -63,7 +63,13 @@ func HttpRequest(client *http.Client, req *http.Request, response any) error {
 		return err
 	}
 	defer resp.Body.Close()
+	return HttpResponse(resp, response)
+}
 
+// HttpResponse reads the JSON body of the resp into response.
+// A status other than 200 returns an [*oidc.Error], if the body contains one.
+// The caller must close the body.
+func HttpResponse(resp *http.Response, response any) error {
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return fmt.Errorf("unable to read response body: %v", err)
@@ -0,0 +1,224 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by the [PolicyTransport] for requests to an endpoint,
+// whose circuit breaker is open after too many consecutive failures.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+const (
+	defaultBackoff    = 100 * time.Millisecond
+	defaultMaxBackoff = 2 * time.Second
+)
+
+// Policy of the outbound requests to an endpoint.
+// The zero value sends requests once, without timeout and circuit breaker.
+type Policy struct {
+	// Timeout of a single attempt, including reading the response body.
+	// Zero means no timeout besides the one of the http.Client.
+	Timeout time.Duration
+	// MaxRetries of requests failing with a connection error or a 5xx status.
+	// Only idempotent requests (GET, HEAD, OPTIONS) are retried,
+	// other requests only if the connection could not be established.
+	MaxRetries int
+	// Backoff before the first retry, which is doubled on every retry up to MaxBackoff.
+	// Defaults to 100ms and 2s.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// FailureThreshold of consecutive failed requests, which opens the circuit breaker of the endpoint.
+	// Requests to an open circuit fail with [ErrCircuitOpen] for the OpenDuration,
+	// afterwards a single request probes whether the endpoint recovered.
+	// Zero disables the circuit breaker.
+	FailureThreshold int
+	OpenDuration     time.Duration
+}
+
+// PolicyTransport applies the [Policy] returned by PolicyFor to the requests.
+// Requests without policy (nil) are passed to the Base transport unchanged.
+// Circuit breakers are kept per endpoint (host and path).
+type PolicyTransport struct {
+	// Base defaults to [http.DefaultTransport].
+	Base      http.RoundTripper
+	PolicyFor func(*http.Request) *Policy
+
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+func (t *PolicyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	policy := t.PolicyFor(req)
+	if policy == nil {
+		return t.base().RoundTrip(req)
+	}
+	breaker := t.breaker(req, policy)
+	if !breaker.allow() {
+		return nil, ErrCircuitOpen
+	}
+	backoff := policy.Backoff
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+	maxBackoff := policy.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+	for attempt := 0; ; attempt++ {
+		resp, err := t.attempt(req, policy)
+		failed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+		if !failed || attempt >= policy.MaxRetries || !retryable(req, err) {
+			if req.Context().Err() != nil {
+				breaker.release()
+			} else {
+				breaker.record(failed)
+			}
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		select {
+		case <-req.Context().Done():
+			breaker.release()
+			return nil, req.Context().Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				breaker.release()
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+func (t *PolicyTransport) attempt(req *http.Request, policy *Policy) (*http.Response, error) {
+	if policy.Timeout <= 0 {
+		return t.base().RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), policy.Timeout)
+	resp, err := t.base().RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func (t *PolicyTransport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+func (t *PolicyTransport) breaker(req *http.Request, policy *Policy) *circuitBreaker {
+	if policy.FailureThreshold <= 0 {
+		return nil
+	}
+	key := req.URL.Host + req.URL.Path
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.breakers == nil {
+		t.breakers = make(map[string]*circuitBreaker)
+	}
+	breaker, ok := t.breakers[key]
+	if !ok {
+		breaker = &circuitBreaker{threshold: policy.FailureThreshold, openDuration: policy.OpenDuration}
+		t.breakers[key] = breaker
+	}
+	return breaker
+}
+
+// retryable reports whether the failed request can be sent again:
+// idempotent requests with a replayable body,
+// or any request which could not be sent at all.
+func retryable(req *http.Request, err error) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+type circuitBreaker struct {
+	threshold    int
+	openDuration time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow reports whether a request may be sent.
+// After the open duration, only a single probing request is allowed until its result is recorded.
+func (b *circuitBreaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// release ends a probing request without result, e.g. if it was canceled by the caller.
+func (b *circuitBreaker) release() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *circuitBreaker) record(failed bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.openDuration)
+	}
+}
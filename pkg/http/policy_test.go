@@ -0,0 +1,89 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func policyClient(policy *Policy) *http.Client {
+	return &http.Client{Transport: &PolicyTransport{
+		PolicyFor: func(*http.Request) *Policy { return policy },
+	}}
+}
+
+func failingServer(t *testing.T, failures int32, status int) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestPolicyTransport_retry(t *testing.T) {
+	server, calls := failingServer(t, 2, http.StatusServiceUnavailable)
+	client := policyClient(&Policy{MaxRetries: 2, Backoff: time.Millisecond})
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestPolicyTransport_noRetryOfPost(t *testing.T) {
+	server, calls := failingServer(t, 1, http.StatusInternalServerError)
+	client := policyClient(&Policy{MaxRetries: 2, Backoff: time.Millisecond})
+
+	resp, err := client.Post(server.URL, "application/x-www-form-urlencoded", strings.NewReader("a=b"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestPolicyTransport_timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+	client := policyClient(&Policy{Timeout: 10 * time.Millisecond})
+
+	_, err := client.Get(server.URL)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestPolicyTransport_circuitBreaker(t *testing.T) {
+	server, calls := failingServer(t, 2, http.StatusBadGateway)
+	client := policyClient(&Policy{FailureThreshold: 2, OpenDuration: 50 * time.Millisecond})
+
+	for range 2 {
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	_, err := client.Get(server.URL)
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(2), calls.Load())
+
+	time.Sleep(60 * time.Millisecond)
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err, "probe")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, err = client.Get(server.URL)
+	require.NoError(t, err, "closed")
+	resp.Body.Close()
+}
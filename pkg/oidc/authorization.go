@@ -2,6 +2,8 @@ package oidc
 
 import (
 	"log/slog"
+
+	"golang.org/x/text/language"
 )
 
 const (
@@ -41,6 +43,15 @@ const (
 	// ResponseTypeIDTokenOnly for the Implicit Flow returning only id token directly from the Authorization Server
 	ResponseTypeIDTokenOnly ResponseType = "id_token"
 
+	// ResponseTypeCodeIDToken for the Hybrid Flow returning a code and an id token from the Authorization Server
+	ResponseTypeCodeIDToken ResponseType = "code id_token"
+
+	// ResponseTypeCodeToken for the Hybrid Flow returning a code and an access token from the Authorization Server
+	ResponseTypeCodeToken ResponseType = "code token"
+
+	// ResponseTypeCodeIDTokenToken for the Hybrid Flow returning a code, an id and an access token from the Authorization Server
+	ResponseTypeCodeIDTokenToken ResponseType = "code id_token token"
+
 	DisplayPage  Display = "page"
 	DisplayPopup Display = "popup"
 	DisplayTouch Display = "touch"
@@ -50,6 +61,12 @@ const (
 	ResponseModeFragment ResponseMode = "fragment"
 	ResponseModeFormPost ResponseMode = "form_post"
 
+	// JWT Secured Authorization Response Modes (JARM), https://openid.net/specs/oauth-v2-jarm.html
+	ResponseModeJWT         ResponseMode = "jwt"
+	ResponseModeQueryJWT    ResponseMode = "query.jwt"
+	ResponseModeFragmentJWT ResponseMode = "fragment.jwt"
+	ResponseModeFormPostJWT ResponseMode = "form_post.jwt"
+
 	// PromptNone (`none`) disallows the Authorization Server to display any authentication or consent user interface pages.
 	// An error (login_required, interaction_required, ...) will be returned if the user is not already authenticated or consent is needed
 	PromptNone = "none"
@@ -83,6 +100,13 @@ type AuthRequest struct {
 	IDTokenHint  string              `json:"id_token_hint" schema:"id_token_hint"`
 	LoginHint    string              `json:"login_hint" schema:"login_hint"`
 	ACRValues    SpaceDelimitedArray `json:"acr_values" schema:"acr_values"`
+	// HostedDomain (`hd`) restricts the login to users of the domain.
+	HostedDomain string `json:"hd,omitempty" schema:"hd"`
+	// Hints are further hint parameters of the request, set by the OP for the configured parameter names.
+	Hints map[string]string `json:"-" schema:"-"`
+	// UILocale is the language negotiated by the OP from the UILocales and the Accept-Language header,
+	// if it has supported ui locales. Login UIs should render their pages in it.
+	UILocale language.Tag `json:"-" schema:"-"`
 
 	CodeChallenge       string              `json:"code_challenge" schema:"code_challenge"`
 	CodeChallengeMethod CodeChallengeMethod `json:"code_challenge_method" schema:"code_challenge_method"`
@@ -119,3 +143,19 @@ func (a *AuthRequest) GetState() string {
 func (a *AuthRequest) GetResponseMode() ResponseMode {
 	return a.ResponseMode
 }
+
+// ClaimsRequest is the `claims` parameter of the auth request,
+// requesting individual claims for the userinfo response and the id_token.
+// https://openid.net/specs/openid-connect-core-1_0.html#ClaimsParameter
+type ClaimsRequest struct {
+	UserInfo map[string]*ClaimRequest `json:"userinfo,omitempty"`
+	IDToken  map[string]*ClaimRequest `json:"id_token,omitempty"`
+}
+
+// ClaimRequest defines how a single claim of the [ClaimsRequest] is requested.
+// A nil ClaimRequest requests the claim in the default manner.
+type ClaimRequest struct {
+	Essential bool  `json:"essential,omitempty"`
+	Value     any   `json:"value,omitempty"`
+	Values    []any `json:"values,omitempty"`
+}
@@ -8,6 +8,10 @@ import "encoding/json"
 type DeviceAuthorizationRequest struct {
 	Scopes   SpaceDelimitedArray `schema:"scope"`
 	ClientID string              `schema:"client_id"`
+	// BindingMessage is displayed on the device and the verification page,
+	// so the user can ensure they authorize the right device, as the binding_message of
+	// OpenID Connect CIBA section 7.1.
+	BindingMessage string `schema:"binding_message"`
 }
 
 // DeviceAuthorizationResponse implements
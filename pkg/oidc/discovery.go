@@ -28,6 +28,10 @@ type DiscoveryConfiguration struct {
 
 	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint,omitempty"`
 
+	// MTLSEndpointAliases contains alternative endpoints, which clients using mutual-TLS must use instead of the conventional endpoints.
+	// https://datatracker.ietf.org/doc/html/rfc8705#section-5
+	MTLSEndpointAliases *MTLSEndpointAliases `json:"mtls_endpoint_aliases,omitempty"`
+
 	// CheckSessionIframe is a URL where the OP provides an iframe that support cross-origin communications for session state information with the RP Client.
 	CheckSessionIframe string `json:"check_session_iframe,omitempty"`
 
@@ -35,6 +39,10 @@ type DiscoveryConfiguration struct {
 	// It may also contain the OP's encryption keys that RPs can use to encrypt request to the OP.
 	JwksURI string `json:"jwks_uri,omitempty"`
 
+	// SignedJwksURI is the URL of the JSON Web Key Set of the OP as signed JWT.
+	// https://openid.net/specs/openid-federation-1_0.html#name-openid-connect-and-oauth2-m
+	SignedJwksURI string `json:"signed_jwks_uri,omitempty"`
+
 	// RegistrationEndpoint is the URL for the Dynamic Client Registration.
 	RegistrationEndpoint string `json:"registration_endpoint,omitempty"`
 
@@ -159,6 +167,16 @@ type DiscoveryConfiguration struct {
 	BackChannelLogoutSessionSupported bool `json:"backchannel_logout_session_supported,omitempty"`
 }
 
+// MTLSEndpointAliases are the endpoints of the OP for clients using mutual-TLS.
+// Empty endpoints are not aliased and the conventional endpoint must be used.
+type MTLSEndpointAliases struct {
+	TokenEndpoint               string `json:"token_endpoint,omitempty"`
+	IntrospectionEndpoint       string `json:"introspection_endpoint,omitempty"`
+	UserinfoEndpoint            string `json:"userinfo_endpoint,omitempty"`
+	RevocationEndpoint          string `json:"revocation_endpoint,omitempty"`
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint,omitempty"`
+}
+
 type AuthMethod string
 
 const (
@@ -166,8 +184,11 @@ const (
 	AuthMethodPost          AuthMethod = "client_secret_post"
 	AuthMethodNone          AuthMethod = "none"
 	AuthMethodPrivateKeyJWT AuthMethod = "private_key_jwt"
+	AuthMethodTLSClientAuth AuthMethod = "tls_client_auth"
+
+	AuthMethodSelfSignedTLSClientAuth AuthMethod = "self_signed_tls_client_auth"
 )
 
 var AllAuthMethods = []AuthMethod{
-	AuthMethodBasic, AuthMethodPost, AuthMethodNone, AuthMethodPrivateKeyJWT,
+	AuthMethodBasic, AuthMethodPost, AuthMethodNone, AuthMethodPrivateKeyJWT, AuthMethodTLSClientAuth, AuthMethodSelfSignedTLSClientAuth,
 }
@@ -19,6 +19,7 @@ const (
 	ServerError          errorType = "server_error"
 	InteractionRequired  errorType = "interaction_required"
 	LoginRequired        errorType = "login_required"
+	ConsentRequired      errorType = "consent_required"
 	RequestNotSupported  errorType = "request_not_supported"
 
 	// Additional error codes as defined in
@@ -33,6 +34,10 @@ const (
 	// the requested target or audience is invalid.
 	// [RFC 8693, Section 2.2.2: Error Response](https://www.rfc-editor.org/rfc/rfc8693#section-2.2.2)
 	InvalidTarget errorType = "invalid_target"
+
+	// InvalidBindingMessage error is returned if the binding_message is invalid or unacceptable.
+	// [OpenID Connect CIBA, Section 13: Authentication Error Response](https://openid.net/specs/openid-client-initiated-backchannel-authentication-core-1_0.html#rfc.section.13)
+	InvalidBindingMessage errorType = "invalid_binding_message"
 )
 
 var (
@@ -87,6 +92,11 @@ var (
 			ErrorType: LoginRequired,
 		}
 	}
+	ErrConsentRequired = func() *Error {
+		return &Error{
+			ErrorType: ConsentRequired,
+		}
+	}
 	ErrRequestNotSupported = func() *Error {
 		return &Error{
 			ErrorType: RequestNotSupported,
@@ -126,6 +136,13 @@ var (
 			Description: "The requested audience or target is invalid.",
 		}
 	}
+
+	ErrInvalidBindingMessage = func() *Error {
+		return &Error{
+			ErrorType:   InvalidBindingMessage,
+			Description: "The binding_message is invalid or unacceptable.",
+		}
+	}
 )
 
 type Error struct {
@@ -31,6 +31,7 @@ type IntrospectionResponse struct {
 	JWTID                           string                          `json:"jti,omitempty"`
 	Username                        string                          `json:"username,omitempty"`
 	Actor                           *ActorClaims                    `json:"act,omitempty"`
+	Confirmation                    *Confirmation                   `json:"cnf,omitempty"`
 	UserInfoProfile
 	UserInfoEmail
 	UserInfoPhone
@@ -0,0 +1,23 @@
+package oidc
+
+// JARMResponseClaims are the claims of the `response` JWT
+// of a JWT Secured Authorization Response (JARM).
+// https://openid.net/specs/oauth-v2-jarm.html#section-2.1
+type JARMResponseClaims struct {
+	TokenClaims
+	Code             string `json:"code,omitempty"`
+	IDToken          string `json:"id_token,omitempty"`
+	AccessToken      string `json:"access_token,omitempty"`
+	State            string `json:"state,omitempty"`
+	Error            string `json:"error,omitempty"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// IsJWT reports whether the response mode is one of the JARM response modes.
+func (m ResponseMode) IsJWT() bool {
+	switch m {
+	case ResponseModeJWT, ResponseModeQueryJWT, ResponseModeFragmentJWT, ResponseModeFormPostJWT:
+		return true
+	}
+	return false
+}
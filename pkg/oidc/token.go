@@ -6,6 +6,7 @@ import (
 	"time"
 
 	jose "github.com/go-jose/go-jose/v4"
+	"github.com/google/uuid"
 	"golang.org/x/oauth2"
 
 	"github.com/muhlemmer/gu"
@@ -100,8 +101,18 @@ func (c *TokenClaims) SetSignatureAlgorithm(algorithm jose.SignatureAlgorithm) {
 
 type AccessTokenClaims struct {
 	TokenClaims
-	Scopes SpaceDelimitedArray `json:"scope,omitempty"`
-	Claims map[string]any      `json:"-"`
+	Scopes       SpaceDelimitedArray `json:"scope,omitempty"`
+	Confirmation *Confirmation       `json:"cnf,omitempty"`
+	Claims       map[string]any      `json:"-"`
+}
+
+// Confirmation is the confirmation claim (cnf) of a sender-constrained token.
+// https://www.rfc-editor.org/rfc/rfc7800.html#section-3.1
+type Confirmation struct {
+	// JWKThumbprint of the DPoP proof key, RFC 9449 section 6.1.
+	JWKThumbprint string `json:"jkt,omitempty"`
+	// X509CertificateSHA256Thumbprint of the mTLS client certificate, RFC 8705 section 3.1.
+	X509CertificateSHA256Thumbprint string `json:"x5t#S256,omitempty"`
 }
 
 func NewAccessTokenClaims(issuer, subject string, audience []string, expiration time.Time, jwtid, clientID string, skew time.Duration) *AccessTokenClaims {
@@ -155,6 +166,11 @@ func (t *IDTokenClaims) GetAccessTokenHash() string {
 	return t.AccessTokenHash
 }
 
+// GetCodeHash returns the c_hash of the authorization code.
+func (t *IDTokenClaims) GetCodeHash() string {
+	return t.CodeHash
+}
+
 func (t *IDTokenClaims) SetUserInfo(i *UserInfo) {
 	t.Subject = i.Subject
 	t.UserInfoProfile = i.UserInfoProfile
@@ -237,6 +253,28 @@ type AccessTokenResponse struct {
 	IDToken      string              `json:"id_token,omitempty" schema:"id_token,omitempty"`
 	State        string              `json:"state,omitempty" schema:"state,omitempty"`
 	Scope        SpaceDelimitedArray `json:"scope,omitempty" schema:"scope,omitempty"`
+
+	// Extra members of the JSON response, see [TokenResponseExtra].
+	// They are not added to the fragment of implicit flow responses.
+	Extra TokenResponseExtra `json:"-" schema:"-"`
+}
+
+type atrAlias AccessTokenResponse
+
+// MarshalJSON has a value receiver, so the Extra members are also marshaled
+// with responses passed by value.
+func (a AccessTokenResponse) MarshalJSON() ([]byte, error) {
+	if err := a.Extra.Validate(); err != nil {
+		return nil, err
+	}
+	return mergeAndMarshalClaims((*atrAlias)(&a), a.Extra)
+}
+
+func (a *AccessTokenResponse) UnmarshalJSON(data []byte) error {
+	if err := unmarshalJSONMulti(data, (*atrAlias)(a)); err != nil {
+		return err
+	}
+	return unmarshalTokenResponseExtra(data, &a.Extra)
 }
 
 type JWTProfileAssertionClaims struct {
@@ -247,6 +285,7 @@ type JWTProfileAssertionClaims struct {
 	Audience     Audience `json:"aud"`
 	Expiration   Time     `json:"exp"`
 	IssuedAt     Time     `json:"iat"`
+	JWTID        string   `json:"jti,omitempty"`
 
 	Claims map[string]any `json:"-"`
 }
@@ -318,6 +357,7 @@ func NewJWTProfileAssertion(userID, keyID string, audience []string, key []byte,
 		IssuedAt:     FromTime(time.Now().UTC()),
 		Expiration:   FromTime(time.Now().Add(1 * time.Hour).UTC()),
 		Audience:     audience,
+		JWTID:        uuid.NewString(),
 		Claims:       make(map[string]any),
 	}
 
@@ -382,6 +422,26 @@ type TokenExchangeResponse struct {
 	// IDToken field allows returning an additional ID token
 	// if the requested_token_type was Access Token and scope contained openid.
 	IDToken string `json:"id_token,omitempty"`
+
+	// Extra members of the response, see [TokenResponseExtra].
+	Extra TokenResponseExtra `json:"-"`
+}
+
+type terAlias TokenExchangeResponse
+
+// MarshalJSON has a value receiver like [AccessTokenResponse.MarshalJSON].
+func (t TokenExchangeResponse) MarshalJSON() ([]byte, error) {
+	if err := t.Extra.Validate(); err != nil {
+		return nil, err
+	}
+	return mergeAndMarshalClaims((*terAlias)(&t), t.Extra)
+}
+
+func (t *TokenExchangeResponse) UnmarshalJSON(data []byte) error {
+	if err := unmarshalJSONMulti(data, (*terAlias)(t)); err != nil {
+		return err
+	}
+	return unmarshalTokenResponseExtra(data, &t.Extra)
 }
 
 type LogoutTokenClaims struct {
@@ -394,8 +454,14 @@ type LogoutTokenClaims struct {
 	Events     map[string]any `json:"events,omitempty"`
 	SessionID  string         `json:"sid,omitempty"`
 	Claims     map[string]any `json:"-"`
+
+	// Additional information set by this framework
+	SignatureAlg jose.SignatureAlgorithm `json:"-"`
 }
 
+// EventBackChannelLogout is the member of the events claim of a logout token.
+const EventBackChannelLogout = "http://schemas.openid.net/event/backchannel-logout"
+
 type ltcAlias LogoutTokenClaims
 
 func (i *LogoutTokenClaims) MarshalJSON() ([]byte, error) {
@@ -406,6 +472,49 @@ func (i *LogoutTokenClaims) UnmarshalJSON(data []byte) error {
 	return unmarshalJSONMulti(data, (*ltcAlias)(i), &i.Claims)
 }
 
+func (i *LogoutTokenClaims) GetIssuer() string {
+	return i.Issuer
+}
+
+func (i *LogoutTokenClaims) GetSubject() string {
+	return i.Subject
+}
+
+func (i *LogoutTokenClaims) GetAudience() []string {
+	return i.Audience
+}
+
+func (i *LogoutTokenClaims) GetExpiration() time.Time {
+	return i.Expiration.AsTime()
+}
+
+func (i *LogoutTokenClaims) GetIssuedAt() time.Time {
+	return i.IssuedAt.AsTime()
+}
+
+// GetNonce returns the nonce, which must not be present in a logout token.
+func (i *LogoutTokenClaims) GetNonce() string {
+	nonce, _ := i.Claims["nonce"].(string)
+	return nonce
+}
+
+func (i *LogoutTokenClaims) GetAuthenticationContextClassReference() string {
+	return ""
+}
+
+func (i *LogoutTokenClaims) GetAuthTime() time.Time {
+	return time.Time{}
+}
+
+func (i *LogoutTokenClaims) GetAuthorizedParty() string {
+	azp, _ := i.Claims["azp"].(string)
+	return azp
+}
+
+func (i *LogoutTokenClaims) SetSignatureAlgorithm(algorithm jose.SignatureAlgorithm) {
+	i.SignatureAlg = algorithm
+}
+
 func NewLogoutTokenClaims(issuer, subject string, audience Audience, expiration time.Time, jwtID, sessionID string, skew time.Duration) *LogoutTokenClaims {
 	return &LogoutTokenClaims{
 		Issuer:     issuer,
@@ -415,7 +524,7 @@ func NewLogoutTokenClaims(issuer, subject string, audience Audience, expiration
 		Expiration: FromTime(expiration),
 		JWTID:      jwtID,
 		Events: map[string]any{
-			"http://schemas.openid.net/event/backchannel-logout": struct{}{},
+			EventBackChannelLogout: struct{}{},
 		},
 		SessionID: sessionID,
 	}
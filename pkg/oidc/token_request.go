@@ -107,12 +107,16 @@ func (a *AccessTokenRequest) IsSetClientIDAndClientSecret() bool {
 // RefreshTokenRequest is not useful for making refresh requests because the
 // grant_type is not included explicitly but rather implied.
 type RefreshTokenRequest struct {
-	RefreshToken        string              `schema:"refresh_token"`
-	Scopes              SpaceDelimitedArray `schema:"scope"`
-	ClientID            string              `schema:"client_id"`
-	ClientSecret        string              `schema:"client_secret"`
-	ClientAssertion     string              `schema:"client_assertion"`
-	ClientAssertionType string              `schema:"client_assertion_type"`
+	RefreshToken string              `schema:"refresh_token"`
+	Scopes       SpaceDelimitedArray `schema:"scope"`
+	// Resource and Audience narrow the audience of the issued tokens,
+	// see [RFC 8707, Section 2.2](https://www.rfc-editor.org/rfc/rfc8707#section-2.2).
+	Resource            []string `schema:"resource"`
+	Audience            Audience `schema:"audience"`
+	ClientID            string   `schema:"client_id"`
+	ClientSecret        string   `schema:"client_secret"`
+	ClientAssertion     string   `schema:"client_assertion"`
+	ClientAssertionType string   `schema:"client_assertion_type"`
 }
 
 func (a *RefreshTokenRequest) GrantType() GrantType {
@@ -146,6 +150,7 @@ type JWTTokenRequest struct {
 	Audience  Audience            `json:"aud"`
 	IssuedAt  Time                `json:"iat"`
 	ExpiresAt Time                `json:"exp"`
+	JWTID     string              `json:"jti,omitempty"`
 
 	private map[string]any
 }
@@ -0,0 +1,82 @@
+package oidc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TokenResponseExtra holds additional top-level members of a token response,
+// such as proprietary fields agreed on with a client.
+// Registered members of the response, e.g. access_token, must not be set,
+// responses with them fail to marshal with [ErrRegisteredTokenResponseMember].
+// On unmarshal it contains all members of the response, which are not registered.
+type TokenResponseExtra map[string]any
+
+var ErrRegisteredTokenResponseMember = errors.New("registered member in the extra members of the token response")
+
+// registeredTokenResponseMembers are the members of the [AccessTokenResponse],
+// the [TokenExchangeResponse] and the error response (RFC 6749 section 5.2).
+var registeredTokenResponseMembers = []string{
+	"access_token", "token_type", "expires_in", "refresh_token", "scope", "id_token", "state",
+	"issued_token_type", "error", "error_description", "error_uri",
+}
+
+// Validate returns [ErrRegisteredTokenResponseMember] if a registered member
+// of the token response is set.
+func (e TokenResponseExtra) Validate() error {
+	for _, name := range registeredTokenResponseMembers {
+		if _, ok := e[name]; ok {
+			return fmt.Errorf("%w: %s", ErrRegisteredTokenResponseMember, name)
+		}
+	}
+	return nil
+}
+
+// unmarshalTokenResponseExtra unmarshals the members of the response,
+// which are not registered, into extra.
+func unmarshalTokenResponseExtra(data []byte, extra *TokenResponseExtra) error {
+	if err := unmarshalJSONMulti(data, extra); err != nil {
+		return err
+	}
+	for _, name := range registeredTokenResponseMembers {
+		delete(*extra, name)
+	}
+	if len(*extra) == 0 {
+		*extra = nil
+	}
+	return nil
+}
+
+// Well-known extra members of token responses.
+const (
+	// RefreshExpiresIn is the lifetime of the refresh token in seconds.
+	RefreshExpiresIn = "refresh_expires_in"
+)
+
+// SetRefreshExpiresIn sets the [RefreshExpiresIn] member, rounded down to seconds.
+func (e TokenResponseExtra) SetRefreshExpiresIn(lifetime time.Duration) {
+	e[RefreshExpiresIn] = uint64(lifetime.Seconds())
+}
+
+// RefreshExpiresIn returns the [RefreshExpiresIn] member
+// and whether it is present as a number.
+func (e TokenResponseExtra) RefreshExpiresIn() (time.Duration, bool) {
+	var seconds float64
+	switch v := e[RefreshExpiresIn].(type) {
+	case uint64:
+		seconds = float64(v)
+	case float64:
+		seconds = v
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return 0, false
+		}
+		seconds = f
+	default:
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
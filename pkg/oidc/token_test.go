@@ -7,6 +7,7 @@ import (
 
 	jose "github.com/go-jose/go-jose/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/text/language"
 )
 
@@ -293,3 +294,34 @@ func TestNewLogoutTokenClaims(t *testing.T) {
 
 	assert.Equal(t, want, got)
 }
+
+func TestAccessTokenResponse_Extra(t *testing.T) {
+	resp := AccessTokenResponse{
+		AccessToken: "token",
+		TokenType:   BearerToken,
+		Extra:       TokenResponseExtra{"partner": "acme"},
+	}
+	resp.Extra.SetRefreshExpiresIn(90 * time.Second)
+
+	for name, v := range map[string]any{"value": resp, "pointer": &resp} {
+		data, err := json.Marshal(v)
+		assert.NoError(t, err, name)
+		assert.JSONEq(t, `{"access_token":"token","token_type":"Bearer","partner":"acme","refresh_expires_in":90}`, string(data), name)
+	}
+
+	data, err := json.Marshal(resp)
+	require.NoError(t, err)
+	var got AccessTokenResponse
+	assert.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, "token", got.AccessToken)
+	assert.Equal(t, TokenResponseExtra{"partner": "acme", RefreshExpiresIn: float64(90)}, got.Extra, "registered members removed")
+	refreshExpiresIn, ok := got.Extra.RefreshExpiresIn()
+	assert.True(t, ok)
+	assert.Equal(t, 90*time.Second, refreshExpiresIn)
+	_, err = json.Marshal(got)
+	assert.NoError(t, err, "marshal unmarshaled response")
+
+	resp.Extra["refresh_token"] = "injected"
+	_, err = json.Marshal(resp)
+	assert.ErrorIs(t, err, ErrRegisteredTokenResponseMember)
+}
@@ -1,11 +1,13 @@
 package oidc
 
 import (
+	"cmp"
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
+	"slices"
 	"strings"
 	"time"
 
@@ -201,6 +203,66 @@ type Prompt SpaceDelimitedArray
 
 type ResponseType string
 
+// responseTypeOrder is the order of the values of the registered response types,
+// unknown values are ordered last.
+var responseTypeOrder = map[string]int{"code": 0, "id_token": 1, "token": 2}
+
+func responseTypeRank(value string) int {
+	if rank, ok := responseTypeOrder[value]; ok {
+		return rank
+	}
+	return len(responseTypeOrder)
+}
+
+// Normalize returns the response type with its values in the order of the
+// registered response types, e.g. "code id_token" for "id_token code",
+// as the order of the values is not significant, see
+// https://openid.net/specs/oauth-v2-multiple-response-types-1_0.html#ResponseTypesAndModes
+func (r ResponseType) Normalize() ResponseType {
+	if !strings.Contains(string(r), " ") {
+		return r
+	}
+	values := strings.Fields(string(r))
+	slices.SortStableFunc(values, func(a, b string) int {
+		return cmp.Compare(responseTypeRank(a), responseTypeRank(b))
+	})
+	return ResponseType(strings.Join(values, " "))
+}
+
+// UnmarshalText normalizes the response type, see [ResponseType.Normalize].
+func (r *ResponseType) UnmarshalText(text []byte) error {
+	*r = ResponseType(text).Normalize()
+	return nil
+}
+
+// IsHybrid reports if the response type belongs to the Hybrid Flow,
+// returning a code together with tokens from the Authorization Endpoint.
+func (r ResponseType) IsHybrid() bool {
+	switch r.Normalize() {
+	case ResponseTypeCodeIDToken, ResponseTypeCodeToken, ResponseTypeCodeIDTokenToken:
+		return true
+	}
+	return false
+}
+
+// IncludesIDToken reports if an id token is returned from the Authorization Endpoint.
+func (r ResponseType) IncludesIDToken() bool {
+	switch r.Normalize() {
+	case ResponseTypeIDTokenOnly, ResponseTypeIDToken, ResponseTypeCodeIDToken, ResponseTypeCodeIDTokenToken:
+		return true
+	}
+	return false
+}
+
+// IncludesAccessToken reports if an access token is returned from the Authorization Endpoint.
+func (r ResponseType) IncludesAccessToken() bool {
+	switch r.Normalize() {
+	case ResponseTypeIDToken, ResponseTypeCodeToken, ResponseTypeCodeIDTokenToken:
+		return true
+	}
+	return false
+}
+
 type ResponseMode string
 
 func (s SpaceDelimitedArray) String() string {
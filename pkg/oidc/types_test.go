@@ -457,6 +457,32 @@ func TestLocales_UnmarshalJSON(t *testing.T) {
 	}
 }
 
+func TestResponseType(t *testing.T) {
+	tests := []struct {
+		responseType    ResponseType
+		wantHybrid      bool
+		wantIDToken     bool
+		wantAccessToken bool
+	}{
+		{ResponseTypeCode, false, false, false},
+		{ResponseTypeIDTokenOnly, false, true, false},
+		{ResponseTypeIDToken, false, true, true},
+		{ResponseTypeCodeIDToken, true, true, false},
+		{ResponseTypeCodeToken, true, false, true},
+		{ResponseTypeCodeIDTokenToken, true, true, true},
+		{"id_token code", true, true, false},
+		{"token id_token code", true, true, true},
+		{"token id_token", false, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.responseType), func(t *testing.T) {
+			assert.Equal(t, tt.wantHybrid, tt.responseType.IsHybrid())
+			assert.Equal(t, tt.wantIDToken, tt.responseType.IncludesIDToken())
+			assert.Equal(t, tt.wantAccessToken, tt.responseType.IncludesAccessToken())
+		})
+	}
+}
+
 func TestScopes_UnmarshalText(t *testing.T) {
 	type args struct {
 		text []byte
@@ -747,3 +773,16 @@ func TestTime_UnmarshalJSON(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestResponseType_UnmarshalText(t *testing.T) {
+	var req struct {
+		ResponseType ResponseType `json:"response_type" schema:"response_type"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"response_type":"id_token code"}`), &req))
+	assert.Equal(t, ResponseTypeCodeIDToken, req.ResponseType)
+
+	require.NoError(t, schema.NewDecoder().Decode(&req, map[string][]string{"response_type": {"token  id_token"}}))
+	assert.Equal(t, ResponseTypeIDToken, req.ResponseType)
+
+	assert.Equal(t, ResponseType("code custom"), ResponseType("custom code").Normalize())
+}
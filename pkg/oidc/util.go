@@ -20,19 +20,22 @@ func mergeAndMarshalClaims(registered any, extraClaims map[string]any) ([]byte,
 	}
 
 	if len(extraClaims) > 0 {
-		merged := make(map[string]any)
+		// Decode the registered claims as raw JSON values,
+		// so they are copied instead of parsed and encoded again.
+		var registeredClaims map[string]json.RawMessage
+		if err := json.Unmarshal(buf.Bytes(), &registeredClaims); err != nil {
+			return nil, fmt.Errorf("oidc registered claims: %w", err)
+		}
+		merged := make(map[string]any, len(extraClaims)+len(registeredClaims))
 		for k, v := range extraClaims {
 			merged[k] = v
 		}
-
-		// Merge JSON data into custom claims.
-		// The full-read action by the decoder resets the buffer
-		// to zero len, while retaining underlying cap.
-		if err := json.NewDecoder(buf).Decode(&merged); err != nil {
-			return nil, fmt.Errorf("oidc registered claims: %w", err)
+		for k, v := range registeredClaims {
+			merged[k] = v
 		}
 
 		// Marshal the final result.
+		buf.Reset()
 		if err := json.NewEncoder(buf).Encode(merged); err != nil {
 			return nil, fmt.Errorf("oidc custom claims: %w", err)
 		}
@@ -1,6 +1,7 @@
 package oidc
 
 import (
+	"encoding/json"
 	"errors"
 	"testing"
 
@@ -145,3 +146,17 @@ func Test_unmarshalJSONMulti(t *testing.T) {
 		})
 	}
 }
+
+func BenchmarkIDTokenClaims_MarshalJSON(b *testing.B) {
+	claims := *idTokenData
+	claims.Claims = map[string]any{
+		"foo":  "bar",
+		"list": []string{"a", "b"},
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := json.Marshal(&claims); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -60,6 +60,10 @@ var (
 	ErrAuthTimeNotPresent      = errors.New("claim `auth_time` of token is missing")
 	ErrAuthTimeToOld           = errors.New("auth time of token is too old")
 	ErrAtHash                  = errors.New("at_hash does not correspond to access token")
+	ErrCHash                   = errors.New("c_hash does not correspond to code")
+	ErrHashMissing             = errors.New("hash claim of token is missing")
+	ErrDecryption              = errors.New("token decryption failed")
+	ErrTypeInvalid             = errors.New("typ header is not allowed")
 )
 
 // Verifier caries configuration for the various token verification
@@ -76,8 +80,30 @@ type Verifier struct {
 	AZP               AZPVerifier
 	KeySet            KeySet
 	Nonce             func(ctx context.Context) string
+	// DecryptionKeys decrypt encrypted (JWE) tokens, see [DecryptTokenWithKeys].
+	DecryptionKeys *jose.JSONWebKeySet
+	// TrustedAudiences, besides the ClientID, which are accepted in the aud claim, see [CheckTrustedAudiences].
+	// Nil accepts any additional audience.
+	TrustedAudiences []string
+	// AZPPolicy defines when the azp claim is required, see [CheckAZPPolicy].
+	AZPPolicy AZPPolicy
+	// AllowedTypes of the typ header, see [CheckHeader].
+	// Empty allows any type.
+	AllowedTypes []string
 }
 
+// AZPPolicy defines when the azp (authorized party) claim is required.
+type AZPPolicy int
+
+const (
+	// AZPRequiredForMultipleAudiences requires the azp claim for tokens with multiple audiences (default).
+	AZPRequiredForMultipleAudiences AZPPolicy = iota
+	// AZPRequired requires the azp claim for all tokens, e.g. for FAPI.
+	AZPRequired
+	// AZPOptional never requires the azp claim. If present, it is still verified.
+	AZPOptional
+)
+
 // ACRVerifier specifies the function to be used by the `DefaultVerifier` for validating the acr claim
 type ACRVerifier func(string) error
 
@@ -96,6 +122,50 @@ func DecryptToken(tokenString string) (string, error) {
 	return tokenString, nil // TODO: impl
 }
 
+var (
+	// KeyEncryptionAlgorithms are the algorithms accepted for the key encryption of JWE tokens.
+	KeyEncryptionAlgorithms = []jose.KeyAlgorithm{
+		jose.RSA_OAEP, jose.RSA_OAEP_256,
+		jose.ECDH_ES, jose.ECDH_ES_A128KW, jose.ECDH_ES_A192KW, jose.ECDH_ES_A256KW,
+		jose.A128KW, jose.A192KW, jose.A256KW, jose.DIRECT,
+	}
+	// ContentEncryptionAlgorithms are the algorithms accepted for the content encryption of JWE tokens.
+	ContentEncryptionAlgorithms = []jose.ContentEncryption{
+		jose.A128CBC_HS256, jose.A192CBC_HS384, jose.A256CBC_HS512,
+		jose.A128GCM, jose.A192GCM, jose.A256GCM,
+	}
+)
+
+// DecryptTokenWithKeys decrypts an encrypted (JWE) token in compact serialization
+// with the key of the key set matching its kid, or any key of the set without kid.
+// It returns the nested token, usually a signed JWT.
+// Tokens that are not encrypted are returned unchanged.
+func DecryptTokenWithKeys(tokenString string, keys *jose.JSONWebKeySet) (string, error) {
+	if strings.Count(tokenString, ".") != 4 {
+		return tokenString, nil
+	}
+	if keys == nil || len(keys.Keys) == 0 {
+		return "", fmt.Errorf("%w: token is encrypted, but no decryption keys are configured", ErrDecryption)
+	}
+	jwe, err := jose.ParseEncrypted(tokenString, KeyEncryptionAlgorithms, ContentEncryptionAlgorithms)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrDecryption, err)
+	}
+	candidates := keys.Keys
+	if kid := jwe.Header.KeyID; kid != "" {
+		candidates = keys.Key(kid)
+	}
+	for _, key := range candidates {
+		if key.Use != "" && key.Use != "enc" {
+			continue
+		}
+		if payload, err := jwe.Decrypt(key.Key); err == nil {
+			return string(payload), nil
+		}
+	}
+	return "", fmt.Errorf("%w: no matching key", ErrDecryption)
+}
+
 func ParseToken(tokenString string, claims any) ([]byte, error) {
 	parts := strings.Split(tokenString, ".")
 	if len(parts) != 3 {
@@ -173,6 +243,75 @@ func CheckAZPVerifier(claims Claims, azp AZPVerifier) error {
 	return nil
 }
 
+// CheckTrustedAudiences checks that all audiences of the claims
+// are either the clientID or one of the trusted audiences.
+// Nil trusted audiences accept any audience.
+func CheckTrustedAudiences(claims Claims, clientID string, trusted []string) error {
+	if trusted == nil {
+		return nil
+	}
+	for _, aud := range claims.GetAudience() {
+		if aud != clientID && !slices.Contains(trusted, aud) {
+			return fmt.Errorf("%w: audience %q is not trusted", ErrAudience, aud)
+		}
+	}
+	return nil
+}
+
+// CheckAZPPolicy checks the presence of the azp (authorized party) claim according to the policy
+// and verifies it with the azp verifier, see [CheckAZPVerifier].
+func CheckAZPPolicy(claims Claims, azp AZPVerifier, policy AZPPolicy) error {
+	switch policy {
+	case AZPRequired:
+		if claims.GetAuthorizedParty() == "" {
+			return ErrAzpMissing
+		}
+	case AZPOptional:
+		if claims.GetAuthorizedParty() == "" {
+			return nil
+		}
+	}
+	return CheckAZPVerifier(claims, azp)
+}
+
+// CheckHeader checks the typ parameter of the JOSE header of the token.
+//
+// If allowedTypes is not empty, the typ header must match one of them case-insensitively,
+// where the "application/" prefix may be omitted (RFC 7515, section 4.1.9).
+// An empty string in allowedTypes accepts tokens without typ header.
+//
+// The crit parameter is not checked here, as the signature verification
+// rejects all critical headers, which are not understood by go-jose.
+func CheckHeader(token string, allowedTypes []string) error {
+	if len(allowedTypes) == 0 {
+		return nil
+	}
+	encoded, _, _ := strings.Cut(token, ".")
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("%w: malformed jwt header: %v", ErrParse, err)
+	}
+	var header struct {
+		Type string `json:"typ"`
+	}
+	if err = json.Unmarshal(data, &header); err != nil {
+		return fmt.Errorf("%w: malformed jwt header: %v", ErrParse, err)
+	}
+	if !slices.ContainsFunc(allowedTypes, func(allowed string) bool {
+		return strings.EqualFold(mediaType(allowed), mediaType(header.Type))
+	}) {
+		return fmt.Errorf("%w: %q", ErrTypeInvalid, header.Type)
+	}
+	return nil
+}
+
+func mediaType(typ string) string {
+	if typ != "" && !strings.Contains(typ, "/") {
+		return "application/" + typ
+	}
+	return typ
+}
+
 func CheckSignature(ctx context.Context, token string, payload []byte, claims ClaimsSignature, supportedSigAlgs []string, set KeySet) error {
 	jws, err := jose.ParseSigned(token, toJoseSignatureAlgorithms(supportedSigAlgs))
 	if err != nil {
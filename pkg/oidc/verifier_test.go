@@ -1,10 +1,14 @@
 package oidc
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
 	"errors"
 	"testing"
 	"time"
 
+	jose "github.com/go-jose/go-jose/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -388,3 +392,113 @@ func TestCheckAuthTime(t *testing.T) {
 		})
 	}
 }
+
+func TestDecryptTokenWithKeys(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	encrypter, err := jose.NewEncrypter(jose.A256GCM, jose.Recipient{Algorithm: jose.RSA_OAEP_256, Key: &key.PublicKey, KeyID: "enc"},
+		(&jose.EncrypterOptions{}).WithContentType("JWT"))
+	require.NoError(t, err)
+	object, err := encrypter.Encrypt([]byte("header.payload.signature"))
+	require.NoError(t, err)
+	encrypted, err := object.CompactSerialize()
+	require.NoError(t, err)
+
+	keys := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: key, KeyID: "enc", Use: "enc"}}}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		token   string
+		keys    *jose.JSONWebKeySet
+		want    string
+		wantErr bool
+	}{
+		{name: "not encrypted", token: "header.payload.signature", want: "header.payload.signature"},
+		{name: "decrypted", token: encrypted, keys: keys, want: "header.payload.signature"},
+		{name: "no keys", token: encrypted, wantErr: true},
+		{name: "unknown kid", token: encrypted, keys: &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: key, KeyID: "other"}}}, wantErr: true},
+		{name: "wrong key", token: encrypted, keys: &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: otherKey, KeyID: "enc"}}}, wantErr: true},
+		{name: "signing key", token: encrypted, keys: &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: key, KeyID: "enc", Use: "sig"}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecryptTokenWithKeys(tt.token, tt.keys)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrDecryption)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCheckTrustedAudiences(t *testing.T) {
+	claims := &IDTokenClaims{TokenClaims: TokenClaims{Audience: []string{"client", "api"}}}
+	assert.NoError(t, CheckTrustedAudiences(claims, "client", nil))
+	assert.NoError(t, CheckTrustedAudiences(claims, "client", []string{"api"}))
+	assert.ErrorIs(t, CheckTrustedAudiences(claims, "client", []string{}), ErrAudience)
+}
+
+func TestCheckAZPPolicy(t *testing.T) {
+	verifier := DefaultAZPVerifier("client")
+	single := &IDTokenClaims{TokenClaims: TokenClaims{Audience: []string{"client"}}}
+	multiple := &IDTokenClaims{TokenClaims: TokenClaims{Audience: []string{"client", "api"}}}
+	otherAZP := &IDTokenClaims{TokenClaims: TokenClaims{Audience: []string{"client", "api"}, AuthorizedParty: "api"}}
+
+	tests := []struct {
+		name    string
+		claims  Claims
+		policy  AZPPolicy
+		wantErr error
+	}{
+		{name: "default single", claims: single},
+		{name: "default multiple", claims: multiple, wantErr: ErrAzpMissing},
+		{name: "required", claims: single, policy: AZPRequired, wantErr: ErrAzpMissing},
+		{name: "optional", claims: multiple, policy: AZPOptional},
+		{name: "optional verified", claims: otherAZP, policy: AZPOptional, wantErr: ErrAzpInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckAZPPolicy(tt.claims, verifier, tt.policy)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestCheckHeader(t *testing.T) {
+	token := func(header string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(header)) + ".payload.signature"
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		types   []string
+		wantErr error
+	}{
+		{name: "no policy", token: "invalid"},
+		{name: "typ", token: token(`{"alg":"RS256","typ":"JWT"}`), types: []string{"jwt"}},
+		{name: "typ with prefix", token: token(`{"alg":"RS256","typ":"application/at+jwt"}`), types: []string{"at+jwt"}},
+		{name: "typ not allowed", token: token(`{"alg":"RS256","typ":"at+jwt"}`), types: []string{"JWT"}, wantErr: ErrTypeInvalid},
+		{name: "typ missing", token: token(`{"alg":"RS256"}`), types: []string{"JWT"}, wantErr: ErrTypeInvalid},
+		{name: "typ missing allowed", token: token(`{"alg":"RS256"}`), types: []string{"JWT", ""}},
+		{name: "malformed", token: "!.payload.signature", types: []string{"JWT"}, wantErr: ErrParse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckHeader(tt.token, tt.types)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
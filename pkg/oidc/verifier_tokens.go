@@ -0,0 +1,157 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	ErrLogoutTokenEvent  = errors.New("logout token must contain the back-channel logout event")
+	ErrLogoutTokenSubSID = errors.New("logout token must contain a sub or sid claim")
+	ErrLogoutTokenNonce  = errors.New("logout token must not contain a nonce")
+)
+
+// Types of the typ header.
+const (
+	TypeJWT         = "JWT"
+	TypeAccessToken = "at+jwt"
+	TypeLogoutToken = "logout+jwt"
+)
+
+// VerifyJWT decrypts and parses the token into claims of type C and verifies
+// its header (typ, see [CheckHeader]), signature, expiration and issued at,
+// which is only required with a MaxAgeIAT.
+//
+// Issuer and audience depend on the token type and must be checked by the caller,
+// e.g. with [CheckIssuer] and [CheckAudience], or use one of the verifiers
+// [VerifyLogoutToken], [VerifyJWTAccessToken] or [VerifyJWTProfileAssertion].
+func VerifyJWT[C Claims](ctx context.Context, token string, v *Verifier) (claims C, err error) {
+	return verifyJWT[C](ctx, token, v, nil)
+}
+
+// verifyJWT is [VerifyJWT], which calls checkClaims, if set, before the signature is verified,
+// so tokens with wrong claims are rejected without looking up keys.
+func verifyJWT[C Claims](ctx context.Context, token string, v *Verifier, checkClaims func(C) error) (claims C, err error) {
+	var nilClaims C
+
+	decrypted, err := DecryptTokenWithKeys(token, v.DecryptionKeys)
+	if err != nil {
+		return nilClaims, err
+	}
+	payload, err := ParseToken(decrypted, &claims)
+	if err != nil {
+		return nilClaims, err
+	}
+	if err = CheckHeader(decrypted, v.AllowedTypes); err != nil {
+		return nilClaims, err
+	}
+	if checkClaims != nil {
+		if err = checkClaims(claims); err != nil {
+			return nilClaims, err
+		}
+	}
+	if err = CheckSignature(ctx, decrypted, payload, claims, v.SupportedSignAlgs, v.KeySet); err != nil {
+		return nilClaims, err
+	}
+	if err = CheckExpiration(claims, v.Offset); err != nil {
+		return nilClaims, err
+	}
+	if v.MaxAgeIAT > 0 || !claims.GetIssuedAt().IsZero() {
+		if err = CheckIssuedAt(claims, v.MaxAgeIAT, v.Offset); err != nil {
+			return nilClaims, err
+		}
+	}
+	return claims, nil
+}
+
+// VerifyLogoutToken verifies a logout token of the OpenID Connect Back-Channel Logout
+// received by the client (ClientID) from the OP (Issuer).
+// The typ header is not enforced, unless set by AllowedTypes (e.g. [TypeLogoutToken]).
+// https://openid.net/specs/openid-connect-backchannel-1_0.html#Validation
+func VerifyLogoutToken(ctx context.Context, token string, v *Verifier) (*LogoutTokenClaims, error) {
+	claims, err := VerifyJWT[*LogoutTokenClaims](ctx, token, v)
+	if err != nil {
+		return nil, err
+	}
+	if err = CheckIssuer(claims, v.Issuer); err != nil {
+		return nil, err
+	}
+	if err = CheckAudience(claims, v.ClientID); err != nil {
+		return nil, err
+	}
+	if err = CheckTrustedAudiences(claims, v.ClientID, v.TrustedAudiences); err != nil {
+		return nil, err
+	}
+	if err = CheckIssuedAt(claims, v.MaxAgeIAT, v.Offset); err != nil {
+		return nil, err
+	}
+	if _, ok := claims.Events[EventBackChannelLogout].(map[string]any); !ok {
+		return nil, ErrLogoutTokenEvent
+	}
+	if claims.Subject == "" && claims.SessionID == "" {
+		return nil, ErrLogoutTokenSubSID
+	}
+	if _, ok := claims.Claims["nonce"]; ok {
+		return nil, ErrLogoutTokenNonce
+	}
+	return claims, nil
+}
+
+// VerifyJWTAccessToken verifies a JWT access token of RFC 9068 issued by the Issuer.
+// The typ header must be at+jwt, unless other AllowedTypes are set.
+// Empty, but not nil, AllowedTypes don't check the typ header.
+// If the ClientID, which is the identifier of the resource server, is set,
+// it must be contained in the audience.
+// https://www.rfc-editor.org/rfc/rfc9068#section-4
+func VerifyJWTAccessToken[C Claims](ctx context.Context, token string, v *Verifier) (claims C, err error) {
+	var nilClaims C
+
+	verifier := *v
+	if verifier.AllowedTypes == nil {
+		verifier.AllowedTypes = []string{TypeAccessToken}
+	}
+	claims, err = VerifyJWT[C](ctx, token, &verifier)
+	if err != nil {
+		return nilClaims, err
+	}
+	if err = CheckIssuer(claims, v.Issuer); err != nil {
+		return nilClaims, err
+	}
+	if err = CheckSubject(claims); err != nil {
+		return nilClaims, err
+	}
+	if v.ClientID != "" {
+		if err = CheckAudience(claims, v.ClientID); err != nil {
+			return nilClaims, err
+		}
+	}
+	if err = CheckIssuedAt(claims, v.MaxAgeIAT, v.Offset); err != nil {
+		return nilClaims, err
+	}
+	return claims, nil
+}
+
+// VerifyJWTProfileAssertion verifies a JWT assertion of RFC 7523 (authorization grant or client authentication)
+// signed with a key of the KeySet. Its audience must contain the Issuer, which is the authorization server,
+// or one of the audiences, e.g. the token endpoint URL.
+// The iss and sub claims must be set. They are not compared, as RFC 7523 allows the
+// subject of an authorization grant to differ from the issuer. Callers must check the
+// subject, e.g. op.JWTProfileVerifier requires it to be the issuer by default.
+// https://www.rfc-editor.org/rfc/rfc7523#section-3
+func VerifyJWTProfileAssertion(ctx context.Context, assertion string, v *Verifier, audiences ...string) (*JWTTokenRequest, error) {
+	return verifyJWT(ctx, assertion, v, func(request *JWTTokenRequest) error {
+		if !slices.ContainsFunc(audiences, func(aud string) bool {
+			return slices.Contains(request.Audience, aud)
+		}) {
+			if err := CheckAudience(request, v.Issuer); err != nil {
+				return err
+			}
+		}
+		if request.Issuer == "" {
+			return fmt.Errorf("%w: iss missing", ErrIssuerInvalid)
+		}
+		return CheckSubject(request)
+	})
+}
@@ -0,0 +1,149 @@
+package oidc_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tu "github.com/zitadel/oidc/v3/internal/testutil"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+func signToken(t *testing.T, typ jose.ContentType, claims any) string {
+	t.Helper()
+	options := new(jose.SignerOptions)
+	if typ != "" {
+		options = options.WithType(typ)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: tu.SignatureAlgorithm, Key: tu.WebKey}, options)
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	object, err := signer.Sign(payload)
+	require.NoError(t, err)
+	token, err := object.CompactSerialize()
+	require.NoError(t, err)
+	return token
+}
+
+func newVerifier() *oidc.Verifier {
+	return &oidc.Verifier{
+		Issuer:            "https://op.example.com",
+		ClientID:          "client",
+		KeySet:            tu.KeySet{},
+		SupportedSignAlgs: []string{string(tu.SignatureAlgorithm)},
+	}
+}
+
+func TestVerifyLogoutToken(t *testing.T) {
+	valid := func() map[string]any {
+		return map[string]any{
+			"iss":    "https://op.example.com",
+			"aud":    "client",
+			"sub":    "user1",
+			"iat":    time.Now().Unix(),
+			"exp":    time.Now().Add(time.Minute).Unix(),
+			"jti":    "id",
+			"events": map[string]any{oidc.EventBackChannelLogout: map[string]any{}},
+		}
+	}
+	tests := []struct {
+		name    string
+		modify  func(claims map[string]any)
+		typ     jose.ContentType
+		verify  func(v *oidc.Verifier)
+		wantErr error
+	}{
+		{name: "valid", typ: oidc.TypeLogoutToken},
+		{name: "sid only", modify: func(c map[string]any) { delete(c, "sub"); c["sid"] = "session" }},
+		{name: "no sub and sid", modify: func(c map[string]any) { delete(c, "sub") }, wantErr: oidc.ErrLogoutTokenSubSID},
+		{name: "no event", modify: func(c map[string]any) { delete(c, "events") }, wantErr: oidc.ErrLogoutTokenEvent},
+		{name: "nonce", modify: func(c map[string]any) { c["nonce"] = "n" }, wantErr: oidc.ErrLogoutTokenNonce},
+		{name: "audience", modify: func(c map[string]any) { c["aud"] = "other" }, wantErr: oidc.ErrAudience},
+		{name: "issuer", modify: func(c map[string]any) { c["iss"] = "https://other.example.com" }, wantErr: oidc.ErrIssuerInvalid},
+		{name: "no iat", modify: func(c map[string]any) { delete(c, "iat") }, wantErr: oidc.ErrIatMissing},
+		{name: "expired", modify: func(c map[string]any) { c["exp"] = time.Now().Add(-time.Minute).Unix() }, wantErr: oidc.ErrExpired},
+		{
+			name:    "typ enforced",
+			verify:  func(v *oidc.Verifier) { v.AllowedTypes = []string{oidc.TypeLogoutToken} },
+			wantErr: oidc.ErrTypeInvalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			if tt.modify != nil {
+				tt.modify(claims)
+			}
+			v := newVerifier()
+			if tt.verify != nil {
+				tt.verify(v)
+			}
+			got, err := oidc.VerifyLogoutToken(context.Background(), signToken(t, tt.typ, claims), v)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tu.SignatureAlgorithm, got.SignatureAlg)
+		})
+	}
+}
+
+func TestVerifyJWTAccessToken(t *testing.T) {
+	claims := map[string]any{
+		"iss":       "https://op.example.com",
+		"aud":       []string{"api"},
+		"sub":       "user1",
+		"client_id": "client",
+		"iat":       time.Now().Unix(),
+		"exp":       time.Now().Add(time.Minute).Unix(),
+		"jti":       "id",
+	}
+	v := newVerifier()
+	v.ClientID = "api"
+
+	got, err := oidc.VerifyJWTAccessToken[*oidc.AccessTokenClaims](context.Background(), signToken(t, "at+jwt", claims), v)
+	require.NoError(t, err)
+	assert.Equal(t, "client", got.ClientID)
+
+	_, err = oidc.VerifyJWTAccessToken[*oidc.AccessTokenClaims](context.Background(), signToken(t, "JWT", claims), v)
+	assert.ErrorIs(t, err, oidc.ErrTypeInvalid, "RFC 9068 typ")
+
+	v.ClientID = "other-api"
+	_, err = oidc.VerifyJWTAccessToken[*oidc.AccessTokenClaims](context.Background(), signToken(t, "application/at+jwt", claims), v)
+	assert.ErrorIs(t, err, oidc.ErrAudience)
+}
+
+func TestVerifyJWTProfileAssertion(t *testing.T) {
+	claims := map[string]any{
+		"iss": "client",
+		"sub": "client",
+		"aud": "https://op.example.com/oauth/v2/token",
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}
+	assertion := signToken(t, "", claims)
+
+	got, err := oidc.VerifyJWTProfileAssertion(context.Background(), assertion, newVerifier(), "https://op.example.com/oauth/v2/token")
+	require.NoError(t, err)
+	assert.Equal(t, "client", got.Issuer)
+
+	_, err = oidc.VerifyJWTProfileAssertion(context.Background(), assertion, newVerifier())
+	assert.ErrorIs(t, err, oidc.ErrAudience)
+
+	// the subject is checked by the caller
+	claims["sub"] = "user"
+	got, err = oidc.VerifyJWTProfileAssertion(context.Background(), signToken(t, "", claims), newVerifier(), "https://op.example.com/oauth/v2/token")
+	require.NoError(t, err)
+	assert.Equal(t, "user", got.Subject)
+
+	delete(claims, "sub")
+	_, err = oidc.VerifyJWTProfileAssertion(context.Background(), signToken(t, "", claims), newVerifier(), "https://op.example.com/oauth/v2/token")
+	assert.ErrorIs(t, err, oidc.ErrSubjectMissing)
+}
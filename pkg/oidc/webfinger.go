@@ -0,0 +1,97 @@
+package oidc
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+const (
+	WebFingerEndpoint = "/.well-known/webfinger"
+
+	// WebFingerRelIssuer is the link relation of the issuer of a user, OpenID Connect Discovery section 2.
+	WebFingerRelIssuer = "http://openid.net/specs/connect/1.0/issuer"
+)
+
+// WebFingerRequest is the query of a WebFinger request, RFC 7033 section 4.1.
+type WebFingerRequest struct {
+	Resource string   `schema:"resource"`
+	Rel      []string `schema:"rel"`
+}
+
+// WebFingerResponse is the JSON Resource Descriptor of a WebFinger response, RFC 7033 section 4.4.
+type WebFingerResponse struct {
+	Subject string          `json:"subject"`
+	Links   []WebFingerLink `json:"links,omitempty"`
+}
+
+type WebFingerLink struct {
+	Rel  string `json:"rel"`
+	Href string `json:"href,omitempty"`
+}
+
+// Issuer returns the href of the issuer link, if any.
+func (r *WebFingerResponse) Issuer() string {
+	for _, link := range r.Links {
+		if link.Rel == WebFingerRelIssuer {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+var ErrWebFingerIdentifier = errors.New("invalid identifier for webfinger")
+
+// NormalizeWebFingerResource normalizes the identifier entered by a user,
+// such as an email address or a URL, to the resource of the WebFinger request
+// and returns the host to send it to, OpenID Connect Discovery section 2.1.
+//
+// Identifiers without scheme are acct: URIs, if they only consist of user and host,
+// e.g. joe@example.com. All others are https: URLs, e.g. example.com:8080/joe.
+func NormalizeWebFingerResource(identifier string) (resource, host string, err error) {
+	identifier = strings.TrimSpace(identifier)
+	if identifier == "" {
+		return "", "", ErrWebFingerIdentifier
+	}
+	if rest, ok := strings.CutPrefix(identifier, "acct:"); ok {
+		return acctResource(rest)
+	}
+	if !hasScheme(identifier) {
+		at := strings.LastIndex(identifier, "@")
+		if at > 0 && !strings.ContainsAny(identifier[at+1:], "/?#:") {
+			return acctResource(identifier)
+		}
+		identifier = "https://" + identifier
+	}
+	u, err := url.Parse(identifier)
+	if err != nil || u.Host == "" {
+		return "", "", ErrWebFingerIdentifier
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String(), u.Host, nil
+}
+
+func acctResource(account string) (string, string, error) {
+	at := strings.LastIndex(account, "@")
+	if at <= 0 || at == len(account)-1 {
+		return "", "", ErrWebFingerIdentifier
+	}
+	return "acct:" + account, account[at+1:], nil
+}
+
+// hasScheme reports whether the identifier starts with a URI scheme,
+// which is followed by // to not confuse it with a host and port.
+func hasScheme(identifier string) bool {
+	scheme, _, ok := strings.Cut(identifier, "://")
+	if !ok || scheme == "" {
+		return false
+	}
+	for i, c := range scheme {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && (c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.') {
+			continue
+		}
+		return false
+	}
+	return true
+}
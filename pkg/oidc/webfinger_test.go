@@ -0,0 +1,38 @@
+package oidc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Examples of OpenID Connect Discovery section 2.1 and appendix A.
+func TestNormalizeWebFingerResource(t *testing.T) {
+	tests := []struct {
+		identifier   string
+		wantResource string
+		wantHost     string
+		wantErr      error
+	}{
+		{"joe@example.com", "acct:joe@example.com", "example.com", nil},
+		{"acct:joe@example.com", "acct:joe@example.com", "example.com", nil},
+		{" joe@example.com ", "acct:joe@example.com", "example.com", nil},
+		{"example.com", "https://example.com", "example.com", nil},
+		{"example.com/joe", "https://example.com/joe", "example.com", nil},
+		{"example.com:8080", "https://example.com:8080", "example.com:8080", nil},
+		{"joe@example.com:8080", "https://joe@example.com:8080", "example.com:8080", nil},
+		{"https://example.com/joe#fragment", "https://example.com/joe", "example.com", nil},
+		{"", "", "", ErrWebFingerIdentifier},
+		{"acct:joe", "", "", ErrWebFingerIdentifier},
+		{"acct:joe@", "", "", ErrWebFingerIdentifier},
+		{"https://", "", "", ErrWebFingerIdentifier},
+	}
+	for _, tt := range tests {
+		t.Run(tt.identifier, func(t *testing.T) {
+			resource, host, err := NormalizeWebFingerResource(tt.identifier)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantResource, resource)
+			assert.Equal(t, tt.wantHost, host)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package op
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// AccessTokenFormat encodes access tokens in an alternative format to JWT and opaque bearer tokens,
+// e.g. CBOR Web Tokens for constrained resource servers (see the github.com/zitadel/oidc/v3/pkg/cwt module).
+// Formats are registered with [WithAccessTokenFormat] and selected per client by [AccessTokenFormatClient].
+type AccessTokenFormat interface {
+	// EncodeAccessToken returns the encoded and signed access token of the claims.
+	EncodeAccessToken(ctx context.Context, claims *oidc.AccessTokenClaims) (string, error)
+	// DecodeAccessToken verifies the access token and returns its claims.
+	// It returns an error for tokens of other formats.
+	DecodeAccessToken(ctx context.Context, token string) (*oidc.AccessTokenClaims, error)
+}
+
+// AccessTokenFormatClient is an optional additional interface that may be implemented by
+// implementors of Client to issue its access tokens in the format registered
+// under the returned name with [WithAccessTokenFormat], instead of the [AccessTokenType].
+// An empty name keeps the [AccessTokenType] of the client.
+type AccessTokenFormatClient interface {
+	AccessTokenFormat() string
+}
+
+// WithAccessTokenFormat registers an [AccessTokenFormat] under the name,
+// which is selected by clients implementing [AccessTokenFormatClient].
+// Access tokens of all registered formats are accepted by the userinfo, introspection
+// and token exchange endpoints.
+func WithAccessTokenFormat(name string, format AccessTokenFormat) Option {
+	return func(o *Provider) error {
+		if name == "" || format == nil {
+			return errors.New("access token format requires a name and a format")
+		}
+		if o.accessTokenFormats == nil {
+			o.accessTokenFormats = make(map[string]AccessTokenFormat)
+		}
+		o.accessTokenFormats[name] = format
+		return nil
+	}
+}
+
+// AccessTokenFormats returns the formats registered with [WithAccessTokenFormat].
+func (o *Provider) AccessTokenFormats() map[string]AccessTokenFormat {
+	return o.accessTokenFormats
+}
+
+// accessTokenFormatsProvider is implemented by the [Provider]
+// to pass the formats of [WithAccessTokenFormat] to the token creation and verification.
+type accessTokenFormatsProvider interface {
+	AccessTokenFormats() map[string]AccessTokenFormat
+}
+
+func accessTokenFormatsFrom(v any) map[string]AccessTokenFormat {
+	if p, ok := v.(accessTokenFormatsProvider); ok {
+		return p.AccessTokenFormats()
+	}
+	return nil
+}
+
+// accessTokenFormatOf returns the name of the format selected by the client, if any.
+func accessTokenFormatOf(client AccessTokenClient) string {
+	if c, ok := client.(AccessTokenFormatClient); ok {
+		return c.AccessTokenFormat()
+	}
+	return ""
+}
+
+// createFormattedAccessToken creates the access token in the format registered under the name.
+func createFormattedAccessToken(ctx context.Context, name string, tokenRequest TokenRequest, exp time.Time, id string, client AccessTokenClient, creator TokenCreator) (string, error) {
+	ctx, span := Tracer.Start(ctx, "CreateFormattedAccessToken")
+	defer span.End()
+
+	format, ok := accessTokenFormatsFrom(creator)[name]
+	if !ok {
+		return "", oidc.ErrServerError().WithDescription("access token format %q of the client is not registered", name)
+	}
+	claims, err := createAccessTokenClaims(ctx, IssuerFromContext(ctx), tokenRequest, exp, id, client, creator.Storage(), accessTokenClaimsHooksFrom(creator))
+	if err != nil {
+		return "", err
+	}
+	return format.EncodeAccessToken(ctx, claims)
+}
+
+// decodeFormattedAccessToken returns the claims of an access token
+// of any of the registered formats.
+// It must be called before decrypting bearer tokens, as the decryption is not authenticated
+// and may succeed on tokens of other formats.
+func decodeFormattedAccessToken(ctx context.Context, provider UserinfoProvider, token string) (*oidc.AccessTokenClaims, bool) {
+	formats := accessTokenFormatsFrom(provider)
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		claims, err := formats[name].DecodeAccessToken(ctx, token)
+		if err == nil {
+			return claims, true
+		}
+	}
+	return nil, false
+}
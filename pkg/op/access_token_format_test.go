@@ -0,0 +1,22 @@
+package op_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op/optest"
+)
+
+func TestAccessTokenFormat_notRegistered(t *testing.T) {
+	s := optest.New(t, optest.WithClients(
+		optest.WebClient(optest.WebClientID, optest.WebClientSecret, optest.RedirectURI).WithAccessTokenFormat("cwt"),
+	))
+	relyingParty := s.RelyingParty(t)
+	callback := s.Authorize(t, rp.AuthURL("state", relyingParty))
+	_, err := rp.CodeExchange[*oidc.IDTokenClaims](context.Background(), callback.Query().Get("code"), relyingParty)
+	assert.Error(t, err)
+}
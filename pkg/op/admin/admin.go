@@ -0,0 +1,458 @@
+// Package admin provides an optional management API of the OpenID Provider.
+//
+// The [Handler] serves a REST/JSON API to list and revoke sessions and tokens,
+// inspect active auth requests, rotate the signing key and manage clients.
+// It is mounted separately from the OP, e.g. on an internal listener,
+// and protected by its own [Config.Authorize] function:
+//
+//	handler, err := admin.New(admin.Config{
+//		Provider:  provider,
+//		Authorize: admin.BearerToken(os.Getenv("ADMIN_TOKEN")),
+//	})
+//	go http.ListenAndServe("localhost:9090", handler)
+//
+// The Storage of the OP only has to implement the capabilities it wants to expose:
+// endpoints of unimplemented capabilities (op.SessionStorage, [TokenStorage],
+// [AuthRequestStorage], [KeyRotator], [ClientStorage] and [WritableClientStorage])
+// respond with 501 Not Implemented.
+//
+// Routes, relative to the mount path:
+//
+//	GET    /sessions?sub=            list the sessions of a user
+//	DELETE /sessions?sub=&client_id= terminate the session of a user at a client, or all sessions of the user
+//	DELETE /sessions/{id}            terminate a session and notify its clients
+//	GET    /tokens?sub=&client_id=   list the tokens of a user or client
+//	DELETE /tokens/{id}?type=&sub=&client_id= revoke a token
+//	GET    /auth-requests            list the active auth requests
+//	GET    /auth-requests/{id}       get an auth request
+//	GET    /keys                     get the public signing keys
+//	POST   /keys/rotate              rotate the signing key
+//	GET    /clients                  list the clients
+//	POST   /clients                  create a client
+//	GET    /clients/{id}             get a client
+//	PUT    /clients/{id}             update a client
+//	DELETE /clients/{id}             delete a client
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	httphelper "github.com/zitadel/oidc/v3/pkg/http"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+)
+
+// Session is the representation of an op.UserSession.
+type Session struct {
+	ID        string    `json:"id"`
+	Subject   string    `json:"sub"`
+	AuthTime  time.Time `json:"auth_time,omitzero"`
+	ClientIDs []string  `json:"client_ids"`
+	UserAgent string    `json:"user_agent,omitempty"`
+}
+
+func sessionFrom(session op.UserSession) Session {
+	return Session{
+		ID:        session.ID,
+		Subject:   session.Subject,
+		AuthTime:  session.AuthTime,
+		ClientIDs: nonNil(session.ClientIDs),
+		UserAgent: session.UserAgent,
+	}
+}
+
+// Token is an access or refresh token issued by the OP.
+type Token struct {
+	// ID is the id of the token, as passed to op.CanRevokeTokenFamily.
+	ID         string         `json:"id"`
+	Type       oidc.TokenType `json:"type"`
+	ClientID   string         `json:"client_id"`
+	Subject    string         `json:"sub,omitempty"`
+	Scopes     []string       `json:"scopes,omitempty"`
+	Expiration time.Time      `json:"expiration,omitzero"`
+}
+
+// TokenFilter selects the tokens of [TokenStorage.ListTokens].
+// Empty fields match all tokens.
+type TokenFilter struct {
+	Subject  string
+	ClientID string
+}
+
+// TokenStorage lists the active tokens.
+// Tokens are revoked by op.CanRevokeTokenFamily, or op.Storage.RevokeToken for access tokens.
+type TokenStorage interface {
+	ListTokens(ctx context.Context, filter TokenFilter) ([]Token, error)
+}
+
+// AuthRequestStorage lists the auth requests, which are not yet exchanged for tokens.
+type AuthRequestStorage interface {
+	ListAuthRequests(ctx context.Context) ([]op.AuthRequest, error)
+}
+
+// KeyRotator creates a new signing key, which is returned by
+// op.Storage.SigningKey from now on.
+// The public key of the previous signing key should still be published
+// by op.Storage.KeySet, until the tokens signed with it expired.
+type KeyRotator interface {
+	RotateSigningKey(ctx context.Context) error
+}
+
+// Config of a [Handler].
+type Config struct {
+	// Provider is the managed OP.
+	Provider op.OpenIDProvider
+	// Authorize authorizes the requests to the management API.
+	// A returned error responds with 401 Unauthorized.
+	// See [BearerToken] for a static token.
+	Authorize func(r *http.Request) error
+}
+
+// Handler serves the management API.
+type Handler struct {
+	router   chi.Router
+	provider op.OpenIDProvider
+	storage  op.Storage
+}
+
+// New creates a management API [Handler].
+func New(config Config) (*Handler, error) {
+	if config.Provider == nil {
+		return nil, errors.New("admin: provider is required")
+	}
+	if config.Authorize == nil {
+		return nil, errors.New("admin: authorize is required")
+	}
+	h := &Handler{
+		router:   chi.NewRouter(),
+		provider: config.Provider,
+		storage:  config.Provider.Storage(),
+	}
+	h.router.Use(authorize(config.Authorize))
+	// the issuer is required by the cache of the OP, e.g. for the invalidation of clients
+	h.router.Use(op.NewIssuerInterceptor(config.Provider.IssuerFromRequest).Handler)
+	h.router.Get("/sessions", h.listSessions)
+	h.router.Delete("/sessions", h.terminateSessions)
+	h.router.Delete("/sessions/{id}", h.terminateSession)
+	h.router.Get("/tokens", h.listTokens)
+	h.router.Delete("/tokens/{id}", h.revokeToken)
+	h.router.Get("/auth-requests", h.listAuthRequests)
+	h.router.Get("/auth-requests/{id}", h.getAuthRequest)
+	h.router.Get("/keys", h.keys)
+	h.router.Post("/keys/rotate", h.rotateKey)
+	h.router.Get("/clients", h.listClients)
+	h.router.Post("/clients", h.createClient)
+	h.router.Get("/clients/{id}", h.getClient)
+	h.router.Put("/clients/{id}", h.updateClient)
+	h.router.Delete("/clients/{id}", h.deleteClient)
+	return h, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.router.ServeHTTP(w, r)
+}
+
+// BearerToken authorizes requests with the static token
+// in the Authorization header ("Bearer <token>").
+func BearerToken(token string) func(r *http.Request) error {
+	return func(r *http.Request) error {
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), oidc.PrefixBearer)
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			return errors.New("invalid bearer token")
+		}
+		return nil
+	}
+}
+
+func authorize(fn func(r *http.Request) error) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := fn(r); err != nil {
+				slog.InfoContext(r.Context(), "admin request unauthorized", "path", r.URL.Path, "error", err)
+				writeError(w, http.StatusUnauthorized, "unauthorized", err.Error())
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Error is the response of failed requests.
+type Error struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func writeError(w http.ResponseWriter, status int, errorType, description string) {
+	httphelper.MarshalJSONWithStatus(w, &Error{Error: errorType, Description: description}, status)
+}
+
+func badRequest(w http.ResponseWriter, description string) {
+	writeError(w, http.StatusBadRequest, "invalid_request", description)
+}
+
+func notImplemented(w http.ResponseWriter, capability string) {
+	writeError(w, http.StatusNotImplemented, "not_implemented", "storage does not implement "+capability)
+}
+
+// storageError responds with 404 Not Found for errors implementing op.StorageNotFoundError.
+func storageError(w http.ResponseWriter, r *http.Request, err error) {
+	var notFound op.StorageNotFoundError
+	if errors.As(err, &notFound) {
+		writeError(w, http.StatusNotFound, "not_found", err.Error())
+		return
+	}
+	slog.ErrorContext(r.Context(), "admin request failed", "path", r.URL.Path, "error", err)
+	writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+}
+
+func decode(r *http.Request, v any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+func (h *Handler) listSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, ok := h.storage.(op.SessionStorage)
+	if !ok {
+		notImplemented(w, "op.SessionStorage")
+		return
+	}
+	subject := r.URL.Query().Get("sub")
+	if subject == "" {
+		badRequest(w, "sub is required")
+		return
+	}
+	list, err := sessions.ListSessions(r.Context(), subject)
+	if err != nil {
+		storageError(w, r, err)
+		return
+	}
+	response := make([]Session, len(list))
+	for i, session := range list {
+		response[i] = sessionFrom(session)
+	}
+	httphelper.MarshalJSON(w, response)
+}
+
+// terminateSessions terminates the session of the user at the client,
+// or all sessions of the op.SessionStorage if no client_id is passed.
+func (h *Handler) terminateSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	subject, clientID := r.URL.Query().Get("sub"), r.URL.Query().Get("client_id")
+	if subject == "" {
+		badRequest(w, "sub is required")
+		return
+	}
+	if clientID != "" {
+		if err := h.storage.TerminateSession(ctx, subject, clientID); err != nil {
+			storageError(w, r, err)
+			return
+		}
+		slog.InfoContext(ctx, "admin terminated session", "sub", subject, "client_id", clientID)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	sessions, ok := h.storage.(op.SessionStorage)
+	if !ok {
+		badRequest(w, "client_id is required")
+		return
+	}
+	list, err := sessions.ListSessions(ctx, subject)
+	if err != nil {
+		storageError(w, r, err)
+		return
+	}
+	for _, session := range list {
+		if !h.logoutSession(w, r, session.ID) {
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// terminateSession terminates the session by its id.
+func (h *Handler) terminateSession(w http.ResponseWriter, r *http.Request) {
+	if h.logoutSession(w, r, chi.URLParam(r, "id")) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// logoutSession terminates the session with op.LogoutSession and reports whether it succeeded.
+// The clients are notified by back-channel logout, front-channel logout
+// requires the user agent of the session and is not possible here.
+func (h *Handler) logoutSession(w http.ResponseWriter, r *http.Request, sessionID string) bool {
+	ctx := r.Context()
+	frontChannelURIs, err := op.LogoutSession(ctx, h.provider, sessionID)
+	if errors.Is(err, op.ErrSessionStorageNotImplemented) {
+		notImplemented(w, "op.SessionStorage")
+		return false
+	}
+	if err != nil {
+		storageError(w, r, err)
+		return false
+	}
+	slog.InfoContext(ctx, "admin terminated session", "sid", sessionID, "skipped_frontchannel_logouts", len(frontChannelURIs))
+	return true
+}
+
+func (h *Handler) listTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, ok := h.storage.(TokenStorage)
+	if !ok {
+		notImplemented(w, "admin.TokenStorage")
+		return
+	}
+	list, err := tokens.ListTokens(r.Context(), TokenFilter{
+		Subject:  r.URL.Query().Get("sub"),
+		ClientID: r.URL.Query().Get("client_id"),
+	})
+	if err != nil {
+		storageError(w, r, err)
+		return
+	}
+	httphelper.MarshalJSON(w, nonNil(list))
+}
+
+// parseTokenType accepts the token type identifiers of RFC 8693
+// and the short token_type_hint values of RFC 7009.
+func parseTokenType(value string) (oidc.TokenType, bool) {
+	switch value {
+	case "access_token", string(oidc.AccessTokenType):
+		return oidc.AccessTokenType, true
+	case "refresh_token", string(oidc.RefreshTokenType):
+		return oidc.RefreshTokenType, true
+	}
+	return "", false
+}
+
+func (h *Handler) revokeToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+	tokenType, ok := parseTokenType(query.Get("type"))
+	if !ok {
+		badRequest(w, "type must be access_token or refresh_token")
+		return
+	}
+	request := &op.RevokeTokenFamilyRequest{
+		TokenID:   chi.URLParam(r, "id"),
+		UserID:    query.Get("sub"),
+		ClientID:  query.Get("client_id"),
+		TokenType: tokenType,
+	}
+	if request.ClientID == "" {
+		badRequest(w, "client_id is required")
+		return
+	}
+	var oidcErr *oidc.Error
+	switch family, ok := h.storage.(op.CanRevokeTokenFamily); {
+	case ok:
+		oidcErr = family.RevokeTokenFamily(ctx, request)
+	case tokenType == oidc.AccessTokenType && request.UserID != "":
+		oidcErr = h.storage.RevokeToken(ctx, request.TokenID, request.UserID, request.ClientID)
+	default:
+		// RevokeToken expects the refresh token itself, not its id
+		notImplemented(w, "op.CanRevokeTokenFamily")
+		return
+	}
+	if oidcErr != nil {
+		if oidcErr.ErrorType == oidc.ServerError {
+			storageError(w, r, oidcErr)
+			return
+		}
+		writeError(w, http.StatusBadRequest, string(oidcErr.ErrorType), oidcErr.Description)
+		return
+	}
+	slog.InfoContext(ctx, "admin revoked token", "token_type", tokenType, "client_id", request.ClientID, "sub", request.UserID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AuthRequest is the representation of an op.AuthRequest.
+type AuthRequest struct {
+	ID           string            `json:"id"`
+	ClientID     string            `json:"client_id"`
+	Subject      string            `json:"sub,omitempty"`
+	RedirectURI  string            `json:"redirect_uri"`
+	ResponseType oidc.ResponseType `json:"response_type"`
+	Scopes       []string          `json:"scopes"`
+	AuthTime     time.Time         `json:"auth_time,omitzero"`
+	Done         bool              `json:"done"`
+}
+
+func authRequestFrom(request op.AuthRequest) *AuthRequest {
+	return &AuthRequest{
+		ID:           request.GetID(),
+		ClientID:     request.GetClientID(),
+		Subject:      request.GetSubject(),
+		RedirectURI:  request.GetRedirectURI(),
+		ResponseType: request.GetResponseType(),
+		Scopes:       request.GetScopes(),
+		AuthTime:     request.GetAuthTime(),
+		Done:         request.Done(),
+	}
+}
+
+func (h *Handler) listAuthRequests(w http.ResponseWriter, r *http.Request) {
+	requests, ok := h.storage.(AuthRequestStorage)
+	if !ok {
+		notImplemented(w, "admin.AuthRequestStorage")
+		return
+	}
+	list, err := requests.ListAuthRequests(r.Context())
+	if err != nil {
+		storageError(w, r, err)
+		return
+	}
+	response := make([]*AuthRequest, len(list))
+	for i, request := range list {
+		response[i] = authRequestFrom(request)
+	}
+	httphelper.MarshalJSON(w, response)
+}
+
+func (h *Handler) getAuthRequest(w http.ResponseWriter, r *http.Request) {
+	request, err := h.storage.AuthRequestByID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		storageError(w, r, err)
+		return
+	}
+	httphelper.MarshalJSON(w, authRequestFrom(request))
+}
+
+func (h *Handler) keys(w http.ResponseWriter, r *http.Request) {
+	op.Keys(w, r, h.storage)
+}
+
+func (h *Handler) rotateKey(w http.ResponseWriter, r *http.Request) {
+	rotator, ok := h.storage.(KeyRotator)
+	if !ok {
+		notImplemented(w, "admin.KeyRotator")
+		return
+	}
+	if err := rotator.RotateSigningKey(r.Context()); err != nil {
+		storageError(w, r, err)
+		return
+	}
+	key, err := h.storage.SigningKey(r.Context())
+	if err != nil {
+		storageError(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "admin rotated signing key", "kid", key.ID())
+	httphelper.MarshalJSON(w, map[string]string{"kid": key.ID()})
+}
+
+func nonNil[T any](list []T) []T {
+	if list == nil {
+		return []T{}
+	}
+	return list
+}
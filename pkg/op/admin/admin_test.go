@@ -0,0 +1,233 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/example/server/storage"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/admin"
+	"github.com/zitadel/oidc/v3/pkg/op/optest"
+)
+
+const adminToken = "admin-token"
+
+// withExampleStorage serves the OP with the storage of the example server,
+// which implements the sessions, tokens, clients and key rotation managed by the handler, unlike the optest.MemoryStorage.
+func withExampleStorage() optest.Option {
+	return optest.WithStorage(func(issuer string) (optest.Storage, error) {
+		return storage.NewStorageWithClients(storage.NewUserStore(issuer), map[string]*storage.Client{
+			optest.WebClientID:    storage.WebClient(optest.WebClientID, optest.WebClientSecret, optest.RedirectURI),
+			optest.NativeClientID: storage.NativeClient(optest.NativeClientID, optest.RedirectURI),
+			optest.DeviceClientID: storage.DeviceClient(optest.DeviceClientID, optest.DeviceClientSecret),
+		}), nil
+	})
+}
+
+func newAdmin(t *testing.T, s *optest.Server) func(method, path, body string) (int, []byte) {
+	t.Helper()
+	handler, err := admin.New(admin.Config{
+		Provider:  s.Provider,
+		Authorize: admin.BearerToken(adminToken),
+	})
+	require.NoError(t, err)
+	return func(method, path, body string) (int, []byte) {
+		req := httptest.NewRequest(method, s.Issuer+path, strings.NewReader(body))
+		req.Header.Set("Authorization", oidc.PrefixBearer+adminToken)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		data, err := io.ReadAll(rec.Body)
+		require.NoError(t, err)
+		return rec.Code, data
+	}
+}
+
+func TestNew(t *testing.T) {
+	s := optest.New(t)
+	_, err := admin.New(admin.Config{Provider: s.Provider})
+	assert.Error(t, err)
+	_, err = admin.New(admin.Config{Authorize: admin.BearerToken(adminToken)})
+	assert.Error(t, err)
+}
+
+func TestHandler_unauthorized(t *testing.T) {
+	s := optest.New(t)
+	handler, err := admin.New(admin.Config{
+		Provider:  s.Provider,
+		Authorize: admin.BearerToken(adminToken),
+	})
+	require.NoError(t, err)
+
+	for _, authorization := range []string{"", "Bearer wrong", adminToken} {
+		req := httptest.NewRequest(http.MethodGet, "/clients", nil)
+		req.Header.Set("Authorization", authorization)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, authorization)
+	}
+}
+
+func TestHandler_sessionsAndTokens(t *testing.T) {
+	s := optest.New(t, withExampleStorage())
+	call := newAdmin(t, s)
+	s.CodeFlow(t, s.RelyingParty(t))
+
+	status, body := call(http.MethodGet, "/sessions?sub="+optest.UserID, "")
+	require.Equal(t, http.StatusOK, status, string(body))
+	var sessions []admin.Session
+	require.NoError(t, json.Unmarshal(body, &sessions))
+	require.Len(t, sessions, 1)
+	assert.NotEmpty(t, sessions[0].ID)
+	assert.Equal(t, []string{optest.WebClientID}, sessions[0].ClientIDs)
+
+	status, body = call(http.MethodGet, "/tokens?client_id="+optest.WebClientID, "")
+	require.Equal(t, http.StatusOK, status, string(body))
+	var tokens []admin.Token
+	require.NoError(t, json.Unmarshal(body, &tokens))
+	require.Len(t, tokens, 2)
+
+	// revoking the refresh token revokes the whole grant
+	for _, token := range tokens {
+		if token.Type == oidc.RefreshTokenType {
+			status, body = call(http.MethodDelete, "/tokens/"+token.ID+"?type=refresh_token&client_id="+optest.WebClientID, "")
+			require.Equal(t, http.StatusNoContent, status, string(body))
+		}
+	}
+	status, body = call(http.MethodGet, "/tokens?sub="+optest.UserID, "")
+	require.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, "[]", string(body))
+
+	s.CodeFlow(t, s.RelyingParty(t))
+	status, body = call(http.MethodDelete, "/sessions?sub="+optest.UserID, "")
+	require.Equal(t, http.StatusNoContent, status, string(body))
+	status, body = call(http.MethodGet, "/sessions?sub="+optest.UserID, "")
+	require.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, "[]", string(body))
+
+	s.CodeFlow(t, s.RelyingParty(t))
+	status, body = call(http.MethodGet, "/sessions?sub="+optest.UserID, "")
+	require.Equal(t, http.StatusOK, status)
+	require.NoError(t, json.Unmarshal(body, &sessions))
+	require.Len(t, sessions, 1)
+	status, body = call(http.MethodDelete, "/sessions/"+sessions[0].ID, "")
+	require.Equal(t, http.StatusNoContent, status, string(body))
+	status, _ = call(http.MethodDelete, "/sessions/"+sessions[0].ID, "")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, _ = call(http.MethodGet, "/sessions", "")
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = call(http.MethodDelete, "/tokens/id?type=id_token&client_id=web", "")
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestHandler_authRequests(t *testing.T) {
+	s := optest.New(t, withExampleStorage())
+	call := newAdmin(t, s)
+	request, err := s.Storage.CreateAuthRequest(s.Context(), &oidc.AuthRequest{
+		ClientID:     optest.WebClientID,
+		RedirectURI:  optest.RedirectURI,
+		ResponseType: oidc.ResponseTypeCode,
+		Scopes:       oidc.SpaceDelimitedArray{oidc.ScopeOpenID},
+	}, "")
+	require.NoError(t, err)
+
+	status, body := call(http.MethodGet, "/auth-requests", "")
+	require.Equal(t, http.StatusOK, status, string(body))
+	var requests []admin.AuthRequest
+	require.NoError(t, json.Unmarshal(body, &requests))
+	require.Len(t, requests, 1)
+	assert.Equal(t, request.GetID(), requests[0].ID)
+	assert.False(t, requests[0].Done)
+
+	status, body = call(http.MethodGet, "/auth-requests/"+request.GetID(), "")
+	require.Equal(t, http.StatusOK, status, string(body))
+	assert.Contains(t, string(body), optest.RedirectURI)
+}
+
+func TestHandler_rotateKey(t *testing.T) {
+	s := optest.New(t, withExampleStorage())
+	call := newAdmin(t, s)
+	before, err := s.Storage.SigningKey(s.Context())
+	require.NoError(t, err)
+
+	status, body := call(http.MethodPost, "/keys/rotate", "")
+	require.Equal(t, http.StatusOK, status, string(body))
+	var rotated struct {
+		KeyID string `json:"kid"`
+	}
+	require.NoError(t, json.Unmarshal(body, &rotated))
+	assert.NotEqual(t, before.ID(), rotated.KeyID)
+
+	status, body = call(http.MethodGet, "/keys", "")
+	require.Equal(t, http.StatusOK, status)
+	var keySet jose.JSONWebKeySet
+	require.NoError(t, json.Unmarshal(body, &keySet))
+	assert.Len(t, keySet.Key(before.ID()), 1, "previous key is still published")
+	assert.Len(t, keySet.Key(rotated.KeyID), 1)
+}
+
+func TestHandler_clients(t *testing.T) {
+	s := optest.New(t, withExampleStorage())
+	call := newAdmin(t, s)
+
+	status, body := call(http.MethodPost, "/clients", `{
+		"client_id": "new",
+		"redirect_uris": ["https://example.com/callback"],
+		"application_type": "web",
+		"token_endpoint_auth_method": "client_secret_basic",
+		"response_types": ["code"],
+		"grant_types": ["authorization_code"],
+		"access_token_type": "bearer"
+	}`)
+	require.Equal(t, http.StatusCreated, status, string(body))
+	var created admin.ClientWithSecret
+	require.NoError(t, json.Unmarshal(body, &created))
+	assert.NotEmpty(t, created.Secret)
+	require.NoError(t, s.Storage.AuthorizeClientIDSecret(s.Context(), "new", created.Secret))
+
+	status, body = call(http.MethodPut, "/clients/new", `{
+		"redirect_uris": ["https://example.com/other"],
+		"application_type": "web",
+		"token_endpoint_auth_method": "client_secret_basic",
+		"response_types": ["code"],
+		"grant_types": ["authorization_code", "refresh_token"],
+		"access_token_type": "JWT"
+	}`)
+	require.Equal(t, http.StatusOK, status, string(body))
+
+	status, body = call(http.MethodGet, "/clients/new", "")
+	require.Equal(t, http.StatusOK, status, string(body))
+	var client admin.Client
+	require.NoError(t, json.Unmarshal(body, &client))
+	assert.Equal(t, []string{"https://example.com/other"}, client.RedirectURIs)
+	assert.Equal(t, op.AccessTokenTypeJWT, client.AccessTokenType)
+	// the secret is kept on update
+	assert.NoError(t, s.Storage.AuthorizeClientIDSecret(s.Context(), "new", created.Secret))
+
+	status, body = call(http.MethodGet, "/clients", "")
+	require.Equal(t, http.StatusOK, status)
+	var clients []admin.Client
+	require.NoError(t, json.Unmarshal(body, &clients))
+	assert.Len(t, clients, 4)
+
+	status, _ = call(http.MethodDelete, "/clients/new", "")
+	require.Equal(t, http.StatusNoContent, status)
+	status, _ = call(http.MethodGet, "/clients/new", "")
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = call(http.MethodDelete, "/clients/new", "")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	status, _ = call(http.MethodPut, "/clients/web", `{"client_id": "other"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = call(http.MethodPost, "/clients", `{"unknown": true}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+}
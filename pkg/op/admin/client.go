@@ -0,0 +1,180 @@
+package admin
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	httphelper "github.com/zitadel/oidc/v3/pkg/http"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+)
+
+// Client is the representation of an op.Client,
+// which is also used to create and update clients.
+type Client struct {
+	ID                     string              `json:"client_id"`
+	RedirectURIs           []string            `json:"redirect_uris"`
+	PostLogoutRedirectURIs []string            `json:"post_logout_redirect_uris,omitempty"`
+	ApplicationType        op.ApplicationType  `json:"application_type"`
+	AuthMethod             oidc.AuthMethod     `json:"token_endpoint_auth_method"`
+	ResponseTypes          []oidc.ResponseType `json:"response_types"`
+	GrantTypes             []oidc.GrantType    `json:"grant_types"`
+	AccessTokenType        op.AccessTokenType  `json:"access_token_type"`
+	// IDTokenLifetime in seconds.
+	IDTokenLifetime int64 `json:"id_token_lifetime,omitempty"`
+	// ClockSkew in seconds.
+	ClockSkew int64 `json:"clock_skew,omitempty"`
+	DevMode   bool  `json:"dev_mode,omitempty"`
+}
+
+// ClientWithSecret is the response of a created client.
+// The secret is only returned once.
+type ClientWithSecret struct {
+	*Client
+	Secret string `json:"client_secret,omitempty"`
+}
+
+func clientFrom(client op.Client) *Client {
+	return &Client{
+		ID:                     client.GetID(),
+		RedirectURIs:           client.RedirectURIs(),
+		PostLogoutRedirectURIs: client.PostLogoutRedirectURIs(),
+		ApplicationType:        client.ApplicationType(),
+		AuthMethod:             client.AuthMethod(),
+		ResponseTypes:          client.ResponseTypes(),
+		GrantTypes:             client.GrantTypes(),
+		AccessTokenType:        client.AccessTokenType(),
+		IDTokenLifetime:        int64(client.IDTokenLifetime() / time.Second),
+		ClockSkew:              int64(client.ClockSkew() / time.Second),
+		DevMode:                client.DevMode(),
+	}
+}
+
+// ClientStorage lists the clients.
+type ClientStorage interface {
+	ListClients(ctx context.Context) ([]op.Client, error)
+}
+
+// WritableClientStorage manages the clients.
+//
+// The OP caches clients, if configured by op.WithCache.
+// The [Handler] invalidates the cached client after it was updated or deleted.
+type WritableClientStorage interface {
+	ClientStorage
+	// CreateClient registers a new client and returns its secret,
+	// which is empty for clients using the auth method none.
+	// The secret is returned only once, so only a hash of it should be stored.
+	CreateClient(ctx context.Context, client *Client) (secret string, err error)
+	UpdateClient(ctx context.Context, client *Client) error
+	DeleteClient(ctx context.Context, clientID string) error
+}
+
+func (h *Handler) listClients(w http.ResponseWriter, r *http.Request) {
+	clients, ok := h.storage.(ClientStorage)
+	if !ok {
+		notImplemented(w, "admin.ClientStorage")
+		return
+	}
+	list, err := clients.ListClients(r.Context())
+	if err != nil {
+		storageError(w, r, err)
+		return
+	}
+	response := make([]*Client, len(list))
+	for i, client := range list {
+		response[i] = clientFrom(client)
+	}
+	httphelper.MarshalJSON(w, response)
+}
+
+func (h *Handler) getClient(w http.ResponseWriter, r *http.Request) {
+	client, err := h.storage.GetClientByClientID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		storageError(w, r, err)
+		return
+	}
+	httphelper.MarshalJSON(w, clientFrom(client))
+}
+
+func (h *Handler) createClient(w http.ResponseWriter, r *http.Request) {
+	clients, ok := h.storage.(WritableClientStorage)
+	if !ok {
+		notImplemented(w, "admin.WritableClientStorage")
+		return
+	}
+	client := new(Client)
+	if err := decode(r, client); err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+	if client.ID == "" {
+		badRequest(w, "client_id is required")
+		return
+	}
+	secret, err := clients.CreateClient(r.Context(), client)
+	if err != nil {
+		storageError(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "admin created client", "client_id", client.ID)
+	httphelper.MarshalJSONWithStatus(w, &ClientWithSecret{Client: client, Secret: secret}, http.StatusCreated)
+}
+
+func (h *Handler) updateClient(w http.ResponseWriter, r *http.Request) {
+	clients, ok := h.storage.(WritableClientStorage)
+	if !ok {
+		notImplemented(w, "admin.WritableClientStorage")
+		return
+	}
+	client := new(Client)
+	if err := decode(r, client); err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+	id := chi.URLParam(r, "id")
+	if client.ID != "" && client.ID != id {
+		badRequest(w, "client_id does not match the path")
+		return
+	}
+	client.ID = id
+	if err := clients.UpdateClient(r.Context(), client); err != nil {
+		storageError(w, r, err)
+		return
+	}
+	h.invalidateClient(r, id)
+	slog.InfoContext(r.Context(), "admin updated client", "client_id", id)
+	httphelper.MarshalJSON(w, client)
+}
+
+func (h *Handler) deleteClient(w http.ResponseWriter, r *http.Request) {
+	clients, ok := h.storage.(WritableClientStorage)
+	if !ok {
+		notImplemented(w, "admin.WritableClientStorage")
+		return
+	}
+	id := chi.URLParam(r, "id")
+	if err := clients.DeleteClient(r.Context(), id); err != nil {
+		storageError(w, r, err)
+		return
+	}
+	h.invalidateClient(r, id)
+	slog.InfoContext(r.Context(), "admin deleted client", "client_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// invalidateClient removes the client from the cache of the OP, see op.Provider.InvalidateClient.
+func (h *Handler) invalidateClient(r *http.Request, clientID string) {
+	invalidator, ok := h.provider.(interface {
+		InvalidateClient(ctx context.Context, clientID string) error
+	})
+	if !ok {
+		return
+	}
+	if err := invalidator.InvalidateClient(r.Context(), clientID); err != nil {
+		slog.WarnContext(r.Context(), "admin cannot invalidate cached client", "client_id", clientID, "error", err)
+	}
+}
@@ -69,6 +69,18 @@ type CodeResponseType struct {
 	SessionState string `schema:"session_state,omitempty"`
 }
 
+// HybridResponseType is the successful authentication response of the Hybrid Flow,
+// https://openid.net/specs/openid-connect-core-1_0.html#HybridAuthResponse
+type HybridResponseType struct {
+	Code         string `schema:"code"`
+	AccessToken  string `schema:"access_token,omitempty"`
+	TokenType    string `schema:"token_type,omitempty"`
+	ExpiresIn    uint64 `schema:"expires_in,omitempty"`
+	IDToken      string `schema:"id_token,omitempty"`
+	State        string `schema:"state,omitempty"`
+	SessionState string `schema:"session_state,omitempty"`
+}
+
 func authorizeHandler(authorizer Authorizer) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		Authorize(w, r, authorizer)
@@ -94,12 +106,15 @@ func Authorize(w http.ResponseWriter, r *http.Request, authorizer Authorizer) {
 		return
 	}
 	if authReq.RequestParam != "" && authorizer.RequestObjectSupported() {
-		err = ParseRequestObject(ctx, authReq, authorizer.Storage(), IssuerFromContext(ctx))
+		err = parseRequestObject(ctx, authReq, authorizer.Storage(), IssuerFromContext(ctx), clientKeySetCacheFrom(authorizer), requestObjectSigningAlgorithms(authorizer))
 		if err != nil {
 			AuthRequestError(w, r, nil, err, authorizer)
 			return
 		}
 	}
+	setAuthRequestHints(authReq, r.Form, hintParametersFrom(authorizer))
+	ctx = setAuthRequestUILocale(ctx, authReq, r.Header)
+	r = r.WithContext(ctx)
 	if authReq.ClientID == "" {
 		AuthRequestError(w, r, nil, fmt.Errorf("auth request is missing client_id"), authorizer)
 		return
@@ -111,7 +126,7 @@ func Authorize(w http.ResponseWriter, r *http.Request, authorizer Authorizer) {
 
 	var client Client
 	validation := func(ctx context.Context, authReq *oidc.AuthRequest, storage Storage, verifier *IDTokenHintVerifier) (sub string, err error) {
-		client, err = authorizer.Storage().GetClientByClientID(ctx, authReq.ClientID)
+		client, err = getClientByClientID(ctx, cacheFrom(authorizer), authorizer.Storage(), authReq.ClientID)
 		if err != nil {
 			return "", oidc.ErrInvalidRequestRedirectURI().WithDescription("unable to retrieve client by id").WithParent(err)
 		}
@@ -134,7 +149,7 @@ func Authorize(w http.ResponseWriter, r *http.Request, authorizer Authorizer) {
 	// here. RedirectToLogin dereferences client (client.LoginURL), so fetch it if a
 	// custom validator left it unset, to avoid a nil pointer panic.
 	if client == nil {
-		client, err = authorizer.Storage().GetClientByClientID(ctx, authReq.ClientID)
+		client, err = getClientByClientID(ctx, cacheFrom(authorizer), authorizer.Storage(), authReq.ClientID)
 		if err != nil {
 			// The library cannot assume the custom validator verified the redirect_uri
 			// against the client, so disable the error redirect to avoid an open redirect.
@@ -142,7 +157,31 @@ func Authorize(w http.ResponseWriter, r *http.Request, authorizer Authorizer) {
 			return
 		}
 	}
-	req, err := authorizer.Storage().CreateAuthRequest(ctx, authReq, userID)
+	if err := validateAuthRequestClientType(authorizer, client, authReq); err != nil {
+		AuthRequestError(w, r, authReq, err, authorizer)
+		return
+	}
+	if silentStorage, ok := authorizer.Storage().(SilentAuthStorage); ok && isPromptNone(authReq.Prompt) {
+		req, err := silentAuthRequest(ctx, authorizer, silentStorage, r.Header, authReq, userID)
+		if err != nil {
+			AuthRequestError(w, r, authReq, err, authorizer)
+			return
+		}
+		http.Redirect(w, r, authorizeCallbackURL(ctx, authorizationEndpointFrom(authorizer), req.GetID()), http.StatusFound)
+		return
+	}
+	if stateless, ok := statelessAuthRequesterFrom(authorizer); ok {
+		id, err := createStatelessAuthRequest(stateless, authReq, userID)
+		if err != nil {
+			AuthRequestError(w, r, authReq, oidc.DefaultToServerError(err, "unable to create auth request"), authorizer)
+			return
+		}
+		RedirectToLogin(id, client, w, r)
+		return
+	}
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	req, err := authorizer.Storage().CreateAuthRequest(storageCtx, authReq, userID)
 	if err != nil {
 		AuthRequestError(w, r, authReq, oidc.DefaultToServerError(err, "unable to save auth request"), authorizer)
 		return
@@ -167,6 +206,14 @@ func ParseAuthorizeRequest(r *http.Request, decoder httphelper.Decoder) (*oidc.A
 // ParseRequestObject parse the `request` parameter, validates the token including the signature
 // and copies the token claims into the auth request
 func ParseRequestObject(ctx context.Context, authReq *oidc.AuthRequest, storage Storage, issuer string) error {
+	return parseRequestObject(ctx, authReq, storage, issuer, nil, nil)
+}
+
+// parseRequestObject verifies the signature of the request object with the
+// registered jwks / jwks_uri of the client if clientKeys is set, see [HasJWKS] and [HasJWKSURI].
+// The algorithm must be the request_object_signing_alg of the client, see [HasRequestObjectSigningAlg],
+// or one of the supportedAlgs (default RS256).
+func parseRequestObject(ctx context.Context, authReq *oidc.AuthRequest, storage Storage, issuer string, clientKeys *ClientKeySetCache, supportedAlgs []string) error {
 	requestObject := new(oidc.RequestObject)
 	payload, err := oidc.ParseToken(authReq.RequestParam, requestObject)
 	if err != nil {
@@ -185,8 +232,12 @@ func ParseRequestObject(ctx context.Context, authReq *oidc.AuthRequest, storage
 	if !slices.Contains(requestObject.Audience, issuer) {
 		return oidc.ErrInvalidRequest().WithDescription("issuer missing in audience")
 	}
-	keySet := &jwtProfileKeySet{storage: storage, clientID: requestObject.Issuer}
-	if err = oidc.CheckSignature(ctx, authReq.RequestParam, payload, requestObject, nil, keySet); err != nil {
+	keySet := &jwtProfileKeySet{storage: storage, clientID: requestObject.Issuer, clientKeys: clientKeys, clientAlg: requestObjectSigningAlg}
+	signingAlgs, err := keySet.signingAlgs(ctx, supportedAlgs)
+	if err != nil {
+		return oidc.ErrInvalidRequest().WithParent(err).WithDescription("request_object_signing_alg of the client is not supported")
+	}
+	if err = oidc.CheckSignature(ctx, authReq.RequestParam, payload, requestObject, signingAlgs, keySet); err != nil {
 		return oidc.ErrInvalidRequest().WithParent(err).WithDescription("invalid request signature")
 	}
 	CopyRequestObjectToAuthRequest(authReq, requestObject)
@@ -229,6 +280,9 @@ func CopyRequestObjectToAuthRequest(authReq *oidc.AuthRequest, requestObject *oi
 	if requestObject.LoginHint != "" {
 		authReq.LoginHint = requestObject.LoginHint
 	}
+	if requestObject.HostedDomain != "" {
+		authReq.HostedDomain = requestObject.HostedDomain
+	}
 	if len(requestObject.ACRValues) > 0 {
 		authReq.ACRValues = requestObject.ACRValues
 	}
@@ -248,7 +302,9 @@ func ValidateAuthRequest(ctx context.Context, authReq *oidc.AuthRequest, storage
 	ctx, span := Tracer.Start(ctx, "ValidateAuthRequest")
 	defer span.End()
 
-	client, err := storage.GetClientByClientID(ctx, authReq.ClientID)
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	client, err := storage.GetClientByClientID(storageCtx, authReq.ClientID)
 	if err != nil {
 		return "", oidc.ErrInvalidRequestRedirectURI().WithDescription("unable to retrieve client by id").WithParent(err)
 	}
@@ -268,16 +324,29 @@ func ValidateAuthRequestClient(ctx context.Context, authReq *oidc.AuthRequest, c
 	if err != nil {
 		return "", err
 	}
-	authReq.Scopes, err = ValidateAuthReqScopes(client, authReq.Scopes)
+	authReq.Scopes, err = validateAuthReqScopes(ctx, client, authReq.Scopes)
 	if err != nil {
 		return "", err
 	}
 	if err := ValidateAuthReqResponseType(client, authReq.ResponseType); err != nil {
 		return "", err
 	}
+	if err := ValidateAuthReqNonce(authReq.ResponseType, authReq.Nonce); err != nil {
+		return "", err
+	}
 	return ValidateAuthReqIDTokenHint(ctx, authReq.IDTokenHint, verifier)
 }
 
+// ValidateAuthReqNonce validates the presence of the nonce, which is required
+// when an id_token is returned from the authorization endpoint (Implicit and Hybrid Flow).
+func ValidateAuthReqNonce(responseType oidc.ResponseType, nonce string) error {
+	if nonce == "" && responseType.IncludesIDToken() {
+		return oidc.ErrInvalidRequest().WithDescription("The nonce parameter is required for the response_type %s. "+
+			"Please ensure it is added to the request. If you have any questions, you may contact the administrator of the application.", responseType)
+	}
+	return nil
+}
+
 // ValidateAuthReqPrompt validates the passed prompt values and sets max_age to 0 if prompt login is present
 func ValidateAuthReqPrompt(prompts []string, maxAge *uint) (_ *uint, err error) {
 	for _, prompt := range prompts {
@@ -333,13 +402,19 @@ func checkURIAgainstRedirects(client Client, uri string) error {
 			"If you have any questions, you may contact the administrator of the application.")
 }
 
-// ValidateAuthReqRedirectURI validates the passed redirect_uri and response_type to the registered uris and client type
+// ValidateAuthReqRedirectURI validates the passed redirect_uri and response_type to the registered uris and client type.
+// Clients implementing [HasRedirectURIPolicy] are validated by their [RedirectURIPolicy] instead.
 func ValidateAuthReqRedirectURI(client Client, uri string, responseType oidc.ResponseType) error {
 	uri, err := url.QueryUnescape(uri)
 	if uri == "" || err != nil {
 		return oidc.ErrInvalidRequestRedirectURI().WithDescription("The redirect_uri is missing in the request. " +
 			"Please ensure it is added to the request. If you have any questions, you may contact the administrator of the application.")
 	}
+	if policyClient, ok := client.(HasRedirectURIPolicy); ok {
+		if policy := policyClient.RedirectURIPolicy(); policy != nil {
+			return policy.ValidateRedirectURI(client, uri, responseType)
+		}
+	}
 	if client.ApplicationType() == ApplicationTypeNative {
 		return validateAuthReqRedirectURINative(client, uri)
 	}
@@ -455,7 +530,23 @@ func AuthorizeCallback(w http.ResponseWriter, r *http.Request, authorizer Author
 		AuthRequestError(w, r, nil, err, authorizer)
 		return
 	}
-	authReq, err := authorizer.Storage().AuthRequestByID(r.Context(), id)
+	if stateless, ok := statelessAuthRequesterFrom(authorizer); ok && isStatelessAuthRequestID(id) {
+		authReq, pending, err := completedStatelessAuthRequest(ctx, stateless, authorizer.Storage(), id)
+		if err != nil {
+			if pending != nil {
+				AuthRequestError(w, r, pending, err, authorizer)
+				return
+			}
+			AuthRequestError(w, r, nil, err, authorizer)
+			return
+		}
+		setSessionCookie(w, r, authorizer, authReq)
+		AuthResponse(authReq, authorizer, w, r)
+		return
+	}
+	storageCtx, cancel := storageContext(r.Context())
+	defer cancel()
+	authReq, err := authorizer.Storage().AuthRequestByID(storageCtx, id)
 	if err != nil {
 		AuthRequestError(w, r, nil, err, authorizer)
 		return
@@ -466,6 +557,7 @@ func AuthorizeCallback(w http.ResponseWriter, r *http.Request, authorizer Author
 			authorizer)
 		return
 	}
+	setSessionCookie(w, r, authorizer, authReq)
 	AuthResponse(authReq, authorizer, w, r)
 }
 
@@ -483,10 +575,10 @@ func ParseAuthorizeCallbackRequest(r *http.Request) (id string, err error) {
 // AuthResponse creates the successful authentication response (either code or tokens)
 func AuthResponse(authReq AuthRequest, authorizer Authorizer, w http.ResponseWriter, r *http.Request) {
 	ctx, span := Tracer.Start(r.Context(), "AuthResponse")
-	r = r.WithContext(ctx)
+	r = r.WithContext(ContextWithAuthRequest(ctx, authReq))
 	defer span.End()
 
-	client, err := authorizer.Storage().GetClientByClientID(r.Context(), authReq.GetClientID())
+	client, err := getClientByClientID(r.Context(), cacheFrom(authorizer), authorizer.Storage(), authReq.GetClientID())
 	if err != nil {
 		AuthRequestError(w, r, authReq, err, authorizer)
 		return
@@ -495,6 +587,10 @@ func AuthResponse(authReq AuthRequest, authorizer Authorizer, w http.ResponseWri
 		AuthResponseCode(w, r, authReq, authorizer)
 		return
 	}
+	if authReq.GetResponseType().IsHybrid() {
+		AuthResponseHybrid(w, r, authReq, authorizer, client)
+		return
+	}
 	AuthResponseToken(w, r, authReq, authorizer, client)
 }
 
@@ -595,6 +691,68 @@ func AuthResponseToken(w http.ResponseWriter, r *http.Request, authReq AuthReque
 	http.Redirect(w, r, callback, http.StatusFound)
 }
 
+// AuthResponseHybrid creates the successful authentication response of the Hybrid Flow,
+// returning the code together with the requested tokens.
+// The auth request is kept for the exchange of the code.
+func AuthResponseHybrid(w http.ResponseWriter, r *http.Request, authReq AuthRequest, authorizer Authorizer, client Client) {
+	ctx, span := Tracer.Start(r.Context(), "AuthResponseHybrid")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	resp, err := BuildAuthResponseHybridPayload(r.Context(), authReq, authorizer, client)
+	if err != nil {
+		AuthRequestError(w, r, authReq, err, authorizer)
+		return
+	}
+	if authReq.GetResponseMode() == oidc.ResponseModeFormPost {
+		if err := AuthResponseFormPost(w, authReq.GetRedirectURI(), resp, authorizer.Encoder()); err != nil {
+			AuthRequestError(w, r, authReq, err, authorizer)
+		}
+		return
+	}
+	callback, err := AuthResponseURL(authReq.GetRedirectURI(), authReq.GetResponseType(), authReq.GetResponseMode(), resp, authorizer.Encoder())
+	if err != nil {
+		AuthRequestError(w, r, authReq, err, authorizer)
+		return
+	}
+	http.Redirect(w, r, callback, http.StatusFound)
+}
+
+// BuildAuthResponseHybridPayload creates and stores the code and creates the tokens
+// requested by the response_type of the Hybrid Flow.
+// The id_token contains the c_hash of the code and the at_hash of the access token.
+// Refresh tokens are only issued by the token endpoint.
+func BuildAuthResponseHybridPayload(ctx context.Context, authReq AuthRequest, authorizer Authorizer, client Client) (*HybridResponseType, error) {
+	code, err := CreateAuthRequestCode(ctx, authReq, authorizer.Storage(), authorizer.Crypto())
+	if err != nil {
+		return nil, err
+	}
+	resp := &HybridResponseType{
+		Code:  code,
+		State: authReq.GetState(),
+	}
+	if authRequestSessionState, ok := authReq.(AuthRequestSessionState); ok {
+		resp.SessionState = authRequestSessionState.GetSessionState()
+	}
+	responseType := authReq.GetResponseType()
+	if responseType.IncludesAccessToken() {
+		accessToken, _, validity, err := createAccessToken(ctx, authReq, client.AccessTokenType(), authorizer, client, "", false)
+		if err != nil {
+			return nil, err
+		}
+		resp.AccessToken = accessToken
+		resp.TokenType = oidc.BearerToken
+		resp.ExpiresIn = uint64(validity.Seconds())
+	}
+	if responseType.IncludesIDToken() {
+		resp.IDToken, err = createIDToken(ctx, IssuerFromContext(ctx), authReq, client.IDTokenLifetime(), resp.AccessToken, code, authorizer.Storage(), client, idTokenClaimsHooksFrom(authorizer), clientKeySetCacheFrom(authorizer), signerCacheFrom(authorizer))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
 // CreateAuthRequestCode creates and stores a code for the auth code response
 func CreateAuthRequestCode(ctx context.Context, authReq AuthRequest, storage Storage, encrypter Encrypter) (string, error) {
 	ctx, span := Tracer.Start(ctx, "CreateAuthRequestCode")
@@ -604,7 +762,9 @@ func CreateAuthRequestCode(ctx context.Context, authReq AuthRequest, storage Sto
 	if err != nil {
 		return "", err
 	}
-	if err := storage.SaveAuthCode(ctx, authReq.GetID(), code); err != nil {
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	if err := storage.SaveAuthCode(storageCtx, authReq.GetID(), code); err != nil {
 		return "", err
 	}
 	return code, nil
@@ -633,8 +793,8 @@ func AuthResponseURL(redirectURI string, responseType oidc.ResponseType, respons
 	if responseMode == oidc.ResponseModeFragment {
 		return setFragment(uri, params), nil
 	}
-	// implicit must use fragment mode is not specified by client
-	if responseType == oidc.ResponseTypeIDToken || responseType == oidc.ResponseTypeIDTokenOnly {
+	// implicit and hybrid must use fragment mode if not specified by client
+	if responseType == oidc.ResponseTypeIDToken || responseType == oidc.ResponseTypeIDTokenOnly || responseType.IsHybrid() {
 		return setFragment(uri, params), nil
 	}
 	// if we get here it's code flow: defaults to query
@@ -0,0 +1,51 @@
+package op
+
+import (
+	"net/url"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// WithHintParameters sets the names of custom hint parameters of the auth request,
+// e.g. a tenant or the preferred identity provider.
+// Their values are set to [oidc.AuthRequest.Hints] before the auth request is passed to the Storage,
+// so they can be stored and used by the login UI.
+// The standard login_hint, id_token_hint and hd parameters are always parsed.
+func WithHintParameters(params ...string) Option {
+	return func(o *Provider) error {
+		o.hintParameters = append(o.hintParameters, params...)
+		return nil
+	}
+}
+
+// HintParameters returns the parameter names set by [WithHintParameters].
+func (o *Provider) HintParameters() []string {
+	return o.hintParameters
+}
+
+// hintParametersProvider is implemented by the [Provider]
+// to pass the parameters of [WithHintParameters] to the authorize endpoint.
+type hintParametersProvider interface {
+	HintParameters() []string
+}
+
+func hintParametersFrom(v any) []string {
+	if p, ok := v.(hintParametersProvider); ok {
+		return p.HintParameters()
+	}
+	return nil
+}
+
+// setAuthRequestHints sets the values of the hint parameters in the form to the auth request.
+func setAuthRequestHints(authReq *oidc.AuthRequest, form url.Values, params []string) {
+	for _, param := range params {
+		value := form.Get(param)
+		if value == "" {
+			continue
+		}
+		if authReq.Hints == nil {
+			authReq.Hints = make(map[string]string, len(params))
+		}
+		authReq.Hints[param] = value
+	}
+}
@@ -0,0 +1,25 @@
+package op
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+func TestWithHintParameters(t *testing.T) {
+	provider := &Provider{}
+	require.NoError(t, WithHintParameters("tenant", "idp")(provider))
+	assert.Equal(t, []string{"tenant", "idp"}, hintParametersFrom(provider))
+
+	authReq := new(oidc.AuthRequest)
+	setAuthRequestHints(authReq, url.Values{"tenant": {"acme"}, "other": {"value"}}, hintParametersFrom(provider))
+	assert.Equal(t, map[string]string{"tenant": "acme"}, authReq.Hints)
+
+	authReq = new(oidc.AuthRequest)
+	setAuthRequestHints(authReq, url.Values{"other": {"value"}}, hintParametersFrom(provider))
+	assert.Nil(t, authReq.Hints)
+}
@@ -0,0 +1,199 @@
+package op
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// StatelessAuthRequestStorage is an optional additional interface that must be implemented by
+// implementors of Storage to use [WithStatelessAuthRequests].
+type StatelessAuthRequestStorage interface {
+	// CreateCompletedAuthRequest creates the auth request of req, which was authenticated by the login UI
+	// within req.Session and is therefore [AuthRequest.Done]. The session ID must be passed on to the tokens,
+	// see [SessionRequest].
+	// It is called on the callback of the login, so the auth request can be read by AuthRequestByCode later.
+	//
+	// Each stateless auth request may only be completed once: the storage must return an error
+	// if it already created an auth request with the ID of req before its Expiry,
+	// so a completed request can't be replayed to the callback.
+	CreateCompletedAuthRequest(ctx context.Context, req *StatelessAuthRequest) (AuthRequest, error)
+}
+
+// StatelessAuthRequest is the pending auth request of [WithStatelessAuthRequests],
+// which is passed encrypted to the login UI instead of being stored.
+type StatelessAuthRequest struct {
+	// ID is unique per auth request and kept on completion.
+	ID      string            `json:"id"`
+	Request *oidc.AuthRequest `json:"request"`
+	// UserID is the subject of the id_token_hint, if any.
+	UserID string `json:"user_id,omitempty"`
+	// Session is set by [Provider.CompleteStatelessAuthRequest] after the user was authenticated.
+	Session *UserSession `json:"session,omitempty"`
+	Expiry  time.Time    `json:"expiry"`
+}
+
+const (
+	// statelessAuthRequestPrefix distinguishes the IDs of stateless from stored auth requests.
+	statelessAuthRequestPrefix = "s."
+	// statelessAuthRequestType binds the encrypted values to their purpose.
+	statelessAuthRequestType = "stateless_auth_request"
+)
+
+var ErrStatelessAuthRequestExpired = errors.New("stateless auth request expired")
+
+type sealedAuthRequest struct {
+	Type string `json:"typ"`
+	*StatelessAuthRequest
+}
+
+// WithStatelessAuthRequests passes the pending auth requests encrypted by the CryptoKey
+// of the [Config] as ID to the login UI (see [Client.LoginURL]), instead of storing them
+// between the authorize endpoint and the callback of the login.
+// This avoids storage reads across regions of horizontally scaled OPs during the login.
+// The encryption (AES-256-GCM) protects the integrity of the auth requests,
+// which expire after the lifetime.
+//
+// The login UI reads the auth request with [Provider.OpenStatelessAuthRequest],
+// keeps the ID e.g. in a form field or cookie during the login and
+// completes it by [Provider.CompleteStatelessAuthRequest],
+// whose result is passed to the callback as id.
+// The callback creates the completed auth request by the [StatelessAuthRequestStorage].
+func WithStatelessAuthRequests(lifetime time.Duration) Option {
+	return func(o *Provider) error {
+		if lifetime <= 0 {
+			return errors.New("lifetime of stateless auth requests must be positive")
+		}
+		if _, ok := o.storage.(StatelessAuthRequestStorage); !ok {
+			return errors.New("storage must implement StatelessAuthRequestStorage for stateless auth requests")
+		}
+		o.statelessAuthRequests = lifetime
+		return nil
+	}
+}
+
+// StatelessAuthRequestLifetime returns the lifetime set by [WithStatelessAuthRequests].
+func (o *Provider) StatelessAuthRequestLifetime() time.Duration {
+	return o.statelessAuthRequests
+}
+
+// OpenStatelessAuthRequest decrypts the ID of a stateless auth request passed to the login UI.
+// It returns [ErrStatelessAuthRequestExpired] after the lifetime of the request.
+func (o *Provider) OpenStatelessAuthRequest(id string) (*StatelessAuthRequest, error) {
+	return openStatelessAuthRequest(o.statelessCrypto, id, time.Now())
+}
+
+// CompleteStatelessAuthRequest returns the ID of the auth request authenticated by the login UI
+// within the session, which is passed to the callback (see [AuthCallbackURL]).
+func (o *Provider) CompleteStatelessAuthRequest(id string, session *UserSession) (string, error) {
+	req, err := o.OpenStatelessAuthRequest(id)
+	if err != nil {
+		return "", err
+	}
+	if session == nil || session.Subject == "" {
+		return "", errors.New("stateless auth request: session subject missing")
+	}
+	if req.UserID != "" && req.UserID != session.Subject {
+		return "", errors.New("stateless auth request: subject does not match the id_token_hint")
+	}
+	req.Session = session
+	return sealStatelessAuthRequest(o.statelessCrypto, req)
+}
+
+type statelessAuthRequester interface {
+	StatelessAuthRequestLifetime() time.Duration
+	OpenStatelessAuthRequest(id string) (*StatelessAuthRequest, error)
+	statelessAuthRequestCrypto() Crypto
+}
+
+func (o *Provider) statelessAuthRequestCrypto() Crypto {
+	return o.statelessCrypto
+}
+
+// statelessAuthRequesterFrom returns the authorizer, if it is configured for stateless auth requests.
+func statelessAuthRequesterFrom(v any) (statelessAuthRequester, bool) {
+	s, ok := v.(statelessAuthRequester)
+	if !ok || s.StatelessAuthRequestLifetime() <= 0 {
+		return nil, false
+	}
+	return s, true
+}
+
+func sealStatelessAuthRequest(crypto Crypto, req *StatelessAuthRequest) (string, error) {
+	payload, err := json.Marshal(sealedAuthRequest{Type: statelessAuthRequestType, StatelessAuthRequest: req})
+	if err != nil {
+		return "", err
+	}
+	sealed, err := crypto.Encrypt(string(payload))
+	if err != nil {
+		return "", err
+	}
+	return statelessAuthRequestPrefix + sealed, nil
+}
+
+func openStatelessAuthRequest(crypto Crypto, id string, now time.Time) (*StatelessAuthRequest, error) {
+	sealed, ok := strings.CutPrefix(id, statelessAuthRequestPrefix)
+	if !ok {
+		return nil, errors.New("not a stateless auth request")
+	}
+	payload, err := crypto.Decrypt(sealed)
+	if err != nil {
+		return nil, err
+	}
+	var req sealedAuthRequest
+	if err := json.Unmarshal([]byte(payload), &req); err != nil {
+		return nil, err
+	}
+	if req.Type != statelessAuthRequestType || req.StatelessAuthRequest == nil || req.ID == "" || req.Request == nil {
+		return nil, errors.New("not a stateless auth request")
+	}
+	if now.After(req.Expiry) {
+		return nil, ErrStatelessAuthRequestExpired
+	}
+	return req.StatelessAuthRequest, nil
+}
+
+// createStatelessAuthRequest returns the ID of the encrypted auth request,
+// which replaces the stored auth request.
+func createStatelessAuthRequest(s statelessAuthRequester, authReq *oidc.AuthRequest, userID string) (string, error) {
+	return sealStatelessAuthRequest(s.statelessAuthRequestCrypto(), &StatelessAuthRequest{
+		ID:      uuid.NewString(),
+		Request: authReq,
+		UserID:  userID,
+		Expiry:  time.Now().Add(s.StatelessAuthRequestLifetime()),
+	})
+}
+
+// completedStatelessAuthRequest creates the auth request completed by the login UI in the storage.
+// The returned request is the pending auth request for errors, it is nil if the id is invalid.
+func completedStatelessAuthRequest(ctx context.Context, s statelessAuthRequester, storage Storage, id string) (AuthRequest, *oidc.AuthRequest, error) {
+	req, err := s.OpenStatelessAuthRequest(id)
+	if err != nil {
+		return nil, nil, oidc.ErrInvalidRequest().WithDescription("invalid or expired auth request").WithParent(err)
+	}
+	if req.Session == nil {
+		return nil, req.Request, oidc.ErrInteractionRequired().WithDescription("Unfortunately, the user may be not logged in and/or additional interaction is required.")
+	}
+	completer, ok := storage.(StatelessAuthRequestStorage)
+	if !ok {
+		return nil, req.Request, oidc.ErrServerError().WithDescription("storage does not support stateless auth requests")
+	}
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	authReq, err := completer.CreateCompletedAuthRequest(storageCtx, req)
+	if err != nil {
+		return nil, req.Request, oidc.DefaultToServerError(err, "unable to save auth request")
+	}
+	return authReq, req.Request, nil
+}
+
+// isStatelessAuthRequestID reports whether the id was created by [WithStatelessAuthRequests].
+func isStatelessAuthRequestID(id string) bool {
+	return strings.HasPrefix(id, statelessAuthRequestPrefix)
+}
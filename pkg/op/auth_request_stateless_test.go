@@ -0,0 +1,102 @@
+package op_test
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/optest"
+)
+
+func TestStatelessAuthRequests(t *testing.T) {
+	for name, opts := range map[string][]optest.Option{
+		"provider":      nil,
+		"legacy server": {optest.WithLegacyServer()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := optest.New(t, append(opts, withExampleStorage(), optest.WithProviderOptions(op.WithStatelessAuthRequests(time.Minute)))...)
+			relyingParty := s.RelyingParty(t)
+
+			tokens := s.CodeFlow(t, relyingParty, rp.WithLoginHint("hint"))
+			assert.Equal(t, optest.UserID, tokens.IDTokenClaims.Subject)
+
+			client := &http.Client{
+				Transport: s.Client().Transport,
+				CheckRedirect: func(*http.Request, []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			}
+			resp, err := client.Get(rp.AuthURL("state", relyingParty))
+			require.NoError(t, err)
+			resp.Body.Close()
+			login, err := resp.Location()
+			require.NoError(t, err)
+			id := login.Query().Get("authRequestID")
+			require.True(t, strings.HasPrefix(id, "s."), "encrypted auth request passed to the login")
+
+			req, err := s.Provider.OpenStatelessAuthRequest(id)
+			require.NoError(t, err)
+			assert.Equal(t, optest.WebClientID, req.Request.ClientID)
+			assert.Equal(t, "state", req.Request.State)
+			assert.Nil(t, req.Session)
+
+			callback := func(t *testing.T, id string) url.Values {
+				resp, err := client.Get(op.AuthCallbackURL(s.Provider)(s.Context(), url.QueryEscape(id)))
+				require.NoError(t, err)
+				defer resp.Body.Close()
+				location, err := resp.Location()
+				require.NoError(t, err)
+				return location.Query()
+			}
+
+			t.Run("not completed", func(t *testing.T) {
+				params := callback(t, id)
+				assert.Equal(t, string(oidc.InteractionRequired), params.Get("error"))
+				assert.Equal(t, "state", params.Get("state"))
+			})
+			t.Run("tampered", func(t *testing.T) {
+				_, err := s.Provider.OpenStatelessAuthRequest(id[:len(id)-2] + "AA")
+				assert.Error(t, err)
+				_, err = s.Provider.CompleteStatelessAuthRequest(id, &op.UserSession{})
+				assert.Error(t, err, "no subject")
+			})
+			t.Run("completed", func(t *testing.T) {
+				completed, err := s.Provider.CompleteStatelessAuthRequest(id, &op.UserSession{ID: "session", Subject: optest.UserID, AuthTime: time.Now()})
+				require.NoError(t, err)
+				params := callback(t, completed)
+				require.Empty(t, params.Get("error"), params.Get("error_description"))
+				assert.NotEmpty(t, params.Get("code"))
+
+				params = callback(t, completed)
+				assert.Equal(t, string(oidc.ServerError), params.Get("error"), "replayed")
+				assert.Empty(t, params.Get("code"))
+			})
+		})
+	}
+}
+
+func TestWithStatelessAuthRequests(t *testing.T) {
+	_, err := op.NewProvider(testConfig, nil, op.StaticIssuer(testIssuer), op.WithStatelessAuthRequests(time.Minute))
+	assert.Error(t, err, "storage not supported")
+
+	s := optest.New(t, withExampleStorage(), optest.WithProviderOptions(op.WithStatelessAuthRequests(time.Nanosecond)))
+	resp, err := (&http.Client{
+		Transport:     s.Client().Transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}).Get(rp.AuthURL("state", s.RelyingParty(t)))
+	require.NoError(t, err)
+	resp.Body.Close()
+	login, err := resp.Location()
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	_, err = s.Provider.OpenStatelessAuthRequest(login.Query().Get("authRequestID"))
+	assert.ErrorIs(t, err, op.ErrStatelessAuthRequestExpired)
+}
@@ -1708,3 +1708,13 @@ func TestBuildAuthResponseCallbackURL(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthRequestFromContext(t *testing.T) {
+	_, ok := op.AuthRequestFromContext(context.Background())
+	assert.False(t, ok)
+
+	authReq := &stubAuthRequest{id: "authReqID"}
+	got, ok := op.AuthRequestFromContext(op.ContextWithAuthRequest(context.Background(), authReq))
+	assert.True(t, ok)
+	assert.Equal(t, "authReqID", got.GetID())
+}
@@ -0,0 +1,167 @@
+package op
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// BackChannelLogoutClient is an optional interface that can be implemented by implementors of
+// Client to be notified about the end of a user's session with a logout token,
+// as defined by https://openid.net/specs/openid-connect-backchannel-1_0.html
+type BackChannelLogoutClient interface {
+	// BackChannelLogoutURI returns the backchannel_logout_uri of the client.
+	// No logout token is sent if it is empty.
+	BackChannelLogoutURI() string
+	// BackChannelLogoutSessionRequired reports whether the client requires the sid claim
+	// in the logout token. Sessions without an ID are skipped for such clients.
+	BackChannelLogoutSessionRequired() bool
+}
+
+// ClientSession is a session of the user at a client, which has to be notified
+// when the user's session ends.
+type ClientSession struct {
+	ClientID string
+	// SessionID is sent as the sid claim of the logout token, if not empty.
+	SessionID string
+}
+
+// CanListBackChannelLogoutSessions is an optional additional interface that may be implemented by
+// implementors of Storage to enable back-channel logout (see [Config.BackChannelLogoutSupported]).
+// BackChannelLogoutSessions is called by the end_session endpoint just before the session is terminated
+// and returns the sessions of the clients the user is logged in to.
+// A logout token is sent in the background to every client implementing [BackChannelLogoutClient]
+// after the session was terminated, and the frontchannel_logout_uri of every client
+// implementing [FrontChannelLogoutClient] is loaded by the user agent.
+type CanListBackChannelLogoutSessions interface {
+	BackChannelLogoutSessions(ctx context.Context, endSessionRequest *EndSessionRequest) ([]ClientSession, error)
+}
+
+const (
+	defaultBackChannelLogoutTimeout = 5 * time.Second
+	logoutTokenLifetime             = 2 * time.Minute
+)
+
+// backChannelLogoutProvider is implemented by the [Provider],
+// to send logout tokens when back-channel logout is supported.
+type backChannelLogoutProvider interface {
+	BackChannelLogoutSupported() bool
+	BackChannelLogoutHTTPClient() *http.Client
+}
+
+func backChannelLogoutClientFrom(v any) *http.Client {
+	if p, ok := v.(backChannelLogoutProvider); ok && p.BackChannelLogoutSupported() {
+		if client := p.BackChannelLogoutHTTPClient(); client != nil {
+			return client
+		}
+		return &http.Client{Timeout: defaultBackChannelLogoutTimeout}
+	}
+	return nil
+}
+
+// listLogoutSessions returns the sessions to be notified by back-channel and front-channel logout
+// after the termination of the session. Errors are only logged, as they must not prevent the logout of the user.
+func listLogoutSessions(ctx context.Context, session *EndSessionRequest, storage Storage) []ClientSession {
+	lister, ok := storage.(CanListBackChannelLogoutSessions)
+	if !ok {
+		return nil
+	}
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	sessions, err := lister.BackChannelLogoutSessions(storageCtx, session)
+	if err != nil {
+		slog.WarnContext(ctx, "back-channel logout: listing sessions failed", "error", err, "user_id", session.UserID)
+		return nil
+	}
+	return sessions
+}
+
+// sendBackChannelLogout creates the signed logout tokens of the sessions
+// and posts them to the backchannel_logout_uri of their clients in the background,
+// so the logout of the user is not delayed by slow or unreachable clients.
+// Each delivery is bounded by the lifetime of the logout token.
+// Failed deliveries are logged, as the spec does not allow to retry them synchronously.
+func sendBackChannelLogout(ctx context.Context, provider SessionEnder, storage Storage, userID string, sessions []ClientSession) {
+	httpClient := backChannelLogoutClientFrom(provider)
+	if httpClient == nil || len(sessions) == 0 {
+		return
+	}
+	ctx, span := Tracer.Start(ctx, "sendBackChannelLogout")
+	defer span.End()
+
+	issuer := IssuerFromContext(ctx)
+	for _, session := range sessions {
+		client, err := getClientByClientID(ctx, cacheFrom(provider), storage, session.ClientID)
+		if err != nil {
+			slog.WarnContext(ctx, "back-channel logout: client not found", "error", err, "client_id", session.ClientID)
+			continue
+		}
+		logoutClient, ok := client.(BackChannelLogoutClient)
+		if !ok || logoutClient.BackChannelLogoutURI() == "" {
+			continue
+		}
+		if logoutClient.BackChannelLogoutSessionRequired() && session.SessionID == "" {
+			continue
+		}
+		token, err := createLogoutToken(ctx, issuer, userID, session, client.ClockSkew(), storage, signerCacheFrom(provider))
+		if err != nil {
+			slog.ErrorContext(ctx, "back-channel logout: creating logout token failed", "error", err, "client_id", session.ClientID)
+			continue
+		}
+		go func(uri, clientID string) {
+			// the request of the user may finish before the delivery
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), logoutTokenLifetime)
+			defer cancel()
+			if err := postLogoutToken(ctx, httpClient, uri, token); err != nil {
+				slog.WarnContext(ctx, "back-channel logout: delivery failed", "error", err, "client_id", clientID)
+			}
+		}(logoutClient.BackChannelLogoutURI(), session.ClientID)
+	}
+}
+
+// CreateLogoutToken creates the logout token of the back-channel logout of the user
+// for the client and session, signed with the signing key of the storage.
+// It is valid for two minutes of the [Clock] of the context
+// and identified by an ID of the [TokenIDSource] of the context.
+func CreateLogoutToken(ctx context.Context, issuer, userID string, session ClientSession, skew time.Duration, storage Storage) (string, error) {
+	return createLogoutToken(ctx, issuer, userID, session, skew, storage, nil)
+}
+
+// createLogoutToken signs the logout token with the signer of the key from the cache.
+func createLogoutToken(ctx context.Context, issuer, userID string, session ClientSession, skew time.Duration, storage Storage, signers *signerCache) (string, error) {
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	key, err := storage.SigningKey(storageCtx)
+	if err != nil {
+		return "", err
+	}
+	now := Now(ctx)
+	claims := oidc.NewLogoutTokenClaims(issuer, userID, oidc.Audience{session.ClientID}, now.Add(logoutTokenLifetime), NewTokenID(ctx), session.SessionID, skew)
+	claims.IssuedAt = oidc.FromTime(now.Add(-skew))
+	return signClaims(ctx, signers, claims, key, oidc.TypeLogoutToken)
+}
+
+// postLogoutToken delivers the logout token as defined by
+// https://openid.net/specs/openid-connect-backchannel-1_0.html#BCRequest
+func postLogoutToken(ctx context.Context, client *http.Client, uri, token string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, strings.NewReader(url.Values{"logout_token": {token}}.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
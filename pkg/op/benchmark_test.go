@@ -0,0 +1,207 @@
+package op_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+)
+
+// serveTestProvider sends the request to the testProvider.
+// Form values are posted to the path, which must be relative to the issuer.
+func serveTestProvider(method, path, bearer string, form url.Values) *httptest.ResponseRecorder {
+	var req *http.Request
+	if form != nil {
+		req = httptest.NewRequest(method, testIssuer[:len(testIssuer)-1]+path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth("web", "secret")
+	} else {
+		req = httptest.NewRequest(method, testIssuer[:len(testIssuer)-1]+path, nil)
+	}
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	rec := httptest.NewRecorder()
+	testProvider.ServeHTTP(rec, req)
+	return rec
+}
+
+// newTestCode creates a done auth request of the web client
+// and saves the code for it.
+func newTestCode(tb testing.TB, code string) {
+	storage := testProvider.Storage().(routesTestStorage)
+	ctx := op.ContextWithIssuer(context.Background(), testIssuer)
+	authReq, err := storage.CreateAuthRequest(ctx, &oidc.AuthRequest{
+		ClientID:     "web",
+		RedirectURI:  "https://example.com",
+		Scopes:       oidc.SpaceDelimitedArray{oidc.ScopeOpenID, oidc.ScopeOfflineAccess, oidc.ScopeProfile},
+		ResponseType: oidc.ResponseTypeCode,
+	}, "id1")
+	require.NoError(tb, err)
+	require.NoError(tb, storage.AuthRequestDone(authReq.GetID()))
+	require.NoError(tb, storage.SaveAuthCode(ctx, authReq.GetID(), code))
+}
+
+// newTestTokens creates an access and refresh token of the web client.
+func newTestTokens(tb testing.TB, tokenType op.AccessTokenType) (accessToken, refreshToken string) {
+	storage := testProvider.Storage().(routesTestStorage)
+	ctx := op.ContextWithIssuer(context.Background(), testIssuer)
+	client, err := storage.GetClientByClientID(ctx, "web")
+	require.NoError(tb, err)
+	authReq, err := storage.CreateAuthRequest(ctx, &oidc.AuthRequest{
+		ClientID:     "web",
+		RedirectURI:  "https://example.com",
+		Scopes:       oidc.SpaceDelimitedArray{oidc.ScopeOpenID, oidc.ScopeOfflineAccess, oidc.ScopeProfile},
+		ResponseType: oidc.ResponseTypeCode,
+	}, "id1")
+	require.NoError(tb, err)
+	accessToken, refreshToken, _, err = op.CreateAccessToken(ctx, authReq, tokenType, testProvider, client, "")
+	require.NoError(tb, err)
+	return accessToken, refreshToken
+}
+
+func codeExchange(tb testing.TB, code string) {
+	rec := serveTestProvider(http.MethodPost, testProvider.TokenEndpoint().Relative(), "", url.Values{
+		"grant_type":   {string(oidc.GrantTypeCode)},
+		"code":         {code},
+		"redirect_uri": {"https://example.com"},
+	})
+	if rec.Code != http.StatusOK {
+		tb.Fatalf("code exchange: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func refreshGrant(tb testing.TB, refreshToken string) {
+	rec := serveTestProvider(http.MethodPost, testProvider.TokenEndpoint().Relative(), "", url.Values{
+		"grant_type":    {string(oidc.GrantTypeRefreshToken)},
+		"refresh_token": {refreshToken},
+	})
+	if rec.Code != http.StatusOK {
+		tb.Fatalf("refresh grant: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func userinfo(tb testing.TB, accessToken string) {
+	rec := serveTestProvider(http.MethodGet, testProvider.UserinfoEndpoint().Relative(), accessToken, nil)
+	if rec.Code != http.StatusOK {
+		tb.Fatalf("userinfo: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func discovery(tb testing.TB) {
+	rec := serveTestProvider(http.MethodGet, oidc.DiscoveryEndpoint, "", nil)
+	if rec.Code != http.StatusOK {
+		tb.Fatalf("discovery: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func verifyAccessToken(tb testing.TB, verifier *op.AccessTokenVerifier, token string) {
+	ctx := op.ContextWithIssuer(context.Background(), testIssuer)
+	if _, err := op.VerifyAccessToken[*oidc.AccessTokenClaims](ctx, token, verifier); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+func BenchmarkCodeExchange(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		code := "bench-code-" + strconv.Itoa(i)
+		newTestCode(b, code)
+		b.StartTimer()
+		codeExchange(b, code)
+	}
+}
+
+func BenchmarkRefreshGrant(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		_, refreshToken := newTestTokens(b, op.AccessTokenTypeBearer)
+		b.StartTimer()
+		refreshGrant(b, refreshToken)
+	}
+}
+
+func BenchmarkVerifyAccessToken(b *testing.B) {
+	accessToken, _ := newTestTokens(b, op.AccessTokenTypeJWT)
+	verifier := testProvider.AccessTokenVerifier(op.ContextWithIssuer(context.Background(), testIssuer))
+	b.ReportAllocs()
+	for b.Loop() {
+		verifyAccessToken(b, verifier, accessToken)
+	}
+}
+
+func BenchmarkUserinfo(b *testing.B) {
+	accessToken, _ := newTestTokens(b, op.AccessTokenTypeBearer)
+	b.ReportAllocs()
+	for b.Loop() {
+		userinfo(b, accessToken)
+	}
+}
+
+func BenchmarkDiscovery(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		discovery(b)
+	}
+}
+
+// allocationBudgets are the maximum allocations of the hot paths,
+// with some headroom over the measured allocations.
+// Raising a budget must be justified by the change which needs it.
+// The token grants include the allocations of the go-jose signer.
+var allocationBudgets = map[string]float64{
+	"code exchange":       400,
+	"refresh grant":       380,
+	"verify access token": 200,
+	"userinfo":            220,
+	"discovery":           70,
+}
+
+func TestAllocationBudgets(t *testing.T) {
+	if testing.Short() {
+		t.Skip("allocation budgets are not checked in short mode")
+	}
+	jwtAccessToken, _ := newTestTokens(t, op.AccessTokenTypeJWT)
+	accessToken, _ := newTestTokens(t, op.AccessTokenTypeBearer)
+	verifier := testProvider.AccessTokenVerifier(op.ContextWithIssuer(context.Background(), testIssuer))
+
+	// The tokens and codes are prepared in advance,
+	// as they can only be used once.
+	const runs = 20
+	var codes, refreshTokens []string
+	for i := range runs + 1 {
+		code := "budget-code-" + strconv.Itoa(i)
+		newTestCode(t, code)
+		codes = append(codes, code)
+		_, refreshToken := newTestTokens(t, op.AccessTokenTypeBearer)
+		refreshTokens = append(refreshTokens, refreshToken)
+	}
+	next := func(s *[]string) string {
+		v := (*s)[0]
+		*s = (*s)[1:]
+		return v
+	}
+
+	tests := map[string]func(){
+		"code exchange":       func() { codeExchange(t, next(&codes)) },
+		"refresh grant":       func() { refreshGrant(t, next(&refreshTokens)) },
+		"verify access token": func() { verifyAccessToken(t, verifier, jwtAccessToken) },
+		"userinfo":            func() { userinfo(t, accessToken) },
+		"discovery":           func() { discovery(t) },
+	}
+	for name, f := range tests {
+		allocs := testing.AllocsPerRun(runs, f)
+		t.Logf("%s: %.0f allocations", name, allocs)
+		assert.LessOrEqual(t, allocs, allocationBudgets[name], name)
+	}
+}
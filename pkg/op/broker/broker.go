@@ -0,0 +1,281 @@
+// Package broker delegates the authentication of the OpenID Provider to upstream
+// OpenID Providers, such as Google or Azure AD ("Login with ...").
+//
+// Each [Upstream] is a relying party of the rp package and is added to the login flow
+// of the op/login package as an authenticator, see [Broker.Authenticators].
+// After the code exchange with the upstream provider, the external identity is linked
+// to a local user by the [Storage]. The claims mapped from the upstream identity are added
+// to the local tokens by [Broker.IDTokenClaimsHook] and [Broker.AccessTokenClaimsHook].
+package broker
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/login"
+)
+
+// Storage links external identities to local users.
+type Storage interface {
+	// LinkExternalIdentity returns the id of the local user linked to the identity.
+	// Implementations may link the identity to an existing user, e.g. by a verified email,
+	// or provision a new user. Mapped claims of the identity can be stored
+	// with the user, to be returned in the local tokens.
+	// An error aborts the login.
+	LinkExternalIdentity(ctx context.Context, identity *ExternalIdentity) (userID string, err error)
+}
+
+// ClaimsStorage is an optional interface of the [Storage], returning the claims
+// mapped by [Upstream.MapClaims], which were stored with the user by [Storage.LinkExternalIdentity].
+// The claims are added to the local tokens by [Broker.IDTokenClaimsHook] and [Broker.AccessTokenClaimsHook].
+type ClaimsStorage interface {
+	ExternalClaims(ctx context.Context, userID string) (map[string]any, error)
+}
+
+// ExternalIdentity is the authenticated user of an upstream provider.
+type ExternalIdentity struct {
+	// Upstream is the name of the [Upstream].
+	Upstream string
+	// Subject is the sub claim of the upstream provider.
+	Subject       string
+	IDTokenClaims *oidc.IDTokenClaims
+	// UserInfo is only set if [Upstream.FetchUserInfo] is enabled.
+	UserInfo *oidc.UserInfo
+	// Claims are the local claims mapped by [Upstream.MapClaims].
+	Claims map[string]any
+}
+
+// Upstream is an upstream OpenID Provider.
+type Upstream struct {
+	// Name identifies the upstream and is used as amr value of the login,
+	// e.g. "google".
+	Name string
+	// RelyingParty must use the callback URL of the [Broker] as redirect URI.
+	RelyingParty rp.RelyingParty
+	// FetchUserInfo requests the userinfo of the upstream provider after the code exchange.
+	FetchUserInfo bool
+	// MapClaims optionally maps the upstream claims to local claims.
+	MapClaims func(identity *ExternalIdentity) map[string]any
+	// MapACR optionally maps the upstream acr to the local acr of the login.
+	// Without it, the acr of the upstream provider is not used.
+	// Note that the ACR of the login.Config takes precedence, if set.
+	MapACR func(acr string) string
+}
+
+// Broker handles the login with [Upstream] providers.
+type Broker struct {
+	storage   Storage
+	key       []byte
+	loginURL  *url.URL
+	upstreams []*Upstream
+}
+
+// New creates a [Broker].
+// The key is used to derive the PKCE code verifier and the nonce from the state and must be kept secret.
+// The loginURL is the URL of the login.Flow, where the user returns to after the upstream login.
+//
+// The nonce check of the ID token verifier of the relying parties is replaced,
+// so the relying parties must not be used outside of the broker.
+func New(storage Storage, key []byte, loginURL string, upstreams ...*Upstream) (*Broker, error) {
+	if len(key) < 32 {
+		return nil, errors.New("broker: key must be at least 32 bytes")
+	}
+	parsedLoginURL, err := url.Parse(loginURL)
+	if err != nil {
+		return nil, fmt.Errorf("broker: invalid login URL: %w", err)
+	}
+	b := &Broker{
+		storage:  storage,
+		key:      key,
+		loginURL: parsedLoginURL,
+	}
+	for _, upstream := range upstreams {
+		if upstream.Name == "" || strings.Contains(upstream.Name, stateSeparator) || upstream.RelyingParty == nil {
+			return nil, errors.New("broker: upstream requires a valid name and relying party")
+		}
+		if b.upstream(upstream.Name) != nil {
+			return nil, fmt.Errorf("broker: duplicate upstream %s", upstream.Name)
+		}
+		if verifier := upstream.RelyingParty.IDTokenVerifier(); verifier != nil {
+			verifier.Nonce = nonceFromContext
+		}
+		b.upstreams = append(b.upstreams, upstream)
+	}
+	return b, nil
+}
+
+func (b *Broker) upstream(name string) *Upstream {
+	for _, upstream := range b.upstreams {
+		if upstream.Name == name {
+			return upstream
+		}
+	}
+	return nil
+}
+
+// Authenticators returns a login.Authenticator for each [Upstream],
+// to be added to the login.Config.
+func (b *Broker) Authenticators() []login.Authenticator {
+	authenticators := make([]login.Authenticator, 0, len(b.upstreams))
+	for _, upstream := range b.upstreams {
+		authenticators = append(authenticators, login.NewExternalIdPAuthenticator(upstream.Name, &idp{broker: b, upstream: upstream}))
+	}
+	return authenticators
+}
+
+// the state sent to the upstream carries the upstream name and auth request id,
+// so the callback can return to the login flow.
+const stateSeparator = "~"
+
+// ServeHTTP handles the callback of the upstream providers
+// and redirects the user back to the login flow.
+// It must be registered at the redirect URI of the relying parties.
+func (b *Broker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(r.FormValue("state"), stateSeparator, 3)
+	if len(parts) != 3 {
+		http.Error(w, "invalid state", http.StatusBadRequest)
+		return
+	}
+	name, authRequestID, state := parts[0], parts[1], parts[2]
+	if b.upstream(name) == nil {
+		http.Error(w, "unknown upstream", http.StatusBadRequest)
+		return
+	}
+	redirect := *b.loginURL
+	query := redirect.Query()
+	query.Set(login.FormAuthRequestID, authRequestID)
+	query.Set(login.FormMethod, name)
+	query.Set("state", state)
+	for _, param := range []string{"code", "error", "error_description"} {
+		if value := r.FormValue(param); value != "" {
+			query.Set(param, value)
+		}
+	}
+	redirect.RawQuery = query.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// codeVerifier derives the PKCE code verifier from the upstream state,
+// so it doesn't need to be stored.
+func (b *Broker) codeVerifier(upstreamState string) string {
+	return b.derive("code_verifier", upstreamState)
+}
+
+// nonce derives the nonce of the upstream auth request from the upstream state.
+func (b *Broker) nonce(upstreamState string) string {
+	return b.derive("nonce", upstreamState)
+}
+
+func (b *Broker) derive(label, upstreamState string) string {
+	mac := hmac.New(sha256.New, b.key)
+	mac.Write([]byte(label + stateSeparator + upstreamState))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+type nonceKey struct{}
+
+// nonceFromContext returns the nonce expected by the ID token verifier of the upstreams.
+func nonceFromContext(ctx context.Context) string {
+	nonce, _ := ctx.Value(nonceKey{}).(string)
+	return nonce
+}
+
+// IDTokenClaimsHook adds the claims of the [ClaimsStorage] to the ID tokens of the user,
+// to be registered with op.WithIDTokenClaimsHook.
+// Claims already set on the token are not overwritten.
+func (b *Broker) IDTokenClaimsHook() op.IDTokenClaimsHook {
+	return func(ctx context.Context, claims *oidc.IDTokenClaims, hc *op.ClaimsHookContext) error {
+		return b.appendClaims(ctx, hc.Subject, &claims.Claims)
+	}
+}
+
+// AccessTokenClaimsHook adds the claims of the [ClaimsStorage] to the JWT access tokens of the user,
+// to be registered with op.WithAccessTokenClaimsHook.
+// Claims already set on the token are not overwritten.
+func (b *Broker) AccessTokenClaimsHook() op.AccessTokenClaimsHook {
+	return func(ctx context.Context, claims *oidc.AccessTokenClaims, hc *op.ClaimsHookContext) error {
+		return b.appendClaims(ctx, hc.Subject, &claims.Claims)
+	}
+}
+
+func (b *Broker) appendClaims(ctx context.Context, userID string, claims *map[string]any) error {
+	storage, ok := b.storage.(ClaimsStorage)
+	if !ok {
+		return nil
+	}
+	external, err := storage.ExternalClaims(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for k, v := range external {
+		if *claims == nil {
+			*claims = make(map[string]any, len(external))
+		}
+		if _, ok := (*claims)[k]; !ok {
+			(*claims)[k] = v
+		}
+	}
+	return nil
+}
+
+type idp struct {
+	broker   *Broker
+	upstream *Upstream
+}
+
+func (i *idp) upstreamState(authRequestID, state string) string {
+	return strings.Join([]string{i.upstream.Name, authRequestID, state}, stateSeparator)
+}
+
+func (i *idp) AuthURL(_ context.Context, authRequestID, state string) (string, error) {
+	upstreamState := i.upstreamState(authRequestID, state)
+	challenge := oidc.NewSHACodeChallenge(i.broker.codeVerifier(upstreamState))
+	return rp.AuthURL(upstreamState, i.upstream.RelyingParty, rp.WithCodeChallenge(challenge),
+		rp.AuthURLOpt(rp.WithURLParam("nonce", i.broker.nonce(upstreamState)))), nil
+}
+
+func (i *idp) Callback(ctx context.Context, r *http.Request, state *login.State) (string, error) {
+	if upstreamErr := r.FormValue("error"); upstreamErr != "" {
+		return "", fmt.Errorf("%s: %s %s", i.upstream.Name, upstreamErr, r.FormValue("error_description"))
+	}
+	// the state was already verified by the login flow
+	upstreamState := i.upstreamState(state.AuthRequestID, r.FormValue("state"))
+	ctx = context.WithValue(ctx, nonceKey{}, i.broker.nonce(upstreamState))
+	tokens, err := rp.CodeExchange[*oidc.IDTokenClaims](ctx, r.FormValue("code"), i.upstream.RelyingParty,
+		rp.WithCodeVerifier(i.broker.codeVerifier(upstreamState)))
+	if err != nil {
+		return "", fmt.Errorf("%s: code exchange: %w", i.upstream.Name, err)
+	}
+	identity := &ExternalIdentity{
+		Upstream:      i.upstream.Name,
+		Subject:       tokens.IDTokenClaims.GetSubject(),
+		IDTokenClaims: tokens.IDTokenClaims,
+	}
+	if i.upstream.FetchUserInfo {
+		identity.UserInfo, err = rp.Userinfo[*oidc.UserInfo](ctx, tokens.AccessToken, tokens.TokenType, identity.Subject, i.upstream.RelyingParty)
+		if err != nil {
+			return "", fmt.Errorf("%s: userinfo: %w", i.upstream.Name, err)
+		}
+	}
+	if i.upstream.MapClaims != nil {
+		identity.Claims = i.upstream.MapClaims(identity)
+	}
+	userID, err := i.broker.storage.LinkExternalIdentity(ctx, identity)
+	if err != nil {
+		return "", err
+	}
+	if i.upstream.MapACR != nil {
+		state.ACR = i.upstream.MapACR(tokens.IDTokenClaims.AuthenticationContextClassReference)
+	}
+	return userID, nil
+}
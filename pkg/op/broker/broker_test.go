@@ -0,0 +1,159 @@
+package broker_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/example/server/exampleop"
+	"github.com/zitadel/oidc/v3/example/server/storage"
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/broker"
+	"github.com/zitadel/oidc/v3/pkg/op/login"
+)
+
+type testStorage struct {
+	states    map[string]*login.State
+	completed *login.State
+	identity  *broker.ExternalIdentity
+}
+
+func (s *testStorage) LoginState(_ context.Context, id string) (*login.State, error) {
+	if state, ok := s.states[id]; ok {
+		return state, nil
+	}
+	return &login.State{AuthRequestID: id}, nil
+}
+
+func (s *testStorage) SaveLoginState(_ context.Context, state *login.State) error {
+	s.states[state.AuthRequestID] = state
+	return nil
+}
+
+func (s *testStorage) CompleteLogin(_ context.Context, state *login.State) error {
+	s.completed = state
+	return nil
+}
+
+func (s *testStorage) LinkExternalIdentity(_ context.Context, identity *broker.ExternalIdentity) (string, error) {
+	s.identity = identity
+	return "local-" + identity.Subject, nil
+}
+
+func (s *testStorage) ExternalClaims(_ context.Context, userID string) (map[string]any, error) {
+	if s.identity == nil || userID != "local-"+s.identity.Subject {
+		return nil, nil
+	}
+	return s.identity.Claims, nil
+}
+
+func TestBroker(t *testing.T) {
+	ctx := context.Background()
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	// upstream OpenID Provider
+	upstream := httptest.NewUnstartedServer(nil)
+	upstream.Start()
+	defer upstream.Close()
+	upstream.Config.Handler = exampleop.SetupServer(upstream.URL, storage.NewStorage(storage.NewUserStore("http://local-site")), slog.Default(), false)
+
+	// local OpenID Provider login
+	local := httptest.NewServer(nil)
+	defer local.Close()
+	storage.RegisterClients(storage.WebClient("broker", "secret", local.URL+"/callback"))
+	relyingParty, err := rp.NewRelyingPartyOIDC(ctx, upstream.URL, "broker", "secret", local.URL+"/callback", []string{oidc.ScopeOpenID, oidc.ScopeEmail})
+	require.NoError(t, err)
+
+	s := &testStorage{states: make(map[string]*login.State)}
+	b, err := broker.New(s, []byte(strings.Repeat("k", 32)), local.URL+"/login?tenant=t1", &broker.Upstream{
+		Name:          "upstream",
+		RelyingParty:  relyingParty,
+		FetchUserInfo: true,
+		MapClaims: func(identity *broker.ExternalIdentity) map[string]any {
+			return map[string]any{"upstream_email": identity.UserInfo.Email}
+		},
+		MapACR: func(string) string { return "federated" },
+	})
+	require.NoError(t, err)
+	flow, err := login.New(login.Config{
+		Storage:        s,
+		Authenticators: b.Authenticators(),
+		Callback:       func(_ context.Context, id string) string { return "/authorize/callback?id=" + id },
+	})
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	mux.Handle("/login", flow)
+	mux.Handle("/callback", b)
+	local.Config.Handler = mux
+
+	get := func(uri string) *url.URL {
+		t.Helper()
+		resp, err := noRedirect.Get(uri)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusFound, resp.StatusCode, uri)
+		location, err := resp.Location()
+		require.NoError(t, err)
+		return location
+	}
+
+	upstreamAuth := get(local.URL + "/login?authRequestID=req1")
+	assert.Equal(t, "S256", upstreamAuth.Query().Get("code_challenge_method"))
+	assert.NotEmpty(t, upstreamAuth.Query().Get("nonce"))
+	upstreamLogin := get(upstreamAuth.String())
+
+	resp, err := noRedirect.Get(upstreamLogin.String())
+	require.NoError(t, err)
+	page, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	csrf := regexp.MustCompile(`name="csrf" value="([^"]+)"`).FindSubmatch(page)
+	require.Len(t, csrf, 2, "csrf token of the login form")
+
+	resp, err = noRedirect.PostForm(upstreamLogin.String(), url.Values{
+		"authRequestID": {upstreamLogin.Query().Get("authRequestID")},
+		"csrf":          {string(csrf[1])},
+		"username":      {"test-user@local-site"},
+		"password":      {"verysecure"},
+	})
+	require.NoError(t, err)
+	resp.Body.Close()
+	upstreamCallback, err := resp.Location()
+	require.NoError(t, err)
+
+	brokerCallback := get(upstreamCallback.String())
+	loginCallback := get(brokerCallback.String())
+	assert.Equal(t, "t1", loginCallback.Query().Get("tenant"), "query of the login URL is kept")
+	assert.Equal(t, "upstream", loginCallback.Query().Get(login.FormMethod))
+	final := get(loginCallback.String())
+
+	assert.Equal(t, "/authorize/callback", final.Path)
+	require.NotNil(t, s.completed)
+	assert.Equal(t, "local-id1", s.completed.UserID)
+	assert.Equal(t, []string{"upstream"}, s.completed.AMR)
+	assert.Equal(t, "federated", s.completed.ACR)
+	require.NotNil(t, s.identity)
+	assert.Equal(t, "upstream", s.identity.Upstream)
+	assert.Equal(t, "id1", s.identity.Subject)
+	assert.Equal(t, map[string]any{"upstream_email": "test-user@zitadel.ch"}, s.identity.Claims)
+
+	idTokenClaims := &oidc.IDTokenClaims{Claims: map[string]any{"upstream_email": "kept"}}
+	require.NoError(t, b.IDTokenClaimsHook()(ctx, idTokenClaims, &op.ClaimsHookContext{Subject: "local-id1"}))
+	assert.Equal(t, "kept", idTokenClaims.Claims["upstream_email"], "claims of the token are not overwritten")
+	accessTokenClaims := new(oidc.AccessTokenClaims)
+	require.NoError(t, b.AccessTokenClaimsHook()(ctx, accessTokenClaims, &op.ClaimsHookContext{Subject: "local-id1"}))
+	assert.Equal(t, map[string]any{"upstream_email": "test-user@zitadel.ch"}, accessTokenClaims.Claims)
+}
@@ -0,0 +1,310 @@
+package op
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// Cache stores the results of hot reads of the OP, such as client lookups,
+// the discovery document and introspection responses, to save calls to the Storage.
+// See [WithCache].
+//
+// Deployments with multiple instances of the OP can use a cache shared between
+// the instances, for example backed by Redis.
+// Errors of the cache are logged and the value is read from the Storage instead.
+type Cache interface {
+	// Get returns the value of the key, or ok false if the key is unknown or expired.
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	// Set stores the value of the key for the ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the key.
+	Delete(ctx context.Context, key string) error
+}
+
+// ValueCache is an optional interface of in-process caches,
+// which store values without encoding them.
+// Clients are only cached by a ValueCache, or by any Cache if a [ClientCodec] is set.
+type ValueCache interface {
+	Cache
+	GetValue(ctx context.Context, key string) (value any, ok bool, err error)
+	SetValue(ctx context.Context, key string, value any, ttl time.Duration) error
+}
+
+// ClientCodec encodes clients, so they can be stored in a [Cache].
+type ClientCodec interface {
+	MarshalClient(client Client) ([]byte, error)
+	UnmarshalClient(data []byte) (Client, error)
+}
+
+// CacheConfig sets how long the entries are cached.
+// A zero duration disables caching of the entry.
+type CacheConfig struct {
+	// Client is the duration of client lookups ([OPStorage.GetClientByClientID]).
+	// Storage implementations should call [Provider.InvalidateClient]
+	// when a client changes.
+	Client time.Duration
+	// Discovery is the duration of the discovery document.
+	Discovery time.Duration
+	// Introspection is the duration of active introspection responses,
+	// during which revoked tokens are still returned as active.
+	Introspection time.Duration
+	// ClientCodec encodes clients for caches which do not implement [ValueCache].
+	ClientCodec ClientCodec
+}
+
+// DefaultCacheConfig caches clients and the discovery document for one minute.
+// Introspection responses are not cached.
+var DefaultCacheConfig = CacheConfig{
+	Client:    time.Minute,
+	Discovery: time.Minute,
+}
+
+// Keys of the entries in the [Cache].
+const (
+	cacheKeyClient        = "oidc:client:"
+	cacheKeyClientKeys    = "oidc:client_keys:"
+	cacheKeyDiscovery     = "oidc:discovery:"
+	cacheKeyIntrospection = "oidc:introspection:"
+)
+
+// clientKeysCacheKey is the key of the keys of the client of the issuer of the context,
+// as the same client ID may be used by multiple issuers sharing a cache.
+func clientKeysCacheKey(ctx context.Context, clientID string) string {
+	return cacheKeyClientKeys + IssuerFromContext(ctx) + ":" + clientID
+}
+
+// providerCache reads and writes the entries of the [Cache] set by [WithCache].
+// A nil providerCache does not cache.
+type providerCache struct {
+	cache  Cache
+	config CacheConfig
+}
+
+// cacheProvider is implemented by the [Provider]
+// to pass its cache to the handlers.
+type cacheProvider interface {
+	providerCache() *providerCache
+}
+
+func cacheFrom(v any) *providerCache {
+	if p, ok := v.(cacheProvider); ok {
+		return p.providerCache()
+	}
+	return nil
+}
+
+type clientGetter interface {
+	GetClientByClientID(ctx context.Context, clientID string) (Client, error)
+}
+
+// getClientByClientID returns the cached client,
+// or loads it from the storage and caches it.
+func getClientByClientID(ctx context.Context, c *providerCache, storage clientGetter, clientID string) (Client, error) {
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	if c == nil || c.config.Client <= 0 {
+		return storage.GetClientByClientID(storageCtx, clientID)
+	}
+	key := cacheKeyClient + IssuerFromContext(ctx) + ":" + clientID
+	values, isValueCache := c.cache.(ValueCache)
+	switch {
+	case isValueCache:
+		value, ok, err := values.GetValue(ctx, key)
+		logCacheError(ctx, err, key)
+		if client, isClient := value.(Client); ok && isClient {
+			return client, nil
+		}
+	case c.config.ClientCodec != nil:
+		if data, ok := c.get(ctx, key); ok {
+			client, err := c.config.ClientCodec.UnmarshalClient(data)
+			logCacheError(ctx, err, key)
+			if err == nil {
+				return client, nil
+			}
+		}
+	default:
+		return storage.GetClientByClientID(storageCtx, clientID)
+	}
+
+	client, err := storage.GetClientByClientID(storageCtx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if isValueCache {
+		logCacheError(ctx, values.SetValue(ctx, key, client, c.config.Client), key)
+		return client, nil
+	}
+	data, err := c.config.ClientCodec.MarshalClient(client)
+	if err == nil {
+		err = c.cache.Set(ctx, key, data, c.config.Client)
+	}
+	logCacheError(ctx, err, key)
+	return client, nil
+}
+
+// invalidateClient removes the cached client and its keys.
+func (c *providerCache) invalidateClient(ctx context.Context, clientID string) error {
+	if c == nil {
+		return nil
+	}
+	if err := c.cache.Delete(ctx, cacheKeyClient+IssuerFromContext(ctx)+":"+clientID); err != nil {
+		return err
+	}
+	return c.cache.Delete(ctx, clientKeysCacheKey(ctx, clientID))
+}
+
+// discovery returns the cached discovery document of the issuer,
+// or creates and caches it.
+func (c *providerCache) discovery(ctx context.Context, create func() *oidc.DiscoveryConfiguration) any {
+	if c == nil || c.config.Discovery <= 0 {
+		return create()
+	}
+	key := cacheKeyDiscovery + IssuerFromContext(ctx)
+	if data, ok := c.get(ctx, key); ok {
+		return json.RawMessage(data)
+	}
+	config := create()
+	data, err := json.Marshal(config)
+	if err == nil {
+		err = c.cache.Set(ctx, key, data, c.config.Discovery)
+	}
+	logCacheError(ctx, err, key)
+	return config
+}
+
+// introspectionKey identifies the token by its hash,
+// so tokens are not stored in the cache.
+func introspectionKey(token, clientID string) string {
+	hash := sha256.Sum256([]byte(token))
+	return cacheKeyIntrospection + clientID + ":" + base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// introspection returns the cached response of the token, or nil.
+func (c *providerCache) introspection(ctx context.Context, token, clientID string) *oidc.IntrospectionResponse {
+	if c == nil || c.config.Introspection <= 0 {
+		return nil
+	}
+	key := introspectionKey(token, clientID)
+	data, ok := c.get(ctx, key)
+	if !ok {
+		return nil
+	}
+	response := new(oidc.IntrospectionResponse)
+	if err := json.Unmarshal(data, response); err != nil {
+		logCacheError(ctx, err, key)
+		return nil
+	}
+	return response
+}
+
+// setIntrospection caches an active response,
+// at most until the token expires.
+func (c *providerCache) setIntrospection(ctx context.Context, token, clientID string, response *oidc.IntrospectionResponse) {
+	if c == nil || c.config.Introspection <= 0 || !response.Active {
+		return
+	}
+	ttl := c.config.Introspection
+	if exp := response.Expiration.AsTime(); !exp.IsZero() {
+		ttl = min(ttl, time.Until(exp))
+	}
+	if ttl <= 0 {
+		return
+	}
+	key := introspectionKey(token, clientID)
+	data, err := json.Marshal(response)
+	if err == nil {
+		err = c.cache.Set(ctx, key, data, ttl)
+	}
+	logCacheError(ctx, err, key)
+}
+
+func (c *providerCache) get(ctx context.Context, key string) ([]byte, bool) {
+	data, ok, err := c.cache.Get(ctx, key)
+	logCacheError(ctx, err, key)
+	return data, ok && err == nil
+}
+
+func logCacheError(ctx context.Context, err error, key string) {
+	if err != nil {
+		slog.WarnContext(ctx, "op cache", "error", err, "key", key)
+	}
+}
+
+// MemoryCache is an in-memory [Cache] and [ValueCache],
+// suitable for deployments with a single instance of the OP.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryCacheEntry
+	now     func() time.Time
+	swept   time.Time
+}
+
+type memoryCacheEntry struct {
+	value   any
+	expires time.Time
+}
+
+// NewMemoryCache creates an empty [MemoryCache].
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		entries: make(map[string]memoryCacheEntry),
+		now:     time.Now,
+	}
+}
+
+// Get implements [Cache].
+func (c *MemoryCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, ok, _ := c.GetValue(ctx, key)
+	data, isBytes := value.([]byte)
+	return data, ok && isBytes, nil
+}
+
+// Set implements [Cache].
+func (c *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.SetValue(ctx, key, value, ttl)
+}
+
+// Delete implements [Cache].
+func (c *MemoryCache) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+	return nil
+}
+
+// GetValue implements [ValueCache].
+func (c *MemoryCache) GetValue(_ context.Context, key string) (any, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || !c.now().Before(entry.expires) {
+		return nil, false, nil
+	}
+	return entry.value, true, nil
+}
+
+// SetValue implements [ValueCache].
+// Expired entries are removed at most once per minute.
+func (c *MemoryCache) SetValue(_ context.Context, key string, value any, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if now.Sub(c.swept) > time.Minute {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.swept = now
+	}
+	c.entries[key] = memoryCacheEntry{value: value, expires: now.Add(ttl)}
+	return nil
+}
@@ -0,0 +1,197 @@
+package op
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tu "github.com/zitadel/oidc/v3/internal/testutil"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+	cache := NewMemoryCache()
+	cache.now = func() time.Time { return now }
+
+	require.NoError(t, cache.Set(ctx, "a", []byte("value"), time.Minute))
+	got, ok, err := cache.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("value"), got)
+
+	require.NoError(t, cache.SetValue(ctx, "b", 42, time.Minute))
+	_, ok, _ = cache.Get(ctx, "b")
+	assert.False(t, ok, "value is not bytes")
+	value, ok, _ := cache.GetValue(ctx, "b")
+	assert.True(t, ok)
+	assert.Equal(t, 42, value)
+
+	require.NoError(t, cache.Delete(ctx, "b"))
+	_, ok, _ = cache.GetValue(ctx, "b")
+	assert.False(t, ok, "deleted")
+
+	now = now.Add(time.Minute)
+	_, ok, _ = cache.Get(ctx, "a")
+	assert.False(t, ok, "expired")
+
+	now = now.Add(time.Minute)
+	require.NoError(t, cache.Set(ctx, "c", nil, time.Minute))
+	assert.Len(t, cache.entries, 1, "expired entries removed")
+}
+
+type countingClientStorage struct {
+	calls atomic.Int32
+}
+
+type cacheTestClient struct {
+	Client
+	id string
+}
+
+func (c cacheTestClient) GetID() string { return c.id }
+
+func (s *countingClientStorage) GetClientByClientID(_ context.Context, clientID string) (Client, error) {
+	s.calls.Add(1)
+	return cacheTestClient{id: clientID}, nil
+}
+
+// bytesCache hides the ValueCache methods of the MemoryCache.
+type bytesCache struct {
+	Cache
+}
+
+type cacheTestCodec struct{}
+
+func (cacheTestCodec) MarshalClient(client Client) ([]byte, error) {
+	return []byte(client.GetID()), nil
+}
+
+func (cacheTestCodec) UnmarshalClient(data []byte) (Client, error) {
+	return cacheTestClient{id: string(data)}, nil
+}
+
+func Test_getClientByClientID(t *testing.T) {
+	ctx := ContextWithIssuer(context.Background(), "https://issuer.example.com")
+	tests := []struct {
+		name      string
+		cache     *providerCache
+		wantCalls int32
+	}{
+		{
+			name:      "no cache",
+			wantCalls: 3,
+		},
+		{
+			name:      "value cache",
+			cache:     &providerCache{cache: NewMemoryCache(), config: DefaultCacheConfig},
+			wantCalls: 1,
+		},
+		{
+			name:      "bytes cache without codec",
+			cache:     &providerCache{cache: bytesCache{NewMemoryCache()}, config: DefaultCacheConfig},
+			wantCalls: 3,
+		},
+		{
+			name:      "bytes cache with codec",
+			cache:     &providerCache{cache: bytesCache{NewMemoryCache()}, config: CacheConfig{Client: time.Minute, ClientCodec: cacheTestCodec{}}},
+			wantCalls: 1,
+		},
+		{
+			name:      "disabled",
+			cache:     &providerCache{cache: NewMemoryCache()},
+			wantCalls: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := new(countingClientStorage)
+			for range 3 {
+				client, err := getClientByClientID(ctx, tt.cache, storage, "client1")
+				require.NoError(t, err)
+				assert.Equal(t, "client1", client.GetID())
+			}
+			assert.Equal(t, tt.wantCalls, storage.calls.Load())
+		})
+	}
+
+	t.Run("invalidate", func(t *testing.T) {
+		storage := new(countingClientStorage)
+		cache := &providerCache{cache: NewMemoryCache(), config: DefaultCacheConfig}
+		_, err := getClientByClientID(ctx, cache, storage, "client1")
+		require.NoError(t, err)
+		require.NoError(t, cache.invalidateClient(ctx, "client1"))
+		_, err = getClientByClientID(ctx, cache, storage, "client1")
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), storage.calls.Load())
+	})
+}
+
+func Test_providerCache_discovery(t *testing.T) {
+	ctx := ContextWithIssuer(context.Background(), "https://issuer.example.com")
+	cache := &providerCache{cache: NewMemoryCache(), config: DefaultCacheConfig}
+	var calls int
+	create := func() *oidc.DiscoveryConfiguration {
+		calls++
+		return &oidc.DiscoveryConfiguration{Issuer: IssuerFromContext(ctx)}
+	}
+
+	first, err := json.Marshal(cache.discovery(ctx, create))
+	require.NoError(t, err)
+	second, err := json.Marshal(cache.discovery(ctx, create))
+	require.NoError(t, err)
+	assert.JSONEq(t, string(first), string(second))
+	assert.Equal(t, 1, calls)
+
+	other := ContextWithIssuer(context.Background(), "https://other.example.com")
+	cache.discovery(other, create)
+	assert.Equal(t, 2, calls, "cached per issuer")
+}
+
+func Test_providerCache_introspection(t *testing.T) {
+	ctx := context.Background()
+	cache := &providerCache{cache: NewMemoryCache(), config: CacheConfig{Introspection: time.Minute}}
+
+	cache.setIntrospection(ctx, "inactive", "client", &oidc.IntrospectionResponse{})
+	assert.Nil(t, cache.introspection(ctx, "inactive", "client"), "inactive not cached")
+
+	expired := &oidc.IntrospectionResponse{Active: true, Expiration: oidc.FromTime(time.Now().Add(-time.Second))}
+	cache.setIntrospection(ctx, "expired", "client", expired)
+	assert.Nil(t, cache.introspection(ctx, "expired", "client"), "expired not cached")
+
+	active := &oidc.IntrospectionResponse{Active: true, ClientID: "client", Expiration: oidc.FromTime(time.Now().Add(time.Hour))}
+	cache.setIntrospection(ctx, "token", "client", active)
+	got := cache.introspection(ctx, "token", "client")
+	require.NotNil(t, got)
+	assert.True(t, got.Active)
+	assert.Nil(t, cache.introspection(ctx, "token", "other"), "cached per client")
+}
+
+func TestClientKeySetCache_shared(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{tu.WebKey.Public()}})
+	}))
+	defer server.Close()
+
+	shared := NewMemoryCache()
+	for range 2 {
+		// each instance of the OP has its own ClientKeySetCache
+		c := NewClientKeySetCache(WithClientKeySetSharedCache(shared))
+		keys, _, err := c.keys(context.Background(), "client1", server.URL, false)
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		assert.Equal(t, tu.WebKey.KeyID, keys[0].KeyID)
+	}
+	assert.Equal(t, int32(1), fetches.Load())
+}
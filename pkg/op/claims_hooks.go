@@ -0,0 +1,67 @@
+package op
+
+import (
+	"context"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// ClaimsHookContext describes the token passed to an [IDTokenClaimsHook]
+// or [AccessTokenClaimsHook].
+type ClaimsHookContext struct {
+	// Client the token is issued to, which can be asserted to a [Client].
+	Client AccessTokenClient
+	// Request of the token, such as an [AuthRequest], [RefreshTokenRequest],
+	// [TokenExchangeRequest] or [*DeviceAuthorizationState].
+	Request TokenRequest
+	// AuthRequest is set if the Request is an [AuthRequest].
+	AuthRequest AuthRequest
+	// Scopes granted by the request.
+	Scopes []string
+	// Subject is the user (or client for client credentials) the token is issued for.
+	Subject string
+}
+
+func newClaimsHookContext(client AccessTokenClient, request TokenRequest) *ClaimsHookContext {
+	hc := &ClaimsHookContext{
+		Client:  client,
+		Request: request,
+		Scopes:  request.GetScopes(),
+		Subject: request.GetSubject(),
+	}
+	hc.AuthRequest, _ = request.(AuthRequest)
+	return hc
+}
+
+// IDTokenClaimsHook is called with the claims of an ID token just before it is signed.
+// It may modify the claims, e.g. add custom claims to [oidc.IDTokenClaims.Claims].
+// A returned error fails the token request.
+type IDTokenClaimsHook func(ctx context.Context, claims *oidc.IDTokenClaims, hc *ClaimsHookContext) error
+
+// AccessTokenClaimsHook is called with the claims of a JWT access token just before it is signed.
+// It may modify the claims, e.g. add custom claims to [oidc.AccessTokenClaims.Claims].
+// A returned error fails the token request.
+// Opaque access tokens have no claims and don't call the hook.
+type AccessTokenClaimsHook func(ctx context.Context, claims *oidc.AccessTokenClaims, hc *ClaimsHookContext) error
+
+// claimsHooksProvider is implemented by the [Provider]
+// to pass the hooks of [WithIDTokenClaimsHook] and [WithAccessTokenClaimsHook]
+// to the token creation.
+type claimsHooksProvider interface {
+	IDTokenClaimsHooks() []IDTokenClaimsHook
+	AccessTokenClaimsHooks() []AccessTokenClaimsHook
+}
+
+func idTokenClaimsHooksFrom(v any) []IDTokenClaimsHook {
+	if p, ok := v.(claimsHooksProvider); ok {
+		return p.IDTokenClaimsHooks()
+	}
+	return nil
+}
+
+func accessTokenClaimsHooksFrom(v any) []AccessTokenClaimsHook {
+	if p, ok := v.(claimsHooksProvider); ok {
+		return p.AccessTokenClaimsHooks()
+	}
+	return nil
+}
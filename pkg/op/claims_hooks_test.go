@@ -0,0 +1,89 @@
+package op_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/example/server/storage"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+)
+
+func jwtPayload(t *testing.T, token string) map[string]any {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var payload map[string]any
+	require.NoError(t, json.Unmarshal(data, &payload))
+	return payload
+}
+
+func TestClaimsHooks(t *testing.T) {
+	var gotIDToken, gotAccessToken *op.ClaimsHookContext
+	s := storage.NewStorage(storage.NewUserStore(testIssuer))
+	provider, err := op.NewOpenIDProvider(testIssuer, testConfig, s,
+		op.WithAllowInsecure(),
+		op.WithIDTokenClaimsHook(func(_ context.Context, claims *oidc.IDTokenClaims, hc *op.ClaimsHookContext) error {
+			gotIDToken = hc
+			claims.Claims = map[string]any{"tenant": "acme"}
+			return nil
+		}),
+		op.WithAccessTokenClaimsHook(func(_ context.Context, claims *oidc.AccessTokenClaims, hc *op.ClaimsHookContext) error {
+			gotAccessToken = hc
+			claims.Claims = map[string]any{"roles": []string{"admin"}}
+			return nil
+		}),
+	)
+	require.NoError(t, err)
+
+	ctx := op.ContextWithIssuer(context.Background(), testIssuer)
+	client := storage.WebClient("web", "secret", "https://example.com/callback")
+	request := &op.DeviceAuthorizationState{
+		ClientID: "web",
+		Scopes:   []string{oidc.ScopeOpenID},
+		Subject:  "id1",
+		AuthTime: time.Now(),
+	}
+
+	resp, err := op.CreateTokenResponse(ctx, request, client, provider, false, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", jwtPayload(t, resp.IDToken)["tenant"])
+	require.NotNil(t, gotIDToken)
+	assert.Equal(t, "id1", gotIDToken.Subject)
+	assert.Equal(t, []string{oidc.ScopeOpenID}, gotIDToken.Scopes)
+	assert.Equal(t, client, gotIDToken.Client)
+	assert.Nil(t, gotIDToken.AuthRequest)
+
+	accessToken, _, _, err := op.CreateAccessToken(ctx, request, op.AccessTokenTypeJWT, provider, client, "")
+	require.NoError(t, err)
+	assert.Equal(t, []any{"admin"}, jwtPayload(t, accessToken)["roles"])
+	require.NotNil(t, gotAccessToken)
+	assert.Equal(t, request, gotAccessToken.Request)
+}
+
+func TestClaimsHooks_error(t *testing.T) {
+	s := storage.NewStorage(storage.NewUserStore(testIssuer))
+	hookErr := errors.New("hook failed")
+	provider, err := op.NewOpenIDProvider(testIssuer, testConfig, s,
+		op.WithAllowInsecure(),
+		op.WithIDTokenClaimsHook(func(context.Context, *oidc.IDTokenClaims, *op.ClaimsHookContext) error {
+			return hookErr
+		}),
+	)
+	require.NoError(t, err)
+
+	ctx := op.ContextWithIssuer(context.Background(), testIssuer)
+	client := storage.WebClient("web", "secret", "https://example.com/callback")
+	_, err = op.CreateTokenResponse(ctx, &op.DeviceAuthorizationState{ClientID: "web", Subject: "id1"}, client, provider, false, "", "")
+	assert.ErrorIs(t, err, hookErr)
+}
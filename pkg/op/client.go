@@ -99,7 +99,7 @@ func ClientJWTAuth(ctx context.Context, ca oidc.ClientAssertionParams, verifier
 		return "", oidc.ErrInvalidClient().WithParent(ErrNoClientCredentials)
 	}
 
-	profile, err := VerifyJWTAssertion(ctx, ca.ClientAssertion, verifier.JWTProfileVerifier(ctx))
+	profile, err := verifyClientAssertion(ctx, ca.ClientAssertion, verifier.JWTProfileVerifier(ctx))
 	if err != nil {
 		return "", oidc.ErrUnauthorizedClient().WithParent(err).WithDescription("JWT assertion failed")
 	}
@@ -123,7 +123,9 @@ func ClientBasicAuth(r *http.Request, storage Storage) (clientID string, err err
 	if err != nil {
 		return "", oidc.ErrInvalidClient().WithParent(ErrInvalidAuthHeader)
 	}
-	if err := storage.AuthorizeClientIDSecret(r.Context(), clientID, clientSecret); err != nil {
+	storageCtx, cancel := storageContext(r.Context())
+	defer cancel()
+	if err := authorizeClientSecret(storageCtx, storage, clientID, clientSecret); err != nil {
 		return "", oidc.ErrUnauthorizedClient().WithParent(err)
 	}
 	return clientID, nil
@@ -152,6 +154,9 @@ type clientData struct {
 //
 // If no client id can be obtained by any method, oidc.ErrInvalidClient
 // is returned with ErrMissingClientID wrapped in it.
+//
+// On the endpoints of the [Provider] router, the client was already authenticated
+// by the [ClientAuthenticator] chain, which is used instead.
 func ClientIDFromRequest(r *http.Request, p ClientProvider) (clientID string, authenticated bool, err error) {
 	err = r.ParseForm()
 	if err != nil {
@@ -162,6 +167,10 @@ func ClientIDFromRequest(r *http.Request, p ClientProvider) (clientID string, au
 	r = r.WithContext(ctx)
 	defer span.End()
 
+	if ac, ok := authenticatedClientFromContext(r.Context()); ok {
+		return ac.client.GetID(), ac.method != oidc.AuthMethodNone, nil
+	}
+
 	data := new(clientData)
 	if err = p.Decoder().Decode(data, r.Form); err != nil {
 		return "", false, err
@@ -0,0 +1,430 @@
+package op
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// ClientAuthenticator implements a client authentication method for the
+// token, introspection, revocation and device authorization endpoints.
+// Authenticators are chained by [AuthenticateClient].
+// Custom methods can be added to the [Provider] using [WithClientAuthenticators].
+type ClientAuthenticator interface {
+	// AuthMethod returns the method as registered with the client
+	// and advertised in the discovery document.
+	AuthMethod() oidc.AuthMethod
+
+	// Matches reports if the request carries credentials for this method.
+	Matches(r *Request[ClientCredentials]) bool
+
+	// Authenticate verifies the credentials of the request
+	// and returns the authenticated client.
+	Authenticate(ctx context.Context, r *Request[ClientCredentials]) (Client, error)
+}
+
+// HasAuthMethods is an optional interface that can be implemented by implementors of
+// Client, to allow more than one authentication method for a single client.
+// Clients which do not implement it may only use Client.AuthMethod, where
+// client_secret_basic and client_secret_post are interchangeable as they share the same secret.
+type HasAuthMethods interface {
+	Client
+	AuthMethods() []oidc.AuthMethod
+}
+
+// HasTLSClientAuth is an optional interface that must be implemented by implementors of
+// Client using the tls_client_auth method.
+// https://datatracker.ietf.org/doc/html/rfc8705#section-2.1
+type HasTLSClientAuth interface {
+	Client
+	// TLSClientAuthSubjectDN returns the expected subject distinguished name
+	// of the client certificate, as registered with tls_client_auth_subject_dn.
+	TLSClientAuthSubjectDN() string
+}
+
+type clientAuthenticatorProvider interface {
+	ClientAuthenticators() []ClientAuthenticator
+}
+
+// DefaultClientAuthenticators returns the authenticators for the client_secret_basic,
+// client_secret_post, private_key_jwt, tls_client_auth, self_signed_tls_client_auth and none methods.
+// private_key_jwt is only included when the provider implements [JWTAuthorizationGrantExchanger].
+func DefaultClientAuthenticators(provider OpenIDProvider) []ClientAuthenticator {
+	storage, cache := provider.Storage(), cacheFrom(provider)
+	clientKeys := clientKeySetCacheFrom(provider)
+	if clientKeys == nil {
+		clientKeys = NewClientKeySetCache()
+	}
+	authenticators := []ClientAuthenticator{
+		clientSecretBasicAuthenticator{storage: storage, cache: cache},
+		clientSecretPostAuthenticator{storage: storage, supported: provider.AuthMethodPostSupported(), cache: cache},
+	}
+	if exchanger, ok := provider.(JWTAuthorizationGrantExchanger); ok {
+		authenticators = append(authenticators, NewPrivateKeyJWTAuthenticator(exchanger, provider.AuthMethodPrivateKeyJWTSupported()))
+	}
+	return append(authenticators,
+		tlsClientAuthenticator{storage: storage, cache: cache},
+		selfSignedTLSClientAuthenticator{storage: storage, cache: cache, clientKeys: clientKeys},
+		noneAuthenticator{storage: storage, cache: cache},
+	)
+}
+
+// mergeClientAuthenticators appends custom to defaults,
+// replacing the defaults with the same AuthMethod.
+func mergeClientAuthenticators(defaults, custom []ClientAuthenticator) []ClientAuthenticator {
+	merged := slices.DeleteFunc(slices.Clone(defaults), func(d ClientAuthenticator) bool {
+		return slices.ContainsFunc(custom, func(c ClientAuthenticator) bool {
+			return c.AuthMethod() == d.AuthMethod()
+		})
+	})
+	return append(merged, custom...)
+}
+
+// AuthenticateClient authenticates the client using the authenticator
+// which matches the credentials of the request.
+// The none method is only used when no other authenticator matches.
+//
+// An [oidc.ErrInvalidRequest] is returned when the request contains
+// credentials for more than one method (RFC 6749, section 2.3).
+// Failed authentication, unknown methods and methods the client
+// is not allowed to use result in an [oidc.ErrInvalidClient].
+func AuthenticateClient(ctx context.Context, r *Request[ClientCredentials], authenticators ...ClientAuthenticator) (Client, error) {
+	client, _, err := authenticateClient(ctx, r, authenticators...)
+	return client, err
+}
+
+// authenticateClient is [AuthenticateClient], also returning the method used.
+func authenticateClient(ctx context.Context, r *Request[ClientCredentials], authenticators ...ClientAuthenticator) (Client, oidc.AuthMethod, error) {
+	ctx, span := Tracer.Start(ctx, "AuthenticateClient")
+	defer span.End()
+
+	var matched, fallback ClientAuthenticator
+	for _, authenticator := range authenticators {
+		if !authenticator.Matches(r) {
+			continue
+		}
+		if authenticator.AuthMethod() == oidc.AuthMethodNone {
+			fallback = authenticator
+			continue
+		}
+		if matched != nil {
+			return nil, "", oidc.ErrInvalidRequest().WithDescription("client authentication must not use more than one method")
+		}
+		matched = authenticator
+	}
+	if matched == nil {
+		matched = fallback
+	}
+	if matched == nil {
+		return nil, "", oidc.ErrInvalidClient().WithDescription("no supported client authentication method")
+	}
+	client, err := matched.Authenticate(ctx, r)
+	if err != nil {
+		return nil, "", oidc.DefaultToServerError(err, "client authentication failed")
+	}
+	if !clientAllowsAuthMethod(client, matched.AuthMethod()) {
+		return nil, "", oidc.ErrInvalidClient().WithDescription("%s not allowed for this client", matched.AuthMethod())
+	}
+	return client, matched.AuthMethod(), nil
+}
+
+// clientAuthenticatorsOf returns the authenticators of the provider,
+// see [WithClientAuthenticators], or the [DefaultClientAuthenticators].
+func clientAuthenticatorsOf(provider OpenIDProvider) []ClientAuthenticator {
+	if p, ok := provider.(clientAuthenticatorProvider); ok {
+		return p.ClientAuthenticators()
+	}
+	return DefaultClientAuthenticators(provider)
+}
+
+type authenticatedClientKey struct{}
+
+type authenticatedClient struct {
+	client Client
+	method oidc.AuthMethod
+}
+
+// authenticatedClientFromContext returns the client authenticated by [withClientAuthentication].
+func authenticatedClientFromContext(ctx context.Context) (authenticatedClient, bool) {
+	ac, ok := ctx.Value(authenticatedClientKey{}).(authenticatedClient)
+	return ac, ok
+}
+
+// clientAuthenticatedGrantTypes are the grant types of the token endpoint
+// which authenticate the client. The client_credentials grant authenticates
+// the client through [ClientCredentialsStorage] and the jwt-bearer grant
+// authenticates the assertion instead of a client.
+var clientAuthenticatedGrantTypes = []oidc.GrantType{
+	oidc.GrantTypeCode,
+	oidc.GrantTypeRefreshToken,
+	oidc.GrantTypeTokenExchange,
+	oidc.GrantTypeDeviceCode,
+}
+
+// withClientAuthentication authenticates the client of requests to the token, introspection,
+// revocation and device authorization endpoints of the [Provider] router with the authenticators
+// of the provider, like [LegacyServer.VerifyClient]. The client is set on the context,
+// where [ClientIDFromRequest], [AuthorizeCodeClient], [AuthorizeRefreshClient],
+// [AuthorizeTokenExchangeClient] and [ParseTokenRevocationRequest] take it from.
+//
+// When grantTypes are passed, only requests with one of them are authenticated. Others are passed
+// to the handler, which authenticates them by the grant or returns the error.
+func withClientAuthentication(provider OpenIDProvider, grantTypes []oidc.GrantType, handler http.HandlerFunc, writeError func(http.ResponseWriter, *http.Request, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			writeError(w, r, oidc.ErrInvalidRequest().WithDescription("error parsing form").WithParent(err))
+			return
+		}
+		if grantTypes != nil && !slices.Contains(grantTypes, oidc.GrantType(r.Form.Get("grant_type"))) {
+			handler(w, r)
+			return
+		}
+		cc, err := decodeClientCredentials(r, provider.Decoder())
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		cr := newClientCredentialsRequest(r, cc)
+		authenticators := clientAuthenticatorsOf(provider)
+		if !slices.ContainsFunc(authenticators, func(a ClientAuthenticator) bool { return a.Matches(cr) }) {
+			writeError(w, r, oidc.ErrInvalidClient().WithParent(ErrNoClientCredentials))
+			return
+		}
+		client, method, err := authenticateClient(r.Context(), cr, authenticators...)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		ctx := context.WithValue(r.Context(), authenticatedClientKey{}, authenticatedClient{client: client, method: method})
+		handler(w, r.WithContext(ctx))
+	}
+}
+
+func clientAllowsAuthMethod(client Client, method oidc.AuthMethod) bool {
+	if multi, ok := client.(HasAuthMethods); ok {
+		return slices.Contains(multi.AuthMethods(), method)
+	}
+	registered := client.AuthMethod()
+	if registered == method {
+		return true
+	}
+	isSecret := func(m oidc.AuthMethod) bool {
+		return m == oidc.AuthMethodBasic || m == oidc.AuthMethodPost
+	}
+	return isSecret(registered) && isSecret(method)
+}
+
+func getClientForAuthentication(ctx context.Context, storage Storage, cache *providerCache, clientID string) (Client, error) {
+	client, err := getClientByClientID(ctx, cache, storage, clientID)
+	if err != nil {
+		return nil, oidc.ErrInvalidClient().WithParent(err)
+	}
+	return client, nil
+}
+
+type clientSecretBasicAuthenticator struct {
+	storage Storage
+	cache   *providerCache
+}
+
+// NewClientSecretBasicAuthenticator authenticates clients using
+// the client_secret_basic method.
+// https://datatracker.ietf.org/doc/html/rfc6749#section-2.3.1
+func NewClientSecretBasicAuthenticator(storage Storage) ClientAuthenticator {
+	return clientSecretBasicAuthenticator{storage: storage}
+}
+
+func (clientSecretBasicAuthenticator) AuthMethod() oidc.AuthMethod {
+	return oidc.AuthMethodBasic
+}
+
+func (clientSecretBasicAuthenticator) Matches(r *Request[ClientCredentials]) bool {
+	_, _, ok := (&http.Request{Header: r.Header}).BasicAuth()
+	return ok
+}
+
+func (a clientSecretBasicAuthenticator) Authenticate(ctx context.Context, r *Request[ClientCredentials]) (Client, error) {
+	client, err := getClientForAuthentication(ctx, a.storage, a.cache, r.Data.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if err = AuthorizeClientIDSecret(ctx, r.Data.ClientID, r.Data.ClientSecret, a.storage); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+type clientSecretPostAuthenticator struct {
+	storage   Storage
+	supported bool
+	cache     *providerCache
+}
+
+// NewClientSecretPostAuthenticator authenticates clients using
+// the client_secret_post method, if supported.
+// https://datatracker.ietf.org/doc/html/rfc6749#section-2.3.1
+func NewClientSecretPostAuthenticator(storage Storage, supported bool) ClientAuthenticator {
+	return clientSecretPostAuthenticator{storage: storage, supported: supported}
+}
+
+func (clientSecretPostAuthenticator) AuthMethod() oidc.AuthMethod {
+	return oidc.AuthMethodPost
+}
+
+func (clientSecretPostAuthenticator) Matches(r *Request[ClientCredentials]) bool {
+	return r.Form.Get("client_secret") != ""
+}
+
+func (a clientSecretPostAuthenticator) Authenticate(ctx context.Context, r *Request[ClientCredentials]) (Client, error) {
+	client, err := getClientForAuthentication(ctx, a.storage, a.cache, r.Data.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if client.AuthMethod() == oidc.AuthMethodPost && !a.supported {
+		return nil, oidc.ErrInvalidClient().WithDescription("auth_method post not supported")
+	}
+	if err = AuthorizeClientIDSecret(ctx, r.Data.ClientID, r.Data.ClientSecret, a.storage); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+type privateKeyJWTAuthenticator struct {
+	exchanger JWTAuthorizationGrantExchanger
+	supported bool
+}
+
+// NewPrivateKeyJWTAuthenticator authenticates clients using
+// the private_key_jwt method, if supported.
+// https://openid.net/specs/openid-connect-core-1_0.html#ClientAuthentication
+func NewPrivateKeyJWTAuthenticator(exchanger JWTAuthorizationGrantExchanger, supported bool) ClientAuthenticator {
+	return privateKeyJWTAuthenticator{exchanger, supported}
+}
+
+func (privateKeyJWTAuthenticator) AuthMethod() oidc.AuthMethod {
+	return oidc.AuthMethodPrivateKeyJWT
+}
+
+func (privateKeyJWTAuthenticator) Matches(r *Request[ClientCredentials]) bool {
+	return r.Data.ClientAssertionType == oidc.ClientAssertionTypeJWTAssertion
+}
+
+func (a privateKeyJWTAuthenticator) Authenticate(ctx context.Context, r *Request[ClientCredentials]) (Client, error) {
+	if !a.supported {
+		return nil, oidc.ErrInvalidClient().WithDescription("auth_method private_key_jwt not supported")
+	}
+	client, err := AuthorizePrivateJWTKey(ctx, r.Data.ClientAssertion, a.exchanger)
+	if err != nil {
+		return nil, oidc.ErrInvalidClient().WithParent(err)
+	}
+	return client, nil
+}
+
+type tlsClientAuthenticator struct {
+	storage Storage
+	cache   *providerCache
+}
+
+// NewTLSClientAuthenticator authenticates clients using the tls_client_auth method
+// of PKI mutual-TLS. The client certificate must have been verified by the TLS server
+// and the client must implement [HasTLSClientAuth].
+// Certificates not verified by the TLS server are left to [NewSelfSignedTLSClientAuthenticator].
+// https://datatracker.ietf.org/doc/html/rfc8705#section-2.1
+func NewTLSClientAuthenticator(storage Storage) ClientAuthenticator {
+	return tlsClientAuthenticator{storage: storage}
+}
+
+func (tlsClientAuthenticator) AuthMethod() oidc.AuthMethod {
+	return oidc.AuthMethodTLSClientAuth
+}
+
+func (tlsClientAuthenticator) Matches(r *Request[ClientCredentials]) bool {
+	return hasClientCertificate(r) && len(r.TLS.VerifiedChains) > 0
+}
+
+func hasClientCertificate(r *Request[ClientCredentials]) bool {
+	return r.TLS != nil && len(r.TLS.PeerCertificates) > 0 &&
+		r.Data.ClientSecret == "" && r.Data.ClientAssertion == ""
+}
+
+func (a tlsClientAuthenticator) Authenticate(ctx context.Context, r *Request[ClientCredentials]) (Client, error) {
+	client, err := getClientForAuthentication(ctx, a.storage, a.cache, r.Data.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	tlsClient, ok := client.(HasTLSClientAuth)
+	if !ok {
+		return nil, oidc.ErrInvalidClient().WithDescription("tls_client_auth not allowed for this client")
+	}
+	if len(r.TLS.VerifiedChains) == 0 {
+		return nil, oidc.ErrInvalidClient().WithDescription("client certificate not trusted")
+	}
+	if r.TLS.PeerCertificates[0].Subject.String() != tlsClient.TLSClientAuthSubjectDN() {
+		return nil, oidc.ErrInvalidClient().WithDescription("client certificate subject does not match")
+	}
+	return client, nil
+}
+
+type selfSignedTLSClientAuthenticator struct {
+	storage    Storage
+	cache      *providerCache
+	clientKeys *ClientKeySetCache
+}
+
+// NewSelfSignedTLSClientAuthenticator authenticates clients using the self_signed_tls_client_auth method
+// of mutual-TLS. The TLS server requests the client certificate without verifying it,
+// e.g. with tls.RequireAnyClientCert, and the public key of the certificate must be
+// one of the registered keys of the client, see [HasJWKS] and [HasJWKSURI].
+// clientKeys fetches the jwks_uri of the clients and defaults to a new [ClientKeySetCache].
+// https://datatracker.ietf.org/doc/html/rfc8705#section-2.2
+func NewSelfSignedTLSClientAuthenticator(storage Storage, clientKeys *ClientKeySetCache) ClientAuthenticator {
+	if clientKeys == nil {
+		clientKeys = NewClientKeySetCache()
+	}
+	return selfSignedTLSClientAuthenticator{storage: storage, clientKeys: clientKeys}
+}
+
+func (selfSignedTLSClientAuthenticator) AuthMethod() oidc.AuthMethod {
+	return oidc.AuthMethodSelfSignedTLSClientAuth
+}
+
+func (selfSignedTLSClientAuthenticator) Matches(r *Request[ClientCredentials]) bool {
+	return hasClientCertificate(r) && len(r.TLS.VerifiedChains) == 0
+}
+
+func (a selfSignedTLSClientAuthenticator) Authenticate(ctx context.Context, r *Request[ClientCredentials]) (Client, error) {
+	client, err := getClientForAuthentication(ctx, a.storage, a.cache, r.Data.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = a.clientKeys.CertificateKey(ctx, client, r.TLS.PeerCertificates[0]); err != nil {
+		return nil, oidc.ErrInvalidClient().WithParent(err).WithDescription("client certificate does not match the registered keys")
+	}
+	return client, nil
+}
+
+type noneAuthenticator struct {
+	storage Storage
+	cache   *providerCache
+}
+
+// NewNoneAuthenticator accepts public clients which do not authenticate.
+// The authorization code of such clients is protected by PKCE instead,
+// which is enforced during the code exchange.
+func NewNoneAuthenticator(storage Storage) ClientAuthenticator {
+	return noneAuthenticator{storage: storage}
+}
+
+func (noneAuthenticator) AuthMethod() oidc.AuthMethod {
+	return oidc.AuthMethodNone
+}
+
+func (noneAuthenticator) Matches(r *Request[ClientCredentials]) bool {
+	return r.Data.ClientID != "" && r.Data.ClientSecret == "" && r.Data.ClientAssertion == ""
+}
+
+func (a noneAuthenticator) Authenticate(ctx context.Context, r *Request[ClientCredentials]) (Client, error) {
+	return getClientForAuthentication(ctx, a.storage, a.cache, r.Data.ClientID)
+}
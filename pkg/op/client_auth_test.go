@@ -0,0 +1,313 @@
+package op_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/example/server/storage"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/mock"
+)
+
+type hmacAuthenticator struct {
+	client op.Client
+}
+
+func (hmacAuthenticator) AuthMethod() oidc.AuthMethod { return "hmac_request_signing" }
+
+func (hmacAuthenticator) Matches(r *op.Request[op.ClientCredentials]) bool {
+	return r.Header.Get("Signature") != ""
+}
+
+func (a hmacAuthenticator) Authenticate(context.Context, *op.Request[op.ClientCredentials]) (op.Client, error) {
+	return a.client, nil
+}
+
+func TestAuthenticateClient(t *testing.T) {
+	newClient := func(ctrl *gomock.Controller, authMethod oidc.AuthMethod) op.Client {
+		c := mock.NewMockClient(ctrl)
+		c.EXPECT().GetID().AnyTimes().Return("client")
+		c.EXPECT().AuthMethod().AnyTimes().Return(authMethod)
+		return c
+	}
+	basicHeader := func() http.Header {
+		r := &http.Request{Header: make(http.Header)}
+		r.SetBasicAuth("client", "secret")
+		return r.Header
+	}
+
+	tests := []struct {
+		name          string
+		authMethod    oidc.AuthMethod
+		header        http.Header
+		form          url.Values
+		data          op.ClientCredentials
+		secretErr     error
+		custom        bool
+		wantErr       error
+		wantErrSubstr string
+	}{
+		{
+			name:       "basic",
+			authMethod: oidc.AuthMethodBasic,
+			header:     basicHeader(),
+			data:       op.ClientCredentials{ClientID: "client", ClientSecret: "secret"},
+		},
+		{
+			name:       "post for basic client",
+			authMethod: oidc.AuthMethodBasic,
+			form:       url.Values{"client_id": {"client"}, "client_secret": {"secret"}},
+			data:       op.ClientCredentials{ClientID: "client", ClientSecret: "secret"},
+		},
+		{
+			name:       "wrong secret",
+			authMethod: oidc.AuthMethodBasic,
+			header:     basicHeader(),
+			data:       op.ClientCredentials{ClientID: "client", ClientSecret: "wrong"},
+			secretErr:  errors.New("wrong secret"),
+			wantErr:    oidc.ErrInvalidClient(),
+		},
+		{
+			name:       "more than one method",
+			authMethod: oidc.AuthMethodBasic,
+			header:     basicHeader(),
+			form:       url.Values{"client_secret": {"secret"}},
+			data:       op.ClientCredentials{ClientID: "client", ClientSecret: "secret"},
+			wantErr:    oidc.ErrInvalidRequest(),
+		},
+		{
+			name:       "public client",
+			authMethod: oidc.AuthMethodNone,
+			form:       url.Values{"client_id": {"client"}},
+			data:       op.ClientCredentials{ClientID: "client"},
+		},
+		{
+			name:          "confidential client without secret",
+			authMethod:    oidc.AuthMethodBasic,
+			form:          url.Values{"client_id": {"client"}},
+			data:          op.ClientCredentials{ClientID: "client"},
+			wantErr:       oidc.ErrInvalidClient(),
+			wantErrSubstr: "none not allowed",
+		},
+		{
+			name:       "private_key_jwt not supported",
+			authMethod: oidc.AuthMethodPrivateKeyJWT,
+			data:       op.ClientCredentials{ClientAssertion: "jwt", ClientAssertionType: oidc.ClientAssertionTypeJWTAssertion},
+			wantErr:    oidc.ErrInvalidClient(),
+		},
+		{
+			name:       "custom method",
+			authMethod: "hmac_request_signing",
+			header:     http.Header{"Signature": {"sig"}},
+			data:       op.ClientCredentials{ClientID: "client"},
+			custom:     true,
+		},
+		{
+			name:          "custom method not allowed",
+			authMethod:    oidc.AuthMethodBasic,
+			header:        http.Header{"Signature": {"sig"}},
+			data:          op.ClientCredentials{ClientID: "client"},
+			custom:        true,
+			wantErr:       oidc.ErrInvalidClient(),
+			wantErrSubstr: "hmac_request_signing not allowed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := newClient(ctrl, tt.authMethod)
+			storage := mock.NewMockStorage(ctrl)
+			storage.EXPECT().GetClientByClientID(gomock.Any(), "client").AnyTimes().Return(client, nil)
+			storage.EXPECT().AuthorizeClientIDSecret(gomock.Any(), "client", gomock.Any()).AnyTimes().Return(tt.secretErr)
+
+			authenticators := []op.ClientAuthenticator{
+				op.NewClientSecretBasicAuthenticator(storage),
+				op.NewClientSecretPostAuthenticator(storage, true),
+				op.NewPrivateKeyJWTAuthenticator(nil, false),
+				op.NewTLSClientAuthenticator(storage),
+				op.NewNoneAuthenticator(storage),
+			}
+			if tt.custom {
+				authenticators = append(authenticators, hmacAuthenticator{client})
+			}
+			header := tt.header
+			if header == nil {
+				header = make(http.Header)
+			}
+			got, err := op.AuthenticateClient(context.Background(), &op.Request[op.ClientCredentials]{
+				Method: http.MethodPost,
+				Header: header,
+				Form:   tt.form,
+				Data:   &tt.data,
+			}, authenticators...)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				if tt.wantErrSubstr != "" {
+					assert.ErrorContains(t, err, tt.wantErrSubstr)
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, client, got)
+		})
+	}
+}
+
+func selfSignedCertificate(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "client"}}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+func TestAuthenticateClient_selfSignedTLS(t *testing.T) {
+	cert, key := selfSignedCertificate(t)
+	otherCert, _ := selfSignedCertificate(t)
+	tests := []struct {
+		name       string
+		authMethod oidc.AuthMethod
+		cert       *x509.Certificate
+		verified   bool
+		wantErr    bool
+	}{
+		{name: "registered key", authMethod: oidc.AuthMethodSelfSignedTLSClientAuth, cert: cert},
+		{name: "other key", authMethod: oidc.AuthMethodSelfSignedTLSClientAuth, cert: otherCert, wantErr: true},
+		{name: "tls_client_auth client", authMethod: oidc.AuthMethodTLSClientAuth, cert: cert, wantErr: true},
+		{name: "verified certificate", authMethod: oidc.AuthMethodSelfSignedTLSClientAuth, cert: cert, verified: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			c := mock.NewMockClient(ctrl)
+			c.EXPECT().GetID().AnyTimes().Return("client")
+			c.EXPECT().AuthMethod().AnyTimes().Return(tt.authMethod)
+			client := jwksClient{c, &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: key.Public(), Use: "sig"}}}}
+			storage := mock.NewMockStorage(ctrl)
+			storage.EXPECT().GetClientByClientID(gomock.Any(), "client").AnyTimes().Return(client, nil)
+
+			state := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{tt.cert}}
+			if tt.verified {
+				state.VerifiedChains = [][]*x509.Certificate{{tt.cert}}
+			}
+			got, err := op.AuthenticateClient(context.Background(), &op.Request[op.ClientCredentials]{
+				Method: http.MethodPost,
+				Header: make(http.Header),
+				TLS:    state,
+				Data:   &op.ClientCredentials{ClientID: "client"},
+			}, op.NewTLSClientAuthenticator(storage), op.NewSelfSignedTLSClientAuthenticator(storage, nil), op.NewNoneAuthenticator(storage))
+			if tt.wantErr {
+				require.ErrorIs(t, err, oidc.ErrInvalidClient())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, client, got)
+		})
+	}
+}
+
+type hmacClient struct {
+	op.Client
+}
+
+func (hmacClient) AuthMethod() oidc.AuthMethod { return "hmac_request_signing" }
+
+func TestProvider_clientAuthenticators(t *testing.T) {
+	s := storage.NewStorage(storage.NewUserStore(testIssuer))
+	ctx := op.ContextWithIssuer(context.Background(), testIssuer)
+	client, err := s.GetClientByClientID(ctx, "web")
+	require.NoError(t, err)
+	provider, err := op.NewOpenIDProvider(testIssuer, testConfig, s,
+		op.WithAllowInsecure(),
+		op.WithClientAuthenticators(hmacAuthenticator{hmacClient{client}}),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		endpoint  *op.Endpoint
+		form      url.Values
+		signature string
+		wantCode  int
+		wantBody  string
+	}{
+		{
+			name:      "token",
+			endpoint:  provider.TokenEndpoint(),
+			form:      url.Values{"grant_type": {string(oidc.GrantTypeRefreshToken)}, "refresh_token": {"unknown"}},
+			signature: "sig",
+			wantCode:  http.StatusBadRequest,
+			wantBody:  `"error":"invalid_grant"`,
+		},
+		{
+			name:     "token without signature",
+			endpoint: provider.TokenEndpoint(),
+			form:     url.Values{"grant_type": {string(oidc.GrantTypeRefreshToken)}, "refresh_token": {"unknown"}},
+			wantCode: http.StatusUnauthorized,
+			wantBody: `"error":"invalid_client"`,
+		},
+		{
+			name:      "introspection",
+			endpoint:  provider.IntrospectionEndpoint(),
+			form:      url.Values{"token": {"unknown"}},
+			signature: "sig",
+			wantCode:  http.StatusOK,
+			wantBody:  `{"active":false}`,
+		},
+		{
+			name:     "introspection without signature",
+			endpoint: provider.IntrospectionEndpoint(),
+			form:     url.Values{"token": {"unknown"}},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:      "revocation",
+			endpoint:  provider.RevocationEndpoint(),
+			form:      url.Values{"token": {"unknown"}},
+			signature: "sig",
+			wantCode:  http.StatusOK,
+		},
+		{
+			name:     "revocation without signature",
+			endpoint: provider.RevocationEndpoint(),
+			form:     url.Values{"token": {"unknown"}},
+			wantCode: http.StatusUnauthorized,
+			wantBody: `"error":"invalid_client"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.endpoint.Absolute(testIssuer), strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.signature != "" {
+				req.Header.Set("Signature", tt.signature)
+			}
+			rec := httptest.NewRecorder()
+			provider.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code, rec.Body.String())
+			assert.Contains(t, rec.Body.String(), tt.wantBody)
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package op
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+
+	"github.com/zitadel/oidc/v3/internal/singleflight"
+	httphelper "github.com/zitadel/oidc/v3/pkg/http"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// HasJWKS is an optional interface that can be implemented by implementors of
+// Client, which registered their public keys by value (jwks).
+// The keys are used to verify private_key_jwt client assertions and signed request objects,
+// instead of calling [JWTProfileKeyStorage.GetKeyByIDAndClientID].
+type HasJWKS interface {
+	Client
+	JWKS() *jose.JSONWebKeySet
+}
+
+// HasJWKSURI is an optional interface that can be implemented by implementors of
+// Client, which registered their public keys by reference (jwks_uri).
+// The keys are fetched and cached by the [ClientKeySetCache].
+type HasJWKSURI interface {
+	Client
+	JWKSURI() string
+}
+
+var ErrNoClientKeys = errors.New("client has no registered jwks or jwks_uri")
+
+const defaultClientKeySetTimeout = 10 * time.Second
+
+// ClientKeySetCache fetches and caches the key sets of clients,
+// which registered a jwks_uri ([HasJWKSURI]).
+// Cached keys are reused until they expire. If a JWT references a kid
+// which is not part of the cached set, the set is fetched again, as the client might have rotated its keys.
+//
+// Storage implementations should call [ClientKeySetCache.Invalidate]
+// when the registration of a client changes.
+type ClientKeySetCache struct {
+	httpClient *http.Client
+	ttl        time.Duration
+	minRefresh time.Duration
+	now        func() time.Time
+	shared     Cache
+
+	mu      sync.Mutex
+	entries map[string]*clientKeySetEntry
+	fetches singleflight.Group[[]jose.JSONWebKey]
+}
+
+type clientKeySetEntry struct {
+	uri     string
+	keys    []jose.JSONWebKey
+	fetched time.Time
+}
+
+// sharedClientKeySetEntry is the encoding of a clientKeySetEntry in the shared [Cache].
+type sharedClientKeySetEntry struct {
+	URI     string            `json:"uri"`
+	Keys    []jose.JSONWebKey `json:"keys"`
+	Fetched time.Time         `json:"fetched"`
+}
+
+type ClientKeySetCacheOption func(*ClientKeySetCache)
+
+// WithClientKeySetHTTPClient sets the http.Client used to fetch the jwks_uri of clients.
+// Defaults to the client set by [WithHTTPClient] for the [Provider],
+// or a client with a timeout of 10 seconds.
+func WithClientKeySetHTTPClient(client *http.Client) ClientKeySetCacheOption {
+	return func(c *ClientKeySetCache) {
+		c.httpClient = client
+	}
+}
+
+// WithClientKeySetTTL sets how long fetched key sets are cached (defaults to 1 hour)
+// and the minimal duration between two fetches triggered by an unknown kid (defaults to 1 minute).
+func WithClientKeySetTTL(ttl, minRefresh time.Duration) ClientKeySetCacheOption {
+	return func(c *ClientKeySetCache) {
+		c.ttl = ttl
+		c.minRefresh = minRefresh
+	}
+}
+
+// WithClientKeySetSharedCache additionally stores the fetched key sets in the cache,
+// so instances of the OP don't all fetch the jwks_uri.
+// The cache set by [WithCache] is used, if not set.
+func WithClientKeySetSharedCache(cache Cache) ClientKeySetCacheOption {
+	return func(c *ClientKeySetCache) {
+		c.shared = cache
+	}
+}
+
+// NewClientKeySetCache creates an empty [ClientKeySetCache].
+func NewClientKeySetCache(opts ...ClientKeySetCacheOption) *ClientKeySetCache {
+	c := &ClientKeySetCache{
+		ttl:        time.Hour,
+		minRefresh: time.Minute,
+		now:        time.Now,
+		entries:    make(map[string]*clientKeySetEntry),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Invalidate removes the cached keys of the client,
+// so they are fetched again on next use.
+func (c *ClientKeySetCache) Invalidate(clientID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, clientID)
+}
+
+// sharedEntry returns the entry of the client in the shared cache, or nil.
+func (c *ClientKeySetCache) sharedEntry(ctx context.Context, clientID string) *clientKeySetEntry {
+	if c.shared == nil {
+		return nil
+	}
+	key := clientKeysCacheKey(ctx, clientID)
+	data, ok, err := c.shared.Get(ctx, key)
+	if err != nil || !ok {
+		logCacheError(ctx, err, key)
+		return nil
+	}
+	var shared sharedClientKeySetEntry
+	if err = json.Unmarshal(data, &shared); err != nil {
+		return nil
+	}
+	return &clientKeySetEntry{uri: shared.URI, keys: shared.Keys, fetched: shared.Fetched}
+}
+
+func (c *ClientKeySetCache) setShared(ctx context.Context, clientID string, entry *clientKeySetEntry) {
+	if c.shared == nil {
+		return
+	}
+	key := clientKeysCacheKey(ctx, clientID)
+	data, err := json.Marshal(sharedClientKeySetEntry{URI: entry.uri, Keys: entry.keys, Fetched: entry.fetched})
+	if err == nil {
+		err = c.shared.Set(ctx, key, data, c.ttl)
+	}
+	logCacheError(ctx, err, key)
+}
+
+// InvalidateAll removes the cached keys of all clients.
+func (c *ClientKeySetCache) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
+
+// SigningKey returns the key of the client matching the kid and alg of a JWS.
+// It returns [ErrNoClientKeys] if the client implements neither [HasJWKS] nor [HasJWKSURI].
+func (c *ClientKeySetCache) SigningKey(ctx context.Context, client Client, keyID, alg string) (*jose.JSONWebKey, error) {
+	return c.findKey(ctx, client, func(keys []jose.JSONWebKey) (jose.JSONWebKey, error) {
+		return oidc.FindMatchingKey(keyID, oidc.KeyUseSignature, alg, keys...)
+	})
+}
+
+// EncryptionKey returns the key of the client for encrypting tokens, such as ID tokens,
+// with the passed key management algorithm.
+// It returns [ErrNoClientKeys] if the client implements neither [HasJWKS] nor [HasJWKSURI].
+func (c *ClientKeySetCache) EncryptionKey(ctx context.Context, client Client, alg jose.KeyAlgorithm) (*jose.JSONWebKey, error) {
+	return c.findKey(ctx, client, func(keys []jose.JSONWebKey) (jose.JSONWebKey, error) {
+		for _, key := range keys {
+			if key.Use == "enc" && (key.Algorithm == "" || key.Algorithm == string(alg)) {
+				return key, nil
+			}
+		}
+		return jose.JSONWebKey{}, oidc.ErrKeyNone
+	})
+}
+
+// CertificateKey returns the key of the client matching the public key of the certificate,
+// which authenticates clients using self_signed_tls_client_auth.
+// It returns [ErrNoClientKeys] if the client implements neither [HasJWKS] nor [HasJWKSURI].
+func (c *ClientKeySetCache) CertificateKey(ctx context.Context, client Client, cert *x509.Certificate) (*jose.JSONWebKey, error) {
+	certKey, ok := cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok {
+		return nil, oidc.ErrKeyNone
+	}
+	return c.findKey(ctx, client, func(keys []jose.JSONWebKey) (jose.JSONWebKey, error) {
+		for _, key := range keys {
+			if key.Use != "enc" && certKey.Equal(key.Key) {
+				return key, nil
+			}
+		}
+		return jose.JSONWebKey{}, oidc.ErrKeyNone
+	})
+}
+
+func (c *ClientKeySetCache) findKey(ctx context.Context, client Client, match func([]jose.JSONWebKey) (jose.JSONWebKey, error)) (*jose.JSONWebKey, error) {
+	if static, ok := client.(HasJWKS); ok && static.JWKS() != nil {
+		key, err := match(static.JWKS().Keys)
+		if err != nil {
+			return nil, err
+		}
+		return &key, nil
+	}
+	remote, ok := client.(HasJWKSURI)
+	if !ok || remote.JWKSURI() == "" {
+		return nil, ErrNoClientKeys
+	}
+	keys, fresh, err := c.keys(ctx, client.GetID(), remote.JWKSURI(), false)
+	if err != nil {
+		return nil, err
+	}
+	key, err := match(keys)
+	if err == nil {
+		return &key, nil
+	}
+	if fresh {
+		return nil, err
+	}
+	// the client might have rotated its keys
+	if keys, _, err = c.keys(ctx, client.GetID(), remote.JWKSURI(), true); err != nil {
+		return nil, err
+	}
+	key, err = match(keys)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// keys returns the key set of the client, fetching it if it isn't cached, has expired
+// or refresh is requested and the last fetch is older than minRefresh.
+// fresh reports if the keys were fetched during this call.
+func (c *ClientKeySetCache) keys(ctx context.Context, clientID, uri string, refresh bool) (keys []jose.JSONWebKey, fresh bool, err error) {
+	c.mu.Lock()
+	entry, ok := c.entries[clientID]
+	c.mu.Unlock()
+
+	now := c.now()
+	valid := func(entry *clientKeySetEntry) bool {
+		age := now.Sub(entry.fetched)
+		return entry.uri == uri && age < c.ttl && (!refresh || age < c.minRefresh)
+	}
+	if ok && valid(entry) {
+		return entry.keys, false, nil
+	}
+	if entry = c.sharedEntry(ctx, clientID); entry != nil && valid(entry) {
+		c.mu.Lock()
+		c.entries[clientID] = entry
+		c.mu.Unlock()
+		return entry.keys, false, nil
+	}
+
+	// concurrent requests of the client, e.g. after a key rotation, share one fetch
+	keys, _, err = c.fetches.Do(ctx, clientID+" "+uri, func(ctx context.Context) ([]jose.JSONWebKey, error) {
+		return c.fetch(ctx, uri)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	entry = &clientKeySetEntry{uri: uri, keys: keys, fetched: now}
+	c.mu.Lock()
+	c.entries[clientID] = entry
+	c.mu.Unlock()
+	c.setShared(ctx, clientID, entry)
+	return keys, true, nil
+}
+
+func (c *ClientKeySetCache) fetch(ctx context.Context, uri string) ([]jose.JSONWebKey, error) {
+	ctx, span := Tracer.Start(ctx, "ClientKeySetCache.fetch")
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("client jwks_uri: %w", err)
+	}
+	keySet := new(jose.JSONWebKeySet)
+	httpClient := c.httpClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultClientKeySetTimeout}
+	}
+	if err = httphelper.HttpRequest(httpClient, req, keySet); err != nil {
+		return nil, fmt.Errorf("client jwks_uri: %w", err)
+	}
+	return keySet.Keys, nil
+}
+
+// clientKeySetCacheProvider is implemented by the [Provider]
+// to pass its [ClientKeySetCache] to the request object verification.
+type clientKeySetCacheProvider interface {
+	ClientKeySetCache() *ClientKeySetCache
+}
+
+func clientKeySetCacheFrom(v any) *ClientKeySetCache {
+	if p, ok := v.(clientKeySetCacheProvider); ok {
+		return p.ClientKeySetCache()
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package op_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/example/server/storage"
+	tu "github.com/zitadel/oidc/v3/internal/testutil"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/mock"
+)
+
+type jwksClient struct {
+	op.Client
+	jwks *jose.JSONWebKeySet
+}
+
+func (c jwksClient) JWKS() *jose.JSONWebKeySet { return c.jwks }
+
+type jwksURIClient struct {
+	op.Client
+	uri string
+}
+
+func (c jwksURIClient) JWKSURI() string { return c.uri }
+
+// clientKeyStorage returns the client by id and never returns a key itself.
+type clientKeyStorage struct {
+	client op.Client
+}
+
+func (s clientKeyStorage) GetKeyByIDAndClientID(context.Context, string, string) (*jose.JSONWebKey, error) {
+	return nil, errors.New("no key in storage")
+}
+
+func (s clientKeyStorage) GetClientByClientID(context.Context, string) (op.Client, error) {
+	return s.client, nil
+}
+
+func newKeysClient(ctrl *gomock.Controller) op.Client {
+	c := mock.NewMockClient(ctrl)
+	c.EXPECT().GetID().AnyTimes().Return(tu.ValidClientID)
+	return c
+}
+
+func TestVerifyJWTAssertion_clientKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	rotatedKey := tu.WebKey.Public()
+	rotatedKey.KeyID = "old"
+
+	var (
+		fetches atomic.Int32
+		keys    atomic.Pointer[jose.JSONWebKeySet]
+	)
+	keys.Store(&jose.JSONWebKeySet{Keys: []jose.JSONWebKey{rotatedKey}})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		json.NewEncoder(w).Encode(keys.Load())
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		client      op.Client
+		wantErr     bool
+		wantFetches int32
+	}{
+		{
+			name:   "static jwks",
+			client: jwksClient{newKeysClient(ctrl), &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{tu.WebKey.Public()}}},
+		},
+		{
+			name:    "static jwks, unknown kid",
+			client:  jwksClient{newKeysClient(ctrl), &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{rotatedKey}}},
+			wantErr: true,
+		},
+		{
+			name:        "jwks_uri, fetched again after kid miss",
+			client:      jwksURIClient{newKeysClient(ctrl), server.URL},
+			wantFetches: 2,
+		},
+		{
+			name:    "no keys registered, storage fallback",
+			client:  newKeysClient(ctrl),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetches.Store(0)
+			cache := op.NewClientKeySetCache(op.WithClientKeySetTTL(time.Hour, 0))
+			verifier := op.NewJWTProfileVerifier(clientKeyStorage{tt.client}, tu.ValidIssuer, time.Minute, 0, op.WithClientKeys(cache))
+
+			// after the first fetch of the jwks_uri the client rotates to the current key
+			cache.SigningKey(context.Background(), tt.client, "old", string(tu.SignatureAlgorithm))
+			keys.Store(&jose.JSONWebKeySet{Keys: []jose.JSONWebKey{rotatedKey, tu.WebKey.Public()}})
+			defer keys.Store(&jose.JSONWebKeySet{Keys: []jose.JSONWebKey{rotatedKey}})
+
+			assertion, _ := tu.ValidJWTProfileAssertion()
+			_, err := op.VerifyJWTAssertion(context.Background(), assertion, verifier)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFetches, fetches.Load())
+		})
+	}
+}
+
+func TestClientKeySetCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		encKey := tu.WebKey.Public()
+		encKey.KeyID = "enc"
+		encKey.Use = "enc"
+		encKey.Algorithm = string(jose.RSA_OAEP_256)
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{tu.WebKey.Public(), encKey}})
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client := jwksURIClient{newKeysClient(ctrl), server.URL}
+	cache := op.NewClientKeySetCache(op.WithClientKeySetHTTPClient(server.Client()))
+
+	key, err := cache.SigningKey(ctx, client, "1", string(tu.SignatureAlgorithm))
+	require.NoError(t, err)
+	assert.Equal(t, "1", key.KeyID)
+	key, err = cache.EncryptionKey(ctx, client, jose.RSA_OAEP_256)
+	require.NoError(t, err)
+	assert.Equal(t, "enc", key.KeyID)
+	assert.Equal(t, int32(1), fetches.Load(), "cached")
+
+	_, err = cache.SigningKey(ctx, client, "unknown", string(tu.SignatureAlgorithm))
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), fetches.Load(), "kid miss within min refresh")
+
+	cache.Invalidate(tu.ValidClientID)
+	_, err = cache.SigningKey(ctx, client, "1", string(tu.SignatureAlgorithm))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), fetches.Load(), "fetched after invalidation")
+
+	_, err = cache.SigningKey(ctx, newKeysClient(ctrl), "1", string(tu.SignatureAlgorithm))
+	assert.ErrorIs(t, err, op.ErrNoClientKeys)
+}
+
+func TestClientKeySetCache_concurrentFetches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{tu.WebKey.Public()}})
+	}))
+	defer server.Close()
+
+	client := jwksURIClient{newKeysClient(ctrl), server.URL}
+	cache := op.NewClientKeySetCache(op.WithClientKeySetHTTPClient(server.Client()))
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.SigningKey(context.Background(), client, "1", string(tu.SignatureAlgorithm))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), fetches.Load())
+}
+
+type idTokenEncryptionClient struct {
+	jwksClient
+}
+
+func (idTokenEncryptionClient) IDTokenEncryptedResponseAlg() jose.KeyAlgorithm {
+	return jose.RSA_OAEP_256
+}
+
+func (idTokenEncryptionClient) IDTokenEncryptedResponseEnc() jose.ContentEncryption {
+	return ""
+}
+
+func TestCreateIDToken_encrypted(t *testing.T) {
+	s := storage.NewStorage(storage.NewUserStore(testIssuer))
+	ctx := op.ContextWithIssuer(context.Background(), testIssuer)
+	web, err := s.GetClientByClientID(ctx, "web")
+	require.NoError(t, err)
+	encKey := tu.WebKey.Public()
+	encKey.KeyID = "enc"
+	encKey.Use = "enc"
+	encKey.Algorithm = string(jose.RSA_OAEP_256)
+	client := idTokenEncryptionClient{jwksClient{web, &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{encKey}}}}
+	authReq, err := s.CreateAuthRequest(ctx, &oidc.AuthRequest{
+		ClientID:     web.GetID(),
+		RedirectURI:  "https://example.com",
+		Scopes:       oidc.SpaceDelimitedArray{oidc.ScopeOpenID},
+		ResponseType: oidc.ResponseTypeCode,
+	}, "id1")
+	require.NoError(t, err)
+
+	token, err := op.CreateIDToken(ctx, testIssuer, authReq.(op.IDTokenRequest), time.Hour, "", "", s, client)
+	require.NoError(t, err)
+
+	object, err := jose.ParseEncrypted(token, []jose.KeyAlgorithm{jose.RSA_OAEP_256}, []jose.ContentEncryption{jose.A128CBC_HS256})
+	require.NoError(t, err)
+	assert.Equal(t, "enc", object.Header.KeyID)
+	assert.Equal(t, "JWT", object.Header.ExtraHeaders[jose.HeaderContentType])
+	payload, err := object.Decrypt(tu.WebKey.Key)
+	require.NoError(t, err)
+	claims := new(oidc.IDTokenClaims)
+	_, err = oidc.ParseToken(string(payload), claims)
+	require.NoError(t, err)
+	assert.Equal(t, "id1", claims.Subject)
+
+	_, err = op.CreateIDToken(ctx, testIssuer, authReq.(op.IDTokenRequest), time.Hour, "", "", s,
+		idTokenEncryptionClient{jwksClient{web, &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{tu.WebKey.Public()}}}})
+	assert.ErrorIs(t, err, oidc.ErrServerError(), "no encryption key")
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{tu.WebKey.Public()}})
+	}))
+	defer server.Close()
+
+	provider, err := op.NewOpenIDProvider(testIssuer, testConfig,
+		storage.NewStorage(storage.NewUserStore(testIssuer)),
+		op.WithAllowInsecure(),
+		op.WithHTTPClient(server.Client()),
+	)
+	require.NoError(t, err)
+	key, err := provider.ClientKeySetCache().SigningKey(context.Background(), jwksURIClient{newKeysClient(ctrl), server.URL}, "1", string(tu.SignatureAlgorithm))
+	require.NoError(t, err, "jwks_uri fetched with the client of the provider, trusting the test certificate")
+	assert.Equal(t, "1", key.KeyID)
+}
@@ -0,0 +1,92 @@
+package op
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/zitadel/oidc/v3/pkg/crypto"
+)
+
+// ClientSecret is a hashed secret of a client.
+type ClientSecret struct {
+	// Hash of the secret, compared by the [SecretComparer],
+	// e.g. created by [crypto.HashSecret] or bcrypt.
+	Hash string
+	// ExpiresAt is the client_secret_expires_at of the secret (RFC 7591 section 3.2.1).
+	// Expired secrets are rejected. The zero value never expires.
+	ExpiresAt time.Time
+}
+
+// ClientSecretStorage is an optional additional interface that may be implemented by
+// implementors of Storage to only store hashes of client secrets.
+// If implemented, the OP compares the client_secret of the requests itself
+// and the AuthorizeClientIDSecret method of the Storage is not called.
+type ClientSecretStorage interface {
+	// ClientSecrets returns the secrets of the client.
+	// During a rotation, the old and new secrets are returned, so both are accepted
+	// until the old one expires or is removed.
+	ClientSecrets(ctx context.Context, clientID string) ([]ClientSecret, error)
+}
+
+// SecretComparer compares a secret with a hash.
+// It returns nil if they match.
+//
+// A [ClientSecretStorage] may implement it to support other hash algorithms.
+// Otherwise, [crypto.CompareSecret] is used, which supports bcrypt and argon2id.
+//
+// bcrypt and argon2id make every authentication of a client expensive, for every secret
+// returned by the ClientSecretStorage. Secrets generated by the OP with enough entropy,
+// e.g. 32 random bytes, can't be brute forced and should rather be stored as SHA-256 hash:
+//
+//	func (s *Storage) CompareSecret(hash, secret string) error {
+//		sum := sha256.Sum256([]byte(secret))
+//		if subtle.ConstantTimeCompare([]byte(hash), []byte(hex.EncodeToString(sum[:]))) != 1 {
+//			return crypto.ErrSecretMismatch
+//		}
+//		return nil
+//	}
+type SecretComparer interface {
+	CompareSecret(hash, secret string) error
+}
+
+var (
+	ErrClientSecretInvalid = errors.New("client secret invalid")
+	ErrClientSecretExpired = errors.New("client secret expired")
+)
+
+// authorizeClientSecret validates the secret of the client with the [ClientSecretStorage],
+// if implemented, or by the AuthorizeClientIDSecret method of the storage.
+func authorizeClientSecret(ctx context.Context, storage Storage, clientID, clientSecret string) error {
+	secretStorage, ok := storage.(ClientSecretStorage)
+	if !ok {
+		return storage.AuthorizeClientIDSecret(ctx, clientID, clientSecret)
+	}
+	if clientSecret == "" {
+		return ErrClientSecretInvalid
+	}
+	secrets, err := secretStorage.ClientSecrets(ctx, clientID)
+	if err != nil {
+		return err
+	}
+	compare := crypto.CompareSecret
+	if comparer, ok := storage.(SecretComparer); ok {
+		compare = comparer.CompareSecret
+	}
+	now := time.Now()
+	var expired bool
+	for _, secret := range secrets {
+		if compare(secret.Hash, clientSecret) != nil {
+			continue
+		}
+		if !secret.ExpiresAt.IsZero() && !now.Before(secret.ExpiresAt) {
+			expired = true
+			continue
+		}
+		return nil
+	}
+	if expired {
+		return ErrClientSecretExpired
+	}
+	return ErrClientSecretInvalid
+}
@@ -0,0 +1,147 @@
+package op_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/zitadel/oidc/v3/pkg/crypto"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/mock"
+)
+
+type secretStorage struct {
+	*mock.MockStorage
+	secrets map[string][]op.ClientSecret
+	err     error
+}
+
+func (s *secretStorage) ClientSecrets(_ context.Context, clientID string) ([]op.ClientSecret, error) {
+	return s.secrets[clientID], s.err
+}
+
+type plainComparer struct {
+	*secretStorage
+}
+
+func (plainComparer) CompareSecret(hash, secret string) error {
+	if hash != secret {
+		return crypto.ErrSecretMismatch
+	}
+	return nil
+}
+
+func TestAuthorizeClientIDSecret_ClientSecretStorage(t *testing.T) {
+	current, err := crypto.HashSecret("current")
+	require.NoError(t, err)
+	previous, err := bcrypt.GenerateFromPassword([]byte("previous"), bcrypt.MinCost)
+	require.NoError(t, err)
+	expired, err := crypto.HashSecret("expired")
+	require.NoError(t, err)
+
+	storage := &secretStorage{
+		// AuthorizeClientIDSecret of the storage must not be called
+		MockStorage: mock.NewMockStorage(gomock.NewController(t)),
+		secrets: map[string][]op.ClientSecret{
+			"client": {
+				{Hash: current},
+				{Hash: string(previous), ExpiresAt: time.Now().Add(time.Hour)},
+				{Hash: expired, ExpiresAt: time.Now().Add(-time.Minute)},
+			},
+		},
+	}
+	tests := []struct {
+		name       string
+		storage    op.Storage
+		clientID   string
+		secret     string
+		wantParent error
+	}{
+		{
+			name:     "current secret",
+			storage:  storage,
+			clientID: "client",
+			secret:   "current",
+		},
+		{
+			name:     "rotated secret",
+			storage:  storage,
+			clientID: "client",
+			secret:   "previous",
+		},
+		{
+			name:       "expired secret",
+			storage:    storage,
+			clientID:   "client",
+			secret:     "expired",
+			wantParent: op.ErrClientSecretExpired,
+		},
+		{
+			name:       "wrong secret",
+			storage:    storage,
+			clientID:   "client",
+			secret:     "wrong",
+			wantParent: op.ErrClientSecretInvalid,
+		},
+		{
+			name:       "empty secret",
+			storage:    storage,
+			clientID:   "client",
+			wantParent: op.ErrClientSecretInvalid,
+		},
+		{
+			name:       "unknown client",
+			storage:    storage,
+			clientID:   "unknown",
+			secret:     "current",
+			wantParent: op.ErrClientSecretInvalid,
+		},
+		{
+			name: "storage error",
+			storage: &secretStorage{
+				MockStorage: storage.MockStorage,
+				err:         errors.New("storage error"),
+			},
+			clientID:   "client",
+			secret:     "current",
+			wantParent: errors.New("storage error"),
+		},
+		{
+			name: "secret comparer",
+			storage: plainComparer{&secretStorage{
+				MockStorage: storage.MockStorage,
+				secrets: map[string][]op.ClientSecret{
+					"client": {{Hash: "plain"}},
+				},
+			}},
+			clientID: "client",
+			secret:   "plain",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := op.AuthorizeClientIDSecret(context.Background(), tt.clientID, tt.secret, tt.storage)
+			if tt.wantParent == nil {
+				assert.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, &oidc.Error{ErrorType: oidc.InvalidClient})
+			var oidcErr *oidc.Error
+			require.ErrorAs(t, err, &oidcErr)
+			assert.EqualError(t, oidcErr.Parent, tt.wantParent.Error())
+		})
+	}
+}
+
+func TestAuthorizeClientIDSecret_Storage(t *testing.T) {
+	storage := mock.NewMockStorage(gomock.NewController(t))
+	storage.EXPECT().AuthorizeClientIDSecret(gomock.Any(), "client", "secret").Return(nil)
+	assert.NoError(t, op.AuthorizeClientIDSecret(context.Background(), "client", "secret", storage))
+}
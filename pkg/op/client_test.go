@@ -9,10 +9,12 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	tu "github.com/zitadel/oidc/v3/internal/testutil"
 	httphelper "github.com/zitadel/oidc/v3/pkg/http"
 	"github.com/zitadel/oidc/v3/pkg/oidc"
 	"github.com/zitadel/oidc/v3/pkg/op"
@@ -66,6 +68,29 @@ func TestClientJWTAuth(t *testing.T) {
 	}
 }
 
+type clientJWTProfile struct {
+	verifier *op.JWTProfileVerifier
+}
+
+func (c clientJWTProfile) JWTProfileVerifier(context.Context) *op.JWTProfileVerifier {
+	return c.verifier
+}
+
+func TestClientJWTAuth_subject(t *testing.T) {
+	verifier := clientJWTProfile{op.NewJWTProfileVerifier(tu.JWTProfileKeyStorage{}, tu.ValidIssuer, time.Minute, 0,
+		op.SubjectCheck(func(*oidc.JWTTokenRequest) error { return nil }),
+	)}
+	assertion, _ := tu.ValidJWTProfileAssertion()
+	clientID, err := op.ClientJWTAuth(context.Background(), oidc.ClientAssertionParams{ClientAssertion: assertion}, verifier)
+	require.NoError(t, err)
+	assert.Equal(t, tu.ValidClientID, clientID)
+
+	// the subject check of JWT profile grants does not apply to client authentication
+	assertion, _ = tu.NewJWTProfileAssertion(tu.ValidClientID, "user", []string{tu.ValidIssuer}, time.Now(), tu.ValidExpiration)
+	_, err = op.ClientJWTAuth(context.Background(), oidc.ClientAssertionParams{ClientAssertion: assertion}, verifier)
+	assert.ErrorIs(t, err, oidc.ErrSubjectInvalid)
+}
+
 func TestClientBasicAuth(t *testing.T) {
 	errWrong := errors.New("wrong secret")
 
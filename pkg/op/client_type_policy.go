@@ -0,0 +1,163 @@
+package op
+
+import (
+	"context"
+	"net/url"
+	"slices"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// ClientTypePolicy are the rules enforced for a client, depending on its [ApplicationType],
+// in addition to its registration. See [WithClientTypePolicies].
+type ClientTypePolicy struct {
+	// RequirePKCE rejects auth requests without code_challenge.
+	RequirePKCE bool
+	// LoopbackOrCustomSchemeRedirects only accepts redirect URIs on a loopback interface
+	// or with a private-use URI scheme (RFC 8252 section 7).
+	LoopbackOrCustomSchemeRedirects bool
+	// HTTPSRedirects only accepts https redirect URIs, unless the client is in DevMode.
+	HTTPSRedirects bool
+	// NoClientSecret rejects clients registered with client_secret_basic or client_secret_post,
+	// as they cannot keep a secret.
+	NoClientSecret bool
+	// RotateRefreshTokens requires a new refresh token at every refresh,
+	// see [RefreshTokenRotationRequired].
+	RotateRefreshTokens bool
+}
+
+// DefaultClientTypePolicy returns the policy for the application type of the client:
+//   - native apps must use PKCE and loopback or private-use URI scheme redirects,
+//     and must not use a client secret, following RFC 8252;
+//   - web applications must use https redirects, unless they are in DevMode;
+//   - single-page applications, web applications with auth method none and user agent applications,
+//     must rotate their refresh tokens, following the OAuth 2.0 for Browser-Based Apps BCP.
+func DefaultClientTypePolicy(client Client) ClientTypePolicy {
+	switch client.ApplicationType() {
+	case ApplicationTypeNative:
+		return ClientTypePolicy{
+			RequirePKCE:                     true,
+			LoopbackOrCustomSchemeRedirects: true,
+			NoClientSecret:                  true,
+		}
+	case ApplicationTypeUserAgent:
+		return ClientTypePolicy{
+			RotateRefreshTokens: true,
+		}
+	default:
+		return ClientTypePolicy{
+			HTTPSRedirects:      true,
+			RotateRefreshTokens: client.AuthMethod() == oidc.AuthMethodNone,
+		}
+	}
+}
+
+// ClientTypePolicyFunc returns the policy of the client, based on its default policy.
+// It allows to override the defaults, e.g. to allow https redirects
+// (claimed URLs) for the native apps of a company.
+type ClientTypePolicyFunc func(client Client, policy ClientTypePolicy) ClientTypePolicy
+
+// WithClientTypePolicies enforces the [DefaultClientTypePolicy] for all clients,
+// overridden by the optional override.
+func WithClientTypePolicies(override ClientTypePolicyFunc) Option {
+	return func(o *Provider) error {
+		o.clientTypePolicies = func(client Client) ClientTypePolicy {
+			policy := DefaultClientTypePolicy(client)
+			if override != nil {
+				policy = override(client, policy)
+			}
+			return policy
+		}
+		return nil
+	}
+}
+
+// ClientTypePolicy returns the policy of the client set by [WithClientTypePolicies].
+// It reports false if no policies are enforced.
+func (o *Provider) ClientTypePolicy(client Client) (ClientTypePolicy, bool) {
+	if o.clientTypePolicies == nil {
+		return ClientTypePolicy{}, false
+	}
+	return o.clientTypePolicies(client), true
+}
+
+type clientTypePolicyProvider interface {
+	ClientTypePolicy(client Client) (ClientTypePolicy, bool)
+}
+
+func clientTypePolicyFrom(v any, client Client) (ClientTypePolicy, bool) {
+	if p, ok := v.(clientTypePolicyProvider); ok && client != nil {
+		return p.ClientTypePolicy(client)
+	}
+	return ClientTypePolicy{}, false
+}
+
+// validateAuthRequestClientType checks the auth request against the policy of the client.
+func validateAuthRequestClientType(v any, client Client, authReq *oidc.AuthRequest) error {
+	policy, ok := clientTypePolicyFrom(v, client)
+	if !ok {
+		return nil
+	}
+	if err := validateClientSecretPolicy(policy, client); err != nil {
+		return err
+	}
+	if policy.RequirePKCE && authReq.CodeChallenge == "" {
+		return oidc.ErrInvalidRequest().WithDescription("The code_challenge (PKCE) is required for %s clients.", client.ApplicationType())
+	}
+	uri, err := url.Parse(authReq.RedirectURI)
+	if err != nil {
+		return oidc.ErrInvalidRequestRedirectURI().WithDescription("The redirect_uri is invalid.").WithParent(err)
+	}
+	if policy.LoopbackOrCustomSchemeRedirects {
+		_, isLoopback := HTTPLoopbackOrLocalhost(uri.String())
+		if !isLoopback && (uri.Scheme == "http" || uri.Scheme == "https") {
+			return oidc.ErrInvalidRequestRedirectURI().WithDescription("The redirect_uri of %s clients must use a loopback interface or a custom scheme.", client.ApplicationType())
+		}
+	}
+	if policy.HTTPSRedirects && uri.Scheme != "https" && !client.DevMode() {
+		return oidc.ErrInvalidRequestRedirectURI().WithDescription("The redirect_uri of %s clients must use https.", client.ApplicationType())
+	}
+	return nil
+}
+
+// validateClientSecretPolicy rejects clients registered with a secret, if forbidden by the policy.
+func validateClientSecretPolicy(policy ClientTypePolicy, client Client) error {
+	if !policy.NoClientSecret {
+		return nil
+	}
+	methods := []oidc.AuthMethod{client.AuthMethod()}
+	if multi, ok := client.(HasAuthMethods); ok {
+		methods = multi.AuthMethods()
+	}
+	if slices.Contains(methods, oidc.AuthMethodBasic) || slices.Contains(methods, oidc.AuthMethodPost) {
+		return oidc.ErrUnauthorizedClient().WithDescription("%s clients must not use a client secret.", client.ApplicationType())
+	}
+	return nil
+}
+
+type refreshTokenRotationKey struct{}
+
+// RefreshTokenRotationRequired reports whether the storage must return a new refresh token
+// from CreateAccessAndRefreshTokens and invalidate the current one, or no refresh token at all.
+// It is set on the context by the OP for clients whose [ClientTypePolicy] requires the rotation.
+// The OP enforces the rotation: refreshes returning the current refresh token fail with a server_error,
+// so the current refresh token is never handed out again. Storages may check it to fail early,
+// before they persist the tokens.
+func RefreshTokenRotationRequired(ctx context.Context) bool {
+	required, _ := ctx.Value(refreshTokenRotationKey{}).(bool)
+	return required
+}
+
+// refreshTokenRotation returns the context for the storage calls of the token request,
+// which requires the rotation of the current refresh token by the policy of the client.
+func refreshTokenRotation(ctx context.Context, v any, client AccessTokenClient, currentRefreshToken string) (context.Context, bool) {
+	c, ok := client.(Client)
+	if !ok || currentRefreshToken == "" {
+		return ctx, false
+	}
+	policy, ok := clientTypePolicyFrom(v, c)
+	if !ok || !policy.RotateRefreshTokens {
+		return ctx, false
+	}
+	return context.WithValue(ctx, refreshTokenRotationKey{}, true), true
+}
@@ -0,0 +1,235 @@
+package op_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/example/server/storage"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+)
+
+type applicationTypeClient struct {
+	*storage.Client
+	applicationType op.ApplicationType
+	authMethod      oidc.AuthMethod
+}
+
+func (c applicationTypeClient) ApplicationType() op.ApplicationType { return c.applicationType }
+func (c applicationTypeClient) AuthMethod() oidc.AuthMethod         { return c.authMethod }
+func (c applicationTypeClient) DevMode() bool                       { return false }
+
+// clientTypeStorage returns the client for all client ids.
+// CreateAccessAndRefreshTokens keeps the current refresh token, or returns none,
+// and records, if the rotation was required.
+type clientTypeStorage struct {
+	*storage.Storage
+	client           op.Client
+	rotationRequired *bool
+	noRefreshToken   bool
+}
+
+func (s clientTypeStorage) GetClientByClientID(context.Context, string) (op.Client, error) {
+	return s.client, nil
+}
+
+func (s clientTypeStorage) CreateAccessAndRefreshTokens(ctx context.Context, request op.TokenRequest, currentRefreshToken string) (string, string, time.Time, error) {
+	*s.rotationRequired = op.RefreshTokenRotationRequired(ctx)
+	if s.noRefreshToken {
+		return "access", "", time.Now().Add(time.Minute), nil
+	}
+	return "access", currentRefreshToken, time.Now().Add(time.Minute), nil
+}
+
+func TestDefaultClientTypePolicy(t *testing.T) {
+	web := storage.WebClient("web", "secret")
+	tests := []struct {
+		name   string
+		client op.Client
+		want   op.ClientTypePolicy
+	}{
+		{
+			name:   "native",
+			client: storage.NativeClient("native"),
+			want:   op.ClientTypePolicy{RequirePKCE: true, LoopbackOrCustomSchemeRedirects: true, NoClientSecret: true},
+		},
+		{
+			name:   "web",
+			client: web,
+			want:   op.ClientTypePolicy{HTTPSRedirects: true},
+		},
+		{
+			name:   "single-page application",
+			client: applicationTypeClient{web, op.ApplicationTypeWeb, oidc.AuthMethodNone},
+			want:   op.ClientTypePolicy{HTTPSRedirects: true, RotateRefreshTokens: true},
+		},
+		{
+			name:   "user agent",
+			client: applicationTypeClient{web, op.ApplicationTypeUserAgent, oidc.AuthMethodNone},
+			want:   op.ClientTypePolicy{RotateRefreshTokens: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, op.DefaultClientTypePolicy(tt.client))
+		})
+	}
+}
+
+func TestWithClientTypePolicies_authorize(t *testing.T) {
+	const (
+		loopbackRedirect = "http://127.0.0.1:9999/callback"
+		customRedirect   = "com.example.app:/callback"
+		httpsRedirect    = "https://app.example.com/callback"
+	)
+	native := storage.NativeClient("native", loopbackRedirect, customRedirect, httpsRedirect)
+	web := storage.WebClient("web", "secret", "http://app.example.com/callback", httpsRedirect)
+	allowHTTPS := func(_ op.Client, policy op.ClientTypePolicy) op.ClientTypePolicy {
+		policy.LoopbackOrCustomSchemeRedirects = false
+		return policy
+	}
+
+	tests := []struct {
+		name       string
+		client     op.Client
+		override   op.ClientTypePolicyFunc
+		disabled   bool
+		query      url.Values
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "native with PKCE and loopback redirect",
+			client:     native,
+			query:      url.Values{"redirect_uri": {loopbackRedirect}, "code_challenge": {"challenge"}},
+			wantStatus: http.StatusFound,
+		},
+		{
+			name:       "native with PKCE and custom scheme redirect",
+			client:     native,
+			query:      url.Values{"redirect_uri": {customRedirect}, "code_challenge": {"challenge"}},
+			wantStatus: http.StatusFound,
+		},
+		{
+			name:       "native without PKCE",
+			client:     native,
+			query:      url.Values{"redirect_uri": {loopbackRedirect}},
+			wantStatus: http.StatusFound,
+			wantError:  "invalid_request",
+		},
+		{
+			name:       "native without PKCE, policies disabled",
+			client:     native,
+			disabled:   true,
+			query:      url.Values{"redirect_uri": {loopbackRedirect}},
+			wantStatus: http.StatusFound,
+		},
+		{
+			name:       "native with https redirect",
+			client:     native,
+			query:      url.Values{"redirect_uri": {httpsRedirect}, "code_challenge": {"challenge"}},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "native with https redirect, allowed by override",
+			client:     native,
+			override:   allowHTTPS,
+			query:      url.Values{"redirect_uri": {httpsRedirect}, "code_challenge": {"challenge"}},
+			wantStatus: http.StatusFound,
+		},
+		{
+			name:       "native with client secret",
+			client:     applicationTypeClient{native, op.ApplicationTypeNative, oidc.AuthMethodBasic},
+			query:      url.Values{"redirect_uri": {loopbackRedirect}, "code_challenge": {"challenge"}},
+			wantStatus: http.StatusFound,
+			wantError:  "unauthorized_client",
+		},
+		{
+			name:       "web with https redirect",
+			client:     applicationTypeClient{web, op.ApplicationTypeWeb, oidc.AuthMethodBasic},
+			query:      url.Values{"redirect_uri": {httpsRedirect}},
+			wantStatus: http.StatusFound,
+		},
+		{
+			name:       "web with http redirect",
+			client:     applicationTypeClient{web, op.ApplicationTypeWeb, oidc.AuthMethodBasic},
+			query:      url.Values{"redirect_uri": {"http://app.example.com/callback"}},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "web with http redirect in dev mode",
+			client:     web,
+			query:      url.Values{"redirect_uri": {"http://app.example.com/callback"}},
+			wantStatus: http.StatusFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []op.Option
+			if !tt.disabled {
+				opts = append(opts, op.WithClientTypePolicies(tt.override))
+			}
+			s := clientTypeStorage{Storage: storage.NewStorage(storage.NewUserStore(testIssuer)), client: tt.client}
+			provider, err := op.NewOpenIDProvider(testIssuer, testConfig, s, append(opts, op.WithAllowInsecure())...)
+			require.NoError(t, err)
+
+			query := url.Values{
+				"client_id":     {tt.client.GetID()},
+				"response_type": {string(oidc.ResponseTypeCode)},
+				"scope":         {oidc.ScopeOpenID},
+				"state":         {"state"},
+			}
+			for key, values := range tt.query {
+				query[key] = values
+			}
+			w := httptest.NewRecorder()
+			provider.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/authorize?"+query.Encode(), nil))
+			require.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			if tt.wantStatus != http.StatusFound {
+				return
+			}
+			location, err := url.Parse(w.Header().Get("Location"))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantError, location.Query().Get("error"), location.Query().Get("error_description"))
+		})
+	}
+}
+
+func TestWithClientTypePolicies_refreshTokenRotation(t *testing.T) {
+	spa := applicationTypeClient{storage.WebClient("spa", ""), op.ApplicationTypeWeb, oidc.AuthMethodNone}
+	request := &op.DeviceAuthorizationState{
+		ClientID: "spa",
+		Scopes:   []string{oidc.ScopeOpenID, oidc.ScopeOfflineAccess},
+		Subject:  "id1",
+	}
+	ctx := op.ContextWithIssuer(context.Background(), testIssuer)
+
+	var rotationRequired bool
+	s := clientTypeStorage{Storage: storage.NewStorage(storage.NewUserStore(testIssuer)), client: spa, rotationRequired: &rotationRequired}
+	provider, err := op.NewOpenIDProvider(testIssuer, testConfig, s, op.WithAllowInsecure())
+	require.NoError(t, err)
+	_, err = op.CreateTokenResponse(ctx, request, spa, provider, true, "", "current")
+	require.NoError(t, err)
+	assert.False(t, rotationRequired, "without policies")
+
+	provider, err = op.NewOpenIDProvider(testIssuer, testConfig, s, op.WithAllowInsecure(), op.WithClientTypePolicies(nil))
+	require.NoError(t, err)
+	_, err = op.CreateTokenResponse(ctx, request, spa, provider, true, "", "current")
+	assert.ErrorIs(t, err, oidc.ErrServerError())
+	assert.True(t, rotationRequired)
+
+	s.noRefreshToken = true
+	provider, err = op.NewOpenIDProvider(testIssuer, testConfig, s, op.WithAllowInsecure(), op.WithClientTypePolicies(nil))
+	require.NoError(t, err)
+	resp, err := op.CreateTokenResponse(ctx, request, spa, provider, true, "", "current")
+	require.NoError(t, err, "no refresh token")
+	assert.Empty(t, resp.RefreshToken)
+	assert.True(t, rotationRequired)
+}
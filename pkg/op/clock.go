@@ -0,0 +1,118 @@
+package op
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Clock returns the current time used for the timestamps of tokens, see [WithClock].
+type Clock func() time.Time
+
+// TokenIDSource returns the unique IDs (jti) of tokens created by the OP itself,
+// such as logout tokens, see [WithTokenIDSource].
+type TokenIDSource func() string
+
+type (
+	clockKey         struct{}
+	tokenIDSourceKey struct{}
+)
+
+// ContextWithClock returns a context, in which the timestamps (iat, nbf, exp)
+// of created tokens are derived from clock instead of the system time.
+// It is set for all requests of the OP by [WithClock]
+// and can be used when calling the functions of this package directly,
+// e.g. to create reproducible tokens in tests.
+func ContextWithClock(ctx context.Context, clock Clock) context.Context {
+	return context.WithValue(ctx, clockKey{}, clock)
+}
+
+// ContextWithTokenIDSource returns a context, in which the IDs of tokens
+// created by the OP are returned by source instead of random values.
+// It is set for all requests of the OP by [WithTokenIDSource].
+func ContextWithTokenIDSource(ctx context.Context, source TokenIDSource) context.Context {
+	return context.WithValue(ctx, tokenIDSourceKey{}, source)
+}
+
+// Now returns the current time of the [Clock] of the context, or the system time.
+// Storages should use it for the expiration of the tokens they create,
+// so they are consistent with the tokens of the OP.
+func Now(ctx context.Context) time.Time {
+	if clock, ok := ctx.Value(clockKey{}).(Clock); ok && clock != nil {
+		return clock()
+	}
+	return time.Now()
+}
+
+// NewTokenID returns a new ID for a token of the [TokenIDSource] of the context,
+// or a random value.
+func NewTokenID(ctx context.Context) string {
+	if source, ok := ctx.Value(tokenIDSourceKey{}).(TokenIDSource); ok && source != nil {
+		return source()
+	}
+	return rand.Text()
+}
+
+// WithClock sets the clock for the timestamps of the tokens created by the OP.
+// Together with [WithTokenIDSource] and a static signing key of the Storage
+// it allows to create byte-exact tokens, e.g. for golden file tests
+// of claim customizations. It must not be used in production.
+func WithClock(clock Clock) Option {
+	return func(o *Provider) error {
+		if clock == nil {
+			return errors.New("clock must not be nil")
+		}
+		o.clock = clock
+		return nil
+	}
+}
+
+// Clock returns the clock set by [WithClock].
+func (o *Provider) Clock() Clock {
+	return o.clock
+}
+
+// WithTokenIDSource sets the source of the IDs of tokens created by the OP.
+// Defaults to random values.
+func WithTokenIDSource(source TokenIDSource) Option {
+	return func(o *Provider) error {
+		if source == nil {
+			return errors.New("token id source must not be nil")
+		}
+		o.tokenIDSource = source
+		return nil
+	}
+}
+
+// TokenIDSource returns the source set by [WithTokenIDSource].
+func (o *Provider) TokenIDSource() TokenIDSource {
+	return o.tokenIDSource
+}
+
+type clockProvider interface {
+	Clock() Clock
+	TokenIDSource() TokenIDSource
+}
+
+// clockInterceptor sets the clock and token ID source of the provider on the context of the requests.
+func clockInterceptor(v any) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		p, ok := v.(clockProvider)
+		if !ok || p.Clock() == nil && p.TokenIDSource() == nil {
+			return next
+		}
+		clock, source := p.Clock(), p.TokenIDSource()
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if clock != nil {
+				ctx = ContextWithClock(ctx, clock)
+			}
+			if source != nil {
+				ctx = ContextWithTokenIDSource(ctx, source)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
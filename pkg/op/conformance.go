@@ -0,0 +1,68 @@
+package op
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// ConformanceProfile is a certification profile of the OpenID Foundation conformance suite,
+// see https://openid.net/certification/
+type ConformanceProfile string
+
+const (
+	ConformanceProfileBasic             ConformanceProfile = "basic"
+	ConformanceProfileImplicit          ConformanceProfile = "implicit"
+	ConformanceProfileHybrid            ConformanceProfile = "hybrid"
+	ConformanceProfileFormPost          ConformanceProfile = "form_post"
+	ConformanceProfileRPInitiatedLogout ConformanceProfile = "rp_initiated_logout"
+	ConformanceProfileBackChannelLogout ConformanceProfile = "backchannel_logout"
+)
+
+// ConformanceProfiles are all profiles supported by [ConformanceConfig].
+var ConformanceProfiles = []ConformanceProfile{
+	ConformanceProfileBasic,
+	ConformanceProfileImplicit,
+	ConformanceProfileHybrid,
+	ConformanceProfileFormPost,
+	ConformanceProfileRPInitiatedLogout,
+	ConformanceProfileBackChannelLogout,
+}
+
+// ConformanceConfig returns a Config announcing and enabling everything
+// the test plans of the requested profiles check, so the OP can be certified
+// with the OpenID Foundation conformance suite.
+// The Storage still has to implement the optional interfaces of the profiles,
+// e.g. [CanListBackChannelLogoutSessions] for back-channel logout.
+func ConformanceConfig(cryptoKey [32]byte, profiles ...ConformanceProfile) (*Config, error) {
+	config := &Config{
+		CryptoKey:              cryptoKey,
+		CodeMethodS256:         true,
+		AuthMethodPost:         true,
+		GrantTypeRefreshToken:  true,
+		RequestObjectSupported: true,
+		SupportedScopes:        DefaultSupportedScopes,
+		SupportedClaims:        DefaultSupportedClaims,
+		SupportedResponseTypes: []oidc.ResponseType{oidc.ResponseTypeCode},
+	}
+	for _, profile := range profiles {
+		switch profile {
+		case ConformanceProfileBasic, ConformanceProfileRPInitiatedLogout:
+		case ConformanceProfileImplicit:
+			config.SupportedResponseTypes = append(config.SupportedResponseTypes, oidc.ResponseTypeIDTokenOnly, oidc.ResponseTypeIDToken)
+		case ConformanceProfileHybrid:
+			config.SupportedResponseTypes = append(config.SupportedResponseTypes, oidc.ResponseTypeCodeIDToken, oidc.ResponseTypeCodeToken, oidc.ResponseTypeCodeIDTokenToken)
+		case ConformanceProfileFormPost:
+			config.SupportedResponseModes = []oidc.ResponseMode{oidc.ResponseModeQuery, oidc.ResponseModeFragment, oidc.ResponseModeFormPost}
+		case ConformanceProfileBackChannelLogout:
+			config.BackChannelLogoutSupported = true
+			config.BackChannelLogoutSessionSupported = true
+			config.SessionIDClaim = true
+		default:
+			return nil, fmt.Errorf("unknown conformance profile %q", profile)
+		}
+	}
+	config.SupportedResponseTypes = slices.Compact(config.SupportedResponseTypes)
+	return config, nil
+}
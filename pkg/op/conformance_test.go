@@ -0,0 +1,184 @@
+package op_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/example/server/storage"
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/optest"
+)
+
+func TestConformanceConfig(t *testing.T) {
+	tests := []struct {
+		name                  string
+		profiles              []op.ConformanceProfile
+		wantResponseTypes     []oidc.ResponseType
+		wantResponseModes     []oidc.ResponseMode
+		wantBackChannelLogout bool
+		wantErr               bool
+	}{
+		{
+			name:              "basic",
+			profiles:          []op.ConformanceProfile{op.ConformanceProfileBasic},
+			wantResponseTypes: []oidc.ResponseType{oidc.ResponseTypeCode},
+		},
+		{
+			name:              "implicit and hybrid",
+			profiles:          []op.ConformanceProfile{op.ConformanceProfileImplicit, op.ConformanceProfileHybrid},
+			wantResponseTypes: []oidc.ResponseType{oidc.ResponseTypeCode, oidc.ResponseTypeIDTokenOnly, oidc.ResponseTypeIDToken, oidc.ResponseTypeCodeIDToken, oidc.ResponseTypeCodeToken, oidc.ResponseTypeCodeIDTokenToken},
+		},
+		{
+			name:                  "form_post and back-channel logout",
+			profiles:              []op.ConformanceProfile{op.ConformanceProfileFormPost, op.ConformanceProfileBackChannelLogout},
+			wantResponseTypes:     []oidc.ResponseType{oidc.ResponseTypeCode},
+			wantResponseModes:     []oidc.ResponseMode{oidc.ResponseModeQuery, oidc.ResponseModeFragment, oidc.ResponseModeFormPost},
+			wantBackChannelLogout: true,
+		},
+		{
+			name:     "unknown profile",
+			profiles: []op.ConformanceProfile{"fapi"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := op.ConformanceConfig([32]byte{}, tt.profiles...)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantResponseTypes, config.SupportedResponseTypes)
+			assert.Equal(t, tt.wantResponseModes, config.SupportedResponseModes)
+			assert.Equal(t, tt.wantBackChannelLogout, config.BackChannelLogoutSupported)
+			assert.True(t, config.CodeMethodS256)
+		})
+	}
+}
+
+// conformanceRedirectURI is https, as tokens are not returned in the fragment to http redirect uris.
+const conformanceRedirectURI = "https://localhost:9999/auth/callback"
+
+func newConformanceServer(t *testing.T, baseURL string, profiles ...op.ConformanceProfile) *optest.Server {
+	config, err := op.ConformanceConfig(sha256.Sum256([]byte("conformance")), profiles...)
+	require.NoError(t, err)
+	return optest.New(t, optest.WithConfig(config), withExampleStorage(storage.ConformanceClient("conformance", "secret", baseURL)))
+}
+
+func TestHybridFlow(t *testing.T) {
+	s := newConformanceServer(t, "https://localhost:9999/auth", op.ConformanceProfileHybrid)
+	relyingParty, err := rp.NewRelyingPartyOIDC(context.Background(), s.Issuer, "conformance", "secret", conformanceRedirectURI, []string{oidc.ScopeOpenID},
+		rp.WithHTTPClient(s.Client()),
+		rp.WithVerifierOpts(rp.WithNonce(func(context.Context) string { return "nonce" })),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		responseType    oidc.ResponseType
+		wantIDToken     bool
+		wantAccessToken bool
+	}{
+		{"code id_token", oidc.ResponseTypeCodeIDToken, true, false},
+		{"code token", oidc.ResponseTypeCodeToken, false, true},
+		{"code id_token token", oidc.ResponseTypeCodeIDTokenToken, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			callback := s.Authorize(t, rp.AuthURL("state", relyingParty,
+				rp.AuthURLOpt(rp.WithURLParam("response_type", string(tt.responseType))),
+				rp.AuthURLOpt(rp.WithURLParam("nonce", "nonce")),
+			))
+			assert.Empty(t, callback.RawQuery, "hybrid response defaults to the fragment")
+			fragment, err := url.ParseQuery(callback.Fragment)
+			require.NoError(t, err)
+			require.Empty(t, fragment.Get("error"), fragment.Get("error_description"))
+			assert.Equal(t, "state", fragment.Get("state"))
+			code := fragment.Get("code")
+			require.NotEmpty(t, code)
+			assert.Equal(t, tt.wantAccessToken, fragment.Get("access_token") != "")
+
+			if tt.wantIDToken {
+				claims, err := rp.VerifyIDToken[*oidc.IDTokenClaims](context.Background(), fragment.Get("id_token"), relyingParty.IDTokenVerifier())
+				require.NoError(t, err)
+				assert.Equal(t, "nonce", claims.Nonce)
+				cHash, err := oidc.ClaimHash(code, claims.SignatureAlg)
+				require.NoError(t, err)
+				assert.Equal(t, cHash, claims.CodeHash)
+				if tt.wantAccessToken {
+					assert.NoError(t, rp.VerifyAccessToken(fragment.Get("access_token"), claims.AccessTokenHash, claims.SignatureAlg))
+				}
+			} else {
+				assert.Empty(t, fragment.Get("id_token"))
+			}
+
+			tokens, err := rp.CodeExchange[*oidc.IDTokenClaims](context.Background(), code, relyingParty)
+			require.NoError(t, err, "the code of the hybrid response is exchanged at the token endpoint")
+			assert.Equal(t, optest.UserID, tokens.IDTokenClaims.Subject)
+		})
+	}
+}
+
+func TestHybridFlow_nonceRequired(t *testing.T) {
+	s := newConformanceServer(t, "https://localhost:9999/auth", op.ConformanceProfileHybrid)
+	relyingParty, err := rp.NewRelyingPartyOIDC(context.Background(), s.Issuer, "conformance", "secret", conformanceRedirectURI, []string{oidc.ScopeOpenID},
+		rp.WithHTTPClient(s.Client()))
+	require.NoError(t, err)
+
+	callback := s.Authorize(t, rp.AuthURL("state", relyingParty, rp.AuthURLOpt(rp.WithURLParam("response_type", string(oidc.ResponseTypeCodeIDToken)))))
+	fragment, err := url.ParseQuery(callback.Fragment)
+	require.NoError(t, err)
+	assert.Equal(t, "invalid_request", fragment.Get("error"))
+}
+
+func TestBackChannelLogout(t *testing.T) {
+	logoutTokens := make(chan string, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/backchannel_logout" {
+			logoutTokens <- r.FormValue("logout_token")
+		}
+	}))
+	defer receiver.Close()
+
+	s := newConformanceServer(t, receiver.URL, op.ConformanceProfileBasic, op.ConformanceProfileBackChannelLogout)
+	relyingParty, err := rp.NewRelyingPartyOIDC(context.Background(), s.Issuer, "conformance", "secret", receiver.URL+"/callback", []string{oidc.ScopeOpenID},
+		rp.WithHTTPClient(s.Client()))
+	require.NoError(t, err)
+	tokens := s.CodeFlow(t, relyingParty)
+
+	endSession, err := url.Parse(relyingParty.GetEndSessionEndpoint())
+	require.NoError(t, err)
+	endSession.RawQuery = url.Values{"id_token_hint": {tokens.IDToken}}.Encode()
+	resp, err := s.Client().Get(endSession.String())
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), `<iframe src="`+receiver.URL+`/frontchannel_logout?iss=`, "front-channel logout")
+
+	var logoutToken string
+	select {
+	case logoutToken = <-logoutTokens:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no logout token received")
+	}
+	claims, err := rp.VerifyLogoutToken(context.Background(), logoutToken, relyingParty.IDTokenVerifier())
+	require.NoError(t, err)
+	assert.Equal(t, optest.UserID, claims.Subject)
+	assert.Contains(t, claims.Events, oidc.EventBackChannelLogout)
+	assert.NotEmpty(t, claims.SessionID)
+	assert.Equal(t, tokens.IDTokenClaims.SessionID, claims.SessionID)
+}
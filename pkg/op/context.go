@@ -8,7 +8,8 @@ import (
 type key int
 
 const (
-	issuerKey key = 0
+	issuerKey key = iota
+	authRequestKey
 )
 
 type IssuerInterceptor struct {
@@ -47,6 +48,19 @@ func ContextWithIssuer(ctx context.Context, issuer string) context.Context {
 	return context.WithValue(ctx, issuerKey, issuer)
 }
 
+// ContextWithAuthRequest returns a new context with the auth request set to it.
+func ContextWithAuthRequest(ctx context.Context, authReq AuthRequest) context.Context {
+	return context.WithValue(ctx, authRequestKey, authReq)
+}
+
+// AuthRequestFromContext reads the auth request from the context.
+// It is set by the op while creating the authentication response,
+// so Storage implementations and hooks called on behalf of an auth request can read it.
+func AuthRequestFromContext(ctx context.Context) (AuthRequest, bool) {
+	authReq, ok := ctx.Value(authRequestKey).(AuthRequest)
+	return authReq, ok
+}
+
 func (i *IssuerInterceptor) setIssuerCtx(w http.ResponseWriter, r *http.Request, next http.Handler) {
 	r = r.WithContext(ContextWithIssuer(r.Context(), i.issuerFromRequest(r)))
 	next.ServeHTTP(w, r)
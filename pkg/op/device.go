@@ -6,12 +6,15 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
 	"net/http"
 	"net/url"
 	"slices"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	httphelper "github.com/zitadel/oidc/v3/pkg/http"
 	"github.com/zitadel/oidc/v3/pkg/oidc"
@@ -29,6 +32,16 @@ type DeviceAuthorizationConfig struct {
 	// The hostname for the URL is taken from the request by IssuerFromContext.
 	UserFormPath string
 	UserCode     UserCodeConfig
+
+	// ShortVerificationURIComplete appends the user code without dashes as path segment
+	// to the verification_uri_complete, instead of the user_code query parameter,
+	// e.g. https://example.com/device/BCDFGHJK. The shorter URI results in smaller QR codes.
+	// The user form must accept the code from the path, like the handler of the op/device package.
+	ShortVerificationURIComplete bool
+
+	// MinUserCodeEntropy is the minimum entropy in bits of the user codes,
+	// which is validated by [NewProvider]. Defaults to [DefaultMinUserCodeEntropy].
+	MinUserCodeEntropy float64
 }
 
 type UserCodeConfig struct {
@@ -37,6 +50,55 @@ type UserCodeConfig struct {
 	DashInterval int
 }
 
+// DefaultMinUserCodeEntropy is the minimum entropy of user codes in bits,
+// unless configured otherwise. Low entropy user codes are prone to be guessed,
+// see RFC 8628 section 5.1, or to be completed by the user for a device of an attacker.
+const DefaultMinUserCodeEntropy = 20
+
+var ErrUserCodeConfig = errors.New("invalid user code config")
+
+// Entropy returns the entropy of the user codes in bits.
+func (c UserCodeConfig) Entropy() float64 {
+	return float64(c.CharAmount) * math.Log2(float64(utf8.RuneCountInString(c.CharSet)))
+}
+
+// Validate checks the char set, which must consist of at least two distinct characters
+// other than the dash, and the entropy of the user codes, which must be at least minEntropy bits
+// or [DefaultMinUserCodeEntropy] if minEntropy is 0.
+func (c UserCodeConfig) Validate(minEntropy float64) error {
+	seen := make(map[rune]bool, len(c.CharSet))
+	for _, r := range c.CharSet {
+		if r == '-' || seen[r] {
+			return fmt.Errorf("%w: char set must consist of distinct characters other than '-'", ErrUserCodeConfig)
+		}
+		seen[r] = true
+	}
+	if len(seen) < 2 || c.CharAmount <= 0 {
+		return fmt.Errorf("%w: at least two characters and a positive char amount are required", ErrUserCodeConfig)
+	}
+	if minEntropy == 0 {
+		minEntropy = DefaultMinUserCodeEntropy
+	}
+	if entropy := c.Entropy(); entropy < minEntropy {
+		return fmt.Errorf("%w: entropy of %.1f bits is below %.1f bits", ErrUserCodeConfig, entropy, minEntropy)
+	}
+	return nil
+}
+
+// MaxBindingMessageLength is the maximum length in characters of the binding_message
+// of device authorization requests, so it can be displayed on small screens.
+const MaxBindingMessageLength = 64
+
+// DeviceAuthorizationRequestStorage is an optional interface that may be implemented by
+// implementors of [DeviceAuthorizationStorage] to receive the complete request,
+// including the binding_message. If implemented, it is called instead of StoreDeviceAuthorization.
+// The binding message must be returned in the [DeviceAuthorizationState].
+//
+// Requests with a binding_message are rejected if the interface is not implemented.
+type DeviceAuthorizationRequestStorage interface {
+	StoreDeviceAuthorizationRequest(ctx context.Context, deviceCode, userCode string, expires time.Time, req *oidc.DeviceAuthorizationRequest) error
+}
+
 const (
 	CharSetBase20 = "BCDFGHJKLMNPQRSTVWXZ"
 	CharSetDigits = "0123456789"
@@ -89,6 +151,9 @@ func createDeviceAuthorization(ctx context.Context, req *oidc.DeviceAuthorizatio
 	if err != nil {
 		return nil, err
 	}
+	if err := validateBindingMessage(req.BindingMessage, storage); err != nil {
+		return nil, NewStatusError(err, http.StatusBadRequest)
+	}
 	config := o.DeviceAuthorization()
 
 	deviceCode, _ := NewDeviceCode(RecommendedDeviceCodeBytes)
@@ -98,7 +163,14 @@ func createDeviceAuthorization(ctx context.Context, req *oidc.DeviceAuthorizatio
 	}
 
 	expires := time.Now().Add(config.Lifetime)
-	err = storage.StoreDeviceAuthorization(ctx, clientID, deviceCode, userCode, expires, req.Scopes)
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	if requestStorage, ok := storage.(DeviceAuthorizationRequestStorage); ok {
+		req.ClientID = clientID
+		err = requestStorage.StoreDeviceAuthorizationRequest(storageCtx, deviceCode, userCode, expires, req)
+	} else {
+		err = storage.StoreDeviceAuthorization(storageCtx, clientID, deviceCode, userCode, expires, req.Scopes)
+	}
 	if err != nil {
 		return nil, NewStatusError(err, http.StatusInternalServerError)
 	}
@@ -125,7 +197,11 @@ func createDeviceAuthorization(ctx context.Context, req *oidc.DeviceAuthorizatio
 		Interval:        int(config.PollInterval / time.Second),
 	}
 
-	verification.RawQuery = "user_code=" + userCode
+	if config.ShortVerificationURIComplete {
+		verification = verification.JoinPath(strings.ReplaceAll(userCode, "-", ""))
+	} else {
+		verification.RawQuery = "user_code=" + userCode
+	}
 	response.VerificationURIComplete = verification.String()
 	return response, nil
 }
@@ -139,7 +215,7 @@ func ParseDeviceCodeRequest(r *http.Request, o OpenIDProvider) (*oidc.DeviceAuth
 	if err != nil {
 		return nil, err
 	}
-	client, err := o.Storage().GetClientByClientID(r.Context(), clientID)
+	client, err := getClientByClientID(r.Context(), cacheFrom(o), o.Storage(), clientID)
 	if err != nil {
 		return nil, err
 	}
@@ -152,10 +228,32 @@ func ParseDeviceCodeRequest(r *http.Request, o OpenIDProvider) (*oidc.DeviceAuth
 		return nil, oidc.ErrInvalidRequest().WithDescription("cannot parse device authentication request").WithParent(err)
 	}
 	req.ClientID = clientID
+	req.Scopes, err = validateDeviceScopes(r.Context(), client, req.Scopes)
+	if err != nil {
+		return nil, err
+	}
 
 	return req, nil
 }
 
+func validateBindingMessage(message string, storage DeviceAuthorizationStorage) error {
+	if message == "" {
+		return nil
+	}
+	if _, ok := storage.(DeviceAuthorizationRequestStorage); !ok {
+		return oidc.ErrInvalidBindingMessage().WithDescription("binding_message is not supported")
+	}
+	if utf8.RuneCountInString(message) > MaxBindingMessageLength {
+		return oidc.ErrInvalidBindingMessage().WithDescription("binding_message must not exceed %d characters", MaxBindingMessageLength)
+	}
+	for _, r := range message {
+		if !unicode.IsPrint(r) {
+			return oidc.ErrInvalidBindingMessage().WithDescription("binding_message must only contain printable characters")
+		}
+	}
+	return nil
+}
+
 // 16 bytes gives 128 bit of entropy.
 // results in a 22 character base64 encoded string.
 const RecommendedDeviceCodeBytes = 16
@@ -197,6 +295,30 @@ func NewUserCode(charSet []rune, charAmount, dashInterval int) (string, error) {
 	return buf.String(), nil
 }
 
+// NormalizeUserCode converts a user code entered by the user into the format
+// created by [NewUserCode] with the config: lower case letters are converted
+// to upper case if the char set has no lower case letters,
+// characters not in the char set are removed and the dashes are inserted.
+// The result may be looked up in the storage.
+func NormalizeUserCode(code string, config UserCodeConfig) string {
+	if strings.ToUpper(config.CharSet) == config.CharSet {
+		code = strings.ToUpper(code)
+	}
+	var buf strings.Builder
+	n := 0
+	for _, r := range code {
+		if !strings.ContainsRune(config.CharSet, r) {
+			continue
+		}
+		if config.DashInterval != 0 && n != 0 && n%config.DashInterval == 0 {
+			buf.WriteByte('-')
+		}
+		buf.WriteRune(r)
+		n++
+	}
+	return buf.String()
+}
+
 func DeviceAccessToken(w http.ResponseWriter, r *http.Request, exchanger Exchanger) {
 	ctx, span := Tracer.Start(r.Context(), "DeviceAccessToken")
 	defer span.End()
@@ -228,7 +350,7 @@ func deviceAccessToken(w http.ResponseWriter, r *http.Request, exchanger Exchang
 		return err
 	}
 
-	client, err := exchanger.Storage().GetClientByClientID(ctx, clientID)
+	client, err := getClientByClientID(ctx, cacheFrom(exchanger), exchanger.Storage(), clientID)
 	if err != nil {
 		return err
 	}
@@ -261,9 +383,11 @@ type DeviceAuthorizationState struct {
 	ClientID string
 	Audience []string
 	Scopes   []string
-	Expires  time.Time // The time after we consider the authorization request timed-out
-	Done     bool      // The user authenticated and approved the authorization request
-	Denied   bool      // The user authenticated and denied the authorization request
+	// BindingMessage of the request, see [DeviceAuthorizationRequestStorage].
+	BindingMessage string
+	Expires        time.Time // The time after we consider the authorization request timed-out
+	Done           bool      // The user authenticated and approved the authorization request
+	Denied         bool      // The user authenticated and denied the authorization request
 
 	// The following fields are populated after Done == true
 	Subject  string
@@ -307,7 +431,9 @@ func CheckDeviceAuthorizationState(ctx context.Context, clientID, deviceCode str
 		return nil, err
 	}
 
-	state, err := storage.GetDeviceAuthorizatonState(ctx, clientID, deviceCode)
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	state, err := storage.GetDeviceAuthorizatonState(storageCtx, clientID, deviceCode)
 	if errors.Is(err, context.DeadlineExceeded) {
 		return nil, oidc.ErrSlowDown().WithParent(err)
 	}
@@ -349,11 +475,15 @@ func CreateDeviceTokenResponse(ctx context.Context, tokenRequest TokenRequest, c
 
 	// TODO(v4): remove type assertion
 	if idTokenRequest, ok := tokenRequest.(IDTokenRequest); ok && slices.Contains(tokenRequest.GetScopes(), oidc.ScopeOpenID) {
-		response.IDToken, err = CreateIDToken(ctx, IssuerFromContext(ctx), idTokenRequest, client.IDTokenLifetime(), accessToken, "", creator.Storage(), client)
+		response.IDToken, err = createIDToken(ctx, IssuerFromContext(ctx), idTokenRequest, client.IDTokenLifetime(), accessToken, "", creator.Storage(), client, idTokenClaimsHooksFrom(creator), clientKeySetCacheFrom(creator), signerCacheFrom(creator))
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	response.Extra, err = tokenResponseExtra(ctx, creator, client, tokenRequest, refreshToken)
+	if err != nil {
+		return nil, err
+	}
 	return response, nil
 }
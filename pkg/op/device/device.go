@@ -0,0 +1,355 @@
+// Package device provides the user-facing verification pages of the
+// OAuth 2.0 Device Authorization Grant (RFC 8628): entering the user code,
+// the login of the user, the confirmation and the result pages.
+//
+// The [Handler] must be mounted at the UserFormPath of the op.DeviceAuthorizationConfig.
+// It accepts the user code of the verification_uri_complete as user_code query parameter
+// or, for short URIs, as last path segment.
+package device
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/i18n"
+)
+
+// Step is a page of the device verification.
+type Step string
+
+const (
+	StepUserCode Step = "user_code"
+	StepLogin    Step = "login"
+	StepConfirm  Step = "confirm"
+	StepAllowed  Step = "allowed"
+	StepDenied   Step = "denied"
+)
+
+// Form parameters of the pages.
+const (
+	FormUserCode = "user_code"
+	FormAction   = "action"
+	FormToken    = "token"
+)
+
+// Values of the [FormAction] parameter.
+const (
+	ActionLogin = "login"
+	ActionAllow = "allow"
+	ActionDeny  = "deny"
+)
+
+// Storage of the device authorizations, usually implemented
+// next to the op.DeviceAuthorizationStorage.
+type Storage interface {
+	// GetDeviceAuthorizationByUserCode returns the current state of the device authorization,
+	// identified by the (normalized) user code.
+	GetDeviceAuthorizationByUserCode(ctx context.Context, userCode string) (*op.DeviceAuthorizationState, error)
+	// CompleteDeviceAuthorization marks the device authorization as done
+	// and sets the subject, so the device receives its tokens.
+	CompleteDeviceAuthorization(ctx context.Context, userCode, subject string) error
+	// DenyDeviceAuthorization marks the device authorization as denied.
+	DenyDeviceAuthorization(ctx context.Context, userCode string) error
+}
+
+// Config of a [Handler].
+type Config struct {
+	Storage Storage
+	// Authenticate verifies the login form, e.g. the "username" and "password" parameters,
+	// and returns the subject of the user and the time the user authenticated,
+	// which is time.Now() for verified credentials or the auth time of an existing session.
+	// The returned error is displayed on the login page.
+	Authenticate func(ctx context.Context, r *http.Request) (subject string, authTime time.Time, err error)
+	// Key signs the confirmation of the logged-in user and must be at least 32 bytes.
+	Key []byte
+	// ConfirmLifetime is the time the user has to allow or deny
+	// the device authorization after the login, defaults to [DefaultConfirmLifetime].
+	ConfirmLifetime time.Duration
+	// JTICache makes sure a confirmation is only used once,
+	// defaults to op.NewMemoryJTICache, which is only suitable for a single instance.
+	JTICache op.JTICache
+	// Path where the Handler is mounted, e.g. "/device".
+	// The forms are submitted to it and short URIs are resolved below it.
+	Path string
+	// UserCode is the format of the user codes, defaults to op.UserCodeBase20.
+	// Entered codes are normalized with op.NormalizeUserCode.
+	UserCode op.UserCodeConfig
+	// Renderer defaults to [DefaultRenderer].
+	Renderer Renderer
+	// Catalog provides the messages of the pages, defaults to [i18n.NewDefaultCatalog].
+	Catalog *i18n.Catalog
+	// ClientName returns the name of the client displayed to the user,
+	// so they can recognize the device they authorize.
+	// The client_id is displayed if not set or on error.
+	ClientName func(ctx context.Context, clientID string) (string, error)
+	// RequireReauthentication decides whether the user must authenticate
+	// within the ReauthenticationMaxAge before allowing the device authorization,
+	// e.g. for high value scopes, see [HighValueScopes].
+	// Authenticate must then not rely on an existing session, see [ReauthenticationRequired].
+	RequireReauthentication func(ctx context.Context, state *op.DeviceAuthorizationState) bool
+	// ReauthenticationMaxAge defaults to [DefaultReauthenticationMaxAge].
+	ReauthenticationMaxAge time.Duration
+	// Scopes is optional and provides the descriptions
+	// of the consentable scopes of the confirm page, see [op.ScopeRegistry.Consent].
+	Scopes *op.ScopeRegistry
+}
+
+// DefaultReauthenticationMaxAge is the time a user has
+// to confirm a device authorization after a required re-authentication.
+const DefaultReauthenticationMaxAge = 5 * time.Minute
+
+// DefaultConfirmLifetime is the time a user has to confirm a device authorization after the login.
+const DefaultConfirmLifetime = 10 * time.Minute
+
+// HighValueScopes returns a Config.RequireReauthentication function
+// requiring re-authentication if any of the scopes is requested.
+func HighValueScopes(scopes ...string) func(context.Context, *op.DeviceAuthorizationState) bool {
+	return func(_ context.Context, state *op.DeviceAuthorizationState) bool {
+		return slices.ContainsFunc(state.Scopes, func(scope string) bool {
+			return slices.Contains(scopes, scope)
+		})
+	}
+}
+
+type reauthenticationKey struct{}
+
+// ReauthenticationRequired reports whether the user must re-authenticate,
+// which is set on the context passed to Config.Authenticate.
+// Authenticate must then verify the credentials of the user
+// instead of accepting an existing session.
+func ReauthenticationRequired(ctx context.Context) bool {
+	required, _ := ctx.Value(reauthenticationKey{}).(bool)
+	return required
+}
+
+// Handler serves the device verification pages.
+//
+// Without user code it renders the [StepUserCode] page.
+// A valid user code leads to the [StepLogin] page, which submits [ActionLogin].
+// After the login, the user confirms the authorization on the [StepConfirm] page
+// with [ActionAllow] or [ActionDeny], which results in the [StepAllowed] or [StepDenied] page.
+type Handler struct {
+	config Config
+}
+
+// New creates a device verification [Handler].
+func New(config Config) (*Handler, error) {
+	if config.Storage == nil {
+		return nil, errors.New("device: storage is required")
+	}
+	if config.Authenticate == nil {
+		return nil, errors.New("device: authenticate is required")
+	}
+	if len(config.Key) < 32 {
+		return nil, errors.New("device: key must be at least 32 bytes")
+	}
+	if config.UserCode.CharSet == "" {
+		config.UserCode = op.UserCodeBase20
+	}
+	if config.Renderer == nil {
+		config.Renderer = DefaultRenderer
+	}
+	if config.Catalog == nil {
+		config.Catalog = i18n.NewDefaultCatalog()
+	}
+	if config.ReauthenticationMaxAge == 0 {
+		config.ReauthenticationMaxAge = DefaultReauthenticationMaxAge
+	}
+	if config.ConfirmLifetime == 0 {
+		config.ConfirmLifetime = DefaultConfirmLifetime
+	}
+	if config.JTICache == nil {
+		config.JTICache = op.NewMemoryJTICache()
+	}
+	return &Handler{config: config}, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, fmt.Sprintf("cannot parse form: %s", err), http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	page := &Page{
+		Step:      StepUserCode,
+		Action:    h.config.Path,
+		Localizer: h.config.Catalog.FromRequest(r, nil),
+	}
+
+	userCode := r.FormValue(FormUserCode)
+	if userCode == "" {
+		userCode = strings.Trim(strings.TrimPrefix(r.URL.Path, h.config.Path), "/")
+	}
+	if userCode == "" {
+		h.config.Renderer.Render(w, r, page)
+		return
+	}
+	page.UserCode = op.NormalizeUserCode(userCode, h.config.UserCode)
+	state, err := h.config.Storage.GetDeviceAuthorizationByUserCode(ctx, page.UserCode)
+	if err != nil || state.Done || state.Denied || time.Now().After(state.Expires) {
+		slog.InfoContext(ctx, "device user code rejected", "error", err)
+		page.UserCode = ""
+		page.Error = page.Localizer.T("device.error.invalid_code")
+		h.config.Renderer.Render(w, r, page)
+		return
+	}
+	page.ClientID = state.ClientID
+	page.ClientName = h.clientName(ctx, state.ClientID)
+	page.Scopes = state.Scopes
+	if h.config.Scopes != nil {
+		page.ConsentScopes = h.config.Scopes.Consent(state.Scopes)
+	}
+	page.BindingMessage = state.BindingMessage
+	reauthenticate := h.config.RequireReauthentication != nil && h.config.RequireReauthentication(ctx, state)
+	if reauthenticate {
+		ctx = context.WithValue(ctx, reauthenticationKey{}, true)
+	}
+
+	action := r.FormValue(FormAction)
+	if r.Method != http.MethodPost {
+		action = ""
+	}
+	switch action {
+	case ActionLogin:
+		subject, authTime, err := h.config.Authenticate(ctx, r)
+		if err != nil {
+			page.Step = StepLogin
+			page.Error = err.Error()
+			break
+		}
+		page.Step = StepConfirm
+		page.Token, err = h.token(page.UserCode, confirmation{
+			subject:  subject,
+			authTime: authTime,
+			expires:  time.Now().Add(h.config.ConfirmLifetime),
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	case ActionAllow, ActionDeny:
+		c, ok := h.verifyToken(page.UserCode, r.FormValue(FormToken))
+		if !ok || time.Now().After(c.expires) {
+			page.Step = StepLogin
+			page.Error = page.Localizer.T("device.error.invalid_token")
+			break
+		}
+		if action == ActionAllow && reauthenticate && time.Since(c.authTime) > h.config.ReauthenticationMaxAge {
+			page.Step = StepLogin
+			page.Error = page.Localizer.T("device.error.reauthentication")
+			break
+		}
+		unused, err := h.config.JTICache.UseJTI(ctx, h.config.Path, c.id, c.expires)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !unused {
+			page.Step = StepLogin
+			page.Error = page.Localizer.T("device.error.invalid_token")
+			break
+		}
+		if action == ActionAllow {
+			err = h.config.Storage.CompleteDeviceAuthorization(ctx, page.UserCode, c.subject)
+			page.Step = StepAllowed
+		} else {
+			err = h.config.Storage.DenyDeviceAuthorization(ctx, page.UserCode)
+			page.Step = StepDenied
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		page.Step = StepLogin
+	}
+	h.config.Renderer.Render(w, r, page)
+}
+
+func (h *Handler) clientName(ctx context.Context, clientID string) string {
+	if h.config.ClientName == nil {
+		return clientID
+	}
+	name, err := h.config.ClientName(ctx, clientID)
+	if err != nil || name == "" {
+		slog.WarnContext(ctx, "device client name", "client_id", clientID, "error", err)
+		return clientID
+	}
+	return name
+}
+
+// confirmation of the logged-in user, which is valid until expires
+// and identified by id, so it can only be used once.
+type confirmation struct {
+	id       string
+	subject  string
+	authTime time.Time
+	expires  time.Time
+}
+
+// token binds the confirmation to the user code,
+// so the confirmation doesn't require a session.
+func (h *Handler) token(userCode string, c confirmation) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	payload := strings.Join([]string{
+		base64.RawURLEncoding.EncodeToString(id),
+		base64.RawURLEncoding.EncodeToString([]byte(c.subject)),
+		strconv.FormatInt(c.authTime.Unix(), 10),
+		strconv.FormatInt(c.expires.Unix(), 10),
+	}, ".")
+	return payload + "." + h.mac(userCode, payload), nil
+}
+
+func (h *Handler) verifyToken(userCode, token string) (c confirmation, ok bool) {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return c, false
+	}
+	payload, mac := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(mac), []byte(h.mac(userCode, payload))) {
+		return c, false
+	}
+	parts := strings.Split(payload, ".")
+	if len(parts) != 4 {
+		return c, false
+	}
+	subject, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return c, false
+	}
+	authTime, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return c, false
+	}
+	expires, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return c, false
+	}
+	return confirmation{
+		id:       parts[0],
+		subject:  string(subject),
+		authTime: time.Unix(authTime, 0),
+		expires:  time.Unix(expires, 0),
+	}, true
+}
+
+func (h *Handler) mac(userCode, payload string) string {
+	mac := hmac.New(sha256.New, h.config.Key)
+	mac.Write([]byte(userCode + "." + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
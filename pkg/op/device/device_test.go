@@ -0,0 +1,212 @@
+package device_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/device"
+)
+
+type memoryStorage struct {
+	states map[string]*op.DeviceAuthorizationState
+}
+
+func (s *memoryStorage) GetDeviceAuthorizationByUserCode(_ context.Context, userCode string) (*op.DeviceAuthorizationState, error) {
+	if state, ok := s.states[userCode]; ok {
+		return state, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (s *memoryStorage) CompleteDeviceAuthorization(_ context.Context, userCode, subject string) error {
+	s.states[userCode].Subject = subject
+	s.states[userCode].Done = true
+	return nil
+}
+
+func (s *memoryStorage) DenyDeviceAuthorization(_ context.Context, userCode string) error {
+	s.states[userCode].Denied = true
+	return nil
+}
+
+func newHandler(t *testing.T, confirmLifetime time.Duration) (*device.Handler, *memoryStorage) {
+	storage := &memoryStorage{states: map[string]*op.DeviceAuthorizationState{
+		"BCDF-GHJK": {ClientID: "tv", Scopes: []string{"openid"}, Expires: time.Now().Add(time.Minute)},
+		"LMNP-QRST": {ClientID: "tv", Expires: time.Now().Add(-time.Minute)},
+	}}
+	handler, err := device.New(device.Config{
+		Storage: storage,
+		Authenticate: func(_ context.Context, r *http.Request) (string, time.Time, error) {
+			if r.FormValue("username") == "alice" && r.FormValue("password") == "secret" {
+				return "user1", time.Now(), nil
+			}
+			return "", time.Time{}, errors.New("wrong password")
+		},
+		Key:             []byte(strings.Repeat("k", 32)),
+		Path:            "/device",
+		ConfirmLifetime: confirmLifetime,
+	})
+	require.NoError(t, err)
+	return handler, storage
+}
+
+func get(handler http.Handler, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+func post(handler http.Handler, values url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/device", strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+var tokenRegexp = regexp.MustCompile(`name="token" value="([^"]+)"`)
+
+func TestHandler(t *testing.T) {
+	handler, storage := newHandler(t, 0)
+
+	w := get(handler, "/device")
+	assert.Contains(t, w.Body.String(), `id="user_code"`)
+
+	w = get(handler, "/device?user_code=LMNP-QRST")
+	assert.Contains(t, w.Body.String(), "The code is invalid or expired.")
+
+	w = get(handler, "/device/bcdfghjk")
+	assert.Contains(t, w.Body.String(), `name="user_code" value="BCDF-GHJK"`, "short URI")
+	assert.Contains(t, w.Body.String(), `name="password"`)
+
+	w = post(handler, url.Values{"user_code": {"BCDF-GHJK"}, "action": {"login"}, "username": {"alice"}, "password": {"wrong"}})
+	assert.Contains(t, w.Body.String(), "wrong password")
+
+	w = post(handler, url.Values{"user_code": {"BCDF-GHJK"}, "action": {"login"}, "username": {"alice"}, "password": {"secret"}})
+	match := tokenRegexp.FindStringSubmatch(w.Body.String())
+	require.Len(t, match, 2, "confirm page")
+	assert.Contains(t, w.Body.String(), "tv")
+
+	w = post(handler, url.Values{"user_code": {"BCDF-GHJK"}, "action": {"allow"}, "token": {match[1] + "x"}})
+	assert.Contains(t, w.Body.String(), "The confirmation is invalid")
+	assert.False(t, storage.states["BCDF-GHJK"].Done)
+
+	w = post(handler, url.Values{"user_code": {"BCDF-GHJK"}, "action": {"allow"}, "token": {match[1]}})
+	assert.Contains(t, w.Body.String(), "Device authorized")
+	assert.True(t, storage.states["BCDF-GHJK"].Done)
+	assert.Equal(t, "user1", storage.states["BCDF-GHJK"].Subject)
+
+	w = get(handler, "/device?user_code=BCDF-GHJK")
+	assert.Contains(t, w.Body.String(), "The code is invalid or expired.", "already used")
+}
+
+func TestHandler_confirmationUsedOnce(t *testing.T) {
+	handler, storage := newHandler(t, 0)
+
+	w := post(handler, url.Values{"user_code": {"BCDF-GHJK"}, "action": {"login"}, "username": {"alice"}, "password": {"secret"}})
+	match := tokenRegexp.FindStringSubmatch(w.Body.String())
+	require.Len(t, match, 2)
+
+	w = post(handler, url.Values{"user_code": {"BCDF-GHJK"}, "action": {"deny"}, "token": {match[1]}})
+	assert.Contains(t, w.Body.String(), "Device denied")
+
+	storage.states["BCDF-GHJK"].Denied = false
+	w = post(handler, url.Values{"user_code": {"BCDF-GHJK"}, "action": {"allow"}, "token": {match[1]}})
+	assert.Contains(t, w.Body.String(), "The confirmation is invalid")
+	assert.False(t, storage.states["BCDF-GHJK"].Done)
+}
+
+func TestHandler_confirmationExpired(t *testing.T) {
+	handler, storage := newHandler(t, -time.Second)
+
+	w := post(handler, url.Values{"user_code": {"BCDF-GHJK"}, "action": {"login"}, "username": {"alice"}, "password": {"secret"}})
+	match := tokenRegexp.FindStringSubmatch(w.Body.String())
+	require.Len(t, match, 2)
+
+	w = post(handler, url.Values{"user_code": {"BCDF-GHJK"}, "action": {"allow"}, "token": {match[1]}})
+	assert.Contains(t, w.Body.String(), "The confirmation is invalid")
+	assert.False(t, storage.states["BCDF-GHJK"].Done)
+}
+
+func TestHandler_deny(t *testing.T) {
+	handler, storage := newHandler(t, 0)
+
+	w := post(handler, url.Values{"user_code": {"BCDF-GHJK"}, "action": {"login"}, "username": {"alice"}, "password": {"secret"}})
+	match := tokenRegexp.FindStringSubmatch(w.Body.String())
+	require.Len(t, match, 2)
+
+	w = post(handler, url.Values{"user_code": {"BCDF-GHJK"}, "action": {"deny"}, "token": {match[1]}})
+	assert.Contains(t, w.Body.String(), "Device denied")
+	assert.True(t, storage.states["BCDF-GHJK"].Denied)
+}
+
+func TestHandler_clientNameAndBindingMessage(t *testing.T) {
+	storage := &memoryStorage{states: map[string]*op.DeviceAuthorizationState{
+		"BCDF-GHJK": {ClientID: "tv", Scopes: []string{"openid"}, BindingMessage: "Living room 4821", Expires: time.Now().Add(time.Minute)},
+	}}
+	handler, err := device.New(device.Config{
+		Storage: storage,
+		Authenticate: func(context.Context, *http.Request) (string, time.Time, error) {
+			return "user1", time.Now(), nil
+		},
+		Key:  []byte(strings.Repeat("k", 32)),
+		Path: "/device",
+		ClientName: func(_ context.Context, clientID string) (string, error) {
+			return "Smart TV", nil
+		},
+	})
+	require.NoError(t, err)
+
+	w := get(handler, "/device?user_code=BCDF-GHJK")
+	assert.Contains(t, w.Body.String(), "Only continue if Smart TV displays the following message: <strong>Living room 4821</strong>")
+
+	w = post(handler, url.Values{"user_code": {"BCDF-GHJK"}, "action": {"login"}})
+	assert.Contains(t, w.Body.String(), "You are about to grant device Smart TV access")
+	assert.Contains(t, w.Body.String(), "<strong>Living room 4821</strong>")
+}
+
+func TestHandler_reauthentication(t *testing.T) {
+	storage := &memoryStorage{states: map[string]*op.DeviceAuthorizationState{
+		"BCDF-GHJK": {ClientID: "tv", Scopes: []string{"openid", "payments"}, Expires: time.Now().Add(time.Minute)},
+		"LMNP-QRST": {ClientID: "tv", Scopes: []string{"openid"}, Expires: time.Now().Add(time.Minute)},
+	}}
+	var reauthenticate bool
+	handler, err := device.New(device.Config{
+		Storage: storage,
+		Authenticate: func(ctx context.Context, _ *http.Request) (string, time.Time, error) {
+			reauthenticate = device.ReauthenticationRequired(ctx)
+			// an existing session, which authenticated before the ReauthenticationMaxAge
+			return "user1", time.Now().Add(-time.Hour), nil
+		},
+		Key:                     []byte(strings.Repeat("k", 32)),
+		Path:                    "/device",
+		RequireReauthentication: device.HighValueScopes("payments"),
+	})
+	require.NoError(t, err)
+
+	for userCode, wantReauthentication := range map[string]bool{"BCDF-GHJK": true, "LMNP-QRST": false} {
+		w := post(handler, url.Values{"user_code": {userCode}, "action": {"login"}})
+		assert.Equal(t, wantReauthentication, reauthenticate, userCode)
+		match := tokenRegexp.FindStringSubmatch(w.Body.String())
+		require.Len(t, match, 2)
+
+		w = post(handler, url.Values{"user_code": {userCode}, "action": {"allow"}, "token": {match[1]}})
+		if wantReauthentication {
+			assert.Contains(t, w.Body.String(), "Please log in again to authorize this device.")
+			assert.False(t, storage.states[userCode].Done)
+		} else {
+			assert.Contains(t, w.Body.String(), "Device authorized")
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package device
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+
+	"golang.org/x/text/language"
+
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/i18n"
+)
+
+// Page is the data passed to the [Renderer].
+type Page struct {
+	Step Step
+	// Action is the URL forms must be submitted to.
+	Action string
+	// UserCode is the normalized user code, which must be submitted as [FormUserCode].
+	UserCode string
+	// ClientID and Scopes of the device authorization, to be confirmed by the user.
+	ClientID string
+	Scopes   []string
+	// ConsentScopes are the consentable scopes of the device authorization,
+	// if Config.Scopes is set.
+	ConsentScopes []op.Scope
+	// ClientName is displayed instead of the ClientID, see Config.ClientName.
+	ClientName string
+	// BindingMessage must match the message displayed on the device,
+	// so the user doesn't authorize a device of an attacker.
+	BindingMessage string
+	// Token of the logged-in user, which must be submitted as [FormToken] on the confirm page.
+	Token string
+	Error string
+	// Localizer translates the texts of the page
+	// into the language negotiated from the Accept-Language header.
+	Localizer *i18n.Localizer
+}
+
+// Renderer renders the pages of the device verification [Handler].
+type Renderer interface {
+	Render(w http.ResponseWriter, r *http.Request, page *Page)
+}
+
+type RendererFunc func(w http.ResponseWriter, r *http.Request, page *Page)
+
+func (f RendererFunc) Render(w http.ResponseWriter, r *http.Request, page *Page) {
+	f(w, r, page)
+}
+
+// DefaultRenderer renders simple localized HTML pages,
+// with a username and password form for the login.
+var DefaultRenderer Renderer = &templateRenderer{
+	template.Must(template.New("device").Funcs(i18n.NewCatalog(language.Und).Localizer().FuncMap()).Parse(defaultTemplate)),
+}
+
+type templateRenderer struct {
+	tmpl *template.Template
+}
+
+func (t *templateRenderer) Render(w http.ResponseWriter, r *http.Request, page *Page) {
+	tmpl, err := t.tmpl.Clone()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tmpl.Funcs(page.Localizer.FuncMap()).Execute(w, page); err != nil {
+		slog.ErrorContext(r.Context(), "device template", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+const defaultTemplate = `<!DOCTYPE html>
+<html lang="{{lang}}">
+	<head>
+		<meta charset="UTF-8">
+		<title>{{t "device.title"}}</title>
+	</head>
+	<body style="display: flex; align-items: center; justify-content: center; height: 100vh;">
+		{{- if or (eq .Step "allowed") (eq .Step "denied")}}
+		<div>
+			<h1>{{t (print "device.result." .Step)}}</h1>
+			<p>{{t "device.result.message"}}</p>
+		</div>
+		{{- else}}
+		<form method="POST" action="{{.Action}}" style="width: 300px;">
+			{{- if eq .Step "user_code"}}
+			<h1>{{t "device.title"}}</h1>
+			<div>
+				<label for="user_code">{{t "device.code"}}:</label>
+				<input id="user_code" name="user_code" autocomplete="off" autocapitalize="characters" style="width: 100%">
+			</div>
+			{{- else}}
+			<input type="hidden" name="user_code" value="{{.UserCode}}">
+			{{- end}}
+			{{- if eq .Step "login"}}
+			<h1>{{t "login.title"}}</h1>
+			<p>{{.UserCode}}</p>
+			{{- if .BindingMessage}}
+			<p>{{t "device.binding_message" .ClientName}} <strong>{{.BindingMessage}}</strong></p>
+			{{- end}}
+			<input type="hidden" name="action" value="login">
+			<div>
+				<label for="username">{{t "login.username"}}:</label>
+				<input id="username" name="username" style="width: 100%">
+			</div>
+			<div>
+				<label for="password">{{t "login.password"}}:</label>
+				<input id="password" name="password" type="password" style="width: 100%">
+			</div>
+			{{- else if eq .Step "confirm"}}
+			<h1>{{t "device.confirm.title"}}</h1>
+			<p>{{t "device.confirm.scopes" .ClientName .Scopes}}</p>
+			{{- if .ConsentScopes}}
+			<ul>
+				{{- range .ConsentScopes}}
+				<li>{{.Description}}</li>
+				{{- end}}
+			</ul>
+			{{- end}}
+			{{- if .BindingMessage}}
+			<p>{{t "device.binding_message" .ClientName}} <strong>{{.BindingMessage}}</strong></p>
+			{{- end}}
+			<input type="hidden" name="token" value="{{.Token}}">
+			<button type="submit" name="action" value="allow">{{t "device.confirm.allow"}}</button>
+			<button type="submit" name="action" value="deny">{{t "device.confirm.deny"}}</button>
+			{{- end}}
+			<p style="color:red; min-height: 1rem;">{{.Error}}</p>
+			{{- if ne .Step "confirm"}}
+			<button type="submit">{{t "login.next"}}</button>
+			{{- end}}
+		</form>
+		{{- end}}
+	</body>
+</html>`
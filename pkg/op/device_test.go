@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/json"
 	"io"
 	mr "math/rand"
 	"net/http"
@@ -23,24 +24,36 @@ import (
 
 func Test_deviceAuthorizationHandler(t *testing.T) {
 	type conf struct {
-		UserFormURL  string
-		UserFormPath string
+		UserFormURL                  string
+		UserFormPath                 string
+		ShortVerificationURIComplete bool
 	}
 	tests := []struct {
-		name string
-		conf conf
+		name         string
+		conf         conf
+		wantComplete string
 	}{
 		{
 			name: "UserFormURL",
 			conf: conf{
 				UserFormURL: "https://localhost:9998/device",
 			},
+			wantComplete: "https://localhost:9998/device?user_code=JKRV-FRGK",
 		},
 		{
 			name: "UserFormPath",
 			conf: conf{
 				UserFormPath: "/device",
 			},
+			wantComplete: "https://localhost:9998/device?user_code=JKRV-FRGK",
+		},
+		{
+			name: "ShortVerificationURIComplete",
+			conf: conf{
+				UserFormPath:                 "/device",
+				ShortVerificationURIComplete: true,
+			},
+			wantComplete: "https://localhost:9998/device/JKRVFRGK",
 		},
 	}
 	for _, tt := range tests {
@@ -48,6 +61,7 @@ func Test_deviceAuthorizationHandler(t *testing.T) {
 			conf := gu.PtrCopy(testConfig)
 			conf.DeviceAuthorization.UserFormURL = tt.conf.UserFormURL
 			conf.DeviceAuthorization.UserFormPath = tt.conf.UserFormPath
+			conf.DeviceAuthorization.ShortVerificationURIComplete = tt.conf.ShortVerificationURIComplete
 			provider := newTestProvider(conf)
 
 			req := &oidc.DeviceAuthorizationRequest{
@@ -73,7 +87,57 @@ func Test_deviceAuthorizationHandler(t *testing.T) {
 			assert.Less(t, result.StatusCode, 300)
 
 			got, _ := io.ReadAll(result.Body)
-			assert.JSONEq(t, `{"device_code":"Uv38ByGCZU8WP18PmmIdcg", "expires_in":300, "interval":5, "user_code":"JKRV-FRGK", "verification_uri":"https://localhost:9998/device", "verification_uri_complete":"https://localhost:9998/device?user_code=JKRV-FRGK"}`, string(got))
+			assert.JSONEq(t, `{"device_code":"Uv38ByGCZU8WP18PmmIdcg", "expires_in":300, "interval":5, "user_code":"JKRV-FRGK", "verification_uri":"https://localhost:9998/device", "verification_uri_complete":"`+tt.wantComplete+`"}`, string(got))
+		})
+	}
+}
+
+func Test_deviceAuthorizationHandler_bindingMessage(t *testing.T) {
+	tests := []struct {
+		name           string
+		bindingMessage string
+		wantErr        bool
+	}{
+		{
+			name:           "valid",
+			bindingMessage: "Living room TV 4821",
+		},
+		{
+			name:           "too long",
+			bindingMessage: strings.Repeat("x", op.MaxBindingMessageLength+1),
+			wantErr:        true,
+		},
+		{
+			name:           "not printable",
+			bindingMessage: "TV\n4821",
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newTestProvider(testConfig)
+			values := url.Values{
+				"client_id":       {"device"},
+				"scope":           {"openid"},
+				"binding_message": {tt.bindingMessage},
+			}
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(values.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			r = r.WithContext(op.ContextWithIssuer(r.Context(), testIssuer))
+			w := httptest.NewRecorder()
+			op.DeviceAuthorizationHandler(provider)(w, r)
+
+			if tt.wantErr {
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+				assert.Contains(t, w.Body.String(), `"error":"invalid_binding_message"`)
+				return
+			}
+			require.Less(t, w.Code, 300)
+			var resp oidc.DeviceAuthorizationResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			state, err := provider.Storage().(*storage.Storage).GetDeviceAuthorizationByUserCode(context.Background(), resp.UserCode)
+			require.NoError(t, err)
+			assert.Equal(t, tt.bindingMessage, state.BindingMessage)
 		})
 	}
 }
@@ -309,6 +373,54 @@ func BenchmarkNewUserCode(b *testing.B) {
 	}
 }
 
+func TestUserCodeConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     op.UserCodeConfig
+		minEntropy float64
+		wantErr    bool
+	}{
+		{name: "base20", config: op.UserCodeBase20},
+		{name: "digits", config: op.UserCodeDigits},
+		{name: "digits above min entropy", config: op.UserCodeDigits, minEntropy: 32, wantErr: true},
+		{name: "too short", config: op.UserCodeConfig{CharSet: op.CharSetBase20, CharAmount: 4}, wantErr: true},
+		{name: "duplicate chars", config: op.UserCodeConfig{CharSet: "AAB", CharAmount: 20}, wantErr: true},
+		{name: "dash", config: op.UserCodeConfig{CharSet: "AB-", CharAmount: 20}, wantErr: true},
+		{name: "single char", config: op.UserCodeConfig{CharSet: "A", CharAmount: 30}, wantErr: true},
+		{name: "no char amount", config: op.UserCodeConfig{CharSet: op.CharSetBase20}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate(tt.minEntropy)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, op.ErrUserCodeConfig)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestNormalizeUserCode(t *testing.T) {
+	tests := []struct {
+		code   string
+		config op.UserCodeConfig
+		want   string
+	}{
+		{"BCDF-GHJK", op.UserCodeBase20, "BCDF-GHJK"},
+		{"bcdfghjk", op.UserCodeBase20, "BCDF-GHJK"},
+		{" bcdf ghjk ", op.UserCodeBase20, "BCDF-GHJK"},
+		{"123456789", op.UserCodeDigits, "123-456-789"},
+		{"123-456-789", op.UserCodeDigits, "123-456-789"},
+		{"abc", op.UserCodeConfig{CharSet: "abc"}, "abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			assert.Equal(t, tt.want, op.NormalizeUserCode(tt.code, tt.config))
+		})
+	}
+}
+
 func TestDeviceAccessToken(t *testing.T) {
 	storage := testProvider.Storage().(*storage.Storage)
 	storage.StoreDeviceAuthorization(context.Background(), "native", "qwerty", "yuiop", time.Now().Add(time.Minute), []string{"foo"})
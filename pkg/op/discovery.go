@@ -25,7 +25,10 @@ var DefaultSupportedScopes = []string{
 
 func discoveryHandler(c Configuration, s DiscoverStorage) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		Discover(w, CreateDiscoveryConfig(r.Context(), c, s))
+		config := cacheFrom(c).discovery(r.Context(), func() *oidc.DiscoveryConfiguration {
+			return CreateDiscoveryConfig(r.Context(), c, s)
+		})
+		httphelper.MarshalJSON(w, config)
 	}
 }
 
@@ -44,10 +47,12 @@ func CreateDiscoveryConfig(ctx context.Context, config Configuration, storage Di
 		RevocationEndpoint:                         config.RevocationEndpoint().Absolute(issuer),
 		EndSessionEndpoint:                         config.EndSessionEndpoint().Absolute(issuer),
 		JwksURI:                                    config.KeysEndpoint().Absolute(issuer),
+		SignedJwksURI:                              signedKeysEndpoint(config).Absolute(issuer),
 		DeviceAuthorizationEndpoint:                config.DeviceAuthorizationEndpoint().Absolute(issuer),
 		CheckSessionIframe:                         config.CheckSessionIframe().Absolute(issuer),
 		ScopesSupported:                            Scopes(config),
 		ResponseTypesSupported:                     ResponseTypes(config),
+		ResponseModesSupported:                     ResponseModes(config),
 		GrantTypesSupported:                        GrantTypes(config),
 		SubjectTypesSupported:                      SubjectTypes(config),
 		IDTokenSigningAlgValuesSupported:           SigAlgorithms(ctx, storage),
@@ -78,9 +83,11 @@ func createDiscoveryConfigV2(ctx context.Context, config Configuration, storage
 		RevocationEndpoint:                         endpoints.Revocation.Absolute(issuer),
 		EndSessionEndpoint:                         endpoints.EndSession.Absolute(issuer),
 		JwksURI:                                    endpoints.JwksURI.Absolute(issuer),
+		SignedJwksURI:                              endpoints.SignedJwksURI.Absolute(issuer),
 		DeviceAuthorizationEndpoint:                endpoints.DeviceAuthorization.Absolute(issuer),
 		ScopesSupported:                            Scopes(config),
 		ResponseTypesSupported:                     ResponseTypes(config),
+		ResponseModesSupported:                     ResponseModes(config),
 		GrantTypesSupported:                        GrantTypes(config),
 		SubjectTypesSupported:                      SubjectTypes(config),
 		IDTokenSigningAlgValuesSupported:           SigAlgorithms(ctx, storage),
@@ -100,7 +107,12 @@ func createDiscoveryConfigV2(ctx context.Context, config Configuration, storage
 	}
 }
 
+// Scopes returns the scopes_supported of the discovery,
+// the names of the [ScopeRegistry] if set, or the [Config.SupportedScopes].
 func Scopes(c Configuration) []string {
+	if p, ok := c.(scopeRegistryProvider); ok && p.ScopeRegistry() != nil {
+		return p.ScopeRegistry().Names()
+	}
 	provider, ok := c.(*Provider)
 	if ok && provider.config.SupportedScopes != nil {
 		return provider.config.SupportedScopes
@@ -108,12 +120,52 @@ func Scopes(c Configuration) []string {
 	return DefaultSupportedScopes
 }
 
+// HasSupportedResponseTypes is an optional interface of the [Configuration]
+// setting the response_types_supported of the discovery.
+// The [Provider] implements it with [Config.SupportedResponseTypes].
+type HasSupportedResponseTypes interface {
+	SupportedResponseTypes() []oidc.ResponseType
+}
+
+// HasSupportedResponseModes is an optional interface of the [Configuration]
+// setting the response_modes_supported of the discovery.
+// The [Provider] implements it with [Config.SupportedResponseModes].
+type HasSupportedResponseModes interface {
+	SupportedResponseModes() []oidc.ResponseMode
+}
+
+// ResponseTypes returns the response_types_supported of the discovery,
+// see [HasSupportedResponseTypes].
+// Defaults to code, id_token and id_token token.
 func ResponseTypes(c Configuration) []string {
+	if p, ok := c.(HasSupportedResponseTypes); ok && p.SupportedResponseTypes() != nil {
+		responseTypes := p.SupportedResponseTypes()
+		types := make([]string, len(responseTypes))
+		for i, responseType := range responseTypes {
+			types[i] = string(responseType)
+		}
+		return types
+	}
 	return []string{
 		string(oidc.ResponseTypeCode),
 		string(oidc.ResponseTypeIDTokenOnly),
 		string(oidc.ResponseTypeIDToken),
-	} // TODO: ok for now, check later if dynamic needed
+	}
+}
+
+// ResponseModes returns the response_modes_supported of the discovery,
+// see [HasSupportedResponseModes], or nil to omit them.
+func ResponseModes(c Configuration) []string {
+	p, ok := c.(HasSupportedResponseModes)
+	if !ok || p.SupportedResponseModes() == nil {
+		return nil
+	}
+	responseModes := p.SupportedResponseModes()
+	modes := make([]string, len(responseModes))
+	for i, mode := range responseModes {
+		modes[i] = string(mode)
+	}
+	return modes
 }
 
 func GrantTypes(c Configuration) []oidc.GrantType {
@@ -147,7 +199,9 @@ func SigAlgorithms(ctx context.Context, storage DiscoverStorage) []string {
 	ctx, span := Tracer.Start(ctx, "SigAlgorithms")
 	defer span.End()
 
-	algorithms, err := storage.SignatureAlgorithms(ctx)
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	algorithms, err := storage.SignatureAlgorithms(storageCtx)
 	if err != nil {
 		return nil
 	}
@@ -119,6 +119,11 @@ func Test_ResponseTypes(t *testing.T) {
 			args{},
 			[]string{"code", "id_token", "id_token token"},
 		},
+		{
+			"configured",
+			args{newTestProvider(&op.Config{SupportedResponseTypes: []oidc.ResponseType{oidc.ResponseTypeCode, oidc.ResponseTypeCodeIDToken}})},
+			[]string{"code", "code id_token"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -128,6 +133,12 @@ func Test_ResponseTypes(t *testing.T) {
 	}
 }
 
+func Test_ResponseModes(t *testing.T) {
+	assert.Nil(t, op.ResponseModes(testProvider))
+	provider := newTestProvider(&op.Config{SupportedResponseModes: []oidc.ResponseMode{oidc.ResponseModeQuery, oidc.ResponseModeFormPost}})
+	assert.Equal(t, []string{"query", "form_post"}, op.ResponseModes(provider))
+}
+
 func Test_GrantTypes(t *testing.T) {
 	type args struct {
 		c op.Configuration
@@ -32,6 +32,10 @@ func AuthRequestError(w http.ResponseWriter, r *http.Request, authReq ErrAuthReq
 
 	if authReq == nil {
 		slog.Log(r.Context(), e.LogLevel(), "auth request", args...)
+		if catalog := errorPageCatalogFrom(authorizer); catalog != nil {
+			renderErrorPage(w, r, catalog, errorPageUILocales(r, nil), e, http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -42,6 +46,10 @@ func AuthRequestError(w http.ResponseWriter, r *http.Request, authReq ErrAuthReq
 
 	if authReq.GetRedirectURI() == "" || e.IsRedirectDisabled() {
 		slog.Log(r.Context(), e.LogLevel(), "auth request: not redirecting", args...)
+		if catalog := errorPageCatalogFrom(authorizer); catalog != nil {
+			renderErrorPage(w, r, catalog, errorPageUILocales(r, authReq), e, http.StatusBadRequest)
+			return
+		}
 		http.Error(w, e.Description, http.StatusBadRequest)
 		return
 	}
@@ -80,6 +88,10 @@ func RequestError(w http.ResponseWriter, r *http.Request, err error, _ *slog.Log
 	httphelper.MarshalJSONWithStatus(w, e, status)
 }
 
+func writeRequestError(w http.ResponseWriter, r *http.Request, err error) {
+	RequestError(w, r, err, nil)
+}
+
 // TryErrorRedirect tries to handle an error by redirecting a client.
 // If this attempt fails, an error is returned that must be returned
 // to the client instead.
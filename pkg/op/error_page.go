@@ -0,0 +1,80 @@
+package op
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"golang.org/x/text/language"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op/i18n"
+)
+
+// errorPageCatalogProvider is implemented by the [Provider] and the [LegacyServer]
+// when localized error pages are enabled with [WithLocalizedErrorPages].
+type errorPageCatalogProvider interface {
+	ErrorPageCatalog() *i18n.Catalog
+}
+
+func errorPageCatalogFrom(v any) *i18n.Catalog {
+	if p, ok := v.(errorPageCatalogProvider); ok {
+		return p.ErrorPageCatalog()
+	}
+	return nil
+}
+
+var errorPage = template.Must(template.New("error").Funcs(i18n.NewCatalog(language.Und).Localizer().FuncMap()).Parse(errorPageTemplate))
+
+// renderErrorPage writes a HTML page in the language negotiated from the ui_locales
+// and the Accept-Language header, for errors which cannot be returned to the client.
+// The language negotiated by the OP is used, if it has supported ui locales.
+func renderErrorPage(w http.ResponseWriter, r *http.Request, catalog *i18n.Catalog, uiLocales []language.Tag, e *oidc.Error, statusCode int) {
+	localizer := catalog.FromRequest(r, uiLocales)
+	if tag, ok := UILocaleFromContext(r.Context()); ok {
+		localizer = catalog.Localizer(tag)
+	}
+	message := localizer.T("error." + string(e.ErrorType))
+	if message == "error."+string(e.ErrorType) {
+		message = localizer.T("error.message")
+	}
+	tmpl, err := errorPage.Clone()
+	if err != nil {
+		http.Error(w, e.Description, statusCode)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(statusCode)
+	err = tmpl.Funcs(localizer.FuncMap()).Execute(w, struct {
+		Message     string
+		Description string
+	}{message, e.Description})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "error page template", "error", err)
+	}
+}
+
+// errorPageUILocales returns the ui_locales of the auth request,
+// or of the request parameters if the auth request is unknown.
+func errorPageUILocales(r *http.Request, authReq ErrAuthRequest) []language.Tag {
+	if req, ok := authReq.(*oidc.AuthRequest); ok {
+		return req.UILocales
+	}
+	return oidc.ParseLocales(strings.Fields(r.FormValue("ui_locales")))
+}
+
+const errorPageTemplate = `<!DOCTYPE html>
+<html lang="{{lang}}">
+	<head>
+		<meta charset="UTF-8">
+		<title>{{t "error.title"}}</title>
+	</head>
+	<body>
+		<h1>{{t "error.title"}}</h1>
+		<p>{{.Message}}</p>
+		{{- if .Description}}
+		<p>{{t "error.details" .Description}}</p>
+		{{- end}}
+	</body>
+</html>`
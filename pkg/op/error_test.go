@@ -14,7 +14,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op/i18n"
 	"github.com/zitadel/schema"
+	"golang.org/x/text/language"
 )
 
 // setDefaultTestLogger installs a process-wide default logger for the duration of t.
@@ -225,6 +227,38 @@ func TestAuthRequestError(t *testing.T) {
 	}
 }
 
+func TestAuthRequestError_localizedErrorPage(t *testing.T) {
+	authorizer := &Provider{
+		encoder:          schema.NewEncoder(),
+		errorPageCatalog: i18n.NewDefaultCatalog(),
+	}
+	authReq := &oidc.AuthRequest{
+		ClientID:  "123",
+		UILocales: oidc.Locales{language.German},
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/path", nil)
+	r.Header.Set("Accept-Language", "en")
+	AuthRequestError(w, r, authReq, oidc.ErrInvalidRequest().WithDescription("<missing redirect_uri>"), authorizer)
+
+	res := w.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", res.Header.Get("Content-Type"))
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `<html lang="de">`)
+	assert.Contains(t, string(body), "Die Anfrage ist ungültig oder unvollständig.")
+	assert.Contains(t, string(body), "&lt;missing redirect_uri&gt;")
+
+	w = httptest.NewRecorder()
+	AuthRequestError(w, r, nil, io.ErrClosedPipe, authorizer)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `<html lang="en">`)
+	assert.Contains(t, w.Body.String(), "An unexpected error occurred")
+}
+
 func TestRequestError(t *testing.T) {
 	tests := []struct {
 		name     string
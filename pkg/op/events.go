@@ -0,0 +1,115 @@
+package op
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// EventType is the type of a security [Event].
+type EventType string
+
+const (
+	// EventSessionRevoked is published when the session of a user ended,
+	// by the end_session endpoint or [LogoutSession].
+	EventSessionRevoked EventType = "session_revoked"
+	// EventCredentialChange is published by the application with [PublishEvent],
+	// when a credential of the user was created, changed or removed.
+	EventCredentialChange EventType = "credential_change"
+	// EventTokenClaimsChange is published by the application with [PublishEvent],
+	// when claims of the tokens issued to the user changed.
+	EventTokenClaimsChange EventType = "token_claims_change"
+)
+
+// Event is a security event of a user, which is passed to the listeners of [WithEventListener],
+// e.g. to transmit it to other parties by the Shared Signals Framework, see package ssf.
+type Event struct {
+	Type    EventType
+	Subject string
+	// SessionID of the session the event relates to, if any.
+	SessionID string
+	// ClientIDs are the clients affected by the event. Empty for all clients.
+	ClientIDs []string
+	Time      time.Time
+	// Details are additional members of the event type, e.g. the changed claims
+	// or the credential_type and change_type of a credential change.
+	Details map[string]any
+}
+
+// EventListener receives the events of the OP. The context carries the issuer.
+// Listeners are called synchronously and should not block the request for long.
+type EventListener func(ctx context.Context, event Event)
+
+// WithEventListener adds a listener of the security events of the OP.
+func WithEventListener(listener EventListener) Option {
+	return func(o *Provider) error {
+		if listener == nil {
+			return errors.New("event listener must not be nil")
+		}
+		o.eventListeners = append(o.eventListeners, listener)
+		return nil
+	}
+}
+
+// EventListeners returns the listeners added by [WithEventListener].
+func (o *Provider) EventListeners() []EventListener {
+	return o.eventListeners
+}
+
+// EventPublisher passes the events of [PublishEvent] to its listeners.
+// It is implemented by the [Provider].
+type EventPublisher interface {
+	EventListeners() []EventListener
+}
+
+func eventPublisherFrom(v any) EventPublisher {
+	if p, ok := v.(EventPublisher); ok {
+		return p
+	}
+	return nil
+}
+
+// PublishEvent passes the event to the listeners of the publisher, e.g. the [Provider].
+// The time of the event defaults to now.
+// The context must carry the issuer, see [ContextWithIssuer].
+func PublishEvent(ctx context.Context, publisher EventPublisher, event Event) {
+	if publisher == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = Now(ctx)
+	}
+	for _, listener := range publisher.EventListeners() {
+		listener(ctx, event)
+	}
+}
+
+// publishSessionRevoked publishes an [EventSessionRevoked] per session of the clients.
+// Clients without session ID share one event, which is for all clients if no client is known.
+func publishSessionRevoked(ctx context.Context, publisher EventPublisher, userID string, sessions []ClientSession) {
+	if publisher == nil {
+		return
+	}
+	var (
+		order     []string
+		bySession = make(map[string][]string)
+	)
+	for _, session := range sessions {
+		if _, ok := bySession[session.SessionID]; !ok {
+			order = append(order, session.SessionID)
+		}
+		if session.ClientID != "" {
+			bySession[session.SessionID] = append(bySession[session.SessionID], session.ClientID)
+		} else if _, ok := bySession[session.SessionID]; !ok {
+			bySession[session.SessionID] = nil
+		}
+	}
+	for _, sessionID := range order {
+		PublishEvent(ctx, publisher, Event{
+			Type:      EventSessionRevoked,
+			Subject:   userID,
+			SessionID: sessionID,
+			ClientIDs: bySession[sessionID],
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package op
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishSessionRevoked(t *testing.T) {
+	var events []Event
+	provider := &Provider{}
+	require.NoError(t, WithEventListener(func(_ context.Context, event Event) {
+		events = append(events, event)
+	})(provider))
+
+	publishSessionRevoked(context.Background(), provider, "user", []ClientSession{
+		{ClientID: "a", SessionID: "s1"},
+		{ClientID: "b", SessionID: "s1"},
+		{ClientID: "c"},
+		{SessionID: "s2"},
+	})
+	require.Len(t, events, 3)
+	for _, event := range events {
+		assert.Equal(t, EventSessionRevoked, event.Type)
+		assert.Equal(t, "user", event.Subject)
+		assert.False(t, event.Time.IsZero())
+	}
+	assert.Equal(t, "s1", events[0].SessionID)
+	assert.Equal(t, []string{"a", "b"}, events[0].ClientIDs)
+	assert.Empty(t, events[1].SessionID)
+	assert.Equal(t, []string{"c"}, events[1].ClientIDs)
+	assert.Equal(t, "s2", events[2].SessionID)
+	assert.Empty(t, events[2].ClientIDs, "all clients")
+
+	assert.Error(t, WithEventListener(nil)(provider))
+}
@@ -0,0 +1,104 @@
+package op_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/example/server/storage"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/optest"
+)
+
+var goldenTime = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// goldenStorage signs with a static key and creates access tokens with a fixed ID.
+type goldenStorage struct {
+	*storage.Storage
+}
+
+func (goldenStorage) SigningKey(context.Context) (op.SigningKey, error) {
+	return optest.StaticSigningKey("golden"), nil
+}
+
+func (goldenStorage) CreateAccessToken(ctx context.Context, _ op.TokenRequest) (string, time.Time, error) {
+	return "access-token-1", op.Now(ctx).Add(5 * time.Minute), nil
+}
+
+type jwtAccessTokenClient struct {
+	*storage.Client
+}
+
+func (jwtAccessTokenClient) AccessTokenType() op.AccessTokenType { return op.AccessTokenTypeJWT }
+
+func goldenContext() context.Context {
+	ctx := op.ContextWithIssuer(context.Background(), testIssuer)
+	ctx = op.ContextWithClock(ctx, optest.FixedClock(goldenTime))
+	return op.ContextWithTokenIDSource(ctx, optest.SequentialTokenIDs("jti-"))
+}
+
+func TestGolden_tokenResponse(t *testing.T) {
+	s := goldenStorage{storage.NewStorage(storage.NewUserStore(testIssuer))}
+	provider, err := op.NewOpenIDProvider(testIssuer, testConfig, s,
+		op.WithAllowInsecure(),
+		op.WithIDTokenClaimsHook(func(_ context.Context, claims *oidc.IDTokenClaims, _ *op.ClaimsHookContext) error {
+			claims.Claims = map[string]any{"tenant": "acme"}
+			return nil
+		}),
+	)
+	require.NoError(t, err)
+	client := jwtAccessTokenClient{storage.WebClient("web", "secret", "https://example.com/callback")}
+	request := &op.DeviceAuthorizationState{
+		ClientID: "web",
+		Scopes:   []string{oidc.ScopeOpenID, oidc.ScopeProfile, oidc.ScopeEmail},
+		Subject:  "id1",
+		AuthTime: goldenTime.Add(-time.Minute),
+	}
+
+	resp, err := op.CreateTokenResponse(goldenContext(), request, client, provider, true, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(300), resp.ExpiresIn)
+	optest.GoldenJWT(t, "golden/id_token.txt", resp.IDToken)
+	optest.GoldenJWT(t, "golden/access_token.txt", resp.AccessToken)
+}
+
+func TestGolden_logoutToken(t *testing.T) {
+	s := goldenStorage{storage.NewStorage(storage.NewUserStore(testIssuer))}
+	token, err := op.CreateLogoutToken(goldenContext(), testIssuer, "id1", op.ClientSession{ClientID: "web", SessionID: "session-1"}, 0, s)
+	require.NoError(t, err)
+	optest.GoldenJWT(t, "golden/logout_token.txt", token)
+}
+
+func TestGolden_deterministic(t *testing.T) {
+	s := goldenStorage{storage.NewStorage(storage.NewUserStore(testIssuer))}
+	session := op.ClientSession{ClientID: "web", SessionID: "session-1"}
+	first, err := op.CreateLogoutToken(goldenContext(), testIssuer, "id1", session, 0, s)
+	require.NoError(t, err)
+	second, err := op.CreateLogoutToken(goldenContext(), testIssuer, "id1", session, 0, s)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	ids := optest.SequentialTokenIDs("jti-")
+	ctx := op.ContextWithTokenIDSource(context.Background(), ids)
+	assert.Equal(t, "jti-1", op.NewTokenID(ctx))
+	assert.Equal(t, "jti-2", op.NewTokenID(ctx))
+	assert.NotEqual(t, op.NewTokenID(context.Background()), op.NewTokenID(context.Background()))
+	assert.Equal(t, goldenTime, op.Now(op.ContextWithClock(context.Background(), optest.FixedClock(goldenTime))))
+}
+
+func TestWithClock(t *testing.T) {
+	_, err := op.NewProvider(testConfig, nil, op.StaticIssuer(testIssuer), op.WithClock(nil))
+	assert.Error(t, err)
+	_, err = op.NewProvider(testConfig, nil, op.StaticIssuer(testIssuer), op.WithTokenIDSource(nil))
+	assert.Error(t, err)
+
+	provider, err := op.NewProvider(testConfig, nil, op.StaticIssuer(testIssuer),
+		op.WithClock(optest.FixedClock(goldenTime)), op.WithTokenIDSource(optest.SequentialTokenIDs("jti-")))
+	require.NoError(t, err)
+	assert.Equal(t, goldenTime, provider.Clock()())
+	assert.Equal(t, "jti-1", provider.TokenIDSource()())
+}
@@ -0,0 +1,118 @@
+package op
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// GrantRequest describes a code exchange or refresh passed to a [GrantPolicy].
+type GrantRequest struct {
+	GrantType oidc.GrantType
+	Client    Client
+	// Request is the original auth context of the grant:
+	// the [AuthRequest] of the code or the [RefreshTokenRequest] of the refresh token.
+	// The auth time, AMR and session (see [SessionRequest]) can be read from it.
+	Request TokenRequest
+	// RemoteAddr is the network address of the client, as set by [http.Request.RemoteAddr].
+	// Proxies have to be accounted for by the policy, e.g. with the X-Forwarded-For header.
+	RemoteAddr string
+	// UserAgent of the token request.
+	UserAgent string
+	Header    http.Header
+	// TLS is the connection state of the token request, nil for plain HTTP connections.
+	// The client certificate of mTLS is in TLS.PeerCertificates.
+	TLS *tls.ConnectionState
+}
+
+// GrantPolicy is called at the code exchange and refresh, after the client and grant were validated
+// and before any token is issued.
+// It allows to reject suspicious requests, e.g. a refresh from an impossible location
+// or with a changed user agent.
+// A returned [oidc.Error] is sent to the client as is, any other error results in invalid_grant.
+type GrantPolicy func(ctx context.Context, request *GrantRequest) error
+
+// WithGrantPolicy adds policies, which are called in order at every code exchange and refresh.
+// The first error rejects the request.
+func WithGrantPolicy(policies ...GrantPolicy) Option {
+	return func(o *Provider) error {
+		o.grantPolicies = append(o.grantPolicies, policies...)
+		return nil
+	}
+}
+
+// GrantPolicies returns the policies added by [WithGrantPolicy].
+func (o *Provider) GrantPolicies() []GrantPolicy {
+	return o.grantPolicies
+}
+
+// grantPoliciesProvider is implemented by the [Provider]
+// to pass the policies of [WithGrantPolicy] to the code exchange and refresh.
+type grantPoliciesProvider interface {
+	GrantPolicies() []GrantPolicy
+}
+
+func grantPoliciesFrom(v any) []GrantPolicy {
+	if p, ok := v.(grantPoliciesProvider); ok {
+		return p.GrantPolicies()
+	}
+	return nil
+}
+
+// checkGrantPolicies calls the policies of the provider with the grant request,
+// after the client was checked against its [ClientTypePolicy].
+func checkGrantPolicies(ctx context.Context, provider Exchanger, request *GrantRequest) error {
+	if policy, ok := clientTypePolicyFrom(provider, request.Client); ok {
+		if err := validateClientSecretPolicy(policy, request.Client); err != nil {
+			return err
+		}
+	}
+	policies := grantPoliciesFrom(provider)
+	if len(policies) == 0 {
+		return nil
+	}
+	ctx, span := Tracer.Start(ctx, "checkGrantPolicies")
+	defer span.End()
+
+	for _, policy := range policies {
+		err := policy(ctx, request)
+		if err == nil {
+			continue
+		}
+		slog.InfoContext(ctx, "grant rejected by policy", "grant_type", request.GrantType, "client_id", request.Client.GetID(), "error", err)
+		var oidcErr *oidc.Error
+		if errors.As(err, &oidcErr) {
+			return err
+		}
+		return oidc.ErrInvalidGrant().WithParent(err)
+	}
+	return nil
+}
+
+func newGrantRequest(r *http.Request, grantType oidc.GrantType, client Client, request TokenRequest) *GrantRequest {
+	return &GrantRequest{
+		GrantType:  grantType,
+		Client:     client,
+		Request:    request,
+		RemoteAddr: r.RemoteAddr,
+		UserAgent:  r.UserAgent(),
+		Header:     r.Header,
+		TLS:        r.TLS,
+	}
+}
+
+func newGrantRequestFrom[T any](r *ClientRequest[T], grantType oidc.GrantType, request TokenRequest) *GrantRequest {
+	return &GrantRequest{
+		GrantType:  grantType,
+		Client:     r.Client,
+		Request:    request,
+		RemoteAddr: r.RemoteAddr,
+		UserAgent:  r.Header.Get("User-Agent"),
+		Header:     r.Header,
+		TLS:        r.TLS,
+	}
+}
@@ -0,0 +1,61 @@
+package op_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/optest"
+)
+
+func TestWithGrantPolicy(t *testing.T) {
+	var (
+		requests []*op.GrantRequest
+		reject   error
+	)
+	s := optest.New(t, optest.WithProviderOptions(op.WithGrantPolicy(func(ctx context.Context, request *op.GrantRequest) error {
+		requests = append(requests, request)
+		if request.GrantType == oidc.GrantTypeRefreshToken {
+			return reject
+		}
+		return nil
+	})))
+	relyingParty := s.RelyingParty(t)
+	tokens := s.CodeFlow(t, relyingParty)
+
+	require.Len(t, requests, 1)
+	assert.Equal(t, oidc.GrantTypeCode, requests[0].GrantType)
+	assert.Equal(t, optest.WebClientID, requests[0].Client.GetID())
+	_, ok := requests[0].Request.(op.AuthRequest)
+	assert.True(t, ok, "request of the code is the auth request")
+	assert.NotEmpty(t, requests[0].RemoteAddr)
+	assert.NotEmpty(t, requests[0].UserAgent)
+
+	refreshed, err := rp.RefreshTokens[*oidc.IDTokenClaims](context.Background(), relyingParty, tokens.RefreshToken, "", "")
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+	_, ok = requests[1].Request.(op.RefreshTokenRequest)
+	assert.True(t, ok, "request of the refresh is the refresh token request")
+
+	tests := []struct {
+		name    string
+		reject  error
+		wantErr error
+	}{
+		{"plain error", errors.New("user agent changed"), &oidc.Error{ErrorType: oidc.InvalidGrant}},
+		{"oidc error", oidc.ErrAccessDenied(), &oidc.Error{ErrorType: oidc.AccessDenied}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reject = tt.reject
+			_, err := rp.RefreshTokens[*oidc.IDTokenClaims](context.Background(), relyingParty, refreshed.RefreshToken, "", "")
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
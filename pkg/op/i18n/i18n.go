@@ -0,0 +1,178 @@
+// Package i18n provides message catalogs for the pages rendered to the user,
+// such as login, consent, error, logout and device authorization pages.
+//
+// The language is negotiated with a [language.Matcher], preferring the ui_locales
+// of the request over the Accept-Language header.
+// The built-in catalog of [NewDefaultCatalog] can be extended or overridden with [Catalog.Add] and [Catalog.AddFS].
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"maps"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/language"
+)
+
+//go:embed messages/*.json
+var defaultMessages embed.FS
+
+// Catalog holds the messages of all supported languages.
+// It is safe for concurrent use.
+type Catalog struct {
+	fallback language.Tag
+
+	mu       sync.RWMutex
+	tags     []language.Tag
+	messages map[language.Tag]map[string]string
+	matcher  language.Matcher
+}
+
+// NewCatalog creates an empty catalog.
+// The fallback language is used if none of the preferred languages is supported
+// and for messages missing in the matched language.
+func NewCatalog(fallback language.Tag) *Catalog {
+	return &Catalog{
+		fallback: fallback,
+		tags:     []language.Tag{fallback},
+		messages: map[language.Tag]map[string]string{fallback: {}},
+		matcher:  language.NewMatcher([]language.Tag{fallback}),
+	}
+}
+
+// NewDefaultCatalog creates a catalog with the built-in messages
+// in English (fallback) and German.
+func NewDefaultCatalog() *Catalog {
+	c := NewCatalog(language.English)
+	if err := c.AddFS(defaultMessages, "messages"); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Add adds the messages of the language to the catalog.
+// Existing messages with the same key are overridden.
+func (c *Catalog) Add(tag language.Tag, messages map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	existing, ok := c.messages[tag]
+	if !ok {
+		existing = make(map[string]string, len(messages))
+		c.messages[tag] = existing
+		c.tags = append(c.tags, tag)
+		c.matcher = language.NewMatcher(c.tags)
+	}
+	maps.Copy(existing, messages)
+}
+
+// AddFS adds the messages of all JSON files in the directory of fsys.
+// Each file must be named after the BCP 47 language tag (e.g. de-CH.json)
+// and contain a flat object of message keys and texts.
+func (c *Catalog) AddFS(fsys fs.FS, dir string) error {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		tag, err := language.Parse(strings.TrimSuffix(path.Base(file), ".json"))
+		if err != nil {
+			return fmt.Errorf("i18n: file %s: %w", file, err)
+		}
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		messages := make(map[string]string)
+		if err = json.Unmarshal(data, &messages); err != nil {
+			return fmt.Errorf("i18n: file %s: %w", file, err)
+		}
+		c.Add(tag, messages)
+	}
+	return nil
+}
+
+// Tags returns the languages of the catalog, starting with the fallback.
+func (c *Catalog) Tags() []language.Tag {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]language.Tag(nil), c.tags...)
+}
+
+// Match returns the supported language best matching the preferred languages.
+func (c *Catalog) Match(preferred ...language.Tag) language.Tag {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, index, confidence := c.matcher.Match(preferred...)
+	if confidence == language.No {
+		return c.fallback
+	}
+	return c.tags[index]
+}
+
+// Localizer returns the [Localizer] of the supported language best matching the preferred languages.
+func (c *Catalog) Localizer(preferred ...language.Tag) *Localizer {
+	return &Localizer{catalog: c, Tag: c.Match(preferred...)}
+}
+
+// FromRequest returns the [Localizer] for the user of the request.
+// The uiLocales, typically of the auth request, are preferred over the Accept-Language header.
+func (c *Catalog) FromRequest(r *http.Request, uiLocales []language.Tag) *Localizer {
+	preferred := append([]language.Tag(nil), uiLocales...)
+	if accept, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language")); err == nil {
+		preferred = append(preferred, accept...)
+	}
+	return c.Localizer(preferred...)
+}
+
+func (c *Catalog) message(tag language.Tag, key string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if msg, ok := c.messages[tag][key]; ok {
+		return msg, true
+	}
+	msg, ok := c.messages[c.fallback][key]
+	return msg, ok
+}
+
+// Localizer translates messages into a single language.
+type Localizer struct {
+	catalog *Catalog
+	Tag     language.Tag
+}
+
+// T returns the message of the key, formatted with the args using [fmt.Sprintf].
+// Messages missing in the language are taken from the fallback language,
+// unknown keys are returned as is.
+func (l *Localizer) T(key string, args ...any) string {
+	msg, ok := l.catalog.message(l.Tag, key)
+	if !ok {
+		return key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Lang returns the BCP 47 tag of the language, for the lang attribute of html pages.
+func (l *Localizer) Lang() string {
+	return l.Tag.String()
+}
+
+// FuncMap returns the template functions "t" ([Localizer.T]) and "lang" ([Localizer.Lang]).
+// Templates using them must be parsed with a placeholder FuncMap, e.g. of a Localizer of any catalog,
+// and be cloned with the FuncMap of the request's Localizer before execution.
+func (l *Localizer) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"t":    l.T,
+		"lang": l.Lang,
+	}
+}
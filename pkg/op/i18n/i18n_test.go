@@ -0,0 +1,75 @@
+package i18n_test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+
+	"github.com/zitadel/oidc/v3/pkg/op/i18n"
+)
+
+func TestCatalog_Match(t *testing.T) {
+	catalog := i18n.NewDefaultCatalog()
+	tests := []struct {
+		name      string
+		preferred []language.Tag
+		want      language.Tag
+	}{
+		{"none", nil, language.English},
+		{"unsupported", []language.Tag{language.Japanese}, language.English},
+		{"exact", []language.Tag{language.German}, language.German},
+		{"region", []language.Tag{language.MustParse("de-CH")}, language.German},
+		{"order", []language.Tag{language.Japanese, language.German, language.English}, language.German},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, catalog.Match(tt.preferred...))
+		})
+	}
+}
+
+func TestLocalizer_T(t *testing.T) {
+	catalog := i18n.NewCatalog(language.English)
+	catalog.Add(language.English, map[string]string{
+		"greeting": "Hello %s",
+		"only.en":  "English",
+	})
+	catalog.Add(language.German, map[string]string{"greeting": "Hallo %s"})
+
+	de := catalog.Localizer(language.German)
+	assert.Equal(t, "Hallo Alice", de.T("greeting", "Alice"))
+	assert.Equal(t, "English", de.T("only.en"), "fallback language")
+	assert.Equal(t, "unknown.key", de.T("unknown.key"))
+	assert.Equal(t, "de", de.Lang())
+}
+
+func TestCatalog_AddFS(t *testing.T) {
+	catalog := i18n.NewDefaultCatalog()
+	err := catalog.AddFS(fstest.MapFS{
+		"texts/de.json": {Data: []byte(`{"login.title": "Einloggen"}`)},
+		"texts/fr.json": {Data: []byte(`{"login.title": "Connexion"}`)},
+	}, "texts")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Einloggen", catalog.Localizer(language.German).T("login.title"), "override")
+	assert.Equal(t, "Passwort", catalog.Localizer(language.German).T("login.password"), "kept")
+	assert.Equal(t, "Connexion", catalog.Localizer(language.French).T("login.title"), "added")
+	assert.Contains(t, catalog.Tags(), language.French)
+
+	err = catalog.AddFS(fstest.MapFS{"texts/invalid!.json": {Data: []byte(`{}`)}}, "texts")
+	assert.Error(t, err)
+}
+
+func TestCatalog_FromRequest(t *testing.T) {
+	catalog := i18n.NewDefaultCatalog()
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Language", "de-DE,de;q=0.9,en;q=0.8")
+
+	assert.Equal(t, language.German, catalog.FromRequest(r, nil).Tag, "Accept-Language")
+	assert.Equal(t, language.English, catalog.FromRequest(r, []language.Tag{language.English}).Tag, "ui_locales preferred")
+	assert.Equal(t, language.German, catalog.FromRequest(r, []language.Tag{language.Japanese}).Tag, "unsupported ui_locales")
+}
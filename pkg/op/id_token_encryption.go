@@ -0,0 +1,52 @@
+package op
+
+import (
+	"context"
+
+	jose "github.com/go-jose/go-jose/v4"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// HasIDTokenEncryptedResponse is an optional interface of a [Client]
+// registered with the id_token_encrypted_response_alg and id_token_encrypted_response_enc metadata.
+// ID tokens of the client are signed and then encrypted (nested JWT) with the encryption key
+// of its jwks or jwks_uri, see [HasJWKS], [HasJWKSURI] and [ClientKeySetCache.EncryptionKey].
+// An empty algorithm disables encryption, an empty enc defaults to A128CBC-HS256.
+type HasIDTokenEncryptedResponse interface {
+	IDTokenEncryptedResponseAlg() jose.KeyAlgorithm
+	IDTokenEncryptedResponseEnc() jose.ContentEncryption
+}
+
+// encryptIDToken encrypts the signed ID token, if requested by the client.
+// Without a cache, only keys registered by value are used.
+func encryptIDToken(ctx context.Context, idToken string, client Client, clientKeys *ClientKeySetCache) (string, error) {
+	c, ok := client.(HasIDTokenEncryptedResponse)
+	if !ok || c.IDTokenEncryptedResponseAlg() == "" {
+		return idToken, nil
+	}
+	ctx, span := Tracer.Start(ctx, "encryptIDToken")
+	defer span.End()
+
+	alg, enc := c.IDTokenEncryptedResponseAlg(), c.IDTokenEncryptedResponseEnc()
+	if enc == "" {
+		enc = jose.A128CBC_HS256
+	}
+	if clientKeys == nil {
+		clientKeys = NewClientKeySetCache()
+	}
+	key, err := clientKeys.EncryptionKey(ctx, client, alg)
+	if err != nil {
+		return "", oidc.ErrServerError().WithDescription("no encryption key for id_token_encrypted_response_alg %s", alg).WithParent(err)
+	}
+	encrypter, err := jose.NewEncrypter(enc, jose.Recipient{Algorithm: alg, Key: key.Key, KeyID: key.KeyID},
+		(&jose.EncrypterOptions{}).WithContentType("JWT"))
+	if err != nil {
+		return "", err
+	}
+	object, err := encrypter.Encrypt([]byte(idToken))
+	if err != nil {
+		return "", err
+	}
+	return object.CompactSerialize()
+}
@@ -0,0 +1,80 @@
+package op
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// JTICache keeps track of the jti (JWT ID) of assertions,
+// to reject replays. See [WithJTICache].
+//
+// Deployments with multiple instances of the OP
+// should use a cache shared between the instances.
+type JTICache interface {
+	// UseJTI marks the jti of the issuer as used until expiration.
+	// It must return false if the jti was already used before.
+	UseJTI(ctx context.Context, issuer, jti string, expiration time.Time) (ok bool, err error)
+}
+
+// MemoryJTICache is an in-memory [JTICache],
+// suitable for deployments with a single instance of the OP.
+type MemoryJTICache struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+	expiry  jtiExpiryHeap
+	now     func() time.Time
+}
+
+// NewMemoryJTICache creates an empty [MemoryJTICache].
+func NewMemoryJTICache() *MemoryJTICache {
+	return &MemoryJTICache{
+		entries: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// UseJTI implements [JTICache].
+// Expired entries are removed on each call, in the order of their expiration,
+// so only the expired entries are visited.
+func (c *MemoryJTICache) UseJTI(_ context.Context, issuer, jti string, expiration time.Time) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for len(c.expiry) > 0 && now.After(c.expiry[0].expiration) {
+		e := heap.Pop(&c.expiry).(jtiExpiry)
+		if exp, ok := c.entries[e.key]; ok && exp.Equal(e.expiration) {
+			delete(c.entries, e.key)
+		}
+	}
+	key := issuer + ":" + jti
+	if _, ok := c.entries[key]; ok {
+		return false, nil
+	}
+	c.entries[key] = expiration
+	heap.Push(&c.expiry, jtiExpiry{key: key, expiration: expiration})
+	return true, nil
+}
+
+type jtiExpiry struct {
+	key        string
+	expiration time.Time
+}
+
+// jtiExpiryHeap is a min-heap of the entries of [MemoryJTICache] by expiration.
+type jtiExpiryHeap []jtiExpiry
+
+func (h jtiExpiryHeap) Len() int           { return len(h) }
+func (h jtiExpiryHeap) Less(i, j int) bool { return h[i].expiration.Before(h[j].expiration) }
+func (h jtiExpiryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *jtiExpiryHeap) Push(x any) { *h = append(*h, x.(jtiExpiry)) }
+
+func (h *jtiExpiryHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,305 @@
+package op
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// KeyIDFunc returns the key ID (kid) of a key whose ID() is empty.
+// The key is the public key of a [Key] or the private key of a [SigningKey],
+// so both must result in the same ID.
+type KeyIDFunc func(key any) (string, error)
+
+// ThumbprintKeyID is the default [KeyIDFunc], which returns the base64url encoded
+// SHA-256 JWK thumbprint (RFC 7638) of the public key.
+func ThumbprintKeyID(key any) (string, error) {
+	jwk := jose.JSONWebKey{Key: key}
+	if !jwk.IsPublic() {
+		jwk = jwk.Public()
+	}
+	if !jwk.Valid() {
+		return "", errors.New("key id: unsupported key type")
+	}
+	thumbprint, err := jwk.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
+
+type keyIDFuncKey struct{}
+
+// ContextWithKeyIDFunc returns a context, which derives the ID of keys
+// without ID by fn, when publishing the keys and signing tokens with them.
+// It is set for all requests of the OP by [WithKeyIDFunc]
+// and can be used when calling the functions of this package directly.
+func ContextWithKeyIDFunc(ctx context.Context, fn KeyIDFunc) context.Context {
+	return context.WithValue(ctx, keyIDFuncKey{}, fn)
+}
+
+// keyID returns id, or derives the ID of the key by the [KeyIDFunc] of the context
+// if id is empty. It defaults to [ThumbprintKeyID].
+func keyID(ctx context.Context, id string, key any) (string, error) {
+	if id != "" {
+		return id, nil
+	}
+	fn, ok := ctx.Value(keyIDFuncKey{}).(KeyIDFunc)
+	if !ok || fn == nil {
+		fn = ThumbprintKeyID
+	}
+	return fn(key)
+}
+
+// SigningKeyID returns the ID of the signing key as used in the kid header of the tokens of the OP.
+// It allows to sign other JWTs of the issuer, e.g. Security Event Tokens, with the same key.
+func SigningKeyID(ctx context.Context, key SigningKey) (string, error) {
+	return keyID(ctx, key.ID(), key.Key())
+}
+
+// WithKeyIDFunc sets the function deriving the ID of keys without ID.
+// Defaults to [ThumbprintKeyID].
+func WithKeyIDFunc(fn KeyIDFunc) Option {
+	return func(o *Provider) error {
+		if fn == nil {
+			return errors.New("key id function must not be nil")
+		}
+		o.keyIDFunc = fn
+		return nil
+	}
+}
+
+// KeyIDFunc returns the function set by [WithKeyIDFunc].
+func (o *Provider) KeyIDFunc() KeyIDFunc {
+	return o.keyIDFunc
+}
+
+type keyIDFuncProvider interface {
+	KeyIDFunc() KeyIDFunc
+}
+
+// keyIDInterceptor sets the key ID function of the provider on the context of the requests.
+func keyIDInterceptor(v any) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		p, ok := v.(keyIDFuncProvider)
+		if !ok || p.KeyIDFunc() == nil {
+			return next
+		}
+		fn := p.KeyIDFunc()
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(ContextWithKeyIDFunc(r.Context(), fn)))
+		})
+	}
+}
+
+// defaultKeyAlgorithm returns the algorithm announced for a public key without algorithm.
+func defaultKeyAlgorithm(key any) jose.SignatureAlgorithm {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return jose.RS256
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return jose.ES256
+		case elliptic.P384():
+			return jose.ES384
+		case elliptic.P521():
+			return jose.ES512
+		}
+	case ed25519.PublicKey:
+		return jose.EdDSA
+	}
+	return ""
+}
+
+// sortKeys orders the keys by use, algorithm and ID,
+// so the key set does not change between requests with the same keys.
+func sortKeys(keys []jose.JSONWebKey) {
+	sort.SliceStable(keys, func(i, j int) bool {
+		if keys[i].Use != keys[j].Use {
+			return keys[i].Use < keys[j].Use
+		}
+		if keys[i].Algorithm != keys[j].Algorithm {
+			return keys[i].Algorithm < keys[j].Algorithm
+		}
+		return keys[i].KeyID < keys[j].KeyID
+	})
+}
+
+// maxRetiredKeyIssuers limits the issuers whose keys are remembered by the [keySetPolicy],
+// as the issuer may be derived from the Host header of the request, see [IssuerFromHost].
+const maxRetiredKeyIssuers = 256
+
+// keySetPolicy keeps publishing keys after they were removed from the Storage
+// for the grace period, and announces the max age of the key set.
+type keySetPolicy struct {
+	grace  time.Duration
+	maxAge time.Duration
+
+	mu      sync.Mutex
+	retired map[string]*issuerKeys // by issuer
+}
+
+type issuerKeys struct {
+	keys     map[string]*retiredKey // by kid
+	lastSeen time.Time
+}
+
+type retiredKey struct {
+	key      jose.JSONWebKey
+	lastSeen time.Time
+}
+
+// issuerKeys returns the remembered keys of the issuer. If the issuers are exceeding
+// [maxRetiredKeyIssuers], the issuers not seen during the grace period are removed,
+// or else the least recently seen issuer.
+func (p *keySetPolicy) issuerKeys(issuer string, now time.Time) *issuerKeys {
+	if p.retired == nil {
+		p.retired = make(map[string]*issuerKeys)
+	}
+	known, ok := p.retired[issuer]
+	if !ok {
+		if len(p.retired) >= maxRetiredKeyIssuers {
+			p.removeIssuers(now)
+		}
+		known = &issuerKeys{keys: make(map[string]*retiredKey)}
+		p.retired[issuer] = known
+	}
+	known.lastSeen = now
+	return known
+}
+
+func (p *keySetPolicy) removeIssuers(now time.Time) {
+	var (
+		oldest     string
+		oldestSeen time.Time
+	)
+	for issuer, known := range p.retired {
+		if now.Sub(known.lastSeen) > p.grace {
+			delete(p.retired, issuer)
+			continue
+		}
+		if oldest == "" || known.lastSeen.Before(oldestSeen) {
+			oldest, oldestSeen = issuer, known.lastSeen
+		}
+	}
+	if len(p.retired) >= maxRetiredKeyIssuers {
+		delete(p.retired, oldest)
+	}
+}
+
+// keySet appends the retired keys of the issuer, which are still in their grace period,
+// to the current keys. Each part is sorted by [sortKeys].
+func (p *keySetPolicy) keySet(issuer string, current []jose.JSONWebKey, now time.Time) []jose.JSONWebKey {
+	sortKeys(current)
+	if p == nil || p.grace <= 0 {
+		return current
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	known := p.issuerKeys(issuer, now).keys
+	ids := make(map[string]bool, len(current))
+	for _, key := range current {
+		ids[key.KeyID] = true
+		known[key.KeyID] = &retiredKey{key: key, lastSeen: now}
+	}
+	var retired []jose.JSONWebKey
+	for kid, key := range known {
+		if ids[kid] {
+			continue
+		}
+		if now.Sub(key.lastSeen) > p.grace {
+			delete(known, kid)
+			continue
+		}
+		retired = append(retired, key.key)
+	}
+	sortKeys(retired)
+	return append(current, retired...)
+}
+
+// setCacheControl announces the max age of the key set, if set.
+func (p *keySetPolicy) setCacheControl(header http.Header) {
+	if p == nil || p.maxAge <= 0 {
+		return
+	}
+	header.Set("Cache-Control", "public, max-age="+strconv.Itoa(int(p.maxAge.Seconds())))
+}
+
+// WithRetiredKeys keeps publishing keys, which were removed from the KeySet of the Storage,
+// for the grace period. Relying parties caching the key set can still verify
+// the tokens signed by the retired key during this period.
+// The grace period should exceed the max age set by [WithKeySetMaxAge].
+// Retired keys are remembered in memory, so each instance of the OP
+// must have published a key before it retires.
+// They are remembered for up to 256 issuers, which suffices for an OP
+// serving multiple issuers by [IssuerFromHost].
+func WithRetiredKeys(grace time.Duration) Option {
+	return func(o *Provider) error {
+		if grace <= 0 {
+			return errors.New("grace period of retired keys must be positive")
+		}
+		o.keySets.grace = grace
+		return nil
+	}
+}
+
+// WithKeySetMaxAge sets Cache-Control: public, max-age on the responses of the
+// jwks_uri and signed_jwks_uri, so relying parties refresh their cached keys
+// before retired keys are removed.
+func WithKeySetMaxAge(maxAge time.Duration) Option {
+	return func(o *Provider) error {
+		if maxAge <= 0 {
+			return errors.New("max age of the key set must be positive")
+		}
+		o.keySets.maxAge = maxAge
+		return nil
+	}
+}
+
+type keySetPolicyProvider interface {
+	keySetPolicy() *keySetPolicy
+}
+
+func (o *Provider) keySetPolicy() *keySetPolicy {
+	return o.keySets
+}
+
+func keySetPolicyFrom(v any) *keySetPolicy {
+	if p, ok := v.(keySetPolicyProvider); ok {
+		return p.keySetPolicy()
+	}
+	return nil
+}
+
+// publishedKeySet returns the JSON Web Key Set of the keys and the retired keys of the policy.
+func publishedKeySet(ctx context.Context, keys []Key, policy *keySetPolicy) (*jose.JSONWebKeySet, error) {
+	keySet, err := jsonWebKeySet(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	keySet.Keys = policy.keySet(IssuerFromContext(ctx), keySet.Keys, time.Now())
+	return keySet, nil
+}
+
+// keyUse defaults the use of keys to signature.
+func keyUse(use string) string {
+	if use == "" {
+		return oidc.KeyUseSignature
+	}
+	return use
+}
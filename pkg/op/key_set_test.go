@@ -0,0 +1,121 @@
+package op
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testKey struct {
+	id  string
+	alg jose.SignatureAlgorithm
+	use string
+	key any
+}
+
+func (k testKey) ID() string                         { return k.id }
+func (k testKey) Algorithm() jose.SignatureAlgorithm { return k.alg }
+func (k testKey) Use() string                        { return k.use }
+func (k testKey) Key() any                           { return k.key }
+
+func TestThumbprintKeyID(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	fromPublic, err := ThumbprintKeyID(publicKey)
+	require.NoError(t, err)
+	fromPrivate, err := ThumbprintKeyID(privateKey)
+	require.NoError(t, err)
+	assert.Equal(t, fromPublic, fromPrivate)
+	assert.Len(t, fromPublic, 43)
+
+	_, err = ThumbprintKeyID("not a key")
+	assert.Error(t, err)
+}
+
+func TestJSONWebKeySet_defaults(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	keySet, err := jsonWebKeySet(context.Background(), []Key{
+		testKey{key: &ecKey.PublicKey},
+		testKey{id: "ed", alg: jose.EdDSA, use: "sig", key: edKey},
+	})
+	require.NoError(t, err)
+	require.Len(t, keySet.Keys, 2)
+	wantKID, err := ThumbprintKeyID(&ecKey.PublicKey)
+	require.NoError(t, err)
+	assert.Equal(t, wantKID, keySet.Keys[0].KeyID)
+	assert.Equal(t, string(jose.ES384), keySet.Keys[0].Algorithm)
+	assert.Equal(t, "sig", keySet.Keys[0].Use)
+	assert.Equal(t, "ed", keySet.Keys[1].KeyID)
+
+	ctx := ContextWithKeyIDFunc(context.Background(), func(any) (string, error) { return "custom", nil })
+	keySet, err = jsonWebKeySet(ctx, []Key{testKey{key: &ecKey.PublicKey}})
+	require.NoError(t, err)
+	assert.Equal(t, "custom", keySet.Keys[0].KeyID)
+}
+
+func TestKeySetPolicy(t *testing.T) {
+	jwk := func(kid, alg string) jose.JSONWebKey {
+		return jose.JSONWebKey{KeyID: kid, Algorithm: alg, Use: "sig"}
+	}
+	kids := func(keys []jose.JSONWebKey) []string {
+		ids := make([]string, len(keys))
+		for i, key := range keys {
+			ids[i] = key.KeyID
+		}
+		return ids
+	}
+	now := time.Unix(1700000000, 0)
+
+	var disabled *keySetPolicy
+	assert.Equal(t, []string{"b", "c", "a"}, kids(disabled.keySet("issuer", []jose.JSONWebKey{jwk("a", "RS256"), jwk("c", "ES256"), jwk("b", "ES256")}, now)),
+		"sorted by alg and kid")
+
+	policy := &keySetPolicy{grace: time.Hour}
+	assert.Equal(t, []string{"a", "b"}, kids(policy.keySet("issuer", []jose.JSONWebKey{jwk("b", "RS256"), jwk("a", "RS256")}, now)))
+	assert.Equal(t, []string{"x"}, kids(policy.keySet("other", []jose.JSONWebKey{jwk("x", "RS256")}, now)), "separate issuer")
+
+	// a was rotated out, but is published for the grace period after the current keys
+	assert.Equal(t, []string{"b", "c", "a"}, kids(policy.keySet("issuer", []jose.JSONWebKey{jwk("c", "RS256"), jwk("b", "RS256")}, now.Add(time.Minute))))
+	assert.Equal(t, []string{"b", "c", "a"}, kids(policy.keySet("issuer", []jose.JSONWebKey{jwk("c", "RS256"), jwk("b", "RS256")}, now.Add(time.Hour))))
+	assert.Equal(t, []string{"b", "c"}, kids(policy.keySet("issuer", []jose.JSONWebKey{jwk("c", "RS256"), jwk("b", "RS256")}, now.Add(time.Hour+time.Second))))
+}
+
+func TestKeySetPolicy_issuers(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	current := []jose.JSONWebKey{{KeyID: "a", Algorithm: "RS256", Use: "sig"}}
+	policy := &keySetPolicy{grace: time.Hour}
+	policy.keySet("issuer", current, now)
+	for i := range 2 * maxRetiredKeyIssuers {
+		policy.keySet("https://"+strconv.Itoa(i)+".example.com", current, now.Add(time.Second))
+	}
+	assert.Len(t, policy.retired, maxRetiredKeyIssuers)
+	assert.NotContains(t, policy.retired, "issuer", "least recently seen issuer removed")
+
+	policy.keySet("issuer", current, now.Add(2*time.Hour))
+	assert.Len(t, policy.retired, 1, "issuers not seen during the grace period removed")
+}
+
+func TestKeySetPolicy_setCacheControl(t *testing.T) {
+	rec := httptest.NewRecorder()
+	(&keySetPolicy{maxAge: 10 * time.Minute}).setCacheControl(rec.Header())
+	assert.Equal(t, "public, max-age=600", rec.Header().Get("Cache-Control"))
+
+	rec = httptest.NewRecorder()
+	(*keySetPolicy)(nil).setCacheControl(rec.Header())
+	assert.Empty(t, rec.Header().Get("Cache-Control"))
+}
@@ -2,10 +2,14 @@ package op
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/x509"
 	"net/http"
+	"time"
 
 	jose "github.com/go-jose/go-jose/v4"
 
+	"github.com/zitadel/oidc/v3/pkg/crypto"
 	httphelper "github.com/zitadel/oidc/v3/pkg/http"
 )
 
@@ -13,34 +17,193 @@ type KeyProvider interface {
 	KeySet(context.Context) ([]Key, error)
 }
 
-func keysHandler(k KeyProvider) func(http.ResponseWriter, *http.Request) {
+// CertificateKey is an optional interface that may be implemented by a [Key]
+// to publish its X.509 certificate chain in the JSON Web Key Set,
+// as x5c and x5t#S256 parameters (RFC 7517 section 4.7 and 4.9).
+type CertificateKey interface {
+	Key
+	// Certificates returns the chain, starting with the certificate of the key,
+	// each following certificate certifying the previous one.
+	Certificates() []*x509.Certificate
+}
+
+func keysHandler(o OpenIDProvider) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		Keys(w, r, k)
+		writeKeys(w, r, o.Storage(), keySetPolicyFrom(o))
 	}
 }
 
 func Keys(w http.ResponseWriter, r *http.Request, k KeyProvider) {
+	writeKeys(w, r, k, nil)
+}
+
+func writeKeys(w http.ResponseWriter, r *http.Request, k KeyProvider, policy *keySetPolicy) {
 	ctx, span := Tracer.Start(r.Context(), "Keys")
 	r = r.WithContext(ctx)
 	defer span.End()
 
-	keySet, err := k.KeySet(r.Context())
+	storageCtx, cancel := storageContext(r.Context())
+	defer cancel()
+	keys, err := k.KeySet(storageCtx)
 	if err != nil {
 		httphelper.MarshalJSONWithStatus(w, err, http.StatusInternalServerError)
 		return
 	}
-	httphelper.MarshalJSON(w, jsonWebKeySet(keySet))
+	keySet, err := publishedKeySet(ctx, keys, policy)
+	if err != nil {
+		httphelper.MarshalJSONWithStatus(w, err, http.StatusInternalServerError)
+		return
+	}
+	policy.setCacheControl(w.Header())
+	httphelper.MarshalJSON(w, keySet)
 }
 
-func jsonWebKeySet(keys []Key) *jose.JSONWebKeySet {
+// jsonWebKeySet returns the JSON Web Key Set of the keys.
+// Keys without ID are identified by the [KeyIDFunc] of the context,
+// the use defaults to sig and the algorithm to the one of the key type.
+func jsonWebKeySet(ctx context.Context, keys []Key) (*jose.JSONWebKeySet, error) {
 	webKeys := make([]jose.JSONWebKey, len(keys))
 	for i, key := range keys {
+		publicKey := key.Key()
+		kid, err := keyID(ctx, key.ID(), publicKey)
+		if err != nil {
+			return nil, err
+		}
+		alg := key.Algorithm()
+		if alg == "" {
+			alg = defaultKeyAlgorithm(publicKey)
+		}
 		webKeys[i] = jose.JSONWebKey{
-			KeyID:     key.ID(),
-			Algorithm: string(key.Algorithm()),
-			Use:       key.Use(),
-			Key:       key.Key(),
+			KeyID:     kid,
+			Algorithm: string(alg),
+			Use:       keyUse(key.Use()),
+			Key:       publicKey,
+		}
+		if certKey, ok := key.(CertificateKey); ok {
+			if chain := certKey.Certificates(); len(chain) > 0 {
+				thumbprint := sha256.Sum256(chain[0].Raw)
+				webKeys[i].Certificates = chain
+				webKeys[i].CertificateThumbprintSHA256 = thumbprint[:]
+			}
+		}
+	}
+	return &jose.JSONWebKeySet{Keys: webKeys}, nil
+}
+
+// SignedKeyProvider provides the keys and the signing key of a signed JSON Web Key Set.
+type SignedKeyProvider interface {
+	KeyProvider
+	SigningKey(context.Context) (SigningKey, error)
+}
+
+const (
+	// SignedKeySetType is the typ header of signed JSON Web Key Sets.
+	SignedKeySetType = "jwk-set+jwt"
+	// SignedKeySetContentType is the content type of signed JSON Web Key Sets.
+	SignedKeySetContentType = "application/jwk-set+jwt"
+)
+
+// signedKeySetLifetime is the exp of signed JSON Web Key Sets,
+// so they are refreshed after key rotations.
+const signedKeySetLifetime = 24 * time.Hour
+
+// signedKeySetClaims are the claims of a signed JSON Web Key Set.
+// The issuer is also the subject, as the keys are its own.
+type signedKeySetClaims struct {
+	Issuer   string            `json:"iss"`
+	Subject  string            `json:"sub"`
+	IssuedAt int64             `json:"iat"`
+	Expiry   int64             `json:"exp"`
+	Keys     []jose.JSONWebKey `json:"keys"`
+}
+
+func signedKeysHandler(o OpenIDProvider) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := Tracer.Start(r.Context(), "SignedKeys")
+		defer span.End()
+
+		policy := keySetPolicyFrom(o)
+		keySet, err := signKeySet(ctx, o.Storage(), policy)
+		if err != nil {
+			httphelper.MarshalJSONWithStatus(w, err, http.StatusInternalServerError)
+			return
 		}
+		policy.setCacheControl(w.Header())
+		writeSignedKeySet(w, keySet)
+	}
+}
+
+// SignedKeys writes the JSON Web Key Set as JWT signed by the current signing key,
+// which is served at the signed_jwks_uri.
+func SignedKeys(w http.ResponseWriter, r *http.Request, k SignedKeyProvider) {
+	ctx, span := Tracer.Start(r.Context(), "SignedKeys")
+	defer span.End()
+
+	keySet, err := SignKeySet(ctx, k)
+	if err != nil {
+		httphelper.MarshalJSONWithStatus(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeSignedKeySet(w, keySet)
+}
+
+// SignKeySet returns the JSON Web Key Set of the issuer of the context
+// as JWT signed by the current signing key.
+func SignKeySet(ctx context.Context, k SignedKeyProvider) (string, error) {
+	return signKeySet(ctx, k, nil)
+}
+
+func signKeySet(ctx context.Context, k SignedKeyProvider, policy *keySetPolicy) (string, error) {
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	keys, err := k.KeySet(storageCtx)
+	if err != nil {
+		return "", err
+	}
+	keySet, err := publishedKeySet(ctx, keys, policy)
+	if err != nil {
+		return "", err
+	}
+	signingKey, err := k.SigningKey(storageCtx)
+	if err != nil {
+		return "", err
+	}
+	signingKeyID, err := keyID(ctx, signingKey.ID(), signingKey.Key())
+	if err != nil {
+		return "", err
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: signingKey.SignatureAlgorithm(),
+		Key: &jose.JSONWebKey{
+			Key:   signingKey.Key(),
+			KeyID: signingKeyID,
+		},
+	}, (&jose.SignerOptions{}).WithType(SignedKeySetType))
+	if err != nil {
+		return "", ErrSignerCreationFailed
+	}
+	issuer := IssuerFromContext(ctx)
+	now := time.Now()
+	return crypto.Sign(&signedKeySetClaims{
+		Issuer:   issuer,
+		Subject:  issuer,
+		IssuedAt: now.Unix(),
+		Expiry:   now.Add(signedKeySetLifetime).Unix(),
+		Keys:     keySet.Keys,
+	}, signer)
+}
+
+func writeSignedKeySet(w http.ResponseWriter, keySet string) {
+	w.Header().Set("Content-Type", SignedKeySetContentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(keySet))
+}
+
+// signedKeysEndpoint returns the signed_jwks_uri of configurations
+// set by [WithCustomSignedKeysEndpoint].
+func signedKeysEndpoint(config Configuration) *Endpoint {
+	if c, ok := config.(interface{ SignedKeysEndpoint() *Endpoint }); ok {
+		return c.SignedKeysEndpoint()
 	}
-	return &jose.JSONWebKeySet{Keys: webKeys}
+	return nil
 }
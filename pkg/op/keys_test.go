@@ -1,19 +1,33 @@
 package op_test
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"io"
 	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	jose "github.com/go-jose/go-jose/v4"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/zitadel/oidc/v3/pkg/client"
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
 	"github.com/zitadel/oidc/v3/pkg/oidc"
 	"github.com/zitadel/oidc/v3/pkg/op"
 	"github.com/zitadel/oidc/v3/pkg/op/mock"
+	"github.com/zitadel/oidc/v3/pkg/op/optest"
 )
 
 func TestKeys(t *testing.T) {
@@ -98,3 +112,136 @@ func TestKeys(t *testing.T) {
 		})
 	}
 }
+
+type certKey struct {
+	key   *ecdsa.PrivateKey
+	chain []*x509.Certificate
+}
+
+func (k certKey) ID() string                                  { return "cert" }
+func (k certKey) Algorithm() jose.SignatureAlgorithm          { return jose.ES256 }
+func (k certKey) SignatureAlgorithm() jose.SignatureAlgorithm { return jose.ES256 }
+func (k certKey) Use() string                                 { return oidc.KeyUseSignature }
+func (k certKey) Certificates() []*x509.Certificate           { return k.chain }
+
+type signedKeyProvider struct {
+	certKey
+}
+
+func (p signedKeyProvider) KeySet(context.Context) ([]op.Key, error) {
+	return []op.Key{keyOnly{p.certKey}}, nil
+}
+
+func (p signedKeyProvider) SigningKey(context.Context) (op.SigningKey, error) {
+	return signingKeyOnly{p.certKey}, nil
+}
+
+// keyOnly exposes the public key of a certKey as op.Key.
+type keyOnly struct{ certKey }
+
+func (k keyOnly) Key() any { return &k.key.PublicKey }
+
+// signingKeyOnly exposes the private key of a certKey as op.SigningKey.
+type signingKeyOnly struct{ certKey }
+
+func (k signingKeyOnly) Key() any { return k.key }
+
+func newCertKey(t *testing.T) certKey {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	leafDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "signing key"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}, ca, &key.PublicKey, caKey)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(leafDER)
+	require.NoError(t, err)
+	return certKey{key: key, chain: []*x509.Certificate{leaf, ca}}
+}
+
+func TestKeys_certificates(t *testing.T) {
+	key := newCertKey(t)
+	w := httptest.NewRecorder()
+	op.Keys(w, httptest.NewRequest("GET", "/keys", nil), signedKeyProvider{key})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var keySet jose.JSONWebKeySet
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &keySet))
+	require.Len(t, keySet.Keys, 1)
+	assert.Equal(t, key.chain, keySet.Keys[0].Certificates)
+	thumbprint := sha256.Sum256(key.chain[0].Raw)
+	assert.Equal(t, thumbprint[:], keySet.Keys[0].CertificateThumbprintSHA256)
+	assert.Contains(t, w.Body.String(), `"x5t#S256"`)
+}
+
+func TestSignedKeys(t *testing.T) {
+	key := newCertKey(t)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/keys.jwt", nil)
+	r = r.WithContext(op.ContextWithIssuer(r.Context(), "https://op.example.com"))
+	op.SignedKeys(w, r, signedKeyProvider{key})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, op.SignedKeySetContentType, w.Header().Get("Content-Type"))
+
+	jws, err := jose.ParseSigned(w.Body.String(), []jose.SignatureAlgorithm{jose.ES256})
+	require.NoError(t, err)
+	assert.Equal(t, op.SignedKeySetType, jws.Signatures[0].Header.ExtraHeaders[jose.HeaderType])
+	assert.Equal(t, "cert", jws.Signatures[0].Header.KeyID)
+	payload, err := jws.Verify(&key.key.PublicKey)
+	require.NoError(t, err)
+
+	var claims struct {
+		Issuer  string            `json:"iss"`
+		Subject string            `json:"sub"`
+		Expiry  int64             `json:"exp"`
+		Keys    []jose.JSONWebKey `json:"keys"`
+	}
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	assert.Equal(t, "https://op.example.com", claims.Issuer)
+	assert.Equal(t, "https://op.example.com", claims.Subject)
+	assert.Greater(t, claims.Expiry, time.Now().Unix())
+	require.Len(t, claims.Keys, 1)
+	assert.Equal(t, key.chain, claims.Keys[0].Certificates)
+}
+
+func TestProvider_signedKeysEndpoint(t *testing.T) {
+	s := optest.New(t, optest.WithProviderOptions(op.WithCustomSignedKeysEndpoint(op.NewEndpoint("keys.jwt"))))
+	discovery, err := client.Discover(context.Background(), s.Issuer, s.Client())
+	require.NoError(t, err)
+	require.Equal(t, s.Issuer+"/keys.jwt", discovery.SignedJwksURI)
+
+	resp, err := s.Client().Get(discovery.SignedJwksURI)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, op.SignedKeySetContentType, resp.Header.Get("Content-Type"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	jws, err := jose.ParseSigned(string(body), []jose.SignatureAlgorithm{jose.RS256})
+	require.NoError(t, err)
+	keySet := rp.NewRemoteKeySet(s.Client(), discovery.JwksURI)
+	payload, err := keySet.VerifySignature(context.Background(), jws)
+	require.NoError(t, err)
+	assert.Contains(t, string(payload), `"iss":"`+s.Issuer+`"`)
+}
@@ -0,0 +1,238 @@
+package login
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	FormUsername = "username"
+	FormPassword = "password"
+	FormCode     = "code"
+)
+
+// IdentifierFunc implements [Identifier] by resolving the submitted username.
+type IdentifierFunc func(ctx context.Context, username string) (userID string, err error)
+
+func (f IdentifierFunc) Identify(ctx context.Context, r *http.Request, state *State) (string, error) {
+	username := r.PostFormValue(FormUsername)
+	if username == "" {
+		return "", ErrUserNotIdentified
+	}
+	state.Values[FormUsername] = username
+	return f(ctx, username)
+}
+
+// PasswordAuthenticator verifies the username and password of the user.
+// If the user was not identified in a previous step, it identifies the user.
+type PasswordAuthenticator struct {
+	check func(ctx context.Context, username, password string) (userID string, err error)
+}
+
+// NewPasswordAuthenticator creates a [PasswordAuthenticator],
+// check returns the id of the user if the password matches.
+func NewPasswordAuthenticator(check func(ctx context.Context, username, password string) (userID string, err error)) *PasswordAuthenticator {
+	return &PasswordAuthenticator{check: check}
+}
+
+func (*PasswordAuthenticator) Method() string { return "pwd" }
+
+func (*PasswordAuthenticator) Available(context.Context, *State) bool { return true }
+
+func (*PasswordAuthenticator) Prompt(_ context.Context, state *State) (*Prompt, error) {
+	username := state.Values[FormUsername]
+	if username == "" {
+		username = state.LoginHint
+	}
+	return &Prompt{Data: map[string]any{FormUsername: username}}, nil
+}
+
+func (a *PasswordAuthenticator) Verify(ctx context.Context, r *http.Request, state *State) error {
+	username := r.PostFormValue(FormUsername)
+	if username == "" {
+		username = state.Values[FormUsername]
+	}
+	userID, err := a.check(ctx, username, r.PostFormValue(FormPassword))
+	if err != nil {
+		return err
+	}
+	if state.UserID != "" && state.UserID != userID {
+		return errors.New("username does not match the identified user")
+	}
+	state.UserID = userID
+	return nil
+}
+
+// TOTPAuthenticator verifies time-based one-time passwords (RFC 6238)
+// with HMAC-SHA1, 6 digits and a period of 30 seconds,
+// accepting one period of clock skew.
+type TOTPAuthenticator struct {
+	secret func(ctx context.Context, userID string) ([]byte, error)
+	now    func() time.Time
+}
+
+// NewTOTPAuthenticator creates a [TOTPAuthenticator],
+// secret returns the enrolled secret of the user, or nil if the user has none.
+func NewTOTPAuthenticator(secret func(ctx context.Context, userID string) ([]byte, error)) *TOTPAuthenticator {
+	return &TOTPAuthenticator{secret: secret, now: time.Now}
+}
+
+func (*TOTPAuthenticator) Method() string { return "otp" }
+
+func (a *TOTPAuthenticator) Available(ctx context.Context, state *State) bool {
+	if state.UserID == "" {
+		return false
+	}
+	secret, err := a.secret(ctx, state.UserID)
+	return err == nil && len(secret) > 0
+}
+
+func (*TOTPAuthenticator) Prompt(context.Context, *State) (*Prompt, error) { return nil, nil }
+
+func (a *TOTPAuthenticator) Verify(ctx context.Context, r *http.Request, state *State) error {
+	secret, err := a.secret(ctx, state.UserID)
+	if err != nil {
+		return err
+	}
+	code := r.PostFormValue(FormCode)
+	counter := uint64(a.now().Unix() / 30)
+	for _, c := range []uint64{counter, counter - 1, counter + 1} {
+		if subtle.ConstantTimeCompare([]byte(TOTP(secret, c)), []byte(code)) == 1 {
+			return nil
+		}
+	}
+	return errors.New("invalid code")
+}
+
+// TOTP computes the 6 digit one-time password of the counter (RFC 4226).
+func TOTP(secret []byte, counter uint64) string {
+	mac := hmac.New(sha1.New, secret)
+	binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1_000_000)
+}
+
+// WebAuthn is implemented with a WebAuthn library of choice,
+// to authenticate users with security keys or passkeys.
+type WebAuthn interface {
+	// HasCredentials reports if the user registered any credential.
+	HasCredentials(ctx context.Context, userID string) bool
+	// BeginLogin returns the credential request options for the browser
+	// and the session data, which is passed to FinishLogin.
+	BeginLogin(ctx context.Context, userID string) (options any, session string, err error)
+	// FinishLogin verifies the assertion response of the browser.
+	FinishLogin(ctx context.Context, userID, session string, r *http.Request) error
+}
+
+// WebAuthnAuthenticator authenticates the user with a [WebAuthn] implementation.
+// The credential request options are passed to the [Renderer] as "options".
+type WebAuthnAuthenticator struct {
+	webAuthn WebAuthn
+}
+
+func NewWebAuthnAuthenticator(webAuthn WebAuthn) *WebAuthnAuthenticator {
+	return &WebAuthnAuthenticator{webAuthn: webAuthn}
+}
+
+const valueWebAuthnSession = "webauthn_session"
+
+func (*WebAuthnAuthenticator) Method() string { return "hwk" }
+
+func (a *WebAuthnAuthenticator) Available(ctx context.Context, state *State) bool {
+	return state.UserID != "" && a.webAuthn.HasCredentials(ctx, state.UserID)
+}
+
+func (a *WebAuthnAuthenticator) Prompt(ctx context.Context, state *State) (*Prompt, error) {
+	options, session, err := a.webAuthn.BeginLogin(ctx, state.UserID)
+	if err != nil {
+		return nil, err
+	}
+	state.Values[valueWebAuthnSession] = session
+	return &Prompt{Data: map[string]any{"options": options}}, nil
+}
+
+func (a *WebAuthnAuthenticator) Verify(ctx context.Context, r *http.Request, state *State) error {
+	session := state.Values[valueWebAuthnSession]
+	if session == "" {
+		return errors.New("webauthn session missing")
+	}
+	delete(state.Values, valueWebAuthnSession)
+	return a.webAuthn.FinishLogin(ctx, state.UserID, session, r)
+}
+
+// ExternalIdP is an identity provider the user is redirected to for authentication.
+type ExternalIdP interface {
+	// AuthURL returns the URL of the identity provider. Its callback must return to the [Flow]
+	// with the [FormAuthRequestID] and [FormMethod] query parameters and pass back the state parameter.
+	AuthURL(ctx context.Context, authRequestID, state string) (string, error)
+	// Callback handles the response of the identity provider
+	// and returns the id of the local user.
+	// It may set the ACR of the state from the response.
+	Callback(ctx context.Context, r *http.Request, state *State) (userID string, err error)
+}
+
+// ExternalIdPAuthenticator authenticates the user at an [ExternalIdP].
+type ExternalIdPAuthenticator struct {
+	method string
+	idp    ExternalIdP
+}
+
+// NewExternalIdPAuthenticator creates an [ExternalIdPAuthenticator],
+// the method is added to the amr on success and selects the identity provider
+// if more than one is configured.
+func NewExternalIdPAuthenticator(method string, idp ExternalIdP) *ExternalIdPAuthenticator {
+	return &ExternalIdPAuthenticator{method: method, idp: idp}
+}
+
+func (a *ExternalIdPAuthenticator) Method() string { return a.method }
+
+func (*ExternalIdPAuthenticator) Available(context.Context, *State) bool { return true }
+
+func (a *ExternalIdPAuthenticator) valueKey() string { return "idp_state_" + a.method }
+
+func (a *ExternalIdPAuthenticator) Prompt(ctx context.Context, state *State) (*Prompt, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	idpState := base64.RawURLEncoding.EncodeToString(b)
+	state.Values[a.valueKey()] = idpState
+	authURL, err := a.idp.AuthURL(ctx, state.AuthRequestID, idpState)
+	if err != nil {
+		return nil, err
+	}
+	return &Prompt{RedirectURL: authURL}, nil
+}
+
+// CallbackState implements [CallbackAuthenticator].
+func (a *ExternalIdPAuthenticator) CallbackState(state *State) string {
+	return state.Values[a.valueKey()]
+}
+
+func (a *ExternalIdPAuthenticator) Verify(ctx context.Context, r *http.Request, state *State) error {
+	expected := state.Values[a.valueKey()]
+	delete(state.Values, a.valueKey())
+	if expected == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(r.FormValue("state"))) != 1 {
+		return errors.New("invalid state of identity provider callback")
+	}
+	userID, err := a.idp.Callback(ctx, r, state)
+	if err != nil {
+		return err
+	}
+	if state.UserID != "" && state.UserID != userID {
+		return errors.New("identity provider returned another user")
+	}
+	state.UserID = userID
+	return nil
+}
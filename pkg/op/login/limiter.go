@@ -0,0 +1,89 @@
+package login
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// AttemptLimiter limits the attempts of login flows beyond a single [State],
+// for example per remote address or per username.
+type AttemptLimiter interface {
+	// Allow reports if another attempt is allowed for the request.
+	Allow(ctx context.Context, r *http.Request, state *State) bool
+	// Failed records a failed attempt of the request.
+	Failed(ctx context.Context, r *http.Request, state *State)
+}
+
+// MemoryAttemptLimiter is an in-memory [AttemptLimiter], which limits
+// the failed attempts per remote address within a window.
+// Behind a reverse proxy, the remote address must be set from the forwarded headers
+// before the [Flow], e.g. with a RealIP middleware.
+type MemoryAttemptLimiter struct {
+	max    int
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	attempts  map[string]*attemptWindow
+	lastSweep time.Time
+}
+
+type attemptWindow struct {
+	start  time.Time
+	failed int
+}
+
+// NewMemoryAttemptLimiter creates a [MemoryAttemptLimiter]
+// allowing max failed attempts per remote address within the window.
+func NewMemoryAttemptLimiter(max int, window time.Duration) *MemoryAttemptLimiter {
+	return &MemoryAttemptLimiter{
+		max:      max,
+		window:   window,
+		now:      time.Now,
+		attempts: make(map[string]*attemptWindow),
+	}
+}
+
+func (l *MemoryAttemptLimiter) Allow(_ context.Context, r *http.Request, _ *State) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	a, ok := l.attempts[remoteHost(r)]
+	return !ok || l.now().Sub(a.start) >= l.window || a.failed < l.max
+}
+
+// Failed records the failed attempt. Expired windows are removed once per window,
+// so the limiter only keeps the addresses with recent failures.
+func (l *MemoryAttemptLimiter) Failed(_ context.Context, r *http.Request, _ *State) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if now.Sub(l.lastSweep) >= l.window {
+		for key, a := range l.attempts {
+			if now.Sub(a.start) >= l.window {
+				delete(l.attempts, key)
+			}
+		}
+		l.lastSweep = now
+	}
+	key := remoteHost(r)
+	a, ok := l.attempts[key]
+	if ok && now.Sub(a.start) >= l.window {
+		ok = false
+	}
+	if !ok {
+		a = &attemptWindow{start: now}
+		l.attempts[key] = a
+	}
+	a.failed++
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,503 @@
+// Package login provides a multi step login flow for the user interface of an OpenID Provider.
+//
+// The [Flow] guides the user through the steps identify, authenticate, MFA and consent.
+// Each step is performed by pluggable implementations, such as [PasswordAuthenticator] or [TOTPAuthenticator].
+// Once all required steps succeeded, the resulting auth_time, acr and amr are passed to the [Storage],
+// which completes the auth request, and the user is redirected to the callback of the OP.
+package login
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"golang.org/x/text/language"
+
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/i18n"
+)
+
+// Step of the login flow.
+type Step string
+
+const (
+	StepIdentify     Step = "identify"
+	StepAuthenticate Step = "authenticate"
+	StepMFA          Step = "mfa"
+	StepConsent      Step = "consent"
+)
+
+const (
+	// FormAuthRequestID is the name of the query / form parameter
+	// carrying the id of the auth request through the flow.
+	FormAuthRequestID = "authRequestID"
+	// FormMethod is the name of the form parameter selecting the [Authenticator]
+	// when more than one is available for the current step.
+	FormMethod = "method"
+	// FormConsent is the name of the form parameter of the consent step,
+	// which must be "accept" to grant consent.
+	FormConsent = "consent"
+	// FormCSRFToken is the name of the form parameter carrying the [State.CSRFToken],
+	// which is required for all POST requests.
+	FormCSRFToken = "csrf"
+)
+
+const (
+	defaultMaxAttempts   = 5
+	defaultStateLifetime = 30 * time.Minute
+)
+
+var (
+	ErrUserNotIdentified = errors.New("user not identified")
+	ErrUnknownMethod     = errors.New("unknown authentication method")
+	ErrConsentDenied     = errors.New("consent denied")
+	ErrUserMismatch      = errors.New("user does not match the id_token_hint")
+	ErrTooManyAttempts   = errors.New("too many failed attempts")
+	ErrInvalidCSRFToken  = errors.New("invalid csrf token")
+	ErrInvalidCallback   = errors.New("invalid callback")
+	ErrLoginExpired      = errors.New("login expired")
+)
+
+// State of the login flow of an auth request.
+type State struct {
+	AuthRequestID string
+	// UILocales of the auth request are used to select the language of the pages.
+	UILocales []language.Tag
+	// UILocale is the language negotiated by the OP, see oidc.AuthRequest.UILocale.
+	// If set, it is used instead of the UILocales and the Accept-Language header.
+	UILocale language.Tag
+	// LoginHint of the auth request prefills the username.
+	LoginHint string
+	// IDTokenHintSubject is the verified subject of the id_token_hint of the auth request.
+	// If set, the login fails for any other user.
+	IDTokenHintSubject string
+	// Scopes of the auth request, whose descriptions are displayed on the consent page,
+	// see Config.Scopes.
+	Scopes []string
+	// Hints are the further hint parameters of the auth request, see op.WithHintParameters.
+	Hints  map[string]string
+	UserID string
+	// AMR lists the methods (RFC 8176) of all succeeded authenticators.
+	AMR      []string
+	ACR      string
+	AuthTime time.Time
+	// Consented is set once the user granted consent.
+	Consented bool
+	// Values allows authenticators to keep data between
+	// rendering the prompt and verifying the response, such as a WebAuthn session.
+	Values map[string]string
+	// CSRFToken is generated on the first request of the flow
+	// and must be submitted with every form, see [FormCSRFToken].
+	CSRFToken string
+	// FailedAttempts counts the failed steps, see Config.MaxAttempts.
+	FailedAttempts int
+	// Expires is set on the first request of the flow, see Config.StateLifetime.
+	// Storage implementations may delete states after their expiration.
+	Expires time.Time
+}
+
+// Storage keeps the [State] of login flows
+// and completes the auth request with its result.
+type Storage interface {
+	// LoginState returns the state of the auth request.
+	// For auth requests without state yet, a new State with only the AuthRequestID must be returned.
+	LoginState(ctx context.Context, authRequestID string) (*State, error)
+	SaveLoginState(ctx context.Context, state *State) error
+	// CompleteLogin is called after all required steps succeeded.
+	// It must set the user, auth_time, acr and amr of the state
+	// on the auth request and mark it as done.
+	CompleteLogin(ctx context.Context, state *State) error
+}
+
+// Identifier resolves the user in the identify step, typically by the username.
+// It is optional: without an Identifier, the authenticator of the first factor
+// must identify the user, like the [PasswordAuthenticator] does.
+type Identifier interface {
+	Identify(ctx context.Context, r *http.Request, state *State) (userID string, err error)
+}
+
+// Authenticator verifies a factor of the user.
+type Authenticator interface {
+	// Method returns the value added to the amr on success,
+	// e.g. "pwd", "otp" or "hwk" (RFC 8176).
+	Method() string
+	// Available reports if the user of the state can use the authenticator,
+	// e.g. if a TOTP secret is enrolled.
+	Available(ctx context.Context, state *State) bool
+	// Prompt prepares the page of the authenticator.
+	// It may return nil if no data is required.
+	Prompt(ctx context.Context, state *State) (*Prompt, error)
+	// Verify checks the response of the user.
+	// Authenticators of the first factor set the UserID of the state if it is empty.
+	Verify(ctx context.Context, r *http.Request, state *State) error
+}
+
+// CallbackAuthenticator is an [Authenticator], which is verified by a GET request
+// to the callback of a third party, such as the [ExternalIdPAuthenticator].
+// The callback must carry the [FormMethod] of the authenticator and the state
+// of the prompt as "state" query parameter, which replaces the CSRF token.
+type CallbackAuthenticator interface {
+	Authenticator
+	// CallbackState returns the state issued by the prompt,
+	// or an empty string if no callback is expected.
+	CallbackState(state *State) string
+}
+
+// Prompt is returned by [Authenticator.Prompt].
+type Prompt struct {
+	// Data is passed to the [Renderer].
+	Data map[string]any
+	// RedirectURL, if set, redirects the user instead of rendering a page,
+	// e.g. to an external identity provider.
+	RedirectURL string
+}
+
+// Config of a [Flow].
+type Config struct {
+	Storage Storage
+	// Identifier is optional, see [Identifier].
+	Identifier Identifier
+	// Authenticators for the first factor, at least one is required.
+	Authenticators []Authenticator
+	// MFA authenticators are required as second factor, if at least one of them is available for the user.
+	MFA []Authenticator
+	// ConsentRequired is optional and reports if the user must grant consent.
+	ConsentRequired func(ctx context.Context, state *State) bool
+	// Scopes is optional and provides the descriptions
+	// of the consentable scopes of the consent page, see [op.ScopeRegistry.Consent].
+	Scopes *op.ScopeRegistry
+	// ACR is optional and computes the acr from the amr of the state.
+	ACR func(amr []string) string
+	// Renderer defaults to [DefaultRenderer].
+	Renderer Renderer
+	// Catalog provides the messages of the pages, defaults to [i18n.NewDefaultCatalog].
+	Catalog *i18n.Catalog
+	// Callback returns the URL the user is redirected to after the login,
+	// usually op.AuthCallbackURL of the OpenID Provider.
+	Callback func(ctx context.Context, authRequestID string) string
+	// FormAction is the URL the forms of the flow are submitted to,
+	// which is where the Flow is mounted.
+	FormAction string
+	// MaxAttempts of failed steps of a login flow, defaults to 5.
+	// Further attempts are rejected, the user must start a new auth request.
+	MaxAttempts int
+	// AttemptLimiter is optional and limits the attempts across login flows,
+	// e.g. per remote address, see [NewMemoryAttemptLimiter].
+	AttemptLimiter AttemptLimiter
+	// StateLifetime limits the duration of a login flow, defaults to 30 minutes.
+	StateLifetime time.Duration
+}
+
+// Flow is the http.Handler of the login.
+// The LoginURL of the clients must point to it,
+// with the id of the auth request as [FormAuthRequestID] query parameter.
+//
+// GET requests render the current step, POST requests with the [FormCSRFToken] verify it.
+// GET requests with a [FormMethod] parameter are only verified as callbacks
+// of a [CallbackAuthenticator] of the current step, such as of external identity providers.
+type Flow struct {
+	config Config
+}
+
+// New creates the login [Flow].
+func New(config Config) (*Flow, error) {
+	if config.Storage == nil {
+		return nil, errors.New("login: storage is required")
+	}
+	if len(config.Authenticators) == 0 {
+		return nil, errors.New("login: at least one authenticator is required")
+	}
+	if config.Callback == nil {
+		return nil, errors.New("login: callback is required")
+	}
+	if config.Renderer == nil {
+		config.Renderer = DefaultRenderer
+	}
+	if config.Catalog == nil {
+		config.Catalog = i18n.NewDefaultCatalog()
+	}
+	if config.MaxAttempts == 0 {
+		config.MaxAttempts = defaultMaxAttempts
+	}
+	if config.StateLifetime == 0 {
+		config.StateLifetime = defaultStateLifetime
+	}
+	return &Flow{config: config}, nil
+}
+
+func (f *Flow) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, fmt.Sprintf("cannot parse form: %s", err), http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	id := r.FormValue(FormAuthRequestID)
+	if id == "" {
+		http.Error(w, "missing "+FormAuthRequestID, http.StatusBadRequest)
+		return
+	}
+	state, err := f.config.Storage.LoginState(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if state.Values == nil {
+		state.Values = make(map[string]string)
+	}
+	if state.CSRFToken == "" {
+		if err = f.start(ctx, state); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if time.Now().After(state.Expires) {
+		http.Error(w, ErrLoginExpired.Error(), http.StatusBadRequest)
+		return
+	}
+
+	step := f.next(ctx, state)
+	callback := r.Method == http.MethodGet && r.URL.Query().Get(FormMethod) != ""
+	if r.Method == http.MethodPost || callback {
+		if callback && !f.validCallback(ctx, r, step, state) {
+			http.Error(w, ErrInvalidCallback.Error(), http.StatusForbidden)
+			return
+		}
+		if !callback && subtle.ConstantTimeCompare([]byte(state.CSRFToken), []byte(r.PostFormValue(FormCSRFToken))) != 1 {
+			http.Error(w, ErrInvalidCSRFToken.Error(), http.StatusForbidden)
+			return
+		}
+		if !f.allow(ctx, r, state) {
+			http.Error(w, ErrTooManyAttempts.Error(), http.StatusTooManyRequests)
+			return
+		}
+		if err = f.verify(ctx, r, step, state); err != nil {
+			// denying consent is no failed attempt
+			if step != StepConsent {
+				f.failed(ctx, r, state)
+			}
+			f.render(w, r, step, state, err)
+			return
+		}
+		if err = f.config.Storage.SaveLoginState(ctx, state); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		step = f.next(ctx, state)
+	}
+	if step != "" {
+		f.render(w, r, step, state, nil)
+		return
+	}
+	f.complete(w, r, state)
+}
+
+// validCallback reports whether the GET request is the callback of a [CallbackAuthenticator]
+// of the step, carrying the state issued by its prompt.
+func (f *Flow) validCallback(ctx context.Context, r *http.Request, step Step, state *State) bool {
+	if step != StepAuthenticate && step != StepMFA {
+		return false
+	}
+	a, err := f.authenticator(ctx, step, state, r.URL.Query().Get(FormMethod))
+	if err != nil {
+		return false
+	}
+	callback, ok := a.(CallbackAuthenticator)
+	if !ok {
+		return false
+	}
+	expected := callback.CallbackState(state)
+	return expected != "" && subtle.ConstantTimeCompare([]byte(expected), []byte(r.URL.Query().Get("state"))) == 1
+}
+
+// start initializes the state on the first request of the flow.
+func (f *Flow) start(ctx context.Context, state *State) error {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	state.CSRFToken = base64.RawURLEncoding.EncodeToString(b)
+	state.Expires = time.Now().Add(f.config.StateLifetime)
+	return f.config.Storage.SaveLoginState(ctx, state)
+}
+
+func (f *Flow) allow(ctx context.Context, r *http.Request, state *State) bool {
+	if state.FailedAttempts >= f.config.MaxAttempts {
+		return false
+	}
+	return f.config.AttemptLimiter == nil || f.config.AttemptLimiter.Allow(ctx, r, state)
+}
+
+// failed records the failed attempt of the state and the [AttemptLimiter].
+func (f *Flow) failed(ctx context.Context, r *http.Request, state *State) {
+	state.FailedAttempts++
+	if f.config.AttemptLimiter != nil {
+		f.config.AttemptLimiter.Failed(ctx, r, state)
+	}
+	if err := f.config.Storage.SaveLoginState(ctx, state); err != nil {
+		slog.ErrorContext(ctx, "login state", "auth_request_id", state.AuthRequestID, "error", err)
+	}
+}
+
+// next returns the first step not yet completed by the state,
+// or an empty Step if the login is complete.
+func (f *Flow) next(ctx context.Context, state *State) Step {
+	if state.UserID == "" && f.config.Identifier != nil {
+		return StepIdentify
+	}
+	if !completed(state, f.config.Authenticators) {
+		return StepAuthenticate
+	}
+	if len(f.available(ctx, StepMFA, state)) > 0 && !completed(state, f.config.MFA) {
+		return StepMFA
+	}
+	if f.config.ConsentRequired != nil && !state.Consented && f.config.ConsentRequired(ctx, state) {
+		return StepConsent
+	}
+	return ""
+}
+
+func completed(state *State, authenticators []Authenticator) bool {
+	return slices.ContainsFunc(authenticators, func(a Authenticator) bool {
+		return slices.Contains(state.AMR, a.Method())
+	})
+}
+
+func (f *Flow) available(ctx context.Context, step Step, state *State) []Authenticator {
+	authenticators := f.config.Authenticators
+	if step == StepMFA {
+		authenticators = f.config.MFA
+	}
+	available := make([]Authenticator, 0, len(authenticators))
+	for _, a := range authenticators {
+		if a.Available(ctx, state) {
+			available = append(available, a)
+		}
+	}
+	return available
+}
+
+// authenticator selects the authenticator of the step by the method.
+// The method may be omitted if only one authenticator is available.
+func (f *Flow) authenticator(ctx context.Context, step Step, state *State, method string) (Authenticator, error) {
+	available := f.available(ctx, step, state)
+	if method == "" && len(available) == 1 {
+		return available[0], nil
+	}
+	for _, a := range available {
+		if a.Method() == method {
+			return a, nil
+		}
+	}
+	return nil, ErrUnknownMethod
+}
+
+func (f *Flow) verify(ctx context.Context, r *http.Request, step Step, state *State) error {
+	switch step {
+	case StepIdentify:
+		userID, err := f.config.Identifier.Identify(ctx, r, state)
+		if err != nil {
+			return err
+		}
+		if state.IDTokenHintSubject != "" && userID != state.IDTokenHintSubject {
+			return ErrUserMismatch
+		}
+		state.UserID = userID
+		return nil
+	case StepAuthenticate, StepMFA:
+		a, err := f.authenticator(ctx, step, state, r.FormValue(FormMethod))
+		if err != nil {
+			return err
+		}
+		userID := state.UserID
+		if err = a.Verify(ctx, r, state); err != nil {
+			return err
+		}
+		if state.UserID == "" {
+			return ErrUserNotIdentified
+		}
+		if state.IDTokenHintSubject != "" && state.UserID != state.IDTokenHintSubject {
+			state.UserID = userID
+			return ErrUserMismatch
+		}
+		state.AMR = append(state.AMR, a.Method())
+		return nil
+	case StepConsent:
+		if r.PostFormValue(FormConsent) != "accept" {
+			return ErrConsentDenied
+		}
+		state.Consented = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+func (f *Flow) render(w http.ResponseWriter, r *http.Request, step Step, state *State, verifyErr error) {
+	ctx := r.Context()
+	page := &Page{
+		AuthRequestID: state.AuthRequestID,
+		CSRFToken:     state.CSRFToken,
+		Action:        f.config.FormAction,
+		Step:          step,
+		LoginHint:     state.LoginHint,
+		Localizer:     f.config.Catalog.FromRequest(r, state.UILocales),
+	}
+	if state.UILocale != language.Und {
+		page.Localizer = f.config.Catalog.Localizer(state.UILocale)
+	}
+	if step == StepConsent && f.config.Scopes != nil {
+		page.ConsentScopes = f.config.Scopes.Consent(state.Scopes)
+	}
+	if verifyErr != nil {
+		slog.InfoContext(ctx, "login step failed", "step", step, "auth_request_id", state.AuthRequestID, "error", verifyErr)
+		page.Error = verifyErr.Error()
+	}
+	if step == StepAuthenticate || step == StepMFA {
+		for _, a := range f.available(ctx, step, state) {
+			page.Methods = append(page.Methods, a.Method())
+		}
+		page.Method = r.FormValue(FormMethod)
+		if !slices.Contains(page.Methods, page.Method) && len(page.Methods) > 0 {
+			page.Method = page.Methods[0]
+		}
+		if a, err := f.authenticator(ctx, step, state, page.Method); err == nil {
+			prompt, err := a.Prompt(ctx, state)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if err = f.config.Storage.SaveLoginState(ctx, state); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if prompt != nil && prompt.RedirectURL != "" && verifyErr == nil {
+				http.Redirect(w, r, prompt.RedirectURL, http.StatusFound)
+				return
+			}
+			if prompt != nil {
+				page.Data = prompt.Data
+			}
+		}
+	}
+	f.config.Renderer.Render(w, r, page)
+}
+
+func (f *Flow) complete(w http.ResponseWriter, r *http.Request, state *State) {
+	ctx := r.Context()
+	state.AuthTime = time.Now()
+	if f.config.ACR != nil {
+		state.ACR = f.config.ACR(state.AMR)
+	}
+	if err := f.config.Storage.CompleteLogin(ctx, state); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, f.config.Callback(ctx, state.AuthRequestID), http.StatusFound)
+}
@@ -0,0 +1,330 @@
+package login_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/login"
+)
+
+type memoryStorage struct {
+	states    map[string]*login.State
+	completed *login.State
+}
+
+func (s *memoryStorage) LoginState(_ context.Context, id string) (*login.State, error) {
+	if state, ok := s.states[id]; ok {
+		return state, nil
+	}
+	return &login.State{AuthRequestID: id}, nil
+}
+
+func (s *memoryStorage) SaveLoginState(_ context.Context, state *login.State) error {
+	s.states[state.AuthRequestID] = state
+	return nil
+}
+
+func (s *memoryStorage) CompleteLogin(_ context.Context, state *login.State) error {
+	s.completed = state
+	return nil
+}
+
+var totpSecret = []byte("12345678901234567890")
+
+func checkPassword(_ context.Context, username, password string) (string, error) {
+	if username == "alice" && password == "secret" {
+		return "user1", nil
+	}
+	return "", errors.New("wrong password")
+}
+
+func newFlow(t *testing.T, storage login.Storage, mfa bool) *login.Flow {
+	config := login.Config{
+		Storage: storage,
+		Authenticators: []login.Authenticator{
+			login.NewPasswordAuthenticator(checkPassword),
+		},
+		ConsentRequired: func(context.Context, *login.State) bool { return true },
+		ACR: func(amr []string) string {
+			if len(amr) > 1 {
+				return "mfa"
+			}
+			return "pwd"
+		},
+		Callback: func(_ context.Context, id string) string {
+			return "/authorize/callback?id=" + id
+		},
+		FormAction: "/login",
+	}
+	if mfa {
+		config.MFA = []login.Authenticator{
+			login.NewTOTPAuthenticator(func(context.Context, string) ([]byte, error) { return totpSecret, nil }),
+		}
+	}
+	flow, err := login.New(config)
+	require.NoError(t, err)
+	return flow
+}
+
+func post(flow http.Handler, storage *memoryStorage, values url.Values) *httptest.ResponseRecorder {
+	values.Set(login.FormAuthRequestID, "req1")
+	if state, ok := storage.states["req1"]; ok && !values.Has(login.FormCSRFToken) {
+		values.Set(login.FormCSRFToken, state.CSRFToken)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	flow.ServeHTTP(w, req)
+	return w
+}
+
+func TestFlow(t *testing.T) {
+	storage := &memoryStorage{states: make(map[string]*login.State)}
+	flow := newFlow(t, storage, true)
+
+	w := httptest.NewRecorder()
+	flow.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login?authRequestID=req1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `name="password"`)
+
+	w = post(flow, storage, url.Values{"username": {"alice"}, "password": {"wrong"}})
+	assert.Contains(t, w.Body.String(), "wrong password")
+
+	w = post(flow, storage, url.Values{"username": {"alice"}, "password": {"secret"}})
+	assert.Contains(t, w.Body.String(), `name="code"`, "mfa step")
+
+	w = post(flow, storage, url.Values{"code": {"000000"}})
+	assert.Contains(t, w.Body.String(), "invalid code")
+
+	code := login.TOTP(totpSecret, uint64(time.Now().Unix()/30))
+	w = post(flow, storage, url.Values{"method": {"otp"}, "code": {code}})
+	assert.Contains(t, w.Body.String(), `name="consent"`, "consent step")
+
+	w = post(flow, storage, url.Values{"consent": {"deny"}})
+	assert.Contains(t, w.Body.String(), login.ErrConsentDenied.Error())
+	assert.Nil(t, storage.completed)
+
+	w = post(flow, storage, url.Values{"consent": {"accept"}})
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/authorize/callback?id=req1", w.Header().Get("Location"))
+	require.NotNil(t, storage.completed)
+	assert.Equal(t, "user1", storage.completed.UserID)
+	assert.Equal(t, []string{"pwd", "otp"}, storage.completed.AMR)
+	assert.Equal(t, "mfa", storage.completed.ACR)
+	assert.False(t, storage.completed.AuthTime.IsZero())
+}
+
+func TestTOTP(t *testing.T) {
+	// RFC 6238 appendix B, T = 59, truncated to 6 digits
+	assert.Equal(t, "287082", login.TOTP(totpSecret, 1))
+}
+
+type testIdP struct{}
+
+func (testIdP) AuthURL(_ context.Context, authRequestID, state string) (string, error) {
+	return "https://idp.example.com/authorize?state=" + state, nil
+}
+
+func (testIdP) Callback(_ context.Context, r *http.Request, _ *login.State) (string, error) {
+	if r.FormValue("code") != "idp-code" {
+		return "", errors.New("invalid code")
+	}
+	return "user2", nil
+}
+
+func TestFlow_externalIdP(t *testing.T) {
+	storage := &memoryStorage{states: make(map[string]*login.State)}
+	flow, err := login.New(login.Config{
+		Storage:        storage,
+		Authenticators: []login.Authenticator{login.NewExternalIdPAuthenticator("google", testIdP{})},
+		Callback:       func(_ context.Context, id string) string { return "/callback?id=" + id },
+	})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	flow.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login?authRequestID=req1", nil))
+	require.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	state := location.Query().Get("state")
+	require.NotEmpty(t, state)
+
+	w = httptest.NewRecorder()
+	flow.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login?authRequestID=req1&method=google&code=idp-code&state=other", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code, "wrong state")
+
+	w = httptest.NewRecorder()
+	flow.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login?authRequestID=req1&method=google&code=idp-code&state="+state, nil))
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/callback?id=req1", w.Header().Get("Location"))
+	require.NotNil(t, storage.completed)
+	assert.Equal(t, "user2", storage.completed.UserID)
+	assert.Equal(t, []string{"google"}, storage.completed.AMR)
+}
+
+func TestFlow_hints(t *testing.T) {
+	storage := &memoryStorage{states: map[string]*login.State{
+		"req1": {AuthRequestID: "req1", LoginHint: "alice", IDTokenHintSubject: "user2"},
+	}}
+	flow := newFlow(t, storage, false)
+
+	w := httptest.NewRecorder()
+	flow.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login?authRequestID=req1", nil))
+	assert.Contains(t, w.Body.String(), `name="username" value="alice"`, "login_hint prefills the username")
+
+	w = post(flow, storage, url.Values{"username": {"alice"}, "password": {"secret"}})
+	assert.Contains(t, w.Body.String(), login.ErrUserMismatch.Error())
+	assert.Empty(t, storage.states["req1"].UserID)
+	assert.Nil(t, storage.completed)
+}
+
+func TestFlow_consentScopes(t *testing.T) {
+	scopes, err := op.NewScopeRegistry(op.DefaultScopes()...)
+	require.NoError(t, err)
+	storage := &memoryStorage{states: map[string]*login.State{
+		"req1": {
+			AuthRequestID: "req1",
+			UserID:        "user1",
+			AMR:           []string{"pwd"},
+			Scopes:        []string{oidc.ScopeOpenID, oidc.ScopeEmail, "custom"},
+		},
+	}}
+	var page *login.Page
+	flow, err := login.New(login.Config{
+		Storage:         storage,
+		Authenticators:  []login.Authenticator{login.NewPasswordAuthenticator(checkPassword)},
+		ConsentRequired: func(context.Context, *login.State) bool { return true },
+		Scopes:          scopes,
+		Callback:        func(context.Context, string) string { return "/authorize/callback" },
+		Renderer: login.RendererFunc(func(w http.ResponseWriter, r *http.Request, p *login.Page) {
+			page = p
+			login.DefaultRenderer.Render(w, r, p)
+		}),
+	})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	flow.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login?authRequestID=req1", nil))
+	require.NotNil(t, page)
+	assert.Equal(t, login.StepConsent, page.Step)
+	email, _ := scopes.Scope(oidc.ScopeEmail)
+	assert.Equal(t, []op.Scope{email}, page.ConsentScopes)
+	assert.Contains(t, w.Body.String(), "<li>Read your email address</li>")
+}
+
+func TestFlow_csrf(t *testing.T) {
+	storage := &memoryStorage{states: make(map[string]*login.State)}
+	flow := newFlow(t, storage, false)
+
+	w := httptest.NewRecorder()
+	flow.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login?authRequestID=req1", nil))
+	require.NotEmpty(t, storage.states["req1"].CSRFToken)
+	assert.Contains(t, w.Body.String(), `name="csrf" value="`+storage.states["req1"].CSRFToken+`"`)
+
+	w = post(flow, storage, url.Values{"username": {"alice"}, "password": {"secret"}, "csrf": {""}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = post(flow, storage, url.Values{"username": {"alice"}, "password": {"secret"}, "csrf": {"other"}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, storage.states["req1"].UserID)
+
+	w = post(flow, storage, url.Values{"username": {"alice"}, "password": {"secret"}})
+	assert.Contains(t, w.Body.String(), `name="consent"`)
+}
+
+func TestFlow_get(t *testing.T) {
+	storage := &memoryStorage{states: make(map[string]*login.State)}
+	flow := newFlow(t, storage, false)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		flow.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login?authRequestID=req1&"+query, nil))
+		return w
+	}
+	get("")
+	w := get("method=pwd&username=alice&password=secret")
+	assert.Equal(t, http.StatusForbidden, w.Code, "no callback authenticator")
+	csrf := storage.states["req1"].CSRFToken
+	w = get("method=pwd&username=alice&password=secret&csrf=" + csrf)
+	assert.Equal(t, http.StatusForbidden, w.Code, "csrf token in query")
+	assert.Empty(t, storage.states["req1"].UserID)
+
+	req := httptest.NewRequest(http.MethodPost, "/login?username=alice&password=secret", strings.NewReader(url.Values{
+		login.FormAuthRequestID: {"req1"},
+		login.FormCSRFToken:     {csrf},
+	}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	flow.ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), "wrong password", "credentials in query")
+	assert.Empty(t, storage.states["req1"].UserID)
+
+	post(flow, storage, url.Values{"username": {"alice"}, "password": {"secret"}})
+	w = get("method=pwd&consent=accept&csrf=" + csrf)
+	assert.Equal(t, http.StatusForbidden, w.Code, "consent")
+	assert.Nil(t, storage.completed)
+}
+
+func TestFlow_maxAttempts(t *testing.T) {
+	storage := &memoryStorage{states: make(map[string]*login.State)}
+	flow := newFlow(t, storage, false)
+
+	w := httptest.NewRecorder()
+	flow.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login?authRequestID=req1", nil))
+	for range 5 {
+		w = post(flow, storage, url.Values{"username": {"alice"}, "password": {"wrong"}})
+		assert.Contains(t, w.Body.String(), "wrong password")
+	}
+	w = post(flow, storage, url.Values{"username": {"alice"}, "password": {"secret"}})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Empty(t, storage.states["req1"].UserID)
+}
+
+func TestFlow_expired(t *testing.T) {
+	storage := &memoryStorage{states: map[string]*login.State{
+		"req1": {AuthRequestID: "req1", CSRFToken: "token", Expires: time.Now().Add(-time.Second)},
+	}}
+	flow := newFlow(t, storage, false)
+
+	w := post(flow, storage, url.Values{"username": {"alice"}, "password": {"secret"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), login.ErrLoginExpired.Error())
+}
+
+func TestMemoryAttemptLimiter(t *testing.T) {
+	limiter := login.NewMemoryAttemptLimiter(2, time.Minute)
+	ctx := context.Background()
+	r := httptest.NewRequest(http.MethodPost, "/login", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	other := httptest.NewRequest(http.MethodPost, "/login", nil)
+	other.RemoteAddr = "192.0.2.2:1234"
+
+	assert.True(t, limiter.Allow(ctx, r, nil))
+	limiter.Failed(ctx, r, nil)
+	assert.True(t, limiter.Allow(ctx, r, nil))
+	limiter.Failed(ctx, r, nil)
+	assert.False(t, limiter.Allow(ctx, r, nil))
+	assert.True(t, limiter.Allow(ctx, other, nil), "other address")
+
+	storage := &memoryStorage{states: make(map[string]*login.State)}
+	flow, err := login.New(login.Config{
+		Storage:        storage,
+		Authenticators: []login.Authenticator{login.NewPasswordAuthenticator(checkPassword)},
+		Callback:       func(context.Context, string) string { return "/callback" },
+		AttemptLimiter: limiter,
+	})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	flow.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login?authRequestID=req1", nil))
+	w = post(flow, storage, url.Values{"username": {"alice"}, "password": {"secret"}})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "httptest address is limited")
+}
@@ -0,0 +1,121 @@
+package login
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+
+	"golang.org/x/text/language"
+
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/i18n"
+)
+
+// Page is the data passed to the [Renderer].
+type Page struct {
+	AuthRequestID string
+	// CSRFToken must be submitted with the forms as [FormCSRFToken].
+	CSRFToken string
+	// Action is the URL forms must be submitted to.
+	Action string
+	Step   Step
+	// LoginHint of the auth request, which should prefill the username.
+	LoginHint string
+	// Methods lists the available authenticators of the authenticate and mfa step.
+	Methods []string
+	// Method is the selected authenticator, which should be submitted as [FormMethod].
+	Method string
+	// ConsentScopes are the consentable scopes of the auth request on the consent page,
+	// if Config.Scopes is set.
+	ConsentScopes []op.Scope
+	// Data returned by the [Authenticator.Prompt].
+	Data  map[string]any
+	Error string
+	// Localizer translates the texts of the page
+	// into the language negotiated from the ui_locales and Accept-Language.
+	Localizer *i18n.Localizer
+}
+
+// Renderer renders the pages of the login [Flow].
+type Renderer interface {
+	Render(w http.ResponseWriter, r *http.Request, page *Page)
+}
+
+type RendererFunc func(w http.ResponseWriter, r *http.Request, page *Page)
+
+func (f RendererFunc) Render(w http.ResponseWriter, r *http.Request, page *Page) {
+	f(w, r, page)
+}
+
+// DefaultRenderer renders simple localized HTML forms for the identify, consent,
+// password ("pwd") and one-time password ("otp") steps.
+// Other authenticators, such as WebAuthn, require a custom [Renderer].
+var DefaultRenderer Renderer = &templateRenderer{
+	template.Must(template.New("login").Funcs(i18n.NewCatalog(language.Und).Localizer().FuncMap()).Parse(defaultTemplate)),
+}
+
+type templateRenderer struct {
+	tmpl *template.Template
+}
+
+func (t *templateRenderer) Render(w http.ResponseWriter, r *http.Request, page *Page) {
+	tmpl, err := t.tmpl.Clone()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tmpl.Funcs(page.Localizer.FuncMap()).Execute(w, page); err != nil {
+		slog.ErrorContext(r.Context(), "login template", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+const defaultTemplate = `<!DOCTYPE html>
+<html lang="{{lang}}">
+	<head>
+		<meta charset="UTF-8">
+		<title>{{t "login.title"}}</title>
+	</head>
+	<body style="display: flex; align-items: center; justify-content: center; height: 100vh;">
+		<form method="POST" action="{{.Action}}" style="width: 200px;">
+			<input type="hidden" name="authRequestID" value="{{.AuthRequestID}}">
+			<input type="hidden" name="csrf" value="{{.CSRFToken}}">
+			<input type="hidden" name="method" value="{{.Method}}">
+			{{- if eq .Step "identify"}}
+			<div>
+				<label for="username">{{t "login.username"}}:</label>
+				<input id="username" name="username" value="{{.LoginHint}}" style="width: 100%">
+			</div>
+			{{- else if eq .Step "consent"}}
+			<p>{{t "consent.question"}}</p>
+			{{- if .ConsentScopes}}
+			<ul>
+				{{- range .ConsentScopes}}
+				<li>{{.Description}}</li>
+				{{- end}}
+			</ul>
+			{{- end}}
+			<button type="submit" name="consent" value="accept">{{t "consent.allow"}}</button>
+			<button type="submit" name="consent" value="deny">{{t "consent.deny"}}</button>
+			{{- else if eq .Method "pwd"}}
+			<div>
+				<label for="username">{{t "login.username"}}:</label>
+				<input id="username" name="username" value="{{index .Data "username"}}" style="width: 100%">
+			</div>
+			<div>
+				<label for="password">{{t "login.password"}}:</label>
+				<input id="password" name="password" type="password" style="width: 100%">
+			</div>
+			{{- else if eq .Method "otp"}}
+			<div>
+				<label for="code">{{t "login.code"}}:</label>
+				<input id="code" name="code" autocomplete="one-time-code" style="width: 100%">
+			</div>
+			{{- end}}
+			<p style="color:red; min-height: 1rem;">{{.Error}}</p>
+			{{- if ne .Step "consent"}}
+			<button type="submit">{{t "login.next"}}</button>
+			{{- end}}
+		</form>
+	</body>
+</html>`
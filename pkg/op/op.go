@@ -2,6 +2,7 @@ package op
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -16,6 +17,7 @@ import (
 
 	httphelper "github.com/zitadel/oidc/v3/pkg/http"
 	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op/i18n"
 )
 
 const (
@@ -131,19 +133,34 @@ func CreateRouter(o OpenIDProvider, interceptors ...HttpInterceptor) chi.Router
 	} else {
 		router.Use(cors.New(defaultCORSOptions).Handler)
 	}
+	if so, ok := o.(securityHeadersOptioner); ok {
+		router.Use(securityHeadersInterceptor(so.SecurityHeaders(), endpointsOf(o)))
+	} else {
+		router.Use(securityHeadersInterceptor(DefaultSecurityHeadersConfig(), endpointsOf(o)))
+	}
 	router.Use(intercept(o.IssuerFromRequest, interceptors...))
+	router.Use(storageTimeoutInterceptor(o))
+	router.Use(keyIDInterceptor(o))
+	router.Use(opaqueTokenFormatInterceptor(o))
+	router.Use(clockInterceptor(o))
+	router.Use(uiLocaleInterceptor(o))
+	router.Use(scopeRegistryInterceptor(o))
 	router.HandleFunc(healthEndpoint, healthHandler)
 	router.HandleFunc(readinessEndpoint, readyHandler(o.Probes()))
 	router.HandleFunc(oidc.DiscoveryEndpoint, discoveryHandler(o, o.Storage()))
+	router.HandleFunc(oidc.WebFingerEndpoint, webFingerHandler(o))
 	router.HandleFunc(o.AuthorizationEndpoint().Relative(), authorizeHandler(o))
 	router.HandleFunc(authCallbackPath(o), AuthorizeCallbackHandler(o))
-	router.HandleFunc(o.TokenEndpoint().Relative(), tokenHandler(o))
-	router.HandleFunc(o.IntrospectionEndpoint().Relative(), introspectionHandler(o))
+	router.HandleFunc(o.TokenEndpoint().Relative(), withClientAuthentication(o, clientAuthenticatedGrantTypes, tokenHandler(o), writeRequestError))
+	router.HandleFunc(o.IntrospectionEndpoint().Relative(), withClientAuthentication(o, nil, introspectionHandler(o), writeIntrospectionError))
 	router.HandleFunc(o.UserinfoEndpoint().Relative(), userinfoHandler(o))
-	router.HandleFunc(o.RevocationEndpoint().Relative(), revocationHandler(o))
+	router.HandleFunc(o.RevocationEndpoint().Relative(), withClientAuthentication(o, nil, revocationHandler(o), RevocationRequestError))
 	router.HandleFunc(o.EndSessionEndpoint().Relative(), endSessionHandler(o))
-	router.HandleFunc(o.KeysEndpoint().Relative(), keysHandler(o.Storage()))
-	router.HandleFunc(o.DeviceAuthorizationEndpoint().Relative(), DeviceAuthorizationHandler(o))
+	router.HandleFunc(o.KeysEndpoint().Relative(), keysHandler(o))
+	if endpoint := signedKeysEndpoint(o); endpoint != nil {
+		router.HandleFunc(endpoint.Relative(), signedKeysHandler(o))
+	}
+	router.HandleFunc(o.DeviceAuthorizationEndpoint().Relative(), withClientAuthentication(o, nil, DeviceAuthorizationHandler(o), writeRequestError))
 	return router
 }
 
@@ -154,6 +171,21 @@ func AuthCallbackURL(o OpenIDProvider) func(context.Context, string) string {
 	}
 }
 
+// endpointsOf returns the endpoints of the configuration.
+func endpointsOf(c Configuration) Endpoints {
+	return Endpoints{
+		Authorization:       c.AuthorizationEndpoint(),
+		Token:               c.TokenEndpoint(),
+		Introspection:       c.IntrospectionEndpoint(),
+		Userinfo:            c.UserinfoEndpoint(),
+		Revocation:          c.RevocationEndpoint(),
+		EndSession:          c.EndSessionEndpoint(),
+		CheckSessionIframe:  c.CheckSessionIframe(),
+		JwksURI:             c.KeysEndpoint(),
+		DeviceAuthorization: c.DeviceAuthorizationEndpoint(),
+	}
+}
+
 func authCallbackPath(o OpenIDProvider) string {
 	return o.AuthorizationEndpoint().Relative() + authCallbackPathSuffix
 }
@@ -173,6 +205,18 @@ type Config struct {
 	DeviceAuthorization               DeviceAuthorizationConfig
 	BackChannelLogoutSupported        bool
 	BackChannelLogoutSessionSupported bool
+	// RevokeRefreshTokenWithAccessToken requests Storage implementing [CanRevokeTokenFamily]
+	// to also revoke the refresh token when one of its access tokens is revoked.
+	RevokeRefreshTokenWithAccessToken bool
+	// SupportedResponseTypes are announced in the discovery document.
+	// Defaults to code, id_token and id_token token.
+	SupportedResponseTypes []oidc.ResponseType
+	// SupportedResponseModes are announced in the discovery document.
+	// If not set, the announcement is omitted, meaning query and fragment.
+	SupportedResponseModes []oidc.ResponseMode
+	// SessionIDClaim adds the sid claim to the ID tokens of requests
+	// implementing [SessionRequest], which binds them to the session of the user.
+	SessionIDClaim bool
 }
 
 // Endpoints defines endpoint routes.
@@ -186,6 +230,9 @@ type Endpoints struct {
 	CheckSessionIframe  *Endpoint
 	JwksURI             *Endpoint
 	DeviceAuthorization *Endpoint
+	// SignedJwksURI serves the JSON Web Key Set as signed JWT,
+	// if set by [WithCustomSignedKeysEndpoint].
+	SignedJwksURI *Endpoint
 }
 
 // NewOpenIDProvider creates a provider. The provider provides (with HttpHandler())
@@ -194,6 +241,7 @@ type Endpoints struct {
 //	/healthz
 //	/ready
 //	/.well-known/openid-configuration
+//	/.well-known/webfinger
 //	/oauth/token
 //	/oauth/introspect
 //	/callback
@@ -239,6 +287,7 @@ func NewForwardedOpenIDProvider(path string, config *Config, storage Storage, op
 //	/healthz
 //	/ready
 //	/.well-known/openid-configuration
+//	/.well-known/webfinger
 //	/oauth/token
 //	/oauth/introspect
 //	/callback
@@ -269,15 +318,24 @@ func NewProvider(
 			NewAESCrypto(config.CryptoKey),
 		},
 	)
+	// copy the defaults, so the options don't modify them
+	endpoints := *DefaultEndpoints
 	o := &Provider{
 		config:            config,
 		storage:           storage,
 		accessTokenKeySet: keySet,
 		idTokenHinKeySet:  keySet,
 		crypto:            crypto,
-		endpoints:         DefaultEndpoints,
+		endpoints:         &endpoints,
 		timer:             make(<-chan time.Time),
 		corsOpts:          &defaultCORSOptions,
+		securityHeaders:   DefaultSecurityHeadersConfig(),
+		keySets:           new(keySetPolicy),
+		statelessCrypto:   easgcmCrypto,
+		clientKeys:        NewClientKeySetCache(),
+		clientJTIs:        NewMemoryJTICache(),
+		signers:           newSignerCache(),
+		clientSigningAlgs: []string{string(jose.RS256)},
 	}
 
 	for _, optFunc := range opOpts {
@@ -286,6 +344,24 @@ func NewProvider(
 		}
 	}
 
+	if config.SessionIDClaim {
+		// set before the hooks of the options, so they can still modify the claim
+		o.idTokenClaimsHooks = append([]IDTokenClaimsHook{sessionIDClaimHook}, o.idTokenClaimsHooks...)
+	}
+
+	if o.cache != nil && o.clientKeys != nil && o.clientKeys.shared == nil {
+		o.clientKeys.shared = o.cache.cache
+	}
+	if o.clientKeys != nil && o.clientKeys.httpClient == nil {
+		o.clientKeys.httpClient = o.httpClient
+	}
+
+	if userCode := config.DeviceAuthorization.UserCode; userCode.CharSet != "" {
+		if err := userCode.Validate(config.DeviceAuthorization.MinUserCodeEntropy); err != nil {
+			return nil, err
+		}
+	}
+
 	o.issuer, err = issuer(o.insecure)
 	if err != nil {
 		return nil, err
@@ -313,7 +389,40 @@ type Provider struct {
 	timer                   <-chan time.Time
 	accessTokenVerifierOpts []AccessTokenVerifierOpt
 	idTokenHintVerifierOpts []IDTokenHintVerifierOpt
+	jwtProfileVerifierOpts  []JWTProfileVerifierOption
 	corsOpts                *cors.Options
+	securityHeaders         *SecurityHeadersConfig
+	keyIDFunc               KeyIDFunc
+	keySets                 *keySetPolicy
+	statelessAuthRequests   time.Duration
+	statelessCrypto         Crypto
+	eventListeners          []EventListener
+	opaqueAccessTokens      *OpaqueTokenFormat
+	opaqueRefreshTokens     *OpaqueTokenFormat
+	clock                   Clock
+	tokenIDSource           TokenIDSource
+	clientTypePolicies      func(Client) ClientTypePolicy
+	scopeRegistry           *ScopeRegistry
+	jwtIntrospection        bool
+	accessTokenRevoked      AccessTokenRevocationCheck
+	clientAuthenticators    []ClientAuthenticator
+	clientKeys              *ClientKeySetCache
+	clientJTIs              JTICache
+	errorPageCatalog        *i18n.Catalog
+	idTokenClaimsHooks      []IDTokenClaimsHook
+	accessTokenClaimsHooks  []AccessTokenClaimsHook
+	tokenResponseHooks      []TokenResponseHook
+	grantPolicies           []GrantPolicy
+	storageTimeout          time.Duration
+	hintParameters          []string
+	sessionCookie           string
+	accessTokenFormats      map[string]AccessTokenFormat
+	webFingerResolver       WebFingerResolver
+	clientSigningAlgs       []string
+	cache                   *providerCache
+	signers                 *signerCache
+	backChannelLogoutClient *http.Client
+	httpClient              *http.Client
 }
 
 func (o *Provider) IssuerFromRequest(r *http.Request) string {
@@ -360,6 +469,10 @@ func (o *Provider) KeysEndpoint() *Endpoint {
 	return o.endpoints.JwksURI
 }
 
+func (o *Provider) SignedKeysEndpoint() *Endpoint {
+	return o.endpoints.SignedJwksURI
+}
+
 func (o *Provider) AuthMethodPostSupported() bool {
 	return o.config.AuthMethodPost
 }
@@ -373,7 +486,7 @@ func (o *Provider) AuthMethodPrivateKeyJWTSupported() bool {
 }
 
 func (o *Provider) TokenEndpointSigningAlgorithmsSupported() []string {
-	return []string{"RS256"}
+	return o.clientSigningAlgs
 }
 
 func (o *Provider) GrantTypeRefreshTokenSupported() bool {
@@ -399,7 +512,7 @@ func (o *Provider) IntrospectionAuthMethodPrivateKeyJWTSupported() bool {
 }
 
 func (o *Provider) IntrospectionEndpointSigningAlgorithmsSupported() []string {
-	return []string{"RS256"}
+	return o.clientSigningAlgs
 }
 
 func (o *Provider) GrantTypeClientCredentialsSupported() bool {
@@ -412,7 +525,7 @@ func (o *Provider) RevocationAuthMethodPrivateKeyJWTSupported() bool {
 }
 
 func (o *Provider) RevocationEndpointSigningAlgorithmsSupported() []string {
-	return []string{"RS256"}
+	return o.clientSigningAlgs
 }
 
 func (o *Provider) RequestObjectSupported() bool {
@@ -420,7 +533,7 @@ func (o *Provider) RequestObjectSupported() bool {
 }
 
 func (o *Provider) RequestObjectSigningAlgorithmsSupported() []string {
-	return []string{"RS256"}
+	return o.clientSigningAlgs
 }
 
 func (o *Provider) SupportedUILocales() []language.Tag {
@@ -431,6 +544,14 @@ func (o *Provider) DeviceAuthorization() DeviceAuthorizationConfig {
 	return o.config.DeviceAuthorization
 }
 
+func (o *Provider) SupportedResponseTypes() []oidc.ResponseType {
+	return o.config.SupportedResponseTypes
+}
+
+func (o *Provider) SupportedResponseModes() []oidc.ResponseMode {
+	return o.config.SupportedResponseModes
+}
+
 func (o *Provider) BackChannelLogoutSupported() bool {
 	return o.config.BackChannelLogoutSupported
 }
@@ -439,6 +560,19 @@ func (o *Provider) BackChannelLogoutSessionSupported() bool {
 	return o.config.BackChannelLogoutSessionSupported
 }
 
+// BackChannelLogoutHTTPClient returns the client set by [WithBackChannelLogoutHTTPClient],
+// or else by [WithHTTPClient].
+func (o *Provider) BackChannelLogoutHTTPClient() *http.Client {
+	if o.backChannelLogoutClient != nil {
+		return o.backChannelLogoutClient
+	}
+	return o.httpClient
+}
+
+func (o *Provider) RevokeRefreshTokenWithAccessToken() bool {
+	return o.config.RevokeRefreshTokenWithAccessToken
+}
+
 func (o *Provider) Storage() Storage {
 	return o.storage
 }
@@ -456,7 +590,15 @@ func (o *Provider) IDTokenHintVerifier(ctx context.Context) *IDTokenHintVerifier
 }
 
 func (o *Provider) JWTProfileVerifier(ctx context.Context) *JWTProfileVerifier {
-	return NewJWTProfileVerifier(o.Storage(), IssuerFromContext(ctx), 1*time.Hour, time.Second)
+	issuer := IssuerFromContext(ctx)
+	opts := append([]JWTProfileVerifierOption{
+		WithAssertionAudiences(o.TokenEndpoint().Absolute(issuer)),
+		WithClientKeys(o.clientKeys),
+		withClientAssertionJTICache(o.clientJTIs),
+	}, o.jwtProfileVerifierOpts...)
+	verifier := NewJWTProfileVerifier(o.Storage(), issuer, 1*time.Hour, time.Second, opts...)
+	verifier.SupportedSignAlgs = o.clientSigningAlgs
+	return verifier
 }
 
 func (o *Provider) AccessTokenVerifier(ctx context.Context) *AccessTokenVerifier {
@@ -477,6 +619,71 @@ func (o *Provider) Probes() []ProbesFn {
 	}
 }
 
+func (o *Provider) JWTAccessTokenIntrospection() (bool, AccessTokenRevocationCheck) {
+	return o.jwtIntrospection, o.accessTokenRevoked
+}
+
+// ClientAuthenticators returns the [DefaultClientAuthenticators],
+// extended or overridden by the ones passed with [WithClientAuthenticators].
+func (o *Provider) ClientAuthenticators() []ClientAuthenticator {
+	return mergeClientAuthenticators(DefaultClientAuthenticators(o), o.clientAuthenticators)
+}
+
+// ClientKeySetCache returns the cache of the keys registered by clients
+// with jwks or jwks_uri. Storage implementations can use it to invalidate
+// the keys when a client registration changes.
+func (o *Provider) ClientKeySetCache() *ClientKeySetCache {
+	return o.clientKeys
+}
+
+// Cache returns the cache set by [WithCache], or nil.
+func (o *Provider) Cache() Cache {
+	if o.cache == nil {
+		return nil
+	}
+	return o.cache.cache
+}
+
+func (o *Provider) providerCache() *providerCache {
+	return o.cache
+}
+
+func (o *Provider) signerCache() *signerCache {
+	return o.signers
+}
+
+// InvalidateClient removes the cached client and its keys,
+// so they are loaded again on next use.
+// Storage implementations should call it when a client changes.
+// The issuer of the client is read from the context, see [ContextWithIssuer].
+func (o *Provider) InvalidateClient(ctx context.Context, clientID string) error {
+	if o.clientKeys != nil {
+		o.clientKeys.Invalidate(clientID)
+	}
+	return o.cache.invalidateClient(ctx, clientID)
+}
+
+// ErrorPageCatalog returns the catalog of the localized error pages,
+// or nil if they are not enabled by [WithLocalizedErrorPages].
+func (o *Provider) ErrorPageCatalog() *i18n.Catalog {
+	return o.errorPageCatalog
+}
+
+// IDTokenClaimsHooks returns the hooks added by [WithIDTokenClaimsHook].
+func (o *Provider) IDTokenClaimsHooks() []IDTokenClaimsHook {
+	return o.idTokenClaimsHooks
+}
+
+// AccessTokenClaimsHooks returns the hooks added by [WithAccessTokenClaimsHook].
+func (o *Provider) AccessTokenClaimsHooks() []AccessTokenClaimsHook {
+	return o.accessTokenClaimsHooks
+}
+
+// TokenResponseHooks returns the hooks added by [WithTokenResponseHook].
+func (o *Provider) TokenResponseHooks() []TokenResponseHook {
+	return o.tokenResponseHooks
+}
+
 func (o *Provider) CORSOptions() *cors.Options {
 	return o.corsOpts
 }
@@ -499,12 +706,18 @@ type OpenIDKeySet struct {
 // VerifySignature implements the oidc.KeySet interface
 // providing an implementation for the keys stored in the OP Storage interface
 func (o *OpenIDKeySet) VerifySignature(ctx context.Context, jws *jose.JSONWebSignature) ([]byte, error) {
-	keySet, err := o.Storage.KeySet(ctx)
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	keySet, err := o.Storage.KeySet(storageCtx)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching keys: %w", err)
+	}
+	webKeys, err := jsonWebKeySet(ctx, keySet)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching keys: %w", err)
 	}
 	keyID, alg := oidc.GetKeyIDAndAlg(jws)
-	key, err := oidc.FindMatchingKey(keyID, oidc.KeyUseSignature, alg, jsonWebKeySet(keySet).Keys...)
+	key, err := oidc.FindMatchingKey(keyID, oidc.KeyUseSignature, alg, webKeys.Keys...)
 	if err != nil {
 		return nil, fmt.Errorf("invalid signature: %w", err)
 	}
@@ -592,6 +805,19 @@ func WithCustomKeysEndpoint(endpoint *Endpoint) Option {
 	}
 }
 
+// WithCustomSignedKeysEndpoint serves the JSON Web Key Set as JWT signed by the current signing key
+// at the endpoint, which is announced as signed_jwks_uri in the discovery.
+// It is not served by default.
+func WithCustomSignedKeysEndpoint(endpoint *Endpoint) Option {
+	return func(o *Provider) error {
+		if err := endpoint.Validate(); err != nil {
+			return err
+		}
+		o.endpoints.SignedJwksURI = endpoint
+		return nil
+	}
+}
+
 func WithCustomDeviceAuthorizationEndpoint(endpoint *Endpoint) Option {
 	return func(o *Provider) error {
 		if err := endpoint.Validate(); err != nil {
@@ -661,6 +887,162 @@ func WithIDTokenHintVerifierOpts(opts ...IDTokenHintVerifierOpt) Option {
 	}
 }
 
+// WithJWTAccessTokenIntrospection makes the introspection endpoint validate
+// JWT access tokens locally (signature, issuer and expiration), so no Storage
+// lookup is needed and all RFC 7662 fields are taken from the token claims.
+// The optional revocationCheck is called for every valid token and can be used
+// to consult a deny-list of revoked tokens.
+// Opaque access tokens are still introspected through the Storage.
+func WithJWTAccessTokenIntrospection(revocationCheck AccessTokenRevocationCheck) Option {
+	return func(o *Provider) error {
+		o.jwtIntrospection = true
+		o.accessTokenRevoked = revocationCheck
+		return nil
+	}
+}
+
+// WithClientAuthenticators adds custom client authentication methods,
+// such as HMAC request signing, to the token, introspection, revocation
+// and device authorization endpoints of the [Provider] and of the [Server]
+// created with [NewLegacyServer]. An authenticator replaces the default one for the same [oidc.AuthMethod].
+func WithClientAuthenticators(authenticators ...ClientAuthenticator) Option {
+	return func(o *Provider) error {
+		o.clientAuthenticators = append(o.clientAuthenticators, authenticators...)
+		return nil
+	}
+}
+
+// WithJWTProfileVerifierOpts adds options for the verification of
+// JWT Profile assertions, used by the private_key_jwt client authentication
+// and the JWT Profile authorization grant. Options of repeated calls are appended.
+// For example, [WithJTICache] also protects JWT Profile grants against replayed assertions,
+// while client assertions are protected by default, see [WithClientAssertionJTICache].
+// The token endpoint URL is always accepted as audience, next to the issuer.
+func WithJWTProfileVerifierOpts(opts ...JWTProfileVerifierOption) Option {
+	return func(o *Provider) error {
+		o.jwtProfileVerifierOpts = append(o.jwtProfileVerifierOpts, opts...)
+		return nil
+	}
+}
+
+// WithClientAssertionJTICache sets the cache protecting against replayed assertions
+// of clients authenticating with private_key_jwt, which defaults to a [MemoryJTICache].
+// Deployments with multiple instances of the OP should use a cache shared between the instances.
+// A nil cache disables the replay protection, unless set for all assertions by [WithJTICache].
+func WithClientAssertionJTICache(cache JTICache) Option {
+	return func(o *Provider) error {
+		o.clientJTIs = cache
+		return nil
+	}
+}
+
+// WithClientKeySetCache sets the cache for keys registered by clients
+// with jwks or jwks_uri, for example to use a custom http.Client.
+// See [HasJWKS] and [HasJWKSURI].
+func WithClientKeySetCache(cache *ClientKeySetCache) Option {
+	return func(o *Provider) error {
+		o.clientKeys = cache
+		return nil
+	}
+}
+
+// WithCache caches client lookups, keys of clients, the discovery document
+// and introspection responses in the cache, as set by the config.
+// Use [NewMemoryCache] for a single instance of the OP, or a cache shared
+// between the instances, see [Cache].
+func WithCache(cache Cache, config CacheConfig) Option {
+	return func(o *Provider) error {
+		if cache == nil {
+			return errors.New("cache must not be nil")
+		}
+		o.cache = &providerCache{cache: cache, config: config}
+		return nil
+	}
+}
+
+// WithBackChannelLogoutHTTPClient sets the client used to deliver logout tokens
+// to the backchannel_logout_uri of the clients, when [Config.BackChannelLogoutSupported] is set.
+// By default, a client with a timeout of 5 seconds is used.
+func WithBackChannelLogoutHTTPClient(client *http.Client) Option {
+	return func(o *Provider) error {
+		o.backChannelLogoutClient = client
+		return nil
+	}
+}
+
+// WithHTTPClient sets the client for the outgoing requests of the OP,
+// such as fetching the jwks_uri of clients and delivering back-channel logout tokens.
+// Clients set by [WithClientKeySetHTTPClient] and [WithBackChannelLogoutHTTPClient] take precedence.
+// By default, clients with a timeout are used.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *Provider) error {
+		o.httpClient = client
+		return nil
+	}
+}
+
+// WithLocalizedErrorPages renders authorization errors, which cannot be
+// redirected to the client, as HTML pages in the language of the ui_locales
+// or the Accept-Language header instead of plain text.
+// If catalog is nil, the built-in messages of [i18n.NewDefaultCatalog] are used.
+func WithLocalizedErrorPages(catalog *i18n.Catalog) Option {
+	return func(o *Provider) error {
+		if catalog == nil {
+			catalog = i18n.NewDefaultCatalog()
+		}
+		o.errorPageCatalog = catalog
+		return nil
+	}
+}
+
+// WithIDTokenClaimsHook adds hooks, which are called in order with the claims
+// of every ID token just before it is signed.
+// They allow to add custom claims without changes to the Storage.
+func WithIDTokenClaimsHook(hooks ...IDTokenClaimsHook) Option {
+	return func(o *Provider) error {
+		o.idTokenClaimsHooks = append(o.idTokenClaimsHooks, hooks...)
+		return nil
+	}
+}
+
+// WithAccessTokenClaimsHook adds hooks, which are called in order with the claims
+// of every JWT access token just before it is signed.
+// They allow to add custom claims without changes to the Storage.
+func WithAccessTokenClaimsHook(hooks ...AccessTokenClaimsHook) Option {
+	return func(o *Provider) error {
+		o.accessTokenClaimsHooks = append(o.accessTokenClaimsHooks, hooks...)
+		return nil
+	}
+}
+
+// WithTokenResponseHook adds hooks, which are called in order with the extra
+// members of every token endpoint response.
+// They allow to return custom members, like refresh_expires_in.
+func WithTokenResponseHook(hooks ...TokenResponseHook) Option {
+	return func(o *Provider) error {
+		o.tokenResponseHooks = append(o.tokenResponseHooks, hooks...)
+		return nil
+	}
+}
+
+// WithClientSigningAlgorithms sets the algorithms accepted for JWTs signed by clients,
+// which are request objects and client assertions (private_key_jwt) at the token,
+// introspection and revocation endpoints. They are announced in the discovery.
+// Defaults to RS256.
+// Clients can restrict the algorithm with [HasRequestObjectSigningAlg] and [HasTokenEndpointAuthSigningAlg].
+func WithClientSigningAlgorithms(algs ...jose.SignatureAlgorithm) Option {
+	return func(o *Provider) error {
+		if len(algs) == 0 {
+			return errors.New("at least one client signing algorithm is required")
+		}
+		o.clientSigningAlgs = make([]string, len(algs))
+		for i, alg := range algs {
+			o.clientSigningAlgs[i] = string(alg)
+		}
+		return nil
+	}
+}
+
 func WithCORSOptions(opts *cors.Options) Option {
 	return func(o *Provider) error {
 		o.corsOpts = opts
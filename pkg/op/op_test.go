@@ -18,6 +18,7 @@ import (
 	"github.com/zitadel/oidc/v3/example/server/storage"
 	"github.com/zitadel/oidc/v3/pkg/oidc"
 	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/optest"
 	"golang.org/x/text/language"
 )
 
@@ -84,6 +85,27 @@ func newTestProvider(config *op.Config) op.OpenIDProvider {
 	return provider
 }
 
+// withExampleStorage serves the optest server with the storage of the example server,
+// for the features the optest.MemoryStorage does not implement, such as sessions,
+// silent authentication, stateless auth requests and opaque access tokens.
+// The canned clients of optest are registered, unless clients are passed.
+func withExampleStorage(clients ...*storage.Client) optest.Option {
+	if len(clients) == 0 {
+		clients = []*storage.Client{
+			storage.WebClient(optest.WebClientID, optest.WebClientSecret, optest.RedirectURI),
+			storage.NativeClient(optest.NativeClientID, optest.RedirectURI),
+			storage.DeviceClient(optest.DeviceClientID, optest.DeviceClientSecret),
+		}
+	}
+	return optest.WithStorage(func(issuer string) (optest.Storage, error) {
+		registered := make(map[string]*storage.Client, len(clients))
+		for _, client := range clients {
+			registered[client.GetID()] = client
+		}
+		return storage.NewStorageWithClients(storage.NewUserStore(issuer), registered), nil
+	})
+}
+
 type routesTestStorage interface {
 	op.Storage
 	AuthRequestDone(id string) error
@@ -333,6 +355,17 @@ func TestRoutes(t *testing.T) {
 			},
 			wantCode: http.StatusOK,
 		},
+		{
+			name:      "revoke unknown token",
+			method:    http.MethodGet,
+			path:      testProvider.RevocationEndpoint().Relative(),
+			basicAuth: &basicAuth{"web", "secret"},
+			values: map[string]string{
+				"token":           "unknown",
+				"token_type_hint": "access_token",
+			},
+			wantCode: http.StatusOK,
+		},
 		{
 			name:   "end session",
 			method: http.MethodGet,
@@ -345,6 +378,40 @@ func TestRoutes(t *testing.T) {
 			headerContains: map[string]string{"Location": "/logged-out"},
 			contains:       []string{`<a href="/logged-out">Found</a>.`},
 		},
+		{
+			name:   "end session with POST",
+			method: http.MethodPost,
+			path:   testProvider.EndSessionEndpoint().Relative(),
+			header: map[string]string{
+				"Content-Type": "application/x-www-form-urlencoded",
+			},
+			body: map[string]string{
+				"id_token_hint": idToken,
+				"client_id":     "web",
+			},
+			wantCode:       http.StatusFound,
+			headerContains: map[string]string{"Location": "/logged-out"},
+		},
+		{
+			name:   "end session with PUT",
+			method: http.MethodPut,
+			path:   testProvider.EndSessionEndpoint().Relative(),
+			values: map[string]string{
+				"id_token_hint": idToken,
+				"client_id":     "web",
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:   "end session post_logout_redirect_uri without client",
+			method: http.MethodGet,
+			path:   testProvider.EndSessionEndpoint().Relative(),
+			values: map[string]string{
+				"post_logout_redirect_uri": "https://example.com/logged-out",
+				"state":                    "state1",
+			},
+			wantCode: http.StatusBadRequest,
+		},
 		{
 			name:     "keys",
 			method:   http.MethodGet,
@@ -469,3 +536,32 @@ func TestWithCustomEndpoints(t *testing.T) {
 		})
 	}
 }
+
+func TestProviderSecurityHeaders(t *testing.T) {
+	storage := storage.NewStorage(storage.NewUserStore(testIssuer))
+	provider, err := op.NewOpenIDProvider(testIssuer, testConfig, storage,
+		op.WithAllowInsecure(),
+		op.WithEndpointSecurityHeaders(op.NewEndpoint("end_session"), op.DefaultSecurityHeaders.FramedBy("https://example.com")),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		path         string
+		frameOptions string
+		cacheControl string
+	}{
+		{path: oidc.DiscoveryEndpoint, frameOptions: "DENY"},
+		{path: "/oauth/token", frameOptions: "DENY", cacheControl: "no-store"},
+		{path: "/userinfo", frameOptions: "DENY", cacheControl: "no-store"},
+		{path: "/end_session"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			provider.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, tt.frameOptions, rec.Header().Get("X-Frame-Options"))
+			assert.Equal(t, tt.cacheControl, rec.Header().Get("Cache-Control"))
+			assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package op
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// OpaqueTokenAlphabet is the URL-safe alphabet of RFC 4648 base64url,
+	// the default alphabet of an [OpaqueTokenFormat].
+	OpaqueTokenAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+	// defaultOpaqueTokenLength provides 258 bits of entropy with the default alphabet.
+	defaultOpaqueTokenLength = 43
+	// minOpaqueTokenEntropy is the minimal entropy in bits of the random part of tokens.
+	minOpaqueTokenEntropy = 128
+)
+
+// OpaqueTokenFormat describes opaque tokens consisting of a prefix and a random part,
+// e.g. "myap_at_" and 43 characters of the URL-safe alphabet.
+// Identifiable prefixes allow secret scanners, such as GitHub secret scanning,
+// to detect leaked tokens. See [WithOpaqueTokenFormats].
+type OpaqueTokenFormat struct {
+	// Prefix of the tokens, which is stripped before the storage lookup.
+	Prefix string
+	// Length of the random part in characters. Defaults to 43.
+	Length int
+	// Alphabet of the random part. It must be URL-safe. Defaults to [OpaqueTokenAlphabet].
+	Alphabet string
+}
+
+func (f *OpaqueTokenFormat) length() int {
+	if f.Length > 0 {
+		return f.Length
+	}
+	return defaultOpaqueTokenLength
+}
+
+func (f *OpaqueTokenFormat) alphabet() string {
+	if f.Alphabet != "" {
+		return f.Alphabet
+	}
+	return OpaqueTokenAlphabet
+}
+
+// Entropy returns the entropy of the random part in bits.
+func (f *OpaqueTokenFormat) Entropy() float64 {
+	return float64(f.length()) * math.Log2(float64(len(f.alphabet())))
+}
+
+// Validate checks that the prefix and alphabet are URL-safe, the alphabet has no duplicates
+// and the random part has at least 128 bits of entropy.
+func (f *OpaqueTokenFormat) Validate() error {
+	if !isURLSafe(f.Prefix) {
+		return errors.New("opaque token prefix must be URL-safe")
+	}
+	alphabet := f.alphabet()
+	if len(alphabet) < 2 || !isURLSafe(alphabet) {
+		return errors.New("opaque token alphabet must consist of at least two URL-safe characters")
+	}
+	for i := range len(alphabet) {
+		if strings.IndexByte(alphabet[i+1:], alphabet[i]) >= 0 {
+			return errors.New("opaque token alphabet must not contain duplicates")
+		}
+	}
+	if f.Entropy() < minOpaqueTokenEntropy {
+		return errors.New("opaque token entropy must be at least 128 bits")
+	}
+	return nil
+}
+
+// isURLSafe reports whether s only consists of unreserved characters of RFC 3986.
+func isURLSafe(s string) bool {
+	for _, c := range []byte(s) {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("-._~", c) >= 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// Random returns a new random part of the format, without the prefix.
+// Each character is chosen uniformly from the alphabet.
+func (f *OpaqueTokenFormat) Random() (string, error) {
+	alphabet := f.alphabet()
+	// bytes above the largest multiple of the alphabet size are rejected to avoid modulo bias
+	limit := 256 - 256%len(alphabet)
+	value := make([]byte, 0, f.length())
+	buf := make([]byte, f.length())
+	for len(value) < cap(value) {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(value) < cap(value) {
+				value = append(value, alphabet[int(b)%len(alphabet)])
+			}
+		}
+	}
+	return string(value), nil
+}
+
+// Generate returns a new token of the format.
+func (f *OpaqueTokenFormat) Generate() (string, error) {
+	value, err := f.Random()
+	if err != nil {
+		return "", err
+	}
+	return f.Prefix + value, nil
+}
+
+// Parse strips the prefix of the token and returns the remaining value,
+// which is used for the storage lookup. It reports false for tokens without
+// the prefix. The length and alphabet of the value are only checked if they are set,
+// so Parse should only be used for random tokens created by [OpaqueTokenFormat.Generate].
+func (f *OpaqueTokenFormat) Parse(token string) (string, bool) {
+	value, ok := strings.CutPrefix(token, f.Prefix)
+	if !ok || value == "" {
+		return "", false
+	}
+	if f.Length > 0 && len(value) != f.Length {
+		return "", false
+	}
+	if f.Alphabet != "" {
+		for _, c := range []byte(value) {
+			if strings.IndexByte(f.Alphabet, c) < 0 {
+				return "", false
+			}
+		}
+	}
+	return value, true
+}
+
+// random reports whether the tokens are random values of the format,
+// instead of prefixed values of the OP or storage.
+func (f *OpaqueTokenFormat) random() bool {
+	return f.Length > 0 || f.Alphabet != ""
+}
+
+// OpaqueAccessTokenStorage is an optional additional interface that must be implemented by
+// implementors of Storage to issue random opaque access tokens with [WithOpaqueTokenFormats].
+// The storage maps the random values to the tokens created by CreateAccessToken and
+// CreateAccessAndRefreshTokens. Revoked or expired tokens must not be returned.
+type OpaqueAccessTokenStorage interface {
+	SaveOpaqueAccessToken(ctx context.Context, value, tokenID, subject string, expiration time.Time) error
+	// OpaqueAccessToken returns the ID and subject of the access token of the value,
+	// which is passed without the prefix.
+	OpaqueAccessToken(ctx context.Context, value string) (tokenID, subject string, err error)
+}
+
+// WithOpaqueTokenFormats customizes the opaque access and refresh tokens. A nil format
+// keeps the default tokens.
+//
+// Access tokens of clients with [AccessTokenTypeBearer] are random values of the format,
+// which are stored by the [OpaqueAccessTokenStorage]. If the format only sets a Prefix,
+// the default encrypted access tokens are prefixed instead and no storage is required.
+//
+// Refresh tokens created by the storage are prefixed by the OP and the prefix is stripped
+// before they are passed to the storage again, so the storage only handles the values.
+// Implementations should create the values with [NewRefreshTokenValue], which uses the
+// length and alphabet of the refresh token format. They are not checked on refresh.
+//
+// Tokens without the prefix, e.g. issued before the prefix was configured, are still accepted.
+func WithOpaqueTokenFormats(accessToken, refreshToken *OpaqueTokenFormat) Option {
+	return func(o *Provider) error {
+		for _, format := range []*OpaqueTokenFormat{accessToken, refreshToken} {
+			if format == nil {
+				continue
+			}
+			if err := format.Validate(); err != nil {
+				return err
+			}
+		}
+		if accessToken != nil && accessToken.random() {
+			if _, ok := o.storage.(OpaqueAccessTokenStorage); !ok {
+				return errors.New("storage must implement OpaqueAccessTokenStorage for random opaque access tokens")
+			}
+		}
+		o.opaqueAccessTokens, o.opaqueRefreshTokens = accessToken, refreshToken
+		return nil
+	}
+}
+
+// OpaqueTokenFormats returns the formats set by [WithOpaqueTokenFormats].
+func (o *Provider) OpaqueTokenFormats() (accessToken, refreshToken *OpaqueTokenFormat) {
+	return o.opaqueAccessTokens, o.opaqueRefreshTokens
+}
+
+type opaqueTokenFormatsProvider interface {
+	OpaqueTokenFormats() (accessToken, refreshToken *OpaqueTokenFormat)
+}
+
+func opaqueTokenFormatsFrom(v any) (accessToken, refreshToken *OpaqueTokenFormat) {
+	if p, ok := v.(opaqueTokenFormatsProvider); ok {
+		return p.OpaqueTokenFormats()
+	}
+	return nil, nil
+}
+
+type refreshTokenFormatKey struct{}
+
+// NewRefreshTokenValue returns a random value for a new refresh token,
+// of the refresh token format of [WithOpaqueTokenFormats] or the default format.
+// The prefix is not included, as it is added by the OP.
+// Storages should call it with the context passed to CreateAccessAndRefreshTokens.
+func NewRefreshTokenValue(ctx context.Context) (string, error) {
+	format, ok := ctx.Value(refreshTokenFormatKey{}).(*OpaqueTokenFormat)
+	if !ok {
+		format = &OpaqueTokenFormat{}
+	}
+	return format.Random()
+}
+
+// opaqueTokenFormatInterceptor sets the refresh token format of the provider on the context of the requests,
+// for [NewRefreshTokenValue].
+func opaqueTokenFormatInterceptor(v any) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		_, format := opaqueTokenFormatsFrom(v)
+		if format == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), refreshTokenFormatKey{}, format)))
+		})
+	}
+}
+
+// formatRefreshToken prefixes the refresh token created by the storage.
+func formatRefreshToken(provider TokenCreator, refreshToken string) string {
+	if _, format := opaqueTokenFormatsFrom(provider); format != nil && refreshToken != "" {
+		return format.Prefix + refreshToken
+	}
+	return refreshToken
+}
+
+// parseRefreshToken strips the prefix of the refresh token for the storage lookup.
+// The value is not checked against the length and alphabet of the format,
+// as the storage may create refresh tokens without [NewRefreshTokenValue].
+// Tokens without the prefix are passed unchanged.
+func parseRefreshToken(provider TokenCreator, refreshToken string) string {
+	if _, format := opaqueTokenFormatsFrom(provider); format != nil && format.Prefix != "" {
+		if value, ok := strings.CutPrefix(refreshToken, format.Prefix); ok && value != "" {
+			return value
+		}
+	}
+	return refreshToken
+}
+
+// createOpaqueAccessToken creates the access token of [WithOpaqueTokenFormats], if configured.
+func createOpaqueAccessToken(ctx context.Context, creator TokenCreator, tokenID, subject string, exp time.Time) (token string, ok bool, err error) {
+	format, _ := opaqueTokenFormatsFrom(creator)
+	if format == nil {
+		return "", false, nil
+	}
+	if !format.random() {
+		token, err = CreateBearerToken(tokenID, subject, creator.Crypto())
+		if err != nil {
+			return "", true, err
+		}
+		return format.Prefix + token, true, nil
+	}
+	storage, ok := creator.Storage().(OpaqueAccessTokenStorage)
+	if !ok {
+		return "", true, errors.New("storage does not implement OpaqueAccessTokenStorage")
+	}
+	value, err := format.Random()
+	if err != nil {
+		return "", true, err
+	}
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	if err := storage.SaveOpaqueAccessToken(storageCtx, value, tokenID, subject, exp); err != nil {
+		return "", true, err
+	}
+	return format.Prefix + value, true, nil
+}
+
+// parseOpaqueAccessToken returns the ID and subject of a random access token of [WithOpaqueTokenFormats].
+// Otherwise ok is false and the returned token is stripped of the prefix of the format,
+// to be decrypted as default access token.
+func parseOpaqueAccessToken(ctx context.Context, provider UserinfoProvider, accessToken string) (token, tokenID, subject string, ok bool) {
+	format, _ := opaqueTokenFormatsFrom(provider)
+	if format == nil {
+		return accessToken, "", "", false
+	}
+	value, matches := format.Parse(accessToken)
+	if !matches {
+		return accessToken, "", "", false
+	}
+	if !format.random() {
+		return value, "", "", false
+	}
+	storage, isStorage := provider.Storage().(OpaqueAccessTokenStorage)
+	if !isStorage {
+		return accessToken, "", "", false
+	}
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	tokenID, subject, err := storage.OpaqueAccessToken(storageCtx, value)
+	if err != nil {
+		return accessToken, "", "", false
+	}
+	return accessToken, tokenID, subject, true
+}
@@ -0,0 +1,29 @@
+package op
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseRefreshToken(t *testing.T) {
+	provider := &Provider{opaqueRefreshTokens: &OpaqueTokenFormat{Prefix: "myap_rt_", Length: 48, Alphabet: "abcdefghijklmnopqrstuvwxyz234567"}}
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{"value of the format", "myap_rt_" + strings.Repeat("a", 48), strings.Repeat("a", 48)},
+		// e.g. created by the storage without NewRefreshTokenValue
+		{"other value", "myap_rt_0f8fad5b-d9cb-469f-a165-70867728950e", "0f8fad5b-d9cb-469f-a165-70867728950e"},
+		{"without prefix", "0f8fad5b-d9cb-469f-a165-70867728950e", "0f8fad5b-d9cb-469f-a165-70867728950e"},
+		{"prefix only", "myap_rt_", "myap_rt_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseRefreshToken(provider, tt.token))
+		})
+	}
+	assert.Equal(t, "myap_rt_value", parseRefreshToken(&Provider{}, "myap_rt_value"), "no format")
+}
@@ -0,0 +1,103 @@
+package op_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/optest"
+)
+
+func TestOpaqueTokenFormat(t *testing.T) {
+	format := &op.OpaqueTokenFormat{Prefix: "myap_at_", Length: 32, Alphabet: "abcdefghijklmnopqrstuvwxyz234567"}
+	require.NoError(t, format.Validate())
+	assert.Equal(t, 160.0, format.Entropy())
+
+	token, err := format.Generate()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(token, "myap_at_"))
+	value, ok := format.Parse(token)
+	require.True(t, ok)
+	assert.Len(t, value, 32)
+	assert.Equal(t, strings.TrimPrefix(token, "myap_at_"), value)
+
+	for _, invalid := range []string{value, "myap_at_", "myap_at_" + value[1:], "myap_at_" + strings.ToUpper(value)} {
+		_, ok := format.Parse(invalid)
+		assert.False(t, ok, invalid)
+	}
+
+	assert.NoError(t, (&op.OpaqueTokenFormat{}).Validate(), "defaults")
+	for _, invalid := range []*op.OpaqueTokenFormat{
+		{Prefix: "my ap"},
+		{Alphabet: "ab+/"},
+		{Alphabet: "aab"},
+		{Length: 16},
+	} {
+		assert.Error(t, invalid.Validate(), "%+v", invalid)
+	}
+}
+
+func TestNewRefreshTokenValue(t *testing.T) {
+	value, err := op.NewRefreshTokenValue(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, value, 43)
+}
+
+func TestWithOpaqueTokenFormats(t *testing.T) {
+	tests := []struct {
+		name        string
+		accessToken *op.OpaqueTokenFormat
+	}{
+		{
+			name:        "random",
+			accessToken: &op.OpaqueTokenFormat{Prefix: "myap_at_", Length: 40},
+		},
+		{
+			name:        "prefix only",
+			accessToken: &op.OpaqueTokenFormat{Prefix: "myap_at_"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refreshToken := &op.OpaqueTokenFormat{Prefix: "myap_rt_", Length: 48}
+			s := optest.New(t, withExampleStorage(), optest.WithProviderOptions(op.WithOpaqueTokenFormats(tt.accessToken, refreshToken)))
+			relyingParty := s.RelyingParty(t)
+			ctx := context.Background()
+
+			tokens := s.CodeFlow(t, relyingParty)
+			require.True(t, strings.HasPrefix(tokens.AccessToken, "myap_at_"), tokens.AccessToken)
+			if tt.accessToken.Length > 0 {
+				assert.Len(t, tokens.AccessToken, len("myap_at_")+40)
+			}
+			require.True(t, strings.HasPrefix(tokens.RefreshToken, "myap_rt_"), tokens.RefreshToken)
+			assert.Len(t, tokens.RefreshToken, len("myap_rt_")+48)
+
+			info, err := rp.Userinfo[*oidc.UserInfo](ctx, tokens.AccessToken, oidc.BearerToken, optest.UserID, relyingParty)
+			require.NoError(t, err)
+			assert.Equal(t, optest.UserID, info.Subject)
+
+			refreshed, err := rp.RefreshTokens[*oidc.IDTokenClaims](ctx, relyingParty, tokens.RefreshToken, "", "")
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(refreshed.RefreshToken, "myap_rt_"), refreshed.RefreshToken)
+			_, err = rp.RefreshTokens[*oidc.IDTokenClaims](ctx, relyingParty, tokens.RefreshToken, "", "")
+			assert.Error(t, err, "rotated")
+
+			require.NoError(t, rp.RevokeToken(ctx, relyingParty, refreshed.AccessToken, "access_token"))
+			_, err = rp.Userinfo[*oidc.UserInfo](ctx, refreshed.AccessToken, oidc.BearerToken, optest.UserID, relyingParty)
+			assert.Error(t, err, "revoked")
+		})
+	}
+}
+
+func TestWithOpaqueTokenFormats_invalid(t *testing.T) {
+	_, err := op.NewProvider(testConfig, nil, op.StaticIssuer(testIssuer), op.WithOpaqueTokenFormats(&op.OpaqueTokenFormat{Length: 40}, nil))
+	assert.Error(t, err, "storage not supported")
+	_, err = op.NewProvider(testConfig, nil, op.StaticIssuer(testIssuer), op.WithOpaqueTokenFormats(nil, &op.OpaqueTokenFormat{Length: 8}))
+	assert.Error(t, err, "entropy")
+}
@@ -0,0 +1,95 @@
+package optest
+
+import (
+	"time"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+)
+
+// Client is a client of the [MemoryStorage].
+type Client struct {
+	id                string
+	secret            string
+	redirectURIs      []string
+	applicationType   op.ApplicationType
+	authMethod        oidc.AuthMethod
+	responseTypes     []oidc.ResponseType
+	grantTypes        []oidc.GrantType
+	accessTokenType   op.AccessTokenType
+	accessTokenFormat string
+	devMode           bool
+}
+
+// WebClient creates a confidential client using basic auth, which may use the code flow
+// and refresh tokens. It is in dev mode, so the redirect URIs may use http.
+func WebClient(id, secret string, redirectURIs ...string) *Client {
+	return &Client{
+		id:              id,
+		secret:          secret,
+		redirectURIs:    redirectURIs,
+		applicationType: op.ApplicationTypeWeb,
+		authMethod:      oidc.AuthMethodBasic,
+		responseTypes:   []oidc.ResponseType{oidc.ResponseTypeCode},
+		grantTypes:      []oidc.GrantType{oidc.GrantTypeCode, oidc.GrantTypeRefreshToken},
+		accessTokenType: op.AccessTokenTypeBearer,
+		devMode:         true,
+	}
+}
+
+// NativeClient creates a public client, which must use PKCE and may use refresh tokens.
+func NativeClient(id string, redirectURIs ...string) *Client {
+	return &Client{
+		id:              id,
+		redirectURIs:    redirectURIs,
+		applicationType: op.ApplicationTypeNative,
+		authMethod:      oidc.AuthMethodNone,
+		responseTypes:   []oidc.ResponseType{oidc.ResponseTypeCode},
+		grantTypes:      []oidc.GrantType{oidc.GrantTypeCode, oidc.GrantTypeRefreshToken},
+		accessTokenType: op.AccessTokenTypeBearer,
+	}
+}
+
+// DeviceClient creates a confidential client using basic auth,
+// which may only use the device authorization grant.
+func DeviceClient(id, secret string) *Client {
+	return &Client{
+		id:              id,
+		secret:          secret,
+		applicationType: op.ApplicationTypeWeb,
+		authMethod:      oidc.AuthMethodBasic,
+		responseTypes:   []oidc.ResponseType{oidc.ResponseTypeCode},
+		grantTypes:      []oidc.GrantType{oidc.GrantTypeDeviceCode},
+		accessTokenType: op.AccessTokenTypeBearer,
+	}
+}
+
+// WithAccessTokenFormat issues the access tokens of the client in the format
+// registered under the name with [op.WithAccessTokenFormat].
+func (c *Client) WithAccessTokenFormat(name string) *Client {
+	c.accessTokenFormat = name
+	return c
+}
+
+func (c *Client) AccessTokenFormat() string { return c.accessTokenFormat }
+
+func (c *Client) GetID() string                        { return c.id }
+func (c *Client) RedirectURIs() []string               { return c.redirectURIs }
+func (c *Client) PostLogoutRedirectURIs() []string     { return nil }
+func (c *Client) ApplicationType() op.ApplicationType  { return c.applicationType }
+func (c *Client) AuthMethod() oidc.AuthMethod          { return c.authMethod }
+func (c *Client) ResponseTypes() []oidc.ResponseType   { return c.responseTypes }
+func (c *Client) GrantTypes() []oidc.GrantType         { return c.grantTypes }
+func (c *Client) LoginURL(id string) string            { return loginPath + "?authRequestID=" + id }
+func (c *Client) AccessTokenType() op.AccessTokenType  { return c.accessTokenType }
+func (c *Client) IDTokenLifetime() time.Duration       { return time.Hour }
+func (c *Client) DevMode() bool                        { return c.devMode }
+func (c *Client) IsScopeAllowed(string) bool           { return false }
+func (c *Client) IDTokenUserinfoClaimsAssertion() bool { return false }
+func (c *Client) ClockSkew() time.Duration             { return 0 }
+func (c *Client) RestrictAdditionalIdTokenScopes() func([]string) []string {
+	return func(scopes []string) []string { return scopes }
+}
+func (c *Client) RestrictAdditionalAccessTokenScopes() func([]string) []string {
+	return func(scopes []string) []string { return scopes }
+}
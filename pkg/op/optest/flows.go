@@ -0,0 +1,166 @@
+package optest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	"github.com/zitadel/oidc/v3/pkg/client/rs"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// NativeRelyingParty creates a [rp.RelyingParty] of the native client,
+// requesting the openid, profile, email and offline_access scopes.
+// Its code flow requires PKCE, see [Server.CodeFlowPKCE], and it sends its client_id in the parameters.
+func (s *Server) NativeRelyingParty(tb testing.TB, opts ...rp.Option) rp.RelyingParty {
+	tb.Helper()
+	scopes := []string{oidc.ScopeOpenID, oidc.ScopeProfile, oidc.ScopeEmail, oidc.ScopeOfflineAccess}
+	relyingParty, err := rp.NewRelyingPartyOIDC(context.Background(), s.Issuer, NativeClientID, "", RedirectURI, scopes,
+		append([]rp.Option{rp.WithHTTPClient(s.Client()), rp.WithAuthStyle(oauth2.AuthStyleInParams)}, opts...)...)
+	require.NoError(tb, err)
+	return relyingParty
+}
+
+// DeviceRelyingParty creates a [rp.RelyingParty] of the device client,
+// requesting the openid and profile scopes. It authenticates with basic auth.
+func (s *Server) DeviceRelyingParty(tb testing.TB, opts ...rp.Option) rp.RelyingParty {
+	tb.Helper()
+	scopes := []string{oidc.ScopeOpenID, oidc.ScopeProfile}
+	relyingParty, err := rp.NewRelyingPartyOIDC(context.Background(), s.Issuer, DeviceClientID, DeviceClientSecret, "", scopes,
+		append([]rp.Option{rp.WithHTTPClient(s.Client()), rp.WithAuthStyle(oauth2.AuthStyleInHeader)}, opts...)...)
+	require.NoError(tb, err)
+	return relyingParty
+}
+
+// ResourceServer creates a [rs.ResourceServer] authenticated as the web client,
+// which is in the audience of the tokens of the web client and may introspect them.
+func (s *Server) ResourceServer(tb testing.TB, opts ...rs.Option) rs.ResourceServer {
+	tb.Helper()
+	resourceServer, err := rs.NewResourceServerClientCredentials(context.Background(), s.Issuer, WebClientID, WebClientSecret,
+		append([]rs.Option{rs.WithClient(s.Client())}, opts...)...)
+	require.NoError(tb, err)
+	return resourceServer
+}
+
+// CodeFlowPKCE runs the authorization code flow of relyingParty like [Server.CodeFlow],
+// with a new S256 code challenge and its verifier.
+func (s *Server) CodeFlowPKCE(tb testing.TB, relyingParty rp.RelyingParty, opts ...rp.AuthURLOpt) *oidc.Tokens[*oidc.IDTokenClaims] {
+	tb.Helper()
+	verifier := rand.Text() + rand.Text()
+	state := "state"
+	callback := s.Authorize(tb, rp.AuthURL(state, relyingParty, append(opts, rp.WithCodeChallenge(oidc.NewSHACodeChallenge(verifier)))...))
+	query := callback.Query()
+	require.Empty(tb, query.Get("error"), "authorization failed: %s", query.Get("error_description"))
+	require.Equal(tb, state, query.Get("state"))
+
+	tokens, err := rp.CodeExchange[*oidc.IDTokenClaims](context.Background(), query.Get("code"), relyingParty, rp.WithCodeVerifier(verifier))
+	require.NoError(tb, err)
+	return tokens
+}
+
+// DeviceFlow runs the device authorization grant of relyingParty.
+// The authorization is approved for the login user directly in the Storage,
+// instead of the user verification pages, and the tokens are polled.
+func (s *Server) DeviceFlow(tb testing.TB, relyingParty rp.RelyingParty) *oidc.AccessTokenResponse {
+	tb.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	authorization, err := rp.DeviceAuthorization(ctx, relyingParty.OAuthConfig().Scopes, relyingParty, nil)
+	require.NoError(tb, err)
+	require.NoError(tb, s.Storage.CompleteDeviceAuthorization(s.Context(), authorization.UserCode, s.loginUser))
+
+	tokens, err := rp.DeviceAccessToken(ctx, authorization.DeviceCode, 100*time.Millisecond, relyingParty)
+	require.NoError(tb, err)
+	return tokens
+}
+
+// Exchange is a request to the OP and its response, recorded by a [Recorder].
+type Exchange struct {
+	Request     *http.Request
+	RequestBody []byte
+	Response    *http.Response
+	// Body of the response, which remains readable from the Response as well.
+	Body []byte
+}
+
+// Recorder is an [http.RoundTripper] recording the exchanges with the OP,
+// so tests can assert on wire-level details, such as headers, status codes
+// and the exact parameters sent by a relying party.
+type Recorder struct {
+	transport http.RoundTripper
+	mu        sync.Mutex
+	exchanges []Exchange
+}
+
+// Recorder returns a new [Recorder] using the transport of the test server.
+// Pass [Recorder.Client] to the relying parties, e.g. with [rp.WithHTTPClient].
+func (s *Server) Recorder() *Recorder {
+	return &Recorder{transport: s.Client().Transport}
+}
+
+// Client returns an http.Client recording its requests.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var exchange Exchange
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		exchange.RequestBody = body
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	exchange.Request = req
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	exchange.Body, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(exchange.Body))
+	exchange.Response = resp
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.exchanges = append(r.exchanges, exchange)
+	return resp, nil
+}
+
+// Exchanges returns all recorded exchanges, in their order.
+func (r *Recorder) Exchanges() []Exchange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Exchange(nil), r.exchanges...)
+}
+
+// Last returns the last exchange with a request path ending in path,
+// e.g. "/oauth/token". It fails the test if there is none.
+func (r *Recorder) Last(tb testing.TB, path string) Exchange {
+	tb.Helper()
+	exchanges := r.Exchanges()
+	for i := len(exchanges) - 1; i >= 0; i-- {
+		if strings.HasSuffix(exchanges[i].Request.URL.Path, path) {
+			return exchanges[i]
+		}
+	}
+	tb.Fatalf("optest: no request to %s recorded", path)
+	return Exchange{}
+}
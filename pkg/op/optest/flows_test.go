@@ -0,0 +1,28 @@
+package optest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	"github.com/zitadel/oidc/v3/pkg/op/optest"
+)
+
+func TestServer_CodeFlowPKCE(t *testing.T) {
+	s := optest.New(t, optest.WithLegacyServer())
+	recorder := s.Recorder()
+	tokens := s.CodeFlowPKCE(t, s.NativeRelyingParty(t, rp.WithHTTPClient(recorder.Client())))
+	assert.Equal(t, optest.UserID, tokens.IDTokenClaims.GetSubject())
+
+	exchange := recorder.Last(t, "/oauth/token")
+	assert.Contains(t, string(exchange.RequestBody), "code_verifier=")
+	assert.Contains(t, string(exchange.Body), tokens.AccessToken)
+}
+
+func TestServer_DeviceFlow(t *testing.T) {
+	s := optest.New(t, optest.WithLoginUser(optest.OtherUserID))
+	tokens := s.DeviceFlow(t, s.DeviceRelyingParty(t))
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.NotEmpty(t, tokens.IDToken)
+}
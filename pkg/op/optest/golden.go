@@ -0,0 +1,92 @@
+package optest
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/pkg/op"
+)
+
+// UpdateGoldenEnv is the environment variable which makes [Golden] and [GoldenJWT]
+// (re)write the golden files instead of comparing against them:
+//
+//	OPTEST_UPDATE_GOLDEN=1 go test ./...
+const UpdateGoldenEnv = "OPTEST_UPDATE_GOLDEN"
+
+// Golden compares got with the golden file testdata/name of the package under test.
+// It fails the test on any difference, e.g. when a library upgrade changes
+// the wire format of tokens. The files are written if [UpdateGoldenEnv] is set.
+func Golden(tb testing.TB, name string, got []byte) {
+	tb.Helper()
+	path := filepath.Join("testdata", name)
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		require.NoError(tb, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(tb, os.WriteFile(path, got, 0o644))
+		return
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(tb, err, "run with %s=1 to create the golden file", UpdateGoldenEnv)
+	require.Equal(tb, string(want), string(got), "golden file %s differs, run with %s=1 to update it", path, UpdateGoldenEnv)
+}
+
+// GoldenJWT compares the compact JWT token with the golden file testdata/name.
+// The file holds the decoded header and payload as sent on the wire,
+// so differences in claims are readable, followed by the token itself,
+// which also covers the signature.
+func GoldenJWT(tb testing.TB, name, token string) {
+	tb.Helper()
+	parts := strings.Split(token, ".")
+	require.Len(tb, parts, 3, "token is not a compact JWT")
+	var b strings.Builder
+	for _, part := range parts[:2] {
+		decoded, err := base64.RawURLEncoding.DecodeString(part)
+		require.NoError(tb, err)
+		b.Write(decoded)
+		b.WriteByte('\n')
+	}
+	b.WriteString(token)
+	b.WriteByte('\n')
+	Golden(tb, name, []byte(b.String()))
+}
+
+// StaticSigningKey returns an Ed25519 signing key with the id, derived from the id only.
+// Ed25519 signatures are deterministic, so tokens signed with the key are byte-exact
+// for the same claims, unlike RSA-PSS or ECDSA signatures.
+// It is meant for golden file tests and must never be used outside of tests.
+func StaticSigningKey(id string) op.SigningKey {
+	seed := sha256.Sum256([]byte(id))
+	return &staticSigningKey{id: id, key: ed25519.NewKeyFromSeed(seed[:])}
+}
+
+type staticSigningKey struct {
+	id  string
+	key ed25519.PrivateKey
+}
+
+func (k *staticSigningKey) SignatureAlgorithm() jose.SignatureAlgorithm { return jose.EdDSA }
+func (k *staticSigningKey) Key() any                                    { return k.key }
+func (k *staticSigningKey) ID() string                                  { return k.id }
+
+// FixedClock returns an [op.Clock] which always returns t.
+func FixedClock(t time.Time) op.Clock {
+	return func() time.Time { return t }
+}
+
+// SequentialTokenIDs returns an [op.TokenIDSource] returning prefix1, prefix2 and so on.
+func SequentialTokenIDs(prefix string) op.TokenIDSource {
+	var n atomic.Int64
+	return func() string {
+		return prefix + strconv.FormatInt(n.Add(1), 10)
+	}
+}
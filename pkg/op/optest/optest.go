@@ -0,0 +1,472 @@
+// Package optest provides an OpenID Provider for tests,
+// in the spirit of [net/http/httptest].
+//
+// [New] starts a fully functional OP on a random local port,
+// backed by a [MemoryStorage] with canned users and clients.
+// The storage of an application can be tested with [WithStorage] instead.
+// Logins are completed automatically for the configured user,
+// so a code flow of a [rp.RelyingParty] can run without a browser:
+//
+//	s := optest.New(t)
+//	tokens := s.CodeFlow(t, s.RelyingParty(t))
+//
+// Tokens with arbitrary claims, such as expired tokens or tokens with a wrong audience,
+// can be minted with [Server.IDToken] and [Server.AccessToken].
+//
+// Tokens created with [op.ContextWithClock], [op.ContextWithTokenIDSource] and a
+// [StaticSigningKey] are byte-exact and can be compared against golden files
+// with [GoldenJWT], to detect unintended changes of claims or their wire format.
+// This covers the ID, access and logout tokens issued by the OP. JWT secured
+// authorization responses (JARM) are not covered, as the OP does not issue them.
+package optest
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	"github.com/zitadel/oidc/v3/pkg/crypto"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+)
+
+// Canned clients, registered on the [MemoryStorage] of every [Server] unless replaced using [WithClients].
+const (
+	// WebClientID is a confidential client using basic auth.
+	WebClientID = "web"
+	// WebClientSecret is the secret of the web client.
+	WebClientSecret = "secret"
+	// NativeClientID is a public client, which must use PKCE.
+	NativeClientID = "native"
+	// DeviceClientID is a confidential client using basic auth,
+	// which may only use the device authorization grant.
+	DeviceClientID = "device"
+	// DeviceClientSecret is the secret of the device client.
+	DeviceClientSecret = "secret"
+	// RedirectURI is the registered redirect URI of the canned clients.
+	// Nothing is served on it, the [Server.Authorize] helper stops at the redirect.
+	RedirectURI = "http://localhost:9999/auth/callback"
+)
+
+// Canned users of the [MemoryStorage].
+const (
+	// UserID is the default user, which is logged in on every auth request.
+	UserID = "id1"
+	// OtherUserID is a second, non-admin user.
+	OtherUserID = "id2"
+)
+
+// loginPath is the login URL of the clients of the [MemoryStorage].
+const loginPath = "/login/username"
+
+// Server is an OpenID Provider listening on a local address.
+type Server struct {
+	*httptest.Server
+
+	// Issuer of the OP, the URL of the test server.
+	Issuer string
+	// Storage is the storage of the OP, which can be used
+	// to prepare auth requests or tokens directly.
+	// It is a [*MemoryStorage], unless replaced using [WithStorage].
+	Storage Storage
+	// Provider is the OP serving the requests.
+	Provider *op.Provider
+
+	loginUser    string
+	clients      []*Client
+	newStorage   func(issuer string) (Storage, error)
+	config       *op.Config
+	opOpts       []op.Option
+	legacyServer bool
+}
+
+// Option configures the [Server].
+type Option func(*Server) error
+
+// WithClients replaces the canned clients of the [MemoryStorage].
+func WithClients(clients ...*Client) Option {
+	return func(s *Server) error {
+		s.clients = clients
+		return nil
+	}
+}
+
+// WithStorage replaces the [MemoryStorage], e.g. to run the tests against the storage
+// of an application. newStorage is called with the issuer of the server.
+// The storage must know the canned users and clients, unless the tests use others.
+// The clients must redirect to the login path of the server, which completes
+// the auth request by [Storage.CompleteAuthRequest]:
+//
+//	/login/username?authRequestID=<id>
+func WithStorage(newStorage func(issuer string) (Storage, error)) Option {
+	return func(s *Server) error {
+		if newStorage == nil {
+			return errors.New("newStorage must not be nil")
+		}
+		s.newStorage = newStorage
+		return nil
+	}
+}
+
+// WithLoginUser sets the ID of the user which is logged in on auth requests.
+// It must be known by the storage. The default is [UserID].
+func WithLoginUser(userID string) Option {
+	return func(s *Server) error {
+		if userID == "" {
+			return errors.New("login user must not be empty")
+		}
+		s.loginUser = userID
+		return nil
+	}
+}
+
+// WithConfig replaces the configuration of the OP.
+func WithConfig(config *op.Config) Option {
+	return func(s *Server) error {
+		if config == nil {
+			return errors.New("config must not be nil")
+		}
+		s.config = config
+		return nil
+	}
+}
+
+// WithProviderOptions passes additional options to the OP.
+func WithProviderOptions(opts ...op.Option) Option {
+	return func(s *Server) error {
+		s.opOpts = append(s.opOpts, opts...)
+		return nil
+	}
+}
+
+// WithLegacyServer serves the OP through the [op.Server] interface,
+// using [op.RegisterLegacyServer], instead of the router of the [op.Provider].
+// Running the same tests with and without it covers both implementations.
+func WithLegacyServer() Option {
+	return func(s *Server) error {
+		s.legacyServer = true
+		return nil
+	}
+}
+
+// DefaultConfig returns the configuration used, unless set by [WithConfig].
+func DefaultConfig() *op.Config {
+	return &op.Config{
+		CryptoKey:               sha256.Sum256([]byte("optest")),
+		CodeMethodS256:          true,
+		AuthMethodPost:          true,
+		AuthMethodPrivateKeyJWT: true,
+		GrantTypeRefreshToken:   true,
+		RequestObjectSupported:  true,
+		SupportedClaims:         op.DefaultSupportedClaims,
+		DeviceAuthorization: op.DeviceAuthorizationConfig{
+			Lifetime:     5 * time.Minute,
+			PollInterval: time.Second,
+			UserFormPath: "/device",
+			UserCode:     op.UserCodeBase20,
+		},
+	}
+}
+
+// New starts a [Server], which is closed when the test finishes.
+// It fails the test if the OP can't be created.
+func New(tb testing.TB, opts ...Option) *Server {
+	tb.Helper()
+	s := &Server{
+		Server:    httptest.NewUnstartedServer(nil),
+		loginUser: UserID,
+		clients: []*Client{
+			WebClient(WebClientID, WebClientSecret, RedirectURI),
+			NativeClient(NativeClientID, RedirectURI),
+			DeviceClient(DeviceClientID, DeviceClientSecret),
+		},
+		config: DefaultConfig(),
+	}
+	tb.Cleanup(s.Close)
+	for _, opt := range opts {
+		require.NoError(tb, opt(s))
+	}
+	s.Issuer = "http://" + s.Listener.Addr().String()
+	if s.newStorage == nil {
+		if users[s.loginUser] == nil {
+			tb.Fatalf("optest: unknown login user %q", s.loginUser)
+		}
+		s.newStorage = func(string) (Storage, error) {
+			return NewMemoryStorage(s.clients...)
+		}
+	}
+	storage, err := s.newStorage(s.Issuer)
+	require.NoError(tb, err)
+	s.Storage = storage
+	keySet := &op.OpenIDKeySet{Storage: s.Storage}
+	provider, err := op.NewProvider(s.config, s.Storage, op.StaticIssuer(s.Issuer), append([]op.Option{
+		op.WithAllowInsecure(),
+		op.WithAccessTokenKeySet(keySet),
+		op.WithIDTokenHintKeySet(keySet),
+	}, s.opOpts...)...)
+	require.NoError(tb, err)
+	s.Provider = provider
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(loginPath, s.login)
+	if s.legacyServer {
+		// the legacy server ignores the endpoints of the provider, so they are passed explicitly
+		endpoints := op.Endpoints{
+			Authorization:       provider.AuthorizationEndpoint(),
+			Token:               provider.TokenEndpoint(),
+			Introspection:       provider.IntrospectionEndpoint(),
+			Userinfo:            provider.UserinfoEndpoint(),
+			Revocation:          provider.RevocationEndpoint(),
+			EndSession:          provider.EndSessionEndpoint(),
+			CheckSessionIframe:  provider.CheckSessionIframe(),
+			JwksURI:             provider.KeysEndpoint(),
+			DeviceAuthorization: provider.DeviceAuthorizationEndpoint(),
+		}
+		mux.Handle("/", op.RegisterLegacyServer(op.NewLegacyServer(provider, endpoints), op.AuthorizeCallbackHandler(provider)))
+	} else {
+		mux.Handle("/", provider)
+	}
+	s.Config.Handler = mux
+	s.Start()
+	return s
+}
+
+// login completes the auth request for the login user
+// and redirects back to the OP.
+// Stateless auth requests are completed by the Provider instead of the Storage.
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	id := r.FormValue("authRequestID")
+	if s.Provider.StatelessAuthRequestLifetime() > 0 {
+		var err error
+		id, err = s.Provider.CompleteStatelessAuthRequest(id, &op.UserSession{
+			ID:       op.NewSessionID(),
+			Subject:  s.loginUser,
+			AuthTime: time.Now(),
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else if err := s.Storage.CompleteAuthRequest(r.Context(), id, s.loginUser); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Redirect(w, r, op.AuthCallbackURL(s.Provider)(op.ContextWithIssuer(r.Context(), s.Issuer), id), http.StatusFound)
+}
+
+// Context returns a context carrying the issuer of the server,
+// as required when calling the [Server.Provider] or [Server.Storage] directly.
+func (s *Server) Context() context.Context {
+	return op.ContextWithIssuer(context.Background(), s.Issuer)
+}
+
+// RelyingParty creates a [rp.RelyingParty] of the web client,
+// requesting the openid, profile, email and offline_access scopes.
+// It authenticates with basic auth.
+func (s *Server) RelyingParty(tb testing.TB, opts ...rp.Option) rp.RelyingParty {
+	tb.Helper()
+	scopes := []string{oidc.ScopeOpenID, oidc.ScopeProfile, oidc.ScopeEmail, oidc.ScopeOfflineAccess}
+	relyingParty, err := rp.NewRelyingPartyOIDC(context.Background(), s.Issuer, WebClientID, WebClientSecret, RedirectURI, scopes,
+		append([]rp.Option{rp.WithHTTPClient(s.Client()), rp.WithAuthStyle(oauth2.AuthStyleInHeader)}, opts...)...)
+	require.NoError(tb, err)
+	return relyingParty
+}
+
+// Authorize sends the authorization request of authURL, follows the login
+// of the login user and returns the redirect to the client,
+// carrying either the code or the error of the request.
+func (s *Server) Authorize(tb testing.TB, authURL string) *url.URL {
+	tb.Helper()
+	client := &http.Client{
+		Transport: s.Client().Transport,
+		CheckRedirect: func(req *http.Request, _ []*http.Request) error {
+			if req.URL.Host != s.Listener.Addr().String() {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+	resp, err := client.Get(authURL)
+	require.NoError(tb, err)
+	defer resp.Body.Close()
+	location, err := resp.Location()
+	require.NoError(tb, err, "authorization did not redirect to the client (status %d)", resp.StatusCode)
+	return location
+}
+
+// CodeFlow runs the authorization code flow of relyingParty for the login user
+// and returns the exchanged tokens.
+func (s *Server) CodeFlow(tb testing.TB, relyingParty rp.RelyingParty, opts ...rp.AuthURLOpt) *oidc.Tokens[*oidc.IDTokenClaims] {
+	tb.Helper()
+	state := "state"
+	callback := s.Authorize(tb, rp.AuthURL(state, relyingParty, opts...))
+	query := callback.Query()
+	require.Empty(tb, query.Get("error"), "authorization failed: %s", query.Get("error_description"))
+	require.Equal(tb, state, query.Get("state"))
+
+	tokens, err := rp.CodeExchange[*oidc.IDTokenClaims](context.Background(), query.Get("code"), relyingParty)
+	require.NoError(tb, err)
+	return tokens
+}
+
+// TokenOption modifies the claims of a token minted by the [Server].
+type TokenOption func(*tokenClaims)
+
+type tokenClaims struct {
+	issuer     string
+	subject    string
+	audience   []string
+	clientID   string
+	expiration time.Time
+	issuedAt   time.Time
+	notBefore  time.Time
+	nonce      string
+	scopes     []string
+	claims     map[string]any
+}
+
+// Expired sets the expiration into the past.
+func Expired() TokenOption {
+	return ExpiresAt(time.Now().Add(-time.Hour))
+}
+
+// ExpiresAt sets the expiration.
+func ExpiresAt(exp time.Time) TokenOption {
+	return func(c *tokenClaims) {
+		c.expiration = exp
+	}
+}
+
+// NotBefore sets the time before which the token must not be accepted.
+func NotBefore(nbf time.Time) TokenOption {
+	return func(c *tokenClaims) {
+		c.notBefore = nbf
+	}
+}
+
+// IssuedAt sets the time of issuance.
+func IssuedAt(iat time.Time) TokenOption {
+	return func(c *tokenClaims) {
+		c.issuedAt = iat
+	}
+}
+
+// Audience replaces the audience, which defaults to the web client.
+func Audience(audience ...string) TokenOption {
+	return func(c *tokenClaims) {
+		c.audience = audience
+	}
+}
+
+// ClientID sets the client the token is issued to.
+// It is also used as authorized party of ID tokens.
+func ClientID(clientID string) TokenOption {
+	return func(c *tokenClaims) {
+		c.clientID = clientID
+	}
+}
+
+// Issuer replaces the issuer of the server.
+func Issuer(issuer string) TokenOption {
+	return func(c *tokenClaims) {
+		c.issuer = issuer
+	}
+}
+
+// Subject replaces the login user as subject.
+func Subject(subject string) TokenOption {
+	return func(c *tokenClaims) {
+		c.subject = subject
+	}
+}
+
+// Nonce sets the nonce of an ID token.
+func Nonce(nonce string) TokenOption {
+	return func(c *tokenClaims) {
+		c.nonce = nonce
+	}
+}
+
+// Scopes sets the scopes of an access token.
+func Scopes(scopes ...string) TokenOption {
+	return func(c *tokenClaims) {
+		c.scopes = scopes
+	}
+}
+
+// Claim sets an additional claim.
+func Claim(key string, value any) TokenOption {
+	return func(c *tokenClaims) {
+		if c.claims == nil {
+			c.claims = make(map[string]any)
+		}
+		c.claims[key] = value
+	}
+}
+
+func (s *Server) tokenClaims(opts []TokenOption) *tokenClaims {
+	now := time.Now()
+	c := &tokenClaims{
+		issuer:     s.Issuer,
+		subject:    s.loginUser,
+		clientID:   WebClientID,
+		expiration: now.Add(time.Hour),
+		issuedAt:   now,
+		notBefore:  now,
+		scopes:     []string{oidc.ScopeOpenID},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.audience == nil {
+		c.audience = []string{c.clientID}
+	}
+	return c
+}
+
+// IDToken mints an ID token of the login user for the web client,
+// signed with the key of the OP and valid for an hour, unless changed by opts.
+func (s *Server) IDToken(tb testing.TB, opts ...TokenOption) string {
+	tb.Helper()
+	c := s.tokenClaims(opts)
+	claims := oidc.NewIDTokenClaims(c.issuer, c.subject, c.audience, c.expiration, c.issuedAt, c.nonce, "", nil, c.clientID, 0)
+	claims.Audience = c.audience
+	claims.IssuedAt = oidc.FromTime(c.issuedAt)
+	claims.NotBefore = oidc.FromTime(c.notBefore)
+	claims.Claims = c.claims
+	return s.Sign(tb, oidc.TypeJWT, claims)
+}
+
+// AccessToken mints a JWT access token of the login user for the web client,
+// signed with the key of the OP and valid for an hour, unless changed by opts.
+func (s *Server) AccessToken(tb testing.TB, opts ...TokenOption) string {
+	tb.Helper()
+	c := s.tokenClaims(opts)
+	claims := oidc.NewAccessTokenClaims(c.issuer, c.subject, c.audience, c.expiration, "", c.clientID, 0)
+	claims.IssuedAt = oidc.FromTime(c.issuedAt)
+	claims.NotBefore = oidc.FromTime(c.notBefore)
+	claims.Scopes = c.scopes
+	claims.Claims = c.claims
+	return s.Sign(tb, oidc.TypeAccessToken, claims)
+}
+
+// Sign signs arbitrary claims with the key of the OP, using typ as type header.
+func (s *Server) Sign(tb testing.TB, typ string, claims any) string {
+	tb.Helper()
+	key, err := s.Storage.SigningKey(s.Context())
+	require.NoError(tb, err)
+	signer, err := crypto.NewCompactSigner(key.SignatureAlgorithm(), key.Key(), key.ID(), typ)
+	require.NoError(tb, err)
+	token, err := signer.SignObject(claims)
+	require.NoError(tb, err)
+	return token
+}
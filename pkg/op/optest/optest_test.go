@@ -0,0 +1,123 @@
+package optest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/pkg/client"
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/optest"
+)
+
+func TestServer_CodeFlow(t *testing.T) {
+	s := optest.New(t)
+	relyingParty := s.RelyingParty(t)
+
+	tokens := s.CodeFlow(t, relyingParty)
+	assert.Equal(t, optest.UserID, tokens.IDTokenClaims.GetSubject())
+	assert.Equal(t, s.Issuer, tokens.IDTokenClaims.GetIssuer())
+	assert.NotEmpty(t, tokens.RefreshToken)
+
+	info, err := rp.Userinfo[*oidc.UserInfo](context.Background(), tokens.AccessToken, tokens.TokenType, tokens.IDTokenClaims.GetSubject(), relyingParty)
+	require.NoError(t, err)
+	assert.Equal(t, optest.UserID, info.Subject)
+}
+
+func TestServer_CodeFlow_loginUser(t *testing.T) {
+	s := optest.New(t, optest.WithLoginUser(optest.OtherUserID))
+	tokens := s.CodeFlow(t, s.RelyingParty(t))
+	assert.Equal(t, optest.OtherUserID, tokens.IDTokenClaims.GetSubject())
+}
+
+func TestServer_Authorize_error(t *testing.T) {
+	s := optest.New(t, optest.WithClients(
+		optest.WebClient("other", "secret", "http://localhost:9999/other"),
+	))
+	relyingParty, err := rp.NewRelyingPartyOIDC(context.Background(), s.Issuer, "other", "secret", "http://localhost:9999/other", []string{oidc.ScopeOpenID})
+	require.NoError(t, err)
+
+	callback := s.Authorize(t, rp.AuthURL("state", relyingParty, rp.AuthURLOpt(rp.WithURLParam("response_type", "token"))))
+	assert.Equal(t, "/other", callback.Path)
+	assert.Equal(t, "unauthorized_client", callback.Query().Get("error"))
+}
+
+func TestNew_options(t *testing.T) {
+	config := optest.DefaultConfig()
+	config.SupportedScopes = []string{oidc.ScopeOpenID, "api"}
+	s := optest.New(t, optest.WithConfig(config), optest.WithProviderOptions(op.WithCustomKeysEndpoint(op.NewEndpoint("jwks"))))
+	discovery, err := client.Discover(context.Background(), s.Issuer, s.Client())
+	require.NoError(t, err)
+	assert.Equal(t, config.SupportedScopes, discovery.ScopesSupported)
+	assert.Equal(t, s.Issuer+"/jwks", discovery.JwksURI)
+}
+
+func TestServer_IDToken(t *testing.T) {
+	s := optest.New(t)
+	verifier := rp.NewIDTokenVerifier(s.Issuer, optest.WebClientID, rp.NewRemoteKeySet(s.Client(), s.Issuer+"/keys"))
+	ctx := context.Background()
+
+	claims, err := rp.VerifyIDToken[*oidc.IDTokenClaims](ctx, s.IDToken(t, optest.Claim("foo", "bar")), verifier)
+	require.NoError(t, err)
+	assert.Equal(t, optest.UserID, claims.Subject)
+	assert.Equal(t, "bar", claims.Claims["foo"])
+
+	tests := []struct {
+		name    string
+		opts    []optest.TokenOption
+		wantErr error
+	}{
+		{
+			name:    "expired",
+			opts:    []optest.TokenOption{optest.Expired()},
+			wantErr: oidc.ErrExpired,
+		},
+		{
+			name:    "wrong audience",
+			opts:    []optest.TokenOption{optest.Audience("other")},
+			wantErr: oidc.ErrAudience,
+		},
+		{
+			name:    "wrong issuer",
+			opts:    []optest.TokenOption{optest.Issuer("https://other.example.com")},
+			wantErr: oidc.ErrIssuerInvalid,
+		},
+		{
+			name:    "issued in the future",
+			opts:    []optest.TokenOption{optest.IssuedAt(time.Now().Add(time.Hour))},
+			wantErr: oidc.ErrIatInFuture,
+		},
+		{
+			name:    "nonce",
+			opts:    []optest.TokenOption{optest.Nonce("nonce")},
+			wantErr: oidc.ErrNonceInvalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := rp.VerifyIDToken[*oidc.IDTokenClaims](ctx, s.IDToken(t, tt.opts...), verifier)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestServer_AccessToken(t *testing.T) {
+	s := optest.New(t)
+	verifier := s.Provider.AccessTokenVerifier(s.Context())
+	nbf := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	token := s.AccessToken(t, optest.Scopes(oidc.ScopeOpenID, "api"), optest.NotBefore(nbf), optest.Subject("service"))
+	claims, err := op.VerifyAccessToken[*oidc.AccessTokenClaims](s.Context(), token, verifier)
+	require.NoError(t, err)
+	assert.Equal(t, "service", claims.Subject)
+	assert.Equal(t, oidc.SpaceDelimitedArray{oidc.ScopeOpenID, "api"}, claims.Scopes)
+	assert.Equal(t, nbf, claims.NotBefore.AsTime())
+
+	_, err = op.VerifyAccessToken[*oidc.AccessTokenClaims](s.Context(), s.AccessToken(t, optest.Expired()), verifier)
+	assert.ErrorIs(t, err, oidc.ErrExpired)
+}
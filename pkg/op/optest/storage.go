@@ -0,0 +1,531 @@
+package optest
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+)
+
+// Storage is the storage of the OP of a [Server].
+// Besides [op.Storage], it completes the logins of the canned users,
+// as the login UI of a real OP would.
+type Storage interface {
+	op.Storage
+	// CompleteAuthRequest authenticates the user for the auth request in a new session.
+	CompleteAuthRequest(ctx context.Context, authRequestID, userID string) error
+	// CompleteDeviceAuthorization approves the device authorization of userCode for the user.
+	CompleteDeviceAuthorization(ctx context.Context, userCode, userID string) error
+}
+
+type user struct {
+	id         string
+	username   string
+	givenName  string
+	familyName string
+	email      string
+}
+
+var users = map[string]*user{
+	UserID:      {id: UserID, username: "test-user", givenName: "Test", familyName: "User", email: "test-user@example.com"},
+	OtherUserID: {id: OtherUserID, username: "test-user2", givenName: "Other", familyName: "User", email: "test-user2@example.com"},
+}
+
+var (
+	_ Storage                       = (*MemoryStorage)(nil)
+	_ op.DeviceAuthorizationStorage = (*MemoryStorage)(nil)
+	_ op.CanSetUserinfoFromRequest  = (*MemoryStorage)(nil)
+)
+
+// MemoryStorage is the in-memory [Storage] of a [Server], unless replaced by [WithStorage].
+// It knows the canned users [UserID] and [OtherUserID], and implements the
+// code flow, refresh tokens with rotation, the device authorization grant,
+// introspection, revocation and userinfo.
+// Access tokens are opaque and expire after five minutes, refresh tokens after five hours.
+type MemoryStorage struct {
+	mu            sync.Mutex
+	clients       map[string]*Client
+	signingKey    signingKey
+	authRequests  map[string]*authRequest
+	codes         map[string]string
+	accessTokens  map[string]*accessToken
+	refreshTokens map[string]*refreshToken
+	deviceCodes   map[string]*op.DeviceAuthorizationState
+	userCodes     map[string]string
+}
+
+// NewMemoryStorage creates a [MemoryStorage] with the clients and a new RS256 signing key.
+func NewMemoryStorage(clients ...*Client) (*MemoryStorage, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	s := &MemoryStorage{
+		clients:       make(map[string]*Client, len(clients)),
+		signingKey:    signingKey{id: rand.Text(), key: key},
+		authRequests:  make(map[string]*authRequest),
+		codes:         make(map[string]string),
+		accessTokens:  make(map[string]*accessToken),
+		refreshTokens: make(map[string]*refreshToken),
+		deviceCodes:   make(map[string]*op.DeviceAuthorizationState),
+		userCodes:     make(map[string]string),
+	}
+	for _, client := range clients {
+		s.clients[client.GetID()] = client
+	}
+	return s, nil
+}
+
+type notFoundError string
+
+func (e notFoundError) Error() string { return string(e) }
+func (notFoundError) IsNotFound()     {}
+
+func (s *MemoryStorage) CreateAuthRequest(_ context.Context, request *oidc.AuthRequest, userID string) (op.AuthRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	authReq := &authRequest{id: rand.Text(), request: request, userID: userID}
+	s.authRequests[authReq.id] = authReq
+	return authReq, nil
+}
+
+func (s *MemoryStorage) CompleteAuthRequest(_ context.Context, authRequestID, userID string) error {
+	if users[userID] == nil {
+		return notFoundError("user not found")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	authReq, ok := s.authRequests[authRequestID]
+	if !ok {
+		return notFoundError("auth request not found")
+	}
+	authReq.userID = userID
+	authReq.sessionID = op.NewSessionID()
+	authReq.authTime = time.Now()
+	authReq.done = true
+	return nil
+}
+
+func (s *MemoryStorage) AuthRequestByID(_ context.Context, id string) (op.AuthRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	authReq, ok := s.authRequests[id]
+	if !ok {
+		return nil, notFoundError("auth request not found")
+	}
+	return authReq, nil
+}
+
+func (s *MemoryStorage) AuthRequestByCode(ctx context.Context, code string) (op.AuthRequest, error) {
+	s.mu.Lock()
+	id, ok := s.codes[code]
+	s.mu.Unlock()
+	if !ok {
+		return nil, notFoundError("code not found")
+	}
+	return s.AuthRequestByID(ctx, id)
+}
+
+func (s *MemoryStorage) SaveAuthCode(_ context.Context, id, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.codes[code] = id
+	return nil
+}
+
+func (s *MemoryStorage) DeleteAuthRequest(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.authRequests, id)
+	for code, requestID := range s.codes {
+		if requestID == id {
+			delete(s.codes, code)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStorage) CreateAccessToken(_ context.Context, request op.TokenRequest) (string, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token := s.newAccessToken(request)
+	return token.id, token.expiration, nil
+}
+
+// CreateAccessAndRefreshTokens always rotates the refresh token,
+// so it satisfies [op.RefreshTokenRotationRequired].
+func (s *MemoryStorage) CreateAccessAndRefreshTokens(ctx context.Context, request op.TokenRequest, currentRefreshToken string) (string, string, time.Time, error) {
+	value, err := op.NewRefreshTokenValue(ctx)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	refresh := &refreshToken{
+		subject:  request.GetSubject(),
+		clientID: clientIDOf(request),
+		audience: request.GetAudience(),
+		scopes:   request.GetScopes(),
+	}
+	if authReq, ok := request.(interface {
+		GetAMR() []string
+		GetAuthTime() time.Time
+	}); ok {
+		refresh.amr = authReq.GetAMR()
+		refresh.authTime = authReq.GetAuthTime()
+	}
+	refresh.sessionID = op.SessionIDFromRequest(request)
+	if currentRefreshToken != "" {
+		current, ok := s.refreshTokens[currentRefreshToken]
+		if !ok || current.expiration.Before(time.Now()) {
+			return "", "", time.Time{}, op.ErrInvalidRefreshToken
+		}
+		s.revokeRefreshToken(current)
+		// the refresh token keeps the original grant, also if the issued tokens were narrowed
+		refresh = current
+	}
+	refresh.token = value
+	refresh.expiration = time.Now().Add(5 * time.Hour)
+	access := s.newAccessToken(request)
+	refresh.accessTokenID = access.id
+	s.refreshTokens[value] = refresh
+	return access.id, value, access.expiration, nil
+}
+
+// newAccessToken must be called with the lock held.
+func (s *MemoryStorage) newAccessToken(request op.TokenRequest) *accessToken {
+	token := &accessToken{
+		id:         rand.Text(),
+		subject:    request.GetSubject(),
+		clientID:   clientIDOf(request),
+		audience:   request.GetAudience(),
+		scopes:     request.GetScopes(),
+		expiration: time.Now().Add(5 * time.Minute),
+	}
+	s.accessTokens[token.id] = token
+	return token
+}
+
+// revokeRefreshToken revokes the refresh token and the access token issued with it.
+// It must be called with the lock held.
+func (s *MemoryStorage) revokeRefreshToken(token *refreshToken) {
+	delete(s.refreshTokens, token.token)
+	delete(s.accessTokens, token.accessTokenID)
+}
+
+func clientIDOf(request op.TokenRequest) string {
+	if r, ok := request.(interface{ GetClientID() string }); ok {
+		return r.GetClientID()
+	}
+	return ""
+}
+
+func (s *MemoryStorage) TokenRequestByRefreshToken(_ context.Context, token string) (op.RefreshTokenRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	refresh, ok := s.refreshTokens[token]
+	if !ok || refresh.expiration.Before(time.Now()) {
+		return nil, op.ErrInvalidRefreshToken
+	}
+	// the request works on a copy, so narrowed scopes and audience only apply to the issued tokens
+	return &refreshTokenRequest{*refresh}, nil
+}
+
+func (s *MemoryStorage) TerminateSession(_ context.Context, userID, clientID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, token := range s.accessTokens {
+		if token.subject == userID && token.clientID == clientID {
+			delete(s.accessTokens, id)
+		}
+	}
+	for _, token := range s.refreshTokens {
+		if token.subject == userID && token.clientID == clientID {
+			s.revokeRefreshToken(token)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStorage) RevokeToken(_ context.Context, tokenOrTokenID, _, clientID string) *oidc.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token, ok := s.accessTokens[tokenOrTokenID]; ok {
+		if token.clientID != clientID {
+			return oidc.ErrInvalidClient().WithDescription("token was not issued for this client")
+		}
+		delete(s.accessTokens, tokenOrTokenID)
+		return nil
+	}
+	if token, ok := s.refreshTokens[tokenOrTokenID]; ok {
+		if token.clientID != clientID {
+			return oidc.ErrInvalidClient().WithDescription("token was not issued for this client")
+		}
+		s.revokeRefreshToken(token)
+	}
+	return nil
+}
+
+func (s *MemoryStorage) GetRefreshTokenInfo(_ context.Context, _, token string) (string, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	refresh, ok := s.refreshTokens[token]
+	if !ok {
+		return "", "", op.ErrInvalidRefreshToken
+	}
+	return refresh.subject, refresh.token, nil
+}
+
+func (s *MemoryStorage) SigningKey(context.Context) (op.SigningKey, error) {
+	return &s.signingKey, nil
+}
+
+func (s *MemoryStorage) SignatureAlgorithms(context.Context) ([]jose.SignatureAlgorithm, error) {
+	return []jose.SignatureAlgorithm{jose.RS256}, nil
+}
+
+func (s *MemoryStorage) KeySet(context.Context) ([]op.Key, error) {
+	return []op.Key{&publicKey{&s.signingKey}}, nil
+}
+
+func (s *MemoryStorage) GetClientByClientID(_ context.Context, clientID string) (op.Client, error) {
+	client, ok := s.clients[clientID]
+	if !ok {
+		return nil, notFoundError("client not found")
+	}
+	return client, nil
+}
+
+func (s *MemoryStorage) AuthorizeClientIDSecret(_ context.Context, clientID, clientSecret string) error {
+	client, ok := s.clients[clientID]
+	if !ok || client.secret == "" || subtle.ConstantTimeCompare([]byte(client.secret), []byte(clientSecret)) != 1 {
+		return errors.New("invalid client credentials")
+	}
+	return nil
+}
+
+func (s *MemoryStorage) SetUserinfoFromScopes(context.Context, *oidc.UserInfo, string, string, []string) error {
+	return nil
+}
+
+func (s *MemoryStorage) SetUserinfoFromRequest(_ context.Context, userinfo *oidc.UserInfo, request op.IDTokenRequest, scopes []string) error {
+	return setUserinfo(userinfo, request.GetSubject(), scopes)
+}
+
+func (s *MemoryStorage) SetUserinfoFromToken(_ context.Context, userinfo *oidc.UserInfo, tokenID, _, _ string) error {
+	token, err := s.accessToken(tokenID)
+	if err != nil {
+		return err
+	}
+	return setUserinfo(userinfo, token.subject, token.scopes)
+}
+
+func (s *MemoryStorage) SetIntrospectionFromToken(_ context.Context, introspection *oidc.IntrospectionResponse, tokenID, _, clientID string) error {
+	token, err := s.accessToken(tokenID)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(token.audience, clientID) {
+		return errors.New("token is not valid for this client")
+	}
+	userinfo := new(oidc.UserInfo)
+	if err := setUserinfo(userinfo, token.subject, token.scopes); err != nil {
+		return err
+	}
+	introspection.SetUserInfo(userinfo)
+	introspection.Scope = token.scopes
+	introspection.ClientID = token.clientID
+	introspection.Expiration = oidc.FromTime(token.expiration)
+	return nil
+}
+
+func (s *MemoryStorage) accessToken(id string) (*accessToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.accessTokens[id]
+	if !ok || token.expiration.Before(time.Now()) {
+		return nil, errors.New("token is invalid or expired")
+	}
+	return token, nil
+}
+
+func setUserinfo(userinfo *oidc.UserInfo, userID string, scopes []string) error {
+	user, ok := users[userID]
+	if !ok {
+		return notFoundError("user not found")
+	}
+	for _, scope := range scopes {
+		switch scope {
+		case oidc.ScopeOpenID:
+			userinfo.Subject = user.id
+		case oidc.ScopeProfile:
+			userinfo.PreferredUsername = user.username
+			userinfo.Name = user.givenName + " " + user.familyName
+			userinfo.GivenName = user.givenName
+			userinfo.FamilyName = user.familyName
+		case oidc.ScopeEmail:
+			userinfo.Email = user.email
+			userinfo.EmailVerified = true
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStorage) GetPrivateClaimsFromScopes(context.Context, string, string, []string) (map[string]any, error) {
+	return nil, nil
+}
+
+// GetKeyByIDAndClientID returns an error, as the storage has no keys of clients,
+// which would be required for the JWT profile grant and private_key_jwt.
+func (s *MemoryStorage) GetKeyByIDAndClientID(context.Context, string, string) (*jose.JSONWebKey, error) {
+	return nil, notFoundError("key not found")
+}
+
+func (s *MemoryStorage) ValidateJWTProfileScopes(context.Context, string, []string) ([]string, error) {
+	return nil, errors.New("JWT profile grant not supported")
+}
+
+func (s *MemoryStorage) Health(context.Context) error {
+	return nil
+}
+
+func (s *MemoryStorage) StoreDeviceAuthorization(_ context.Context, clientID, deviceCode, userCode string, expires time.Time, scopes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.userCodes[userCode]; ok {
+		return op.ErrDuplicateUserCode
+	}
+	s.deviceCodes[deviceCode] = &op.DeviceAuthorizationState{
+		ClientID: clientID,
+		Scopes:   scopes,
+		Expires:  expires,
+	}
+	s.userCodes[userCode] = deviceCode
+	return nil
+}
+
+func (s *MemoryStorage) GetDeviceAuthorizatonState(_ context.Context, clientID, deviceCode string) (*op.DeviceAuthorizationState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.deviceCodes[deviceCode]
+	if !ok || state.ClientID != clientID {
+		return nil, notFoundError("device code not found")
+	}
+	copied := *state
+	return &copied, nil
+}
+
+func (s *MemoryStorage) CompleteDeviceAuthorization(_ context.Context, userCode, userID string) error {
+	if users[userID] == nil {
+		return notFoundError("user not found")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.deviceCodes[s.userCodes[userCode]]
+	if !ok {
+		return notFoundError("user code not found")
+	}
+	state.Subject = userID
+	state.AMR = []string{"pwd"}
+	state.AuthTime = time.Now()
+	state.Done = true
+	return nil
+}
+
+type signingKey struct {
+	id  string
+	key *rsa.PrivateKey
+}
+
+func (k *signingKey) SignatureAlgorithm() jose.SignatureAlgorithm { return jose.RS256 }
+func (k *signingKey) Key() any                                    { return k.key }
+func (k *signingKey) ID() string                                  { return k.id }
+
+type publicKey struct {
+	*signingKey
+}
+
+func (k *publicKey) Algorithm() jose.SignatureAlgorithm { return jose.RS256 }
+func (k *publicKey) Use() string                        { return "sig" }
+func (k *publicKey) Key() any                           { return &k.key.PublicKey }
+
+type authRequest struct {
+	id        string
+	request   *oidc.AuthRequest
+	userID    string
+	sessionID string
+	authTime  time.Time
+	done      bool
+}
+
+func (a *authRequest) GetID() string  { return a.id }
+func (a *authRequest) GetACR() string { return "" }
+func (a *authRequest) GetAMR() []string {
+	if a.done {
+		return []string{"pwd"}
+	}
+	return nil
+}
+func (a *authRequest) GetAudience() []string              { return []string{a.request.ClientID} }
+func (a *authRequest) GetAuthTime() time.Time             { return a.authTime }
+func (a *authRequest) GetClientID() string                { return a.request.ClientID }
+func (a *authRequest) GetNonce() string                   { return a.request.Nonce }
+func (a *authRequest) GetRedirectURI() string             { return a.request.RedirectURI }
+func (a *authRequest) GetResponseType() oidc.ResponseType { return a.request.ResponseType }
+func (a *authRequest) GetResponseMode() oidc.ResponseMode { return a.request.ResponseMode }
+func (a *authRequest) GetScopes() []string                { return a.request.Scopes }
+func (a *authRequest) GetState() string                   { return a.request.State }
+func (a *authRequest) GetSubject() string                 { return a.userID }
+func (a *authRequest) GetSessionID() string               { return a.sessionID }
+func (a *authRequest) Done() bool                         { return a.done }
+func (a *authRequest) GetCodeChallenge() *oidc.CodeChallenge {
+	if a.request.CodeChallenge == "" {
+		return nil
+	}
+	return &oidc.CodeChallenge{Challenge: a.request.CodeChallenge, Method: a.request.CodeChallengeMethod}
+}
+
+type accessToken struct {
+	id         string
+	subject    string
+	clientID   string
+	audience   []string
+	scopes     []string
+	expiration time.Time
+}
+
+type refreshToken struct {
+	token         string
+	subject       string
+	clientID      string
+	sessionID     string
+	audience      []string
+	scopes        []string
+	amr           []string
+	authTime      time.Time
+	expiration    time.Time
+	accessTokenID string
+}
+
+type refreshTokenRequest struct {
+	refreshToken
+}
+
+func (r *refreshTokenRequest) GetAMR() []string                     { return r.amr }
+func (r *refreshTokenRequest) GetAudience() []string                { return r.audience }
+func (r *refreshTokenRequest) GetAuthTime() time.Time               { return r.authTime }
+func (r *refreshTokenRequest) GetClientID() string                  { return r.clientID }
+func (r *refreshTokenRequest) GetScopes() []string                  { return r.scopes }
+func (r *refreshTokenRequest) GetSubject() string                   { return r.subject }
+func (r *refreshTokenRequest) GetSessionID() string                 { return r.sessionID }
+func (r *refreshTokenRequest) SetCurrentScopes(scopes []string)     { r.scopes = scopes }
+func (r *refreshTokenRequest) SetCurrentAudience(audience []string) { r.audience = audience }
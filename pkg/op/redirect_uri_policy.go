@@ -0,0 +1,134 @@
+package op
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// RedirectURIPolicy validates the redirect_uri of an authorization request
+// of a client, which is already unescaped.
+type RedirectURIPolicy interface {
+	ValidateRedirectURI(client Client, uri string, responseType oidc.ResponseType) error
+}
+
+// HasRedirectURIPolicy is an optional interface that can be implemented by implementors of
+// Client to replace the built-in rules of [ValidateAuthReqRedirectURI] by a [RedirectURIPolicy].
+// If RedirectURIPolicy returns nil, the built-in rules apply.
+type HasRedirectURIPolicy interface {
+	Client
+	RedirectURIPolicy() RedirectURIPolicy
+}
+
+var (
+	// StrictRedirectURIs only accepts redirect URIs which exactly match a registered one
+	// and use https, or http on loopback interfaces.
+	StrictRedirectURIs RedirectURIPolicy = RedirectURIRules{}
+
+	// NativeRedirectURIs additionally accepts the redirect URIs of native apps
+	// described in RFC 8252: loopback redirects on any port and private-use URI schemes.
+	NativeRedirectURIs RedirectURIPolicy = RedirectURIRules{
+		LoopbackAnyPort:   true,
+		PrivateUseSchemes: true,
+	}
+)
+
+// RedirectURIRules is a [RedirectURIPolicy] built from a set of rules.
+// The zero value is [StrictRedirectURIs]: redirect URIs must exactly match
+// a registered one, must not contain a fragment and must use https,
+// or http on a loopback interface.
+type RedirectURIRules struct {
+	// LoopbackAnyPort accepts redirect URIs on loopback interfaces
+	// with any port, as the port of native apps is picked at runtime (RFC 8252 section 7.3).
+	// All other parts must match a registered loopback redirect URI.
+	LoopbackAnyPort bool
+
+	// PrivateUseSchemes accepts registered redirect URIs with a private-use URI scheme,
+	// which must be in reverse domain name notation, e.g. com.example.app:/callback (RFC 8252 section 7.1).
+	PrivateUseSchemes bool
+
+	// Dev loosens the rules for development environments.
+	// It is only applied to clients in DevMode.
+	Dev DevRedirectURIRules
+}
+
+// DevRedirectURIRules loosens the [RedirectURIRules] of clients in DevMode.
+// They must never be enabled for production clients.
+type DevRedirectURIRules struct {
+	// AllowHTTP accepts registered http redirect URIs on any host.
+	AllowHTTP bool
+
+	// Patterns are additional redirect URI patterns, matched with
+	// https://pkg.go.dev/github.com/bmatcuk/doublestar/v4#Match,
+	// e.g. https://*.preview.example.com/callback.
+	Patterns []string
+}
+
+// ValidateRedirectURI implements [RedirectURIPolicy].
+func (r RedirectURIRules) ValidateRedirectURI(client Client, uri string, _ oidc.ResponseType) error {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme == "" {
+		return oidc.ErrInvalidRequestRedirectURI().WithDescription("The redirect_uri is invalid.").WithParent(err)
+	}
+	if parsed.Fragment != "" || strings.Contains(uri, "#") {
+		return oidc.ErrInvalidRequestRedirectURI().WithDescription("The redirect_uri must not contain a fragment.")
+	}
+	dev := client.DevMode()
+	if slices.Contains(client.RedirectURIs(), uri) {
+		return r.checkScheme(parsed, dev)
+	}
+	if r.LoopbackAnyPort && r.matchLoopback(client, parsed) {
+		return nil
+	}
+	if dev {
+		for _, pattern := range r.Dev.Patterns {
+			isMatch, err := doublestar.Match(pattern, uri)
+			if err != nil {
+				return oidc.ErrServerError().WithParent(err)
+			}
+			if isMatch {
+				return nil
+			}
+		}
+	}
+	return oidc.ErrInvalidRequestRedirectURI().
+		WithDescription("The requested redirect_uri is missing in the client configuration. " +
+			"If you have any questions, you may contact the administrator of the application.")
+}
+
+func (r RedirectURIRules) checkScheme(uri *url.URL, dev bool) error {
+	switch uri.Scheme {
+	case "https":
+		return nil
+	case "http":
+		if _, isLoopback := HTTPLoopbackOrLocalhost(uri.String()); isLoopback || dev && r.Dev.AllowHTTP {
+			return nil
+		}
+		return oidc.ErrInvalidRequestRedirectURI().WithDescription("This client's redirect_uri is http and is not allowed. " +
+			"If you have any questions, you may contact the administrator of the application.")
+	}
+	if r.PrivateUseSchemes && strings.Contains(uri.Scheme, ".") {
+		return nil
+	}
+	return oidc.ErrInvalidRequestRedirectURI().WithDescription("This client's redirect_uri is using a custom schema and is not allowed. " +
+		"If you have any questions, you may contact the administrator of the application.")
+}
+
+// matchLoopback reports whether the uri matches a registered loopback redirect URI,
+// ignoring the port.
+func (r RedirectURIRules) matchLoopback(client Client, uri *url.URL) bool {
+	if _, isLoopback := HTTPLoopbackOrLocalhost(uri.String()); !isLoopback {
+		return false
+	}
+	for _, registered := range client.RedirectURIs() {
+		redirectURI, ok := HTTPLoopbackOrLocalhost(registered)
+		if ok && redirectURI.Scheme == uri.Scheme && redirectURI.Hostname() == uri.Hostname() &&
+			redirectURI.User.String() == uri.User.String() && equalURI(uri, redirectURI) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+package op_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/mock"
+)
+
+type policyClient struct {
+	op.Client
+	policy op.RedirectURIPolicy
+}
+
+func (c policyClient) RedirectURIPolicy() op.RedirectURIPolicy {
+	return c.policy
+}
+
+func TestValidateAuthReqRedirectURI_Policy(t *testing.T) {
+	devRules := op.RedirectURIRules{
+		Dev: op.DevRedirectURIRules{
+			AllowHTTP: true,
+			Patterns:  []string{"https://*.preview.example.com/callback"},
+		},
+	}
+	tests := []struct {
+		name         string
+		uri          string
+		redirectURIs []string
+		devMode      bool
+		policy       op.RedirectURIPolicy
+		wantErr      bool
+	}{
+		{
+			name:         "strict exact",
+			uri:          "https://example.com/callback",
+			redirectURIs: []string{"https://example.com/callback"},
+			policy:       op.StrictRedirectURIs,
+		},
+		{
+			name:         "strict path differs",
+			uri:          "https://example.com/callback/other",
+			redirectURIs: []string{"https://example.com/callback"},
+			policy:       op.StrictRedirectURIs,
+			wantErr:      true,
+		},
+		{
+			name:         "strict fragment",
+			uri:          "https://example.com/callback#fragment",
+			redirectURIs: []string{"https://example.com/callback#fragment"},
+			policy:       op.StrictRedirectURIs,
+			wantErr:      true,
+		},
+		{
+			name:         "strict http",
+			uri:          "http://example.com/callback",
+			redirectURIs: []string{"http://example.com/callback"},
+			policy:       op.StrictRedirectURIs,
+			wantErr:      true,
+		},
+		{
+			name:         "strict http loopback",
+			uri:          "http://127.0.0.1:8080/callback",
+			redirectURIs: []string{"http://127.0.0.1:8080/callback"},
+			policy:       op.StrictRedirectURIs,
+		},
+		{
+			name:         "strict loopback other port",
+			uri:          "http://127.0.0.1:51234/callback",
+			redirectURIs: []string{"http://127.0.0.1/callback"},
+			policy:       op.StrictRedirectURIs,
+			wantErr:      true,
+		},
+		{
+			name:         "strict private-use scheme",
+			uri:          "com.example.app:/callback",
+			redirectURIs: []string{"com.example.app:/callback"},
+			policy:       op.StrictRedirectURIs,
+			wantErr:      true,
+		},
+		{
+			name:         "native loopback any port",
+			uri:          "http://127.0.0.1:51234/callback",
+			redirectURIs: []string{"http://127.0.0.1/callback"},
+			policy:       op.NativeRedirectURIs,
+		},
+		{
+			name:         "native loopback IPv6 any port",
+			uri:          "http://[::1]:51234/callback",
+			redirectURIs: []string{"http://[::1]/callback"},
+			policy:       op.NativeRedirectURIs,
+		},
+		{
+			name:         "native loopback path differs",
+			uri:          "http://127.0.0.1:51234/other",
+			redirectURIs: []string{"http://127.0.0.1/callback"},
+			policy:       op.NativeRedirectURIs,
+			wantErr:      true,
+		},
+		{
+			name:         "native loopback host differs",
+			uri:          "http://[::1]:51234/callback",
+			redirectURIs: []string{"http://127.0.0.1/callback"},
+			policy:       op.NativeRedirectURIs,
+			wantErr:      true,
+		},
+		{
+			name:         "native private-use scheme",
+			uri:          "com.example.app:/callback",
+			redirectURIs: []string{"com.example.app:/callback"},
+			policy:       op.NativeRedirectURIs,
+		},
+		{
+			name:         "native custom scheme without domain",
+			uri:          "custom://callback",
+			redirectURIs: []string{"custom://callback"},
+			policy:       op.NativeRedirectURIs,
+			wantErr:      true,
+		},
+		{
+			name:         "dev rules without dev mode",
+			uri:          "https://pr-1.preview.example.com/callback",
+			redirectURIs: []string{"https://example.com/callback"},
+			policy:       devRules,
+			wantErr:      true,
+		},
+		{
+			name:         "dev pattern",
+			uri:          "https://pr-1.preview.example.com/callback",
+			redirectURIs: []string{"https://example.com/callback"},
+			devMode:      true,
+			policy:       devRules,
+		},
+		{
+			name:         "dev pattern no match",
+			uri:          "https://pr-1.example.com/callback",
+			redirectURIs: []string{"https://example.com/callback"},
+			devMode:      true,
+			policy:       devRules,
+			wantErr:      true,
+		},
+		{
+			name:         "dev http",
+			uri:          "http://dev.example.com/callback",
+			redirectURIs: []string{"http://dev.example.com/callback"},
+			devMode:      true,
+			policy:       devRules,
+		},
+		{
+			name:         "dev http without dev mode",
+			uri:          "http://dev.example.com/callback",
+			redirectURIs: []string{"http://dev.example.com/callback"},
+			policy:       devRules,
+			wantErr:      true,
+		},
+		{
+			name:         "nil policy uses built-in rules",
+			uri:          "custom://callback",
+			redirectURIs: []string{"custom://callback"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := policyClient{
+				Client: mock.NewClientWithConfig(t, tt.redirectURIs, op.ApplicationTypeNative, nil, tt.devMode),
+				policy: tt.policy,
+			}
+			err := op.ValidateAuthReqRedirectURI(client, tt.uri, oidc.ResponseTypeCode)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, &oidc.Error{ErrorType: oidc.InvalidRequest})
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
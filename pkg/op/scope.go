@@ -0,0 +1,261 @@
+package op
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// Scope declares a scope supported by the OP, see [ScopeRegistry].
+type Scope struct {
+	Name string
+	// Description explains the access granted by the scope to the user,
+	// e.g. on the consent page.
+	Description string
+	// Claims lists the claims released for the scope,
+	// e.g. the profile claims for the profile scope.
+	Claims []string
+	// Consentable scopes grant access to data or actions of the user,
+	// which the user must consent to. Scopes such as openid are not consentable.
+	Consentable bool
+	// Offline scopes request a refresh token, such as offline_access.
+	// They are only valid for clients allowed to use the refresh_token grant.
+	Offline bool
+	// Audience lists the resource servers the scope grants access to.
+	// Audience-bound scopes are only valid for clients allowed by [Client.IsScopeAllowed].
+	Audience []string
+}
+
+// DefaultScopes returns the scopes defined by OpenID Connect Core,
+// which are announced by default, see [DefaultSupportedScopes].
+func DefaultScopes() []Scope {
+	return []Scope{
+		{
+			Name:        oidc.ScopeOpenID,
+			Description: "Sign you in",
+			Claims:      []string{"sub"},
+		},
+		{
+			Name:        oidc.ScopeProfile,
+			Description: "Read your profile",
+			Claims: []string{
+				"name", "family_name", "given_name", "middle_name", "nickname", "preferred_username",
+				"profile", "picture", "website", "gender", "birthdate", "zoneinfo", "locale", "updated_at",
+			},
+			Consentable: true,
+		},
+		{
+			Name:        oidc.ScopeEmail,
+			Description: "Read your email address",
+			Claims:      []string{"email", "email_verified"},
+			Consentable: true,
+		},
+		{
+			Name:        oidc.ScopePhone,
+			Description: "Read your phone number",
+			Claims:      []string{"phone_number", "phone_number_verified"},
+			Consentable: true,
+		},
+		{
+			Name:        oidc.ScopeAddress,
+			Description: "Read your address",
+			Claims:      []string{"address"},
+			Consentable: true,
+		},
+		{
+			Name:        oidc.ScopeOfflineAccess,
+			Description: "Keep access while you are not signed in",
+			Consentable: true,
+			Offline:     true,
+		},
+	}
+}
+
+// ScopeRegistry holds the declared scopes of the OP, see [WithScopeRegistry].
+type ScopeRegistry struct {
+	scopes []Scope
+	byName map[string]int
+}
+
+// NewScopeRegistry creates a registry of the scopes, usually
+// the [DefaultScopes] together with the custom scopes of the OP.
+// The names must not be empty nor declared twice.
+func NewScopeRegistry(scopes ...Scope) (*ScopeRegistry, error) {
+	r := &ScopeRegistry{
+		scopes: slices.Clone(scopes),
+		byName: make(map[string]int, len(scopes)),
+	}
+	for i, scope := range scopes {
+		if scope.Name == "" {
+			return nil, errors.New("scope name must not be empty")
+		}
+		if _, ok := r.byName[scope.Name]; ok {
+			return nil, fmt.Errorf("scope %q is declared twice", scope.Name)
+		}
+		r.byName[scope.Name] = i
+	}
+	return r, nil
+}
+
+// Scope returns the declared scope by its name.
+func (r *ScopeRegistry) Scope(name string) (Scope, bool) {
+	i, ok := r.byName[name]
+	if !ok {
+		return Scope{}, false
+	}
+	return r.scopes[i], true
+}
+
+// Names returns the names of the declared scopes, in their order.
+// They are announced as scopes_supported in the discovery.
+func (r *ScopeRegistry) Names() []string {
+	names := make([]string, len(r.scopes))
+	for i, scope := range r.scopes {
+		names[i] = scope.Name
+	}
+	return names
+}
+
+// Consent returns the declared and consentable scopes of the requested scopes,
+// so consent pages can display their descriptions.
+func (r *ScopeRegistry) Consent(scopes []string) []Scope {
+	var consent []Scope
+	for _, name := range scopes {
+		if scope, ok := r.Scope(name); ok && scope.Consentable {
+			consent = append(consent, scope)
+		}
+	}
+	return consent
+}
+
+// Claims returns the claims released for the scopes, without duplicates.
+// Storages can use it to set the claims of the userinfo and the id_token.
+func (r *ScopeRegistry) Claims(scopes []string) []string {
+	var claims []string
+	for _, name := range scopes {
+		scope, _ := r.Scope(name)
+		for _, claim := range scope.Claims {
+			if !slices.Contains(claims, claim) {
+				claims = append(claims, claim)
+			}
+		}
+	}
+	return claims
+}
+
+// Audience returns the audience of the audience-bound scopes, without duplicates.
+// Storages can add it to the audience of the access tokens.
+func (r *ScopeRegistry) Audience(scopes []string) []string {
+	var audience []string
+	for _, name := range scopes {
+		scope, _ := r.Scope(name)
+		for _, aud := range scope.Audience {
+			if !slices.Contains(audience, aud) {
+				audience = append(audience, aud)
+			}
+		}
+	}
+	return audience
+}
+
+// Validate checks the requested scopes against the registry and the allowances of the client.
+// Unlike [ValidateAuthReqScopes], unsupported scopes are not removed,
+// but rejected with an invalid_scope error:
+//   - scopes not declared must be allowed by [Client.IsScopeAllowed], e.g. for dynamic scopes;
+//   - audience-bound scopes must be allowed by [Client.IsScopeAllowed];
+//   - offline scopes require the refresh_token grant type of the client.
+//
+// An invalid_request error is returned if scopes is empty.
+func (r *ScopeRegistry) Validate(client Client, scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, oidc.ErrInvalidRequest().
+			WithDescription("The scope of your request is missing. Please ensure some scopes are requested. " +
+				"If you have any questions, you may contact the administrator of the application.")
+	}
+	for _, name := range scopes {
+		scope, ok := r.Scope(name)
+		if (!ok || len(scope.Audience) > 0) && !client.IsScopeAllowed(name) {
+			return nil, oidc.ErrInvalidScope().WithDescription("The scope %s is not allowed for the client.", name)
+		}
+		if scope.Offline && !ValidateGrantType(client, oidc.GrantTypeRefreshToken) {
+			return nil, oidc.ErrInvalidScope().WithDescription("The scope %s requires the refresh_token grant type.", name)
+		}
+	}
+	return scopes, nil
+}
+
+// WithScopeRegistry declares the scopes of the OP. The scopes of auth requests and
+// device authorization requests are validated by [ScopeRegistry.Validate],
+// and the names are announced as scopes_supported in the discovery,
+// instead of [Config.SupportedScopes].
+func WithScopeRegistry(registry *ScopeRegistry) Option {
+	return func(o *Provider) error {
+		if registry == nil {
+			return errors.New("scope registry must not be nil")
+		}
+		o.scopeRegistry = registry
+		return nil
+	}
+}
+
+// ScopeRegistry returns the registry set by [WithScopeRegistry].
+func (o *Provider) ScopeRegistry() *ScopeRegistry {
+	return o.scopeRegistry
+}
+
+type scopeRegistryKey struct{}
+
+// ContextWithScopeRegistry returns a context carrying the registry.
+// It is set for all requests of the OP by [WithScopeRegistry].
+func ContextWithScopeRegistry(ctx context.Context, registry *ScopeRegistry) context.Context {
+	return context.WithValue(ctx, scopeRegistryKey{}, registry)
+}
+
+// ScopeRegistryFromContext returns the registry of the OP,
+// so storages and UIs can look up the declared scopes.
+// It returns nil if no registry is set.
+func ScopeRegistryFromContext(ctx context.Context) *ScopeRegistry {
+	registry, _ := ctx.Value(scopeRegistryKey{}).(*ScopeRegistry)
+	return registry
+}
+
+type scopeRegistryProvider interface {
+	ScopeRegistry() *ScopeRegistry
+}
+
+// scopeRegistryInterceptor sets the scope registry of the provider on the context of the requests.
+func scopeRegistryInterceptor(v any) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		p, ok := v.(scopeRegistryProvider)
+		if !ok || p.ScopeRegistry() == nil {
+			return next
+		}
+		registry := p.ScopeRegistry()
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(ContextWithScopeRegistry(r.Context(), registry)))
+		})
+	}
+}
+
+// validateAuthReqScopes validates the scopes with the registry of the context,
+// or with [ValidateAuthReqScopes] if there is none.
+func validateAuthReqScopes(ctx context.Context, client Client, scopes []string) ([]string, error) {
+	if registry := ScopeRegistryFromContext(ctx); registry != nil {
+		return registry.Validate(client, scopes)
+	}
+	return ValidateAuthReqScopes(client, scopes)
+}
+
+// validateDeviceScopes validates the scopes of a device authorization request
+// with the registry of the context. The scope is optional for device authorization requests,
+// so they are not validated without scopes or registry.
+func validateDeviceScopes(ctx context.Context, client Client, scopes []string) ([]string, error) {
+	if registry := ScopeRegistryFromContext(ctx); registry != nil && len(scopes) > 0 {
+		return registry.Validate(client, scopes)
+	}
+	return scopes, nil
+}
@@ -0,0 +1,146 @@
+package op_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/example/server/storage"
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/optest"
+)
+
+var apiScope = op.Scope{
+	Name:        storage.CustomScope,
+	Description: "Access the API",
+	Claims:      []string{storage.CustomClaim},
+	Consentable: true,
+	Audience:    []string{"api"},
+}
+
+func newScopeRegistry(t *testing.T) *op.ScopeRegistry {
+	registry, err := op.NewScopeRegistry(append(op.DefaultScopes(), apiScope)...)
+	require.NoError(t, err)
+	return registry
+}
+
+func TestNewScopeRegistry(t *testing.T) {
+	_, err := op.NewScopeRegistry(op.Scope{})
+	assert.Error(t, err, "empty name")
+	_, err = op.NewScopeRegistry(apiScope, apiScope)
+	assert.Error(t, err, "declared twice")
+
+	registry := newScopeRegistry(t)
+	assert.Equal(t, append(op.DefaultSupportedScopes, storage.CustomScope), registry.Names())
+	scope, ok := registry.Scope(storage.CustomScope)
+	assert.True(t, ok)
+	assert.Equal(t, apiScope, scope)
+	_, ok = registry.Scope("unknown")
+	assert.False(t, ok)
+
+	scopes := []string{oidc.ScopeOpenID, oidc.ScopeEmail, storage.CustomScope, "unknown"}
+	email, _ := registry.Scope(oidc.ScopeEmail)
+	assert.Equal(t, []op.Scope{email, apiScope}, registry.Consent(scopes))
+	assert.Equal(t, []string{"sub", "email", "email_verified", storage.CustomClaim}, registry.Claims(scopes))
+	assert.Equal(t, []string{"api"}, registry.Audience(scopes))
+}
+
+func TestScopeRegistry_Validate(t *testing.T) {
+	registry := newScopeRegistry(t)
+	tests := []struct {
+		name      string
+		client    op.Client
+		scopes    []string
+		wantErr   error
+		wantScope []string
+	}{
+		{
+			name:    "missing",
+			client:  storage.WebClient("web", "secret"),
+			wantErr: oidc.ErrInvalidRequest(),
+		},
+		{
+			name:      "declared",
+			client:    storage.WebClient("web", "secret"),
+			scopes:    []string{oidc.ScopeOpenID, oidc.ScopeProfile, oidc.ScopeOfflineAccess},
+			wantScope: []string{oidc.ScopeOpenID, oidc.ScopeProfile, oidc.ScopeOfflineAccess},
+		},
+		{
+			name:    "unknown",
+			client:  storage.WebClient("web", "secret"),
+			scopes:  []string{oidc.ScopeOpenID, "unknown"},
+			wantErr: oidc.ErrInvalidScope(),
+		},
+		{
+			name:      "audience-bound, allowed for the client",
+			client:    storage.WebClient("web", "secret"),
+			scopes:    []string{oidc.ScopeOpenID, storage.CustomScope},
+			wantScope: []string{oidc.ScopeOpenID, storage.CustomScope},
+		},
+		{
+			name:    "offline without refresh_token grant",
+			client:  storage.DeviceClient("device", "secret"),
+			scopes:  []string{oidc.ScopeOpenID, oidc.ScopeOfflineAccess},
+			wantErr: oidc.ErrInvalidScope(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scopes, err := registry.Validate(tt.client, tt.scopes)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantScope, scopes)
+		})
+	}
+}
+
+func TestWithScopeRegistry(t *testing.T) {
+	_, err := op.NewProvider(testConfig, nil, op.StaticIssuer(testIssuer), op.WithScopeRegistry(nil))
+	assert.Error(t, err)
+
+	registry := newScopeRegistry(t)
+	s := optest.New(t, optest.WithProviderOptions(op.WithScopeRegistry(registry)))
+	assert.Same(t, registry, s.Provider.ScopeRegistry())
+
+	resp, err := s.Client().Get(s.Issuer + oidc.DiscoveryEndpoint)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var discovery oidc.DiscoveryConfiguration
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&discovery))
+	assert.Equal(t, registry.Names(), discovery.ScopesSupported)
+
+	relyingParty, err := rp.NewRelyingPartyOIDC(context.Background(), s.Issuer, optest.WebClientID, optest.WebClientSecret, optest.RedirectURI,
+		[]string{oidc.ScopeOpenID, "unknown"}, rp.WithHTTPClient(s.Client()))
+	require.NoError(t, err)
+	callback := s.Authorize(t, rp.AuthURL("state", relyingParty))
+	assert.Equal(t, "invalid_scope", callback.Query().Get("error"))
+
+	tokens := s.CodeFlow(t, s.RelyingParty(t))
+	assert.NotEmpty(t, tokens.RefreshToken)
+}
+
+func TestWithScopeRegistry_deviceAuthorization(t *testing.T) {
+	s := optest.New(t,
+		optest.WithConfig(testConfig),
+		optest.WithClients(optest.DeviceClient("device", "secret")),
+		optest.WithProviderOptions(op.WithScopeRegistry(newScopeRegistry(t))),
+	)
+	relyingParty, err := rp.NewRelyingPartyOIDC(context.Background(), s.Issuer, "device", "secret", "",
+		[]string{oidc.ScopeOpenID, oidc.ScopeOfflineAccess}, rp.WithHTTPClient(s.Client()))
+	require.NoError(t, err)
+	_, err = rp.DeviceAuthorization(context.Background(), relyingParty.OAuthConfig().Scopes, relyingParty, nil)
+	var oidcErr *oidc.Error
+	require.ErrorAs(t, err, &oidcErr)
+	assert.Equal(t, oidc.InvalidScope, oidcErr.ErrorType)
+
+	_, err = rp.DeviceAuthorization(context.Background(), []string{oidc.ScopeOpenID}, relyingParty, nil)
+	assert.NoError(t, err)
+}
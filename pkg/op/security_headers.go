@@ -0,0 +1,201 @@
+package op
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// SecurityHeaders are the HTTP response headers which protect the pages
+// and responses served by the OP. Empty fields are not set.
+type SecurityHeaders struct {
+	ContentSecurityPolicy string
+	FrameOptions          string
+	ReferrerPolicy        string
+	ContentTypeOptions    string
+	CacheControl          string
+	Pragma                string
+}
+
+// DefaultSecurityHeaders are set on all endpoints of the OP.
+// The Content-Security-Policy allows the inline scripts and styles of the form_post,
+// front-channel logout and error pages, which submit forms to and frame the clients.
+var DefaultSecurityHeaders = SecurityHeaders{
+	ContentSecurityPolicy: "default-src 'none'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; img-src 'self' data:; frame-src *; form-action *; base-uri 'none'; frame-ancestors 'none'",
+	FrameOptions:          "DENY",
+	ReferrerPolicy:        "no-referrer",
+	ContentTypeOptions:    "nosniff",
+}
+
+// NoStore returns a copy of the headers, which forbid caching of the response,
+// as required for token responses by RFC 6749, section 5.1.
+func (h SecurityHeaders) NoStore() SecurityHeaders {
+	h.CacheControl = "no-store"
+	h.Pragma = "no-cache"
+	return h
+}
+
+// FramedBy returns a copy of the headers, which allow the page to be framed by the sources,
+// such as "*" or the origins of the clients.
+// The X-Frame-Options header is removed, as it cannot express the exception.
+func (h SecurityHeaders) FramedBy(sources ...string) SecurityHeaders {
+	directives := strings.Split(h.ContentSecurityPolicy, ";")
+	policy := make([]string, 0, len(directives)+1)
+	for _, directive := range directives {
+		directive = strings.TrimSpace(directive)
+		if directive == "" || strings.HasPrefix(directive, "frame-ancestors") {
+			continue
+		}
+		policy = append(policy, directive)
+	}
+	policy = append(policy, "frame-ancestors "+strings.Join(sources, " "))
+	h.ContentSecurityPolicy = strings.Join(policy, "; ")
+	h.FrameOptions = ""
+	return h
+}
+
+// Handler sets the headers on the response before calling next,
+// which may still override them.
+// It can be used to protect pages served next to the OP, such as the login UI.
+func (h SecurityHeaders) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.set(w.Header())
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h SecurityHeaders) set(header http.Header) {
+	setHeader(header, "Content-Security-Policy", h.ContentSecurityPolicy)
+	setHeader(header, "X-Frame-Options", h.FrameOptions)
+	setHeader(header, "Referrer-Policy", h.ReferrerPolicy)
+	setHeader(header, "X-Content-Type-Options", h.ContentTypeOptions)
+	setHeader(header, "Cache-Control", h.CacheControl)
+	setHeader(header, "Pragma", h.Pragma)
+}
+
+func setHeader(header http.Header, key, value string) {
+	if value != "" {
+		header.Set(key, value)
+	}
+}
+
+// SecurityHeadersConfig configures the security headers of the endpoints of the OP.
+type SecurityHeadersConfig struct {
+	// Default is set on all endpoints without an entry in Endpoints.
+	// The token, introspection, userinfo, revocation and device authorization endpoints
+	// additionally forbid caching and the check_session_iframe may be framed,
+	// see [SecurityHeaders.NoStore] and [SecurityHeaders.FramedBy].
+	Default SecurityHeaders
+	// Endpoints overrides the headers by the relative path of the endpoint, e.g. "/userinfo".
+	Endpoints map[string]SecurityHeaders
+}
+
+// DefaultSecurityHeadersConfig returns the config, which is used if not set
+// by [WithSecurityHeaders] or [WithServerSecurityHeaders].
+func DefaultSecurityHeadersConfig() *SecurityHeadersConfig {
+	return &SecurityHeadersConfig{
+		Default: DefaultSecurityHeaders,
+	}
+}
+
+// resolve returns the headers by the relative path of the endpoints.
+func (c *SecurityHeadersConfig) resolve(endpoints Endpoints) map[string]SecurityHeaders {
+	headers := make(map[string]SecurityHeaders, len(c.Endpoints)+6)
+	for _, e := range []*Endpoint{
+		endpoints.Token,
+		endpoints.Introspection,
+		endpoints.Userinfo,
+		endpoints.Revocation,
+		endpoints.DeviceAuthorization,
+	} {
+		if e != nil {
+			headers[e.Relative()] = c.Default.NoStore()
+		}
+	}
+	if e := endpoints.CheckSessionIframe; e != nil {
+		headers[e.Relative()] = c.Default.FramedBy("*")
+	}
+	for path, h := range c.Endpoints {
+		headers[path] = h
+	}
+	return headers
+}
+
+// securityHeadersInterceptor sets the headers of the config on the responses of the endpoints,
+// which are matched by the [routePath], so the OP can be mounted on a sub path.
+// A nil config disables the headers.
+func securityHeadersInterceptor(config *SecurityHeadersConfig, endpoints Endpoints) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if config == nil {
+			return next
+		}
+		headers := config.resolve(endpoints)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h, ok := headers[routePath(r)]
+			if !ok {
+				h = config.Default
+			}
+			h.set(w.Header())
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WithSecurityHeaders sets the security headers of the endpoints of the provider.
+// Passing nil disables them. Defaults to [DefaultSecurityHeadersConfig].
+func WithSecurityHeaders(config *SecurityHeadersConfig) Option {
+	return func(o *Provider) error {
+		o.securityHeaders = config
+		return nil
+	}
+}
+
+// WithEndpointSecurityHeaders overrides the security headers of a single endpoint,
+// e.g. to allow framing the end_session page by the clients.
+// It has no effect if the headers are disabled.
+func WithEndpointSecurityHeaders(endpoint *Endpoint, headers SecurityHeaders) Option {
+	return func(o *Provider) error {
+		if o.securityHeaders == nil {
+			return nil
+		}
+		config := *o.securityHeaders
+		config.Endpoints = make(map[string]SecurityHeaders, len(o.securityHeaders.Endpoints)+1)
+		for path, h := range o.securityHeaders.Endpoints {
+			config.Endpoints[path] = h
+		}
+		config.Endpoints[endpoint.Relative()] = headers
+		o.securityHeaders = &config
+		return nil
+	}
+}
+
+// SecurityHeaders returns the config set by [WithSecurityHeaders].
+func (o *Provider) SecurityHeaders() *SecurityHeadersConfig {
+	return o.securityHeaders
+}
+
+type securityHeadersOptioner interface {
+	SecurityHeaders() *SecurityHeadersConfig
+}
+
+// WithServerSecurityHeaders sets the security headers of the Server's endpoints.
+// Passing nil disables them. Defaults to [DefaultSecurityHeadersConfig].
+func WithServerSecurityHeaders(config *SecurityHeadersConfig) ServerOption {
+	return func(s *webServer) {
+		s.securityHeaders = config
+	}
+}
+
+// routePath returns the path the routes of a chi router are matched by.
+// It is relative to the mount point, if the router is mounted on another chi router,
+// see [chi.Mux.Mount].
+func routePath(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePath != "" {
+		return rctx.RoutePath
+	}
+	if r.URL.RawPath != "" {
+		return r.URL.RawPath
+	}
+	return r.URL.Path
+}
@@ -0,0 +1,118 @@
+package op
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityHeaders_FramedBy(t *testing.T) {
+	got := DefaultSecurityHeaders.FramedBy("https://rp.example.com", "https://other.example.com")
+	assert.Equal(t, "default-src 'none'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; img-src 'self' data:; frame-src *; form-action *; base-uri 'none'; frame-ancestors https://rp.example.com https://other.example.com", got.ContentSecurityPolicy)
+	assert.Empty(t, got.FrameOptions)
+	assert.Equal(t, "DENY", DefaultSecurityHeaders.FrameOptions, "defaults unchanged")
+}
+
+func TestRegisterServer_securityHeaders(t *testing.T) {
+	endpoints := Endpoints{
+		Authorization:      NewEndpoint("authorize"),
+		Token:              NewEndpoint("oauth/token"),
+		Userinfo:           NewEndpoint("userinfo"),
+		EndSession:         NewEndpoint("end_session"),
+		CheckSessionIframe: NewEndpoint("check_session"),
+	}
+	tests := []struct {
+		name    string
+		options []ServerOption
+		path    string
+		want    http.Header
+	}{
+		{
+			name: "default",
+			path: "/authorize",
+			want: http.Header{
+				"Content-Security-Policy": {DefaultSecurityHeaders.ContentSecurityPolicy},
+				"X-Frame-Options":         {"DENY"},
+				"Referrer-Policy":         {"no-referrer"},
+				"X-Content-Type-Options":  {"nosniff"},
+			},
+		},
+		{
+			name: "token no-store",
+			path: "/oauth/token",
+			want: http.Header{
+				"Content-Security-Policy": {DefaultSecurityHeaders.ContentSecurityPolicy},
+				"X-Frame-Options":         {"DENY"},
+				"Referrer-Policy":         {"no-referrer"},
+				"X-Content-Type-Options":  {"nosniff"},
+				"Cache-Control":           {"no-store"},
+				"Pragma":                  {"no-cache"},
+			},
+		},
+		{
+			name: "check session iframe",
+			path: "/check_session",
+			want: http.Header{
+				"Content-Security-Policy": {DefaultSecurityHeaders.FramedBy("*").ContentSecurityPolicy},
+				"Referrer-Policy":         {"no-referrer"},
+				"X-Content-Type-Options":  {"nosniff"},
+			},
+		},
+		{
+			name: "endpoint override",
+			options: []ServerOption{WithServerSecurityHeaders(&SecurityHeadersConfig{
+				Default:   DefaultSecurityHeaders,
+				Endpoints: map[string]SecurityHeaders{"/end_session": {ReferrerPolicy: "origin"}},
+			})},
+			path: "/end_session",
+			want: http.Header{
+				"Referrer-Policy": {"origin"},
+			},
+		},
+		{
+			name:    "disabled",
+			options: []ServerOption{WithServerSecurityHeaders(nil)},
+			path:    "/userinfo",
+			want:    http.Header{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := append([]ServerOption{WithServerCORSOptions(nil)}, tt.options...)
+			h := RegisterServer(UnimplementedServer{}, endpoints, options...)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			got := rec.Header().Clone()
+			got.Del("Content-Type")
+			got.Del("Content-Length")
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRegisterServer_securityHeadersMounted(t *testing.T) {
+	endpoints := Endpoints{Token: NewEndpoint("oauth/token")}
+	h := RegisterServer(UnimplementedServer{}, endpoints, WithServerCORSOptions(nil))
+	router := chi.NewRouter()
+	router.Mount("/chi", h)
+	router.Mount("/std", http.StripPrefix("/std", h))
+
+	for _, path := range []string{"/chi/oauth/token", "/std/oauth/token"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"), path)
+	}
+}
+
+func TestSecurityHeaders_Handler(t *testing.T) {
+	h := DefaultSecurityHeaders.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Referrer-Policy", "origin")
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/login", nil))
+	assert.Equal(t, "DENY", rec.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "origin", rec.Header().Get("Referrer-Policy"))
+}
@@ -2,6 +2,8 @@ package op
 
 import (
 	"context"
+	"crypto/tls"
+	"log/slog"
 	"net/http"
 	"net/url"
 
@@ -50,6 +52,11 @@ type Server interface {
 	// The recommended Response Data type is [jose.JSONWebKeySet].
 	Keys(context.Context, *Request[struct{}]) (*Response, error)
 
+	// WebFinger returns the issuer of the user identified by the resource of the request.
+	// https://openid.net/specs/openid-connect-discovery-1_0.html#IssuerDiscovery
+	// The recommended Response Data type is [oidc.WebFingerResponse].
+	WebFinger(context.Context, *Request[oidc.WebFingerRequest]) (*Response, error)
+
 	// VerifyAuthRequest verifies the Auth Request and
 	// adds the Client to the request.
 	//
@@ -149,6 +156,14 @@ type Server interface {
 	mustImpl()
 }
 
+// SignedKeysServer is an optional interface of a [Server]
+// to serve the JSON Web Key Set as signed JWT at the SignedJwksURI endpoint.
+type SignedKeysServer interface {
+	// SignedKeys returns the JSON Web Key Set as signed JWT, see [SignKeySet].
+	// https://openid.net/specs/openid-federation-1_0.html#name-openid-connect-and-oauth2-m
+	SignedKeys(context.Context, *Request[struct{}]) (string, error)
+}
+
 // Request contains the [http.Request] informational fields
 // and parsed Data from the request body (POST) or URL parameters (GET).
 // Data can be assumed to be validated according to the applicable
@@ -161,7 +176,12 @@ type Request[T any] struct {
 	Header   http.Header
 	Form     url.Values
 	PostForm url.Values
-	Data     *T
+	// TLS is the connection state of the request,
+	// nil for plain HTTP connections.
+	TLS *tls.ConnectionState
+	// RemoteAddr is the network address of the client, see [http.Request.RemoteAddr].
+	RemoteAddr string
+	Data       *T
 }
 
 func (r *Request[_]) path() string {
@@ -170,12 +190,14 @@ func (r *Request[_]) path() string {
 
 func newRequest[T any](r *http.Request, data *T) *Request[T] {
 	return &Request[T]{
-		Method:   r.Method,
-		URL:      r.URL,
-		Header:   r.Header,
-		Form:     r.Form,
-		PostForm: r.PostForm,
-		Data:     data,
+		Method:     r.Method,
+		URL:        r.URL,
+		Header:     r.Header,
+		Form:       r.Form,
+		PostForm:   r.PostForm,
+		TLS:        r.TLS,
+		RemoteAddr: r.RemoteAddr,
+		Data:       data,
 	}
 }
 
@@ -240,6 +262,10 @@ type Redirect struct {
 	Header http.Header
 
 	URL string
+
+	// FrontChannelLogoutURIs are loaded by the user agent
+	// in hidden iframes before the redirect, see [RenderFrontChannelLogout].
+	FrontChannelLogoutURIs []string
 }
 
 func NewRedirect(url string) *Redirect {
@@ -251,6 +277,12 @@ func NewRedirect(url string) *Redirect {
 
 func (red *Redirect) writeOut(w http.ResponseWriter, r *http.Request) {
 	gu.MapMerge(red.Header, w.Header())
+	if len(red.FrontChannelLogoutURIs) > 0 {
+		if err := RenderFrontChannelLogout(w, red.FrontChannelLogoutURIs, red.URL); err != nil {
+			slog.ErrorContext(r.Context(), "front-channel logout: rendering page failed", "error", err)
+		}
+		return
+	}
 	http.Redirect(w, r, red.URL, http.StatusFound)
 }
 
@@ -290,6 +322,10 @@ func (UnimplementedServer) Keys(ctx context.Context, r *Request[struct{}]) (*Res
 	return nil, unimplementedError(r)
 }
 
+func (UnimplementedServer) WebFinger(ctx context.Context, r *Request[oidc.WebFingerRequest]) (*Response, error) {
+	return nil, unimplementedError(r)
+}
+
 func (UnimplementedServer) VerifyAuthRequest(ctx context.Context, r *Request[oidc.AuthRequest]) (*ClientRequest[oidc.AuthRequest], error) {
 	if r.Data.RequestParam != "" {
 		return nil, oidc.ErrRequestNotSupported()
@@ -2,6 +2,7 @@ package op
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 	"net/url"
@@ -24,11 +25,12 @@ func RegisterServer(server Server, endpoints Endpoints, options ...ServerOption)
 	decoder.IgnoreUnknownKeys(true)
 
 	ws := &webServer{
-		router:    chi.NewRouter(),
-		server:    server,
-		endpoints: endpoints,
-		decoder:   decoder,
-		corsOpts:  &defaultCORSOptions,
+		router:          chi.NewRouter(),
+		server:          server,
+		endpoints:       endpoints,
+		decoder:         decoder,
+		corsOpts:        &defaultCORSOptions,
+		securityHeaders: DefaultSecurityHeadersConfig(),
 	}
 
 	for _, option := range options {
@@ -36,9 +38,9 @@ func RegisterServer(server Server, endpoints Endpoints, options ...ServerOption)
 	}
 
 	ws.createRouter()
-	ws.handler = ws.router
+	ws.handler = securityHeadersInterceptor(ws.securityHeaders, endpoints)(ws.router)
 	if ws.corsOpts != nil {
-		ws.handler = cors.New(*ws.corsOpts).Handler(ws.router)
+		ws.handler = cors.New(*ws.corsOpts).Handler(ws.handler)
 	}
 	return ws
 }
@@ -83,12 +85,13 @@ func WithFallbackLogger(*slog.Logger) ServerOption {
 }
 
 type webServer struct {
-	server    Server
-	router    *chi.Mux
-	handler   http.Handler
-	endpoints Endpoints
-	decoder   httphelper.Decoder
-	corsOpts  *cors.Options
+	server          Server
+	router          *chi.Mux
+	handler         http.Handler
+	endpoints       Endpoints
+	decoder         httphelper.Decoder
+	corsOpts        *cors.Options
+	securityHeaders *SecurityHeadersConfig
 }
 
 func (s *webServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -99,6 +102,7 @@ func (s *webServer) createRouter() {
 	s.router.HandleFunc(healthEndpoint, simpleHandler(s, s.server.Health))
 	s.router.HandleFunc(readinessEndpoint, simpleHandler(s, s.server.Ready))
 	s.router.HandleFunc(oidc.DiscoveryEndpoint, simpleHandler(s, s.server.Discovery))
+	s.router.HandleFunc(oidc.WebFingerEndpoint, s.webFingerHandler)
 
 	s.endpointRoute(s.endpoints.Authorization, s.authorizeHandler)
 	s.endpointRoute(s.endpoints.DeviceAuthorization, s.withClient(s.deviceAuthorizationHandler))
@@ -108,6 +112,9 @@ func (s *webServer) createRouter() {
 	s.endpointRoute(s.endpoints.Revocation, s.withClient(s.revocationHandler))
 	s.endpointRoute(s.endpoints.EndSession, s.endSessionHandler)
 	s.endpointRoute(s.endpoints.JwksURI, simpleHandler(s, s.server.Keys))
+	if signedKeysServer, ok := s.server.(SignedKeysServer); ok {
+		s.endpointRoute(s.endpoints.SignedJwksURI, s.signedKeysHandler(signedKeysServer))
+	}
 }
 
 func (s *webServer) endpointRoute(e *Endpoint, hf http.HandlerFunc) {
@@ -147,25 +154,45 @@ func (s *webServer) withClient(handler clientHandler) http.HandlerFunc {
 }
 
 func (s *webServer) verifyRequestClient(r *http.Request) (_ Client, err error) {
-	cc, err := s.parseClientCredentials(r)
+	cc, err := parseClientCredentials(r, s.decoder)
 	if err != nil {
 		return nil, err
 	}
-	return s.server.VerifyClient(r.Context(), &Request[ClientCredentials]{
-		Method: r.Method,
-		URL:    r.URL,
-		Header: r.Header,
-		Form:   r.Form,
-		Data:   cc,
-	})
+	return s.server.VerifyClient(r.Context(), newClientCredentialsRequest(r, cc))
+}
+
+func newClientCredentialsRequest(r *http.Request, cc *ClientCredentials) *Request[ClientCredentials] {
+	return &Request[ClientCredentials]{
+		Method:     r.Method,
+		URL:        r.URL,
+		Header:     r.Header,
+		Form:       r.Form,
+		PostForm:   r.PostForm,
+		TLS:        r.TLS,
+		RemoteAddr: r.RemoteAddr,
+		Data:       cc,
+	}
+}
+
+func parseClientCredentials(r *http.Request, decoder httphelper.Decoder) (*ClientCredentials, error) {
+	cc, err := decodeClientCredentials(r, decoder)
+	if err != nil {
+		return nil, err
+	}
+	if cc.ClientID == "" && cc.ClientAssertion == "" {
+		return nil, oidc.ErrInvalidRequest().WithDescription("client_id or client_assertion must be provided")
+	}
+	return cc, nil
 }
 
-func (s *webServer) parseClientCredentials(r *http.Request) (_ *ClientCredentials, err error) {
+// decodeClientCredentials decodes the credentials of the request,
+// which may be empty when the client authenticates by other means, e.g. a custom [ClientAuthenticator].
+func decodeClientCredentials(r *http.Request, decoder httphelper.Decoder) (_ *ClientCredentials, err error) {
 	if err := r.ParseForm(); err != nil {
 		return nil, oidc.ErrInvalidRequest().WithDescription("error parsing form").WithParent(err)
 	}
 	cc := new(ClientCredentials)
-	if err = s.decoder.Decode(cc, r.Form); err != nil {
+	if err = decoder.Decode(cc, r.Form); err != nil {
 		return nil, oidc.ErrInvalidRequest().WithDescription("error decoding form").WithParent(err)
 	}
 
@@ -191,9 +218,6 @@ func (s *webServer) parseClientCredentials(r *http.Request) (_ *ClientCredential
 			return nil, oidc.ErrInvalidClient().WithDescription("invalid basic auth header").WithParent(err)
 		}
 	}
-	if cc.ClientID == "" && cc.ClientAssertion == "" {
-		return nil, oidc.ErrInvalidRequest().WithDescription("client_id or client_assertion must be provided")
-	}
 	if cc.ClientAssertion != "" && cc.ClientAssertionType != oidc.ClientAssertionTypeJWTAssertion {
 		return nil, oidc.ErrInvalidRequest().WithDescription("invalid client_assertion_type %s", cc.ClientAssertionType)
 	}
@@ -206,20 +230,51 @@ func (s *webServer) authorizeHandler(w http.ResponseWriter, r *http.Request) {
 		WriteError(w, r, err, nil)
 		return
 	}
+	r = r.WithContext(setAuthRequestUILocale(r.Context(), request, r.Header))
 	redirect, err := s.authorize(r.Context(), newRequest(r, request))
 	if err != nil {
-		WriteError(w, r, err, nil)
+		// the ui_locales of a request object are only known after VerifyAuthRequest
+		r = r.WithContext(setAuthRequestUILocale(r.Context(), request, r.Header))
+		s.writeAuthorizeError(w, r, request, err)
 		return
 	}
 	redirect.writeOut(w, r)
 }
 
+// writeAuthorizeError renders a localized error page for the user,
+// if enabled by the server, see [WithLocalizedErrorPages].
+func (s *webServer) writeAuthorizeError(w http.ResponseWriter, r *http.Request, request *oidc.AuthRequest, err error) {
+	catalog := errorPageCatalogFrom(s.server)
+	if catalog == nil {
+		WriteError(w, r, err, nil)
+		return
+	}
+	var (
+		e          *oidc.Error
+		statusCode = http.StatusBadRequest
+	)
+	var statusError StatusError
+	if errors.As(err, &statusError) {
+		e = oidc.DefaultToServerError(statusError.parent, statusError.parent.Error())
+		statusCode = statusError.statusCode
+	} else {
+		e = oidc.DefaultToServerError(err, err.Error())
+		if e.ErrorType == oidc.ServerError {
+			statusCode = http.StatusInternalServerError
+		}
+	}
+	slog.Log(r.Context(), e.LogLevel(), "request error", slog.Any("oidc_error", e), slog.Int("status_code", statusCode))
+	renderErrorPage(w, r, catalog, request.UILocales, e, statusCode)
+}
+
 func (s *webServer) authorize(ctx context.Context, r *Request[oidc.AuthRequest]) (_ *Redirect, err error) {
 	cr, err := s.server.VerifyAuthRequest(ctx, r)
 	if err != nil {
 		return nil, err
 	}
 	authReq := cr.Data
+	// VerifyAuthRequest may set the ui_locales of the request object, which the context must carry
+	ctx = setAuthRequestUILocale(ctx, authReq, cr.Header)
 	if authReq.RedirectURI == "" {
 		return nil, ErrAuthReqMissingRedirectURI
 	}
@@ -227,7 +282,7 @@ func (s *webServer) authorize(ctx context.Context, r *Request[oidc.AuthRequest])
 	if err != nil {
 		return nil, err
 	}
-	authReq.Scopes, err = ValidateAuthReqScopes(cr.Client, authReq.Scopes)
+	authReq.Scopes, err = validateAuthReqScopes(ctx, cr.Client, authReq.Scopes)
 	if err != nil {
 		return nil, err
 	}
@@ -237,6 +292,9 @@ func (s *webServer) authorize(ctx context.Context, r *Request[oidc.AuthRequest])
 	if err := ValidateAuthReqResponseType(cr.Client, authReq.ResponseType); err != nil {
 		return nil, err
 	}
+	if err := validateAuthRequestClientType(s.server, cr.Client, authReq); err != nil {
+		return nil, err
+	}
 	return s.server.Authorize(ctx, cr)
 }
 
@@ -246,6 +304,11 @@ func (s *webServer) deviceAuthorizationHandler(w http.ResponseWriter, r *http.Re
 		WriteError(w, r, err, nil)
 		return
 	}
+	request.Scopes, err = validateDeviceScopes(r.Context(), client, request.Scopes)
+	if err != nil {
+		WriteError(w, r, err, nil)
+		return
+	}
 	resp, err := s.server.DeviceAuthorization(r.Context(), newClientRequest(r, request, client))
 	if err != nil {
 		WriteError(w, r, err, nil)
@@ -410,12 +473,13 @@ func (s *webServer) deviceTokenHandler(w http.ResponseWriter, r *http.Request, c
 }
 
 func (s *webServer) introspectionHandler(w http.ResponseWriter, r *http.Request) {
-	cc, err := s.parseClientCredentials(r)
+	cc, err := parseClientCredentials(r, s.decoder)
 	if err != nil {
 		WriteError(w, r, err, nil)
 		return
 	}
-	if cc.ClientSecret == "" && cc.ClientAssertion == "" {
+	hasCertificate := r.TLS != nil && len(r.TLS.PeerCertificates) > 0
+	if cc.ClientSecret == "" && cc.ClientAssertion == "" && !hasCertificate {
 		WriteError(w, r, oidc.ErrInvalidClient().WithDescription("client must be authenticated"), nil)
 		return
 	}
@@ -461,6 +525,31 @@ func (s *webServer) userInfoHandler(w http.ResponseWriter, r *http.Request) {
 	resp.writeOut(w)
 }
 
+func (s *webServer) signedKeysHandler(server SignedKeysServer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		keySet, err := server.SignedKeys(r.Context(), newRequest(r, &struct{}{}))
+		if err != nil {
+			WriteError(w, r, err, nil)
+			return
+		}
+		writeSignedKeySet(w, keySet)
+	}
+}
+
+func (s *webServer) webFingerHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := decodeRequest[oidc.WebFingerRequest](s.decoder, r, false)
+	if err != nil {
+		WriteError(w, r, err, nil)
+		return
+	}
+	resp, err := s.server.WebFinger(r.Context(), newRequest(r, request))
+	if err != nil {
+		WriteError(w, r, err, nil)
+		return
+	}
+	resp.writeOut(w)
+}
+
 func (s *webServer) revocationHandler(w http.ResponseWriter, r *http.Request, client Client) {
 	request, err := decodeRequest[oidc.RevocationRequest](s.decoder, r, false)
 	if err != nil {
@@ -480,6 +569,10 @@ func (s *webServer) revocationHandler(w http.ResponseWriter, r *http.Request, cl
 }
 
 func (s *webServer) endSessionHandler(w http.ResponseWriter, r *http.Request) {
+	if err := validateEndSessionMethod(r); err != nil {
+		WriteError(w, r, err, nil)
+		return
+	}
 	request, err := decodeRequest[oidc.EndSessionRequest](s.decoder, r, false)
 	if err != nil {
 		WriteError(w, r, err, nil)
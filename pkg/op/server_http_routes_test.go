@@ -108,6 +108,21 @@ func TestServerRoutes(t *testing.T) {
 			wantCode: http.StatusOK,
 			json:     `{"issuer":"https://localhost:9998/","authorization_endpoint":"https://localhost:9998/authorize","token_endpoint":"https://localhost:9998/oauth/token","introspection_endpoint":"https://localhost:9998/oauth/introspect","userinfo_endpoint":"https://localhost:9998/userinfo","revocation_endpoint":"https://localhost:9998/revoke","end_session_endpoint":"https://localhost:9998/end_session","device_authorization_endpoint":"https://localhost:9998/device_authorization","jwks_uri":"https://localhost:9998/keys","scopes_supported":["openid","profile","email","phone","address","offline_access"],"response_types_supported":["code","id_token","id_token token"],"grant_types_supported":["authorization_code","implicit","refresh_token","client_credentials","urn:ietf:params:oauth:grant-type:token-exchange","urn:ietf:params:oauth:grant-type:jwt-bearer","urn:ietf:params:oauth:grant-type:device_code"],"subject_types_supported":["public"],"id_token_signing_alg_values_supported":["RS256"],"request_object_signing_alg_values_supported":["RS256"],"token_endpoint_auth_methods_supported":["none","client_secret_basic","client_secret_post","private_key_jwt"],"token_endpoint_auth_signing_alg_values_supported":["RS256"],"revocation_endpoint_auth_methods_supported":["none","client_secret_basic","client_secret_post","private_key_jwt"],"revocation_endpoint_auth_signing_alg_values_supported":["RS256"],"introspection_endpoint_auth_methods_supported":["client_secret_basic","private_key_jwt"],"introspection_endpoint_auth_signing_alg_values_supported":["RS256"],"claims_supported":["sub","aud","exp","iat","iss","auth_time","nonce","acr","amr","c_hash","at_hash","act","scopes","client_id","azp","preferred_username","name","family_name","given_name","locale","email","email_verified","phone_number","phone_number_verified"],"code_challenge_methods_supported":["S256"],"ui_locales_supported":["en"],"request_parameter_supported":true,"request_uri_parameter_supported":false}`,
 		},
+		{
+			name:     "webfinger",
+			method:   http.MethodGet,
+			path:     oidc.WebFingerEndpoint,
+			values:   map[string]string{"resource": "acct:joe@localhost", "rel": oidc.WebFingerRelIssuer},
+			wantCode: http.StatusOK,
+			json:     `{"subject":"acct:joe@localhost","links":[{"rel":"http://openid.net/specs/connect/1.0/issuer","href":"https://localhost:9998/"}]}`,
+		},
+		{
+			name:     "webfinger without resource",
+			method:   http.MethodGet,
+			path:     oidc.WebFingerEndpoint,
+			wantCode: http.StatusBadRequest,
+			json:     `{"error":"invalid_request","error_description":"resource missing"}`,
+		},
 		{
 			name:   "authorization",
 			method: http.MethodGet,
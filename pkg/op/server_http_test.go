@@ -20,6 +20,7 @@ import (
 	httphelper "github.com/zitadel/oidc/v3/pkg/http"
 	"github.com/zitadel/oidc/v3/pkg/oidc"
 	"github.com/zitadel/schema"
+	"golang.org/x/text/language"
 )
 
 func TestRegisterServer(t *testing.T) {
@@ -588,6 +589,41 @@ func Test_webServer_authorize(t *testing.T) {
 	}
 }
 
+// requestObjectServer sets the ui_locales of a request object in VerifyAuthRequest
+// and records the ui locale of the context passed to Authorize.
+type requestObjectServer struct {
+	requestVerifier
+	uiLocale language.Tag
+}
+
+func (s *requestObjectServer) VerifyAuthRequest(ctx context.Context, r *Request[oidc.AuthRequest]) (*ClientRequest[oidc.AuthRequest], error) {
+	r.Data.UILocales = oidc.Locales{language.French}
+	return s.requestVerifier.VerifyAuthRequest(ctx, r)
+}
+
+func (s *requestObjectServer) Authorize(ctx context.Context, r *ClientRequest[oidc.AuthRequest]) (*Redirect, error) {
+	s.uiLocale, _ = UILocaleFromContext(ctx)
+	return NewRedirect(r.Data.RedirectURI), nil
+}
+
+func Test_webServer_authorize_uiLocale(t *testing.T) {
+	server := &requestObjectServer{requestVerifier: requestVerifier{client: newClient(clientTypeWeb)}}
+	s := &webServer{server: server, decoder: testDecoder}
+	ctx := context.WithValue(context.Background(), uiLocaleMatcherKey{}, newUILocaleMatcher([]language.Tag{language.English, language.French}))
+	ctx = ContextWithUILocale(ctx, language.English)
+	_, err := s.authorize(ctx, &Request[oidc.AuthRequest]{
+		Header: http.Header{"Accept-Language": []string{"en"}},
+		Data: &oidc.AuthRequest{
+			Scopes:       oidc.SpaceDelimitedArray{"openid"},
+			ResponseType: oidc.ResponseTypeCode,
+			ClientID:     "web",
+			RedirectURI:  "https://registered.com/callback",
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, language.French, server.uiLocale)
+}
+
 func Test_webServer_deviceAuthorizationHandler(t *testing.T) {
 	type fields struct {
 		server  Server
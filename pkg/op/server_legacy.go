@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op/i18n"
 )
 
 // ExtendedLegacyServer allows embedding [LegacyServer] in a struct,
@@ -28,8 +30,18 @@ type ExtendedLegacyServer interface {
 //
 // EXPERIMENTAL: may change until v4
 func RegisterLegacyServer(s ExtendedLegacyServer, authorizeCallbackHandler http.HandlerFunc, options ...ServerOption) http.Handler {
+	if so, ok := s.Provider().(securityHeadersOptioner); ok {
+		// the provider's headers are the default, which the options may override
+		options = append([]ServerOption{WithServerSecurityHeaders(so.SecurityHeaders())}, options...)
+	}
 	options = append(options,
 		WithHTTPMiddleware(intercept(s.Provider().IssuerFromRequest)),
+		WithHTTPMiddleware(storageTimeoutInterceptor(s.Provider())),
+		WithHTTPMiddleware(keyIDInterceptor(s.Provider())),
+		WithHTTPMiddleware(opaqueTokenFormatInterceptor(s.Provider())),
+		WithHTTPMiddleware(clockInterceptor(s.Provider())),
+		WithHTTPMiddleware(uiLocaleInterceptor(s.Provider())),
+		WithHTTPMiddleware(scopeRegistryInterceptor(s.Provider())),
 		WithSetRouter(func(r chi.Router) {
 			r.HandleFunc(s.Endpoints().Authorization.Relative()+authCallbackPathSuffix, authorizeCallbackHandler)
 		}),
@@ -75,6 +87,18 @@ func (s *LegacyServer) Endpoints() Endpoints {
 	return s.endpoints
 }
 
+// ErrorPageCatalog returns the catalog of the localized error pages of the provider,
+// see [WithLocalizedErrorPages].
+func (s *LegacyServer) ErrorPageCatalog() *i18n.Catalog {
+	return errorPageCatalogFrom(s.provider)
+}
+
+// ClientTypePolicy returns the policy of the client enforced by the provider,
+// see [WithClientTypePolicies].
+func (s *LegacyServer) ClientTypePolicy(client Client) (ClientTypePolicy, bool) {
+	return clientTypePolicyFrom(s.provider, client)
+}
+
 // AuthCallbackURL builds the url for the redirect (with the requestID) after a successful login
 func (s *LegacyServer) AuthCallbackURL() func(context.Context, string) string {
 	return func(ctx context.Context, requestID string) string {
@@ -104,7 +128,9 @@ func (s *LegacyServer) Discovery(ctx context.Context, r *Request[struct{}]) (*Re
 	defer span.End()
 
 	return NewResponse(
-		createDiscoveryConfigV2(ctx, s.provider, s.provider.Storage(), &s.endpoints),
+		cacheFrom(s.provider).discovery(ctx, func() *oidc.DiscoveryConfiguration {
+			return createDiscoveryConfigV2(ctx, s.provider, s.provider.Storage(), &s.endpoints)
+		}),
 	), nil
 }
 
@@ -112,11 +138,43 @@ func (s *LegacyServer) Keys(ctx context.Context, r *Request[struct{}]) (*Respons
 	ctx, span := Tracer.Start(ctx, "LegacyServer.Keys")
 	defer span.End()
 
-	keys, err := s.provider.Storage().KeySet(ctx)
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	keys, err := s.provider.Storage().KeySet(storageCtx)
 	if err != nil {
 		return nil, AsStatusError(err, http.StatusInternalServerError)
 	}
-	return NewResponse(jsonWebKeySet(keys)), nil
+	policy := keySetPolicyFrom(s.provider)
+	keySet, err := publishedKeySet(ctx, keys, policy)
+	if err != nil {
+		return nil, AsStatusError(err, http.StatusInternalServerError)
+	}
+	resp := NewResponse(keySet)
+	resp.Header = make(http.Header)
+	policy.setCacheControl(resp.Header)
+	return resp, nil
+}
+
+func (s *LegacyServer) SignedKeys(ctx context.Context, r *Request[struct{}]) (string, error) {
+	ctx, span := Tracer.Start(ctx, "LegacyServer.SignedKeys")
+	defer span.End()
+
+	keySet, err := signKeySet(ctx, s.provider.Storage(), keySetPolicyFrom(s.provider))
+	if err != nil {
+		return "", AsStatusError(err, http.StatusInternalServerError)
+	}
+	return keySet, nil
+}
+
+func (s *LegacyServer) WebFinger(ctx context.Context, r *Request[oidc.WebFingerRequest]) (*Response, error) {
+	ctx, span := Tracer.Start(ctx, "LegacyServer.WebFinger")
+	defer span.End()
+
+	resp, err := WebFinger(ctx, webFingerProviderFrom(s.provider), r.Data)
+	if err != nil {
+		return nil, err
+	}
+	return NewResponse(resp), nil
 }
 
 const authReqMissingClientID = "auth request is missing client_id"
@@ -134,15 +192,17 @@ func (s *LegacyServer) VerifyAuthRequest(ctx context.Context, r *Request[oidc.Au
 		if !s.provider.RequestObjectSupported() {
 			return nil, oidc.ErrRequestNotSupported()
 		}
-		err := ParseRequestObject(ctx, r.Data, s.provider.Storage(), IssuerFromContext(ctx))
+		err := parseRequestObject(ctx, r.Data, s.provider.Storage(), IssuerFromContext(ctx), clientKeySetCacheFrom(s.provider), requestObjectSigningAlgorithms(s.provider))
 		if err != nil {
 			return nil, err
 		}
 	}
+	setAuthRequestHints(r.Data, r.Form, hintParametersFrom(s.provider))
+	ctx = setAuthRequestUILocale(ctx, r.Data, r.Header)
 	if r.Data.ClientID == "" {
 		return nil, oidc.ErrInvalidRequest().WithParent(ErrAuthReqMissingClientID).WithDescription(authReqMissingClientID)
 	}
-	client, err := s.provider.Storage().GetClientByClientID(ctx, r.Data.ClientID)
+	client, err := getClientByClientID(ctx, cacheFrom(s.provider), s.provider.Storage(), r.Data.ClientID)
 	if err != nil {
 		return nil, oidc.DefaultToServerError(err, "unable to retrieve client by id")
 	}
@@ -161,7 +221,23 @@ func (s *LegacyServer) Authorize(ctx context.Context, r *ClientRequest[oidc.Auth
 	if err != nil {
 		return nil, err
 	}
-	req, err := s.provider.Storage().CreateAuthRequest(ctx, r.Data, userID)
+	if silentStorage, ok := s.provider.Storage().(SilentAuthStorage); ok && isPromptNone(r.Data.Prompt) {
+		req, err := silentAuthRequest(ctx, s.provider, silentStorage, r.Header, r.Data, userID)
+		if err != nil {
+			return TryErrorRedirect(ctx, r.Data, err, s.provider.Encoder(), nil)
+		}
+		return NewRedirect(authorizeCallbackURL(ctx, s.Endpoints().Authorization, req.GetID())), nil
+	}
+	if stateless, ok := statelessAuthRequesterFrom(s.provider); ok {
+		id, err := createStatelessAuthRequest(stateless, r.Data, userID)
+		if err != nil {
+			return TryErrorRedirect(ctx, r.Data, oidc.DefaultToServerError(err, "unable to create auth request"), s.provider.Encoder(), nil)
+		}
+		return NewRedirect(r.Client.LoginURL(id)), nil
+	}
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	req, err := s.provider.Storage().CreateAuthRequest(storageCtx, r.Data, userID)
 	if err != nil {
 		return TryErrorRedirect(ctx, r.Data, oidc.DefaultToServerError(err, "unable to save auth request"), s.provider.Encoder(), nil)
 	}
@@ -188,38 +264,12 @@ func (s *LegacyServer) VerifyClient(ctx context.Context, r *Request[ClientCreden
 		if !ok {
 			return nil, oidc.ErrUnsupportedGrantType().WithDescription("client_credentials grant not supported")
 		}
-		return storage.ClientCredentials(ctx, r.Data.ClientID, r.Data.ClientSecret)
+		storageCtx, cancel := storageContext(ctx)
+		defer cancel()
+		return storage.ClientCredentials(storageCtx, r.Data.ClientID, r.Data.ClientSecret)
 	}
 
-	if r.Data.ClientAssertionType == oidc.ClientAssertionTypeJWTAssertion {
-		jwtExchanger, ok := s.provider.(JWTAuthorizationGrantExchanger)
-		if !ok || !s.provider.AuthMethodPrivateKeyJWTSupported() {
-			return nil, oidc.ErrInvalidClient().WithDescription("auth_method private_key_jwt not supported")
-		}
-		return AuthorizePrivateJWTKey(ctx, r.Data.ClientAssertion, jwtExchanger)
-	}
-	client, err := s.provider.Storage().GetClientByClientID(ctx, r.Data.ClientID)
-	if err != nil {
-		return nil, oidc.ErrInvalidClient().WithParent(err)
-	}
-
-	switch client.AuthMethod() {
-	case oidc.AuthMethodNone:
-		return client, nil
-	case oidc.AuthMethodPrivateKeyJWT:
-		return nil, oidc.ErrInvalidClient().WithDescription("private_key_jwt not allowed for this client")
-	case oidc.AuthMethodPost:
-		if !s.provider.AuthMethodPostSupported() {
-			return nil, oidc.ErrInvalidClient().WithDescription("auth_method post not supported")
-		}
-	}
-
-	err = AuthorizeClientIDSecret(ctx, r.Data.ClientID, r.Data.ClientSecret, s.provider.Storage())
-	if err != nil {
-		return nil, err
-	}
-
-	return client, nil
+	return AuthenticateClient(ctx, r, clientAuthenticatorsOf(s.provider)...)
 }
 
 func (s *LegacyServer) CodeExchange(ctx context.Context, r *ClientRequest[oidc.AccessTokenRequest]) (*Response, error) {
@@ -230,6 +280,9 @@ func (s *LegacyServer) CodeExchange(ctx context.Context, r *ClientRequest[oidc.A
 	if err != nil {
 		return nil, err
 	}
+	if r.Client.AuthMethod() == oidc.AuthMethodNone && authReq.GetCodeChallenge() == nil {
+		return nil, oidc.ErrInvalidRequest().WithDescription("PKCE required")
+	}
 	if r.Client.AuthMethod() == oidc.AuthMethodNone || r.Data.CodeVerifier != "" {
 		if err = AuthorizeCodeChallenge(r.Data.CodeVerifier, authReq.GetCodeChallenge()); err != nil {
 			return nil, err
@@ -241,6 +294,9 @@ func (s *LegacyServer) CodeExchange(ctx context.Context, r *ClientRequest[oidc.A
 	if r.Data.RedirectURI != authReq.GetRedirectURI() {
 		return nil, oidc.ErrInvalidGrant().WithDescription("redirect_uri does not correspond")
 	}
+	if err = checkGrantPolicies(ctx, s.provider, newGrantRequestFrom(r, oidc.GrantTypeCode, authReq)); err != nil {
+		return nil, err
+	}
 	resp, err := CreateTokenResponse(ctx, authReq, r.Client, s.provider, true, r.Data.Code, "")
 	if err != nil {
 		return nil, err
@@ -255,6 +311,7 @@ func (s *LegacyServer) RefreshToken(ctx context.Context, r *ClientRequest[oidc.R
 	if !s.provider.GrantTypeRefreshTokenSupported() {
 		return nil, unimplementedGrantError(oidc.GrantTypeRefreshToken)
 	}
+	r.Data.RefreshToken = parseRefreshToken(s.provider, r.Data.RefreshToken)
 	request, err := RefreshTokenRequestByRefreshToken(ctx, s.provider.Storage(), r.Data.RefreshToken)
 	if err != nil {
 		return nil, err
@@ -265,6 +322,12 @@ func (s *LegacyServer) RefreshToken(ctx context.Context, r *ClientRequest[oidc.R
 	if err = ValidateRefreshTokenScopes(r.Data.Scopes, request); err != nil {
 		return nil, err
 	}
+	if err = ValidateRefreshTokenAudience(slices.Concat(r.Data.Resource, r.Data.Audience), request); err != nil {
+		return nil, err
+	}
+	if err = checkGrantPolicies(ctx, s.provider, newGrantRequestFrom(r, oidc.GrantTypeRefreshToken, request)); err != nil {
+		return nil, err
+	}
 	resp, err := CreateTokenResponse(ctx, request, r.Client, s.provider, true, "", r.Data.RefreshToken)
 	if err != nil {
 		return nil, err
@@ -285,7 +348,9 @@ func (s *LegacyServer) JWTProfile(ctx context.Context, r *Request[oidc.JWTProfil
 		return nil, oidc.ErrInvalidRequest().WithParent(err).WithDescription("assertion invalid")
 	}
 
-	tokenRequest.Scopes, err = exchanger.Storage().ValidateJWTProfileScopes(ctx, tokenRequest.Issuer, r.Data.Scope)
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	tokenRequest.Scopes, err = exchanger.Storage().ValidateJWTProfileScopes(storageCtx, tokenRequest.Issuer, r.Data.Scope)
 	if err != nil {
 		return nil, err
 	}
@@ -322,7 +387,9 @@ func (s *LegacyServer) ClientCredentialsExchange(ctx context.Context, r *ClientR
 	if !ok {
 		return nil, unimplementedGrantError(oidc.GrantTypeClientCredentials)
 	}
-	tokenRequest, err := storage.ClientCredentialsTokenRequest(ctx, r.Client.GetID(), r.Data.Scope)
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	tokenRequest, err := storage.ClientCredentialsTokenRequest(storageCtx, r.Client.GetID(), r.Data.Scope)
 	if err != nil {
 		return nil, err
 	}
@@ -356,41 +423,40 @@ func (s *LegacyServer) DeviceToken(ctx context.Context, r *ClientRequest[oidc.De
 	return NewResponse(resp), nil
 }
 
-func (s *LegacyServer) authenticateResourceClient(ctx context.Context, cc *ClientCredentials) (string, error) {
+// authenticateResourceClient authenticates the resource server with the authenticators
+// of the provider, see [WithClientAuthenticators]. Public clients are not allowed.
+func (s *LegacyServer) authenticateResourceClient(ctx context.Context, r *Request[IntrospectionRequest]) (string, error) {
 	ctx, span := Tracer.Start(ctx, "LegacyServer.authenticateResourceClient")
 	defer span.End()
 
-	if cc.ClientAssertion != "" {
-		if jp, ok := s.provider.(ClientJWTProfile); ok {
-			return ClientJWTAuth(ctx, oidc.ClientAssertionParams{ClientAssertion: cc.ClientAssertion}, jp)
-		}
-		return "", oidc.ErrInvalidClient().WithDescription("client_assertion not supported")
+	client, method, err := authenticateClient(ctx, &Request[ClientCredentials]{
+		Method:     r.Method,
+		URL:        r.URL,
+		Header:     r.Header,
+		Form:       r.Form,
+		PostForm:   r.PostForm,
+		TLS:        r.TLS,
+		RemoteAddr: r.RemoteAddr,
+		Data:       r.Data.ClientCredentials,
+	}, clientAuthenticatorsOf(s.provider)...)
+	if err != nil {
+		return "", err
 	}
-	if err := s.provider.Storage().AuthorizeClientIDSecret(ctx, cc.ClientID, cc.ClientSecret); err != nil {
-		return "", oidc.ErrUnauthorizedClient().WithParent(err)
+	if method == oidc.AuthMethodNone {
+		return "", oidc.ErrInvalidClient().WithDescription("client must be authenticated")
 	}
-	return cc.ClientID, nil
+	return client.GetID(), nil
 }
 
 func (s *LegacyServer) Introspect(ctx context.Context, r *Request[IntrospectionRequest]) (*Response, error) {
 	ctx, span := Tracer.Start(ctx, "LegacyServer.Introspect")
 	defer span.End()
 
-	clientID, err := s.authenticateResourceClient(ctx, r.Data.ClientCredentials)
+	clientID, err := s.authenticateResourceClient(ctx, r)
 	if err != nil {
 		return nil, err
 	}
-	response := new(oidc.IntrospectionResponse)
-	tokenID, subject, ok := getTokenIDAndSubject(ctx, s.provider, r.Data.Token)
-	if !ok {
-		return NewResponse(response), nil
-	}
-	err = s.provider.Storage().SetIntrospectionFromToken(ctx, response, tokenID, subject, clientID)
-	if err != nil {
-		return NewResponse(response), nil
-	}
-	response.Active = true
-	return NewResponse(response), nil
+	return NewResponse(introspectToken(ctx, s.provider, r.Data.Token, clientID)), nil
 }
 
 func (s *LegacyServer) UserInfo(ctx context.Context, r *Request[oidc.UserInfoRequest]) (*Response, error) {
@@ -402,7 +468,9 @@ func (s *LegacyServer) UserInfo(ctx context.Context, r *Request[oidc.UserInfoReq
 		return nil, NewStatusError(oidc.ErrAccessDenied().WithDescription("access token invalid"), http.StatusUnauthorized)
 	}
 	info := new(oidc.UserInfo)
-	err := s.provider.Storage().SetUserinfoFromToken(ctx, info, tokenID, subject, r.Header.Get("origin"))
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	err := s.provider.Storage().SetUserinfoFromToken(storageCtx, info, tokenID, subject, r.Header.Get("origin"))
 	if err != nil {
 		return nil, NewStatusError(err, http.StatusForbidden)
 	}
@@ -413,29 +481,7 @@ func (s *LegacyServer) Revocation(ctx context.Context, r *ClientRequest[oidc.Rev
 	ctx, span := Tracer.Start(ctx, "LegacyServer.Revocation")
 	defer span.End()
 
-	var subject string
-	doDecrypt := true
-	if r.Data.TokenTypeHint != "access_token" {
-		userID, tokenID, err := s.provider.Storage().GetRefreshTokenInfo(ctx, r.Client.GetID(), r.Data.Token)
-		if err != nil {
-			// An invalid refresh token means that we'll try other things (leaving doDecrypt==true)
-			if !errors.Is(err, ErrInvalidRefreshToken) {
-				return nil, RevocationError(oidc.ErrServerError().WithParent(err))
-			}
-		} else {
-			r.Data.Token = tokenID
-			subject = userID
-			doDecrypt = false
-		}
-	}
-	if doDecrypt {
-		tokenID, userID, ok := getTokenIDAndSubjectForRevocation(ctx, s.provider, r.Data.Token)
-		if ok {
-			r.Data.Token = tokenID
-			subject = userID
-		}
-	}
-	if err := s.provider.Storage().RevokeToken(ctx, r.Data.Token, subject, r.Client.GetID()); err != nil {
+	if err := revokeToken(ctx, s.provider, r.Data.Token, r.Data.TokenTypeHint, r.Client.GetID()); err != nil {
 		return nil, RevocationError(err)
 	}
 	return NewResponse(nil), nil
@@ -449,14 +495,14 @@ func (s *LegacyServer) EndSession(ctx context.Context, r *Request[oidc.EndSessio
 	if err != nil {
 		return nil, err
 	}
-	redirect := session.RedirectURI
-	if fromRequest, ok := s.provider.Storage().(CanTerminateSessionFromRequest); ok {
-		redirect, err = fromRequest.TerminateSessionFromRequest(ctx, session)
-	} else {
-		err = s.provider.Storage().TerminateSession(ctx, session.UserID, session.ClientID)
-	}
+	terminated, err := terminateSession(ctx, session, s.provider, s.provider.Storage())
 	if err != nil {
 		return nil, err
 	}
-	return NewRedirect(redirect), nil
+	resp := NewRedirect(terminated.redirect)
+	resp.FrontChannelLogoutURIs = terminated.frontChannelURIs
+	if cookie := terminated.sessionCookie(ctx, r.TLS != nil, s.provider); cookie != nil {
+		resp.Header.Add("Set-Cookie", cookie.String())
+	}
+	return resp, nil
 }
@@ -32,6 +32,10 @@ func EndSession(w http.ResponseWriter, r *http.Request, ender SessionEnder) {
 	defer span.End()
 	r = r.WithContext(ctx)
 
+	if err := validateEndSessionMethod(r); err != nil {
+		RequestError(w, r, err, nil)
+		return
+	}
 	req, err := ParseEndSessionRequest(r, ender.Decoder())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -42,17 +46,96 @@ func EndSession(w http.ResponseWriter, r *http.Request, ender SessionEnder) {
 		RequestError(w, r, err, nil)
 		return
 	}
-	redirect := session.RedirectURI
-	if fromRequest, ok := ender.Storage().(CanTerminateSessionFromRequest); ok {
-		redirect, err = fromRequest.TerminateSessionFromRequest(r.Context(), session)
-	} else {
-		err = ender.Storage().TerminateSession(r.Context(), session.UserID, session.ClientID)
-	}
+	terminated, err := terminateSession(r.Context(), session, ender, ender.Storage())
 	if err != nil {
 		RequestError(w, r, oidc.DefaultToServerError(err, "error terminating session"), nil)
 		return
 	}
-	http.Redirect(w, r, redirect, http.StatusFound)
+	if cookie := terminated.sessionCookie(r.Context(), r.TLS != nil, ender); cookie != nil {
+		http.SetCookie(w, cookie)
+	}
+	if len(terminated.frontChannelURIs) > 0 {
+		if err := RenderFrontChannelLogout(w, terminated.frontChannelURIs, terminated.redirect); err != nil {
+			slog.ErrorContext(r.Context(), "front-channel logout: rendering page failed", "error", err)
+		}
+		return
+	}
+	http.Redirect(w, r, terminated.redirect, http.StatusFound)
+}
+
+// validateEndSessionMethod makes sure the end_session endpoint is only called
+// with GET or a POST form submission, as defined by
+// https://openid.net/specs/openid-connect-rpinitiated-1_0.html#RPLogout
+func validateEndSessionMethod(r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet, http.MethodPost:
+		return nil
+	default:
+		return oidc.ErrInvalidRequest().WithDescription("%s not supported on end_session endpoint, use GET or POST", r.Method)
+	}
+}
+
+// sessionTermination is the result of [terminateSession].
+type sessionTermination struct {
+	// redirect is the uri the user agent must be redirected to.
+	redirect string
+	// frontChannelURIs must be loaded by the user agent before the redirect,
+	// see [RenderFrontChannelLogout].
+	frontChannelURIs []string
+	// terminated is false if the user agent is sent to the logout confirmation.
+	terminated bool
+}
+
+// sessionCookie returns the expired cookie of [WithSessionCookie]
+// if the session was terminated, or nil.
+func (t *sessionTermination) sessionCookie(ctx context.Context, tls bool, provider SessionEnder) *http.Cookie {
+	if !t.terminated {
+		return nil
+	}
+	return expiredSessionCookie(ctx, tls, provider)
+}
+
+// terminateSession ends the session described by the validated request.
+// When the request could not be bound to a user's session through a valid id_token_hint
+// and the storage implements [CanConfirmLogout], the user agent is sent to the
+// confirmation page instead and the session is left untouched.
+// Otherwise the clients of the session are notified by back-channel logout, if supported,
+// and an [EventSessionRevoked] is published.
+func terminateSession(ctx context.Context, session *EndSessionRequest, provider SessionEnder, storage Storage) (*sessionTermination, error) {
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	if confirmer, ok := storage.(CanConfirmLogout); ok && session.IDTokenHintClaims == nil {
+		confirmURI, err := confirmer.LogoutConfirmationURI(storageCtx, session)
+		if err != nil {
+			return nil, err
+		}
+		if confirmURI != "" {
+			return &sessionTermination{redirect: confirmURI}, nil
+		}
+	}
+	sessions := listLogoutSessions(ctx, session, storage)
+	var (
+		redirect string
+		err      error
+	)
+	if fromRequest, ok := storage.(CanTerminateSessionFromRequest); ok {
+		redirect, err = fromRequest.TerminateSessionFromRequest(storageCtx, session)
+	} else {
+		redirect, err = session.RedirectURI, storage.TerminateSession(storageCtx, session.UserID, session.ClientID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sendBackChannelLogout(ctx, provider, storage, session.UserID, sessions)
+	if len(sessions) == 0 {
+		sessions = []ClientSession{{ClientID: session.ClientID, SessionID: session.SessionID}}
+	}
+	publishSessionRevoked(ctx, eventPublisherFrom(provider), session.UserID, sessions)
+	return &sessionTermination{
+		redirect:         redirect,
+		frontChannelURIs: frontChannelLogoutURIs(ctx, provider, storage, sessions),
+		terminated:       true,
+	}, nil
 }
 
 func ParseEndSessionRequest(r *http.Request, decoder httphelper.Decoder) (*oidc.EndSessionRequest, error) {
@@ -84,13 +167,17 @@ func ValidateEndSessionRequest(ctx context.Context, req *oidc.EndSessionRequest,
 		}
 		session.UserID = claims.GetSubject()
 		session.IDTokenHintClaims = claims
+		session.SessionID = claims.SessionID
 		if req.ClientID != "" && req.ClientID != claims.GetAuthorizedParty() {
 			return nil, oidc.ErrInvalidRequest().WithDescription("client_id does not match azp of id_token_hint")
 		}
 		req.ClientID = claims.GetAuthorizedParty()
 	}
+	if req.ClientID == "" && req.PostLogoutRedirectURI != "" {
+		return nil, oidc.ErrInvalidRequest().WithDescription("post_logout_redirect_uri requires client_id or id_token_hint")
+	}
 	if req.ClientID != "" {
-		client, err := ender.Storage().GetClientByClientID(ctx, req.ClientID)
+		client, err := getClientByClientID(ctx, cacheFrom(ender), ender.Storage(), req.ClientID)
 		if err != nil {
 			return nil, oidc.DefaultToServerError(err, "")
 		}
@@ -100,14 +187,17 @@ func ValidateEndSessionRequest(ctx context.Context, req *oidc.EndSessionRequest,
 				return nil, err
 			}
 			session.RedirectURI = req.PostLogoutRedirectURI
+			session.State = req.State
 		}
 	}
-	if req.State != "" {
+	// state is only relayed to a post_logout_redirect_uri registered by the client,
+	// never to the default logout page of the OP.
+	if session.State != "" {
 		redirect, err := url.Parse(session.RedirectURI)
 		if err != nil {
 			return nil, oidc.DefaultToServerError(err, "")
 		}
-		session.RedirectURI = mergeQueryParams(redirect, url.Values{"state": {req.State}})
+		session.RedirectURI = mergeQueryParams(redirect, url.Values{"state": {session.State}})
 	}
 	return session, nil
 }
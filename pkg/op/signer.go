@@ -1,9 +1,16 @@
 package op
 
 import (
+	"bytes"
+	"context"
+	gocrypto "crypto"
 	"errors"
+	"reflect"
+	"sync"
 
 	jose "github.com/go-jose/go-jose/v4"
+
+	"github.com/zitadel/oidc/v3/pkg/crypto"
 )
 
 var ErrSignerCreationFailed = errors.New("signer creation failed")
@@ -14,12 +21,28 @@ type SigningKey interface {
 	ID() string
 }
 
+// SignerFromKey returns a signer of the key.
+// A key without ID is identified by [ThumbprintKeyID].
+//
+// Deprecated: use [SignerFromKeyContext] with the context of the request,
+// which carries the function of [WithKeyIDFunc].
 func SignerFromKey(key SigningKey) (jose.Signer, error) {
+	return SignerFromKeyContext(context.Background(), key)
+}
+
+// SignerFromKeyContext returns a signer of the key.
+// A key without ID is identified by the function of [WithKeyIDFunc]
+// passed in the context of the request, or [ThumbprintKeyID].
+func SignerFromKeyContext(ctx context.Context, key SigningKey) (jose.Signer, error) {
+	kid, err := keyID(ctx, key.ID(), key.Key())
+	if err != nil {
+		return nil, ErrSignerCreationFailed
+	}
 	signer, err := jose.NewSigner(jose.SigningKey{
 		Algorithm: key.SignatureAlgorithm(),
 		Key: &jose.JSONWebKey{
 			Key:   key.Key(),
-			KeyID: key.ID(),
+			KeyID: kid,
 		},
 	}, (&jose.SignerOptions{}).WithType("JWT"))
 	if err != nil {
@@ -28,6 +51,118 @@ func SignerFromKey(key SigningKey) (jose.Signer, error) {
 	return signer, nil
 }
 
+// maxCachedSigners limits the signers kept by the [signerCache],
+// which only grows by key rotation.
+const maxCachedSigners = 32
+
+type signerCacheKey struct {
+	id  string
+	alg jose.SignatureAlgorithm
+	typ string
+}
+
+type cachedSigner struct {
+	key    any
+	signer *crypto.CompactSigner
+}
+
+// signerCache reuses the signers of the signing keys, which are
+// identified by their ID, algorithm and key, so the go-jose signer
+// is not created on every token issued.
+// Each [Provider] has its own signerCache.
+// A nil signerCache creates a new signer on every call.
+type signerCache struct {
+	mu      sync.RWMutex
+	signers map[signerCacheKey]cachedSigner
+}
+
+func newSignerCache() *signerCache {
+	return &signerCache{signers: make(map[signerCacheKey]cachedSigner)}
+}
+
+// signerCacheProvider is implemented by the [Provider]
+// to pass its signers to the handlers.
+type signerCacheProvider interface {
+	signerCache() *signerCache
+}
+
+func signerCacheFrom(v any) *signerCache {
+	if p, ok := v.(signerCacheProvider); ok {
+		return p.signerCache()
+	}
+	return nil
+}
+
+func (c *signerCache) signer(ctx context.Context, key SigningKey, typ string) (*crypto.CompactSigner, error) {
+	privateKey := key.Key()
+	kid, err := keyID(ctx, key.ID(), privateKey)
+	if err != nil {
+		return nil, ErrSignerCreationFailed
+	}
+	cacheKey := signerCacheKey{id: kid, alg: key.SignatureAlgorithm(), typ: typ}
+	if c == nil {
+		signer, err := crypto.NewCompactSigner(cacheKey.alg, privateKey, cacheKey.id, typ)
+		if err != nil {
+			return nil, ErrSignerCreationFailed
+		}
+		return signer, nil
+	}
+
+	c.mu.RLock()
+	cached, ok := c.signers[cacheKey]
+	c.mu.RUnlock()
+	if ok && equalKeys(cached.key, privateKey) {
+		return cached.signer, nil
+	}
+	signer, err := crypto.NewCompactSigner(cacheKey.alg, privateKey, cacheKey.id, typ)
+	if err != nil {
+		return nil, ErrSignerCreationFailed
+	}
+	c.mu.Lock()
+	if len(c.signers) >= maxCachedSigners {
+		clear(c.signers)
+	}
+	c.signers[cacheKey] = cachedSigner{key: privateKey, signer: signer}
+	c.mu.Unlock()
+	return signer, nil
+}
+
+// equalPrivateKey is implemented by the rsa, ecdsa and ed25519 private keys.
+type equalPrivateKey interface {
+	Equal(x gocrypto.PrivateKey) bool
+}
+
+// equalKeys reports whether the cached key is the key of the signer.
+// Storages may return a new copy of the same key on every call,
+// so rsa, ecdsa and ed25519 keys are compared by value.
+func equalKeys(cached, key any) bool {
+	switch k := key.(type) {
+	case equalPrivateKey:
+		return k.Equal(cached)
+	case []byte:
+		c, ok := cached.([]byte)
+		return ok && bytes.Equal(k, c)
+	}
+	if key == nil || !reflect.TypeOf(key).Comparable() {
+		return false
+	}
+	return cached == key
+}
+
+// signClaims marshals the claims once and signs them with the signer of the key from the cache,
+// setting the typ header, e.g. [oidc.TypeAccessToken] for JWT access tokens.
+// Nothing is signed if the context is already done, e.g. because the client disconnected.
+func signClaims(ctx context.Context, signers *signerCache, claims any, key SigningKey, typ string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	signer, err := signers.signer(ctx, key, typ)
+	if err != nil {
+		return "", err
+	}
+	return signer.SignObject(claims)
+}
+
 type Key interface {
 	ID() string
 	Algorithm() jose.SignatureAlgorithm
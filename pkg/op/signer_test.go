@@ -0,0 +1,95 @@
+package op
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+type cacheSigningKey struct {
+	id  string
+	alg jose.SignatureAlgorithm
+	key any
+}
+
+func (k cacheSigningKey) SignatureAlgorithm() jose.SignatureAlgorithm { return k.alg }
+func (k cacheSigningKey) Key() any                                    { return k.key }
+func (k cacheSigningKey) ID() string                                  { return k.id }
+
+func Test_signerCache(t *testing.T) {
+	cache := newSignerCache()
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	first, err := cache.signer(context.Background(), cacheSigningKey{"ec", jose.ES256, ecKey}, oidc.TypeJWT)
+	require.NoError(t, err)
+	second, err := cache.signer(context.Background(), cacheSigningKey{"ec", jose.ES256, ecKey}, oidc.TypeJWT)
+	require.NoError(t, err)
+	assert.Same(t, first, second, "same key")
+
+	rotatedKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rotated, err := cache.signer(context.Background(), cacheSigningKey{"ec", jose.ES256, rotatedKey}, oidc.TypeJWT)
+	require.NoError(t, err)
+	assert.NotSame(t, first, rotated, "key with the same ID replaced")
+
+	first, err = cache.signer(context.Background(), cacheSigningKey{"ed", jose.EdDSA, edKey}, oidc.TypeJWT)
+	require.NoError(t, err)
+	second, err = cache.signer(context.Background(), cacheSigningKey{"ed", jose.EdDSA, append(ed25519.PrivateKey(nil), edKey...)}, oidc.TypeJWT)
+	require.NoError(t, err)
+	assert.Same(t, first, second, "copied ed25519 key")
+
+	first, err = cache.signer(context.Background(), cacheSigningKey{"ec", jose.ES256, ecKey}, oidc.TypeJWT)
+	require.NoError(t, err)
+	copiedKey := *ecKey
+	second, err = cache.signer(context.Background(), cacheSigningKey{"ec", jose.ES256, &copiedKey}, oidc.TypeJWT)
+	require.NoError(t, err)
+	assert.Same(t, first, second, "copied ecdsa key")
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	first, err = cache.signer(context.Background(), cacheSigningKey{"rsa", jose.RS256, rsaKey}, oidc.TypeJWT)
+	require.NoError(t, err)
+	copiedRSAKey := *rsaKey
+	second, err = cache.signer(context.Background(), cacheSigningKey{"rsa", jose.RS256, &copiedRSAKey}, oidc.TypeJWT)
+	require.NoError(t, err)
+	assert.Same(t, first, second, "copied rsa key")
+	assert.False(t, equalKeys(ecKey, rsaKey), "different key types")
+
+	_, err = cache.signer(context.Background(), cacheSigningKey{"none", jose.RS256, nil}, oidc.TypeJWT)
+	assert.ErrorIs(t, err, ErrSignerCreationFailed)
+
+	var uncached *signerCache
+	first, err = uncached.signer(context.Background(), cacheSigningKey{"ec", jose.ES256, ecKey}, oidc.TypeJWT)
+	require.NoError(t, err)
+	second, err = uncached.signer(context.Background(), cacheSigningKey{"ec", jose.ES256, ecKey}, oidc.TypeJWT)
+	require.NoError(t, err)
+	assert.NotSame(t, first, second, "nil cache")
+}
+
+func TestSignerFromKeyContext(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ctx := ContextWithKeyIDFunc(context.Background(), func(any) (string, error) { return "custom", nil })
+	signer, err := SignerFromKeyContext(ctx, cacheSigningKey{alg: jose.ES256, key: ecKey})
+	require.NoError(t, err)
+	jws, err := signer.Sign([]byte("payload"))
+	require.NoError(t, err)
+	compact, err := jws.CompactSerialize()
+	require.NoError(t, err)
+	parsed, err := jose.ParseSigned(compact, []jose.SignatureAlgorithm{jose.ES256})
+	require.NoError(t, err)
+	assert.Equal(t, "custom", parsed.Signatures[0].Header.KeyID)
+}
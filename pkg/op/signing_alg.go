@@ -0,0 +1,102 @@
+package op
+
+import (
+	"context"
+	"slices"
+
+	jose "github.com/go-jose/go-jose/v4"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// HasIDTokenSignedResponseAlg is an optional interface of a [Client]
+// registered with the id_token_signed_response_alg metadata.
+// ID tokens of the client are signed with the key of the algorithm,
+// which must be one of the SignatureAlgorithms of the Storage
+// and requires the Storage to implement [CanGetSigningKeyByAlgorithm],
+// unless the algorithm matches the default signing key.
+// An empty algorithm uses the default signing key.
+type HasIDTokenSignedResponseAlg interface {
+	IDTokenSignedResponseAlg() jose.SignatureAlgorithm
+}
+
+// HasRequestObjectSigningAlg is an optional interface of a [Client]
+// registered with the request_object_signing_alg metadata.
+// Request objects of the client signed with any other algorithm are rejected,
+// as well as all request objects if the algorithm is not in [Configuration.RequestObjectSigningAlgorithmsSupported].
+// An empty algorithm accepts all algorithms of [Configuration.RequestObjectSigningAlgorithmsSupported].
+type HasRequestObjectSigningAlg interface {
+	RequestObjectSigningAlg() jose.SignatureAlgorithm
+}
+
+// HasTokenEndpointAuthSigningAlg is an optional interface of a [Client]
+// registered with the token_endpoint_auth_signing_alg metadata.
+// Client assertions (private_key_jwt) of the client signed with any other algorithm are rejected,
+// as well as all assertions if the algorithm is not in [Configuration.TokenEndpointSigningAlgorithmsSupported].
+// An empty algorithm accepts all algorithms of [Configuration.TokenEndpointSigningAlgorithmsSupported].
+type HasTokenEndpointAuthSigningAlg interface {
+	TokenEndpointAuthSigningAlg() jose.SignatureAlgorithm
+}
+
+// CanGetSigningKeyByAlgorithm is an optional interface of the [Storage]
+// to sign the ID tokens of clients implementing [HasIDTokenSignedResponseAlg].
+// The algorithms must be included in the SignatureAlgorithms of the Storage,
+// so they are announced in the discovery.
+type CanGetSigningKeyByAlgorithm interface {
+	SigningKeyByAlgorithm(ctx context.Context, alg jose.SignatureAlgorithm) (SigningKey, error)
+}
+
+// idTokenSigningKey returns the signing key of the id_token_signed_response_alg
+// of the client, or the default signing key.
+func idTokenSigningKey(ctx context.Context, storage Storage, client Client) (SigningKey, error) {
+	var alg jose.SignatureAlgorithm
+	if c, ok := client.(HasIDTokenSignedResponseAlg); ok {
+		alg = c.IDTokenSignedResponseAlg()
+	}
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	if alg == "" {
+		return storage.SigningKey(storageCtx)
+	}
+	supported, err := storage.SignatureAlgorithms(storageCtx)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(supported, alg) {
+		return nil, oidc.ErrServerError().WithDescription("id_token_signed_response_alg %s is not supported", alg)
+	}
+	if s, ok := storage.(CanGetSigningKeyByAlgorithm); ok {
+		return s.SigningKeyByAlgorithm(storageCtx, alg)
+	}
+	key, err := storage.SigningKey(storageCtx)
+	if err != nil {
+		return nil, err
+	}
+	if key.SignatureAlgorithm() != alg {
+		return nil, oidc.ErrServerError().WithDescription("no signing key for id_token_signed_response_alg %s", alg)
+	}
+	return key, nil
+}
+
+func requestObjectSigningAlg(client Client) jose.SignatureAlgorithm {
+	if c, ok := client.(HasRequestObjectSigningAlg); ok {
+		return c.RequestObjectSigningAlg()
+	}
+	return ""
+}
+
+func tokenEndpointAuthSigningAlg(client Client) jose.SignatureAlgorithm {
+	if c, ok := client.(HasTokenEndpointAuthSigningAlg); ok {
+		return c.TokenEndpointAuthSigningAlg()
+	}
+	return ""
+}
+
+// requestObjectSigningAlgorithms returns the algorithms supported for request objects by the provider,
+// or nil if it doesn't implement [Configuration].
+func requestObjectSigningAlgorithms(v any) []string {
+	if c, ok := v.(interface{ RequestObjectSigningAlgorithmsSupported() []string }); ok {
+		return c.RequestObjectSigningAlgorithmsSupported()
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package op_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/example/server/storage"
+	tu "github.com/zitadel/oidc/v3/internal/testutil"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+)
+
+type tokenEndpointAlgClient struct {
+	jwksClient
+	alg jose.SignatureAlgorithm
+}
+
+func (c tokenEndpointAlgClient) TokenEndpointAuthSigningAlg() jose.SignatureAlgorithm { return c.alg }
+
+func TestVerifyJWTAssertion_signingAlgorithms(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	jwks := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{tu.WebKey.Public()}}
+	tests := []struct {
+		name      string
+		clientAlg jose.SignatureAlgorithm
+		supported []string
+		wantErr   bool
+	}{
+		{name: "default RS256"},
+		{name: "supported", supported: []string{"ES256", "RS256"}},
+		{name: "not supported", supported: []string{"ES256"}, wantErr: true},
+		{name: "registered by client", clientAlg: jose.RS256, supported: []string{"ES256", "RS256"}},
+		{name: "registered by client without supported", clientAlg: jose.RS256},
+		{name: "other alg registered by client", clientAlg: jose.ES256, supported: []string{"ES256", "RS256"}, wantErr: true},
+		{name: "unsupported alg registered by client", clientAlg: jose.RS256, supported: []string{"ES256"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := tokenEndpointAlgClient{jwksClient{newKeysClient(ctrl), jwks}, tt.clientAlg}
+			verifier := op.NewJWTProfileVerifier(clientKeyStorage{client}, tu.ValidIssuer, time.Minute, 0,
+				op.WithClientKeys(op.NewClientKeySetCache()))
+			verifier.SupportedSignAlgs = tt.supported
+
+			assertion, _ := tu.ValidJWTProfileAssertion()
+			_, err := op.VerifyJWTAssertion(context.Background(), assertion, verifier)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, oidc.ErrSignatureUnsupportedAlg)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+type idTokenAlgClient struct {
+	*storage.Client
+	alg jose.SignatureAlgorithm
+}
+
+func (c idTokenAlgClient) IDTokenSignedResponseAlg() jose.SignatureAlgorithm { return c.alg }
+
+type testSigningKey struct {
+	alg jose.SignatureAlgorithm
+	key any
+}
+
+func (k testSigningKey) SignatureAlgorithm() jose.SignatureAlgorithm { return k.alg }
+func (k testSigningKey) Key() any                                    { return k.key }
+func (k testSigningKey) ID() string                                  { return "ec" }
+
+type algorithmStorage struct {
+	*storage.Storage
+	key op.SigningKey
+}
+
+func (s algorithmStorage) SignatureAlgorithms(context.Context) ([]jose.SignatureAlgorithm, error) {
+	return []jose.SignatureAlgorithm{jose.RS256, s.key.SignatureAlgorithm()}, nil
+}
+
+func (s algorithmStorage) SigningKeyByAlgorithm(_ context.Context, alg jose.SignatureAlgorithm) (op.SigningKey, error) {
+	if alg != s.key.SignatureAlgorithm() {
+		return nil, oidc.ErrServerError()
+	}
+	return s.key, nil
+}
+
+func TestCreateIDToken_signedResponseAlg(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	s := storage.NewStorage(storage.NewUserStore(testIssuer))
+	request := &op.DeviceAuthorizationState{ClientID: "web", Subject: "id1"}
+	tokenAlg := func(token string) jose.SignatureAlgorithm {
+		header, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+		require.NoError(t, err)
+		var h struct {
+			Alg jose.SignatureAlgorithm `json:"alg"`
+		}
+		require.NoError(t, json.Unmarshal(header, &h))
+		return h.Alg
+	}
+	ctx := context.Background()
+	webClient := storage.WebClient("web", "secret", "https://example.com/callback")
+
+	token, err := op.CreateIDToken(ctx, testIssuer, request, time.Hour, "", "", s, webClient)
+	require.NoError(t, err)
+	assert.Equal(t, jose.RS256, tokenAlg(token), "default key")
+
+	token, err = op.CreateIDToken(ctx, testIssuer, request, time.Hour, "at", "", s, idTokenAlgClient{webClient, jose.RS256})
+	require.NoError(t, err)
+	assert.Equal(t, jose.RS256, tokenAlg(token), "matches default key")
+
+	_, err = op.CreateIDToken(ctx, testIssuer, request, time.Hour, "", "", s, idTokenAlgClient{webClient, jose.ES256})
+	assert.Error(t, err, "storage without keys by algorithm")
+
+	_, err = op.CreateIDToken(ctx, testIssuer, request, time.Hour, "", "", algorithmStorage{s, testSigningKey{jose.ES256, ecKey}}, idTokenAlgClient{webClient, jose.PS256})
+	assert.ErrorIs(t, err, oidc.ErrServerError(), "not in the signature algorithms")
+
+	withKeys := algorithmStorage{s, testSigningKey{jose.ES256, ecKey}}
+	token, err = op.CreateIDToken(ctx, testIssuer, request, time.Hour, "at", "", withKeys, idTokenAlgClient{webClient, jose.ES256})
+	require.NoError(t, err)
+	assert.Equal(t, jose.ES256, tokenAlg(token))
+	claims := new(oidc.IDTokenClaims)
+	_, err = oidc.ParseToken(token, claims)
+	require.NoError(t, err)
+	atHash, err := oidc.ClaimHash("at", jose.ES256)
+	require.NoError(t, err)
+	assert.Equal(t, atHash, claims.AccessTokenHash)
+}
@@ -0,0 +1,208 @@
+package op
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// SilentAuthStorage is an optional additional interface that may be implemented by
+// implementors of Storage to authenticate auth requests with prompt=none
+// from the existing session of the user agent, without any user interaction.
+// The session is identified by the cookie set with [WithSessionCookie].
+//
+// If the Storage implements it, the OP never redirects auth requests with prompt=none to the login UI,
+// but returns login_required, consent_required or interaction_required to the client.
+// Otherwise, they are passed to CreateAuthRequest like any other auth request.
+type SilentAuthStorage interface {
+	// SessionByID returns the active session.
+	// An error implementing [StorageNotFoundError] is returned for unknown or terminated sessions.
+	SessionByID(ctx context.Context, sessionID string) (*UserSession, error)
+	// CreateSilentAuthRequest creates an auth request, which is already authenticated by the session
+	// and therefore [AuthRequest.Done]. The session ID must be passed on to the tokens, see [SessionRequest].
+	// It returns [oidc.ErrConsentRequired] if the user did not consent to the client and scopes yet,
+	// or [oidc.ErrInteractionRequired] for any other required interaction.
+	CreateSilentAuthRequest(ctx context.Context, authReq *oidc.AuthRequest, session *UserSession) (AuthRequest, error)
+}
+
+// WithSessionCookie sets the name of a cookie, which is set to the session ID (see [SessionRequest])
+// of the auth requests completed by the login, to authenticate further requests with prompt=none
+// by the [SilentAuthStorage].
+// As the session ID is also sent to the clients in the sid claim, the cookie value is MAC'd
+// with a key derived from the [Config.CryptoKey], so it can't be forged from a session ID.
+// The cookie is SameSite=None on https issuers, so it is sent by hidden iframes of the clients,
+// and cleared by the end_session endpoint.
+func WithSessionCookie(name string) Option {
+	return func(o *Provider) error {
+		if name == "" {
+			return errors.New("session cookie name must not be empty")
+		}
+		o.sessionCookie = name
+		return nil
+	}
+}
+
+// SessionCookie returns the cookie name set by [WithSessionCookie].
+func (o *Provider) SessionCookie() string {
+	return o.sessionCookie
+}
+
+func (o *Provider) sessionCookieKey() []byte {
+	key := sha256.Sum256(append([]byte("oidc session cookie:"), o.config.CryptoKey[:]...))
+	return key[:]
+}
+
+// sessionCookieProvider is implemented by the [Provider]
+// to pass the cookie of [WithSessionCookie] to the authorize and end_session endpoints.
+type sessionCookieProvider interface {
+	SessionCookie() string
+	sessionCookieKey() []byte
+}
+
+func sessionCookieFrom(v any) (name string, key []byte) {
+	if p, ok := v.(sessionCookieProvider); ok && p.SessionCookie() != "" {
+		return p.SessionCookie(), p.sessionCookieKey()
+	}
+	return "", nil
+}
+
+// sessionCookieMAC returns the MAC of the session ID of the cookie.
+func sessionCookieMAC(key []byte, sessionID string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(sessionID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// sessionCookieValue returns the value of the cookie of the session,
+// the session ID with its MAC.
+func sessionCookieValue(key []byte, sessionID string) string {
+	return sessionID + "." + sessionCookieMAC(key, sessionID)
+}
+
+// sessionIDFromCookie returns the session ID of the cookie value,
+// or false if the MAC is not valid.
+func sessionIDFromCookie(key []byte, value string) (string, bool) {
+	i := strings.LastIndexByte(value, '.')
+	if i <= 0 {
+		return "", false
+	}
+	sessionID, mac := value[:i], value[i+1:]
+	if !hmac.Equal([]byte(mac), []byte(sessionCookieMAC(key, sessionID))) {
+		return "", false
+	}
+	return sessionID, true
+}
+
+// isPromptNone reports whether the auth request must be handled without user interaction.
+func isPromptNone(prompts []string) bool {
+	return slices.Contains(prompts, oidc.PromptNone)
+}
+
+// silentAuthRequest creates the auth request with prompt=none from the session of the user agent.
+// The hintSubject of the id_token_hint, if any, must match the user of the session
+// and the session must satisfy the max_age of the request.
+func silentAuthRequest(ctx context.Context, provider Authorizer, storage SilentAuthStorage, header http.Header, authReq *oidc.AuthRequest, hintSubject string) (AuthRequest, error) {
+	ctx, span := Tracer.Start(ctx, "silentAuthRequest")
+	defer span.End()
+
+	name, key := sessionCookieFrom(provider)
+	if name == "" {
+		return nil, oidc.ErrLoginRequired().WithDescription("session cookie not configured")
+	}
+	cookie, err := (&http.Request{Header: header}).Cookie(name)
+	if err != nil {
+		return nil, oidc.ErrLoginRequired().WithDescription("The user is not logged in.")
+	}
+	sessionID, ok := sessionIDFromCookie(key, cookie.Value)
+	if !ok {
+		return nil, oidc.ErrLoginRequired().WithDescription("The user is not logged in.")
+	}
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	session, err := storage.SessionByID(storageCtx, sessionID)
+	if err != nil {
+		var notFound StorageNotFoundError
+		if errors.As(err, &notFound) {
+			return nil, oidc.ErrLoginRequired().WithDescription("The user is not logged in.").WithParent(err)
+		}
+		return nil, oidc.DefaultToServerError(err, "unable to get session")
+	}
+	if hintSubject != "" && hintSubject != session.Subject {
+		return nil, oidc.ErrLoginRequired().WithDescription("The user of the id_token_hint is not logged in.")
+	}
+	if authReq.MaxAge != nil && time.Since(session.AuthTime) > time.Duration(*authReq.MaxAge)*time.Second {
+		return nil, oidc.ErrLoginRequired().WithDescription("The authentication of the user exceeds the max_age.")
+	}
+	req, err := storage.CreateSilentAuthRequest(storageCtx, authReq, session)
+	if err != nil {
+		return nil, oidc.DefaultToServerError(err, "unable to save auth request")
+	}
+	if !req.Done() {
+		return nil, oidc.ErrInteractionRequired().WithDescription("The auth request requires user interaction.")
+	}
+	return req, nil
+}
+
+// setSessionCookie sets the cookie of [WithSessionCookie] to the session ID of the completed auth request.
+func setSessionCookie(w http.ResponseWriter, r *http.Request, provider Authorizer, authReq AuthRequest) {
+	name, key := sessionCookieFrom(provider)
+	sessionID := SessionIDFromRequest(authReq)
+	if name == "" || sessionID == "" {
+		return
+	}
+	http.SetCookie(w, newSessionCookie(r.Context(), r.TLS != nil, name, sessionCookieValue(key, sessionID)))
+}
+
+// expiredSessionCookie returns the cookie of [WithSessionCookie] removing the session of the user agent,
+// or nil if it is not set.
+func expiredSessionCookie(ctx context.Context, tls bool, provider SessionEnder) *http.Cookie {
+	name, _ := sessionCookieFrom(provider)
+	if name == "" {
+		return nil
+	}
+	cookie := newSessionCookie(ctx, tls, name, "")
+	cookie.MaxAge = -1
+	return cookie
+}
+
+func newSessionCookie(ctx context.Context, tls bool, name, value string) *http.Cookie {
+	secure := tls || strings.HasPrefix(IssuerFromContext(ctx), "https://")
+	sameSite := http.SameSiteLaxMode
+	if secure {
+		sameSite = http.SameSiteNoneMode
+	}
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: sameSite,
+	}
+}
+
+type authorizationEndpointProvider interface {
+	AuthorizationEndpoint() *Endpoint
+}
+
+func authorizationEndpointFrom(v any) *Endpoint {
+	if p, ok := v.(authorizationEndpointProvider); ok {
+		return p.AuthorizationEndpoint()
+	}
+	return DefaultEndpoints.Authorization
+}
+
+// authorizeCallbackURL returns the URL of the callback under the authorization endpoint,
+// which responds to the completed auth request.
+func authorizeCallbackURL(ctx context.Context, authorization *Endpoint, requestID string) string {
+	return authorization.Absolute(IssuerFromContext(ctx)) + authCallbackPathSuffix + "?id=" + url.QueryEscape(requestID)
+}
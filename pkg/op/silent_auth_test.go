@@ -0,0 +1,122 @@
+package op_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/optest"
+)
+
+func TestSilentAuthentication(t *testing.T) {
+	s := optest.New(t, withExampleStorage(), optest.WithProviderOptions(op.WithSessionCookie("session")))
+	relyingParty := s.RelyingParty(t)
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	client := &http.Client{
+		Transport: s.Client().Transport,
+		Jar:       jar,
+		CheckRedirect: func(req *http.Request, _ []*http.Request) error {
+			if req.URL.Host != s.Listener.Addr().String() {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+	authorize := func(t *testing.T, opts ...rp.AuthURLOpt) url.Values {
+		resp, err := client.Get(rp.AuthURL("state", relyingParty, opts...))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		location, err := resp.Location()
+		require.NoError(t, err)
+		return location.Query()
+	}
+	exchange := func(t *testing.T, params url.Values) *oidc.Tokens[*oidc.IDTokenClaims] {
+		require.Empty(t, params.Get("error"), params.Get("error_description"))
+		tokens, err := rp.CodeExchange[*oidc.IDTokenClaims](context.Background(), params.Get("code"), relyingParty)
+		require.NoError(t, err)
+		return tokens
+	}
+
+	params := authorize(t, rp.WithPrompt(oidc.PromptNone))
+	assert.Equal(t, string(oidc.LoginRequired), params.Get("error"), "no session yet")
+
+	tokens := exchange(t, authorize(t))
+
+	tests := []struct {
+		name      string
+		opts      []rp.AuthURLOpt
+		wantError oidc.Error
+	}{
+		{
+			name: "session",
+		},
+		{
+			name: "id_token_hint of the user",
+			opts: []rp.AuthURLOpt{rp.WithIDTokenHint(tokens.IDToken)},
+		},
+		{
+			name:      "id_token_hint of another user",
+			opts:      []rp.AuthURLOpt{rp.WithIDTokenHint(s.IDToken(t, optest.Subject("other")))},
+			wantError: oidc.Error{ErrorType: oidc.LoginRequired},
+		},
+		{
+			name:      "max_age exceeded",
+			opts:      []rp.AuthURLOpt{rp.WithMaxAge(0)},
+			wantError: oidc.Error{ErrorType: oidc.LoginRequired},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			time.Sleep(time.Millisecond)
+			params := authorize(t, append(tt.opts, rp.WithPrompt(oidc.PromptNone))...)
+			if tt.wantError.ErrorType != "" {
+				assert.Equal(t, string(tt.wantError.ErrorType), params.Get("error"))
+				assert.Empty(t, params.Get("code"))
+				return
+			}
+			silent := exchange(t, params)
+			assert.Equal(t, optest.UserID, silent.IDTokenClaims.Subject)
+			assert.Equal(t, tokens.IDTokenClaims.AuthTime, silent.IDTokenClaims.AuthTime, "auth_time of the session")
+		})
+	}
+	issuer, err := url.Parse(s.Issuer)
+	require.NoError(t, err)
+	cookies := jar.Cookies(issuer)
+	require.Len(t, cookies, 1)
+	sessionID, _, ok := strings.Cut(cookies[0].Value, ".")
+	require.True(t, ok, "MAC'd session ID")
+
+	t.Run("forged cookie", func(t *testing.T) {
+		forged, err := cookiejar.New(nil)
+		require.NoError(t, err)
+		forged.SetCookies(issuer, []*http.Cookie{{Name: "session", Value: sessionID, Path: "/"}})
+		client := &http.Client{Transport: client.Transport, Jar: forged, CheckRedirect: client.CheckRedirect}
+		resp, err := client.Get(rp.AuthURL("state", relyingParty, rp.WithPrompt(oidc.PromptNone)))
+		require.NoError(t, err)
+		resp.Body.Close()
+		location, err := resp.Location()
+		require.NoError(t, err)
+		assert.Equal(t, string(oidc.LoginRequired), location.Query().Get("error"))
+	})
+
+	t.Run("end_session clears the cookie", func(t *testing.T) {
+		endSession, err := url.Parse(relyingParty.GetEndSessionEndpoint())
+		require.NoError(t, err)
+		endSession.RawQuery = url.Values{"id_token_hint": {tokens.IDToken}}.Encode()
+		resp, err := client.Get(endSession.String())
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Empty(t, jar.Cookies(issuer))
+	})
+}
@@ -0,0 +1,182 @@
+package ssf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/zitadel/oidc/v3/pkg/crypto"
+	"github.com/zitadel/oidc/v3/pkg/op"
+)
+
+// Event types of the CAEP and SSF specifications.
+const (
+	EventTypeSessionRevoked    = "https://schemas.openid.net/secevent/caep/event-type/session-revoked"
+	EventTypeCredentialChange  = "https://schemas.openid.net/secevent/caep/event-type/credential-change"
+	EventTypeTokenClaimsChange = "https://schemas.openid.net/secevent/caep/event-type/token-claims-change"
+	EventTypeVerification      = "https://schemas.openid.net/secevent/ssf/event-type/verification"
+
+	// TypeSecurityEventToken is the typ header of the Security Event Tokens (RFC 8417).
+	TypeSecurityEventToken = "secevent+jwt"
+	contentTypeSET         = "application/secevent+jwt"
+)
+
+// EventTypesSupported are the event types, which can be requested by the receivers.
+var EventTypesSupported = []string{
+	EventTypeSessionRevoked,
+	EventTypeCredentialChange,
+	EventTypeTokenClaimsChange,
+}
+
+var eventTypes = map[op.EventType]string{
+	op.EventSessionRevoked:    EventTypeSessionRevoked,
+	op.EventCredentialChange:  EventTypeCredentialChange,
+	op.EventTokenClaimsChange: EventTypeTokenClaimsChange,
+}
+
+// securityEventToken are the claims of a Security Event Token with a single event.
+type securityEventToken struct {
+	Issuer   string                    `json:"iss"`
+	JWTID    string                    `json:"jti"`
+	IssuedAt int64                     `json:"iat"`
+	Audience []string                  `json:"aud"`
+	Subject  map[string]any            `json:"sub_id"`
+	Events   map[string]map[string]any `json:"events"`
+}
+
+// subjectUser identifies the user by the iss_sub format of RFC 9493.
+func subjectUser(issuer, subject string) map[string]any {
+	return map[string]any{"format": "iss_sub", "iss": issuer, "sub": subject}
+}
+
+// subjectSession identifies the session of the user by the complex format of SSF.
+func subjectSession(issuer, subject, sessionID string) map[string]any {
+	return map[string]any{
+		"format":  "complex",
+		"user":    subjectUser(issuer, subject),
+		"session": map[string]any{"format": "opaque", "id": sessionID},
+	}
+}
+
+// subjectStream identifies the stream of verification events.
+func subjectStream(streamID string) map[string]any {
+	return map[string]any{"format": "opaque", "id": streamID}
+}
+
+// Listen delivers the event to the streams, which requested its type, in the background,
+// see [Transmitter.Wait]. Events of clients are only delivered to the streams
+// of these clients. Failed deliveries are retried and logged.
+// It must be passed to op.WithEventListener.
+func (t *Transmitter) Listen(ctx context.Context, event op.Event) {
+	eventType, ok := eventTypes[event.Type]
+	if !ok {
+		return
+	}
+	issuer := op.IssuerFromContext(ctx)
+	streams, err := t.streams.EventStreams(ctx, issuer, eventType, event.ClientIDs)
+	if err != nil {
+		slog.ErrorContext(ctx, "ssf: listing streams failed", "error", err)
+		return
+	}
+	subject := subjectUser(issuer, event.Subject)
+	if event.SessionID != "" {
+		subject = subjectSession(issuer, event.Subject, event.SessionID)
+	}
+	payload := maps.Clone(event.Details)
+	if payload == nil {
+		payload = make(map[string]any)
+	}
+	payload["event_timestamp"] = event.Time.Unix()
+
+	for _, stream := range streams {
+		set, err := t.securityEventToken(ctx, stream, subject, eventType, payload)
+		if err != nil {
+			slog.ErrorContext(ctx, "ssf: creating security event token failed", "error", err, "stream_id", stream.ID)
+			continue
+		}
+		t.deliver(ctx, stream, set)
+	}
+}
+
+func (t *Transmitter) securityEventToken(ctx context.Context, stream *Stream, subject map[string]any, eventType string, event map[string]any) (string, error) {
+	key, err := t.storage.SigningKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	kid, err := op.SigningKeyID(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	signer, err := crypto.NewCompactSigner(key.SignatureAlgorithm(), key.Key(), kid, TypeSecurityEventToken)
+	if err != nil {
+		return "", err
+	}
+	return signer.SignObject(&securityEventToken{
+		Issuer:   stream.Issuer,
+		JWTID:    op.NewTokenID(ctx),
+		IssuedAt: op.Now(ctx).Unix(),
+		Audience: stream.Audience,
+		Subject:  subject,
+		Events:   map[string]map[string]any{eventType: event},
+	})
+}
+
+// errDeliveryRejected is returned by push for responses of the receiver,
+// which must not be retried.
+var errDeliveryRejected = errors.New("delivery rejected")
+
+// deliver pushes the Security Event Token in the background, so the request
+// of the event is not delayed by slow or unreachable receivers.
+// Failed attempts are retried with an exponential backoff.
+func (t *Transmitter) deliver(ctx context.Context, stream *Stream, set string) {
+	// the request of the event may finish before the delivery
+	ctx = context.WithoutCancel(ctx)
+	t.deliveries.Add(1)
+	go func() {
+		defer t.deliveries.Done()
+		backoff := t.retryBackoff
+		for attempt := 1; ; attempt++ {
+			err := t.push(ctx, stream, set)
+			if err == nil {
+				return
+			}
+			if errors.Is(err, errDeliveryRejected) || attempt >= t.maxAttempts {
+				slog.WarnContext(ctx, "ssf: delivery failed", "error", err, "stream_id", stream.ID, "attempts", attempt)
+				return
+			}
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}()
+}
+
+// push delivers the Security Event Token as defined by RFC 8935.
+// Client errors other than 429 Too Many Requests wrap errDeliveryRejected.
+func (t *Transmitter) push(ctx context.Context, stream *Stream, set string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, stream.Delivery.EndpointURL, strings.NewReader(set))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentTypeSET)
+	req.Header.Set("Accept", "application/json")
+	if stream.Delivery.AuthorizationHeader != "" {
+		req.Header.Set("Authorization", stream.Delivery.AuthorizationHeader)
+	}
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return fmt.Errorf("%w: status %s", errDeliveryRejected, resp.Status)
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
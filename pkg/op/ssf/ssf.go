@@ -0,0 +1,527 @@
+// Package ssf provides a transmitter of the OpenID Shared Signals Framework (SSF 1.0)
+// for the OpenID Provider.
+//
+// The [Transmitter] delivers the security events of the OP (see op.Event) as
+// Security Event Tokens (RFC 8417) of the Continuous Access Evaluation Profile (CAEP)
+// to the receivers, which registered a stream by the stream configuration endpoint.
+// Events are pushed to the https endpoint of the stream (RFC 8935) in the background
+// and retried if the delivery fails:
+//
+//	transmitter, err := ssf.New(ssf.Config{
+//		Storage:   storage,
+//		Streams:   ssf.NewMemoryStreamStorage(),
+//		Authorize: ssf.BearerTokens(map[string]string{os.Getenv("RECEIVER_TOKEN"): "receiver"}),
+//	})
+//	provider, err := op.NewProvider(config, storage, issuer, op.WithEventListener(transmitter.Listen))
+//	handler := transmitter.Handler(provider)
+//	router.Handle(ssf.ConfigurationPath, handler)
+//	router.Handle("/ssf/*", handler)
+//
+// Routes, relative to the issuer:
+//
+//	GET    /.well-known/ssf-configuration  transmitter configuration metadata
+//	POST   /ssf/stream                     create a stream
+//	GET    /ssf/stream?stream_id=          get a stream, or all streams of the receiver
+//	PATCH  /ssf/stream                     update the passed fields of a stream
+//	PUT    /ssf/stream                     replace the fields of a stream
+//	DELETE /ssf/stream?stream_id=          delete a stream
+//	POST   /ssf/verify                     deliver a verification event to a stream
+//
+// Pending deliveries should be awaited by [Transmitter.Wait] on shutdown.
+package ssf
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	httphelper "github.com/zitadel/oidc/v3/pkg/http"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+)
+
+const (
+	// ConfigurationPath of the transmitter configuration metadata, relative to the issuer.
+	ConfigurationPath  = "/.well-known/ssf-configuration"
+	StreamPath         = "/ssf/stream"
+	VerificationPath   = "/ssf/verify"
+	SpecVersion        = "1_0"
+	DeliveryMethodPush = "urn:ietf:rfc:8935"
+
+	defaultTimeout      = 5 * time.Second
+	defaultMaxAttempts  = 3
+	defaultRetryBackoff = time.Second
+)
+
+// Metadata is the transmitter configuration metadata.
+type Metadata struct {
+	SpecVersion              string   `json:"spec_version"`
+	Issuer                   string   `json:"issuer"`
+	JWKSURI                  string   `json:"jwks_uri"`
+	DeliveryMethodsSupported []string `json:"delivery_methods_supported"`
+	ConfigurationEndpoint    string   `json:"configuration_endpoint"`
+	VerificationEndpoint     string   `json:"verification_endpoint"`
+	DefaultSubjects          string   `json:"default_subjects"`
+}
+
+// Delivery configures how the events of a [Stream] are delivered.
+type Delivery struct {
+	Method      string `json:"method"`
+	EndpointURL string `json:"endpoint_url"`
+	// AuthorizationHeader is sent in the Authorization header of the push requests.
+	AuthorizationHeader string `json:"authorization_header,omitempty"`
+}
+
+// Stream is the stream configuration of a receiver.
+type Stream struct {
+	ID              string        `json:"stream_id"`
+	Issuer          string        `json:"iss"`
+	Audience        oidc.Audience `json:"aud"`
+	EventsSupported []string      `json:"events_supported"`
+	EventsRequested []string      `json:"events_requested"`
+	EventsDelivered []string      `json:"events_delivered"`
+	Delivery        Delivery      `json:"delivery"`
+	Description     string        `json:"description,omitempty"`
+	// Receiver owns the stream, as returned by [Config.Authorize].
+	Receiver string `json:"-"`
+}
+
+func (s Stream) clone() Stream {
+	s.Audience = slices.Clone(s.Audience)
+	s.EventsSupported = slices.Clone(s.EventsSupported)
+	s.EventsRequested = slices.Clone(s.EventsRequested)
+	s.EventsDelivered = slices.Clone(s.EventsDelivered)
+	return s
+}
+
+// streamUpdate are the fields of a [Stream] set by the receiver.
+// Fields missing in a PATCH request are left unchanged.
+type streamUpdate struct {
+	ID              string    `json:"stream_id,omitempty"`
+	EventsRequested *[]string `json:"events_requested,omitempty"`
+	Delivery        *Delivery `json:"delivery,omitempty"`
+	Description     *string   `json:"description,omitempty"`
+}
+
+// Config of a [Transmitter].
+type Config struct {
+	// Storage provides the signing key of the Security Event Tokens.
+	Storage op.Storage
+	Streams StreamStorage
+	// Authorize authorizes the requests of the receivers and returns the receiver,
+	// which is the audience of its streams. Events of clients (op.Event.ClientIDs)
+	// are only delivered to the streams of these receivers, so the client ID
+	// should be used for receivers which are clients of the OP.
+	// A returned error responds with 401 Unauthorized.
+	// See [BearerTokens] for static tokens.
+	Authorize func(r *http.Request) (receiver string, err error)
+	// HTTPClient delivers the events. Defaults to a client with a timeout of 5 seconds,
+	// which does not follow redirects and refuses to connect to loopback, private
+	// and link-local addresses, unless AllowPrivateEndpoints is set.
+	HTTPClient *http.Client
+	// MaxAttempts of a delivery, defaults to 3.
+	// Deliveries rejected by the receiver with a 4xx status are not retried.
+	MaxAttempts int
+	// RetryBackoff is the delay before the first retry of a delivery,
+	// which is doubled for every further retry. Defaults to 1 second.
+	RetryBackoff time.Duration
+	// AllowPrivateEndpoints allows http endpoints and endpoints on loopback,
+	// private and link-local addresses, e.g. for tests or receivers in the same network.
+	AllowPrivateEndpoints bool
+}
+
+// Transmitter delivers the events of the OP to the streams of the receivers.
+type Transmitter struct {
+	storage               op.Storage
+	streams               StreamStorage
+	authorize             func(r *http.Request) (string, error)
+	httpClient            *http.Client
+	maxAttempts           int
+	retryBackoff          time.Duration
+	allowPrivateEndpoints bool
+	deliveries            sync.WaitGroup
+}
+
+// New creates a [Transmitter].
+func New(config Config) (*Transmitter, error) {
+	if config.Storage == nil {
+		return nil, errors.New("ssf: storage is required")
+	}
+	if config.Streams == nil {
+		return nil, errors.New("ssf: streams is required")
+	}
+	if config.Authorize == nil {
+		return nil, errors.New("ssf: authorize is required")
+	}
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = newHTTPClient(config.AllowPrivateEndpoints)
+	}
+	maxAttempts := config.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	retryBackoff := config.RetryBackoff
+	if retryBackoff <= 0 {
+		retryBackoff = defaultRetryBackoff
+	}
+	return &Transmitter{
+		storage:               config.Storage,
+		streams:               config.Streams,
+		authorize:             config.Authorize,
+		httpClient:            httpClient,
+		maxAttempts:           maxAttempts,
+		retryBackoff:          retryBackoff,
+		allowPrivateEndpoints: config.AllowPrivateEndpoints,
+	}, nil
+}
+
+// Wait blocks until the pending deliveries are finished, e.g. on shutdown of the OP.
+func (t *Transmitter) Wait() {
+	t.deliveries.Wait()
+}
+
+// newHTTPClient returns the default client of the deliveries. Unless private endpoints
+// are allowed, it connects directly, without proxy, and only to public addresses,
+// so host names of the streams cannot resolve to internal services.
+func newHTTPClient(allowPrivateEndpoints bool) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !allowPrivateEndpoints {
+		transport.Proxy = nil
+		transport.DialContext = (&net.Dialer{Timeout: defaultTimeout, Control: dialPublic}).DialContext
+	}
+	return &http.Client{
+		Timeout:   defaultTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// dialPublic refuses connections to addresses, which are not public.
+func dialPublic(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !isPublic(addr) {
+		return fmt.Errorf("ssf: connection to %s refused", host)
+	}
+	return nil
+}
+
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return !addr.IsLoopback() && !addr.IsPrivate() && !addr.IsUnspecified() &&
+		!addr.IsLinkLocalUnicast() && !addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() && !addr.IsMulticast()
+}
+
+// validateEndpoint requires an absolute https URL, which does not name a loopback,
+// private or link-local host, unless private endpoints are allowed.
+func validateEndpoint(endpointURL string, allowPrivateEndpoints bool) error {
+	endpoint, err := url.Parse(endpointURL)
+	if err != nil || !endpoint.IsAbs() || endpoint.Hostname() == "" {
+		return errors.New("delivery endpoint_url must be an absolute URL")
+	}
+	if allowPrivateEndpoints {
+		if endpoint.Scheme != "https" && endpoint.Scheme != "http" {
+			return errors.New("delivery endpoint_url must be an http(s) URL")
+		}
+		return nil
+	}
+	if endpoint.Scheme != "https" {
+		return errors.New("delivery endpoint_url must be an https URL")
+	}
+	host := strings.TrimSuffix(strings.ToLower(endpoint.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errors.New("delivery endpoint_url must not be a loopback address")
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !isPublic(addr) {
+		return errors.New("delivery endpoint_url must be a public address")
+	}
+	return nil
+}
+
+// Handler returns the handler of the configuration metadata and the stream management
+// of the provider. It must be served at the paths of the package, relative to the issuer.
+func (t *Transmitter) Handler(provider op.OpenIDProvider) http.Handler {
+	router := chi.NewRouter()
+	router.Use(op.NewIssuerInterceptor(provider.IssuerFromRequest).Handler)
+	router.Get(ConfigurationPath, func(w http.ResponseWriter, r *http.Request) {
+		issuer := op.IssuerFromContext(r.Context())
+		httphelper.MarshalJSON(w, &Metadata{
+			SpecVersion:              SpecVersion,
+			Issuer:                   issuer,
+			JWKSURI:                  provider.KeysEndpoint().Absolute(issuer),
+			DeliveryMethodsSupported: []string{DeliveryMethodPush},
+			ConfigurationEndpoint:    strings.TrimSuffix(issuer, "/") + StreamPath,
+			VerificationEndpoint:     strings.TrimSuffix(issuer, "/") + VerificationPath,
+			DefaultSubjects:          "ALL",
+		})
+	})
+	router.Group(func(router chi.Router) {
+		router.Use(t.authorizeReceiver)
+		router.Post(StreamPath, t.createStream)
+		router.Get(StreamPath, t.getStreams)
+		router.Patch(StreamPath, t.updateStream)
+		router.Put(StreamPath, t.updateStream)
+		router.Delete(StreamPath, t.deleteStream)
+		router.Post(VerificationPath, t.verify)
+	})
+	return router
+}
+
+// BearerTokens authorizes requests with the static tokens in the Authorization header
+// ("Bearer <token>"), which map to the receivers.
+func BearerTokens(receivers map[string]string) func(r *http.Request) (string, error) {
+	return func(r *http.Request) (string, error) {
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), oidc.PrefixBearer)
+		if ok && bearer != "" {
+			for token, receiver := range receivers {
+				if subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) == 1 {
+					return receiver, nil
+				}
+			}
+		}
+		return "", errors.New("invalid bearer token")
+	}
+}
+
+type receiverKey struct{}
+
+func receiverFromContext(ctx context.Context) string {
+	receiver, _ := ctx.Value(receiverKey{}).(string)
+	return receiver
+}
+
+func (t *Transmitter) authorizeReceiver(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receiver, err := t.authorize(r)
+		if err == nil && receiver == "" {
+			err = errors.New("no receiver")
+		}
+		if err != nil {
+			slog.InfoContext(r.Context(), "ssf request unauthorized", "path", r.URL.Path, "error", err)
+			writeError(w, http.StatusUnauthorized, "unauthorized", err.Error())
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), receiverKey{}, receiver)))
+	})
+}
+
+// Error is the response of failed requests.
+type Error struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func writeError(w http.ResponseWriter, status int, errorType, description string) {
+	httphelper.MarshalJSONWithStatus(w, &Error{Error: errorType, Description: description}, status)
+}
+
+func badRequest(w http.ResponseWriter, description string) {
+	writeError(w, http.StatusBadRequest, "invalid_request", description)
+}
+
+// storageError responds with 404 Not Found for errors implementing op.StorageNotFoundError.
+func storageError(w http.ResponseWriter, r *http.Request, err error) {
+	var notFound op.StorageNotFoundError
+	if errors.As(err, &notFound) {
+		writeError(w, http.StatusNotFound, "not_found", err.Error())
+		return
+	}
+	slog.ErrorContext(r.Context(), "ssf request failed", "path", r.URL.Path, "error", err)
+	writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+}
+
+func decode(r *http.Request, v any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// apply sets the fields of the update to the stream
+// and derives the delivered events from the requested events.
+func (u *streamUpdate) apply(stream *Stream, allowPrivateEndpoints bool) error {
+	if u.Delivery != nil {
+		if u.Delivery.Method != DeliveryMethodPush {
+			return errors.New("delivery method must be " + DeliveryMethodPush)
+		}
+		if err := validateEndpoint(u.Delivery.EndpointURL, allowPrivateEndpoints); err != nil {
+			return err
+		}
+		stream.Delivery = *u.Delivery
+	}
+	if u.EventsRequested != nil {
+		stream.EventsRequested = *u.EventsRequested
+	}
+	if u.Description != nil {
+		stream.Description = *u.Description
+	}
+	if stream.Delivery.Method == "" {
+		return errors.New("delivery is required")
+	}
+	stream.EventsSupported = slices.Clone(EventTypesSupported)
+	stream.EventsDelivered = make([]string, 0, len(stream.EventsRequested))
+	for _, event := range stream.EventsRequested {
+		if slices.Contains(EventTypesSupported, event) && !slices.Contains(stream.EventsDelivered, event) {
+			stream.EventsDelivered = append(stream.EventsDelivered, event)
+		}
+	}
+	stream.EventsRequested = nonNil(stream.EventsRequested)
+	return nil
+}
+
+func (t *Transmitter) createStream(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var update streamUpdate
+	if err := decode(r, &update); err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+	receiver := receiverFromContext(ctx)
+	stream := &Stream{
+		ID:       rand.Text(),
+		Issuer:   op.IssuerFromContext(ctx),
+		Audience: oidc.Audience{receiver},
+		Receiver: receiver,
+	}
+	if err := update.apply(stream, t.allowPrivateEndpoints); err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+	if err := t.streams.CreateStream(ctx, stream); err != nil {
+		storageError(w, r, err)
+		return
+	}
+	slog.InfoContext(ctx, "ssf stream created", "stream_id", stream.ID, "receiver", receiver)
+	httphelper.MarshalJSONWithStatus(w, stream, http.StatusCreated)
+}
+
+func (t *Transmitter) getStreams(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	receiver := receiverFromContext(ctx)
+	if id := r.URL.Query().Get("stream_id"); id != "" {
+		stream, err := t.streams.Stream(ctx, receiver, id)
+		if err != nil {
+			storageError(w, r, err)
+			return
+		}
+		httphelper.MarshalJSON(w, stream)
+		return
+	}
+	streams, err := t.streams.Streams(ctx, receiver)
+	if err != nil {
+		storageError(w, r, err)
+		return
+	}
+	httphelper.MarshalJSON(w, nonNil(streams))
+}
+
+// updateStream handles PATCH and PUT requests. PUT replaces the fields
+// set by the receiver, so missing fields are reset.
+func (t *Transmitter) updateStream(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var update streamUpdate
+	if err := decode(r, &update); err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+	if update.ID == "" {
+		badRequest(w, "stream_id is required")
+		return
+	}
+	stream, err := t.streams.Stream(ctx, receiverFromContext(ctx), update.ID)
+	if err != nil {
+		storageError(w, r, err)
+		return
+	}
+	if r.Method == http.MethodPut {
+		stream.Delivery, stream.EventsRequested, stream.Description = Delivery{}, nil, ""
+	}
+	if err := update.apply(stream, t.allowPrivateEndpoints); err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+	if err := t.streams.UpdateStream(ctx, stream); err != nil {
+		storageError(w, r, err)
+		return
+	}
+	httphelper.MarshalJSON(w, stream)
+}
+
+func (t *Transmitter) deleteStream(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := r.URL.Query().Get("stream_id")
+	if id == "" {
+		badRequest(w, "stream_id is required")
+		return
+	}
+	if err := t.streams.DeleteStream(ctx, receiverFromContext(ctx), id); err != nil {
+		storageError(w, r, err)
+		return
+	}
+	slog.InfoContext(ctx, "ssf stream deleted", "stream_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type verificationRequest struct {
+	StreamID string `json:"stream_id"`
+	State    string `json:"state,omitempty"`
+}
+
+// verify delivers a verification event to the stream in the background,
+// see https://openid.net/specs/openid-sharedsignals-framework-1_0.html#name-verification
+func (t *Transmitter) verify(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var request verificationRequest
+	if err := decode(r, &request); err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+	if request.StreamID == "" {
+		badRequest(w, "stream_id is required")
+		return
+	}
+	stream, err := t.streams.Stream(ctx, receiverFromContext(ctx), request.StreamID)
+	if err != nil {
+		storageError(w, r, err)
+		return
+	}
+	event := map[string]any{}
+	if request.State != "" {
+		event["state"] = request.State
+	}
+	set, err := t.securityEventToken(ctx, stream, subjectStream(stream.ID), EventTypeVerification, event)
+	if err != nil {
+		storageError(w, r, err)
+		return
+	}
+	t.deliver(ctx, stream, set)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func nonNil[T any](list []T) []T {
+	if list == nil {
+		return []T{}
+	}
+	return list
+}
@@ -0,0 +1,355 @@
+package ssf_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/example/server/storage"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/optest"
+	"github.com/zitadel/oidc/v3/pkg/op/ssf"
+)
+
+const receiverToken = "receiver-token"
+
+// withExampleStorage serves the OP with the storage of the example server,
+// which implements the sessions of the users, unlike the optest.MemoryStorage.
+func withExampleStorage() optest.Option {
+	return optest.WithStorage(func(issuer string) (optest.Storage, error) {
+		return storage.NewStorageWithClients(storage.NewUserStore(issuer), map[string]*storage.Client{
+			optest.WebClientID: storage.WebClient(optest.WebClientID, optest.WebClientSecret, optest.RedirectURI),
+		}), nil
+	})
+}
+
+// receiver records the security event tokens pushed to it.
+// The first failures requests fail with 503 Service Unavailable.
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	sets     []string
+	failures int
+	requests int
+}
+
+func newReceiver(t *testing.T) *receiver {
+	r := &receiver{}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "application/secevent+jwt", req.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer delivery", req.Header.Get("Authorization"))
+		body, err := io.ReadAll(req.Body)
+		assert.NoError(t, err)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.requests++
+		if r.requests <= r.failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		r.sets = append(r.sets, string(body))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) received() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sets := r.sets
+	r.sets = nil
+	return sets
+}
+
+type setup struct {
+	server      *optest.Server
+	transmitter *ssf.Transmitter
+	call        func(method, path, body string) (int, []byte)
+}
+
+// newTransmitter creates a transmitter, which delivers to the receivers
+// on the loopback address of the tests, unless the options change the config.
+func newTransmitter(t *testing.T, opts ...func(*ssf.Config)) *setup {
+	t.Helper()
+	var transmitter *ssf.Transmitter
+	s := optest.New(t, withExampleStorage(), optest.WithProviderOptions(op.WithEventListener(func(ctx context.Context, event op.Event) {
+		transmitter.Listen(ctx, event)
+	})))
+	config := ssf.Config{
+		Storage:               s.Storage,
+		Streams:               ssf.NewMemoryStreamStorage(),
+		Authorize:             ssf.BearerTokens(map[string]string{receiverToken: optest.WebClientID}),
+		RetryBackoff:          time.Millisecond,
+		AllowPrivateEndpoints: true,
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	var err error
+	transmitter, err = ssf.New(config)
+	require.NoError(t, err)
+	handler := transmitter.Handler(s.Provider)
+	return &setup{
+		server:      s,
+		transmitter: transmitter,
+		call: func(method, path, body string) (int, []byte) {
+			req := httptest.NewRequest(method, s.Issuer+path, strings.NewReader(body))
+			req.Header.Set("Authorization", oidc.PrefixBearer+receiverToken)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			return rec.Code, rec.Body.Bytes()
+		},
+	}
+}
+
+func (s *setup) createStream(t *testing.T, endpoint string, events ...string) ssf.Stream {
+	t.Helper()
+	request, err := json.Marshal(map[string]any{
+		"delivery":         ssf.Delivery{Method: ssf.DeliveryMethodPush, EndpointURL: endpoint, AuthorizationHeader: "Bearer delivery"},
+		"events_requested": events,
+	})
+	require.NoError(t, err)
+	status, body := s.call(http.MethodPost, ssf.StreamPath, string(request))
+	require.Equal(t, http.StatusCreated, status, string(body))
+	var stream ssf.Stream
+	require.NoError(t, json.Unmarshal(body, &stream))
+	return stream
+}
+
+// verify checks the signature of the security event token and returns its claims.
+func (s *setup) verify(t *testing.T, set string) map[string]any {
+	t.Helper()
+	jws, err := jose.ParseSigned(set, []jose.SignatureAlgorithm{jose.RS256, jose.ES256, jose.EdDSA})
+	require.NoError(t, err)
+	assert.Equal(t, ssf.TypeSecurityEventToken, jws.Signatures[0].Header.ExtraHeaders["typ"])
+	keys, err := s.server.Storage.KeySet(s.server.Context())
+	require.NoError(t, err)
+	payload, err := jws.Verify(keys[0].Key())
+	require.NoError(t, err)
+	var claims map[string]any
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	return claims
+}
+
+func TestNew(t *testing.T) {
+	s := optest.New(t)
+	authorize := ssf.BearerTokens(map[string]string{receiverToken: "receiver"})
+	_, err := ssf.New(ssf.Config{Streams: ssf.NewMemoryStreamStorage(), Authorize: authorize})
+	assert.Error(t, err)
+	_, err = ssf.New(ssf.Config{Storage: s.Storage, Authorize: authorize})
+	assert.Error(t, err)
+	_, err = ssf.New(ssf.Config{Storage: s.Storage, Streams: ssf.NewMemoryStreamStorage()})
+	assert.Error(t, err)
+}
+
+func TestTransmitter_metadata(t *testing.T) {
+	s := newTransmitter(t)
+	status, body := s.call(http.MethodGet, ssf.ConfigurationPath, "")
+	require.Equal(t, http.StatusOK, status)
+	var metadata ssf.Metadata
+	require.NoError(t, json.Unmarshal(body, &metadata))
+	assert.Equal(t, ssf.Metadata{
+		SpecVersion:              "1_0",
+		Issuer:                   s.server.Issuer,
+		JWKSURI:                  s.server.Issuer + "/keys",
+		DeliveryMethodsSupported: []string{"urn:ietf:rfc:8935"},
+		ConfigurationEndpoint:    s.server.Issuer + "/ssf/stream",
+		VerificationEndpoint:     s.server.Issuer + "/ssf/verify",
+		DefaultSubjects:          "ALL",
+	}, metadata)
+}
+
+func TestTransmitter_streams(t *testing.T) {
+	s := newTransmitter(t)
+
+	req := httptest.NewRequest(http.MethodGet, s.server.Issuer+ssf.StreamPath, nil)
+	rec := httptest.NewRecorder()
+	transmitter, err := ssf.New(ssf.Config{Storage: s.server.Storage, Streams: ssf.NewMemoryStreamStorage(), Authorize: ssf.BearerTokens(nil)})
+	require.NoError(t, err)
+	transmitter.Handler(s.server.Provider).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	status, body := s.call(http.MethodPost, ssf.StreamPath, `{"delivery":{"method":"urn:ietf:rfc:8936"}}`)
+	assert.Equal(t, http.StatusBadRequest, status, string(body))
+
+	stream := s.createStream(t, "https://receiver.example.com/events", ssf.EventTypeSessionRevoked, "urn:example:unsupported")
+	assert.NotEmpty(t, stream.ID)
+	assert.Equal(t, s.server.Issuer, stream.Issuer)
+	assert.Equal(t, oidc.Audience{optest.WebClientID}, stream.Audience)
+	assert.Equal(t, ssf.EventTypesSupported, stream.EventsSupported)
+	assert.Equal(t, []string{ssf.EventTypeSessionRevoked}, stream.EventsDelivered)
+
+	status, body = s.call(http.MethodPatch, ssf.StreamPath, `{"stream_id":"`+stream.ID+`","events_requested":["`+ssf.EventTypeCredentialChange+`"]}`)
+	require.Equal(t, http.StatusOK, status, string(body))
+	var updated ssf.Stream
+	require.NoError(t, json.Unmarshal(body, &updated))
+	assert.Equal(t, []string{ssf.EventTypeCredentialChange}, updated.EventsDelivered)
+	assert.Equal(t, stream.Delivery, updated.Delivery)
+
+	status, body = s.call(http.MethodPut, ssf.StreamPath, `{"stream_id":"`+stream.ID+`"}`)
+	assert.Equal(t, http.StatusBadRequest, status, "delivery required: %s", body)
+
+	status, body = s.call(http.MethodGet, ssf.StreamPath, "")
+	require.Equal(t, http.StatusOK, status)
+	var streams []ssf.Stream
+	require.NoError(t, json.Unmarshal(body, &streams))
+	require.Len(t, streams, 1)
+	assert.Equal(t, updated.EventsDelivered, streams[0].EventsDelivered)
+
+	status, _ = s.call(http.MethodDelete, ssf.StreamPath+"?stream_id="+stream.ID, "")
+	assert.Equal(t, http.StatusNoContent, status)
+	status, _ = s.call(http.MethodGet, ssf.StreamPath+"?stream_id="+stream.ID, "")
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestTransmitter_verify(t *testing.T) {
+	s := newTransmitter(t)
+	receiver := newReceiver(t)
+	stream := s.createStream(t, receiver.URL)
+
+	status, body := s.call(http.MethodPost, ssf.VerificationPath, `{"stream_id":"`+stream.ID+`","state":"abc"}`)
+	require.Equal(t, http.StatusNoContent, status, string(body))
+	s.transmitter.Wait()
+	sets := receiver.received()
+	require.Len(t, sets, 1)
+	claims := s.verify(t, sets[0])
+	assert.Equal(t, map[string]any{"format": "opaque", "id": stream.ID}, claims["sub_id"])
+	assert.Equal(t, map[string]any{ssf.EventTypeVerification: map[string]any{"state": "abc"}}, claims["events"])
+}
+
+func TestTransmitter_Listen(t *testing.T) {
+	s := newTransmitter(t)
+	receiver := newReceiver(t)
+	s.createStream(t, receiver.URL, ssf.EventTypeSessionRevoked, ssf.EventTypeCredentialChange)
+
+	s.server.CodeFlow(t, s.server.RelyingParty(t))
+	sessions, err := s.server.Storage.(op.SessionStorage).ListSessions(s.server.Context(), optest.UserID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	_, err = op.LogoutSession(s.server.Context(), s.server.Provider, sessions[0].ID)
+	require.NoError(t, err)
+
+	s.transmitter.Wait()
+	sets := receiver.received()
+	require.Len(t, sets, 1)
+	claims := s.verify(t, sets[0])
+	assert.Equal(t, s.server.Issuer, claims["iss"])
+	assert.Equal(t, []any{optest.WebClientID}, claims["aud"])
+	assert.NotEmpty(t, claims["jti"])
+	assert.Equal(t, map[string]any{
+		"format":  "complex",
+		"user":    map[string]any{"format": "iss_sub", "iss": s.server.Issuer, "sub": optest.UserID},
+		"session": map[string]any{"format": "opaque", "id": sessions[0].ID},
+	}, claims["sub_id"])
+	events := claims["events"].(map[string]any)
+	require.Contains(t, events, ssf.EventTypeSessionRevoked)
+	assert.NotZero(t, events[ssf.EventTypeSessionRevoked].(map[string]any)["event_timestamp"])
+
+	op.PublishEvent(s.server.Context(), s.server.Provider, op.Event{
+		Type:    op.EventCredentialChange,
+		Subject: optest.UserID,
+		Details: map[string]any{"credential_type": "password", "change_type": "update"},
+	})
+	s.transmitter.Wait()
+	sets = receiver.received()
+	require.Len(t, sets, 1)
+	claims = s.verify(t, sets[0])
+	assert.Equal(t, map[string]any{"format": "iss_sub", "iss": s.server.Issuer, "sub": optest.UserID}, claims["sub_id"])
+	event := claims["events"].(map[string]any)[ssf.EventTypeCredentialChange].(map[string]any)
+	assert.Equal(t, "password", event["credential_type"])
+	assert.Equal(t, "update", event["change_type"])
+
+	// not requested by the stream
+	op.PublishEvent(s.server.Context(), s.server.Provider, op.Event{Type: op.EventTokenClaimsChange, Subject: optest.UserID})
+	// for other clients
+	op.PublishEvent(s.server.Context(), s.server.Provider, op.Event{Type: op.EventCredentialChange, Subject: optest.UserID, ClientIDs: []string{"other"}})
+	s.transmitter.Wait()
+	assert.Empty(t, receiver.received())
+}
+
+func TestTransmitter_retry(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		wantSets int
+	}{
+		{"retried", 2, 1},
+		{"max attempts", 3, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTransmitter(t)
+			receiver := newReceiver(t)
+			receiver.failures = tt.failures
+			stream := s.createStream(t, receiver.URL)
+
+			status, body := s.call(http.MethodPost, ssf.VerificationPath, `{"stream_id":"`+stream.ID+`"}`)
+			require.Equal(t, http.StatusNoContent, status, string(body))
+			s.transmitter.Wait()
+			assert.Len(t, receiver.received(), tt.wantSets)
+			assert.Equal(t, 3, receiver.requests)
+		})
+	}
+}
+
+func TestTransmitter_endpoint(t *testing.T) {
+	streams := ssf.NewMemoryStreamStorage()
+	s := newTransmitter(t, func(config *ssf.Config) {
+		config.Streams = streams
+		config.AllowPrivateEndpoints = false
+	})
+	tests := []struct {
+		endpoint   string
+		wantStatus int
+	}{
+		{"https://receiver.example.com/events", http.StatusCreated},
+		{"http://receiver.example.com/events", http.StatusBadRequest},
+		{"/events", http.StatusBadRequest},
+		{"https://localhost/events", http.StatusBadRequest},
+		{"https://api.localhost./events", http.StatusBadRequest},
+		{"https://127.0.0.1/events", http.StatusBadRequest},
+		{"https://[::1]/events", http.StatusBadRequest},
+		{"https://[::ffff:10.0.0.1]/events", http.StatusBadRequest},
+		{"https://192.168.1.1/events", http.StatusBadRequest},
+		{"https://169.254.169.254/latest/meta-data", http.StatusBadRequest},
+		{"https://0.0.0.0/events", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			request, err := json.Marshal(map[string]any{
+				"delivery": ssf.Delivery{Method: ssf.DeliveryMethodPush, EndpointURL: tt.endpoint},
+			})
+			require.NoError(t, err)
+			status, body := s.call(http.MethodPost, ssf.StreamPath, string(request))
+			assert.Equal(t, tt.wantStatus, status, string(body))
+		})
+	}
+
+	// connections to private addresses are refused, e.g. of host names resolving to them
+	receiver := newReceiver(t)
+	stream := &ssf.Stream{
+		ID:              "private",
+		Issuer:          s.server.Issuer,
+		Audience:        oidc.Audience{optest.WebClientID},
+		EventsDelivered: []string{ssf.EventTypeCredentialChange},
+		Delivery:        ssf.Delivery{Method: ssf.DeliveryMethodPush, EndpointURL: receiver.URL},
+		Receiver:        optest.WebClientID,
+	}
+	require.NoError(t, streams.CreateStream(s.server.Context(), stream))
+	status, body := s.call(http.MethodPost, ssf.VerificationPath, `{"stream_id":"`+stream.ID+`"}`)
+	require.Equal(t, http.StatusNoContent, status, string(body))
+	s.transmitter.Wait()
+	assert.Zero(t, receiver.requests)
+}
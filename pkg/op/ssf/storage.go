@@ -0,0 +1,122 @@
+package ssf
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// StreamStorage stores the streams of the receivers.
+// Errors for unknown streams must implement op.StorageNotFoundError.
+type StreamStorage interface {
+	CreateStream(ctx context.Context, stream *Stream) error
+	UpdateStream(ctx context.Context, stream *Stream) error
+	DeleteStream(ctx context.Context, receiver, id string) error
+	// Stream returns the stream of the receiver.
+	Stream(ctx context.Context, receiver, id string) (*Stream, error)
+	// Streams returns the streams of the receiver.
+	Streams(ctx context.Context, receiver string) ([]*Stream, error)
+	// EventStreams returns the streams of the issuer, which deliver the event type.
+	// If receivers is not empty, only the streams of these receivers are returned.
+	// It is called for every event of the OP, so it should be served by an index
+	// instead of loading all streams.
+	EventStreams(ctx context.Context, issuer, eventType string, receivers []string) ([]*Stream, error)
+}
+
+type streamNotFoundError struct{}
+
+func (streamNotFoundError) Error() string { return "stream not found" }
+func (streamNotFoundError) IsNotFound()   {}
+
+// MemoryStreamStorage is a [StreamStorage] keeping the streams in memory,
+// e.g. for tests or a single instance of the OP.
+type MemoryStreamStorage struct {
+	mu      sync.RWMutex
+	streams map[string]Stream
+}
+
+func NewMemoryStreamStorage() *MemoryStreamStorage {
+	return &MemoryStreamStorage{streams: make(map[string]Stream)}
+}
+
+func (s *MemoryStreamStorage) CreateStream(_ context.Context, stream *Stream) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.streams[stream.ID] = stream.clone()
+	return nil
+}
+
+func (s *MemoryStreamStorage) UpdateStream(_ context.Context, stream *Stream) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stored, ok := s.streams[stream.ID]; !ok || stored.Receiver != stream.Receiver {
+		return streamNotFoundError{}
+	}
+	s.streams[stream.ID] = stream.clone()
+	return nil
+}
+
+func (s *MemoryStreamStorage) DeleteStream(_ context.Context, receiver, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stored, ok := s.streams[id]; !ok || stored.Receiver != receiver {
+		return streamNotFoundError{}
+	}
+	delete(s.streams, id)
+	return nil
+}
+
+func (s *MemoryStreamStorage) Stream(_ context.Context, receiver, id string) (*Stream, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stored, ok := s.streams[id]
+	if !ok || stored.Receiver != receiver {
+		return nil, streamNotFoundError{}
+	}
+	stream := stored.clone()
+	return &stream, nil
+}
+
+func (s *MemoryStreamStorage) Streams(_ context.Context, receiver string) ([]*Stream, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	streams := make([]*Stream, 0, len(s.streams))
+	for _, stored := range s.streams {
+		if stored.Receiver == receiver {
+			stream := stored.clone()
+			streams = append(streams, &stream)
+		}
+	}
+	sortStreams(streams)
+	return streams, nil
+}
+
+func (s *MemoryStreamStorage) EventStreams(_ context.Context, issuer, eventType string, receivers []string) ([]*Stream, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var streams []*Stream
+	for _, stored := range s.streams {
+		if stored.Issuer != issuer || !slices.Contains(stored.EventsDelivered, eventType) {
+			continue
+		}
+		if len(receivers) > 0 && !slices.Contains(receivers, stored.Receiver) {
+			continue
+		}
+		stream := stored.clone()
+		streams = append(streams, &stream)
+	}
+	sortStreams(streams)
+	return streams, nil
+}
+
+func sortStreams(streams []*Stream) {
+	slices.SortFunc(streams, func(a, b *Stream) int {
+		switch {
+		case a.ID < b.ID:
+			return -1
+		case a.ID > b.ID:
+			return 1
+		}
+		return 0
+	})
+}
@@ -77,6 +77,44 @@ type CanTerminateSessionFromRequest interface {
 	TerminateSessionFromRequest(ctx context.Context, endSessionRequest *EndSessionRequest) (string, error)
 }
 
+// CanConfirmLogout is an optional additional interface that may be implemented by
+// implementors of Storage to protect users against CSRF logout.
+// LogoutConfirmationURI is only called when the end_session request did not contain a valid id_token_hint,
+// meaning the OP cannot tell whether the logout was really initiated by the user's RP.
+// The returned uri (e.g. a UI where the user has to confirm the logout) will be used for redirection
+// instead of terminating the session; the confirmation page is then responsible for terminating the session
+// and redirecting to EndSessionRequest.RedirectURI.
+// An empty uri skips the confirmation and terminates the session directly.
+type CanConfirmLogout interface {
+	LogoutConfirmationURI(ctx context.Context, endSessionRequest *EndSessionRequest) (string, error)
+}
+
+// CanRevokeTokenFamily is an optional additional interface that may be implemented by
+// implementors of Storage as an alternative to RevokeToken of the AuthStorage.
+// RFC 7009 section 2.1 requires that revoking a refresh token also invalidates all access tokens
+// issued based on the same authorization grant. Implementations must therefore revoke the complete
+// token family of a refresh token, and may do the same for access tokens when
+// RevokeTokenFamilyRequest.RevokeRefreshToken is set.
+// Only tokens the OP could identify are passed, unknown tokens never reach the Storage.
+type CanRevokeTokenFamily interface {
+	RevokeTokenFamily(ctx context.Context, request *RevokeTokenFamilyRequest) *oidc.Error
+}
+
+// RevokeTokenFamilyRequest describes a token to be revoked through [CanRevokeTokenFamily].
+type RevokeTokenFamilyRequest struct {
+	// TokenID is the id of the access token or refresh token,
+	// as returned by CreateAccessToken or GetRefreshTokenInfo.
+	TokenID  string
+	UserID   string
+	ClientID string
+	// TokenType is either [oidc.AccessTokenType] or [oidc.RefreshTokenType].
+	TokenType oidc.TokenType
+	// RevokeRefreshToken is set for access tokens when the refresh token
+	// they were issued with must be revoked as well,
+	// see Config.RevokeRefreshTokenWithAccessToken.
+	RevokeRefreshToken bool
+}
+
 type ClientCredentialsStorage interface {
 	ClientCredentials(ctx context.Context, clientID, clientSecret string) (Client, error)
 	ClientCredentialsTokenRequest(ctx context.Context, clientID string, scopes []string) (TokenRequest, error)
@@ -124,8 +162,8 @@ type TokenExchangeTokensVerifierStorage interface {
 var ErrInvalidRefreshToken = errors.New("invalid_refresh_token")
 
 type OPStorage interface {
-	// GetClientByClientID loads a Client. The returned Client is never cached and is only used to
-	// handle the current request.
+	// GetClientByClientID loads a Client. The returned Client is only used to
+	// handle the current request, unless clients are cached by [WithCache].
 	GetClientByClientID(ctx context.Context, clientID string) (Client, error)
 	AuthorizeClientIDSecret(ctx context.Context, clientID, clientSecret string) error
 	// SetUserinfoFromScopes is deprecated and should have an empty implementation for now.
@@ -177,8 +215,15 @@ type EndSessionRequest struct {
 	ClientID          string
 	IDTokenHintClaims *oidc.IDTokenClaims
 	RedirectURI       string
-	LogoutHint        string
-	UILocales         []language.Tag
+	// State is the state passed by the client, which is only set
+	// if a valid post_logout_redirect_uri was requested.
+	// It is already appended to RedirectURI.
+	State      string
+	LogoutHint string
+	UILocales  []language.Tag
+	// SessionID is the sid claim of the id_token_hint, if present.
+	// It identifies the session of the user to be terminated.
+	SessionID string
 }
 
 var ErrDuplicateUserCode = errors.New("user code already exists")
@@ -0,0 +1,68 @@
+package op
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+type storageTimeoutKey struct{}
+
+// ContextWithStorageTimeout returns a context, which bounds every call to the Storage
+// made with it by the timeout.
+// It is set for all requests of the OP by [WithStorageTimeout]
+// and can be used when calling the functions of this package directly.
+func ContextWithStorageTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, storageTimeoutKey{}, timeout)
+}
+
+// storageContext returns the context for a call to the Storage,
+// bounded by the timeout of [ContextWithStorageTimeout], if set.
+// The returned cancel function must be called after the call.
+func storageContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout, ok := ctx.Value(storageTimeoutKey{}).(time.Duration)
+	if !ok || timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// WithStorageTimeout sets a deadline on the context of every call to the Storage.
+// All calls already carry the context of the incoming request,
+// so they are canceled when the client disconnects.
+// The timeout additionally bounds slow calls, e.g. hanging database queries.
+func WithStorageTimeout(timeout time.Duration) Option {
+	return func(o *Provider) error {
+		if timeout <= 0 {
+			return errors.New("storage timeout must be positive")
+		}
+		o.storageTimeout = timeout
+		return nil
+	}
+}
+
+// StorageTimeout returns the timeout set by [WithStorageTimeout].
+func (o *Provider) StorageTimeout() time.Duration {
+	return o.storageTimeout
+}
+
+// storageTimeoutProvider is implemented by the [Provider]
+// to pass the timeout of [WithStorageTimeout] to the requests.
+type storageTimeoutProvider interface {
+	StorageTimeout() time.Duration
+}
+
+// storageTimeoutInterceptor sets the timeout of the provider on the context of the requests.
+func storageTimeoutInterceptor(v any) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		p, ok := v.(storageTimeoutProvider)
+		if !ok || p.StorageTimeout() <= 0 {
+			return next
+		}
+		timeout := p.StorageTimeout()
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(ContextWithStorageTimeout(r.Context(), timeout)))
+		})
+	}
+}
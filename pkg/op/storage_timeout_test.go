@@ -0,0 +1,54 @@
+package op
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+func TestStorageContext(t *testing.T) {
+	ctx, cancel := storageContext(context.Background())
+	cancel()
+	_, ok := ctx.Deadline()
+	assert.False(t, ok, "no deadline without timeout")
+	assert.NoError(t, ctx.Err(), "cancel is a no-op without timeout")
+
+	before := time.Now()
+	ctx, cancel = storageContext(ContextWithStorageTimeout(context.Background(), time.Second))
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	assert.False(t, deadline.Before(before.Add(time.Second)))
+	assert.True(t, deadline.Before(before.Add(2*time.Second)))
+	cancel()
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+}
+
+func TestWithStorageTimeout(t *testing.T) {
+	provider := &Provider{}
+	require.Error(t, WithStorageTimeout(0)(provider))
+	require.NoError(t, WithStorageTimeout(time.Second)(provider))
+	assert.Equal(t, time.Second, provider.StorageTimeout())
+
+	var deadline time.Time
+	handler := storageTimeoutInterceptor(provider)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := storageContext(r.Context())
+		defer cancel()
+		deadline, _ = ctx.Deadline()
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.False(t, deadline.IsZero(), "storage calls of the request have a deadline")
+}
+
+func TestSignClaims_canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := signClaims(ctx, nil, map[string]any{"sub": "subject"}, nil, oidc.TypeJWT)
+	assert.ErrorIs(t, err, context.Canceled)
+}
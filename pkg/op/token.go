@@ -5,7 +5,6 @@ import (
 	"slices"
 	"time"
 
-	"github.com/zitadel/oidc/v3/pkg/crypto"
 	"github.com/zitadel/oidc/v3/pkg/oidc"
 )
 
@@ -40,14 +39,16 @@ func CreateTokenResponse(ctx context.Context, request IDTokenRequest, client Cli
 			return nil, err
 		}
 	}
-	idToken, err := CreateIDToken(ctx, IssuerFromContext(ctx), request, client.IDTokenLifetime(), accessToken, code, creator.Storage(), client)
+	idToken, err := createIDToken(ctx, IssuerFromContext(ctx), request, client.IDTokenLifetime(), accessToken, code, creator.Storage(), client, idTokenClaimsHooksFrom(creator), clientKeySetCacheFrom(creator), signerCacheFrom(creator))
 	if err != nil {
 		return nil, err
 	}
 
 	var state string
 	if authRequest, ok := request.(AuthRequest); ok {
-		err = creator.Storage().DeleteAuthRequest(ctx, authRequest.GetID())
+		storageCtx, cancel := storageContext(ctx)
+		defer cancel()
+		err = creator.Storage().DeleteAuthRequest(storageCtx, authRequest.GetID())
 		if err != nil {
 			return nil, err
 		}
@@ -57,6 +58,11 @@ func CreateTokenResponse(ctx context.Context, request IDTokenRequest, client Cli
 		}
 	}
 
+	extra, err := tokenResponseExtra(ctx, creator, client, request, newRefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
 	exp := uint64(validity.Seconds())
 	return &oidc.AccessTokenResponse{
 		AccessToken:  accessToken,
@@ -66,31 +72,35 @@ func CreateTokenResponse(ctx context.Context, request IDTokenRequest, client Cli
 		ExpiresIn:    exp,
 		State:        state,
 		Scope:        request.GetScopes(),
+		Extra:        extra,
 	}, nil
 }
 
-// createTokens delegates token creation to the appropriate storage method based on
-// the request type and requirements. It returns an access token ID and expiration
+// createTokens delegates token creation to the appropriate storage method,
+// typically based on needsRefreshToken(). It returns an access token ID and expiration
 // in all cases, but the refresh token handling varies:
-//   - When needsRefreshToken() returns true: calls CreateAccessAndRefreshTokens,
+//   - When withRefreshToken is true: calls CreateAccessAndRefreshTokens,
 //     which returns both tokens. The newRefreshToken will contain the actual token value.
-//   - When needsRefreshToken() returns false: calls CreateAccessToken only.
+//   - When withRefreshToken is false: calls CreateAccessToken only.
 //     The newRefreshToken will be an empty string in this case.
-func createTokens(ctx context.Context, tokenRequest TokenRequest, storage Storage, refreshToken string, client AccessTokenClient) (id, newRefreshToken string, exp time.Time, err error) {
+func createTokens(ctx context.Context, tokenRequest TokenRequest, storage Storage, refreshToken string, withRefreshToken bool) (id, newRefreshToken string, exp time.Time, err error) {
 	ctx, span := Tracer.Start(ctx, "createTokens")
 	defer span.End()
 
-	if needsRefreshToken(tokenRequest, client) {
-		return storage.CreateAccessAndRefreshTokens(ctx, tokenRequest, refreshToken)
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	if withRefreshToken {
+		return storage.CreateAccessAndRefreshTokens(storageCtx, tokenRequest, refreshToken)
 	}
-	id, exp, err = storage.CreateAccessToken(ctx, tokenRequest)
+	id, exp, err = storage.CreateAccessToken(storageCtx, tokenRequest)
 	return id, "", exp, err
 }
 
 func needsRefreshToken(tokenRequest TokenRequest, client AccessTokenClient) bool {
 	switch req := tokenRequest.(type) {
 	case AuthRequest:
-		return slices.Contains(req.GetScopes(), oidc.ScopeOfflineAccess) && req.GetResponseType() == oidc.ResponseTypeCode && ValidateGrantType(client, oidc.GrantTypeRefreshToken)
+		responseType := req.GetResponseType()
+		return slices.Contains(req.GetScopes(), oidc.ScopeOfflineAccess) && (responseType == oidc.ResponseTypeCode || responseType.IsHybrid()) && ValidateGrantType(client, oidc.GrantTypeRefreshToken)
 	case TokenExchangeRequest:
 		return req.GetRequestedTokenType() == oidc.RefreshTokenType
 	case RefreshTokenRequest:
@@ -112,20 +122,36 @@ func needsRefreshToken(tokenRequest TokenRequest, client AccessTokenClient) bool
 //
 // The function returns both tokens to support all flows with a single signature.
 func CreateAccessToken(ctx context.Context, tokenRequest TokenRequest, accessTokenType AccessTokenType, creator TokenCreator, client AccessTokenClient, refreshToken string) (accessToken, newRefreshToken string, validity time.Duration, err error) {
+	return createAccessToken(ctx, tokenRequest, accessTokenType, creator, client, refreshToken, needsRefreshToken(tokenRequest, client))
+}
+
+func createAccessToken(ctx context.Context, tokenRequest TokenRequest, accessTokenType AccessTokenType, creator TokenCreator, client AccessTokenClient, refreshToken string, withRefreshToken bool) (accessToken, newRefreshToken string, validity time.Duration, err error) {
 	ctx, span := Tracer.Start(ctx, "CreateAccessToken")
 	defer span.End()
 
-	id, newRefreshToken, exp, err := createTokens(ctx, tokenRequest, creator.Storage(), refreshToken, client)
+	ctx, rotate := refreshTokenRotation(ctx, creator, client, refreshToken)
+	id, newRefreshToken, exp, err := createTokens(ctx, tokenRequest, creator.Storage(), refreshToken, withRefreshToken)
 	if err != nil {
 		return "", "", 0, err
 	}
+	if rotate && newRefreshToken != "" && newRefreshToken == refreshToken {
+		return "", "", 0, oidc.ErrServerError().WithDescription("the refresh token was not rotated")
+	}
+	newRefreshToken = formatRefreshToken(creator, newRefreshToken)
 	var clockSkew time.Duration
 	if client != nil {
 		clockSkew = client.ClockSkew()
 	}
-	validity = exp.Add(clockSkew).Sub(time.Now().UTC())
+	validity = exp.Add(clockSkew).Sub(Now(ctx).UTC())
+	if format := accessTokenFormatOf(client); format != "" {
+		accessToken, err = createFormattedAccessToken(ctx, format, tokenRequest, exp, id, client, creator)
+		return accessToken, newRefreshToken, validity, err
+	}
 	if accessTokenType == AccessTokenTypeJWT {
-		accessToken, err = CreateJWT(ctx, IssuerFromContext(ctx), tokenRequest, exp, id, client, creator.Storage())
+		accessToken, err = createJWT(ctx, IssuerFromContext(ctx), tokenRequest, exp, id, client, creator.Storage(), accessTokenClaimsHooksFrom(creator), signerCacheFrom(creator))
+		return accessToken, newRefreshToken, validity, err
+	}
+	if accessToken, ok, err := createOpaqueAccessToken(ctx, creator, id, tokenRequest.GetSubject(), exp); ok {
 		return accessToken, newRefreshToken, validity, err
 	}
 	_, span = Tracer.Start(ctx, "CreateBearerToken")
@@ -143,10 +169,32 @@ type TokenActorRequest interface {
 }
 
 func CreateJWT(ctx context.Context, issuer string, tokenRequest TokenRequest, exp time.Time, id string, client AccessTokenClient, storage Storage) (string, error) {
+	return createJWT(ctx, issuer, tokenRequest, exp, id, client, storage, nil, nil)
+}
+
+func createJWT(ctx context.Context, issuer string, tokenRequest TokenRequest, exp time.Time, id string, client AccessTokenClient, storage Storage, hooks []AccessTokenClaimsHook, signers *signerCache) (string, error) {
 	ctx, span := Tracer.Start(ctx, "CreateJWT")
 	defer span.End()
 
+	claims, err := createAccessTokenClaims(ctx, issuer, tokenRequest, exp, id, client, storage, hooks)
+	if err != nil {
+		return "", err
+	}
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	signingKey, err := storage.SigningKey(storageCtx)
+	if err != nil {
+		return "", err
+	}
+	return signClaims(ctx, signers, claims, signingKey, oidc.TypeAccessToken)
+}
+
+// createAccessTokenClaims returns the claims of an access token, including the private claims
+// of the storage and the modifications of the hooks, independent of its format.
+func createAccessTokenClaims(ctx context.Context, issuer string, tokenRequest TokenRequest, exp time.Time, id string, client AccessTokenClient, storage Storage, hooks []AccessTokenClaimsHook) (*oidc.AccessTokenClaims, error) {
 	claims := oidc.NewAccessTokenClaims(issuer, tokenRequest.GetSubject(), tokenRequest.GetAudience(), exp, id, client.GetID(), client.ClockSkew())
+	issuedAt := oidc.FromTime(Now(ctx).UTC().Add(-client.ClockSkew()))
+	claims.IssuedAt, claims.NotBefore = issuedAt, issuedAt
 	if client != nil {
 		restrictedScopes := client.RestrictAdditionalAccessTokenScopes()(tokenRequest.GetScopes())
 
@@ -157,36 +205,38 @@ func CreateJWT(ctx context.Context, issuer string, tokenRequest TokenRequest, ex
 
 		tokenExchangeRequest, okReq := tokenRequest.(TokenExchangeRequest)
 		teStorage, okStorage := storage.(TokenExchangeStorage)
+		storageCtx, cancel := storageContext(ctx)
+		defer cancel()
 		if okReq && okStorage {
 			privateClaims, err = teStorage.GetPrivateClaimsFromTokenExchangeRequest(
-				ctx,
+				storageCtx,
 				tokenExchangeRequest,
 			)
 		} else {
 			if fromRequest, ok := storage.(CanGetPrivateClaimsFromRequest); ok {
-				privateClaims, err = fromRequest.GetPrivateClaimsFromRequest(ctx, tokenRequest, removeUserinfoScopes(restrictedScopes))
+				privateClaims, err = fromRequest.GetPrivateClaimsFromRequest(storageCtx, tokenRequest, removeUserinfoScopes(restrictedScopes))
 			} else {
-				privateClaims, err = storage.GetPrivateClaimsFromScopes(ctx, tokenRequest.GetSubject(), client.GetID(), removeUserinfoScopes(restrictedScopes))
+				privateClaims, err = storage.GetPrivateClaimsFromScopes(storageCtx, tokenRequest.GetSubject(), client.GetID(), removeUserinfoScopes(restrictedScopes))
 			}
 		}
 
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		claims.Claims = privateClaims
 	}
 	if actorReq, ok := tokenRequest.(TokenActorRequest); ok {
 		claims.Actor = actorReq.GetActor()
 	}
-	signingKey, err := storage.SigningKey(ctx)
-	if err != nil {
-		return "", err
-	}
-	signer, err := SignerFromKey(signingKey)
-	if err != nil {
-		return "", err
+	if len(hooks) > 0 {
+		hc := newClaimsHookContext(client, tokenRequest)
+		for _, hook := range hooks {
+			if err := hook(ctx, claims, hc); err != nil {
+				return nil, err
+			}
+		}
 	}
-	return crypto.Sign(claims, signer)
+	return claims, nil
 }
 
 type IDTokenRequest interface {
@@ -199,22 +249,28 @@ type IDTokenRequest interface {
 }
 
 func CreateIDToken(ctx context.Context, issuer string, request IDTokenRequest, validity time.Duration, accessToken, code string, storage Storage, client Client) (string, error) {
+	return createIDToken(ctx, issuer, request, validity, accessToken, code, storage, client, nil, nil, nil)
+}
+
+func createIDToken(ctx context.Context, issuer string, request IDTokenRequest, validity time.Duration, accessToken, code string, storage Storage, client Client, hooks []IDTokenClaimsHook, clientKeys *ClientKeySetCache, signers *signerCache) (string, error) {
 	ctx, span := Tracer.Start(ctx, "CreateIDToken")
 	defer span.End()
 
-	exp := time.Now().UTC().Add(client.ClockSkew()).Add(validity)
+	now := Now(ctx).UTC()
+	exp := now.Add(client.ClockSkew()).Add(validity)
 	var acr, nonce string
 	if authRequest, ok := request.(AuthRequest); ok {
 		acr = authRequest.GetACR()
 		nonce = authRequest.GetNonce()
 	}
 	claims := oidc.NewIDTokenClaims(issuer, request.GetSubject(), request.GetAudience(), exp, request.GetAuthTime(), nonce, acr, request.GetAMR(), request.GetClientID(), client.ClockSkew())
+	claims.IssuedAt = oidc.FromTime(now.Add(-client.ClockSkew()))
 	if actorReq, ok := request.(TokenActorRequest); ok {
 		claims.Actor = actorReq.GetActor()
 	}
 
 	scopes := client.RestrictAdditionalIdTokenScopes()(request.GetScopes())
-	signingKey, err := storage.SigningKey(ctx)
+	signingKey, err := idTokenSigningKey(ctx, storage, client)
 	if err != nil {
 		return "", err
 	}
@@ -231,21 +287,23 @@ func CreateIDToken(ctx context.Context, issuer string, request IDTokenRequest, v
 
 	tokenExchangeRequest, okReq := request.(TokenExchangeRequest)
 	teStorage, okStorage := storage.(TokenExchangeStorage)
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
 	if okReq && okStorage {
 		userInfo := new(oidc.UserInfo)
-		err := teStorage.SetUserinfoFromTokenExchangeRequest(ctx, userInfo, tokenExchangeRequest)
+		err := teStorage.SetUserinfoFromTokenExchangeRequest(storageCtx, userInfo, tokenExchangeRequest)
 		if err != nil {
 			return "", err
 		}
 		claims.SetUserInfo(userInfo)
 	} else if len(scopes) > 0 {
 		userInfo := new(oidc.UserInfo)
-		err := storage.SetUserinfoFromScopes(ctx, userInfo, request.GetSubject(), request.GetClientID(), scopes)
+		err := storage.SetUserinfoFromScopes(storageCtx, userInfo, request.GetSubject(), request.GetClientID(), scopes)
 		if err != nil {
 			return "", err
 		}
 		if fromRequest, ok := storage.(CanSetUserinfoFromRequest); ok {
-			err := fromRequest.SetUserinfoFromRequest(ctx, userInfo, request, scopes)
+			err := fromRequest.SetUserinfoFromRequest(storageCtx, userInfo, request, scopes)
 			if err != nil {
 				return "", err
 			}
@@ -259,11 +317,19 @@ func CreateIDToken(ctx context.Context, issuer string, request IDTokenRequest, v
 		}
 		claims.CodeHash = codeHash
 	}
-	signer, err := SignerFromKey(signingKey)
+	if len(hooks) > 0 {
+		hc := newClaimsHookContext(client, request)
+		for _, hook := range hooks {
+			if err := hook(ctx, claims, hc); err != nil {
+				return "", err
+			}
+		}
+	}
+	idToken, err := signClaims(ctx, signers, claims, signingKey, oidc.TypeJWT)
 	if err != nil {
 		return "", err
 	}
-	return crypto.Sign(claims, signer)
+	return encryptIDToken(ctx, idToken, client, clientKeys)
 }
 
 func removeUserinfoScopes(scopes []string) []string {
@@ -84,7 +84,9 @@ func ValidateClientCredentialsRequest(ctx context.Context, request *oidc.ClientC
 		return nil, nil, err
 	}
 
-	tokenRequest, err := storage.ClientCredentialsTokenRequest(ctx, request.ClientID, request.Scope)
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	tokenRequest, err := storage.ClientCredentialsTokenRequest(storageCtx, request.ClientID, request.Scope)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -96,7 +98,9 @@ func AuthorizeClientCredentialsClient(ctx context.Context, request *oidc.ClientC
 	ctx, span := Tracer.Start(ctx, "AuthorizeClientCredentialsClient")
 	defer span.End()
 
-	client, err := storage.ClientCredentials(ctx, request.ClientID, request.ClientSecret)
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	client, err := storage.ClientCredentials(storageCtx, request.ClientID, request.ClientSecret)
 	if err != nil {
 		return nil, oidc.ErrInvalidClient().WithParent(err)
 	}
@@ -116,11 +120,16 @@ func CreateClientCredentialsTokenResponse(ctx context.Context, tokenRequest Toke
 	if err != nil {
 		return nil, err
 	}
+	extra, err := tokenResponseExtra(ctx, creator, client, tokenRequest, "")
+	if err != nil {
+		return nil, err
+	}
 
 	return &oidc.AccessTokenResponse{
 		AccessToken: accessToken,
 		TokenType:   oidc.BearerToken,
 		ExpiresIn:   uint64(validity.Seconds()),
 		Scope:       tokenRequest.GetScopes(),
+		Extra:       extra,
 	}, nil
 }
@@ -29,6 +29,10 @@ func CodeExchange(w http.ResponseWriter, r *http.Request, exchanger Exchanger) {
 		RequestError(w, r, err, nil)
 		return
 	}
+	if err = checkGrantPolicies(r.Context(), exchanger, newGrantRequest(r, oidc.GrantTypeCode, client, authReq)); err != nil {
+		RequestError(w, r, err, nil)
+		return
+	}
 	resp, err := CreateTokenResponse(r.Context(), authReq, client, exchanger, true, tokenReq.Code, "")
 	if err != nil {
 		RequestError(w, r, err, nil)
@@ -86,6 +90,13 @@ func AuthorizeCodeClient(ctx context.Context, tokenReq *oidc.AccessTokenRequest,
 		return nil, nil, err
 	}
 
+	if ac, ok := authenticatedClientFromContext(ctx); ok {
+		if ac.method == oidc.AuthMethodNone && codeChallenge == nil {
+			return nil, nil, oidc.ErrInvalidRequest().WithDescription("PKCE required")
+		}
+		return request, ac.client, nil
+	}
+
 	if tokenReq.ClientAssertionType == oidc.ClientAssertionTypeJWTAssertion {
 		jwtExchanger, ok := exchanger.(JWTAuthorizationGrantExchanger)
 		if !ok || !exchanger.AuthMethodPrivateKeyJWTSupported() {
@@ -98,7 +109,7 @@ func AuthorizeCodeClient(ctx context.Context, tokenReq *oidc.AccessTokenRequest,
 		return request, client, err
 	}
 
-	client, err = exchanger.Storage().GetClientByClientID(ctx, tokenReq.ClientID)
+	client, err = getClientByClientID(ctx, cacheFrom(exchanger), exchanger.Storage(), tokenReq.ClientID)
 	if err != nil {
 		return nil, nil, oidc.ErrInvalidClient().WithParent(err)
 	}
@@ -127,7 +138,9 @@ func AuthRequestByCode(ctx context.Context, storage Storage, code string) (AuthR
 	ctx, span := Tracer.Start(ctx, "AuthRequestByCode")
 	defer span.End()
 
-	authReq, err := storage.AuthRequestByCode(ctx, code)
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	authReq, err := storage.AuthRequestByCode(storageCtx, code)
 	if err != nil {
 		return nil, oidc.ErrInvalidGrant().WithDescription("invalid code").WithParent(err)
 	}
@@ -281,12 +281,14 @@ func CreateTokenExchangeRequest(
 		authTime:           time.Now(),
 	}
 
-	err := teStorage.ValidateTokenExchangeRequest(ctx, req)
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	err := teStorage.ValidateTokenExchangeRequest(storageCtx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	err = teStorage.CreateTokenExchangeRequest(ctx, req)
+	err = teStorage.CreateTokenExchangeRequest(storageCtx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -313,7 +315,10 @@ func GetTokenIDAndSubjectFromToken(
 		}
 		claims = accessTokenClaims.Claims
 	case oidc.RefreshTokenType:
-		refreshTokenRequest, err := exchanger.Storage().TokenRequestByRefreshToken(ctx, token)
+		storageCtx, cancel := storageContext(ctx)
+		defer cancel()
+		token = parseRefreshToken(exchanger, token)
+		refreshTokenRequest, err := exchanger.Storage().TokenRequestByRefreshToken(storageCtx, token)
 		if err != nil {
 			break
 		}
@@ -354,11 +359,17 @@ func AuthorizeTokenExchangeClient(ctx context.Context, clientID, clientSecret st
 	ctx, span := Tracer.Start(ctx, "AuthorizeTokenExchangeClient")
 	defer span.End()
 
+	if ac, ok := authenticatedClientFromContext(ctx); ok {
+		if ac.method == oidc.AuthMethodNone {
+			return nil, oidc.ErrInvalidClient().WithDescription("client must be authenticated")
+		}
+		return ac.client, nil
+	}
 	if err := AuthorizeClientIDSecret(ctx, clientID, clientSecret, exchanger.Storage()); err != nil {
 		return nil, err
 	}
 
-	client, err = exchanger.Storage().GetClientByClientID(ctx, clientID)
+	client, err = getClientByClientID(ctx, cacheFrom(exchanger), exchanger.Storage(), clientID)
 	if err != nil {
 		return nil, oidc.ErrInvalidClient().WithParent(err)
 	}
@@ -389,7 +400,7 @@ func CreateTokenExchangeResponse(
 
 		tokenType = oidc.BearerToken
 	case oidc.IDTokenType:
-		token, err = CreateIDToken(ctx, IssuerFromContext(ctx), tokenExchangeRequest, client.IDTokenLifetime(), "", "", creator.Storage(), client)
+		token, err = createIDToken(ctx, IssuerFromContext(ctx), tokenExchangeRequest, client.IDTokenLifetime(), "", "", creator.Storage(), client, idTokenClaimsHooksFrom(creator), clientKeySetCacheFrom(creator), signerCacheFrom(creator))
 		if err != nil {
 			return nil, err
 		}
@@ -403,6 +414,11 @@ func CreateTokenExchangeResponse(
 		oidc.ErrInvalidRequest().WithDescription("requested_token_type is invalid")
 	}
 
+	extra, err := tokenResponseExtra(ctx, creator, client, tokenExchangeRequest, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
 	exp := uint64(validity.Seconds())
 	return &oidc.TokenExchangeResponse{
 		AccessToken:     token,
@@ -411,10 +427,18 @@ func CreateTokenExchangeResponse(
 		ExpiresIn:       exp,
 		RefreshToken:    refreshToken,
 		Scopes:          tokenExchangeRequest.GetScopes(),
+		Extra:           extra,
 	}, nil
 }
 
 func getTokenIDAndClaims(ctx context.Context, userinfoProvider UserinfoProvider, accessToken string) (string, string, *oidc.AccessTokenClaims, bool) {
+	if claims, ok := decodeFormattedAccessToken(ctx, userinfoProvider, accessToken); ok {
+		return claims.JWTID, claims.Subject, claims, true
+	}
+	accessToken, tokenID, subject, ok := parseOpaqueAccessToken(ctx, userinfoProvider, accessToken)
+	if ok {
+		return tokenID, subject, nil, true
+	}
 	tokenIDSubject, err := userinfoProvider.Crypto().Decrypt(accessToken)
 	if err == nil {
 		splitToken := strings.Split(tokenIDSubject, ":")
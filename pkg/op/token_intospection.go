@@ -3,7 +3,10 @@ package op
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"net/http"
+	"slices"
+	"strings"
 
 	httphelper "github.com/zitadel/oidc/v3/pkg/http"
 	"github.com/zitadel/oidc/v3/pkg/oidc"
@@ -32,24 +35,115 @@ func Introspect(w http.ResponseWriter, r *http.Request, introspector Introspecto
 	defer span.End()
 	r = r.WithContext(ctx)
 
-	response := new(oidc.IntrospectionResponse)
 	token, clientID, err := ParseTokenIntrospectionRequest(r, introspector)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		writeIntrospectionError(w, r, err)
 		return
 	}
-	tokenID, subject, ok := getTokenIDAndSubject(r.Context(), introspector, token)
+	httphelper.MarshalJSON(w, introspectToken(r.Context(), introspector, token, clientID))
+}
+
+func writeIntrospectionError(w http.ResponseWriter, _ *http.Request, err error) {
+	http.Error(w, err.Error(), http.StatusUnauthorized)
+}
+
+// AccessTokenRevocationCheck reports if a JWT access token, which was already verified, has been revoked.
+// It is typically backed by a deny-list of token IDs (jti) and must be fast, as it is
+// called for every introspection of a JWT access token.
+type AccessTokenRevocationCheck func(ctx context.Context, claims *oidc.AccessTokenClaims) (revoked bool, err error)
+
+// jwtIntrospector is implemented by the [Provider]
+// to report whether JWT access tokens are introspected locally.
+// See [WithJWTAccessTokenIntrospection].
+type jwtIntrospector interface {
+	JWTAccessTokenIntrospection() (enabled bool, revocationCheck AccessTokenRevocationCheck)
+}
+
+// introspectToken builds the introspection response for the token.
+// Invalid tokens, or tokens the client is not allowed to introspect,
+// result in an inactive response.
+func introspectToken(ctx context.Context, introspector Introspector, token, clientID string) *oidc.IntrospectionResponse {
+	ctx, span := Tracer.Start(ctx, "introspectToken")
+	defer span.End()
+
+	cache := cacheFrom(introspector)
+	if response := cache.introspection(ctx, token, clientID); response != nil {
+		return response
+	}
+	response := introspectTokenUncached(ctx, introspector, token, clientID)
+	cache.setIntrospection(ctx, token, clientID, response)
+	return response
+}
+
+func introspectTokenUncached(ctx context.Context, introspector Introspector, token, clientID string) *oidc.IntrospectionResponse {
+	if local, ok := introspector.(jwtIntrospector); ok {
+		if enabled, revocationCheck := local.JWTAccessTokenIntrospection(); enabled && isJWT(token) {
+			return introspectJWTAccessToken(ctx, introspector, revocationCheck, token, clientID)
+		}
+	}
+
+	response := new(oidc.IntrospectionResponse)
+	tokenID, subject, ok := getTokenIDAndSubject(ctx, introspector, token)
 	if !ok {
-		httphelper.MarshalJSON(w, response)
-		return
+		return response
 	}
-	err = introspector.Storage().SetIntrospectionFromToken(r.Context(), response, tokenID, subject, clientID)
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	err := introspector.Storage().SetIntrospectionFromToken(storageCtx, response, tokenID, subject, clientID)
 	if err != nil {
-		httphelper.MarshalJSON(w, response)
-		return
+		return response
+	}
+	response.Active = true
+	return response
+}
+
+// introspectJWTAccessToken verifies the signature, issuer and expiration of a JWT access token
+// and builds the response from its claims, without calling the Storage.
+// The requesting client must be part of the token's audience or be the client the token was issued to.
+func introspectJWTAccessToken(ctx context.Context, introspector Introspector, revocationCheck AccessTokenRevocationCheck, token, clientID string) *oidc.IntrospectionResponse {
+	response := new(oidc.IntrospectionResponse)
+	claims, err := VerifyAccessToken[*oidc.AccessTokenClaims](ctx, token, introspector.AccessTokenVerifier(ctx))
+	if err != nil {
+		return response
+	}
+	if claims.ClientID != clientID && !slices.Contains(claims.Audience, clientID) {
+		return response
+	}
+	if revocationCheck != nil {
+		revoked, err := revocationCheck(ctx, claims)
+		if err != nil {
+			slog.ErrorContext(ctx, "access token revocation check", "error", err, "jti", claims.JWTID)
+			return response
+		}
+		if revoked {
+			return response
+		}
 	}
 	response.Active = true
-	httphelper.MarshalJSON(w, response)
+	response.Scope = claims.Scopes
+	response.ClientID = claims.ClientID
+	response.TokenType = oidc.BearerToken
+	if claims.Confirmation != nil && claims.Confirmation.JWKThumbprint != "" {
+		response.TokenType = "DPoP"
+	}
+	response.Expiration = claims.Expiration
+	response.IssuedAt = claims.IssuedAt
+	response.AuthTime = claims.AuthTime
+	response.NotBefore = claims.NotBefore
+	response.Subject = claims.Subject
+	response.Audience = claims.Audience
+	response.AuthenticationMethodsReferences = claims.AuthenticationMethodsReferences
+	response.Issuer = claims.Issuer
+	response.JWTID = claims.JWTID
+	response.Actor = claims.Actor
+	response.Confirmation = claims.Confirmation
+	response.Claims = claims.Claims
+	return response
+}
+
+// isJWT reports if the token has the form of a compact serialized JWS.
+func isJWT(token string) bool {
+	return strings.Count(token, ".") == 2
 }
 
 func ParseTokenIntrospectionRequest(r *http.Request, introspector Introspector) (token, clientID string, err error) {
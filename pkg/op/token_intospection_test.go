@@ -0,0 +1,103 @@
+package op_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/example/server/storage"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+)
+
+func TestJWTAccessTokenIntrospection(t *testing.T) {
+	var revoked bool
+	s := storage.NewStorage(storage.NewUserStore(testIssuer))
+	keySet := &op.OpenIDKeySet{s}
+	provider, err := op.NewOpenIDProvider(testIssuer, testConfig, s,
+		op.WithAllowInsecure(),
+		op.WithAccessTokenKeySet(keySet),
+		op.WithAccessTokenVerifierOpts(op.WithAccessTokenTypes(oidc.TypeAccessToken)),
+		op.WithJWTAccessTokenIntrospection(func(_ context.Context, claims *oidc.AccessTokenClaims) (bool, error) {
+			return revoked, nil
+		}),
+	)
+	require.NoError(t, err)
+
+	ctx := op.ContextWithIssuer(context.Background(), testIssuer)
+	client, err := s.GetClientByClientID(ctx, "web")
+	require.NoError(t, err)
+	authReq, err := s.CreateAuthRequest(ctx, &oidc.AuthRequest{
+		ClientID:     client.GetID(),
+		RedirectURI:  "https://example.com",
+		Scopes:       oidc.SpaceDelimitedArray{oidc.ScopeOpenID},
+		ResponseType: oidc.ResponseTypeCode,
+	}, "id1")
+	require.NoError(t, err)
+	jwtToken, _, _, err := op.CreateAccessToken(ctx, authReq, op.AccessTokenTypeJWT, provider, client, "")
+	require.NoError(t, err)
+	idToken, err := op.CreateIDToken(ctx, testIssuer, authReq.(op.IDTokenRequest), time.Hour, "", "", s, client)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		username string
+		token    string
+		revoked  bool
+		want     []string
+	}{
+		{
+			name:     "active",
+			username: "web",
+			token:    jwtToken,
+			want:     []string{`"active":true`, `"client_id":"web"`, `"token_type":"Bearer"`, `"sub":"id1"`, `"iss":"https://localhost:9998/"`},
+		},
+		{
+			name:     "revoked",
+			username: "web",
+			token:    jwtToken,
+			revoked:  true,
+			want:     []string{`{"active":false}`},
+		},
+		{
+			name:     "not in audience",
+			username: "api",
+			token:    jwtToken,
+			want:     []string{`{"active":false}`},
+		},
+		{
+			name:     "id token",
+			username: "web",
+			token:    idToken,
+			want:     []string{`{"active":false}`},
+		},
+		{
+			name:     "invalid signature",
+			username: "web",
+			token:    jwtToken[:strings.LastIndex(jwtToken, ".")] + ".invalid",
+			want:     []string{`{"active":false}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked = tt.revoked
+			u := provider.IntrospectionEndpoint().Absolute(testIssuer) + "?" + url.Values{"token": {tt.token}}.Encode()
+			req := httptest.NewRequest(http.MethodGet, u, nil)
+			req.SetBasicAuth(tt.username, "secret")
+			rec := httptest.NewRecorder()
+			provider.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			for _, w := range tt.want {
+				assert.Contains(t, rec.Body.String(), w)
+			}
+		})
+	}
+}
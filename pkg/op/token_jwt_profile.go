@@ -32,7 +32,9 @@ func JWTProfile(w http.ResponseWriter, r *http.Request, exchanger JWTAuthorizati
 		return
 	}
 
-	tokenRequest.Scopes, err = exchanger.Storage().ValidateJWTProfileScopes(r.Context(), tokenRequest.Issuer, profileRequest.Scope)
+	storageCtx, cancel := storageContext(r.Context())
+	defer cancel()
+	tokenRequest.Scopes, err = exchanger.Storage().ValidateJWTProfileScopes(storageCtx, tokenRequest.Issuer, profileRequest.Scope)
 	if err != nil {
 		RequestError(w, r, err, nil)
 		return
@@ -76,7 +78,9 @@ func CreateJWTTokenResponse(ctx context.Context, tokenRequest TokenRequest, crea
 	tokenStorage, ok := creator.Storage().(JWTProfileTokenStorage)
 	if ok {
 		var err error
-		tokenType, err = tokenStorage.JWTProfileTokenType(ctx, tokenRequest)
+		storageCtx, cancel := storageContext(ctx)
+		defer cancel()
+		tokenType, err = tokenStorage.JWTProfileTokenType(storageCtx, tokenRequest)
 		if err != nil {
 			return nil, err
 		}
@@ -86,11 +90,16 @@ func CreateJWTTokenResponse(ctx context.Context, tokenRequest TokenRequest, crea
 	if err != nil {
 		return nil, err
 	}
+	extra, err := tokenResponseExtra(ctx, creator, client, tokenRequest, "")
+	if err != nil {
+		return nil, err
+	}
 	return &oidc.AccessTokenResponse{
 		AccessToken: accessToken,
 		TokenType:   oidc.BearerToken,
 		ExpiresIn:   uint64(validity.Seconds()),
 		Scope:       tokenRequest.GetScopes(),
+		Extra:       extra,
 	}, nil
 }
 
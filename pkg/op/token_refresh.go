@@ -21,6 +21,17 @@ type RefreshTokenRequest interface {
 	SetCurrentScopes(scopes []string)
 }
 
+// RefreshTokenAudienceRequest is an optional interface that can be implemented by implementors of
+// RefreshTokenRequest to allow clients to narrow the audience of the issued tokens
+// with the resource and audience parameters of the refresh_token grant.
+// Requests not implementing it reject these parameters with an invalid_target error.
+type RefreshTokenAudienceRequest interface {
+	// SetCurrentAudience sets the audience of the tokens issued by this request only.
+	// The audience of the refresh token must not be changed, so it can be widened again
+	// by later requests, up to the originally granted audience.
+	SetCurrentAudience(audience []string)
+}
+
 // RefreshTokenExchange handles the OAuth 2.0 refresh_token grant, including
 // parsing, validating, authorizing the client and finally exchanging the refresh_token for new tokens
 func RefreshTokenExchange(w http.ResponseWriter, r *http.Request, exchanger Exchanger) {
@@ -38,6 +49,10 @@ func RefreshTokenExchange(w http.ResponseWriter, r *http.Request, exchanger Exch
 		RequestError(w, r, err, nil)
 		return
 	}
+	if err = checkGrantPolicies(r.Context(), exchanger, newGrantRequest(r, oidc.GrantTypeRefreshToken, client, validatedRequest)); err != nil {
+		RequestError(w, r, err, nil)
+		return
+	}
 	resp, err := CreateTokenResponse(r.Context(), validatedRequest, client, exchanger, true, "", tokenReq.RefreshToken)
 	if err != nil {
 		RequestError(w, r, err, nil)
@@ -65,6 +80,9 @@ func ValidateRefreshTokenRequest(ctx context.Context, tokenReq *oidc.RefreshToke
 	if tokenReq.RefreshToken == "" {
 		return nil, nil, oidc.ErrInvalidRequest().WithDescription("refresh_token missing")
 	}
+	// the storage only handles the refresh token without the prefix of WithOpaqueTokenFormats,
+	// also when it is rotated by CreateTokenResponse
+	tokenReq.RefreshToken = parseRefreshToken(exchanger, tokenReq.RefreshToken)
 	request, client, err := AuthorizeRefreshClient(ctx, tokenReq, exchanger)
 	if err != nil {
 		return nil, nil, err
@@ -75,6 +93,9 @@ func ValidateRefreshTokenRequest(ctx context.Context, tokenReq *oidc.RefreshToke
 	if err = ValidateRefreshTokenScopes(tokenReq.Scopes, request); err != nil {
 		return nil, nil, err
 	}
+	if err = ValidateRefreshTokenAudience(slices.Concat(tokenReq.Resource, tokenReq.Audience), request); err != nil {
+		return nil, nil, err
+	}
 	return request, client, nil
 }
 
@@ -94,12 +115,47 @@ func ValidateRefreshTokenScopes(requestedScopes []string, authRequest RefreshTok
 	return nil
 }
 
+// ValidateRefreshTokenAudience validates that the requested audience (resource and audience parameters)
+// is a subset of the originally granted audience
+// it will set the requested audience, including the client_id required by the id_token,
+// as current audience onto the [RefreshTokenAudienceRequest]
+// if empty the original audience will be used
+func ValidateRefreshTokenAudience(requestedAudience []string, request RefreshTokenRequest) error {
+	if len(requestedAudience) == 0 {
+		return nil
+	}
+	audienceRequest, ok := request.(RefreshTokenAudienceRequest)
+	if !ok {
+		return oidc.ErrInvalidTarget().WithDescription("narrowing the audience is not supported")
+	}
+	for _, audience := range requestedAudience {
+		if !slices.Contains(request.GetAudience(), audience) {
+			return oidc.ErrInvalidTarget()
+		}
+	}
+	audience := []string{request.GetClientID()}
+	for _, requested := range requestedAudience {
+		if !slices.Contains(audience, requested) {
+			audience = append(audience, requested)
+		}
+	}
+	audienceRequest.SetCurrentAudience(audience)
+	return nil
+}
+
 // AuthorizeRefreshClient checks the authorization of the client and that the used method was the one previously registered.
 // It than returns the data representing the original auth request corresponding to the refresh_token
 func AuthorizeRefreshClient(ctx context.Context, tokenReq *oidc.RefreshTokenRequest, exchanger Exchanger) (request RefreshTokenRequest, client Client, err error) {
 	ctx, span := Tracer.Start(ctx, "AuthorizeRefreshClient")
 	defer span.End()
 
+	if ac, ok := authenticatedClientFromContext(ctx); ok {
+		if !ValidateGrantType(ac.client, oidc.GrantTypeRefreshToken) {
+			return nil, nil, oidc.ErrUnauthorizedClient()
+		}
+		request, err = RefreshTokenRequestByRefreshToken(ctx, exchanger.Storage(), tokenReq.RefreshToken)
+		return request, ac.client, err
+	}
 	if tokenReq.ClientAssertionType == oidc.ClientAssertionTypeJWTAssertion {
 		jwtExchanger, ok := exchanger.(JWTAuthorizationGrantExchanger)
 		if !ok || !exchanger.AuthMethodPrivateKeyJWTSupported() {
@@ -115,7 +171,7 @@ func AuthorizeRefreshClient(ctx context.Context, tokenReq *oidc.RefreshTokenRequ
 		request, err = RefreshTokenRequestByRefreshToken(ctx, exchanger.Storage(), tokenReq.RefreshToken)
 		return request, client, err
 	}
-	client, err = exchanger.Storage().GetClientByClientID(ctx, tokenReq.ClientID)
+	client, err = getClientByClientID(ctx, cacheFrom(exchanger), exchanger.Storage(), tokenReq.ClientID)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -145,7 +201,9 @@ func RefreshTokenRequestByRefreshToken(ctx context.Context, storage Storage, ref
 	ctx, span := Tracer.Start(ctx, "RefreshTokenRequestByRefreshToken")
 	defer span.End()
 
-	request, err := storage.TokenRequestByRefreshToken(ctx, refreshToken)
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	request, err := storage.TokenRequestByRefreshToken(storageCtx, refreshToken)
 	if err != nil {
 		return nil, oidc.ErrInvalidGrant().WithParent(err)
 	}
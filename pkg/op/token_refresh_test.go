@@ -0,0 +1,119 @@
+package op_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/optest"
+)
+
+type refreshTokenRequest struct {
+	audience []string
+	scopes   []string
+}
+
+func (r *refreshTokenRequest) GetAMR() []string                 { return nil }
+func (r *refreshTokenRequest) GetAudience() []string            { return r.audience }
+func (r *refreshTokenRequest) GetAuthTime() time.Time           { return time.Time{} }
+func (r *refreshTokenRequest) GetClientID() string              { return "client" }
+func (r *refreshTokenRequest) GetScopes() []string              { return r.scopes }
+func (r *refreshTokenRequest) GetSubject() string               { return "subject" }
+func (r *refreshTokenRequest) SetCurrentScopes(scopes []string) { r.scopes = scopes }
+
+type refreshTokenAudienceRequest struct {
+	refreshTokenRequest
+}
+
+func (r *refreshTokenAudienceRequest) SetCurrentAudience(audience []string) { r.audience = audience }
+
+func TestValidateRefreshTokenAudience(t *testing.T) {
+	granted := []string{"client", "api1", "api2"}
+	tests := []struct {
+		name         string
+		request      op.RefreshTokenRequest
+		requested    []string
+		wantAudience []string
+		wantErr      bool
+	}{
+		{
+			name:         "not requested",
+			request:      &refreshTokenRequest{audience: granted},
+			wantAudience: granted,
+		},
+		{
+			name:         "narrowed",
+			request:      &refreshTokenAudienceRequest{refreshTokenRequest{audience: granted}},
+			requested:    []string{"api2", "api2"},
+			wantAudience: []string{"client", "api2"},
+		},
+		{
+			name:      "not granted",
+			request:   &refreshTokenAudienceRequest{refreshTokenRequest{audience: granted}},
+			requested: []string{"api3"},
+			wantErr:   true,
+		},
+		{
+			name:      "not supported",
+			request:   &refreshTokenRequest{audience: granted},
+			requested: []string{"api1"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := op.ValidateRefreshTokenAudience(tt.requested, tt.request)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, &oidc.Error{ErrorType: oidc.InvalidTarget})
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantAudience, tt.request.GetAudience())
+		})
+	}
+}
+
+func TestRefreshTokenExchange_narrowing(t *testing.T) {
+	s := optest.New(t)
+	tokens := s.CodeFlow(t, s.RelyingParty(t))
+
+	refresh := func(params url.Values) (*oidc.AccessTokenResponse, int) {
+		params.Set("grant_type", string(oidc.GrantTypeRefreshToken))
+		params.Set("refresh_token", tokens.RefreshToken)
+		req, err := http.NewRequest(http.MethodPost, s.Issuer+"/oauth/token", strings.NewReader(params.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(optest.WebClientID, optest.WebClientSecret)
+		resp, err := s.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		response := new(oidc.AccessTokenResponse)
+		if resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(response))
+			tokens.RefreshToken = response.RefreshToken
+		}
+		return response, resp.StatusCode
+	}
+
+	response, status := refresh(url.Values{"scope": {oidc.ScopeOpenID}, "audience": {optest.WebClientID}})
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, oidc.SpaceDelimitedArray{oidc.ScopeOpenID}, response.Scope)
+
+	// the refresh token keeps the originally granted scopes
+	response, status = refresh(url.Values{"scope": {oidc.ScopeOpenID + " " + oidc.ScopeProfile}})
+	require.Equal(t, http.StatusOK, status)
+	assert.Contains(t, response.Scope, oidc.ScopeProfile)
+
+	_, status = refresh(url.Values{"resource": {"https://api.example.com"}})
+	assert.Equal(t, http.StatusBadRequest, status)
+	_, status = refresh(url.Values{"scope": {"unknown"}})
+	assert.Equal(t, http.StatusBadRequest, status)
+}
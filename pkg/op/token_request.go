@@ -151,7 +151,9 @@ func AuthorizeClientIDSecret(ctx context.Context, clientID, clientSecret string,
 	ctx, span := Tracer.Start(ctx, "AuthorizeClientIDSecret")
 	defer span.End()
 
-	err := storage.AuthorizeClientIDSecret(ctx, clientID, clientSecret)
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	err := authorizeClientSecret(storageCtx, storage, clientID, clientSecret)
 	if err != nil {
 		return oidc.ErrInvalidClient().WithDescription("invalid client_id / client_secret").WithParent(err)
 	}
@@ -184,11 +186,11 @@ func AuthorizePrivateJWTKey(ctx context.Context, clientAssertion string, exchang
 	ctx, span := Tracer.Start(ctx, "AuthorizePrivateJWTKey")
 	defer span.End()
 
-	jwtReq, err := VerifyJWTAssertion(ctx, clientAssertion, exchanger.JWTProfileVerifier(ctx))
+	jwtReq, err := verifyClientAssertion(ctx, clientAssertion, exchanger.JWTProfileVerifier(ctx))
 	if err != nil {
 		return nil, err
 	}
-	client, err := exchanger.Storage().GetClientByClientID(ctx, jwtReq.Issuer)
+	client, err := getClientByClientID(ctx, cacheFrom(exchanger), exchanger.Storage(), jwtReq.Issuer)
 	if err != nil {
 		return nil, err
 	}
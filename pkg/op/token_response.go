@@ -0,0 +1,72 @@
+package op
+
+import (
+	"context"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// TokenResponseHookContext describes the token response passed to a [TokenResponseHook].
+type TokenResponseHookContext struct {
+	*ClaimsHookContext
+	// RefreshToken is set if the response contains a (new) refresh token.
+	RefreshToken string
+}
+
+// TokenResponseHook is called with the extra members of a token response,
+// just before it is returned to the client of the token endpoint.
+// It may add members to extra, e.g. [oidc.RefreshExpiresIn].
+// A returned error fails the token request, as do registered members
+// of the response added to extra, see [oidc.TokenResponseExtra.Validate].
+type TokenResponseHook func(ctx context.Context, extra oidc.TokenResponseExtra, hc *TokenResponseHookContext) error
+
+// TokenResponseStorage is an optional interface that can be implemented by implementors of
+// Storage to add extra members to token responses, like a [TokenResponseHook].
+// It is called after the hooks of [WithTokenResponseHook].
+type TokenResponseStorage interface {
+	ExtendTokenResponse(ctx context.Context, extra oidc.TokenResponseExtra, hc *TokenResponseHookContext) error
+}
+
+// tokenResponseHooksProvider is implemented by the [Provider]
+// to pass the hooks of [WithTokenResponseHook] to the token creation.
+type tokenResponseHooksProvider interface {
+	TokenResponseHooks() []TokenResponseHook
+}
+
+// tokenResponseExtra calls the hooks of the creator and the [TokenResponseStorage].
+// It returns nil if no extra members were added.
+func tokenResponseExtra(ctx context.Context, creator TokenCreator, client AccessTokenClient, request TokenRequest, refreshToken string) (oidc.TokenResponseExtra, error) {
+	var hooks []TokenResponseHook
+	if p, ok := creator.(tokenResponseHooksProvider); ok {
+		hooks = p.TokenResponseHooks()
+	}
+	tokenStorage, hasStorage := creator.Storage().(TokenResponseStorage)
+	if len(hooks) == 0 && !hasStorage {
+		return nil, nil
+	}
+
+	extra := make(oidc.TokenResponseExtra)
+	hc := &TokenResponseHookContext{
+		ClaimsHookContext: newClaimsHookContext(client, request),
+		RefreshToken:      refreshToken,
+	}
+	for _, hook := range hooks {
+		if err := hook(ctx, extra, hc); err != nil {
+			return nil, err
+		}
+	}
+	if hasStorage {
+		storageCtx, cancel := storageContext(ctx)
+		defer cancel()
+		if err := tokenStorage.ExtendTokenResponse(storageCtx, extra, hc); err != nil {
+			return nil, err
+		}
+	}
+	if len(extra) == 0 {
+		return nil, nil
+	}
+	if err := extra.Validate(); err != nil {
+		return nil, oidc.ErrServerError().WithParent(err)
+	}
+	return extra, nil
+}
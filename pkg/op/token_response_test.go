@@ -0,0 +1,97 @@
+package op_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/example/server/storage"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+)
+
+type tokenResponseStorage struct {
+	*storage.Storage
+}
+
+func (tokenResponseStorage) ExtendTokenResponse(_ context.Context, extra oidc.TokenResponseExtra, hc *op.TokenResponseHookContext) error {
+	extra["tenant"] = "acme"
+	return nil
+}
+
+func TestTokenResponseHook(t *testing.T) {
+	var got *op.TokenResponseHookContext
+	s := tokenResponseStorage{storage.NewStorage(storage.NewUserStore(testIssuer))}
+	provider, err := op.NewOpenIDProvider(testIssuer, testConfig, s,
+		op.WithAllowInsecure(),
+		op.WithTokenResponseHook(func(_ context.Context, extra oidc.TokenResponseExtra, hc *op.TokenResponseHookContext) error {
+			got = hc
+			if hc.RefreshToken != "" {
+				extra.SetRefreshExpiresIn(time.Hour)
+			}
+			return nil
+		}),
+	)
+	require.NoError(t, err)
+
+	ctx := op.ContextWithIssuer(context.Background(), testIssuer)
+	client := storage.WebClient("web", "secret", "https://example.com/callback")
+	request := &op.DeviceAuthorizationState{
+		ClientID: "web",
+		Scopes:   []string{oidc.ScopeOpenID, oidc.ScopeOfflineAccess},
+		Subject:  "id1",
+		AuthTime: time.Now(),
+	}
+
+	resp, err := op.CreateDeviceTokenResponse(ctx, request, provider, client)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "id1", got.Subject)
+	assert.Equal(t, resp.RefreshToken, got.RefreshToken)
+
+	data, err := json.Marshal(resp)
+	require.NoError(t, err)
+	var members map[string]any
+	require.NoError(t, json.Unmarshal(data, &members))
+	assert.Equal(t, float64(3600), members[oidc.RefreshExpiresIn])
+	assert.Equal(t, "acme", members["tenant"])
+}
+
+func TestTokenResponseHook_error(t *testing.T) {
+	s := storage.NewStorage(storage.NewUserStore(testIssuer))
+	hookErr := errors.New("hook failed")
+	provider, err := op.NewOpenIDProvider(testIssuer, testConfig, s,
+		op.WithAllowInsecure(),
+		op.WithTokenResponseHook(func(context.Context, oidc.TokenResponseExtra, *op.TokenResponseHookContext) error {
+			return hookErr
+		}),
+	)
+	require.NoError(t, err)
+
+	ctx := op.ContextWithIssuer(context.Background(), testIssuer)
+	client := storage.WebClient("web", "secret", "https://example.com/callback")
+	_, err = op.CreateTokenResponse(ctx, &op.DeviceAuthorizationState{ClientID: "web", Subject: "id1"}, client, provider, false, "", "")
+	assert.ErrorIs(t, err, hookErr)
+}
+
+func TestTokenResponseHook_registeredMember(t *testing.T) {
+	s := storage.NewStorage(storage.NewUserStore(testIssuer))
+	provider, err := op.NewOpenIDProvider(testIssuer, testConfig, s,
+		op.WithAllowInsecure(),
+		op.WithTokenResponseHook(func(_ context.Context, extra oidc.TokenResponseExtra, _ *op.TokenResponseHookContext) error {
+			extra["refresh_token"] = "injected"
+			return nil
+		}),
+	)
+	require.NoError(t, err)
+
+	ctx := op.ContextWithIssuer(context.Background(), testIssuer)
+	client := storage.WebClient("web", "secret", "https://example.com/callback")
+	_, err = op.CreateTokenResponse(ctx, &op.DeviceAuthorizationState{ClientID: "web", Subject: "id1"}, client, provider, false, "", "")
+	assert.ErrorIs(t, err, oidc.ErrRegisteredTokenResponseMember)
+}
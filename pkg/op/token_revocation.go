@@ -3,6 +3,7 @@ package op
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
@@ -41,34 +42,105 @@ func Revoke(w http.ResponseWriter, r *http.Request, revoker Revoker) {
 		RevocationRequestError(w, r, err)
 		return
 	}
-	var subject string
-	doDecrypt := true
-	if tokenTypeHint != "access_token" {
-		userID, tokenID, err := revoker.Storage().GetRefreshTokenInfo(r.Context(), clientID, token)
-		if err != nil {
-			// An invalid refresh token means that we'll try other things (leaving doDecrypt==true)
-			if !errors.Is(err, ErrInvalidRefreshToken) {
-				RevocationRequestError(w, r, oidc.ErrServerError().WithParent(err))
-				return
-			}
-		} else {
-			token = tokenID
-			subject = userID
-			doDecrypt = false
+	if err := revokeToken(r.Context(), revoker, token, tokenTypeHint, clientID); err != nil {
+		RevocationRequestError(w, r, err)
+		return
+	}
+	httphelper.MarshalJSON(w, nil)
+}
+
+// refreshTokenRevocationCascader is implemented by the [Provider]
+// to report whether revoking an access token should also revoke
+// the refresh token it was issued with.
+type refreshTokenRevocationCascader interface {
+	RevokeRefreshTokenWithAccessToken() bool
+}
+
+// revokeToken identifies the token passed by the client and revokes it through the Storage.
+// Following RFC 7009 section 2.1, the token_type_hint is only used to decide which
+// token type is looked up first; when the lookup fails, the other type is tried.
+// Tokens that could not be identified at all are passed as they are to [Storage.RevokeToken],
+// without subject, so storages resolving the token themselves can still revoke it.
+// Storages should ignore tokens they don't know either,
+// so the client receives a 200 response for them (RFC 7009 section 2.2).
+func revokeToken(ctx context.Context, revoker Revoker, token, tokenTypeHint, clientID string) error {
+	ctx, span := Tracer.Start(ctx, "revokeToken")
+	defer span.End()
+
+	var (
+		tokenID, subject string
+		tokenType        oidc.TokenType
+		err              error
+	)
+	if tokenTypeHint == "access_token" {
+		tokenID, subject, tokenType = identifyAccessTokenForRevocation(ctx, revoker, token)
+		if tokenType == "" {
+			tokenID, subject, tokenType, err = identifyRefreshTokenForRevocation(ctx, revoker, clientID, token)
 		}
+	} else {
+		tokenID, subject, tokenType, err = identifyRefreshTokenForRevocation(ctx, revoker, clientID, token)
+		if err == nil && tokenType == "" {
+			tokenID, subject, tokenType = identifyAccessTokenForRevocation(ctx, revoker, token)
+		}
+	}
+	if err != nil {
+		return err
 	}
-	if doDecrypt {
-		tokenID, userID, ok := getTokenIDAndSubjectForRevocation(r.Context(), revoker, token)
-		if ok {
-			token = tokenID
-			subject = userID
+	if tokenType == "" {
+		slog.DebugContext(ctx, "revocation of unidentified token passed to storage", "client_id", clientID, "token_type_hint", tokenTypeHint)
+		storageCtx, cancel := storageContext(ctx)
+		defer cancel()
+		if err := revoker.Storage().RevokeToken(storageCtx, token, "", clientID); err != nil {
+			return err
 		}
+		return nil
 	}
-	if err := revoker.Storage().RevokeToken(r.Context(), token, subject, clientID); err != nil {
-		RevocationRequestError(w, r, err)
-		return
+
+	if familyStorage, ok := revoker.Storage().(CanRevokeTokenFamily); ok {
+		request := &RevokeTokenFamilyRequest{
+			TokenID:   tokenID,
+			UserID:    subject,
+			ClientID:  clientID,
+			TokenType: tokenType,
+		}
+		if cascader, ok := revoker.(refreshTokenRevocationCascader); ok && tokenType == oidc.AccessTokenType {
+			request.RevokeRefreshToken = cascader.RevokeRefreshTokenWithAccessToken()
+		}
+		storageCtx, cancel := storageContext(ctx)
+		defer cancel()
+		if err := familyStorage.RevokeTokenFamily(storageCtx, request); err != nil {
+			return err
+		}
+		return nil
 	}
-	httphelper.MarshalJSON(w, nil)
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	if err := revoker.Storage().RevokeToken(storageCtx, tokenID, subject, clientID); err != nil {
+		return err
+	}
+	return nil
+}
+
+func identifyRefreshTokenForRevocation(ctx context.Context, revoker Revoker, clientID, token string) (tokenID, subject string, tokenType oidc.TokenType, err error) {
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	userID, tokenID, err := revoker.Storage().GetRefreshTokenInfo(storageCtx, clientID, parseRefreshToken(revoker, token))
+	if err != nil {
+		// An invalid refresh token means that we'll try other things
+		if errors.Is(err, ErrInvalidRefreshToken) {
+			return "", "", "", nil
+		}
+		return "", "", "", oidc.ErrServerError().WithParent(err)
+	}
+	return tokenID, userID, oidc.RefreshTokenType, nil
+}
+
+func identifyAccessTokenForRevocation(ctx context.Context, revoker Revoker, token string) (tokenID, subject string, tokenType oidc.TokenType) {
+	tokenID, subject, ok := getTokenIDAndSubjectForRevocation(ctx, revoker, token)
+	if !ok {
+		return "", "", ""
+	}
+	return tokenID, subject, oidc.AccessTokenType
 }
 
 func ParseTokenRevocationRequest(r *http.Request, revoker Revoker) (token, tokenTypeHint, clientID string, err error) {
@@ -90,12 +162,15 @@ func ParseTokenRevocationRequest(r *http.Request, revoker Revoker) (token, token
 	if err != nil {
 		return "", "", "", oidc.ErrInvalidRequest().WithDescription("error decoding form").WithParent(err)
 	}
+	if ac, ok := authenticatedClientFromContext(r.Context()); ok {
+		return req.Token, req.TokenTypeHint, ac.client.GetID(), nil
+	}
 	if req.ClientAssertionType == oidc.ClientAssertionTypeJWTAssertion {
 		revokerJWTProfile, ok := revoker.(RevokerJWTProfile)
 		if !ok || !revoker.AuthMethodPrivateKeyJWTSupported() {
 			return "", "", "", oidc.ErrInvalidClient().WithDescription("auth_method private_key_jwt not supported")
 		}
-		profile, err := VerifyJWTAssertion(r.Context(), req.ClientAssertion, revokerJWTProfile.JWTProfileVerifier(r.Context()))
+		profile, err := verifyClientAssertion(r.Context(), req.ClientAssertion, revokerJWTProfile.JWTProfileVerifier(r.Context()))
 		if err == nil {
 			return req.Token, req.TokenTypeHint, profile.Issuer, nil
 		}
@@ -119,7 +194,7 @@ func ParseTokenRevocationRequest(r *http.Request, revoker Revoker) (token, token
 	if req.ClientID == "" {
 		return "", "", "", oidc.ErrInvalidClient().WithDescription("invalid authorization")
 	}
-	client, err := revoker.Storage().GetClientByClientID(r.Context(), req.ClientID)
+	client, err := getClientByClientID(r.Context(), cacheFrom(revoker), revoker.Storage(), req.ClientID)
 	if err != nil {
 		return "", "", "", oidc.ErrInvalidClient().WithParent(err)
 	}
@@ -159,6 +234,13 @@ func getTokenIDAndSubjectForRevocation(ctx context.Context, userinfoProvider Use
 	ctx, span := Tracer.Start(ctx, "getTokenIDAndSubjectForRevocation")
 	defer span.End()
 
+	if claims, ok := decodeFormattedAccessToken(ctx, userinfoProvider, accessToken); ok {
+		return claims.JWTID, claims.Subject, true
+	}
+	accessToken, tokenID, subject, ok := parseOpaqueAccessToken(ctx, userinfoProvider, accessToken)
+	if ok {
+		return tokenID, subject, true
+	}
 	tokenIDSubject, err := userinfoProvider.Crypto().Decrypt(accessToken)
 	if err == nil {
 		splitToken := strings.Split(tokenIDSubject, ":")
@@ -0,0 +1,48 @@
+package op_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/example/server/storage"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+)
+
+// revocationStorage records the calls of RevokeToken.
+type revocationStorage struct {
+	*storage.Storage
+	revoked *[]string
+}
+
+func (s revocationStorage) RevokeToken(ctx context.Context, tokenOrTokenID, userID, clientID string) *oidc.Error {
+	*s.revoked = append(*s.revoked, strings.Join([]string{tokenOrTokenID, userID, clientID}, "|"))
+	return nil
+}
+
+func TestRevoke_unidentifiedToken(t *testing.T) {
+	for _, hint := range []string{"", "access_token", "refresh_token"} {
+		t.Run(hint, func(t *testing.T) {
+			var revoked []string
+			s := revocationStorage{Storage: storage.NewStorage(storage.NewUserStore(testIssuer)), revoked: &revoked}
+			provider, err := op.NewOpenIDProvider(testIssuer, testConfig, s, op.WithAllowInsecure())
+			require.NoError(t, err)
+
+			form := url.Values{"token": {"resolved-by-storage"}, "token_type_hint": {hint}}
+			req := httptest.NewRequest(http.MethodPost, "/revoke", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.SetBasicAuth("web", "secret")
+			w := httptest.NewRecorder()
+			provider.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			assert.Equal(t, []string{"resolved-by-storage||web"}, revoked)
+		})
+	}
+}
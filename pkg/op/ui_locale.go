@@ -0,0 +1,103 @@
+package op
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"golang.org/x/text/language"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// NegotiateUILocale returns the supported language best matching the preferred languages
+// of the uiLocales, typically of the auth request, and the acceptLanguage header, in this order.
+// If none matches, the first supported language is returned.
+// Without supported languages, [language.Und] is returned.
+func NegotiateUILocale(supported, uiLocales []language.Tag, acceptLanguage string) language.Tag {
+	if len(supported) == 0 {
+		return language.Und
+	}
+	return newUILocaleMatcher(supported).match(uiLocales, acceptLanguage)
+}
+
+// NegotiateUILocale returns the language of [Config.SupportedUILocales] for the user of the request,
+// preferring the uiLocales over the Accept-Language header, see [NegotiateUILocale].
+// It allows login UIs served outside of the OP to use the same negotiation as the OP.
+func (o *Provider) NegotiateUILocale(r *http.Request, uiLocales []language.Tag) language.Tag {
+	return NegotiateUILocale(o.SupportedUILocales(), uiLocales, r.Header.Get("Accept-Language"))
+}
+
+type uiLocaleMatcher struct {
+	supported []language.Tag
+	matcher   language.Matcher
+}
+
+func newUILocaleMatcher(supported []language.Tag) *uiLocaleMatcher {
+	return &uiLocaleMatcher{
+		supported: supported,
+		matcher:   language.NewMatcher(supported),
+	}
+}
+
+func (m *uiLocaleMatcher) match(uiLocales []language.Tag, acceptLanguage string) language.Tag {
+	preferred := uiLocales
+	if accept, _, err := language.ParseAcceptLanguage(acceptLanguage); err == nil && len(accept) > 0 {
+		preferred = append(append(make([]language.Tag, 0, len(uiLocales)+len(accept)), uiLocales...), accept...)
+	}
+	// the matched tag may carry extensions, the supported tag is returned as configured
+	_, index, _ := m.matcher.Match(preferred...)
+	return m.supported[index]
+}
+
+type (
+	uiLocaleKey        struct{}
+	uiLocaleMatcherKey struct{}
+)
+
+// ContextWithUILocale returns a context carrying the negotiated language of the user.
+func ContextWithUILocale(ctx context.Context, tag language.Tag) context.Context {
+	return context.WithValue(ctx, uiLocaleKey{}, tag)
+}
+
+// UILocaleFromContext returns the language negotiated for the user of the request,
+// if [Config.SupportedUILocales] are set. It is negotiated from the ui_locales of the query
+// and the Accept-Language header for all requests of the OP, and from the ui_locales
+// of the auth request (including a request object) for the authorization endpoint,
+// so custom error renderers can reuse the language of the OP.
+func UILocaleFromContext(ctx context.Context) (language.Tag, bool) {
+	tag, ok := ctx.Value(uiLocaleKey{}).(language.Tag)
+	return tag, ok
+}
+
+type uiLocalesProvider interface {
+	SupportedUILocales() []language.Tag
+}
+
+// uiLocaleInterceptor negotiates the language of the user for the requests,
+// if the provider has supported ui locales.
+func uiLocaleInterceptor(v any) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		p, ok := v.(uiLocalesProvider)
+		if !ok || len(p.SupportedUILocales()) == 0 {
+			return next
+		}
+		matcher := newUILocaleMatcher(p.SupportedUILocales())
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tag := matcher.match(oidc.ParseLocales(strings.Fields(r.URL.Query().Get("ui_locales"))), r.Header.Get("Accept-Language"))
+			ctx := context.WithValue(r.Context(), uiLocaleMatcherKey{}, matcher)
+			next.ServeHTTP(w, r.WithContext(ContextWithUILocale(ctx, tag)))
+		})
+	}
+}
+
+// setAuthRequestUILocale negotiates the [oidc.AuthRequest.UILocale] of the auth request
+// and returns the context carrying it. Without supported ui locales, the context is returned unchanged.
+func setAuthRequestUILocale(ctx context.Context, authReq *oidc.AuthRequest, header http.Header) context.Context {
+	matcher, ok := ctx.Value(uiLocaleMatcherKey{}).(*uiLocaleMatcher)
+	if !ok {
+		return ctx
+	}
+	authReq.UILocale = matcher.match(authReq.UILocales, header.Get("Accept-Language"))
+	return ContextWithUILocale(ctx, authReq.UILocale)
+}
@@ -0,0 +1,66 @@
+package op
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+func TestNegotiateUILocale(t *testing.T) {
+	supported := []language.Tag{language.English, language.German, language.French}
+	tests := []struct {
+		name           string
+		supported      []language.Tag
+		uiLocales      []language.Tag
+		acceptLanguage string
+		want           language.Tag
+	}{
+		{"no supported", nil, []language.Tag{language.German}, "fr", language.Und},
+		{"none", supported, nil, "", language.English},
+		{"unsupported", supported, []language.Tag{language.Japanese}, "", language.English},
+		{"ui_locales", supported, []language.Tag{language.French}, "de", language.French},
+		{"region", supported, []language.Tag{language.MustParse("de-CH")}, "", language.German},
+		{"accept language", supported, []language.Tag{language.Japanese}, "fr-CH, de;q=0.5", language.French},
+		{"invalid accept language", supported, nil, "%%%", language.English},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NegotiateUILocale(tt.supported, tt.uiLocales, tt.acceptLanguage))
+		})
+	}
+}
+
+func TestUILocaleInterceptor(t *testing.T) {
+	provider := &Provider{config: &Config{SupportedUILocales: []language.Tag{language.English, language.German, language.French}}}
+	var (
+		fromContext, fromAuthRequest language.Tag
+		ok                           bool
+	)
+	handler := uiLocaleInterceptor(provider)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fromContext, ok = UILocaleFromContext(r.Context())
+		authReq := &oidc.AuthRequest{UILocales: oidc.Locales{language.French}}
+		tag, _ := UILocaleFromContext(setAuthRequestUILocale(r.Context(), authReq, r.Header))
+		assert.Equal(t, authReq.UILocale, tag)
+		fromAuthRequest = authReq.UILocale
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/authorize?ui_locales=ja+de-CH", nil)
+	req.Header.Set("Accept-Language", "fr")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.True(t, ok)
+	assert.Equal(t, language.German, fromContext)
+	assert.Equal(t, language.French, fromAuthRequest)
+
+	provider.config.SupportedUILocales = nil
+	uiLocaleInterceptor(provider)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ok = UILocaleFromContext(r.Context())
+		authReq := &oidc.AuthRequest{UILocales: oidc.Locales{language.French}}
+		setAuthRequestUILocale(r.Context(), authReq, r.Header)
+		assert.Equal(t, language.Und, authReq.UILocale)
+	})).ServeHTTP(httptest.NewRecorder(), req)
+	assert.False(t, ok, "without supported ui locales")
+}
@@ -0,0 +1,173 @@
+package op
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// UserSession is the session of a user at the OP, which is established by the login
+// and shared by all clients the user signs in to with the same user agent.
+type UserSession struct {
+	// ID identifies the session, it is sent as sid claim of the logout tokens
+	// and the sid parameter of front-channel logout.
+	ID       string
+	Subject  string
+	AuthTime time.Time
+	// ClientIDs are the clients the user signed in to during the session.
+	ClientIDs []string
+	// UserAgent optionally describes the device of the session,
+	// e.g. to be displayed on an "active sessions" page.
+	UserAgent string
+}
+
+// SessionStorage is an optional additional interface that may be implemented by
+// implementors of Storage to make the sessions of the users manageable, e.g. by an
+// "active sessions" page of the user or by an administrator.
+// Sessions are terminated with [LogoutSession], which notifies the clients of the session.
+type SessionStorage interface {
+	// ListSessions returns the active sessions of the user.
+	ListSessions(ctx context.Context, subject string) ([]UserSession, error)
+	// TerminateSessionByID ends the session, revokes the tokens issued within it
+	// and returns the terminated session.
+	// An error implementing [StorageNotFoundError] is returned for unknown sessions.
+	TerminateSessionByID(ctx context.Context, sessionID string) (*UserSession, error)
+}
+
+// FrontChannelLogoutClient is an optional interface that can be implemented by implementors of
+// Client to be notified about the end of a session in the user agent,
+// as defined by https://openid.net/specs/openid-connect-frontchannel-1_0.html
+type FrontChannelLogoutClient interface {
+	// FrontChannelLogoutURI returns the frontchannel_logout_uri of the client.
+	// It is not loaded if it is empty.
+	FrontChannelLogoutURI() string
+	// FrontChannelLogoutSessionRequired reports whether the client requires the iss and sid parameters.
+	// Sessions without an ID are skipped for such clients.
+	FrontChannelLogoutSessionRequired() bool
+}
+
+// SessionRequest is an optional interface that can be implemented by implementors of
+// AuthRequest, RefreshTokenRequest or other TokenRequest, to bind the tokens of the
+// request to the session of the user.
+// The session ID is established at authentication time, e.g. with [NewSessionID],
+// and must be passed on to the tokens issued for it, including refresh tokens,
+// so all tokens of a session can be correlated and revoked by [SessionStorage].
+type SessionRequest interface {
+	GetSessionID() string
+}
+
+// SessionIDFromRequest returns the session ID of the request,
+// if it implements [SessionRequest].
+func SessionIDFromRequest(request TokenRequest) string {
+	if sessionRequest, ok := request.(SessionRequest); ok {
+		return sessionRequest.GetSessionID()
+	}
+	return ""
+}
+
+// NewSessionID returns a random session ID, which can be used
+// when the user is authenticated.
+func NewSessionID() string {
+	return rand.Text()
+}
+
+// sessionIDClaimHook sets the sid claim of the ID token, see [Config.SessionIDClaim].
+func sessionIDClaimHook(_ context.Context, claims *oidc.IDTokenClaims, hc *ClaimsHookContext) error {
+	claims.SessionID = SessionIDFromRequest(hc.Request)
+	return nil
+}
+
+var ErrSessionStorageNotImplemented = errors.New("storage does not implement op.SessionStorage")
+
+// LogoutSession terminates the session through the [SessionStorage].
+// The clients of the session are notified by back-channel logout, if supported,
+// and an [EventSessionRevoked] is published.
+// It returns the front-channel logout URIs of the clients, which must be loaded by the
+// user agent of the session, see [RenderFrontChannelLogout]. Administrative logouts,
+// without the user agent, can't notify these clients.
+//
+// The context must carry the issuer, see [ContextWithIssuer].
+func LogoutSession(ctx context.Context, provider OpenIDProvider, sessionID string) (frontChannelURIs []string, err error) {
+	ctx, span := Tracer.Start(ctx, "LogoutSession")
+	defer span.End()
+
+	storage, ok := provider.Storage().(SessionStorage)
+	if !ok {
+		return nil, ErrSessionStorageNotImplemented
+	}
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	session, err := storage.TerminateSessionByID(storageCtx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	clientSessions := make([]ClientSession, len(session.ClientIDs))
+	for i, clientID := range session.ClientIDs {
+		clientSessions[i] = ClientSession{ClientID: clientID, SessionID: session.ID}
+	}
+	sendBackChannelLogout(ctx, provider, provider.Storage(), session.Subject, clientSessions)
+	publishSessionRevoked(ctx, eventPublisherFrom(provider), session.Subject, append(clientSessions, ClientSession{SessionID: session.ID}))
+	return frontChannelLogoutURIs(ctx, provider, provider.Storage(), clientSessions), nil
+}
+
+// frontChannelLogoutURIs returns the frontchannel_logout_uri of the clients,
+// with the iss and sid parameters appended for sessions with an ID.
+func frontChannelLogoutURIs(ctx context.Context, provider SessionEnder, storage Storage, sessions []ClientSession) []string {
+	issuer := IssuerFromContext(ctx)
+	var uris []string
+	for _, session := range sessions {
+		if session.ClientID == "" {
+			continue
+		}
+		client, err := getClientByClientID(ctx, cacheFrom(provider), storage, session.ClientID)
+		if err != nil {
+			slog.WarnContext(ctx, "front-channel logout: client not found", "error", err, "client_id", session.ClientID)
+			continue
+		}
+		logoutClient, ok := client.(FrontChannelLogoutClient)
+		if !ok || logoutClient.FrontChannelLogoutURI() == "" {
+			continue
+		}
+		if logoutClient.FrontChannelLogoutSessionRequired() && session.SessionID == "" {
+			continue
+		}
+		uri, err := url.Parse(logoutClient.FrontChannelLogoutURI())
+		if err != nil {
+			slog.WarnContext(ctx, "front-channel logout: invalid uri", "error", err, "client_id", session.ClientID)
+			continue
+		}
+		if session.SessionID != "" {
+			query := uri.Query()
+			query.Set("iss", issuer)
+			query.Set("sid", session.SessionID)
+			uri.RawQuery = query.Encode()
+		}
+		uris = append(uris, uri.String())
+	}
+	return uris
+}
+
+//go:embed frontchannel_logout.html.tmpl
+var frontChannelLogoutHtmlTemplate string
+
+var frontChannelLogoutTmpl = template.Must(template.New("frontchannel_logout").Parse(frontChannelLogoutHtmlTemplate))
+
+// RenderFrontChannelLogout responds a html page, which loads the front-channel logout URIs
+// returned by [LogoutSession] in hidden iframes
+// and navigates to the redirectURI afterwards, if not empty.
+func RenderFrontChannelLogout(w http.ResponseWriter, uris []string, redirectURI string) error {
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	return frontChannelLogoutTmpl.Execute(w, struct {
+		URIs        []string
+		RedirectURI string
+	}{uris, redirectURI})
+}
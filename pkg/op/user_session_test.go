@@ -0,0 +1,109 @@
+package op_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/optest"
+)
+
+func TestLogoutSession(t *testing.T) {
+	logoutTokens := make(chan string, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/backchannel_logout" {
+			logoutTokens <- r.FormValue("logout_token")
+		}
+	}))
+	defer receiver.Close()
+
+	s := newConformanceServer(t, receiver.URL, op.ConformanceProfileBasic, op.ConformanceProfileBackChannelLogout)
+	relyingParty, err := rp.NewRelyingPartyOIDC(context.Background(), s.Issuer, "conformance", "secret", receiver.URL+"/callback", []string{oidc.ScopeOpenID},
+		rp.WithHTTPClient(s.Client()))
+	require.NoError(t, err)
+	s.CodeFlow(t, relyingParty)
+
+	sessions, err := s.Storage.(op.SessionStorage).ListSessions(s.Context(), optest.UserID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	session := sessions[0]
+	assert.Equal(t, []string{"conformance"}, session.ClientIDs)
+
+	frontChannelURIs, err := op.LogoutSession(s.Context(), s.Provider, session.ID)
+	require.NoError(t, err)
+	require.Len(t, frontChannelURIs, 1)
+	frontChannelURI, err := url.Parse(frontChannelURIs[0])
+	require.NoError(t, err)
+	assert.Equal(t, "/frontchannel_logout", frontChannelURI.Path)
+	assert.Equal(t, s.Issuer, frontChannelURI.Query().Get("iss"))
+	assert.Equal(t, session.ID, frontChannelURI.Query().Get("sid"))
+
+	var logoutToken string
+	select {
+	case logoutToken = <-logoutTokens:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no logout token received")
+	}
+	claims, err := rp.VerifyLogoutToken(context.Background(), logoutToken, relyingParty.IDTokenVerifier())
+	require.NoError(t, err)
+	assert.Equal(t, optest.UserID, claims.Subject)
+	assert.Equal(t, session.ID, claims.SessionID)
+
+	sessions, err = s.Storage.(op.SessionStorage).ListSessions(s.Context(), optest.UserID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+	_, err = op.LogoutSession(s.Context(), s.Provider, session.ID)
+	var notFound op.StorageNotFoundError
+	assert.ErrorAs(t, err, &notFound)
+}
+
+func TestSessionIDClaim(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+	}{
+		{"enabled", true},
+		{"disabled", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := optest.DefaultConfig()
+			config.SessionIDClaim = tt.enabled
+			s := optest.New(t, withExampleStorage(), optest.WithConfig(config))
+			relyingParty := s.RelyingParty(t)
+			tokens := s.CodeFlow(t, relyingParty)
+
+			sessions, err := s.Storage.(op.SessionStorage).ListSessions(s.Context(), optest.UserID)
+			require.NoError(t, err)
+			require.Len(t, sessions, 1)
+			if !tt.enabled {
+				assert.Empty(t, tokens.IDTokenClaims.SessionID)
+				return
+			}
+			assert.Equal(t, sessions[0].ID, tokens.IDTokenClaims.SessionID)
+
+			// the session is kept by the refresh token
+			refreshed, err := rp.RefreshTokens[*oidc.IDTokenClaims](context.Background(), relyingParty, tokens.RefreshToken, "", "")
+			require.NoError(t, err)
+			assert.Equal(t, sessions[0].ID, refreshed.IDTokenClaims.SessionID)
+		})
+	}
+}
+
+func TestRenderFrontChannelLogout(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := op.RenderFrontChannelLogout(rec, []string{"https://rp.example.com/logout?iss=https%3A%2F%2Fop&sid=1"}, "https://rp.example.com/done")
+	require.NoError(t, err)
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	assert.Contains(t, rec.Body.String(), `src="https://rp.example.com/logout?iss=https%3A%2F%2Fop&amp;sid=1"`)
+	assert.Contains(t, rec.Body.String(), `window.location.href = "https://rp.example.com/done"`)
+}
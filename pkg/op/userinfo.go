@@ -39,7 +39,9 @@ func Userinfo(w http.ResponseWriter, r *http.Request, userinfoProvider UserinfoP
 		return
 	}
 	info := new(oidc.UserInfo)
-	err = userinfoProvider.Storage().SetUserinfoFromToken(r.Context(), info, tokenID, subject, r.Header.Get("origin"))
+	storageCtx, cancel := storageContext(r.Context())
+	defer cancel()
+	err = userinfoProvider.Storage().SetUserinfoFromToken(storageCtx, info, tokenID, subject, r.Header.Get("origin"))
 	if err != nil {
 		httphelper.MarshalJSONWithStatus(w, err, http.StatusForbidden)
 		return
@@ -92,6 +94,13 @@ func getTokenIDAndSubject(ctx context.Context, userinfoProvider UserinfoProvider
 	ctx, span := Tracer.Start(ctx, "getTokenIDAndSubject")
 	defer span.End()
 
+	if claims, ok := decodeFormattedAccessToken(ctx, userinfoProvider, accessToken); ok {
+		return claims.JWTID, claims.Subject, true
+	}
+	accessToken, tokenID, subject, ok := parseOpaqueAccessToken(ctx, userinfoProvider, accessToken)
+	if ok {
+		return tokenID, subject, true
+	}
 	tokenIDSubject, err := userinfoProvider.Crypto().Decrypt(accessToken)
 	if err == nil {
 		splitToken := strings.Split(tokenIDSubject, ":")
@@ -16,11 +16,25 @@ func WithSupportedAccessTokenSigningAlgorithms(algs ...string) AccessTokenVerifi
 	}
 }
 
+// WithAccessTokenTypes sets the accepted typ headers of JWT access tokens,
+// which are [oidc.TypeAccessToken] and [oidc.TypeJWT] by default.
+// Pass only [oidc.TypeAccessToken] for the strict checking of RFC 9068,
+// so ID tokens and logout tokens signed by the OP are not mistaken for access tokens.
+// Without types, the typ header is not checked.
+func WithAccessTokenTypes(types ...string) AccessTokenVerifierOpt {
+	return func(verifier *AccessTokenVerifier) {
+		verifier.AllowedTypes = types
+	}
+}
+
 // NewAccessTokenVerifier returns a AccessTokenVerifier suitable for access token verification.
+// JWTs with the typ header at+jwt or JWT are accepted, so tokens signed before the OP
+// set the typ header of RFC 9068 remain valid, see [WithAccessTokenTypes].
 func NewAccessTokenVerifier(issuer string, keySet oidc.KeySet, opts ...AccessTokenVerifierOpt) *AccessTokenVerifier {
 	verifier := &AccessTokenVerifier{
-		Issuer: issuer,
-		KeySet: keySet,
+		Issuer:       issuer,
+		KeySet:       keySet,
+		AllowedTypes: []string{oidc.TypeAccessToken, oidc.TypeJWT},
 	}
 	for _, opt := range opts {
 		opt(verifier)
@@ -28,33 +42,24 @@ func NewAccessTokenVerifier(issuer string, keySet oidc.KeySet, opts ...AccessTok
 	return verifier
 }
 
-// VerifyAccessToken validates the access token (issuer, signature and expiration).
+// VerifyAccessToken validates the access token (typ header, issuer, subject, signature, expiration and issued at)
+// with [oidc.VerifyJWTAccessToken]. The audience is not checked, as the OP accepts
+// its tokens for any audience.
 func VerifyAccessToken[C oidc.Claims](ctx context.Context, token string, v *AccessTokenVerifier) (claims C, err error) {
 	ctx, span := Tracer.Start(ctx, "VerifyAccessToken")
 	defer span.End()
 
 	var nilClaims C
 
-	decrypted, err := oidc.DecryptToken(token)
-	if err != nil {
-		return nilClaims, err
+	verifier := oidc.Verifier(*v)
+	verifier.ClientID = ""
+	if len(verifier.AllowedTypes) == 0 {
+		// see WithAccessTokenTypes
+		verifier.AllowedTypes = []string{}
 	}
-	payload, err := oidc.ParseToken(decrypted, &claims)
+	claims, err = oidc.VerifyJWTAccessToken[C](ctx, token, &verifier)
 	if err != nil {
 		return nilClaims, err
 	}
-
-	if err := oidc.CheckIssuer(claims, v.Issuer); err != nil {
-		return nilClaims, err
-	}
-
-	if err = oidc.CheckSignature(ctx, decrypted, payload, claims, v.SupportedSignAlgs, v.KeySet); err != nil {
-		return nilClaims, err
-	}
-
-	if err = oidc.CheckExpiration(claims, v.Offset); err != nil {
-		return nilClaims, err
-	}
-
 	return claims, nil
 }
@@ -2,9 +2,11 @@ package op
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 	"time"
 
+	jose "github.com/go-jose/go-jose/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	tu "github.com/zitadel/oidc/v3/internal/testutil"
@@ -29,8 +31,9 @@ func TestNewAccessTokenVerifier(t *testing.T) {
 				keySet: tu.KeySet{},
 			},
 			want: &AccessTokenVerifier{
-				Issuer: tu.ValidIssuer,
-				KeySet: tu.KeySet{},
+				Issuer:       tu.ValidIssuer,
+				KeySet:       tu.KeySet{},
+				AllowedTypes: []string{oidc.TypeAccessToken, oidc.TypeJWT},
 			},
 		},
 		{
@@ -46,6 +49,22 @@ func TestNewAccessTokenVerifier(t *testing.T) {
 				Issuer:            tu.ValidIssuer,
 				KeySet:            tu.KeySet{},
 				SupportedSignAlgs: []string{"ABC", "DEF"},
+				AllowedTypes:      []string{oidc.TypeAccessToken, oidc.TypeJWT},
+			},
+		},
+		{
+			name: "with access token types",
+			args: args{
+				issuer: tu.ValidIssuer,
+				keySet: tu.KeySet{},
+				opts: []AccessTokenVerifierOpt{
+					WithAccessTokenTypes(oidc.TypeAccessToken),
+				},
+			},
+			want: &AccessTokenVerifier{
+				Issuer:       tu.ValidIssuer,
+				KeySet:       tu.KeySet{},
+				AllowedTypes: []string{oidc.TypeAccessToken},
 			},
 		},
 	}
@@ -57,6 +76,40 @@ func TestNewAccessTokenVerifier(t *testing.T) {
 	}
 }
 
+func TestVerifyAccessToken_type(t *testing.T) {
+	_, claims := tu.ValidAccessToken()
+	sign := func(typ jose.ContentType) string {
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: tu.SignatureAlgorithm, Key: tu.WebKey}, (&jose.SignerOptions{}).WithType(typ))
+		require.NoError(t, err)
+		payload, err := json.Marshal(claims)
+		require.NoError(t, err)
+		object, err := signer.Sign(payload)
+		require.NoError(t, err)
+		token, err := object.CompactSerialize()
+		require.NoError(t, err)
+		return token
+	}
+	tests := []struct {
+		name    string
+		typ     jose.ContentType
+		opts    []AccessTokenVerifierOpt
+		wantErr error
+	}{
+		{name: "at+jwt", typ: oidc.TypeAccessToken},
+		{name: "JWT", typ: oidc.TypeJWT},
+		{name: "logout token", typ: oidc.TypeLogoutToken, wantErr: oidc.ErrTypeInvalid},
+		{name: "strict at+jwt", typ: oidc.TypeAccessToken, opts: []AccessTokenVerifierOpt{WithAccessTokenTypes(oidc.TypeAccessToken)}},
+		{name: "strict JWT", typ: oidc.TypeJWT, opts: []AccessTokenVerifierOpt{WithAccessTokenTypes(oidc.TypeAccessToken)}, wantErr: oidc.ErrTypeInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewAccessTokenVerifier(tu.ValidIssuer, tu.KeySet{}, tt.opts...)
+			_, err := VerifyAccessToken[*oidc.AccessTokenClaims](context.Background(), sign(tt.typ), v)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
 func TestVerifyAccessToken(t *testing.T) {
 	verifier := &AccessTokenVerifier{
 		Issuer:            tu.ValidIssuer,
@@ -2,7 +2,9 @@ package op
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	jose "github.com/go-jose/go-jose/v4"
@@ -18,6 +20,11 @@ type JWTProfileVerifier struct {
 	Storage      JWTProfileKeyStorage
 	keySet       oidc.KeySet
 	CheckSubject func(request *oidc.JWTTokenRequest) error
+	audiences    []string
+	maxLifetime  time.Duration
+	jtiCache     JTICache
+	clientJTIs   JTICache
+	clientKeys   *ClientKeySetCache
 }
 
 // NewJWTProfileVerifier creates an oidc.Verifier for JWT Profile assertions (authorization grant and client authentication)
@@ -51,58 +58,154 @@ func newJWTProfileVerifier(storage JWTProfileKeyStorage, keySet oidc.KeySet, iss
 
 type JWTProfileVerifierOption func(*JWTProfileVerifier)
 
-// SubjectCheck sets a custom function to check the subject.
-// Defaults to SubjectIsIssuer()
+// SubjectCheck sets a custom function to check the subject of JWT profile grants,
+// e.g. to allow a service account to request tokens on behalf of a user.
+// It replaces the default [SubjectIsIssuer]. The assertions of client authentication
+// must always have the client_id as sub.
 func SubjectCheck(check func(request *oidc.JWTTokenRequest) error) JWTProfileVerifierOption {
 	return func(verifier *JWTProfileVerifier) {
 		verifier.CheckSubject = check
 	}
 }
 
+// WithAssertionAudiences accepts the audiences, typically the token endpoint URL,
+// in addition to the issuer as valid aud of the assertion.
+func WithAssertionAudiences(audiences ...string) JWTProfileVerifierOption {
+	return func(verifier *JWTProfileVerifier) {
+		verifier.audiences = append(verifier.audiences, audiences...)
+	}
+}
+
+// WithMaxAssertionLifetime restricts the lifetime of an assertion,
+// which is the time between its iat and exp claims.
+func WithMaxAssertionLifetime(lifetime time.Duration) JWTProfileVerifierOption {
+	return func(verifier *JWTProfileVerifier) {
+		verifier.maxLifetime = lifetime
+	}
+}
+
+// WithJTICache enables replay protection for assertions.
+// Each assertion must carry a jti claim, which may be used only once
+// during the lifetime of the assertion.
+func WithJTICache(cache JTICache) JWTProfileVerifierOption {
+	return func(verifier *JWTProfileVerifier) {
+		verifier.jtiCache = cache
+	}
+}
+
+// withClientAssertionJTICache checks the jti of client assertions for replays,
+// if not already done for all assertions by [WithJTICache].
+func withClientAssertionJTICache(cache JTICache) JWTProfileVerifierOption {
+	return func(verifier *JWTProfileVerifier) {
+		verifier.clientJTIs = cache
+	}
+}
+
+// WithClientKeys verifies assertions of clients implementing [HasJWKS] or [HasJWKSURI]
+// with their registered keys, if the Storage is able to load the client.
+// Keys of other issuers are still retrieved from the Storage.
+func WithClientKeys(cache *ClientKeySetCache) JWTProfileVerifierOption {
+	return func(verifier *JWTProfileVerifier) {
+		verifier.clientKeys = cache
+	}
+}
+
 // VerifyJWTAssertion verifies the assertion string from JWT Profile (authorization grant and client authentication)
-//
-// checks audience, exp, iat, signature and that issuer and sub are the same
+// with [oidc.VerifyJWTProfileAssertion], which checks audience, exp, iat and signature,
+// and the subject with the CheckSubject of the verifier.
+// The signature algorithm must be one of the SupportedSignAlgs (default RS256),
+// or the token_endpoint_auth_signing_alg of an issuer implementing [HasTokenEndpointAuthSigningAlg].
+// When configured, the lifetime of the assertion is restricted and
+// its jti is checked for replays.
 func VerifyJWTAssertion(ctx context.Context, assertion string, v *JWTProfileVerifier) (*oidc.JWTTokenRequest, error) {
 	ctx, span := Tracer.Start(ctx, "VerifyJWTAssertion")
 	defer span.End()
 
-	request := new(oidc.JWTTokenRequest)
-	payload, err := oidc.ParseToken(assertion, request)
+	// the keys depend on the issuer, which is verified with the signature
+	unverified := new(oidc.JWTTokenRequest)
+	if _, err := oidc.ParseToken(assertion, unverified); err != nil {
+		return nil, err
+	}
+	verifier := v.Verifier
+	verifier.KeySet = v.keySet
+	if verifier.KeySet == nil {
+		keySet := &jwtProfileKeySet{storage: v.Storage, clientID: unverified.Issuer, clientKeys: v.clientKeys, clientAlg: tokenEndpointAuthSigningAlg}
+		supportedAlgs, err := keySet.signingAlgs(ctx, verifier.SupportedSignAlgs)
+		if err != nil {
+			return nil, err
+		}
+		verifier.KeySet, verifier.SupportedSignAlgs = keySet, supportedAlgs
+	}
+	request, err := oidc.VerifyJWTProfileAssertion(ctx, assertion, &verifier, v.audiences...)
 	if err != nil {
 		return nil, err
 	}
 
-	if err = oidc.CheckAudience(request, v.Issuer); err != nil {
-		return nil, err
+	if v.maxLifetime > 0 && request.ExpiresAt.AsTime().Sub(request.IssuedAt.AsTime()) > v.maxLifetime {
+		return nil, fmt.Errorf("%w: lifetime must not exceed %v", ErrAssertionLifetime, v.maxLifetime)
 	}
 
-	if err = oidc.CheckExpiration(request, v.Offset); err != nil {
-		return nil, err
+	if v.CheckSubject != nil {
+		if err = v.CheckSubject(request); err != nil {
+			return nil, err
+		}
 	}
+	// replays are only checked for correctly signed assertions,
+	// so the cache can't be filled by anyone else.
+	if v.jtiCache != nil {
+		if err = checkAssertionJTI(ctx, v.jtiCache, request); err != nil {
+			return nil, err
+		}
+	}
+	return request, nil
+}
 
-	if err = oidc.CheckIssuedAt(request, v.MaxAgeIAT, v.Offset); err != nil {
+// verifyClientAssertion verifies the assertion of a client authenticating with private_key_jwt.
+// Its sub must be the client_id, regardless of the CheckSubject of the verifier,
+// see https://www.rfc-editor.org/rfc/rfc7523#section-3
+// The jti is checked for replays, see [WithClientAssertionJTICache].
+func verifyClientAssertion(ctx context.Context, assertion string, v *JWTProfileVerifier) (*oidc.JWTTokenRequest, error) {
+	request, err := VerifyJWTAssertion(ctx, assertion, v)
+	if err != nil {
 		return nil, err
 	}
-
-	if err = v.CheckSubject(request); err != nil {
+	if err = SubjectIsIssuer(request); err != nil {
 		return nil, err
 	}
+	if v.jtiCache == nil && v.clientJTIs != nil {
+		if err = checkAssertionJTI(ctx, v.clientJTIs, request); err != nil {
+			return nil, err
+		}
+	}
+	return request, nil
+}
+
+var (
+	ErrAssertionLifetime = errors.New("assertion lifetime too long")
+	ErrJTIMissing        = errors.New("jti missing")
+	ErrJTIReplayed       = errors.New("jti already used")
+)
 
-	keySet := v.keySet
-	if keySet == nil {
-		keySet = &jwtProfileKeySet{storage: v.Storage, clientID: request.Issuer}
+func checkAssertionJTI(ctx context.Context, cache JTICache, request *oidc.JWTTokenRequest) error {
+	if request.JWTID == "" {
+		return ErrJTIMissing
 	}
-	if err = oidc.CheckSignature(ctx, assertion, payload, request, nil, keySet); err != nil {
-		return nil, err
+	ok, err := cache.UseJTI(ctx, request.Issuer, request.JWTID, request.ExpiresAt.AsTime())
+	if err != nil {
+		return fmt.Errorf("jti cache: %w", err)
 	}
-	return request, nil
+	if !ok {
+		return ErrJTIReplayed
+	}
+	return nil
 }
 
 type JWTProfileKeyStorage interface {
 	GetKeyByIDAndClientID(ctx context.Context, keyID, clientID string) (*jose.JSONWebKey, error)
 }
 
-// SubjectIsIssuer
+// SubjectIsIssuer requires the sub of the assertion to be its iss,
+// so it is only valid for the client or service account issuing it.
 func SubjectIsIssuer(request *oidc.JWTTokenRequest) error {
 	if request.Issuer != request.Subject {
 		return oidc.ErrSubjectInvalid
@@ -111,19 +214,85 @@ func SubjectIsIssuer(request *oidc.JWTTokenRequest) error {
 }
 
 type jwtProfileKeySet struct {
-	storage  JWTProfileKeyStorage
-	clientID string
+	storage    JWTProfileKeyStorage
+	clientID   string
+	clientKeys *ClientKeySetCache
+	// clientAlg returns the signing algorithm registered by the client, if any.
+	clientAlg func(client Client) jose.SignatureAlgorithm
+
+	client       Client
+	clientLoaded bool
 }
 
-// VerifySignature implements oidc.KeySet by getting the public key from Storage implementation
+// VerifySignature implements oidc.KeySet by getting the public key from Storage implementation,
+// or from the registered jwks / jwks_uri of the client, see [WithClientKeys].
 func (k *jwtProfileKeySet) VerifySignature(ctx context.Context, jws *jose.JSONWebSignature) (payload []byte, err error) {
 	ctx, span := Tracer.Start(ctx, "VerifySignature")
 	defer span.End()
 
-	keyID, _ := oidc.GetKeyIDAndAlg(jws)
-	key, err := k.storage.GetKeyByIDAndClientID(ctx, keyID, k.clientID)
+	keyID, alg := oidc.GetKeyIDAndAlg(jws)
+	if key, err := k.clientKey(ctx, keyID, alg); !errors.Is(err, ErrNoClientKeys) {
+		if err != nil {
+			return nil, fmt.Errorf("error fetching keys: %w", err)
+		}
+		return jws.Verify(key)
+	}
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	key, err := k.storage.GetKeyByIDAndClientID(storageCtx, keyID, k.clientID)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching keys: %w", err)
 	}
 	return jws.Verify(key)
 }
+
+// signingAlgs returns the signing algorithm registered by the client,
+// or the supported algorithms if there is none.
+// A registered algorithm which is not supported is rejected.
+func (k *jwtProfileKeySet) signingAlgs(ctx context.Context, supported []string) ([]string, error) {
+	if k.clientAlg == nil {
+		return supported, nil
+	}
+	if client := k.loadClient(ctx); client != nil {
+		if alg := k.clientAlg(client); alg != "" {
+			if len(supported) > 0 && !slices.Contains(supported, string(alg)) {
+				return nil, oidc.ErrSignatureUnsupportedAlg
+			}
+			return []string{string(alg)}, nil
+		}
+	}
+	return supported, nil
+}
+
+// loadClient returns the issuer as client,
+// or nil if the Storage is not able to load it.
+func (k *jwtProfileKeySet) loadClient(ctx context.Context) Client {
+	if k.clientLoaded {
+		return k.client
+	}
+	k.clientLoaded = true
+	clients, ok := k.storage.(interface {
+		GetClientByClientID(ctx context.Context, clientID string) (Client, error)
+	})
+	if !ok {
+		return nil
+	}
+	storageCtx, cancel := storageContext(ctx)
+	defer cancel()
+	if client, err := clients.GetClientByClientID(storageCtx, k.clientID); err == nil {
+		k.client = client
+	}
+	return k.client
+}
+
+// clientKey returns [ErrNoClientKeys] if the issuer isn't a client with registered keys.
+func (k *jwtProfileKeySet) clientKey(ctx context.Context, keyID, alg string) (*jose.JSONWebKey, error) {
+	if k.clientKeys == nil {
+		return nil, ErrNoClientKeys
+	}
+	client := k.loadClient(ctx)
+	if client == nil {
+		return nil, ErrNoClientKeys
+	}
+	return k.clientKeys.SigningKey(ctx, client, keyID, alg)
+}
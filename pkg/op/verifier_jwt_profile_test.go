@@ -5,8 +5,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-jose/go-jose/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/example/server/storage"
 	tu "github.com/zitadel/oidc/v3/internal/testutil"
 	"github.com/zitadel/oidc/v3/pkg/oidc"
 	"github.com/zitadel/oidc/v3/pkg/op"
@@ -115,3 +118,187 @@ func TestVerifyJWTAssertion(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifyJWTAssertion_subjectCheck(t *testing.T) {
+	verifier := op.NewJWTProfileVerifier(tu.JWTProfileKeyStorage{}, tu.ValidIssuer, time.Minute, 0,
+		op.SubjectCheck(func(request *oidc.JWTTokenRequest) error {
+			if request.Subject != "user" {
+				return oidc.ErrSubjectInvalid
+			}
+			return nil
+		}),
+	)
+	assertion, _ := tu.NewJWTProfileAssertion(tu.ValidClientID, "user", []string{tu.ValidIssuer}, time.Now(), tu.ValidExpiration)
+	got, err := op.VerifyJWTAssertion(context.Background(), assertion, verifier)
+	require.NoError(t, err, "the subject check replaces the default SubjectIsIssuer")
+	assert.Equal(t, tu.ValidClientID, got.Issuer)
+	assert.Equal(t, "user", got.Subject)
+
+	assertion, _ = tu.ValidJWTProfileAssertion()
+	_, err = op.VerifyJWTAssertion(context.Background(), assertion, verifier)
+	assert.ErrorIs(t, err, oidc.ErrSubjectInvalid)
+}
+
+func TestVerifyJWTAssertion_options(t *testing.T) {
+	tokenEndpoint := "https://local.com/oauth/v2/token"
+	verifier := op.NewJWTProfileVerifier(tu.JWTProfileKeyStorage{}, tu.ValidIssuer, time.Hour, 0,
+		op.WithAssertionAudiences(tokenEndpoint),
+		op.WithMaxAssertionLifetime(5*time.Minute),
+		op.WithJTICache(op.NewMemoryJTICache()),
+	)
+
+	t.Run("token endpoint audience", func(t *testing.T) {
+		assertion, want := tu.NewJWTProfileAssertion(
+			tu.ValidClientID, tu.ValidClientID, []string{tokenEndpoint},
+			time.Now(), time.Now().Add(time.Minute),
+		)
+		got, err := op.VerifyJWTAssertion(context.Background(), assertion, verifier)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	})
+	t.Run("lifetime too long", func(t *testing.T) {
+		assertion, _ := tu.NewJWTProfileAssertion(
+			tu.ValidClientID, tu.ValidClientID, []string{tu.ValidIssuer},
+			time.Now(), time.Now().Add(time.Hour),
+		)
+		_, err := op.VerifyJWTAssertion(context.Background(), assertion, verifier)
+		assert.ErrorIs(t, err, op.ErrAssertionLifetime)
+	})
+	t.Run("replay", func(t *testing.T) {
+		assertion, _ := tu.NewJWTProfileAssertion(
+			tu.ValidClientID, tu.ValidClientID, []string{tu.ValidIssuer},
+			time.Now(), time.Now().Add(time.Minute),
+		)
+		_, err := op.VerifyJWTAssertion(context.Background(), assertion, verifier)
+		require.NoError(t, err)
+		_, err = op.VerifyJWTAssertion(context.Background(), assertion, verifier)
+		assert.ErrorIs(t, err, op.ErrJTIReplayed)
+	})
+}
+
+type jwtProfileKeyStorage struct {
+	*storage.Storage
+}
+
+func (jwtProfileKeyStorage) GetKeyByIDAndClientID(ctx context.Context, keyID, clientID string) (*jose.JSONWebKey, error) {
+	return tu.JWTProfileKeyStorage{}.GetKeyByIDAndClientID(ctx, keyID, clientID)
+}
+
+func TestWithJWTProfileVerifierOpts(t *testing.T) {
+	provider, err := op.NewOpenIDProvider(testIssuer, testConfig,
+		jwtProfileKeyStorage{storage.NewStorage(storage.NewUserStore(testIssuer))},
+		op.WithAllowInsecure(),
+		op.WithJWTProfileVerifierOpts(op.WithJTICache(op.NewMemoryJTICache())),
+		op.WithJWTProfileVerifierOpts(op.WithMaxAssertionLifetime(5*time.Minute)),
+	)
+	require.NoError(t, err)
+	ctx := op.ContextWithIssuer(context.Background(), testIssuer)
+	verifier := provider.JWTProfileVerifier(ctx)
+
+	assertion, _ := tu.NewJWTProfileAssertion(
+		tu.ValidClientID, tu.ValidClientID, []string{testIssuer},
+		time.Now(), time.Now().Add(time.Hour),
+	)
+	_, err = op.VerifyJWTAssertion(ctx, assertion, verifier)
+	assert.ErrorIs(t, err, op.ErrAssertionLifetime)
+
+	assertion, _ = tu.NewJWTProfileAssertion(
+		tu.ValidClientID, tu.ValidClientID, []string{testIssuer},
+		time.Now(), time.Now().Add(time.Minute),
+	)
+	_, err = op.VerifyJWTAssertion(ctx, assertion, verifier)
+	require.NoError(t, err)
+	_, err = op.VerifyJWTAssertion(ctx, assertion, verifier)
+	assert.ErrorIs(t, err, op.ErrJTIReplayed)
+}
+
+func TestClientAssertionJTICache(t *testing.T) {
+	newProvider := func(t *testing.T, opts ...op.Option) *op.Provider {
+		provider, err := op.NewOpenIDProvider(testIssuer, testConfig,
+			jwtProfileKeyStorage{storage.NewStorage(storage.NewUserStore(testIssuer))},
+			append([]op.Option{op.WithAllowInsecure()}, opts...)...,
+		)
+		require.NoError(t, err)
+		return provider
+	}
+	ctx := op.ContextWithIssuer(context.Background(), testIssuer)
+	newAssertion := func() oidc.ClientAssertionParams {
+		assertion, _ := tu.NewJWTProfileAssertion(
+			tu.ValidClientID, tu.ValidClientID, []string{testIssuer},
+			time.Now(), time.Now().Add(time.Minute),
+		)
+		return oidc.ClientAssertionParams{ClientAssertion: assertion}
+	}
+
+	t.Run("default", func(t *testing.T) {
+		provider := newProvider(t)
+		assertion := newAssertion()
+		_, err := op.ClientJWTAuth(ctx, assertion, provider)
+		require.NoError(t, err)
+		_, err = op.ClientJWTAuth(ctx, assertion, provider)
+		assert.ErrorIs(t, err, op.ErrJTIReplayed)
+
+		// JWT profile grants are only checked with WithJTICache
+		_, err = op.VerifyJWTAssertion(ctx, assertion.ClientAssertion, provider.JWTProfileVerifier(ctx))
+		assert.NoError(t, err)
+	})
+	t.Run("shared cache", func(t *testing.T) {
+		cache := op.NewMemoryJTICache()
+		assertion := newAssertion()
+		_, err := op.ClientJWTAuth(ctx, assertion, newProvider(t, op.WithClientAssertionJTICache(cache)))
+		require.NoError(t, err)
+		_, err = op.ClientJWTAuth(ctx, assertion, newProvider(t, op.WithClientAssertionJTICache(cache)))
+		assert.ErrorIs(t, err, op.ErrJTIReplayed, "replayed on another instance")
+	})
+	t.Run("cache for all assertions", func(t *testing.T) {
+		provider := newProvider(t, op.WithJWTProfileVerifierOpts(op.WithJTICache(op.NewMemoryJTICache())))
+		assertion := newAssertion()
+		_, err := op.ClientJWTAuth(ctx, assertion, provider)
+		require.NoError(t, err, "jti checked once")
+		_, err = op.ClientJWTAuth(ctx, assertion, provider)
+		assert.ErrorIs(t, err, op.ErrJTIReplayed)
+	})
+	t.Run("disabled", func(t *testing.T) {
+		provider := newProvider(t, op.WithClientAssertionJTICache(nil))
+		assertion := newAssertion()
+		_, err := op.ClientJWTAuth(ctx, assertion, provider)
+		require.NoError(t, err)
+		_, err = op.ClientJWTAuth(ctx, assertion, provider)
+		assert.NoError(t, err)
+	})
+}
+
+func TestMemoryJTICache(t *testing.T) {
+	ctx := context.Background()
+	cache := op.NewMemoryJTICache()
+
+	ok, err := cache.UseJTI(ctx, "client", "1", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = cache.UseJTI(ctx, "client", "1", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, ok, "replayed jti")
+	ok, err = cache.UseJTI(ctx, "other", "1", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, ok, "jti of another issuer")
+
+	ok, err = cache.UseJTI(ctx, "client", "2", time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = cache.UseJTI(ctx, "client", "2", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, ok, "expired jti is pruned")
+
+	ok, err = cache.UseJTI(ctx, "client", "3", time.Now().Add(-time.Second))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = cache.UseJTI(ctx, "client", "3", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, ok, "expired jti is pruned")
+	ok, err = cache.UseJTI(ctx, "client", "4", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = cache.UseJTI(ctx, "client", "3", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, ok, "reused jti is kept until its new expiration")
+}
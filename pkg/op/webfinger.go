@@ -0,0 +1,92 @@
+package op
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	httphelper "github.com/zitadel/oidc/v3/pkg/http"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+// WebFingerResolver returns the issuer of the user identified by the resource
+// of a WebFinger request, e.g. acct:joe@example.com, for issuer discovery
+// (OpenID Connect Discovery section 2).
+// An empty issuer responds with 404 Not Found, for resources not served by the OP.
+type WebFingerResolver func(ctx context.Context, resource string) (issuer string, err error)
+
+// WithWebFingerResolver sets the [WebFingerResolver] of the /.well-known/webfinger endpoint,
+// e.g. to return the issuer of the tenant of the user.
+// Without a resolver, the issuer of the OP is returned for all resources.
+func WithWebFingerResolver(resolver WebFingerResolver) Option {
+	return func(o *Provider) error {
+		o.webFingerResolver = resolver
+		return nil
+	}
+}
+
+// WebFingerResolver returns the resolver set by [WithWebFingerResolver].
+func (o *Provider) WebFingerResolver() WebFingerResolver {
+	return o.webFingerResolver
+}
+
+// WebFingerProvider passes the resolver of [WithWebFingerResolver] to [WebFinger].
+// It is implemented by the [Provider].
+type WebFingerProvider interface {
+	WebFingerResolver() WebFingerResolver
+}
+
+func webFingerProviderFrom(v any) WebFingerProvider {
+	if p, ok := v.(WebFingerProvider); ok {
+		return p
+	}
+	return nil
+}
+
+func webFingerHandler(o OpenIDProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request, err := decodeRequest[oidc.WebFingerRequest](o.Decoder(), r, false)
+		if err != nil {
+			WriteError(w, r, err, nil)
+			return
+		}
+		resp, err := WebFinger(r.Context(), webFingerProviderFrom(o), request)
+		if err != nil {
+			WriteError(w, r, err, nil)
+			return
+		}
+		httphelper.MarshalJSON(w, resp)
+	}
+}
+
+// WebFinger returns the issuer of the resource of the request in the WebFinger response,
+// if the issuer link relation is requested.
+// The resource is resolved by the resolver of the provider, if any,
+// otherwise the issuer of the context is returned.
+func WebFinger(ctx context.Context, provider WebFingerProvider, request *oidc.WebFingerRequest) (*oidc.WebFingerResponse, error) {
+	ctx, span := Tracer.Start(ctx, "WebFinger")
+	defer span.End()
+
+	if request.Resource == "" {
+		return nil, oidc.ErrInvalidRequest().WithDescription("resource missing")
+	}
+	issuer := IssuerFromContext(ctx)
+	var resolver WebFingerResolver
+	if provider != nil {
+		resolver = provider.WebFingerResolver()
+	}
+	if resolver != nil {
+		var err error
+		if issuer, err = resolver(ctx, request.Resource); err != nil {
+			return nil, oidc.DefaultToServerError(err, "unable to resolve webfinger resource")
+		}
+		if issuer == "" {
+			return nil, NewStatusError(oidc.ErrInvalidRequest().WithDescription("unknown resource"), http.StatusNotFound)
+		}
+	}
+	resp := &oidc.WebFingerResponse{Subject: request.Resource}
+	if len(request.Rel) == 0 || slices.Contains(request.Rel, oidc.WebFingerRelIssuer) {
+		resp.Links = []oidc.WebFingerLink{{Rel: oidc.WebFingerRelIssuer, Href: issuer}}
+	}
+	return resp, nil
+}
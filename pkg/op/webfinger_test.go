@@ -0,0 +1,54 @@
+package op_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zitadel/oidc/v3/pkg/client"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/oidc/v3/pkg/op"
+	"github.com/zitadel/oidc/v3/pkg/op/optest"
+)
+
+func TestWebFinger_resolver(t *testing.T) {
+	s := optest.New(t, optest.WithProviderOptions(op.WithWebFingerResolver(func(ctx context.Context, resource string) (string, error) {
+		if strings.HasSuffix(resource, "@example.com") {
+			return op.IssuerFromContext(ctx), nil
+		}
+		return "", nil
+	})))
+	issuer, err := client.WebFinger(context.Background(), "joe@example.com", &http.Client{Transport: rewriteTransport{s}})
+	require.NoError(t, err)
+	assert.Equal(t, s.Issuer, issuer)
+
+	_, err = client.WebFinger(context.Background(), "joe@other.example.com", &http.Client{Transport: rewriteTransport{s}})
+	assert.Error(t, err, "unknown resource")
+}
+
+// rewriteTransport sends the https webfinger requests of any host to the test server.
+type rewriteTransport struct {
+	s *optest.Server
+}
+
+func (rt rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = "http"
+	req.URL.Host = rt.s.Listener.Addr().String()
+	return rt.s.Client().Transport.RoundTrip(req)
+}
+
+func TestWebFinger_withoutResolver(t *testing.T) {
+	ctx := op.ContextWithIssuer(context.Background(), "https://op.example.com")
+	resp, err := op.WebFinger(ctx, nil, &oidc.WebFingerRequest{Resource: "acct:joe@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, []oidc.WebFingerLink{{Rel: oidc.WebFingerRelIssuer, Href: "https://op.example.com"}}, resp.Links)
+
+	resp, err = op.WebFinger(ctx, &op.Provider{}, &oidc.WebFingerRequest{Resource: "acct:joe@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "acct:joe@example.com", resp.Subject)
+}